@@ -10,6 +10,8 @@ import (
 	"strings"
 	"testing"
 	"time"
+
+	"h2/internal/activitylog"
 )
 
 // tokenPrefix is the common prefix for all receipt tokens.
@@ -265,12 +267,12 @@ type activityLogEntry struct {
 }
 
 // readActivityLog reads and parses all entries from session-activity.jsonl
-// for the given agent.
+// (including rotated files) for the given agent.
 func readActivityLog(t *testing.T, h2Dir, agentName string) []activityLogEntry {
 	t.Helper()
 
 	logPath := filepath.Join(h2Dir, "sessions", agentName, "session-activity.jsonl")
-	f, err := os.Open(logPath)
+	f, err := activitylog.Open(logPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return nil
@@ -313,7 +315,7 @@ func collectReceivedTokens(t *testing.T, h2Dir, agentName string) []string {
 	t.Helper()
 
 	logPath := filepath.Join(h2Dir, "sessions", agentName, "session-activity.jsonl")
-	f, err := os.Open(logPath)
+	f, err := activitylog.Open(logPath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			t.Logf("collectReceivedTokens: no activity log at %s", logPath)
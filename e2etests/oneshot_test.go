@@ -0,0 +1,74 @@
+package e2etests
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// h2 run --output launches the agent, delivers one prompt, prints the
+// captured output once the agent goes idle, and stops the agent.
+func TestRun_OutputOneShot(t *testing.T) {
+	h2Dir := createTestH2Dir(t)
+
+	result := runH2(t, h2Dir, "run", "--command", "cat", "--name", "oneshot",
+		"--output", "--prompt", "hello from a one-shot run", "--timeout", "30s")
+	if result.ExitCode != 0 {
+		t.Fatalf("h2 run --output failed: exit=%d stderr=%s", result.ExitCode, result.Stderr)
+	}
+
+	// The prompt is echoed by the PTY and printed again by cat.
+	if n := strings.Count(result.Stdout, "hello from a one-shot run"); n != 2 {
+		t.Errorf("captured output = %q, want the prompt echoed and printed back", result.Stdout)
+	}
+	if strings.Contains(result.Stdout, "h2 message from") {
+		t.Errorf("prompt should be delivered without the sender prefix, got %q", result.Stdout)
+	}
+
+	sockPath := filepath.Join(h2Dir, "sockets", "agent.oneshot.sock")
+	if _, err := os.Stat(sockPath); !os.IsNotExist(err) {
+		stopAgent(t, h2Dir, "oneshot")
+		t.Errorf("agent should be stopped after a one-shot run (socket %s still present)", sockPath)
+	}
+}
+
+func TestRun_OutputRejectsDryRun(t *testing.T) {
+	h2Dir := createTestH2Dir(t)
+
+	result := runH2(t, h2Dir, "run", "--command", "cat", "--name", "oneshot-dry", "--output", "--prompt", "x", "--dry-run")
+	if result.ExitCode == 0 || !strings.Contains(result.Stderr, "--output cannot be combined with --dry-run") {
+		t.Errorf("--output with --dry-run should fail, got exit=%d stderr=%s", result.ExitCode, result.Stderr)
+	}
+}
+
+func TestRun_DetachedLaunchSummary(t *testing.T) {
+	h2Dir := createTestH2Dir(t)
+
+	result := runH2(t, h2Dir, "run", "--command", "cat", "--name", "summarized", "--detach")
+	if result.ExitCode != 0 {
+		t.Fatalf("h2 run failed: exit=%d stderr=%s", result.ExitCode, result.Stderr)
+	}
+	t.Cleanup(func() { stopAgent(t, h2Dir, "summarized") })
+
+	var sum struct {
+		Name       string `json:"name"`
+		WorkingDir string `json:"working_dir"`
+		Socket     string `json:"socket"`
+		PID        int    `json:"pid"`
+		DaemonPID  int    `json:"daemon_pid"`
+	}
+	if err := json.Unmarshal([]byte(result.Stdout), &sum); err != nil {
+		t.Fatalf("launch summary is not valid JSON: %v\n%s", err, result.Stdout)
+	}
+	if sum.Name != "summarized" || sum.WorkingDir == "" {
+		t.Errorf("unexpected summary: %+v", sum)
+	}
+	if want := filepath.Join(h2Dir, "sockets", "agent.summarized.sock"); sum.Socket != want {
+		t.Errorf("socket = %q, want %q", sum.Socket, want)
+	}
+	if sum.PID <= 0 || sum.DaemonPID <= 0 || sum.PID == sum.DaemonPID {
+		t.Errorf("expected distinct agent and daemon PIDs, got pid=%d daemon_pid=%d", sum.PID, sum.DaemonPID)
+	}
+}
@@ -0,0 +1,65 @@
+package e2etests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// h2 send --interrupt-first types Ctrl+C into the agent's PTY, lets it
+// settle, and then delivers the message at interrupt priority.
+func TestSend_InterruptFirst(t *testing.T) {
+	h2Dir := createTestH2Dir(t)
+
+	// The agent records every byte it receives; raw mode keeps Ctrl+C as a
+	// byte instead of a signal.
+	outFile := filepath.Join(t.TempDir(), "received")
+	script := filepath.Join(t.TempDir(), "record.sh")
+	os.WriteFile(script, []byte("#!/bin/sh\nstty raw -echo\nexec cat > "+outFile+"\n"), 0o755)
+
+	result := runH2(t, h2Dir, "run", "--command", script, "--name", "interrupt-first", "--detach")
+	if result.ExitCode != 0 {
+		t.Fatalf("h2 run failed: exit=%d stderr=%s", result.ExitCode, result.Stderr)
+	}
+	waitForSocket(t, h2Dir, "agent", "interrupt-first")
+	t.Cleanup(func() { stopAgent(t, h2Dir, "interrupt-first") })
+
+	result = runH2(t, h2Dir, "send", "--interrupt-first", "interrupt-first", "stop and do this instead")
+	if result.ExitCode != 0 {
+		t.Fatalf("h2 send --interrupt-first failed: exit=%d stderr=%s", result.ExitCode, result.Stderr)
+	}
+
+	var got string
+	deadline := time.Now().Add(15 * time.Second)
+	for time.Now().Before(deadline) {
+		data, _ := os.ReadFile(outFile)
+		got = string(data)
+		if strings.Contains(got, "stop and do this instead") {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	ctrlC := strings.IndexByte(got, 0x03)
+	body := strings.Index(got, "[URGENT h2 message from: ")
+	if ctrlC < 0 || body < 0 {
+		t.Fatalf("PTY received %q, want Ctrl+C followed by the urgent message", got)
+	}
+	if ctrlC > body {
+		t.Errorf("Ctrl+C should reach the PTY before the message, got %q", got)
+	}
+	if !strings.Contains(got[body:], "stop and do this instead") {
+		t.Errorf("message body not delivered after the interrupt, got %q", got)
+	}
+}
+
+func TestSend_InterruptFirstConflictingPriority(t *testing.T) {
+	h2Dir := createTestH2Dir(t)
+
+	result := runH2(t, h2Dir, "send", "--interrupt-first", "--priority", "idle", "someone", "hi")
+	if result.ExitCode == 0 || !strings.Contains(result.Stderr, "--interrupt-first delivers at interrupt priority") {
+		t.Errorf("expected priority conflict error, got exit=%d stderr=%s", result.ExitCode, result.Stderr)
+	}
+}
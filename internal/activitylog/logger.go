@@ -15,11 +15,24 @@ type Logger struct {
 	w         *os.File
 	actor     string
 	sessionID string
+	cid       string // correlation ID stamped on every entry (empty = none)
+	cidGen    int    // bumped by each SetCorrelationID, so a stale reset is a no-op
+
+	path     string
+	rotation Rotation
+	size     int64     // bytes in the current file
+	opened   time.Time // when the current file was started
 }
 
-// New creates a Logger that appends to logPath. If enabled is false or the
-// file cannot be opened, returns a no-op logger (safe to call methods on).
+// New creates a Logger that appends to logPath, rotating it per
+// DefaultRotation. If enabled is false or the file cannot be opened,
+// returns a no-op logger (safe to call methods on).
 func New(enabled bool, logPath, actor, sessionID string) *Logger {
+	return NewWithRotation(enabled, logPath, actor, sessionID, DefaultRotation)
+}
+
+// NewWithRotation is like New but with explicit rotation settings.
+func NewWithRotation(enabled bool, logPath, actor, sessionID string, rotation Rotation) *Logger {
 	if !enabled {
 		return &Logger{}
 	}
@@ -27,7 +40,18 @@ func New(enabled bool, logPath, actor, sessionID string) *Logger {
 	if err != nil {
 		return &Logger{}
 	}
-	return &Logger{w: f, actor: actor, sessionID: sessionID}
+	l := &Logger{
+		w:         f,
+		actor:     actor,
+		sessionID: sessionID,
+		path:      logPath,
+		rotation:  rotation,
+		opened:    time.Now(),
+	}
+	if info, err := f.Stat(); err == nil {
+		l.size = info.Size()
+	}
+	return l
 }
 
 // Nop returns a disabled logger. All methods are no-ops.
@@ -41,6 +65,60 @@ type entry struct {
 	Actor     string `json:"actor"`
 	SessionID string `json:"session_id"`
 	Event     string `json:"event"`
+
+	CorrelationID string `json:"correlation_id,omitempty"`
+}
+
+// SetCorrelationID stamps id onto every subsequent entry until reset is
+// called or another SetCorrelationID replaces it; a reset after that is a
+// no-op. An empty ID stops stamping.
+func (l *Logger) SetCorrelationID(id string) (reset func()) {
+	l.mu.Lock()
+	l.cid = id
+	l.cidGen++
+	gen := l.cidGen
+	l.mu.Unlock()
+	return func() {
+		l.mu.Lock()
+		if l.cidGen == gen {
+			l.cid = ""
+		}
+		l.mu.Unlock()
+	}
+}
+
+// MessageDelivered logs that a queued message was written to the agent.
+// Callers set the message's correlation ID first so this entry carries it.
+func (l *Logger) MessageDelivered(messageID, from, priority string) {
+	l.log(struct {
+		entry
+		MessageID string `json:"message_id"`
+		From      string `json:"from,omitempty"`
+		Priority  string `json:"priority"`
+	}{
+		entry:     l.entry("message_delivered"),
+		MessageID: messageID,
+		From:      from,
+		Priority:  priority,
+	})
+}
+
+// UserInput logs input typed into an attached client's input bar and
+// written straight to the agent. Input typed at another priority is queued
+// and logged once, by MessageDelivered, when it is delivered. Only the size
+// is recorded, not the text.
+func (l *Logger) UserInput(client, priority string, size int) {
+	l.log(struct {
+		entry
+		Client   string `json:"client"`
+		Priority string `json:"priority"`
+		Bytes    int    `json:"bytes"`
+	}{
+		entry:    l.entry("user_input"),
+		Client:   client,
+		Priority: priority,
+		Bytes:    size,
+	})
 }
 
 // HookEvent logs a Claude Code hook event.
@@ -95,6 +173,23 @@ func (l *Logger) StateChange(from, to string) {
 	})
 }
 
+// MessageHook logs the result of a role's on_message command.
+func (l *Logger) MessageHook(messageID string, exitCode int, output, errText string) {
+	l.log(struct {
+		entry
+		MessageID string `json:"message_id"`
+		ExitCode  int    `json:"exit_code"`
+		Output    string `json:"output,omitempty"`
+		Error     string `json:"error,omitempty"`
+	}{
+		entry:     l.entry("message_hook"),
+		MessageID: messageID,
+		ExitCode:  exitCode,
+		Output:    output,
+		Error:     errText,
+	})
+}
+
 // SessionSummaryData contains all metrics for a session_summary log entry.
 type SessionSummaryData struct {
 	InputTokens  int64
@@ -169,6 +264,8 @@ func (l *Logger) SessionSummary(d SessionSummaryData) {
 
 // Close closes the underlying file.
 func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	if l.w == nil {
 		return nil
 	}
@@ -176,26 +273,25 @@ func (l *Logger) Close() error {
 }
 
 func (l *Logger) entry(event string) entry {
-	return entry{
-		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
-		Actor:     l.actor,
-		SessionID: l.sessionID,
-		Event:     event,
-	}
+	return l.entryWithSession(event, l.sessionID)
 }
 
 func (l *Logger) entryWithSession(event, sessionID string) entry {
+	l.mu.Lock()
+	cid := l.cid
+	l.mu.Unlock()
 	return entry{
-		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
-		Actor:     l.actor,
-		SessionID: sessionID,
-		Event:     event,
+		Timestamp:     time.Now().UTC().Format(time.RFC3339Nano),
+		Actor:         l.actor,
+		SessionID:     sessionID,
+		Event:         event,
+		CorrelationID: cid,
 	}
 }
 
 func (l *Logger) log(v any) {
-	if l.w == nil {
-		return
+	if l.path == "" {
+		return // disabled; path is only set on enabled loggers
 	}
 	data, err := json.Marshal(v)
 	if err != nil {
@@ -203,6 +299,13 @@ func (l *Logger) log(v any) {
 	}
 	data = append(data, '\n')
 	l.mu.Lock()
-	l.w.Write(data)
-	l.mu.Unlock()
+	defer l.mu.Unlock()
+	if now := time.Now(); l.needsRotateLocked(len(data), now) {
+		l.rotateLocked(now)
+	}
+	if l.w == nil {
+		return
+	}
+	n, _ := l.w.Write(data)
+	l.size += int64(n)
 }
@@ -129,6 +129,81 @@ func TestStateChange(t *testing.T) {
 	}
 }
 
+func TestUserInput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "activity.log")
+	l := New(true, path, "agent", "sess")
+	defer l.Close()
+
+	l.UserInput("client 2", "normal", 11)
+
+	lines := readLines(t, path)
+	var e struct {
+		Event    string `json:"event"`
+		Client   string `json:"client"`
+		Priority string `json:"priority"`
+		Bytes    int    `json:"bytes"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if e.Event != "user_input" || e.Client != "client 2" || e.Priority != "normal" || e.Bytes != 11 {
+		t.Errorf("entry = %+v", e)
+	}
+}
+
+func TestCorrelationIDStampedUntilCleared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "activity.log")
+	l := New(true, path, "agent", "sess")
+	defer l.Close()
+
+	l.StateChange("idle", "active")
+	reset := l.SetCorrelationID("req-42")
+	l.MessageDelivered("msg-1", "alice", "normal")
+	l.HookEvent("sess", "PreToolUse", "Bash")
+	reset()
+	l.StateChange("active", "idle")
+
+	lines := readLines(t, path)
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got %d", len(lines))
+	}
+	want := []string{"", "req-42", "req-42", ""}
+	for i, line := range lines {
+		var e struct {
+			CorrelationID *string `json:"correlation_id"`
+		}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("unmarshal line %d: %v", i, err)
+		}
+		got := ""
+		if e.CorrelationID != nil {
+			got = *e.CorrelationID
+			if got == "" {
+				t.Errorf("line %d: empty correlation_id should be omitted", i)
+			}
+		}
+		if got != want[i] {
+			t.Errorf("line %d: correlation_id = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestCorrelationIDStaleResetKeepsNewerID(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "activity.log")
+	l := New(true, path, "agent", "sess")
+	defer l.Close()
+
+	resetFirst := l.SetCorrelationID("req-1")
+	l.SetCorrelationID("req-2")
+	resetFirst()
+	l.StateChange("idle", "active")
+
+	lines := readLines(t, path)
+	if len(lines) != 1 || !strings.Contains(lines[0], `"correlation_id":"req-2"`) {
+		t.Fatalf("a stale reset should not clear the newer ID, got %q", lines)
+	}
+}
+
 func TestDisabledLoggerIsNoop(t *testing.T) {
 	path := filepath.Join(t.TempDir(), "activity.log")
 	l := New(false, path, "agent", "sess")
@@ -0,0 +1,195 @@
+package activitylog
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Rotation controls when the activity log is rotated and how many rotated
+// files are kept. A zero MaxSize or MaxAge disables that trigger; a zero
+// MaxFiles keeps every rotated file.
+type Rotation struct {
+	MaxSize  int64         // rotate once the current file would exceed this many bytes
+	MaxAge   time.Duration // rotate once the current file has been open this long
+	MaxFiles int           // number of rotated (gzipped) files to keep
+}
+
+// DefaultRotation rotates at 10MB or daily and keeps the last 5 files.
+var DefaultRotation = Rotation{
+	MaxSize:  10 << 20,
+	MaxAge:   24 * time.Hour,
+	MaxFiles: 5,
+}
+
+// rotatedTimeFormat sorts lexically in chronological order.
+const rotatedTimeFormat = "20060102T150405.000000000"
+
+// needsRotateLocked reports whether writing n more bytes should first rotate
+// the current file. Caller must hold l.mu.
+func (l *Logger) needsRotateLocked(n int, now time.Time) bool {
+	if l.size == 0 {
+		return false
+	}
+	if l.rotation.MaxSize > 0 && l.size+int64(n) > l.rotation.MaxSize {
+		return true
+	}
+	return l.rotation.MaxAge > 0 && now.Sub(l.opened) >= l.rotation.MaxAge
+}
+
+// rotateLocked moves the current file to a timestamped sibling, gzips it,
+// reopens a fresh file at l.path, and prunes old rotations. Caller must
+// hold l.mu. If compression fails the rotated file is kept uncompressed;
+// if the move fails the logger keeps appending to the current file. Either
+// way the size and age count restart, so a failure is retried at the next
+// limit rather than on every write.
+func (l *Logger) rotateLocked(now time.Time) {
+	if err := l.w.Close(); err != nil {
+		return
+	}
+	rotated := l.path + "." + now.UTC().Format(rotatedTimeFormat)
+	if err := os.Rename(l.path, rotated); err == nil {
+		if err := compressRotated(rotated, rotated+".gz"); err == nil {
+			os.Remove(rotated)
+		}
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		l.w = nil
+		return
+	}
+	l.w = f
+	l.size = 0
+	l.opened = now
+	pruneRotated(l.path, l.rotation.MaxFiles)
+}
+
+// compressRotated gzips a rotated file. Var so tests can make it fail.
+var compressRotated = gzipFile
+
+// gzipFile compresses src into dst.
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
+// RotatedFiles returns the rotated files for logPath, oldest first. Most
+// are gzipped; one whose compression failed is kept as plain text.
+func RotatedFiles(logPath string) []string {
+	matches, _ := filepath.Glob(globEscape(logPath) + ".*")
+	var files []string
+	for _, m := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(m, logPath+"."), ".gz")
+		if _, err := time.Parse(rotatedTimeFormat, stamp); err == nil {
+			files = append(files, m)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// pruneRotated removes the oldest rotated files beyond keep.
+func pruneRotated(logPath string, keep int) {
+	if keep <= 0 {
+		return
+	}
+	files := RotatedFiles(logPath)
+	for len(files) > keep {
+		os.Remove(files[0])
+		files = files[1:]
+	}
+}
+
+// globEscape escapes glob metacharacters in a literal path.
+func globEscape(p string) string {
+	var out []rune
+	for _, r := range p {
+		switch r {
+		case '*', '?', '[', '\\':
+			out = append(out, '\\')
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// Open returns a reader over the full activity log for logPath: every
+// rotated file (decompressed if gzipped, oldest first) followed by the
+// current file.
+// Returns an error satisfying os.IsNotExist if no log exists at all.
+func Open(logPath string) (io.ReadCloser, error) {
+	var readers []io.Reader
+	var closers []io.Closer
+	closeAll := func() {
+		for _, c := range closers {
+			c.Close()
+		}
+	}
+
+	for _, path := range RotatedFiles(logPath) {
+		f, err := os.Open(path)
+		if err != nil {
+			continue // pruned between glob and open
+		}
+		if !strings.HasSuffix(path, ".gz") {
+			readers = append(readers, f)
+			closers = append(closers, f)
+			continue
+		}
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			continue
+		}
+		readers = append(readers, zr)
+		closers = append(closers, zr, f)
+	}
+
+	f, err := os.Open(logPath)
+	if err != nil {
+		if !os.IsNotExist(err) || len(readers) == 0 {
+			closeAll()
+			return nil, err
+		}
+	} else {
+		readers = append(readers, f)
+		closers = append(closers, f)
+	}
+
+	return &multiReadCloser{Reader: io.MultiReader(readers...), closeAll: closeAll}, nil
+}
+
+type multiReadCloser struct {
+	io.Reader
+	closeAll func()
+}
+
+func (m *multiReadCloser) Close() error {
+	m.closeAll()
+	return nil
+}
@@ -0,0 +1,172 @@
+package activitylog
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readAll returns the state_change "to" fields across all rotated files and
+// the current log, in order.
+func readAll(t *testing.T, path string) []string {
+	t.Helper()
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer r.Close()
+
+	var got []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		var e struct {
+			To string `json:"to"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("unmarshal %q: %v", scanner.Text(), err)
+		}
+		got = append(got, e.To)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	return got
+}
+
+func TestRotation_SizeCapRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session-activity.jsonl")
+	l := NewWithRotation(true, path, "agent", "sess", Rotation{MaxSize: 300})
+	defer l.Close()
+
+	for i := 0; i < 10; i++ {
+		l.StateChange("idle", "active")
+	}
+
+	rotated := RotatedFiles(path)
+	if len(rotated) == 0 {
+		t.Fatal("expected at least one rotated file")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("stat current log: %v", err)
+	}
+	if info.Size() > 300 {
+		t.Errorf("current log size = %d, want <= 300", info.Size())
+	}
+
+	// Rotated files are valid gzip.
+	f, err := os.Open(rotated[0])
+	if err != nil {
+		t.Fatalf("open rotated: %v", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	if _, err := io.ReadAll(zr); err != nil {
+		t.Fatalf("decompress: %v", err)
+	}
+}
+
+func TestRotation_AgeRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session-activity.jsonl")
+	l := NewWithRotation(true, path, "agent", "sess", Rotation{MaxAge: time.Hour})
+	defer l.Close()
+
+	l.StateChange("idle", "active")
+	l.mu.Lock()
+	l.opened = l.opened.Add(-2 * time.Hour)
+	l.mu.Unlock()
+	l.StateChange("active", "idle")
+
+	if got := len(RotatedFiles(path)); got != 1 {
+		t.Fatalf("rotated files = %d, want 1", got)
+	}
+	if lines := readLines(t, path); len(lines) != 1 {
+		t.Fatalf("current log lines = %d, want 1", len(lines))
+	}
+}
+
+func TestRotation_PrunesToMaxFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session-activity.jsonl")
+	// Every entry exceeds the cap, so each write after the first rotates.
+	l := NewWithRotation(true, path, "agent", "sess", Rotation{MaxSize: 1, MaxFiles: 2})
+	defer l.Close()
+
+	for i := 0; i < 6; i++ {
+		l.StateChange("idle", "active")
+	}
+
+	if got := len(RotatedFiles(path)); got != 2 {
+		t.Fatalf("rotated files = %d, want 2", got)
+	}
+}
+
+func TestOpen_SpansRotations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session-activity.jsonl")
+	l := NewWithRotation(true, path, "agent", "sess", Rotation{MaxSize: 1})
+	defer l.Close()
+
+	want := []string{"s0", "s1", "s2", "s3"}
+	for _, to := range want {
+		l.StateChange("x", to)
+	}
+	if got := len(RotatedFiles(path)); got != 3 {
+		t.Fatalf("rotated files = %d, want 3", got)
+	}
+
+	got := readAll(t, path)
+	if len(got) != len(want) {
+		t.Fatalf("entries = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("entries = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestRotation_CompressFailureKeepsPlainFile(t *testing.T) {
+	old := compressRotated
+	compressRotated = func(src, dst string) error { return errors.New("disk full") }
+	defer func() { compressRotated = old }()
+
+	path := filepath.Join(t.TempDir(), "session-activity.jsonl")
+	l := NewWithRotation(true, path, "agent", "sess", Rotation{MaxSize: 300})
+	defer l.Close()
+
+	want := make([]string, 10)
+	for i := range want {
+		want[i] = fmt.Sprintf("s%d", i)
+		l.StateChange("x", want[i])
+	}
+
+	rotated := RotatedFiles(path)
+	if len(rotated) == 0 || len(rotated) >= len(want)-1 {
+		t.Fatalf("rotated files = %d, want a rotation per 300 bytes, not per write", len(rotated))
+	}
+	for _, f := range rotated {
+		if strings.HasSuffix(f, ".gz") {
+			t.Errorf("rotated file %s is gzipped despite the failure", f)
+		}
+	}
+	if got := readAll(t, path); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+}
+
+func TestOpen_Missing(t *testing.T) {
+	_, err := Open(filepath.Join(t.TempDir(), "nope.jsonl"))
+	if !os.IsNotExist(err) {
+		t.Fatalf("expected not-exist error, got %v", err)
+	}
+}
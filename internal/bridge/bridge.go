@@ -33,6 +33,17 @@ type TypingIndicator interface {
 	SendTyping(ctx context.Context) error
 }
 
+// StreamSender is the capability interface for bridges that can update a
+// message in place as output accumulates, instead of posting a new one per
+// update. text is the full text so far for the stream identified by key.
+// StreamingEnabled reports whether the bridge is configured to stream;
+// otherwise callers use Send.
+type StreamSender interface {
+	StreamingEnabled() bool
+	SendStreaming(ctx context.Context, key, text string) error
+	EndStreaming(ctx context.Context, key string) error
+}
+
 var agentTagRe = regexp.MustCompile(`^\[([a-zA-Z0-9_-]+)\]\s*`)
 
 // ParseAgentTag extracts an "[agent-name]" tag from the start of text.
@@ -0,0 +1,240 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"h2/internal/bridge"
+)
+
+// streamEditInterval is the minimum gap between edits of one streaming
+// message, to stay under Telegram's per-chat rate limits. Var so tests can
+// override it.
+var streamEditInterval = 1 * time.Second
+
+// stream tracks one in-place message for SendStreaming.
+type stream struct {
+	messageID int64       // current Telegram message (0 = not sent yet)
+	offset    int         // start of the current message within the stream text
+	shown     string      // text currently displayed in the message
+	pending   string      // latest text not yet shown (rate limited)
+	ended     bool        // EndStreaming was called; flush without waiting for the edit interval
+	lastEdit  time.Time   // when the message was last sent or edited
+	notBefore time.Time   // retry_after from a 429 response
+	flush     *time.Timer // shows pending once the rate limit allows
+}
+
+// StreamingEnabled reports whether outbound messages should be streamed
+// into one message per agent instead of sent one by one.
+func (t *Telegram) StreamingEnabled() bool { return t.Streaming }
+
+// SendStreaming shows text, the full output so far for key, as a single
+// message: the first call sends it and later calls edit it in place. Edits
+// closer together than streamEditInterval, or inside a 429's retry_after,
+// are deferred and shown by a timer once allowed. When the text outgrows
+// Telegram's 4096-character cap, the full part is left as its own message
+// and the rest rolls into a new one.
+func (t *Telegram) SendStreaming(ctx context.Context, key, text string) error {
+	t.streamMu.Lock()
+	defer t.streamMu.Unlock()
+
+	if t.streams == nil {
+		t.streams = make(map[string]*stream)
+	}
+	st := t.streams[key]
+	if st == nil {
+		st = &stream{}
+		t.streams[key] = st
+	}
+	if len(text) < st.offset {
+		// The caller restarted the stream with shorter text.
+		st.stopFlush()
+		*st = stream{notBefore: st.notBefore}
+	}
+	return t.updateStream(ctx, st, text)
+}
+
+// EndStreaming forgets the stream for key, so the next SendStreaming with
+// the same key starts a new message. Deferred text is shown now, or once a
+// pending retry_after has passed.
+func (t *Telegram) EndStreaming(ctx context.Context, key string) error {
+	t.streamMu.Lock()
+	defer t.streamMu.Unlock()
+
+	st := t.streams[key]
+	if st == nil {
+		return nil
+	}
+	delete(t.streams, key)
+	st.ended = true
+	if st.pending == "" {
+		st.stopFlush()
+		return nil
+	}
+	return t.updateStream(ctx, st, st.pending)
+}
+
+// stopStreams cancels the flush timers of all open streams.
+func (t *Telegram) stopStreams() {
+	t.streamMu.Lock()
+	defer t.streamMu.Unlock()
+	for _, st := range t.streams {
+		st.stopFlush()
+	}
+}
+
+// nextWrite returns the earliest time the stream may be sent or edited.
+func (st *stream) nextWrite() time.Time {
+	at := st.notBefore
+	if st.messageID != 0 && !st.ended {
+		if next := st.lastEdit.Add(streamEditInterval); next.After(at) {
+			at = next
+		}
+	}
+	return at
+}
+
+func (st *stream) stopFlush() {
+	if st.flush != nil {
+		st.flush.Stop()
+		st.flush = nil
+	}
+}
+
+// updateStream brings the stream's messages up to date with text, or
+// schedules a flush if the rate limit doesn't allow it yet. Caller must
+// hold t.streamMu.
+func (t *Telegram) updateStream(ctx context.Context, st *stream, text string) error {
+	if st.messageID != 0 && text[st.offset:] == st.shown {
+		st.pending = ""
+		return nil
+	}
+	st.pending = text
+	if at := st.nextWrite(); time.Now().Before(at) {
+		t.scheduleFlush(st, at)
+		return nil
+	}
+
+	page := text[st.offset:]
+	for len(page) > maxMessageLen {
+		full := bridge.SplitMessage(page, maxMessageLen, 0)[0]
+		if err := t.showStream(ctx, st, full); err != nil {
+			t.retryLater(st)
+			return err
+		}
+		st.offset += len(full)
+		st.messageID, st.shown = 0, ""
+		page = text[st.offset:]
+	}
+	if err := t.showStream(ctx, st, page); err != nil {
+		t.retryLater(st)
+		return err
+	}
+	st.pending = ""
+	return nil
+}
+
+// retryLater schedules a flush of the stream's pending text when a failed
+// write left a retry_after to wait out. Other failures are retried by the
+// next update.
+func (t *Telegram) retryLater(st *stream) {
+	if time.Now().Before(st.notBefore) {
+		t.scheduleFlush(st, st.notBefore)
+	}
+}
+
+// scheduleFlush arranges for the stream's pending text to be shown at at.
+// Caller must hold t.streamMu.
+func (t *Telegram) scheduleFlush(st *stream, at time.Time) {
+	st.stopFlush()
+	st.flush = time.AfterFunc(time.Until(at), func() {
+		t.streamMu.Lock()
+		defer t.streamMu.Unlock()
+		st.flush = nil
+		if st.pending == "" {
+			return
+		}
+		if err := t.updateStream(context.Background(), st, st.pending); err != nil {
+			log.Printf("telegram: flush streaming message: %v", err)
+		}
+	})
+}
+
+// showStream sends or edits the stream's current message to display text.
+func (t *Telegram) showStream(ctx context.Context, st *stream, text string) error {
+	if text == st.shown && st.messageID != 0 {
+		return nil
+	}
+	if st.messageID == 0 {
+		id, err := t.sendMessageID(ctx, st, text)
+		if err != nil {
+			return err
+		}
+		st.messageID = id
+	} else if err := t.editMessage(ctx, st, text); err != nil {
+		return err
+	}
+	st.shown = text
+	st.lastEdit = time.Now()
+	return nil
+}
+
+// sendMessageID posts text and returns the new message's ID. Like
+// editMessage, a 429 response records retry_after on st.
+func (t *Telegram) sendMessageID(ctx context.Context, st *stream, text string) (int64, error) {
+	resp, err := t.client.PostForm(t.apiURL("sendMessage"), url.Values{
+		"chat_id": {strconv.FormatInt(t.ChatID, 10)},
+		"text":    {text},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("telegram send: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result sendMessageResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, fmt.Errorf("telegram send: decode response: %w", err)
+	}
+	if !result.OK {
+		st.recordRetryAfter(result.Parameters)
+		return 0, fmt.Errorf("telegram send: API error: %s", result.Description)
+	}
+	return result.Result.MessageID, nil
+}
+
+// editMessage replaces the text of the stream's current message. A 429
+// response records retry_after so the stream waits before editing again.
+func (t *Telegram) editMessage(ctx context.Context, st *stream, text string) error {
+	resp, err := t.client.PostForm(t.apiURL("editMessageText"), url.Values{
+		"chat_id":    {strconv.FormatInt(t.ChatID, 10)},
+		"message_id": {strconv.FormatInt(st.messageID, 10)},
+		"text":       {text},
+	})
+	if err != nil {
+		return fmt.Errorf("telegram editMessageText: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result apiResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("telegram editMessageText: decode response: %w", err)
+	}
+	if !result.OK {
+		st.recordRetryAfter(result.Parameters)
+		return fmt.Errorf("telegram editMessageText: API error: %s", result.Description)
+	}
+	return nil
+}
+
+// recordRetryAfter holds off further writes to the stream for the
+// retry_after of a 429 response, if p carries one.
+func (st *stream) recordRetryAfter(p *responseParameters) {
+	if p != nil && p.RetryAfter > 0 {
+		st.notBefore = time.Now().Add(time.Duration(p.RetryAfter) * time.Second)
+	}
+}
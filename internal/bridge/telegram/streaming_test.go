@@ -0,0 +1,307 @@
+package telegram
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"h2/internal/bridge"
+)
+
+func TestStreamSenderInterface(t *testing.T) {
+	var _ bridge.StreamSender = &Telegram{}
+}
+
+// apiCall records one request to the fake Telegram API.
+type apiCall struct {
+	method    string
+	messageID string
+	text      string
+}
+
+// newStreamServer returns a fake API that assigns increasing message IDs
+// to sendMessage calls and records every call.
+func newStreamServer(t *testing.T) (*httptest.Server, func() []apiCall) {
+	t.Helper()
+	var mu sync.Mutex
+	var calls []apiCall
+	var nextID int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		mu.Lock()
+		calls = append(calls, apiCall{method: method, messageID: r.FormValue("message_id"), text: r.FormValue("text")})
+		mu.Unlock()
+		switch method {
+		case "sendMessage":
+			mu.Lock()
+			nextID++
+			id := nextID
+			mu.Unlock()
+			json.NewEncoder(w).Encode(sendMessageResponse{OK: true, Result: sentMessage{MessageID: id}})
+		case "editMessageText":
+			json.NewEncoder(w).Encode(apiResponse{OK: true})
+		default:
+			t.Errorf("unexpected method %s", method)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []apiCall {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]apiCall(nil), calls...)
+	}
+}
+
+func setStreamEditInterval(t *testing.T, d time.Duration) {
+	t.Helper()
+	old := streamEditInterval
+	streamEditInterval = d
+	t.Cleanup(func() { streamEditInterval = old })
+}
+
+func TestSendStreaming_SendsThenEdits(t *testing.T) {
+	setStreamEditInterval(t, 0)
+	srv, calls := newStreamServer(t)
+	tg := &Telegram{Token: "TOKEN", ChatID: 42, BaseURL: srv.URL}
+	ctx := context.Background()
+
+	for _, text := range []string{"line 1", "line 1\nline 2", "line 1\nline 2\nline 3"} {
+		if err := tg.SendStreaming(ctx, "coder", text); err != nil {
+			t.Fatalf("SendStreaming: %v", err)
+		}
+	}
+
+	got := calls()
+	if len(got) != 3 {
+		t.Fatalf("expected 3 calls, got %d: %+v", len(got), got)
+	}
+	if got[0].method != "sendMessage" || got[0].text != "line 1" {
+		t.Errorf("first call = %+v, want sendMessage", got[0])
+	}
+	for _, c := range got[1:] {
+		if c.method != "editMessageText" || c.messageID != "1" {
+			t.Errorf("later call = %+v, want editMessageText of message 1", c)
+		}
+	}
+	if got[2].text != "line 1\nline 2\nline 3" {
+		t.Errorf("last edit text = %q", got[2].text)
+	}
+}
+
+func TestSendStreaming_UnchangedTextNotEdited(t *testing.T) {
+	setStreamEditInterval(t, 0)
+	srv, calls := newStreamServer(t)
+	tg := &Telegram{Token: "TOKEN", ChatID: 42, BaseURL: srv.URL}
+	ctx := context.Background()
+
+	tg.SendStreaming(ctx, "coder", "same")
+	tg.SendStreaming(ctx, "coder", "same")
+	if got := calls(); len(got) != 1 {
+		t.Fatalf("expected only the initial send, got %+v", got)
+	}
+}
+
+func TestSendStreaming_RateLimitedEditsFlushedOnEnd(t *testing.T) {
+	setStreamEditInterval(t, time.Hour)
+	srv, calls := newStreamServer(t)
+	tg := &Telegram{Token: "TOKEN", ChatID: 42, BaseURL: srv.URL}
+	ctx := context.Background()
+
+	tg.SendStreaming(ctx, "coder", "a")
+	tg.SendStreaming(ctx, "coder", "ab")
+	tg.SendStreaming(ctx, "coder", "abc")
+	if got := calls(); len(got) != 1 {
+		t.Fatalf("edits inside the interval should be deferred, got %+v", got)
+	}
+
+	if err := tg.EndStreaming(ctx, "coder"); err != nil {
+		t.Fatalf("EndStreaming: %v", err)
+	}
+	got := calls()
+	if len(got) != 2 || got[1].method != "editMessageText" || got[1].text != "abc" {
+		t.Fatalf("expected one flushing edit with the latest text, got %+v", got)
+	}
+
+	// The key is forgotten: the next update starts a new message.
+	tg.SendStreaming(ctx, "coder", "next")
+	got = calls()
+	if last := got[len(got)-1]; last.method != "sendMessage" || last.text != "next" {
+		t.Errorf("after EndStreaming, call = %+v, want a new sendMessage", last)
+	}
+}
+
+func TestSendStreaming_DeferredEditFlushedByTimer(t *testing.T) {
+	setStreamEditInterval(t, 50*time.Millisecond)
+	srv, calls := newStreamServer(t)
+	tg := &Telegram{Token: "TOKEN", ChatID: 42, BaseURL: srv.URL}
+	defer tg.Close()
+	ctx := context.Background()
+
+	tg.SendStreaming(ctx, "coder", "a")
+	tg.SendStreaming(ctx, "coder", "ab")
+	if got := calls(); len(got) != 1 {
+		t.Fatalf("edit inside the interval should be deferred, got %+v", got)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(calls()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	got := calls()
+	if len(got) != 2 || got[1].method != "editMessageText" || got[1].text != "ab" {
+		t.Fatalf("expected the deferred edit without another update, got %+v", got)
+	}
+}
+
+func TestSendStreaming_RollsOverAtMessageCap(t *testing.T) {
+	setStreamEditInterval(t, 0)
+	srv, calls := newStreamServer(t)
+	tg := &Telegram{Token: "TOKEN", ChatID: 42, BaseURL: srv.URL}
+	ctx := context.Background()
+
+	line := strings.Repeat("x", 99) + "\n"
+	first := strings.Repeat(line, 30) // 3000 chars
+	if err := tg.SendStreaming(ctx, "coder", first); err != nil {
+		t.Fatalf("SendStreaming: %v", err)
+	}
+	full := strings.Repeat(line, 50) // 5000 chars
+	if err := tg.SendStreaming(ctx, "coder", full); err != nil {
+		t.Fatalf("SendStreaming: %v", err)
+	}
+
+	got := calls()
+	if len(got) != 3 {
+		t.Fatalf("expected send, edit, send; got %d calls", len(got))
+	}
+	if got[1].method != "editMessageText" || got[1].messageID != "1" || len(got[1].text) > maxMessageLen {
+		t.Errorf("second call should fill message 1 up to the cap, got %s (%d chars)", got[1].method, len(got[1].text))
+	}
+	if got[2].method != "sendMessage" {
+		t.Errorf("overflow should roll to a new message, got %s", got[2].method)
+	}
+	if got[1].text+got[2].text != full {
+		t.Error("the two messages should together hold the full text")
+	}
+
+	// Further growth edits the new message, not the full one.
+	tg.SendStreaming(ctx, "coder", full+"tail")
+	got = calls()
+	if last := got[len(got)-1]; last.method != "editMessageText" || last.messageID != "2" {
+		t.Errorf("growth after rollover = %+v, want edit of message 2", last)
+	}
+}
+
+func TestSendStreaming_KeysAreIndependent(t *testing.T) {
+	setStreamEditInterval(t, 0)
+	srv, calls := newStreamServer(t)
+	tg := &Telegram{Token: "TOKEN", ChatID: 42, BaseURL: srv.URL}
+	ctx := context.Background()
+
+	tg.SendStreaming(ctx, "coder", "a")
+	tg.SendStreaming(ctx, "reviewer", "b")
+	tg.SendStreaming(ctx, "coder", "a2")
+
+	got := calls()
+	if len(got) != 3 || got[0].method != "sendMessage" || got[1].method != "sendMessage" {
+		t.Fatalf("each key should get its own message, got %+v", got)
+	}
+	if got[2].method != "editMessageText" || got[2].messageID != "1" {
+		t.Errorf("coder update = %+v, want edit of message 1", got[2])
+	}
+}
+
+func TestSendStreaming_RetryAfterDefersEdits(t *testing.T) {
+	setStreamEditInterval(t, 0)
+	var mu sync.Mutex
+	var edits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/sendMessage") {
+			json.NewEncoder(w).Encode(sendMessageResponse{OK: true, Result: sentMessage{MessageID: 7}})
+			return
+		}
+		mu.Lock()
+		edits++
+		mu.Unlock()
+		json.NewEncoder(w).Encode(apiResponse{
+			OK:          false,
+			Description: "Too Many Requests: retry after 30",
+			Parameters:  &responseParameters{RetryAfter: 30},
+		})
+	}))
+	defer srv.Close()
+	tg := &Telegram{Token: "TOKEN", ChatID: 42, BaseURL: srv.URL}
+	ctx := context.Background()
+
+	tg.SendStreaming(ctx, "coder", "a")
+	if err := tg.SendStreaming(ctx, "coder", "ab"); err == nil {
+		t.Fatal("expected the 429 to be reported")
+	}
+	if err := tg.SendStreaming(ctx, "coder", "abc"); err != nil {
+		t.Fatalf("update during retry_after should be deferred, got %v", err)
+	}
+	if err := tg.EndStreaming(ctx, "coder"); err != nil {
+		t.Fatalf("EndStreaming during retry_after should be deferred, got %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if edits != 1 {
+		t.Errorf("edits = %d, want 1 (no edits during retry_after)", edits)
+	}
+}
+
+func TestSendStreaming_RetryAfterOnSendIsHonoured(t *testing.T) {
+	setStreamEditInterval(t, 0)
+	var mu sync.Mutex
+	var sends int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		sends++
+		mu.Unlock()
+		json.NewEncoder(w).Encode(sendMessageResponse{
+			OK:          false,
+			Description: "Too Many Requests: retry after 30",
+			Parameters:  &responseParameters{RetryAfter: 30},
+		})
+	}))
+	defer srv.Close()
+	tg := &Telegram{Token: "TOKEN", ChatID: 42, BaseURL: srv.URL}
+	defer tg.Close()
+	ctx := context.Background()
+
+	if err := tg.SendStreaming(ctx, "coder", "a"); err == nil {
+		t.Fatal("expected the 429 to be reported")
+	}
+	tg.SendStreaming(ctx, "coder", "ab")
+	mu.Lock()
+	defer mu.Unlock()
+	if sends != 1 {
+		t.Errorf("sends = %d, want 1 (no sends during retry_after)", sends)
+	}
+}
+
+func TestSendStreaming_RolloverKeepsRetryAfter(t *testing.T) {
+	setStreamEditInterval(t, 0)
+	srv, calls := newStreamServer(t)
+	tg := &Telegram{Token: "TOKEN", ChatID: 42, BaseURL: srv.URL}
+	defer tg.Close()
+	ctx := context.Background()
+
+	line := strings.Repeat("x", 99) + "\n"
+	full := strings.Repeat(line, 50) // rolls into a second message
+	tg.SendStreaming(ctx, "coder", full)
+	n := len(calls())
+
+	tg.streamMu.Lock()
+	tg.streams["coder"].notBefore = time.Now().Add(time.Hour)
+	tg.streamMu.Unlock()
+	tg.SendStreaming(ctx, "coder", full+strings.Repeat(line, 50))
+	if got := calls(); len(got) != n {
+		t.Errorf("writes during retry_after: %+v", got[n:])
+	}
+}
@@ -36,6 +36,10 @@ type Telegram struct {
 	ChatID          int64
 	AllowedCommands []string
 
+	// Streaming edits one message per agent in place as its output
+	// accumulates (see SendStreaming) instead of posting every message.
+	Streaming bool
+
 	// BaseURL overrides the Telegram API base for testing.
 	// If empty, defaults to "https://api.telegram.org".
 	BaseURL string
@@ -45,12 +49,17 @@ type Telegram struct {
 	wg     sync.WaitGroup
 	mu     sync.Mutex
 	offset int64
+
+	// streamMu guards streams, the in-place messages of SendStreaming.
+	streamMu sync.Mutex
+	streams  map[string]*stream
 }
 
 func (t *Telegram) Name() string { return "telegram" }
 
 func (t *Telegram) Close() error {
 	t.Stop()
+	t.stopStreams()
 	return nil
 }
 
@@ -231,11 +240,57 @@ func (t *Telegram) SendTyping(ctx context.Context) error {
 	return nil
 }
 
+// BotInfo identifies the bot a token belongs to.
+type BotInfo struct {
+	ID        int64  `json:"id"`
+	Username  string `json:"username"`
+	FirstName string `json:"first_name"`
+}
+
+// GetMe validates the token by fetching the bot's identity. It does not
+// poll, so it is safe to call while a bridge is running.
+func (t *Telegram) GetMe(ctx context.Context) (*BotInfo, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", t.apiURL("getMe"), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("telegram getMe: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var result getMeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("telegram getMe: decode response: %w", err)
+	}
+	if !result.OK {
+		return nil, fmt.Errorf("telegram getMe: API error: %s", result.Description)
+	}
+	return &result.Result, nil
+}
+
 // Unexported types for JSON parsing.
 
 type apiResponse struct {
-	OK          bool   `json:"ok"`
-	Description string `json:"description,omitempty"`
+	OK          bool                `json:"ok"`
+	Description string              `json:"description,omitempty"`
+	Parameters  *responseParameters `json:"parameters,omitempty"`
+}
+
+type responseParameters struct {
+	RetryAfter int `json:"retry_after,omitempty"`
+}
+
+type sendMessageResponse struct {
+	OK          bool                `json:"ok"`
+	Description string              `json:"description,omitempty"`
+	Parameters  *responseParameters `json:"parameters,omitempty"`
+	Result      sentMessage         `json:"result"`
+}
+
+type sentMessage struct {
+	MessageID int64 `json:"message_id"`
 }
 
 type getUpdatesResponse struct {
@@ -244,6 +299,12 @@ type getUpdatesResponse struct {
 	Result      []update `json:"result"`
 }
 
+type getMeResponse struct {
+	OK          bool    `json:"ok"`
+	Description string  `json:"description,omitempty"`
+	Result      BotInfo `json:"result"`
+}
+
 type update struct {
 	UpdateID int64    `json:"update_id"`
 	Message  *message `json:"message,omitempty"`
@@ -836,3 +836,39 @@ func TestPoll_BackoffResetsOnSuccess(t *testing.T) {
 		t.Errorf("backoff did not reset after success: gap between call 4 and 5 was %v, expected ~1ms", gap)
 	}
 }
+
+func TestGetMe(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/botTOKEN/getMe" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"ok":true,"result":{"id":123,"is_bot":true,"first_name":"H2","username":"h2_bot"}}`))
+	}))
+	defer srv.Close()
+
+	tg := &Telegram{Token: "TOKEN", BaseURL: srv.URL}
+	bot, err := tg.GetMe(context.Background())
+	if err != nil {
+		t.Fatalf("GetMe: %v", err)
+	}
+	if bot.ID != 123 || bot.Username != "h2_bot" || bot.FirstName != "H2" {
+		t.Errorf("bot = %+v", bot)
+	}
+}
+
+func TestGetMe_InvalidToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"ok":false,"error_code":401,"description":"Unauthorized"}`))
+	}))
+	defer srv.Close()
+
+	tg := &Telegram{Token: "BAD", BaseURL: srv.URL}
+	_, err := tg.GetMe(context.Background())
+	if err == nil {
+		t.Fatal("expected error for invalid token")
+	}
+	if got := err.Error(); got != "telegram getMe: API error: Unauthorized" {
+		t.Errorf("error = %q", got)
+	}
+}
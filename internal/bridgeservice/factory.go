@@ -15,6 +15,7 @@ func FromConfig(cfg *config.BridgesConfig) []bridge.Bridge {
 			Token:           cfg.Telegram.BotToken,
 			ChatID:          cfg.Telegram.ChatID,
 			AllowedCommands: cfg.Telegram.AllowedCommands,
+			Streaming:       cfg.Telegram.Streaming,
 		})
 	}
 	if cfg.MacOSNotify != nil && cfg.MacOSNotify.Enabled {
@@ -14,7 +14,7 @@ import (
 // ForkBridge starts the bridge service as a background daemon process.
 // It re-execs with the hidden _bridge-service subcommand and waits for
 // the bridge socket to appear.
-func ForkBridge(user, concierge string) error {
+func ForkBridge(user, concierge, defaultAgent string) error {
 	exePath, err := os.Executable()
 	if err != nil {
 		return fmt.Errorf("find executable: %w", err)
@@ -24,6 +24,9 @@ func ForkBridge(user, concierge string) error {
 	if concierge != "" {
 		args = append(args, "--concierge", concierge)
 	}
+	if defaultAgent != "" {
+		args = append(args, "--default-agent", defaultAgent)
+	}
 
 	cmd := exec.Command(exePath, args...)
 	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
@@ -26,6 +26,14 @@ type Service struct {
 	lastSender string // tracks last agent who sent outbound
 	cancel     context.CancelFunc
 
+	// streamed holds, per agent, the text streamed to StreamSender bridges
+	// since the agent last went idle.
+	streamed map[string]string
+
+	// DefaultAgent, if set, receives inbound messages that carry no agent
+	// prefix or reply tag. Set before Run.
+	DefaultAgent string
+
 	// Status tracking.
 	startTime        time.Time
 	lastActivityTime time.Time
@@ -50,7 +58,7 @@ func New(bridges []bridge.Bridge, concierge, socketDir, user string) *Service {
 // It blocks until ctx is cancelled.
 func (s *Service) Run(ctx context.Context) error {
 	ctx, s.cancel = context.WithCancel(ctx)
-	log.Printf("bridge: starting for user %q, concierge=%q, %d bridges", s.user, s.concierge, len(s.bridges))
+	log.Printf("bridge: starting for user %q, concierge=%q, default agent=%q, %d bridges", s.user, s.concierge, s.DefaultAgent, len(s.bridges))
 	for _, b := range s.bridges {
 		log.Printf("bridge: loaded %s", b.Name())
 	}
@@ -90,6 +98,7 @@ func (s *Service) Run(ctx context.Context) error {
 
 	// Start typing indicator loop.
 	go s.runTypingLoop(ctx)
+	go s.runStreamLoop(ctx)
 
 	// Block until context is done.
 	<-ctx.Done()
@@ -203,12 +212,20 @@ func (s *Service) handleOutbound(from, body string) error {
 
 	ctx := context.Background()
 	var errs []string
+	var streamText string
 	for _, b := range s.bridges {
-		if sender, ok := b.(bridge.Sender); ok {
-			if err := sender.Send(ctx, tagged); err != nil {
-				log.Printf("bridge: send via %s: %v", b.Name(), err)
-				errs = append(errs, fmt.Sprintf("%s: %v", b.Name(), err))
+		var err error
+		if ss, ok := b.(bridge.StreamSender); ok && ss.StreamingEnabled() {
+			if streamText == "" {
+				streamText = s.appendStream(from, tagged, body)
 			}
+			err = ss.SendStreaming(ctx, from, streamText)
+		} else if sender, ok := b.(bridge.Sender); ok {
+			err = sender.Send(ctx, tagged)
+		}
+		if err != nil {
+			log.Printf("bridge: send via %s: %v", b.Name(), err)
+			errs = append(errs, fmt.Sprintf("%s: %v", b.Name(), err))
 		}
 	}
 	if len(errs) > 0 {
@@ -217,6 +234,65 @@ func (s *Service) handleOutbound(from, body string) error {
 	return nil
 }
 
+// appendStream adds an outbound message to from's stream and returns the
+// stream's full text. The first message carries the agent tag; later ones
+// are appended as they are, since the tag already leads the message.
+func (s *Service) appendStream(from, tagged, body string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.streamed == nil {
+		s.streamed = make(map[string]string)
+	}
+	text := tagged
+	if prev, ok := s.streamed[from]; ok {
+		text = prev + "\n\n" + body
+	}
+	s.streamed[from] = text
+	return text
+}
+
+// runStreamLoop ends each agent's stream once the agent is no longer
+// active, so its next burst of output starts a new message.
+func (s *Service) runStreamLoop(ctx context.Context) {
+	ticker := time.NewTicker(typingTickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.endIdleStreams(ctx)
+		}
+	}
+}
+
+// endIdleStreams ends the streams of agents that are idle or gone.
+func (s *Service) endIdleStreams(ctx context.Context) {
+	s.mu.Lock()
+	var agents []string
+	for agent := range s.streamed {
+		agents = append(agents, agent)
+	}
+	s.mu.Unlock()
+
+	for _, agent := range agents {
+		if state, err := s.queryAgentState(agent); err == nil && state == "active" {
+			continue
+		}
+		s.mu.Lock()
+		delete(s.streamed, agent)
+		s.mu.Unlock()
+		for _, b := range s.bridges {
+			if ss, ok := b.(bridge.StreamSender); ok && ss.StreamingEnabled() {
+				if err := ss.EndStreaming(ctx, agent); err != nil {
+					log.Printf("bridge: end stream via %s: %v", b.Name(), err)
+				}
+			}
+		}
+	}
+}
+
 // sendToAgent connects to an agent's socket and sends a message.
 func (s *Service) sendToAgent(name, from, body string) error {
 	sockPath := filepath.Join(s.socketDir, socketdir.Format(socketdir.TypeAgent, name))
@@ -336,7 +412,11 @@ func (s *Service) buildBridgeInfo() *message.BridgeInfo {
 }
 
 // resolveDefaultTarget returns the agent to route un-addressed inbound messages to.
+// Order: configured default agent, concierge, last outbound sender, first agent.
 func (s *Service) resolveDefaultTarget() string {
+	if s.DefaultAgent != "" {
+		return s.DefaultAgent
+	}
 	if s.concierge != "" {
 		return s.concierge
 	}
@@ -61,6 +61,44 @@ func (m *mockTypingBridge) TypingCalls() int {
 	return m.typingCalls
 }
 
+// mockStreamBridge implements Bridge, Sender, and StreamSender, recording
+// streamed text and ended keys.
+type mockStreamBridge struct {
+	name      string
+	streaming bool
+	sent      []string
+	streamed  []string
+	ended     []string
+	mu        sync.Mutex
+}
+
+func (m *mockStreamBridge) Name() string           { return m.name }
+func (m *mockStreamBridge) Close() error           { return nil }
+func (m *mockStreamBridge) StreamingEnabled() bool { return m.streaming }
+func (m *mockStreamBridge) Send(_ context.Context, text string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, text)
+	return nil
+}
+func (m *mockStreamBridge) SendStreaming(_ context.Context, key, text string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.streamed = append(m.streamed, key+": "+text)
+	return nil
+}
+func (m *mockStreamBridge) EndStreaming(_ context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ended = append(m.ended, key)
+	return nil
+}
+func (m *mockStreamBridge) Calls() (sent, streamed, ended []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.sent...), append([]string(nil), m.streamed...), append([]string(nil), m.ended...)
+}
+
 // mockReceiver exposes its handler so tests can simulate inbound messages.
 type mockReceiver struct {
 	name    string
@@ -301,6 +339,42 @@ func TestHandleInbound_UnaddressedNoConciergeFirstAgent(t *testing.T) {
 	}
 }
 
+func TestHandleInbound_UnaddressedGoesToDefaultAgent(t *testing.T) {
+	tmpDir := shortTempDir(t)
+	concierge := newMockAgent(t, tmpDir, "concierge")
+	reviewer := newMockAgent(t, tmpDir, "reviewer")
+	svc := New(nil, "concierge", tmpDir, "alice")
+	svc.DefaultAgent = "reviewer"
+	svc.lastSender = "concierge"
+
+	svc.handleInbound("", "please review")
+
+	if reqs := reviewer.Received(); len(reqs) != 1 || reqs[0].Body != "please review" {
+		t.Fatalf("expected unaddressed message at default agent, got %+v", reqs)
+	}
+	if reqs := concierge.Received(); len(reqs) != 0 {
+		t.Fatalf("concierge should not receive unaddressed message when a default agent is set, got %d", len(reqs))
+	}
+}
+
+func TestHandleInbound_PrefixOverridesDefaultAgent(t *testing.T) {
+	tmpDir := shortTempDir(t)
+	reviewer := newMockAgent(t, tmpDir, "reviewer")
+	coder := newMockAgent(t, tmpDir, "coder")
+	svc := New(nil, "", tmpDir, "alice")
+	svc.DefaultAgent = "reviewer"
+
+	// Telegram resolves "coder: ..." prefixes and reply tags into targetAgent.
+	svc.handleInbound("coder", "fix the build")
+
+	if reqs := coder.Received(); len(reqs) != 1 || reqs[0].Body != "fix the build" {
+		t.Fatalf("expected prefixed message at coder, got %+v", reqs)
+	}
+	if reqs := reviewer.Received(); len(reqs) != 0 {
+		t.Fatalf("default agent should not receive prefixed message, got %d", len(reqs))
+	}
+}
+
 // --- Error reply tests ---
 
 func TestHandleInbound_DeadAgentRepliesWithError(t *testing.T) {
@@ -419,6 +493,65 @@ func TestHandleOutbound_NoConciergeTag(t *testing.T) {
 	}
 }
 
+func TestHandleOutbound_StreamsWhenEnabled(t *testing.T) {
+	sb := &mockStreamBridge{name: "telegram", streaming: true}
+	svc := New([]bridge.Bridge{sb}, "concierge", t.TempDir(), "alice")
+
+	svc.handleOutbound("researcher", "step one")
+	svc.handleOutbound("researcher", "step two")
+
+	sent, streamed, _ := sb.Calls()
+	if len(sent) != 0 {
+		t.Errorf("streaming bridge should not get Send calls, got %q", sent)
+	}
+	want := []string{
+		"researcher: [researcher] step one",
+		"researcher: [researcher] step one\n\nstep two",
+	}
+	if len(streamed) != 2 || streamed[0] != want[0] || streamed[1] != want[1] {
+		t.Errorf("streamed = %q, want %q", streamed, want)
+	}
+}
+
+func TestHandleOutbound_StreamingDisabledSends(t *testing.T) {
+	sb := &mockStreamBridge{name: "telegram"}
+	svc := New([]bridge.Bridge{sb}, "concierge", t.TempDir(), "alice")
+
+	svc.handleOutbound("researcher", "hello")
+
+	sent, streamed, _ := sb.Calls()
+	if len(sent) != 1 || len(streamed) != 0 {
+		t.Errorf("sent = %q, streamed = %q; want one Send", sent, streamed)
+	}
+}
+
+func TestEndIdleStreams(t *testing.T) {
+	tmpDir := shortTempDir(t)
+	worker := newMockStatusAgent(t, tmpDir, "worker", "active")
+	sb := &mockStreamBridge{name: "telegram", streaming: true}
+	svc := New([]bridge.Bridge{sb}, "concierge", tmpDir, "alice")
+	ctx := context.Background()
+
+	svc.handleOutbound("worker", "working")
+	svc.endIdleStreams(ctx)
+	if _, _, ended := sb.Calls(); len(ended) != 0 {
+		t.Fatalf("stream of an active agent ended: %q", ended)
+	}
+
+	worker.SetState("idle")
+	svc.endIdleStreams(ctx)
+	if _, _, ended := sb.Calls(); len(ended) != 1 || ended[0] != "worker" {
+		t.Fatalf("ended = %q, want the idle agent's stream", ended)
+	}
+
+	// The next message after going idle starts a fresh stream.
+	svc.handleOutbound("worker", "again")
+	_, streamed, _ := sb.Calls()
+	if last := streamed[len(streamed)-1]; last != "worker: [worker] again" {
+		t.Errorf("after idle, streamed %q, want a new stream", last)
+	}
+}
+
 // --- Socket listener test ---
 
 func TestSocketListener(t *testing.T) {
@@ -3,9 +3,12 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/google/uuid"
 
+	"h2/internal/activitylog"
 	"h2/internal/config"
 	"h2/internal/git"
 	"h2/internal/session"
@@ -15,22 +18,89 @@ import (
 // Tests override this to avoid spawning real processes.
 var forkDaemonFunc = session.ForkDaemon
 
+// agentLaunch describes an agent for doSetupAndForkAgent to set up and fork.
+type agentLaunch struct {
+	Name      string // agent name; generated from the role when empty
+	Role      *config.Role
+	Pod       string
+	Overrides []string // --override values recorded in the session metadata
+	Detach    bool     // leave the agent running in the background
+	Quiet     bool     // suppress progress output
+	// AfterFork, when non-nil, runs once the daemon is up and before attaching.
+	AfterFork func(session.ForkDaemonOpts) error
+}
+
 // setupAndForkAgent sets up the agent session, forks the daemon,
 // and optionally attaches to it. This is shared by both 'h2 run' and 'h2 bridge'.
 // The caller is responsible for loading the role and applying any overrides.
-// setupAndForkAgentQuiet is like setupAndForkAgent but suppresses output.
-// Used by pod launch which handles its own output.
-func setupAndForkAgentQuiet(name string, role *config.Role, pod string, overrides []string) error {
-	return doSetupAndForkAgent(name, role, true, pod, overrides, true)
+func setupAndForkAgent(l agentLaunch) error {
+	l.Quiet = false
+	return doSetupAndForkAgent(l)
+}
+
+// setupAndForkAgentQuiet is like setupAndForkAgent but detaches and
+// suppresses output. Used by pod launch which handles its own output.
+func setupAndForkAgentQuiet(l agentLaunch) error {
+	l.Detach, l.Quiet = true, true
+	return doSetupAndForkAgent(l)
+}
+
+// globalSandboxRoot returns sandbox_root from config.yaml, or "" if unset.
+func globalSandboxRoot() (string, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return "", fmt.Errorf("load config: %w", err)
+	}
+	return cfg.SandboxRoot, nil
 }
 
-func setupAndForkAgent(name string, role *config.Role, detach bool, pod string, overrides []string) error {
-	return doSetupAndForkAgent(name, role, detach, pod, overrides, false)
+// resolveAgentName checks a --name given by the user, or generates one for
+// role with config.yaml's name_scheme when name is empty.
+func resolveAgentName(name, role string) (string, error) {
+	if name != "" {
+		return name, session.ValidateAgentName(name)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return "", fmt.Errorf("load config: %w", err)
+	}
+	return session.GenerateNameForRole(cfg.NameScheme, role)
 }
 
-func doSetupAndForkAgent(name string, role *config.Role, detach bool, pod string, overrides []string, quiet bool) error {
-	if name == "" {
-		name = session.GenerateName()
+// checkClaudeProfile fails with instructions for logging in when a role's
+// named Claude profile hasn't been authenticated yet.
+func checkClaudeProfile(profile, configDir string) error {
+	isAuth, err := config.IsClaudeConfigAuthenticated(configDir)
+	if err != nil {
+		return fmt.Errorf("check claude profile %q: %w", profile, err)
+	}
+	if !isAuth {
+		return fmt.Errorf("claude profile %q is not authenticated (%s); run: h2 auth claude --profile %s", profile, configDir, profile)
+	}
+	return nil
+}
+
+// checkRoleRequires fails when any binary the role requires is missing
+// from the agent's PATH (the role's env PATH, if set), naming all of them
+// at once.
+func checkRoleRequires(role *config.Role) error {
+	missing := role.MissingRequirements()
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("role %q requires %s, not found on PATH; install them or adjust PATH (or the role's env PATH) before launching",
+		role.Name, strings.Join(missing, ", "))
+}
+
+func doSetupAndForkAgent(l agentLaunch) error {
+	role := l.Role
+	name, err := resolveAgentName(l.Name, role.Name)
+	if err != nil {
+		return err
+	}
+
+	if err := checkRoleRequires(role); err != nil {
+		return err
 	}
 
 	sessionDir, err := config.SetupSessionDir(name, role)
@@ -43,30 +113,108 @@ func doSetupAndForkAgent(name string, role *config.Role, detach bool, pod string
 		if err := config.EnsureClaudeConfigDir(claudeConfigDir); err != nil {
 			return fmt.Errorf("ensure claude config dir: %w", err)
 		}
+		if role.ClaudeProfile != "" {
+			if err := checkClaudeProfile(role.ClaudeProfile, claudeConfigDir); err != nil {
+				return err
+			}
+		}
+		if role.NoHooks {
+			if claudeConfigDir, err = config.EnsureNoHooksConfigDir(claudeConfigDir); err != nil {
+				return fmt.Errorf("ensure no-hooks claude config dir: %w", err)
+			}
+		}
+	}
+	if role.NoHooks {
+		fmt.Fprintf(os.Stderr, "Warning: agent %q runs without h2 hooks; state comes from output and OTEL only, and permission prompts are not reviewed\n", name)
 	}
 
-	cmdCommand := role.GetAgentType()
+	cmdCommand := role.GetCommand()
 	var heartbeat session.DaemonHeartbeat
 	if role.Heartbeat != nil {
 		d, err := role.Heartbeat.ParseIdleTimeout()
 		if err != nil {
 			return fmt.Errorf("invalid heartbeat idle_timeout: %w", err)
 		}
+		conditionTimeout, err := role.Heartbeat.ParseConditionTimeout()
+		if err != nil {
+			return fmt.Errorf("invalid heartbeat condition_timeout: %w", err)
+		}
 		heartbeat = session.DaemonHeartbeat{
-			IdleTimeout: d,
-			Message:     role.Heartbeat.Message,
-			Condition:   role.Heartbeat.Condition,
+			IdleTimeout:      d,
+			Message:          role.Heartbeat.Message,
+			Condition:        role.Heartbeat.Condition,
+			ConditionTimeout: conditionTimeout,
+			Priority:         role.MessagePriority.Heartbeat,
+			EscalateAfter:    role.Heartbeat.EscalateAfter,
+			EscalateMessage:  role.Heartbeat.EscalateMessage,
+			EscalateCommand:  role.Heartbeat.EscalateCommand,
+		}
+	}
+
+	escalateAfter, err := role.ParseEscalateAfter()
+	if err != nil {
+		return fmt.Errorf("invalid escalate_after: %w", err)
+	}
+	messageAging, err := role.ParseMessageAging()
+	if err != nil {
+		return fmt.Errorf("invalid message_aging: %w", err)
+	}
+	dedupeWindow, err := role.ParseDedupeWindow()
+	if err != nil {
+		return fmt.Errorf("invalid dedupe_window: %w", err)
+	}
+	passthroughIdle, err := role.ParsePassthroughIdleTimeout()
+	if err != nil {
+		return fmt.Errorf("invalid passthrough_idle_timeout: %w", err)
+	}
+	idleThreshold, err := role.ParseIdleThreshold()
+	if err != nil {
+		return fmt.Errorf("invalid idle_threshold: %w", err)
+	}
+	shutdownGrace, err := role.ParseShutdownGrace()
+	if err != nil {
+		return fmt.Errorf("invalid shutdown_grace: %w", err)
+	}
+	var activityLog activitylog.Rotation
+	if role.ActivityLog != nil {
+		maxAge, err := role.ActivityLog.ParseMaxAge()
+		if err != nil {
+			return fmt.Errorf("invalid activity_log max_age: %w", err)
+		}
+		activityLog = activitylog.Rotation{
+			MaxSize:  int64(role.ActivityLog.MaxSizeMB) << 20,
+			MaxAge:   maxAge,
+			MaxFiles: role.ActivityLog.MaxFiles,
+		}
+	}
+	// The daemon runs in the agent's directory, so pin a relative status
+	// file to where the agent was launched from.
+	statusFile := role.StatusFile
+	if statusFile != "" {
+		if statusFile, err = filepath.Abs(statusFile); err != nil {
+			return fmt.Errorf("resolve status_file: %w", err)
 		}
 	}
 
 	// Resolve the working directory for the agent.
 	var agentCWD string
+	sandboxRoot, err := globalSandboxRoot()
+	if err != nil {
+		return err
+	}
 	if role.Worktree != nil {
-		// Worktree mode: create/reuse worktree, CWD = worktree path.
+		// Worktree mode: create/reuse worktree, CWD = worktree path. The
+		// path is vetted against the sandbox before anything is created.
+		if err := role.CheckSandboxed(filepath.Join(config.WorktreesDir(), role.Worktree.Name), sandboxRoot); err != nil {
+			return fmt.Errorf("worktree: %w", err)
+		}
 		worktreePath, err := git.CreateWorktree(role.Worktree)
 		if err != nil {
 			return fmt.Errorf("create worktree: %w", err)
 		}
+		if err := role.CheckSandboxed(worktreePath, sandboxRoot); err != nil {
+			return fmt.Errorf("worktree: %w", err)
+		}
 		agentCWD = worktreePath
 	} else {
 		// Normal mode: resolve working_dir.
@@ -74,45 +222,90 @@ func doSetupAndForkAgent(name string, role *config.Role, detach bool, pod string
 		if err != nil {
 			return fmt.Errorf("get working directory: %w", err)
 		}
-		agentCWD, err = role.ResolveWorkingDir(cwd)
+		agentCWD, err = role.ResolveSandboxedWorkingDir(cwd, sandboxRoot)
 		if err != nil {
 			return fmt.Errorf("resolve working_dir: %w", err)
 		}
+		if err := role.PrepareWorkingDir(agentCWD); err != nil {
+			return err
+		}
+	}
+
+	for _, flag := range role.ExtraArgConflicts() {
+		fmt.Fprintf(os.Stderr, "Warning: role %q extra_args sets %s, which h2 already manages\n", role.Name, flag)
+	}
+	for _, spec := range role.UnknownPermissionTools() {
+		fmt.Fprintf(os.Stderr, "Warning: role %q permission %q names an unknown tool\n", role.Name, spec)
 	}
 
 	sessionID := uuid.New().String()
 
 	// Fork the daemon.
-	if err := forkDaemonFunc(session.ForkDaemonOpts{
-		Name:            name,
-		SessionID:       sessionID,
-		Command:         cmdCommand,
-		RoleName:        role.Name,
-		SessionDir:      sessionDir,
-		ClaudeConfigDir: claudeConfigDir,
-		Instructions:    role.Instructions,
-		SystemPrompt:    role.SystemPrompt,
-		Model:           role.Model,
-		PermissionMode:  role.PermissionMode,
-		AllowedTools:    role.Permissions.Allow,
-		DisallowedTools: role.Permissions.Deny,
-		Heartbeat:       heartbeat,
-		CWD:             agentCWD,
-		Pod:             pod,
-		Overrides:       overrides,
-	}); err != nil {
+	forkOpts := session.ForkDaemonOpts{
+		Name:                 name,
+		SessionID:            sessionID,
+		Command:              cmdCommand,
+		Args:                 role.Args,
+		RoleName:             role.Name,
+		SessionDir:           sessionDir,
+		ClaudeConfigDir:      claudeConfigDir,
+		Instructions:         role.Instructions,
+		SystemPrompt:         role.SystemPrompt,
+		Model:                role.Model,
+		PermissionMode:       role.PermissionMode,
+		AllowedTools:         role.Permissions.Allow,
+		DisallowedTools:      role.Permissions.Deny,
+		ExtraArgs:            role.ExtraArgs,
+		Heartbeat:            heartbeat,
+		EscalateAfter:        escalateAfter,
+		MessageAging:         messageAging,
+		DedupeWindow:         dedupeWindow,
+		NoConfirmQuit:        !role.GetConfirmQuit(),
+		DrainOnQuit:          role.DrainOnQuit,
+		ShutdownGrace:        shutdownGrace,
+		NoPassthrough:        !role.GetAllowPassthrough(),
+		WriteLock:            role.WriteLock,
+		MaxInputLen:          role.MaxInputBytes,
+		SubmitNewline:        role.SubmitNewline,
+		SubmitDelay:          role.SubmitDelay,
+		ModifierEnter:        role.ModifierEnter,
+		InputPlaceholder:     role.InputPlaceholder,
+		DurationPrecision:    role.DurationPrecision,
+		ScrollOnOutput:       role.ScrollOnOutput,
+		Rows:                 role.Rows,
+		Cols:                 role.Cols,
+		ReadyRegex:           role.ReadyRegex,
+		OnMessage:            role.OnMessage,
+		StartMessage:         role.OnStartMessage,
+		StartMessagePriority: role.MessagePriority.Start,
+		NoHooks:              role.NoHooks,
+		PassthroughIdle:      passthroughIdle,
+		IdleThreshold:        idleThreshold,
+		StatusFile:           statusFile,
+		ActivityLog:          activityLog,
+		Env:                  role.Env,
+		CWD:                  agentCWD,
+		Pod:                  l.Pod,
+		Overrides:            l.Overrides,
+	}
+	if err := forkDaemonFunc(forkOpts); err != nil {
 		return err
 	}
+	if l.AfterFork != nil {
+		if err := l.AfterFork(forkOpts); err != nil {
+			return err
+		}
+	}
 
-	if detach {
-		if !quiet {
+	if l.Detach {
+		if !l.Quiet {
 			fmt.Fprintf(os.Stderr, "Agent %q started (detached). Use 'h2 attach %s' to connect.\n", name, name)
 		}
 		return nil
 	}
 
-	if !quiet {
+	if !l.Quiet {
 		fmt.Fprintf(os.Stderr, "Agent %q started. Attaching...\n", name)
 	}
-	return doAttach(name)
+	return doAttach(name, attachOptions{})
 }
@@ -3,64 +3,115 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
 
+	"h2/internal/session/client"
 	"h2/internal/session/message"
+	"h2/internal/session/virtualterminal"
 	"h2/internal/socketdir"
 )
 
+// attachOptions holds optional attach behaviors negotiated with the daemon.
+type attachOptions struct {
+	Compress     bool // request DEFLATE-compressed render frames
+	DetachOnIdle bool // detach automatically when the agent goes active → idle
+	TrimLines    bool // request rows without redundant trailing blanks
+	ReadOnly     bool // attach as an observer that can't type or take control
+
+	Reconnect         bool // reattach with backoff when the connection drops
+	ReconnectAttempts int  // give up after this many failed reattaches
+}
+
 func newAttachCmd() *cobra.Command {
-	return &cobra.Command{
+	var opts attachOptions
+	var pod string
+	var followAttention bool
+
+	cmd := &cobra.Command{
 		Use:   "attach <name>",
 		Short: "Attach to a running agent",
-		Args:  cobra.ExactArgs(1),
+		Long: `Attach to a running agent's terminal.
+
+With --pod <name> --follow-attention, no agent name is given: the view
+starts on one of the pod's agents and switches automatically to whichever
+agent next stops to wait for you (goes idle or asks for permission).
+Press ctrl+] to cycle to the next agent yourself; a manual switch pauses
+auto-follow for a short while.
+
+With --reconnect, a dropped connection (the daemon restarting, a socket
+blip) is retried with exponential backoff instead of ending the attach,
+up to --reconnect-attempts times. Detaching, quitting, or stopping the
+agent still ends it.
+
+With --readonly, the terminal only watches: keystrokes are not sent to the
+agent, passthrough and control can't be taken, and the menu offers just
+redraw, copy and detach. Scrolling (mouse wheel or Up) works as usual and
+doesn't affect other attached terminals.`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return doAttach(args[0])
+			if followAttention {
+				if pod == "" {
+					return fmt.Errorf("--follow-attention requires --pod")
+				}
+				if len(args) > 0 {
+					return fmt.Errorf("--follow-attention attaches to the pod's agents; don't pass an agent name")
+				}
+				if opts.DetachOnIdle {
+					return fmt.Errorf("--follow-attention cannot be combined with --detach-on-idle")
+				}
+				if opts.Reconnect {
+					return fmt.Errorf("--follow-attention cannot be combined with --reconnect")
+				}
+				return doFollowAttach(pod, opts)
+			}
+			if pod != "" {
+				return fmt.Errorf("--pod requires --follow-attention")
+			}
+			if len(args) == 0 {
+				return fmt.Errorf("requires an agent name")
+			}
+			if opts.ReconnectAttempts < 1 {
+				return fmt.Errorf("--reconnect-attempts must be at least 1")
+			}
+			return doAttach(args[0], opts)
 		},
 	}
+
+	cmd.Flags().BoolVar(&opts.Compress, "compress", false, "Request compressed render frames (useful over slow remote links)")
+	cmd.Flags().BoolVar(&opts.TrimLines, "trim-lines", false, "Skip redundant trailing blanks in rendered rows to save bandwidth")
+	cmd.Flags().BoolVar(&opts.ReadOnly, "readonly", false, "Watch only: don't send input or take control of the agent")
+	cmd.Flags().BoolVar(&opts.DetachOnIdle, "detach-on-idle", false, "Detach once the agent finishes work (first active → idle transition)")
+	cmd.Flags().BoolVar(&opts.Reconnect, "reconnect", false, "Reattach with backoff if the connection to the agent drops")
+	cmd.Flags().IntVar(&opts.ReconnectAttempts, "reconnect-attempts", 5, "With --reconnect, give up after this many failed attempts")
+	cmd.Flags().StringVar(&pod, "pod", "", "Pod whose agents --follow-attention cycles through")
+	cmd.Flags().BoolVar(&followAttention, "follow-attention", false, "Switch to whichever pod agent next needs attention")
+
+	return cmd
 }
 
 // doAttach connects to a running daemon and proxies terminal I/O.
-func doAttach(name string) error {
-	sockPath, findErr := socketdir.Find(name)
-	if findErr != nil {
-		return agentConnError(name, findErr)
-	}
-	conn, err := net.Dial("unix", sockPath)
-	if err != nil {
-		return agentConnError(name, err)
-	}
-	defer conn.Close()
-
+func doAttach(name string, opts attachOptions) error {
 	fd := int(os.Stdin.Fd())
 	cols, rows, err := term.GetSize(fd)
 	if err != nil {
 		return fmt.Errorf("get terminal size: %w", err)
 	}
 
-	// Send attach handshake.
-	if err := message.SendRequest(conn, &message.Request{
-		Type: "attach",
-		Cols: cols,
-		Rows: rows,
-	}); err != nil {
-		return fmt.Errorf("send attach request: %w", err)
-	}
-
-	resp, err := message.ReadResponse(conn)
+	conn, err := dialAttach(name, cols, rows, opts)
 	if err != nil {
-		return fmt.Errorf("read attach response: %w", err)
-	}
-	if !resp.OK {
-		return fmt.Errorf("attach failed: %s", resp.Error)
+		return err
 	}
+	ac := &attachConn{conn: conn, cols: cols, rows: rows}
+	defer ac.Close()
 
 	// Put terminal into raw mode.
 	oldState, err := term.MakeRaw(fd)
@@ -68,7 +119,7 @@ func doAttach(name string) error {
 		return fmt.Errorf("set raw mode: %w", err)
 	}
 	defer func() {
-		os.Stdout.WriteString("\033[?1000l\033[?1006l") // Disable mouse mode
+		os.Stdout.WriteString("\033[?1000l\033[?1006l\033[?2004l") // Disable mouse and paste mode
 		term.Restore(fd, oldState)
 		os.Stdout.WriteString("\033[?25h\033[0m\r\n")
 	}()
@@ -76,18 +127,14 @@ func doAttach(name string) error {
 	// Handle SIGWINCH for resizing.
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGWINCH)
+	defer signal.Stop(sigCh)
 	go func() {
 		for range sigCh {
 			cols, rows, err := term.GetSize(fd)
 			if err != nil {
 				continue
 			}
-			ctrl, _ := json.Marshal(message.ResizeControl{
-				Type: "resize",
-				Cols: cols,
-				Rows: rows,
-			})
-			message.WriteFrame(conn, message.FrameTypeControl, ctrl)
+			ac.Resize(cols, rows)
 		}
 	}()
 
@@ -95,14 +142,15 @@ func doAttach(name string) error {
 	var closeOnce sync.Once
 	closeDone := func() { closeOnce.Do(func() { close(done) }) }
 
-	// Goroutine: stdin → data frames to session.
+	// Goroutine: stdin → data frames to session. While reconnecting, a
+	// failed write only drops the keystrokes.
 	go func() {
 		defer closeDone()
 		buf := make([]byte, 4096)
 		for {
 			n, err := os.Stdin.Read(buf)
 			if n > 0 {
-				if err := message.WriteFrame(conn, message.FrameTypeData, buf[:n]); err != nil {
+				if err := ac.WriteFrame(message.FrameTypeData, buf[:n]); err != nil && !opts.Reconnect {
 					return
 				}
 			}
@@ -113,19 +161,185 @@ func doAttach(name string) error {
 	}()
 
 	// Goroutine: read frames from daemon → write to stdout.
+	loopErr := make(chan error, 1)
 	go func() {
 		defer closeDone()
-		for {
-			frameType, payload, err := message.ReadFrame(conn)
+		loopErr <- attachLoop(name, ac, os.Stdout, opts, done)
+	}()
+
+	<-done
+	ac.Close()
+	select {
+	case err := <-loopErr:
+		return err
+	default:
+		return nil
+	}
+}
+
+// attachConn is the daemon connection of an attach, swapped out when
+// --reconnect reattaches. It remembers the terminal size for the redial.
+type attachConn struct {
+	mu   sync.Mutex
+	conn net.Conn
+	cols int
+	rows int
+}
+
+// WriteFrame writes a frame to the current connection.
+func (a *attachConn) WriteFrame(frameType byte, payload []byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return message.WriteFrame(a.conn, frameType, payload)
+}
+
+// Resize records the terminal size and forwards it to the daemon.
+func (a *attachConn) Resize(cols, rows int) {
+	ctrl, _ := json.Marshal(message.ResizeControl{
+		Type: "resize",
+		Cols: cols,
+		Rows: rows,
+	})
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cols, a.rows = cols, rows
+	message.WriteFrame(a.conn, message.FrameTypeControl, ctrl)
+}
+
+// Close closes the current connection.
+func (a *attachConn) Close() {
+	a.mu.Lock()
+	a.conn.Close()
+	a.mu.Unlock()
+}
+
+// attachLoop copies render frames from ac to w. When the connection drops
+// and opts.Reconnect is set, it redials with backoff and resumes on the
+// new connection; the daemon repaints the screen on attach. It returns nil
+// once the daemon ends the attach on purpose (detach, quit, stop) or stop
+// closes, and an error if every reconnect attempt fails.
+func attachLoop(name string, ac *attachConn, w io.Writer, opts attachOptions, stop <-chan struct{}) error {
+	for {
+		ac.mu.Lock()
+		conn := ac.conn
+		ac.mu.Unlock()
+		if copyAttachFrames(conn, w) || !opts.Reconnect {
+			return nil
+		}
+		next, err := reconnectAttach(name, ac, w, opts, stop)
+		if err != nil || next == nil {
+			return err
+		}
+		ac.mu.Lock()
+		ac.conn.Close()
+		ac.conn = next
+		ac.mu.Unlock()
+		io.WriteString(w, "\033[2J\033[H")
+	}
+}
+
+// reconnectBackoff is the wait before the first reconnect attempt; it
+// doubles per attempt up to maxReconnectBackoff. Var so tests can shorten it.
+var reconnectBackoff = 500 * time.Millisecond
+
+const maxReconnectBackoff = 8 * time.Second
+
+// reconnectAttach redials name up to opts.ReconnectAttempts times, waiting
+// with exponential backoff before each try. It returns a nil conn and nil
+// error if stop closes first.
+func reconnectAttach(name string, ac *attachConn, w io.Writer, opts attachOptions, stop <-chan struct{}) (net.Conn, error) {
+	backoff := reconnectBackoff
+	var lastErr error
+	for attempt := 1; attempt <= opts.ReconnectAttempts; attempt++ {
+		fmt.Fprintf(w, "\r\n\033[0m[h2] connection to %s lost; reconnecting (%d/%d)...", name, attempt, opts.ReconnectAttempts)
+		select {
+		case <-time.After(backoff):
+		case <-stop:
+			return nil, nil
+		}
+		backoff *= 2
+		if backoff > maxReconnectBackoff {
+			backoff = maxReconnectBackoff
+		}
+
+		ac.mu.Lock()
+		cols, rows := ac.cols, ac.rows
+		ac.mu.Unlock()
+		conn, err := dialAttach(name, cols, rows, opts)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("gave up reconnecting to %s after %d attempts: %w", name, opts.ReconnectAttempts, lastErr)
+}
+
+// dialAttach connects to the named agent and completes the attach
+// handshake for a terminal of the given size.
+func dialAttach(name string, cols, rows int, opts attachOptions) (net.Conn, error) {
+	sockPath, findErr := socketdir.Find(name)
+	if findErr != nil {
+		return nil, agentConnError(name, findErr)
+	}
+	conn, err := net.Dial("unix", sockPath)
+	if err != nil {
+		return nil, agentConnError(name, err)
+	}
+
+	// Send attach handshake.
+	if err := message.SendRequest(conn, &message.Request{
+		Type:     "attach",
+		Cols:     cols,
+		Rows:     rows,
+		Compress: opts.Compress,
+
+		DetachOnIdle: opts.DetachOnIdle,
+		TrimLines:    opts.TrimLines,
+		ReadOnly:     opts.ReadOnly,
+		ScrollStep:   virtualterminal.ScrollStepEnv(),
+
+		Theme:           os.Getenv("H2_THEME"),
+		LightBackground: !client.EnvDarkBackground(),
+	}); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("send attach request: %w", err)
+	}
+
+	resp, err := message.ReadResponse(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("read attach response: %w", err)
+	}
+	if !resp.OK {
+		conn.Close()
+		return nil, fmt.Errorf("attach failed: %s", resp.Error)
+	}
+	return conn, nil
+}
+
+// copyAttachFrames writes render frames from the daemon to w until the
+// connection fails or closes. It reports whether the daemon ended the
+// attach on purpose (a detach control frame) rather than dropping it.
+func copyAttachFrames(conn net.Conn, w io.Writer) bool {
+	for {
+		frameType, payload, err := message.ReadFrame(conn)
+		if err != nil {
+			return false
+		}
+		switch frameType {
+		case message.FrameTypeData:
+			w.Write(payload)
+		case message.FrameTypeCompressedData:
+			data, err := message.DecompressPayload(payload)
 			if err != nil {
-				return
+				return false
 			}
-			if frameType == message.FrameTypeData {
-				os.Stdout.Write(payload)
+			w.Write(data)
+		case message.FrameTypeControl:
+			var ctrl message.DetachControl
+			if json.Unmarshal(payload, &ctrl) == nil && ctrl.Type == "detach" {
+				return true
 			}
 		}
-	}()
-
-	<-done
-	return nil
+	}
 }
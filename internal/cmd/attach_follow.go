@@ -0,0 +1,340 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sort"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/term"
+
+	"h2/internal/session/message"
+	"h2/internal/socketdir"
+)
+
+// followSwitchKey cycles to the next pod agent in a follow-attention attach
+// (ctrl+]). It is consumed locally and never reaches the agent.
+const followSwitchKey = 0x1D
+
+// followPollInterval is how often a follow-attention attach polls the pod's
+// agents.
+const followPollInterval = time.Second
+
+// followManualHold is how long a manual switch pauses auto-follow.
+var followManualHold = 30 * time.Second
+
+// followTypingHold is how long a keystroke defers auto-follow, so the view
+// doesn't move to another agent while the user is typing.
+var followTypingHold = 5 * time.Second
+
+// needsAttention reports whether an agent is waiting on the user: idle at
+// its prompt, or blocked on a permission decision.
+func needsAttention(info *message.AgentInfo) bool {
+	return info.State == "idle" || info.SubState == "waiting_for_permission"
+}
+
+// attentionFollower decides which of a pod's agents a follow-attention
+// attach shows. It switches to an agent when that agent newly needs
+// attention, unless a manual switch is holding the view. While the user is
+// typing the switch is deferred rather than dropped.
+type attentionFollower struct {
+	current     string
+	waiting     map[string]bool // agents needing attention at the last poll
+	notified    map[string]bool // waiting as of the last poll acted on
+	names       []string        // running agents at the last poll, sorted
+	manualUntil time.Time
+	typingUntil time.Time
+}
+
+// Start picks the initial agent from the first poll: the first agent
+// needing attention, else the first agent by name. Returns "" when the pod
+// has no agents.
+func (f *attentionFollower) Start(agents []*message.AgentInfo) string {
+	f.record(agents)
+	f.notified = f.waiting
+	f.current = ""
+	for _, name := range f.names {
+		if f.waiting[name] {
+			f.current = name
+			break
+		}
+	}
+	if f.current == "" && len(f.names) > 0 {
+		f.current = f.names[0]
+	}
+	return f.current
+}
+
+// Observe records a poll taken at now and returns the agent to switch to,
+// or "" to stay on the current one. If the current agent has gone away it
+// always moves on, even during a manual or typing hold. Agents that come to
+// need attention while the user is typing are switched to once the typing
+// hold ends, if they still need it.
+func (f *attentionFollower) Observe(now time.Time, agents []*message.AgentInfo) string {
+	f.record(agents)
+
+	present := false
+	for _, name := range f.names {
+		if name == f.current {
+			present = true
+			break
+		}
+	}
+	if !present {
+		next := ""
+		for _, name := range f.names {
+			if f.waiting[name] {
+				next = name
+				break
+			}
+		}
+		if next == "" && len(f.names) > 0 {
+			next = f.names[0]
+		}
+		f.current = next
+		f.notified = f.waiting
+		return next
+	}
+
+	if now.Before(f.typingUntil) {
+		return ""
+	}
+	before := f.notified
+	f.notified = f.waiting
+	if now.Before(f.manualUntil) {
+		return ""
+	}
+	for _, name := range f.names {
+		if name != f.current && f.waiting[name] && !before[name] {
+			f.current = name
+			return name
+		}
+	}
+	return ""
+}
+
+// Typed records that the user pressed a key at now, deferring auto-follow
+// until followTypingHold after it.
+func (f *attentionFollower) Typed(now time.Time) {
+	f.typingUntil = now.Add(followTypingHold)
+}
+
+// Manual cycles to the next agent by name and pauses auto-follow until
+// followManualHold after now. Returns "" when there is nowhere else to go.
+func (f *attentionFollower) Manual(now time.Time) string {
+	f.manualUntil = now.Add(followManualHold)
+	if len(f.names) < 2 {
+		return ""
+	}
+	i := sort.SearchStrings(f.names, f.current)
+	if i < len(f.names) && f.names[i] == f.current {
+		i++
+	}
+	f.current = f.names[i%len(f.names)]
+	return f.current
+}
+
+func (f *attentionFollower) record(agents []*message.AgentInfo) {
+	f.waiting = make(map[string]bool, len(agents))
+	f.names = f.names[:0]
+	for _, info := range agents {
+		if info.State == "exited" {
+			continue
+		}
+		f.names = append(f.names, info.Name)
+		f.waiting[info.Name] = needsAttention(info)
+	}
+	sort.Strings(f.names)
+}
+
+// podAgentInfos returns the status of every running agent in pod.
+func podAgentInfos(pod string) []*message.AgentInfo {
+	entries, err := socketdir.ListByType(socketdir.TypeAgent)
+	if err != nil {
+		return nil
+	}
+	var infos []*message.AgentInfo
+	for _, e := range entries {
+		info := queryAgent(e.Path)
+		if info != nil && info.Pod == pod {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+// doFollowAttach attaches to the pod's agents one at a time, switching the
+// view as agents come to need attention or on followSwitchKey. It returns
+// when stdin closes, the attached agent detaches the client, or the pod has
+// no agents left.
+func doFollowAttach(pod string, opts attachOptions) error {
+	var follower attentionFollower
+	first := follower.Start(podAgentInfos(pod))
+	if first == "" {
+		return fmt.Errorf("pod %q has no running agents", pod)
+	}
+
+	fd := int(os.Stdin.Fd())
+	cols, rows, err := term.GetSize(fd)
+	if err != nil {
+		return fmt.Errorf("get terminal size: %w", err)
+	}
+
+	var mu sync.Mutex // guards conn, cols, rows
+	conn, err := dialAttach(first, cols, rows, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		mu.Lock()
+		conn.Close()
+		mu.Unlock()
+	}()
+
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("set raw mode: %w", err)
+	}
+	defer func() {
+		os.Stdout.WriteString("\033[?1000l\033[?1006l\033[?2004l") // Disable mouse and paste mode
+		term.Restore(fd, oldState)
+		os.Stdout.WriteString("\033[?25h\033[0m\r\n")
+	}()
+
+	done := make(chan struct{})
+	var closeOnce sync.Once
+	closeDone := func() { closeOnce.Do(func() { close(done) }) }
+
+	// connLost fires when a connection ends without us switching away from
+	// it: the user detached, or the agent went away.
+	connLost := make(chan net.Conn, 1)
+	watch := func(c net.Conn) {
+		go func() {
+			copyAttachFrames(c, os.Stdout)
+			select {
+			case connLost <- c:
+			default:
+			}
+		}()
+	}
+	watch(conn)
+
+	// switchTo replaces the attached connection with one to name.
+	switchTo := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		next, err := dialAttach(name, cols, rows, opts)
+		if err != nil {
+			return
+		}
+		old := conn
+		conn = next
+		old.Close()
+		os.Stdout.WriteString("\033[2J\033[H")
+		watch(next)
+	}
+
+	// Handle SIGWINCH for resizing.
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGWINCH)
+	defer signal.Stop(sigCh)
+	go func() {
+		for range sigCh {
+			c, r, err := term.GetSize(fd)
+			if err != nil {
+				continue
+			}
+			ctrl, _ := json.Marshal(message.ResizeControl{Type: "resize", Cols: c, Rows: r})
+			mu.Lock()
+			cols, rows = c, r
+			message.WriteFrame(conn, message.FrameTypeControl, ctrl)
+			mu.Unlock()
+		}
+	}()
+
+	// Goroutine: stdin → data frames to the attached agent. The switch key
+	// is consumed here and turned into a manual switch; other input defers
+	// auto-follow.
+	manual := make(chan struct{}, 1)
+	typed := make(chan struct{}, 1)
+	go func() {
+		defer closeDone()
+		buf := make([]byte, 4096)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if n > 0 {
+				data := buf[:n]
+				for i := 0; i < len(data); i++ {
+					if data[i] != followSwitchKey {
+						continue
+					}
+					data = append(data[:i:i], data[i+1:]...)
+					i--
+					select {
+					case manual <- struct{}{}:
+					default:
+					}
+				}
+				if len(data) > 0 {
+					select {
+					case typed <- struct{}{}:
+					default:
+					}
+					mu.Lock()
+					werr := message.WriteFrame(conn, message.FrameTypeData, data)
+					mu.Unlock()
+					if werr != nil {
+						return
+					}
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(followPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return nil
+		case lost := <-connLost:
+			mu.Lock()
+			current := lost == conn
+			mu.Unlock()
+			if !current {
+				continue // an old connection we switched away from
+			}
+			// If the agent is still running the user detached; otherwise
+			// it went away and we follow to another agent if any.
+			infos := podAgentInfos(pod)
+			for _, info := range infos {
+				if info.Name == follower.current && info.State != "exited" {
+					return nil
+				}
+			}
+			next := follower.Observe(time.Now(), infos)
+			if next == "" {
+				return nil
+			}
+			switchTo(next)
+		case <-typed:
+			follower.Typed(time.Now())
+		case <-manual:
+			if next := follower.Manual(time.Now()); next != "" {
+				switchTo(next)
+			}
+		case <-ticker.C:
+			if next := follower.Observe(time.Now(), podAgentInfos(pod)); next != "" {
+				switchTo(next)
+			}
+		}
+	}
+}
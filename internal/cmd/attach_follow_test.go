@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"h2/internal/session/message"
+)
+
+func podStates(states ...string) []*message.AgentInfo {
+	// states come in name/state pairs; a state of "perm" means active and
+	// waiting for permission.
+	var infos []*message.AgentInfo
+	for i := 0; i+1 < len(states); i += 2 {
+		info := &message.AgentInfo{Name: states[i], State: states[i+1]}
+		if info.State == "perm" {
+			info.State = "active"
+			info.SubState = "waiting_for_permission"
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+func TestAttentionFollower_StartPrefersWaitingAgent(t *testing.T) {
+	var f attentionFollower
+	if got := f.Start(podStates("coder-1", "active", "coder-2", "idle")); got != "coder-2" {
+		t.Errorf("Start = %q, want coder-2 (needs attention)", got)
+	}
+
+	var g attentionFollower
+	if got := g.Start(podStates("b", "active", "a", "active")); got != "a" {
+		t.Errorf("Start = %q, want first by name", got)
+	}
+
+	var empty attentionFollower
+	if got := empty.Start(nil); got != "" {
+		t.Errorf("Start with no agents = %q, want empty", got)
+	}
+}
+
+func TestAttentionFollower_SwitchesOnTransition(t *testing.T) {
+	now := time.Now()
+	var f attentionFollower
+	f.Start(podStates("a", "idle", "b", "active", "c", "active"))
+
+	// Nothing new needs attention.
+	if got := f.Observe(now, podStates("a", "idle", "b", "active", "c", "active")); got != "" {
+		t.Errorf("no transition: switched to %q", got)
+	}
+
+	// b finishes its turn: follow it.
+	if got := f.Observe(now, podStates("a", "idle", "b", "idle", "c", "active")); got != "b" {
+		t.Errorf("b went idle: switch = %q, want b", got)
+	}
+
+	// a was already idle, so it doesn't pull the view back.
+	if got := f.Observe(now, podStates("a", "idle", "b", "idle", "c", "active")); got != "" {
+		t.Errorf("steady state: switched to %q", got)
+	}
+
+	// c asks for permission while still active.
+	if got := f.Observe(now, podStates("a", "idle", "b", "idle", "c", "perm")); got != "c" {
+		t.Errorf("c waiting for permission: switch = %q, want c", got)
+	}
+
+	// a goes active then idle again: a fresh transition.
+	f.Observe(now, podStates("a", "active", "b", "idle", "c", "perm"))
+	if got := f.Observe(now, podStates("a", "idle", "b", "idle", "c", "perm")); got != "a" {
+		t.Errorf("a idle again: switch = %q, want a", got)
+	}
+}
+
+func TestAttentionFollower_ManualSwitchOverridesAutoFollow(t *testing.T) {
+	now := time.Now()
+	var f attentionFollower
+	f.Start(podStates("a", "active", "b", "active", "c", "active"))
+
+	if got := f.Manual(now); got != "b" {
+		t.Fatalf("Manual = %q, want b", got)
+	}
+	if got := f.Manual(now); got != "c" {
+		t.Fatalf("Manual = %q, want c", got)
+	}
+	if got := f.Manual(now); got != "a" {
+		t.Fatalf("Manual wraps: got %q, want a", got)
+	}
+
+	// During the hold, a transition elsewhere doesn't move the view.
+	if got := f.Observe(now.Add(time.Second), podStates("a", "active", "b", "idle", "c", "active")); got != "" {
+		t.Errorf("during manual hold: switched to %q", got)
+	}
+
+	// After the hold, new transitions are followed again.
+	later := now.Add(followManualHold + time.Second)
+	if got := f.Observe(later, podStates("a", "active", "b", "idle", "c", "idle")); got != "c" {
+		t.Errorf("after hold: switch = %q, want c", got)
+	}
+}
+
+func TestAttentionFollower_TypingDefersSwitch(t *testing.T) {
+	now := time.Now()
+	var f attentionFollower
+	f.Start(podStates("a", "active", "b", "active", "c", "active"))
+
+	f.Typed(now)
+	if got := f.Observe(now.Add(time.Second), podStates("a", "active", "b", "idle", "c", "active")); got != "" {
+		t.Errorf("while typing: switched to %q", got)
+	}
+
+	// Once typing stops, the agent that came to need attention meanwhile
+	// is switched to.
+	later := now.Add(followTypingHold + time.Second)
+	if got := f.Observe(later, podStates("a", "active", "b", "idle", "c", "active")); got != "b" {
+		t.Errorf("after typing: switch = %q, want b", got)
+	}
+}
+
+func TestAttentionFollower_CurrentAgentGone(t *testing.T) {
+	now := time.Now()
+	var f attentionFollower
+	f.Start(podStates("a", "active", "b", "active", "c", "idle"))
+	f.Manual(now) // cycles from c to a and starts a hold
+
+	// a exits during the manual hold: move on anyway, preferring an agent
+	// that needs attention.
+	if got := f.Observe(now, podStates("a", "exited", "b", "active", "c", "idle")); got != "c" {
+		t.Errorf("current exited: switch = %q, want c", got)
+	}
+
+	// Everything gone.
+	if got := f.Observe(now, nil); got != "" {
+		t.Errorf("empty pod: switch = %q, want empty", got)
+	}
+}
+
+func TestAttachCmd_FollowAttentionFlags(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--follow-attention"}, "--follow-attention requires --pod"},
+		{[]string{"--follow-attention", "--pod", "p", "coder"}, "don't pass an agent name"},
+		{[]string{"--pod", "p"}, "--pod requires --follow-attention"},
+		{[]string{}, "requires an agent name"},
+	}
+	for _, tt := range tests {
+		cmd := newAttachCmd()
+		cmd.SetArgs(tt.args)
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		err := cmd.Execute()
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("attach %v: err = %v, want %q", tt.args, err, tt.want)
+		}
+	}
+}
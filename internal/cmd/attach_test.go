@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"h2/internal/session/message"
+	"h2/internal/socketdir"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// startAttachMock listens on name's agent socket, accepts attach requests,
+// and sends screen as the first render frame. Accepted connections are
+// delivered on the returned channel so the test can drop or detach them.
+func startAttachMock(t *testing.T, h2Root, name, screen string) (net.Listener, <-chan net.Conn) {
+	t.Helper()
+	sockPath := filepath.Join(h2Root, "sockets", socketdir.Format(socketdir.TypeAgent, name))
+	ln, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	conns := make(chan net.Conn, 4)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			req, err := message.ReadRequest(conn)
+			if err != nil || req.Type != "attach" {
+				conn.Close()
+				continue
+			}
+			message.SendResponse(conn, &message.Response{OK: true})
+			// ReadResponse's decoder may buffer past the response; give
+			// the client time to finish the handshake before framing.
+			time.Sleep(20 * time.Millisecond)
+			message.WriteFrame(conn, message.FrameTypeData, []byte(screen))
+			conns <- conn
+		}
+	}()
+	return ln, conns
+}
+
+func waitForOutput(t *testing.T, out *syncBuffer, want string, within time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(within)
+	for !strings.Contains(out.String(), want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %q, got %q", want, out.String())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func sendDetachFrame(conn net.Conn, reason string) {
+	ctrl, _ := json.Marshal(message.DetachControl{Type: "detach", Reason: reason})
+	message.WriteFrame(conn, message.FrameTypeControl, ctrl)
+}
+
+func setReconnectBackoff(t *testing.T, d time.Duration) {
+	t.Helper()
+	old := reconnectBackoff
+	reconnectBackoff = d
+	t.Cleanup(func() { reconnectBackoff = old })
+}
+
+func TestAttachLoop_ReconnectsAfterDrop(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	setReconnectBackoff(t, 50*time.Millisecond)
+	opts := attachOptions{Reconnect: true, ReconnectAttempts: 5}
+
+	ln, conns := startAttachMock(t, h2Root, "worker", "screen-one")
+	conn, err := dialAttach("worker", 80, 24, opts)
+	if err != nil {
+		t.Fatalf("dialAttach: %v", err)
+	}
+	ac := &attachConn{conn: conn, cols: 80, rows: 24}
+	var out syncBuffer
+	stop := make(chan struct{})
+	defer close(stop)
+	result := make(chan error, 1)
+	go func() { result <- attachLoop("worker", ac, &out, opts, stop) }()
+	waitForOutput(t, &out, "screen-one", 2*time.Second)
+
+	// The daemon goes away: listener and connection both drop.
+	dropped := time.Now()
+	ln.Close()
+	(<-conns).Close()
+	waitForOutput(t, &out, "reconnecting (1/5)", 2*time.Second)
+
+	// It comes back a little later, within the first few backoff steps.
+	time.Sleep(120 * time.Millisecond)
+	_, conns2 := startAttachMock(t, h2Root, "worker", "screen-two")
+	waitForOutput(t, &out, "screen-two", 2*time.Second)
+	// Backoff schedule 50+100+200+400ms: the restored socket is reached
+	// by the fourth attempt at the latest.
+	if elapsed := time.Since(dropped); elapsed > 1500*time.Millisecond {
+		t.Errorf("reconnect took %v, want within the backoff schedule", elapsed)
+	}
+
+	// A deliberate detach ends the loop without another reconnect.
+	sendDetachFrame(<-conns2, "detach")
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("attachLoop = %v, want nil after detach", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("attachLoop did not return after a detach frame")
+	}
+}
+
+func TestAttachLoop_GivesUpAfterMaxAttempts(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	setReconnectBackoff(t, 10*time.Millisecond)
+	opts := attachOptions{Reconnect: true, ReconnectAttempts: 3}
+
+	ln, conns := startAttachMock(t, h2Root, "worker", "screen")
+	conn, err := dialAttach("worker", 80, 24, opts)
+	if err != nil {
+		t.Fatalf("dialAttach: %v", err)
+	}
+	ac := &attachConn{conn: conn, cols: 80, rows: 24}
+	var out syncBuffer
+	ln.Close()
+	(<-conns).Close()
+
+	err = attachLoop("worker", ac, &out, opts, make(chan struct{}))
+	if err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Fatalf("attachLoop = %v, want give-up error", err)
+	}
+	if !strings.Contains(out.String(), "reconnecting (3/3)") {
+		t.Errorf("expected three attempts in output, got %q", out.String())
+	}
+}
+
+func TestAttachLoop_NoReconnectWithoutFlag(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	ln, conns := startAttachMock(t, h2Root, "worker", "screen")
+	conn, err := dialAttach("worker", 80, 24, attachOptions{})
+	if err != nil {
+		t.Fatalf("dialAttach: %v", err)
+	}
+	ac := &attachConn{conn: conn, cols: 80, rows: 24}
+	var out syncBuffer
+	ln.Close()
+	(<-conns).Close()
+
+	if err := attachLoop("worker", ac, &out, attachOptions{}, make(chan struct{})); err != nil {
+		t.Fatalf("attachLoop = %v, want nil", err)
+	}
+	if strings.Contains(out.String(), "reconnecting") {
+		t.Errorf("should not reconnect without --reconnect, got %q", out.String())
+	}
+}
@@ -22,7 +22,7 @@ func newAuthCmd() *cobra.Command {
 }
 
 func newAuthClaudeCmd() *cobra.Command {
-	return &cobra.Command{
+	cmd := &cobra.Command{
 		Use:   "claude [config-dir]",
 		Short: "Authenticate a Claude config directory",
 		Long: `Authenticate a Claude config directory for use with h2 agents.
@@ -33,15 +33,26 @@ If no config-dir is provided, authenticates the default shared config:
 You can also specify a custom config directory:
   h2 auth claude ~/.h2/claude-config/custom
 
+or a named profile, as referenced by a role's claude_profile:
+  h2 auth claude --profile work
+
 This will launch an interactive Claude session where you can run /login.`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: runAuthClaude,
 	}
+	cmd.Flags().String("profile", "", "Authenticate the named profile (~/.h2/claude-config/<profile>)")
+	return cmd
 }
 
 func runAuthClaude(cmd *cobra.Command, args []string) error {
 	var configDir string
-	if len(args) > 0 {
+	profile, _ := cmd.Flags().GetString("profile")
+	if profile != "" {
+		if len(args) > 0 {
+			return fmt.Errorf("--profile cannot be combined with a config-dir argument")
+		}
+		configDir = config.ClaudeProfileDir(profile)
+	} else if len(args) > 0 {
 		configDir = args[0]
 	} else {
 		configDir = config.DefaultClaudeConfigDir()
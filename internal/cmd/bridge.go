@@ -1,11 +1,16 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"h2/internal/bridge"
+	"h2/internal/bridge/telegram"
 	"h2/internal/bridgeservice"
 	"h2/internal/config"
 	"h2/internal/tmpl"
@@ -18,6 +23,7 @@ func newBridgeCmd() *cobra.Command {
 	var noConcierge bool
 	var setConcierge string
 	var roleName string
+	var defaultAgent string
 
 	cmd := &cobra.Command{
 		Use:   "bridge [--no-concierge | --set-concierge <name>] [--role <name>]",
@@ -28,7 +34,11 @@ func newBridgeCmd() *cobra.Command {
 By default, also starts a concierge session (named "concierge") using the
 "concierge" role and attaches to it interactively. Use --no-concierge to run
 only the bridge service with no default routing. Use --set-concierge <name>
-to route to an existing agent without spawning a new session.`,
+to route to an existing agent without spawning a new session.
+
+Unaddressed messages (no "agent:" prefix and not a reply to a tagged message)
+go to the concierge. Use --default-agent <name> to send them to a specific
+agent instead; prefixes and replies still override it.`,
 		Args: cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if noConcierge && setConcierge != "" {
@@ -69,7 +79,7 @@ to route to an existing agent without spawning a new session.`,
 
 			// Fork the bridge service as a background daemon.
 			fmt.Fprintf(os.Stderr, "Starting bridge service for user %q...\n", user)
-			if err := bridgeservice.ForkBridge(user, concierge); err != nil {
+			if err := bridgeservice.ForkBridge(user, concierge, defaultAgent); err != nil {
 				return err
 			}
 			fmt.Fprintf(os.Stderr, "Bridge service started.\n")
@@ -88,7 +98,7 @@ to route to an existing agent without spawning a new session.`,
 			if err != nil {
 				return fmt.Errorf("concierge role not found; create one with: h2 role init concierge")
 			}
-			return setupAndForkAgent(conciergeSessionName, role, false, "", nil)
+			return setupAndForkAgent(agentLaunch{Name: conciergeSessionName, Role: role})
 		},
 	}
 
@@ -96,10 +106,94 @@ to route to an existing agent without spawning a new session.`,
 	cmd.Flags().BoolVar(&noConcierge, "no-concierge", false, "Run without a concierge session")
 	cmd.Flags().StringVar(&setConcierge, "set-concierge", "", "Route to an existing concierge agent by name")
 	cmd.Flags().StringVar(&roleName, "role", "concierge", "Role to use for the concierge session")
+	cmd.Flags().StringVar(&defaultAgent, "default-agent", "", "Route unaddressed messages to this agent")
+
+	cmd.AddCommand(newBridgeTestCmd())
+
+	return cmd
+}
+
+func newBridgeTestCmd() *cobra.Command {
+	var forUser string
+	var text string
+
+	cmd := &cobra.Command{
+		Use:   "test [--for <user>] [--text <message>]",
+		Short: "Check the bridge configuration without starting it",
+		Long: `Checks a user's bridge configuration without polling for messages.
+Telegram tokens are validated with getMe and the bot identity, chat ID, and
+allowed commands are reported. Use --text to see how an incoming message
+would be routed (slash command, agent prefix, or unaddressed).`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			user, userCfg, err := resolveUser(cfg, forUser)
+			if err != nil {
+				return err
+			}
+			bridges := bridgeservice.FromConfig(&userCfg.Bridges)
+			if len(bridges) == 0 {
+				return fmt.Errorf("no bridges configured for user %q", user)
+			}
+			fmt.Printf("User: %s\n", user)
+			return reportBridges(cmd.Context(), os.Stdout, bridges, text)
+		},
+	}
+
+	cmd.Flags().StringVar(&forUser, "for", "", "Which user's bridge config to load")
+	cmd.Flags().StringVar(&text, "text", "", "Show how this incoming message would be routed")
 
 	return cmd
 }
 
+// reportBridges writes a check of each bridge to w and, when text is set,
+// how that message would be routed. It returns an error if any bridge
+// failed its check (e.g. an invalid Telegram token).
+func reportBridges(ctx context.Context, w io.Writer, bridges []bridge.Bridge, text string) error {
+	var failed error
+	var allowed []string
+	for _, b := range bridges {
+		switch b := b.(type) {
+		case *telegram.Telegram:
+			allowed = b.AllowedCommands
+			fmt.Fprintln(w, "Telegram:")
+			bot, err := b.GetMe(ctx)
+			if err != nil {
+				fmt.Fprintf(w, "  Bot: invalid token (%v)\n", err)
+				failed = fmt.Errorf("telegram: bot token rejected: %w", err)
+			} else {
+				fmt.Fprintf(w, "  Bot: @%s (%s, id %d)\n", bot.Username, bot.FirstName, bot.ID)
+			}
+			fmt.Fprintf(w, "  Chat ID: %d\n", b.ChatID)
+			if len(b.AllowedCommands) > 0 {
+				fmt.Fprintf(w, "  Allowed Commands: %s\n", strings.Join(b.AllowedCommands, ", "))
+			} else {
+				fmt.Fprintln(w, "  Allowed Commands: (none)")
+			}
+		default:
+			fmt.Fprintf(w, "%s: enabled\n", b.Name())
+		}
+	}
+
+	if text != "" {
+		fmt.Fprintf(w, "\nRouting %q:\n", text)
+		if command, args := bridge.ParseSlashCommand(text, allowed); command != "" {
+			fmt.Fprintf(w, "  Command: %s\n", strings.TrimSpace("/"+command+" "+args))
+		} else {
+			agent, body := bridge.ParseAgentPrefix(text)
+			if agent == "" {
+				agent = "(unaddressed: --default-agent or the concierge)"
+			}
+			fmt.Fprintf(w, "  Agent: %s\n", agent)
+			fmt.Fprintf(w, "  Body: %s\n", body)
+		}
+	}
+	return failed
+}
+
 // resolveUser determines which user config to use.
 func resolveUser(cfg *config.Config, forUser string) (string, *config.UserConfig, error) {
 	if forUser != "" {
@@ -16,6 +16,7 @@ import (
 func newBridgeDaemonCmd() *cobra.Command {
 	var forUser string
 	var concierge string
+	var defaultAgent string
 
 	cmd := &cobra.Command{
 		Use:    "_bridge-service",
@@ -38,6 +39,7 @@ func newBridgeDaemonCmd() *cobra.Command {
 			}
 
 			svc := bridgeservice.New(bridges, concierge, socketdir.Dir(), user)
+			svc.DefaultAgent = defaultAgent
 
 			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 			defer cancel()
@@ -48,6 +50,7 @@ func newBridgeDaemonCmd() *cobra.Command {
 
 	cmd.Flags().StringVar(&forUser, "for", "", "Which user's bridge config to load")
 	cmd.Flags().StringVar(&concierge, "concierge", "", "Concierge session name")
+	cmd.Flags().StringVar(&defaultAgent, "default-agent", "", "Agent that receives unaddressed messages")
 
 	return cmd
 }
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"h2/internal/bridge"
+	"h2/internal/bridge/telegram"
+)
+
+func TestReportBridges_ValidToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/getMe") {
+			t.Errorf("bridge test should only call getMe, got %s", r.URL.Path)
+		}
+		w.Write([]byte(`{"ok":true,"result":{"id":123,"first_name":"H2","username":"h2_bot"}}`))
+	}))
+	defer srv.Close()
+
+	tg := &telegram.Telegram{Token: "TOKEN", ChatID: 42, AllowedCommands: []string{"h2"}, BaseURL: srv.URL}
+	var out bytes.Buffer
+	if err := reportBridges(context.Background(), &out, []bridge.Bridge{tg}, "Coder: run the tests"); err != nil {
+		t.Fatalf("reportBridges: %v", err)
+	}
+	for _, want := range []string{
+		"Bot: @h2_bot (H2, id 123)",
+		"Chat ID: 42",
+		"Allowed Commands: h2",
+		"Agent: coder",
+		"Body: run the tests",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	reportBridges(context.Background(), &out, []bridge.Bridge{tg}, "/h2 list")
+	if !strings.Contains(out.String(), "Command: /h2 list") {
+		t.Errorf("slash command not recognized:\n%s", out.String())
+	}
+}
+
+func TestReportBridges_InvalidToken(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"ok":false,"error_code":401,"description":"Unauthorized"}`))
+	}))
+	defer srv.Close()
+
+	tg := &telegram.Telegram{Token: "BAD", ChatID: 42, BaseURL: srv.URL}
+	var out bytes.Buffer
+	err := reportBridges(context.Background(), &out, []bridge.Bridge{tg}, "")
+	if err == nil {
+		t.Fatal("expected error for an invalid token")
+	}
+	if !strings.Contains(err.Error(), "bot token rejected") || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Errorf("error = %q, want a clear token rejection", err.Error())
+	}
+	if !strings.Contains(out.String(), "Chat ID: 42") {
+		t.Errorf("config should still be reported:\n%s", out.String())
+	}
+}
@@ -8,8 +8,11 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"h2/internal/activitylog"
 	"h2/internal/config"
 	"h2/internal/session"
+	"h2/internal/session/message"
+	"h2/internal/session/virtualterminal"
 )
 
 func newDaemonCmd() *cobra.Command {
@@ -24,9 +27,40 @@ func newDaemonCmd() *cobra.Command {
 	var permissionMode string
 	var allowedTools []string
 	var disallowedTools []string
+	var extraArgs []string
 	var heartbeatIdleTimeout string
 	var heartbeatMessage string
 	var heartbeatCondition string
+	var heartbeatConditionTimeout string
+	var heartbeatEscalateAfter int
+	var heartbeatEscalateMessage string
+	var heartbeatEscalateCommand string
+	var heartbeatPriority string
+	var escalateAfter time.Duration
+	var messageAging time.Duration
+	var dedupeWindow time.Duration
+	var noConfirmQuit bool
+	var drain bool
+	var shutdownGrace time.Duration
+	var noPassthrough bool
+	var writeLock bool
+	var maxInputLen int
+	var submitNewline string
+	var modifierEnter string
+	var inputPlaceholder string
+	var durationPrecision string
+	var scrollOnOutput string
+	var rows, cols int
+	var readyRegex string
+	var onMessage string
+	var startMessage string
+	var startMessagePriority string
+	var noHooks bool
+	var passthroughIdle time.Duration
+	var idleThreshold time.Duration
+	var submitDelay string
+	var statusFile string
+	var activityLog activitylog.Rotation
 	var overrides []string
 
 	cmd := &cobra.Command{
@@ -46,10 +80,51 @@ func newDaemonCmd() *cobra.Command {
 					return fmt.Errorf("invalid --heartbeat-idle-timeout: %w", err)
 				}
 				heartbeat = session.DaemonHeartbeat{
-					IdleTimeout: d,
-					Message:     heartbeatMessage,
-					Condition:   heartbeatCondition,
+					IdleTimeout:     d,
+					Message:         heartbeatMessage,
+					Condition:       heartbeatCondition,
+					Priority:        heartbeatPriority,
+					EscalateAfter:   heartbeatEscalateAfter,
+					EscalateMessage: heartbeatEscalateMessage,
+					EscalateCommand: heartbeatEscalateCommand,
 				}
+				if heartbeatConditionTimeout != "" {
+					ct, err := time.ParseDuration(heartbeatConditionTimeout)
+					if err != nil {
+						return fmt.Errorf("invalid --heartbeat-condition-timeout: %w", err)
+					}
+					heartbeat.ConditionTimeout = ct
+				}
+			}
+
+			if _, ok := message.ParsePriority(heartbeatPriority); heartbeatPriority != "" && !ok {
+				return fmt.Errorf("invalid --heartbeat-priority %q (want interrupt, normal, idle-first, or idle)", heartbeatPriority)
+			}
+			if _, ok := message.ParsePriority(startMessagePriority); startMessagePriority != "" && !ok {
+				return fmt.Errorf("invalid --start-message-priority %q (want interrupt, normal, idle-first, or idle)", startMessagePriority)
+			}
+			if _, ok := virtualterminal.ParseSubmitNewline(submitNewline); !ok {
+				return fmt.Errorf("invalid --submit-newline %q (want cr, lf, or crlf)", submitNewline)
+			}
+			if d, err := time.ParseDuration(submitDelay); submitDelay != "" && (err != nil || d < 0) {
+				return fmt.Errorf("invalid --submit-delay %q (want a duration like 50ms, or 0)", submitDelay)
+			}
+			if _, ok := virtualterminal.ParseEnterAction(modifierEnter); !ok {
+				return fmt.Errorf("invalid --modifier-enter %q (want insert_newline, forward_cr, or forward_lf)", modifierEnter)
+			}
+			if _, ok := virtualterminal.ParseDurationPrecision(durationPrecision); !ok {
+				return fmt.Errorf("invalid --duration-precision %q (want compact or full)", durationPrecision)
+			}
+			if err := session.ValidateDaemonSize(rows, cols); err != nil {
+				return fmt.Errorf("invalid --rows/--cols: %w", err)
+			}
+			if _, ok := virtualterminal.ParseScrollPolicy(scrollOnOutput); !ok {
+				return fmt.Errorf("invalid --scroll-on-output %q (want stay or follow)", scrollOnOutput)
+			}
+
+			childEnv, err := session.TakeRoleEnv()
+			if err != nil {
+				return err
 			}
 
 			// Parse override key=value strings into a map for metadata.
@@ -62,22 +137,50 @@ func newDaemonCmd() *cobra.Command {
 				}
 			}
 
-			err := session.RunDaemon(session.RunDaemonOpts{
-				Name:            name,
-				SessionID:       sessionID,
-				Command:         args[0],
-				Args:            args[1:],
-				RoleName:        roleName,
-				SessionDir:      sessionDir,
-				ClaudeConfigDir: claudeConfigDir,
-				Instructions:    instructions,
-				SystemPrompt:    systemPrompt,
-				Model:           model,
-				PermissionMode:  permissionMode,
-				AllowedTools:    allowedTools,
-				DisallowedTools: disallowedTools,
-				Heartbeat:       heartbeat,
-				Overrides:       overrideMap,
+			err = session.RunDaemon(session.RunDaemonOpts{
+				Name:                 name,
+				SessionID:            sessionID,
+				Command:              args[0],
+				Args:                 args[1:],
+				RoleName:             roleName,
+				SessionDir:           sessionDir,
+				ClaudeConfigDir:      claudeConfigDir,
+				Instructions:         instructions,
+				SystemPrompt:         systemPrompt,
+				Model:                model,
+				PermissionMode:       permissionMode,
+				AllowedTools:         allowedTools,
+				DisallowedTools:      disallowedTools,
+				ExtraArgs:            extraArgs,
+				Heartbeat:            heartbeat,
+				EscalateAfter:        escalateAfter,
+				MessageAging:         messageAging,
+				DedupeWindow:         dedupeWindow,
+				NoConfirmQuit:        noConfirmQuit,
+				DrainOnQuit:          drain,
+				ShutdownGrace:        shutdownGrace,
+				NoPassthrough:        noPassthrough,
+				WriteLock:            writeLock,
+				MaxInputLen:          maxInputLen,
+				SubmitNewline:        submitNewline,
+				SubmitDelay:          submitDelay,
+				ModifierEnter:        modifierEnter,
+				InputPlaceholder:     inputPlaceholder,
+				DurationPrecision:    durationPrecision,
+				ScrollOnOutput:       scrollOnOutput,
+				Rows:                 rows,
+				Cols:                 cols,
+				ReadyRegex:           readyRegex,
+				OnMessage:            onMessage,
+				StartMessage:         startMessage,
+				StartMessagePriority: startMessagePriority,
+				NoHooks:              noHooks,
+				PassthroughIdle:      passthroughIdle,
+				IdleThreshold:        idleThreshold,
+				StatusFile:           statusFile,
+				ActivityLog:          activityLog,
+				Env:                  childEnv,
+				Overrides:            overrideMap,
 			})
 			if err != nil {
 				if _, ok := err.(*exec.ExitError); ok {
@@ -100,9 +203,43 @@ func newDaemonCmd() *cobra.Command {
 	cmd.Flags().StringVar(&permissionMode, "permission-mode", "", "Permission mode to pass via --permission-mode")
 	cmd.Flags().StringArrayVar(&allowedTools, "allowed-tool", nil, "Allowed tool (repeatable)")
 	cmd.Flags().StringArrayVar(&disallowedTools, "disallowed-tool", nil, "Disallowed tool (repeatable)")
+	cmd.Flags().StringArrayVar(&extraArgs, "extra-arg", nil, "Extra argument appended to the child command (repeatable)")
 	cmd.Flags().StringVar(&heartbeatIdleTimeout, "heartbeat-idle-timeout", "", "Heartbeat idle timeout duration")
 	cmd.Flags().StringVar(&heartbeatMessage, "heartbeat-message", "", "Heartbeat nudge message")
 	cmd.Flags().StringVar(&heartbeatCondition, "heartbeat-condition", "", "Heartbeat condition command")
+	cmd.Flags().StringVar(&heartbeatConditionTimeout, "heartbeat-condition-timeout", "", "Heartbeat condition command timeout")
+	cmd.Flags().IntVar(&heartbeatEscalateAfter, "heartbeat-escalate-after", 0, "Unanswered heartbeat nudges before escalating (0 = never)")
+	cmd.Flags().StringVar(&heartbeatEscalateMessage, "heartbeat-escalate-message", "", "Heartbeat escalation message (sent at interrupt priority)")
+	cmd.Flags().StringVar(&heartbeatEscalateCommand, "heartbeat-escalate-command", "", "Heartbeat escalation command")
+	cmd.Flags().StringVar(&heartbeatPriority, "heartbeat-priority", "", "Heartbeat nudge priority: interrupt, normal, idle-first, or idle")
+	cmd.Flags().DurationVar(&escalateAfter, "escalate-after", 0, "Default escalation window for idle-priority messages")
+	cmd.Flags().DurationVar(&messageAging, "message-aging", 0, "Promote queued messages one priority level after waiting this long (0 = off)")
+	cmd.Flags().DurationVar(&dedupeWindow, "dedupe-window", 0, "Drop a message identical to one queued within this long (0 = off)")
+	cmd.Flags().BoolVar(&noConfirmQuit, "no-confirm-quit", false, "Quit from the menu without confirmation")
+	cmd.Flags().BoolVar(&drain, "drain", false, "Deliver queued messages before a menu Quit stops the agent")
+	cmd.Flags().DurationVar(&shutdownGrace, "shutdown-grace", 0, "Bound on draining and on SIGTERM before SIGKILL at quit (0 = 10s)")
+	cmd.Flags().BoolVar(&noPassthrough, "no-passthrough", false, "Disallow clients from entering passthrough mode")
+	cmd.Flags().BoolVar(&writeLock, "write-lock", false, "Let only one attached client at a time send input, with request/grant handoff")
+	cmd.Flags().IntVar(&maxInputLen, "max-input-bytes", 0, "Input bar length cap (0 uses the default)")
+	cmd.Flags().StringVar(&submitNewline, "submit-newline", "", "Bytes sent to submit input: cr, lf, or crlf")
+	cmd.Flags().StringVar(&submitDelay, "submit-delay", "", "Pause between typed text and the submit bytes (\"\" = 50ms, 0 = none)")
+	cmd.Flags().StringVar(&modifierEnter, "modifier-enter", "", "Shift+Enter / Alt+Enter action: insert_newline, forward_cr, or forward_lf")
+	cmd.Flags().StringVar(&inputPlaceholder, "input-placeholder", "", "Dim hint shown in the input bar while it is empty")
+	cmd.Flags().StringVar(&durationPrecision, "duration-precision", "", "Status bar idle time format: compact or full")
+	cmd.Flags().IntVar(&rows, "rows", 0, "Initial PTY rows until a client attaches (0 = 24)")
+	cmd.Flags().IntVar(&cols, "cols", 0, "Initial PTY columns until a client attaches (0 = 80)")
+	cmd.Flags().StringVar(&scrollOnOutput, "scroll-on-output", "", "Scroll mode on new output: stay or follow")
+	cmd.Flags().StringVar(&readyRegex, "ready-regex", "", "Screen pattern that marks the agent idle (prompt ready)")
+	cmd.Flags().StringVar(&onMessage, "on-message", "", "Shell command to run after each delivered message")
+	cmd.Flags().StringVar(&startMessage, "start-message", "", "Message to enqueue when the agent first goes idle")
+	cmd.Flags().StringVar(&startMessagePriority, "start-message-priority", "", "Start message priority: interrupt, normal, idle-first, or idle")
+	cmd.Flags().BoolVar(&noHooks, "no-hooks", false, "Agent runs without h2 hooks; derive state without the hook collector")
+	cmd.Flags().DurationVar(&passthroughIdle, "passthrough-idle-timeout", 0, "Release a passthrough lock idle for this long (0 = never)")
+	cmd.Flags().DurationVar(&idleThreshold, "idle-threshold", 0, "Quiet time before the agent counts as idle (0 = 2s)")
+	cmd.Flags().StringVar(&statusFile, "status-file", "", "Write a one-line status to this file on each status tick")
+	cmd.Flags().Int64Var(&activityLog.MaxSize, "activity-log-max-size", 0, "Rotate the activity log past this many bytes (0 = 10MB)")
+	cmd.Flags().DurationVar(&activityLog.MaxAge, "activity-log-max-age", 0, "Rotate the activity log once it is this old (0 = daily)")
+	cmd.Flags().IntVar(&activityLog.MaxFiles, "activity-log-max-files", 0, "Rotated activity log files to keep (0 = 5)")
 	cmd.Flags().StringArrayVar(&overrides, "override", nil, "Override key=value pairs (internal)")
 
 	return cmd
@@ -0,0 +1,312 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/muesli/termenv"
+	"github.com/spf13/cobra"
+	"github.com/vito/midterm"
+
+	"h2/internal/session/message"
+	"h2/internal/socketdir"
+)
+
+// Timeouts for the dashboard HTTP server, so slow or idle clients can't
+// hold connections open indefinitely.
+const (
+	dashboardReadHeaderTimeout = 5 * time.Second
+	dashboardReadTimeout       = 10 * time.Second
+	dashboardWriteTimeout      = 30 * time.Second
+	dashboardIdleTimeout       = 60 * time.Second
+)
+
+func newDashboardCmd() *cobra.Command {
+	var addr string
+	var allowRemote bool
+
+	cmd := &cobra.Command{
+		Use:   "dashboard",
+		Short: "Serve a read-only web dashboard of running agents",
+		Long: `Start an HTTP server listing all running agents (state, pod, queue depth,
+last activity) with a read-only live view of each agent's screen.
+
+The server binds to localhost by default and refuses a non-loopback
+--dashboard-addr unless --allow-remote is given. Without --allow-remote,
+requests whose Host header isn't a loopback name are rejected, so a web
+page can't reach the dashboard through DNS rebinding. Endpoints:
+  /                  HTML agent list
+  /agents/<name>     live screen view (auto-refreshing)
+  /api/agents        agent list as JSON`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := checkDashboardAddr(addr, allowRemote); err != nil {
+				return err
+			}
+			handler := newDashboardHandler()
+			if !allowRemote {
+				handler = requireLoopbackHost(handler)
+			}
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				return fmt.Errorf("listen on %s: %w", addr, err)
+			}
+			fmt.Printf("Dashboard listening on http://%s\n", ln.Addr())
+			srv := &http.Server{
+				Handler:           handler,
+				ReadHeaderTimeout: dashboardReadHeaderTimeout,
+				ReadTimeout:       dashboardReadTimeout,
+				WriteTimeout:      dashboardWriteTimeout,
+				IdleTimeout:       dashboardIdleTimeout,
+			}
+			return srv.Serve(ln)
+		},
+	}
+
+	cmd.Flags().StringVar(&addr, "dashboard-addr", "127.0.0.1:7600", "Address for the dashboard HTTP server")
+	cmd.Flags().BoolVar(&allowRemote, "allow-remote", false, "Allow a non-loopback --dashboard-addr and any Host header")
+
+	return cmd
+}
+
+// checkDashboardAddr rejects a listen address that isn't loopback unless
+// allowRemote is set. The dashboard has no authentication.
+func checkDashboardAddr(addr string, allowRemote bool) error {
+	if allowRemote {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid --dashboard-addr %q: %w", addr, err)
+	}
+	if !isLoopbackHost(host) {
+		return fmt.Errorf("--dashboard-addr %q is not a loopback address; pass --allow-remote to serve the dashboard on other interfaces", addr)
+	}
+	return nil
+}
+
+// requireLoopbackHost rejects requests whose Host header names anything but
+// a loopback address, so a page on another origin can't reach the dashboard
+// by rebinding its own hostname to 127.0.0.1.
+func requireLoopbackHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if !isLoopbackHost(host) {
+			http.Error(w, "invalid Host header", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLoopbackHost reports whether host is "localhost" or a loopback IP.
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+// dashboardAgent is one row of the dashboard agent list.
+type dashboardAgent struct {
+	Name         string `json:"name"`
+	Pod          string `json:"pod,omitempty"`
+	State        string `json:"state"`
+	StateDisplay string `json:"state_display,omitempty"`
+	QueuedCount  int    `json:"queued_count"`
+	LastActivity string `json:"last_activity"` // time since the last state change
+	Reachable    bool   `json:"reachable"`
+}
+
+// newDashboardHandler returns the dashboard's HTTP routes.
+func newDashboardHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/agents", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(collectDashboardAgents())
+	})
+	mux.HandleFunc("GET /agents/{name}", serveDashboardScreen)
+	mux.HandleFunc("GET /{$}", serveDashboardIndex)
+	return mux
+}
+
+// collectDashboardAgents queries every agent socket for status.
+// Agents whose socket doesn't answer are listed as unreachable.
+func collectDashboardAgents() []dashboardAgent {
+	entries, err := socketdir.ListByType(socketdir.TypeAgent)
+	if err != nil {
+		return []dashboardAgent{}
+	}
+	agents := make([]dashboardAgent, 0, len(entries))
+	for _, e := range entries {
+		info := queryAgent(e.Path)
+		if info == nil {
+			agents = append(agents, dashboardAgent{Name: e.Name, State: "unreachable"})
+			continue
+		}
+		agents = append(agents, dashboardAgent{
+			Name:         info.Name,
+			Pod:          info.Pod,
+			State:        info.State,
+			StateDisplay: info.StateDisplayText,
+			QueuedCount:  info.QueuedCount,
+			LastActivity: info.StateDuration,
+			Reachable:    true,
+		})
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].Name < agents[j].Name })
+	return agents
+}
+
+func serveDashboardIndex(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	buf.WriteString(dashboardHead("h2 agents", 5))
+	buf.WriteString("<h1>h2 agents</h1>\n<table>\n<tr><th>Agent</th><th>Pod</th><th>State</th><th>Queued</th><th>Last activity</th></tr>\n")
+	for _, a := range collectDashboardAgents() {
+		name := html.EscapeString(a.Name)
+		state := a.StateDisplay
+		if state == "" {
+			state = a.State
+		}
+		fmt.Fprintf(&buf, "<tr><td><a href=\"/agents/%s\">%s</a></td><td>%s</td><td>%s</td><td>%d</td><td>%s</td></tr>\n",
+			name, name, html.EscapeString(a.Pod), html.EscapeString(state), a.QueuedCount, html.EscapeString(a.LastActivity))
+	}
+	buf.WriteString("</table>\n</body></html>\n")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+func serveDashboardScreen(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	screen, err := queryAgentScreen(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	vt := midterm.NewTerminal(screen.Rows, screen.Cols)
+	vt.Write([]byte(strings.ReplaceAll(screen.ANSI, "\n", "\r\n")))
+
+	var buf bytes.Buffer
+	buf.WriteString(dashboardHead("h2 — "+name, 2))
+	fmt.Fprintf(&buf, "<p><a href=\"/\">&larr; all agents</a></p>\n<h1>%s</h1>\n", html.EscapeString(name))
+	buf.WriteString(screenHTML(vt))
+	buf.WriteString("\n</body></html>\n")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(buf.Bytes())
+}
+
+// queryAgentScreen fetches the visible screen snapshot from an agent.
+func queryAgentScreen(name string) (*message.ScreenInfo, error) {
+	sockPath, err := socketdir.Find(name)
+	if err != nil {
+		return nil, fmt.Errorf("agent %q not found", name)
+	}
+	conn, err := net.DialTimeout("unix", sockPath, 2*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("agent %q not reachable", name)
+	}
+	defer conn.Close()
+
+	if err := message.SendRequest(conn, &message.Request{Type: "screen"}); err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	resp, err := message.ReadResponse(conn)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if !resp.OK || resp.Screen == nil {
+		return nil, fmt.Errorf("screen request failed: %s", resp.Error)
+	}
+	return resp.Screen, nil
+}
+
+func dashboardHead(title string, refreshSecs int) string {
+	return fmt.Sprintf(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><meta http-equiv="refresh" content="%d">
+<title>%s</title>
+<style>
+body { font-family: sans-serif; background: #111; color: #ddd; }
+a { color: #6cf; }
+table { border-collapse: collapse; }
+th, td { padding: 4px 12px; text-align: left; border-bottom: 1px solid #333; }
+pre.screen { background: #000; color: #ddd; padding: 8px; display: inline-block; }
+</style></head><body>
+`, refreshSecs, html.EscapeString(title))
+}
+
+// screenHTML renders a virtual terminal's content as an HTML <pre> block,
+// mapping SGR formatting to inline styles. Default colors are left unset so
+// the page's own colors apply.
+func screenHTML(vt *midterm.Terminal) string {
+	var buf bytes.Buffer
+	buf.WriteString(`<pre class="screen">`)
+	for row := 0; row < len(vt.Content); row++ {
+		line := vt.Content[row]
+		pos := 0
+		for region := range vt.Format.Regions(row) {
+			end := pos + region.Size
+			if pos >= len(line) {
+				break
+			}
+			if end > len(line) {
+				end = len(line)
+			}
+			text := html.EscapeString(string(line[pos:end]))
+			if css := formatCSS(region.F); css != "" {
+				fmt.Fprintf(&buf, `<span style="%s">%s</span>`, css, text)
+			} else {
+				buf.WriteString(text)
+			}
+			pos += region.Size
+		}
+		buf.WriteByte('\n')
+	}
+	buf.WriteString("</pre>")
+	return buf.String()
+}
+
+// formatCSS converts a midterm cell format to an inline CSS style.
+func formatCSS(f midterm.Format) string {
+	fg, bg := f.Fg, f.Bg
+	if f.IsReverse() {
+		fg, bg = bg, fg
+		if fg == nil {
+			fg = termenv.ANSIBlack
+		}
+		if bg == nil {
+			bg = termenv.ANSIWhite
+		}
+	}
+	var parts []string
+	if fg != nil {
+		parts = append(parts, "color:"+termenv.ConvertToRGB(fg).Hex())
+	}
+	if bg != nil {
+		parts = append(parts, "background-color:"+termenv.ConvertToRGB(bg).Hex())
+	}
+	if f.IsBold() {
+		parts = append(parts, "font-weight:bold")
+	}
+	if f.IsFaint() {
+		parts = append(parts, "opacity:0.6")
+	}
+	if f.IsItalic() {
+		parts = append(parts, "font-style:italic")
+	}
+	if f.IsUnderline() {
+		parts = append(parts, "text-decoration:underline")
+	}
+	return strings.Join(parts, ";")
+}
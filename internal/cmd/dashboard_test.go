@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vito/midterm"
+
+	"h2/internal/socketdir"
+)
+
+func TestDashboardAPIAgents(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	startStatusAgent(t, h2Root, "reviewer", func() string { return "active" })
+	startStatusAgent(t, h2Root, "coder", func() string { return "idle" })
+
+	rec := httptest.NewRecorder()
+	newDashboardHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/agents", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var agents []dashboardAgent
+	if err := json.Unmarshal(rec.Body.Bytes(), &agents); err != nil {
+		t.Fatalf("decode: %v\n%s", err, rec.Body.String())
+	}
+	if len(agents) != 2 {
+		t.Fatalf("got %d agents, want 2", len(agents))
+	}
+	if agents[0].Name != "coder" || agents[0].State != "idle" || !agents[0].Reachable {
+		t.Errorf("agents[0] = %+v", agents[0])
+	}
+	if agents[1].Name != "reviewer" || agents[1].State != "active" {
+		t.Errorf("agents[1] = %+v", agents[1])
+	}
+}
+
+func TestDashboardIndex_ListsUnreachable(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	startStatusAgent(t, h2Root, "coder", func() string { return "idle" })
+	// A stale socket path that refuses connections.
+	stale := filepath.Join(h2Root, "sockets", socketdir.Format(socketdir.TypeAgent, "ghost"))
+	if err := os.WriteFile(stale, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	newDashboardHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `<a href="/agents/coder">coder</a>`) {
+		t.Errorf("index missing coder link:\n%s", body)
+	}
+	if !strings.Contains(body, "unreachable") {
+		t.Errorf("index should mark ghost as unreachable:\n%s", body)
+	}
+}
+
+func TestCheckDashboardAddr(t *testing.T) {
+	for _, tc := range []struct {
+		addr        string
+		allowRemote bool
+		wantErr     bool
+	}{
+		{"127.0.0.1:7600", false, false},
+		{"localhost:7600", false, false},
+		{"[::1]:7600", false, false},
+		{"0.0.0.0:7600", false, true},
+		{":7600", false, true},
+		{"192.168.1.5:7600", false, true},
+		{"0.0.0.0:7600", true, false},
+		{"no-port", false, true},
+	} {
+		err := checkDashboardAddr(tc.addr, tc.allowRemote)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("checkDashboardAddr(%q, %v) error = %v, wantErr %v", tc.addr, tc.allowRemote, err, tc.wantErr)
+		}
+	}
+}
+
+func TestRequireLoopbackHost(t *testing.T) {
+	h := requireLoopbackHost(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for _, tc := range []struct {
+		host string
+		want int
+	}{
+		{"127.0.0.1:7600", http.StatusOK},
+		{"localhost:7600", http.StatusOK},
+		{"localhost", http.StatusOK},
+		{"[::1]:7600", http.StatusOK},
+		{"evil.example.com:7600", http.StatusForbidden},
+		{"10.0.0.2", http.StatusForbidden},
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Host = tc.host
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("Host %q: status = %d, want %d", tc.host, rec.Code, tc.want)
+		}
+	}
+}
+
+func TestScreenHTML_KnownBuffer(t *testing.T) {
+	vt := midterm.NewTerminal(2, 20)
+	vt.Write([]byte("\033[1;31merror\033[0m <ok>\r\nplain"))
+
+	out := screenHTML(vt)
+
+	if !strings.HasPrefix(out, `<pre class="screen">`) || !strings.HasSuffix(out, "</pre>") {
+		t.Fatalf("expected <pre> wrapper, got %q", out)
+	}
+	if !strings.Contains(out, `<span style="color:#800000;font-weight:bold">error</span>`) {
+		t.Errorf("expected bold red span for 'error', got %q", out)
+	}
+	if !strings.Contains(out, " &lt;ok&gt;") {
+		t.Errorf("expected HTML-escaped default-styled text, got %q", out)
+	}
+	if strings.Contains(out, "color:#000000") {
+		t.Errorf("default colors should not be emitted, got %q", out)
+	}
+	if !strings.Contains(out, "\nplain") {
+		t.Errorf("expected second row, got %q", out)
+	}
+}
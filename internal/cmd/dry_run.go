@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -9,6 +10,7 @@ import (
 
 	"h2/internal/config"
 	"h2/internal/session"
+	"h2/internal/session/agent"
 )
 
 // ResolvedAgentConfig holds all resolved values for an agent launch,
@@ -28,17 +30,22 @@ type ResolvedAgentConfig struct {
 	ChildArgs       []string
 	RoleScope       string            // "pod" or "global" — set by pod dry-run
 	MergedVars      map[string]string // final merged vars — set by pod dry-run
+	AgentExtraArgs  []string          // template agent's own extra_args — set by pod dry-run
 }
 
 // resolveAgentConfig computes all values needed to launch an agent without
 // performing any side effects (no dir creation, no worktree creation, no forking).
 func resolveAgentConfig(name string, role *config.Role, pod string, overrides []string) (*ResolvedAgentConfig, error) {
-	if name == "" {
-		name = session.GenerateName()
+	name, err := resolveAgentName(name, role.Name)
+	if err != nil {
+		return nil, err
 	}
 
 	claudeConfigDir := role.GetClaudeConfigDir()
-	cmdCommand := role.GetAgentType()
+	if role.NoHooks && claudeConfigDir != "" {
+		claudeConfigDir = config.NoHooksConfigDir(claudeConfigDir)
+	}
+	cmdCommand := role.GetCommand()
 
 	var heartbeat session.DaemonHeartbeat
 	if role.Heartbeat != nil {
@@ -46,25 +53,41 @@ func resolveAgentConfig(name string, role *config.Role, pod string, overrides []
 		if err != nil {
 			return nil, fmt.Errorf("invalid heartbeat idle_timeout: %w", err)
 		}
+		conditionTimeout, err := role.Heartbeat.ParseConditionTimeout()
+		if err != nil {
+			return nil, fmt.Errorf("invalid heartbeat condition_timeout: %w", err)
+		}
 		heartbeat = session.DaemonHeartbeat{
-			IdleTimeout: d,
-			Message:     role.Heartbeat.Message,
-			Condition:   role.Heartbeat.Condition,
+			IdleTimeout:      d,
+			Message:          role.Heartbeat.Message,
+			Condition:        role.Heartbeat.Condition,
+			ConditionTimeout: conditionTimeout,
+			Priority:         role.MessagePriority.Heartbeat,
+			EscalateAfter:    role.Heartbeat.EscalateAfter,
+			EscalateMessage:  role.Heartbeat.EscalateMessage,
+			EscalateCommand:  role.Heartbeat.EscalateCommand,
 		}
 	}
 
 	// Resolve the working directory without side effects.
 	var agentCWD string
 	var isWorktree bool
+	sandboxRoot, err := globalSandboxRoot()
+	if err != nil {
+		return nil, err
+	}
 	if role.Worktree != nil {
 		isWorktree = true
 		agentCWD = filepath.Join(config.WorktreesDir(), role.Worktree.Name)
+		if err := role.CheckSandboxed(agentCWD, sandboxRoot); err != nil {
+			return nil, fmt.Errorf("worktree: %w", err)
+		}
 	} else {
 		cwd, err := os.Getwd()
 		if err != nil {
 			return nil, fmt.Errorf("get working directory: %w", err)
 		}
-		agentCWD, err = role.ResolveWorkingDir(cwd)
+		agentCWD, err = role.ResolveSandboxedWorkingDir(cwd, sandboxRoot)
 		if err != nil {
 			return nil, fmt.Errorf("resolve working_dir: %w", err)
 		}
@@ -90,29 +113,35 @@ func resolveAgentConfig(name string, role *config.Role, pod string, overrides []
 	if pod != "" {
 		envVars["H2_POD"] = pod
 	}
+	for k, v := range role.Env {
+		envVars[k] = v
+	}
 
-	// Build child args: what the claude command would receive.
+	// Build child args: what the agent command would receive.
 	// Mirrors Session.childArgs() in session.go.
-	var childArgs []string
-	childArgs = append(childArgs, "--session-id", "<generated-uuid>")
-	if role.SystemPrompt != "" {
-		childArgs = append(childArgs, "--system-prompt", role.SystemPrompt)
-	}
-	if role.Instructions != "" {
-		childArgs = append(childArgs, "--append-system-prompt", role.Instructions)
-	}
-	if role.Model != "" {
-		childArgs = append(childArgs, "--model", role.Model)
-	}
-	if role.PermissionMode != "" {
-		childArgs = append(childArgs, "--permission-mode", role.PermissionMode)
-	}
-	if len(role.Permissions.Allow) > 0 {
-		childArgs = append(childArgs, "--allowedTools", strings.Join(role.Permissions.Allow, ","))
-	}
-	if len(role.Permissions.Deny) > 0 {
-		childArgs = append(childArgs, "--disallowedTools", strings.Join(role.Permissions.Deny, ","))
+	childArgs := append([]string(nil), role.Args...)
+	if agent.ResolveAgentType(cmdCommand).SupportsRoleFlags() {
+		childArgs = append([]string{"--session-id", "<generated-uuid>"}, childArgs...)
+		if role.SystemPrompt != "" {
+			childArgs = append(childArgs, "--system-prompt", role.SystemPrompt)
+		}
+		if role.Instructions != "" {
+			childArgs = append(childArgs, "--append-system-prompt", role.Instructions)
+		}
+		if role.Model != "" {
+			childArgs = append(childArgs, "--model", role.Model)
+		}
+		if role.PermissionMode != "" {
+			childArgs = append(childArgs, "--permission-mode", role.PermissionMode)
+		}
+		if len(role.Permissions.Allow) > 0 {
+			childArgs = append(childArgs, "--allowedTools", strings.Join(role.Permissions.Allow, ","))
+		}
+		if len(role.Permissions.Deny) > 0 {
+			childArgs = append(childArgs, "--disallowedTools", strings.Join(role.Permissions.Deny, ","))
+		}
 	}
+	childArgs = append(childArgs, role.ExtraArgs...)
 
 	return &ResolvedAgentConfig{
 		Name:            name,
@@ -165,7 +194,11 @@ func printDryRun(rc *ResolvedAgentConfig) {
 	// Instructions (truncated with line count).
 	if role.Instructions != "" {
 		lines := strings.Split(role.Instructions, "\n")
-		fmt.Printf("Instructions: (%d lines)\n", len(lines))
+		if agent.ResolveAgentType(rc.Command).SupportsRoleFlags() {
+			fmt.Printf("Instructions: (%d lines)\n", len(lines))
+		} else {
+			fmt.Printf("Instructions: (%d lines, sent as the first message)\n", len(lines))
+		}
 		const maxLines = 10
 		for i, line := range lines {
 			if i >= maxLines {
@@ -179,29 +212,19 @@ func printDryRun(rc *ResolvedAgentConfig) {
 	fmt.Println()
 	fmt.Printf("Command: %s\n", rc.Command)
 	if len(rc.ChildArgs) > 0 {
-		// Show args with long values truncated for readability.
-		var displayArgs []string
-		for i := 0; i < len(rc.ChildArgs); i++ {
-			if rc.ChildArgs[i] == "--system-prompt" && i+1 < len(rc.ChildArgs) {
-				displayArgs = append(displayArgs, rc.ChildArgs[i])
-				lines := strings.Count(rc.ChildArgs[i+1], "\n") + 1
-				displayArgs = append(displayArgs, fmt.Sprintf("<system-prompt: %d lines>", lines))
-				i++ // skip the value
-			} else if rc.ChildArgs[i] == "--append-system-prompt" && i+1 < len(rc.ChildArgs) {
-				displayArgs = append(displayArgs, rc.ChildArgs[i])
-				lines := strings.Count(rc.ChildArgs[i+1], "\n") + 1
-				displayArgs = append(displayArgs, fmt.Sprintf("<instructions: %d lines>", lines))
-				i++ // skip the value
-			} else {
-				displayArgs = append(displayArgs, rc.ChildArgs[i])
-			}
-		}
+		displayArgs := displayChildArgs(rc.ChildArgs)
 		fmt.Printf("Args: %s\n", strings.Join(displayArgs, " "))
 	}
 
 	fmt.Println()
 	if rc.IsWorktree {
 		fmt.Printf("Working Dir: %s (worktree)\n", rc.WorkingDir)
+	} else if _, err := os.Stat(rc.WorkingDir); os.IsNotExist(err) {
+		if rc.Role.CreateWorkingDir {
+			fmt.Printf("Working Dir: %s (will be created)\n", rc.WorkingDir)
+		} else {
+			fmt.Printf("Working Dir: %s (missing)\n", rc.WorkingDir)
+		}
 	} else {
 		fmt.Printf("Working Dir: %s\n", rc.WorkingDir)
 	}
@@ -209,6 +232,9 @@ func printDryRun(rc *ResolvedAgentConfig) {
 		fmt.Printf("Claude Config Dir: %s\n", rc.ClaudeConfigDir)
 	}
 	fmt.Printf("Session Dir: %s\n", rc.SessionDir)
+	if role.NoHooks {
+		fmt.Println("Hooks: disabled (no h2 hooks in settings.json)")
+	}
 
 	// Environment variables.
 	fmt.Println()
@@ -219,6 +245,16 @@ func printDryRun(rc *ResolvedAgentConfig) {
 			fmt.Printf("  %s=%s\n", key, val)
 		}
 	}
+	var roleEnvKeys []string
+	for key := range role.Env {
+		if key != "CLAUDE_CONFIG_DIR" {
+			roleEnvKeys = append(roleEnvKeys, key)
+		}
+	}
+	sort.Strings(roleEnvKeys)
+	for _, key := range roleEnvKeys {
+		fmt.Printf("  %s=%s\n", key, rc.EnvVars[key])
+	}
 
 	// Permissions.
 	perms := role.Permissions
@@ -234,6 +270,18 @@ func printDryRun(rc *ResolvedAgentConfig) {
 		if perms.Agent != nil {
 			fmt.Printf("  Agent Reviewer: %v\n", perms.Agent.IsEnabled())
 		}
+		if unknown := role.UnknownPermissionTools(); len(unknown) > 0 {
+			fmt.Printf("  Unknown tools: %s\n", strings.Join(unknown, ", "))
+		}
+	}
+
+	// Required binaries.
+	if len(role.Requires) > 0 {
+		fmt.Println()
+		fmt.Printf("Requires: %s\n", strings.Join(role.Requires, ", "))
+		if missing := role.MissingRequirements(); len(missing) > 0 {
+			fmt.Printf("  Missing from PATH: %s\n", strings.Join(missing, ", "))
+		}
 	}
 
 	// Heartbeat.
@@ -246,6 +294,21 @@ func printDryRun(rc *ResolvedAgentConfig) {
 		}
 		if rc.Heartbeat.Condition != "" {
 			fmt.Printf("  Condition: %s\n", rc.Heartbeat.Condition)
+			if rc.Heartbeat.ConditionTimeout > 0 {
+				fmt.Printf("  Condition Timeout: %s\n", rc.Heartbeat.ConditionTimeout)
+			}
+		}
+		if rc.Heartbeat.EscalateAfter > 0 {
+			fmt.Printf("  Escalate After: %d unanswered nudges\n", rc.Heartbeat.EscalateAfter)
+			if rc.Heartbeat.EscalateMessage != "" {
+				fmt.Printf("  Escalate Message: %s\n", rc.Heartbeat.EscalateMessage)
+			}
+			if rc.Heartbeat.EscalateCommand != "" {
+				fmt.Printf("  Escalate Command: %s\n", rc.Heartbeat.EscalateCommand)
+			}
+		}
+		if rc.Heartbeat.Priority != "" {
+			fmt.Printf("  Priority: %s\n", rc.Heartbeat.Priority)
 		}
 	}
 
@@ -254,6 +317,9 @@ func printDryRun(rc *ResolvedAgentConfig) {
 		fmt.Println()
 		fmt.Printf("Overrides: %s\n", strings.Join(rc.Overrides, ", "))
 	}
+	if len(rc.AgentExtraArgs) > 0 {
+		fmt.Printf("Agent Extra Args: %s\n", strings.Join(rc.AgentExtraArgs, " "))
+	}
 
 	// Merged vars (pod dry-run only).
 	if len(rc.MergedVars) > 0 {
@@ -275,6 +341,28 @@ func printDryRun(rc *ResolvedAgentConfig) {
 	}
 }
 
+// displayChildArgs returns the child args with prompt values replaced by a
+// line-count placeholder, for readability.
+func displayChildArgs(args []string) []string {
+	var displayArgs []string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--system-prompt" && i+1 < len(args) {
+			displayArgs = append(displayArgs, args[i])
+			lines := strings.Count(args[i+1], "\n") + 1
+			displayArgs = append(displayArgs, fmt.Sprintf("<system-prompt: %d lines>", lines))
+			i++ // skip the value
+		} else if args[i] == "--append-system-prompt" && i+1 < len(args) {
+			displayArgs = append(displayArgs, args[i])
+			lines := strings.Count(args[i+1], "\n") + 1
+			displayArgs = append(displayArgs, fmt.Sprintf("<instructions: %d lines>", lines))
+			i++ // skip the value
+		} else {
+			displayArgs = append(displayArgs, args[i])
+		}
+	}
+	return displayArgs
+}
+
 // printPodDryRun displays the full pod expansion without launching.
 func printPodDryRun(templateName string, pod string, agents []*ResolvedAgentConfig) {
 	fmt.Printf("Pod: %s\n", pod)
@@ -300,3 +388,171 @@ func printPodDryRun(templateName string, pod string, agents []*ResolvedAgentConf
 		printDryRun(rc)
 	}
 }
+
+// dryRunPreviewLines is how many lines of a prompt dry-run output shows.
+const dryRunPreviewLines = 10
+
+// dryRunText is a prompt truncated for --json output.
+type dryRunText struct {
+	Lines     int      `json:"lines"`
+	Preview   []string `json:"preview"`
+	Truncated bool     `json:"truncated,omitempty"`
+}
+
+// dryRunHeartbeat is the heartbeat section of --json output.
+type dryRunHeartbeat struct {
+	IdleTimeout      string `json:"idle_timeout"`
+	Message          string `json:"message,omitempty"`
+	Condition        string `json:"condition,omitempty"`
+	ConditionTimeout string `json:"condition_timeout,omitempty"`
+	Priority         string `json:"priority,omitempty"`
+	EscalateAfter    int    `json:"escalate_after,omitempty"`
+	EscalateMessage  string `json:"escalate_message,omitempty"`
+	EscalateCommand  string `json:"escalate_command,omitempty"`
+}
+
+// dryRunPermissions is the permissions section of --json output.
+type dryRunPermissions struct {
+	Allow         []string `json:"allow,omitempty"`
+	Deny          []string `json:"deny,omitempty"`
+	AgentReviewer *bool    `json:"agent_reviewer,omitempty"`
+	UnknownTools  []string `json:"unknown_tools,omitempty"`
+}
+
+// dryRunAgentJSON is the --json form of a ResolvedAgentConfig.
+type dryRunAgentJSON struct {
+	Name            string             `json:"name"`
+	Role            string             `json:"role"`
+	Description     string             `json:"description,omitempty"`
+	Model           string             `json:"model,omitempty"`
+	PermissionMode  string             `json:"permission_mode,omitempty"`
+	SystemPrompt    *dryRunText        `json:"system_prompt,omitempty"`
+	Instructions    *dryRunText        `json:"instructions,omitempty"`
+	Command         string             `json:"command"`
+	Args            []string           `json:"args"`
+	WorkingDir      string             `json:"working_dir"`
+	Worktree        bool               `json:"worktree"`
+	ClaudeConfigDir string             `json:"claude_config_dir,omitempty"`
+	SessionDir      string             `json:"session_dir"`
+	Env             map[string]string  `json:"env"`
+	Permissions     *dryRunPermissions `json:"permissions,omitempty"`
+	Requires        []string           `json:"requires,omitempty"`
+	Heartbeat       *dryRunHeartbeat   `json:"heartbeat,omitempty"`
+	Overrides       []string           `json:"overrides,omitempty"`
+	AgentExtraArgs  []string           `json:"agent_extra_args,omitempty"`
+	Vars            map[string]string  `json:"vars,omitempty"`
+	RoleScope       string             `json:"role_scope,omitempty"`
+}
+
+// dryRunPodJSON is the --json form of a pod dry-run.
+type dryRunPodJSON struct {
+	Pod      string             `json:"pod"`
+	Template string             `json:"template"`
+	Roles    []string           `json:"roles"`
+	Agents   []*dryRunAgentJSON `json:"agents"`
+}
+
+// newDryRunText truncates a prompt the same way the text output does.
+func newDryRunText(s string) *dryRunText {
+	if s == "" {
+		return nil
+	}
+	lines := strings.Split(s, "\n")
+	t := &dryRunText{Lines: len(lines), Preview: lines}
+	if len(lines) > dryRunPreviewLines {
+		t.Preview = lines[:dryRunPreviewLines]
+		t.Truncated = true
+	}
+	return t
+}
+
+// newDryRunAgentJSON converts a resolved config to its --json form.
+func newDryRunAgentJSON(rc *ResolvedAgentConfig) *dryRunAgentJSON {
+	role := rc.Role
+	out := &dryRunAgentJSON{
+		Name:            rc.Name,
+		Role:            role.Name,
+		Description:     role.Description,
+		Model:           role.Model,
+		PermissionMode:  role.PermissionMode,
+		SystemPrompt:    newDryRunText(role.SystemPrompt),
+		Instructions:    newDryRunText(role.Instructions),
+		Command:         rc.Command,
+		Args:            displayChildArgs(rc.ChildArgs),
+		WorkingDir:      rc.WorkingDir,
+		Worktree:        rc.IsWorktree,
+		ClaudeConfigDir: rc.ClaudeConfigDir,
+		SessionDir:      rc.SessionDir,
+		Env:             rc.EnvVars,
+		Requires:        role.Requires,
+		Overrides:       rc.Overrides,
+		AgentExtraArgs:  rc.AgentExtraArgs,
+		Vars:            rc.MergedVars,
+		RoleScope:       rc.RoleScope,
+	}
+	if out.Args == nil {
+		out.Args = []string{}
+	}
+	perms := role.Permissions
+	if len(perms.Allow) > 0 || len(perms.Deny) > 0 || perms.Agent != nil {
+		out.Permissions = &dryRunPermissions{
+			Allow:        perms.Allow,
+			Deny:         perms.Deny,
+			UnknownTools: role.UnknownPermissionTools(),
+		}
+		if perms.Agent != nil {
+			enabled := perms.Agent.IsEnabled()
+			out.Permissions.AgentReviewer = &enabled
+		}
+	}
+	if rc.Heartbeat.IdleTimeout > 0 {
+		out.Heartbeat = &dryRunHeartbeat{
+			IdleTimeout:     rc.Heartbeat.IdleTimeout.String(),
+			Message:         rc.Heartbeat.Message,
+			Condition:       rc.Heartbeat.Condition,
+			Priority:        rc.Heartbeat.Priority,
+			EscalateAfter:   rc.Heartbeat.EscalateAfter,
+			EscalateMessage: rc.Heartbeat.EscalateMessage,
+			EscalateCommand: rc.Heartbeat.EscalateCommand,
+		}
+		if rc.Heartbeat.ConditionTimeout > 0 {
+			out.Heartbeat.ConditionTimeout = rc.Heartbeat.ConditionTimeout.String()
+		}
+	}
+	return out
+}
+
+// printDryRunJSON writes the resolved agent configuration as JSON.
+func printDryRunJSON(rc *ResolvedAgentConfig) error {
+	out, err := json.MarshalIndent(newDryRunAgentJSON(rc), "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+// printPodDryRunJSON writes the full pod expansion as JSON.
+func printPodDryRunJSON(templateName string, pod string, agents []*ResolvedAgentConfig) error {
+	doc := dryRunPodJSON{
+		Pod:      pod,
+		Template: templateName,
+		Roles:    []string{},
+		Agents:   []*dryRunAgentJSON{},
+	}
+	roleSet := make(map[string]bool)
+	for _, rc := range agents {
+		if !roleSet[rc.Role.Name] {
+			roleSet[rc.Role.Name] = true
+			doc.Roles = append(doc.Roles, rc.Role.Name)
+		}
+		doc.Agents = append(doc.Agents, newDryRunAgentJSON(rc))
+	}
+	sort.Strings(doc.Roles)
+	out, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(out))
+	return nil
+}
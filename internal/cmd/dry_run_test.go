@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -49,6 +50,55 @@ func TestResolveAgentConfig_Basic(t *testing.T) {
 	}
 }
 
+func TestResolveAgentConfig_RejectsInvalidName(t *testing.T) {
+	t.Setenv("H2_DIR", "")
+	role := &config.Role{Name: "coder", Instructions: "Write code"}
+
+	for _, name := range []string{"../escape", "has space", "-lead"} {
+		if _, err := resolveAgentConfig(name, role, "", nil); err == nil || !strings.Contains(err.Error(), "invalid agent name") {
+			t.Errorf("name %q: expected invalid agent name error, got %v", name, err)
+		}
+	}
+}
+
+func TestResolveAgentConfig_NameSchemeFromConfig(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	os.WriteFile(filepath.Join(h2Root, "config.yaml"), []byte("name_scheme: role-n\n"), 0o644)
+	role := &config.Role{Name: "coder", Instructions: "Write code"}
+
+	rc, err := resolveAgentConfig("", role, "", nil)
+	if err != nil {
+		t.Fatalf("resolveAgentConfig: %v", err)
+	}
+	if rc.Name != "coder-1" {
+		t.Errorf("Name = %q, want coder-1 from name_scheme role-n", rc.Name)
+	}
+}
+
+func TestPrintDryRun_ShowsMissingRequirements(t *testing.T) {
+	t.Setenv("H2_DIR", "")
+
+	role := &config.Role{
+		Name:         "coder",
+		Instructions: "Write code",
+		Requires:     []string{"sh", "h2-no-such-binary"},
+	}
+	rc, err := resolveAgentConfig("coder-1", role, "", nil)
+	if err != nil {
+		t.Fatalf("resolveAgentConfig: %v", err)
+	}
+
+	output := captureStdout(func() {
+		printDryRun(rc)
+	})
+	if !strings.Contains(output, "Requires: sh, h2-no-such-binary") {
+		t.Errorf("output should list required binaries, got:\n%s", output)
+	}
+	if !strings.Contains(output, "Missing from PATH: h2-no-such-binary\n") {
+		t.Errorf("output should flag the missing binary, got:\n%s", output)
+	}
+}
+
 func TestResolveAgentConfig_WithPod(t *testing.T) {
 	t.Setenv("H2_DIR", "")
 
@@ -88,6 +138,119 @@ func TestResolveAgentConfig_NoPodEnvWhenEmpty(t *testing.T) {
 	}
 }
 
+func TestResolveAgentConfig_RoleEnv(t *testing.T) {
+	t.Setenv("H2_DIR", "")
+
+	role := &config.Role{
+		Name:            "test-role",
+		Instructions:    "Test instructions",
+		ClaudeConfigDir: "/derived",
+		Env:             map[string]string{"ANTHROPIC_MODEL": "opus", "CLAUDE_CONFIG_DIR": "/explicit"},
+	}
+
+	rc, err := resolveAgentConfig("test-agent", role, "", nil)
+	if err != nil {
+		t.Fatalf("resolveAgentConfig: %v", err)
+	}
+	if rc.EnvVars["ANTHROPIC_MODEL"] != "opus" {
+		t.Errorf("ANTHROPIC_MODEL = %q, want opus", rc.EnvVars["ANTHROPIC_MODEL"])
+	}
+	if rc.EnvVars["CLAUDE_CONFIG_DIR"] != "/explicit" {
+		t.Errorf("CLAUDE_CONFIG_DIR = %q, want the role env to win", rc.EnvVars["CLAUDE_CONFIG_DIR"])
+	}
+
+	output := capturePrintDryRun(rc)
+	for _, check := range []string{"ANTHROPIC_MODEL=opus", "CLAUDE_CONFIG_DIR=/explicit"} {
+		if !strings.Contains(output, check) {
+			t.Errorf("output should contain %q, got:\n%s", check, output)
+		}
+	}
+	if strings.Count(output, "CLAUDE_CONFIG_DIR=") != 1 {
+		t.Errorf("CLAUDE_CONFIG_DIR should be listed once, got:\n%s", output)
+	}
+}
+
+func TestResolveAgentConfig_GenericCommand(t *testing.T) {
+	t.Setenv("H2_DIR", "")
+
+	role := &config.Role{
+		Name:         "test-role",
+		Command:      "aider",
+		Args:         []string{"--no-auto-commits"},
+		Instructions: "Test instructions",
+		Model:        "opus",
+		ExtraArgs:    []string{"--yes"},
+	}
+
+	rc, err := resolveAgentConfig("test-agent", role, "", nil)
+	if err != nil {
+		t.Fatalf("resolveAgentConfig: %v", err)
+	}
+	if rc.Command != "aider" {
+		t.Errorf("Command = %q, want aider", rc.Command)
+	}
+	want := []string{"--no-auto-commits", "--yes"}
+	if strings.Join(rc.ChildArgs, " ") != strings.Join(want, " ") {
+		t.Errorf("ChildArgs = %v, want %v (no claude flags)", rc.ChildArgs, want)
+	}
+
+	output := capturePrintDryRun(rc)
+	if !strings.Contains(output, "sent as the first message") {
+		t.Errorf("output should note instructions are sent as a message, got:\n%s", output)
+	}
+}
+
+func TestPrintDryRunJSON(t *testing.T) {
+	t.Setenv("H2_DIR", "")
+
+	instructions := strings.Repeat("line\n", 14) + "last"
+	role := &config.Role{
+		Name:         "test-role",
+		Instructions: instructions,
+		Model:        "opus",
+		Permissions: config.Permissions{
+			Allow: []string{"Read"},
+		},
+		Heartbeat: &config.HeartbeatConfig{IdleTimeout: "30s", Message: "ping"},
+	}
+	rc, err := resolveAgentConfig("test-agent", role, "", nil)
+	if err != nil {
+		t.Fatalf("resolveAgentConfig: %v", err)
+	}
+
+	output := captureStdout(func() {
+		if err := printDryRunJSON(rc); err != nil {
+			t.Fatalf("printDryRunJSON: %v", err)
+		}
+	})
+	var got dryRunAgentJSON
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+	if got.Name != "test-agent" || got.Command != "claude" || got.Model != "opus" {
+		t.Errorf("unexpected agent fields: %+v", got)
+	}
+	if got.Instructions == nil || got.Instructions.Lines != 15 || len(got.Instructions.Preview) != 10 || !got.Instructions.Truncated {
+		t.Errorf("Instructions = %+v, want 15 lines truncated to 10", got.Instructions)
+	}
+	if strings.Contains(output, "last") {
+		t.Errorf("instructions past the preview should be omitted:\n%s", output)
+	}
+	joined := strings.Join(got.Args, " ")
+	if !strings.Contains(joined, "--append-system-prompt <instructions: 15 lines>") {
+		t.Errorf("Args should redact instructions, got %v", got.Args)
+	}
+	if got.Env["H2_ACTOR"] != "test-agent" {
+		t.Errorf("Env = %v", got.Env)
+	}
+	if got.Permissions == nil || len(got.Permissions.Allow) != 1 {
+		t.Errorf("Permissions = %+v", got.Permissions)
+	}
+	if got.Heartbeat == nil || got.Heartbeat.IdleTimeout != "30s" || got.Heartbeat.Message != "ping" {
+		t.Errorf("Heartbeat = %+v", got.Heartbeat)
+	}
+}
+
 func TestResolveAgentConfig_GeneratesName(t *testing.T) {
 	t.Setenv("H2_DIR", "")
 
@@ -716,6 +879,89 @@ agents:
 	}
 }
 
+func TestPodDryRun_JSON(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+
+	tmplContent := `pod_name: test-pod
+agents:
+  - name: builder
+    role: default
+  - name: tester
+    role: default
+`
+	os.WriteFile(filepath.Join(h2Root, "pods", "templates", "simple.yaml"), []byte(tmplContent), 0o644)
+	roleContent := "name: default\ninstructions: |\n  Do work.\n"
+	os.WriteFile(filepath.Join(h2Root, "roles", "default.yaml"), []byte(roleContent), 0o644)
+
+	output := captureStdout(func() {
+		cmd := newPodLaunchCmd()
+		cmd.SetArgs([]string{"--dry-run", "--json", "simple"})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	var doc dryRunPodJSON
+	if err := json.Unmarshal([]byte(output), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+	if doc.Pod != "test-pod" || doc.Template != "simple" {
+		t.Errorf("header = %q/%q, want test-pod/simple", doc.Pod, doc.Template)
+	}
+	if len(doc.Roles) != 1 || doc.Roles[0] != "default" {
+		t.Errorf("Roles = %v, want [default]", doc.Roles)
+	}
+	if len(doc.Agents) != 2 || doc.Agents[0].Name != "builder" || doc.Agents[1].Name != "tester" {
+		t.Fatalf("Agents = %+v", doc.Agents)
+	}
+	if doc.Agents[0].RoleScope != "global" || doc.Agents[0].Env["H2_POD"] != "test-pod" {
+		t.Errorf("agent = %+v", doc.Agents[0])
+	}
+}
+
+func TestPodDryRun_MissingRolesFailFast(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+
+	tmplContent := `pod_name: test-pod
+agents:
+  - name: builder
+    role: builder
+  - name: tester
+    role: tester
+`
+	os.WriteFile(filepath.Join(h2Root, "pods", "templates", "simple.yaml"), []byte(tmplContent), 0o644)
+
+	var err error
+	output := captureStdout(func() {
+		cmd := newPodLaunchCmd()
+		cmd.SetArgs([]string{"--dry-run", "simple"})
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		err = cmd.Execute()
+	})
+	if err == nil {
+		t.Fatal("expected missing roles error")
+	}
+	for _, want := range []string{"builder (needed by builder)", "tester (needed by tester)"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error should contain %q, got: %v", want, err)
+		}
+	}
+	if strings.Contains(output, "Agent:") {
+		t.Errorf("nothing should be resolved before the role check, got:\n%s", output)
+	}
+}
+
+func TestPodLaunch_JSONRequiresDryRun(t *testing.T) {
+	cmd := newPodLaunchCmd()
+	cmd.SetArgs([]string{"--json", "simple"})
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "--json requires --dry-run") {
+		t.Fatalf("expected --json requires --dry-run error, got %v", err)
+	}
+}
+
 func TestPodDryRun_WithCountExpansion(t *testing.T) {
 	h2Root := setupPodTestEnv(t)
 
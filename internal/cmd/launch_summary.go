@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"h2/internal/session"
+	"h2/internal/socketdir"
+)
+
+// launchSummary is the machine-readable record h2 run emits after
+// launching an agent, so callers can talk to it without a status call.
+type launchSummary struct {
+	Name       string `json:"name"`
+	Pod        string `json:"pod,omitempty"`
+	WorkingDir string `json:"working_dir"`
+	Model      string `json:"model,omitempty"`
+	Socket     string `json:"socket"`
+	PID        int    `json:"pid,omitempty"`        // agent process
+	DaemonPID  int    `json:"daemon_pid,omitempty"` // h2 daemon hosting it
+}
+
+// buildLaunchSummary describes the agent just launched with opts. PIDs
+// come from the running daemon and are left zero if it doesn't answer.
+func buildLaunchSummary(opts session.ForkDaemonOpts) (*launchSummary, error) {
+	cwd := opts.CWD
+	if cwd == "" {
+		// The daemon inherits our working directory.
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("get working directory: %w", err)
+		}
+		cwd = wd
+	}
+	sum := &launchSummary{
+		Name:       opts.Name,
+		Pod:        opts.Pod,
+		WorkingDir: cwd,
+		Model:      opts.Model,
+		Socket:     socketdir.Path(socketdir.TypeAgent, opts.Name),
+	}
+	if _, err := os.Stat(sum.Socket); err != nil {
+		return sum, nil
+	}
+	if info, err := queryAgentStatus(sum.Socket, opts.Name, defaultSocketTimeout); err == nil {
+		sum.PID = info.PID
+		sum.DaemonPID = info.DaemonPID
+	}
+	return sum, nil
+}
+
+// emitLaunchSummary writes the summary as JSON to path, or to w when path
+// is empty.
+func emitLaunchSummary(sum *launchSummary, path string, w io.Writer) error {
+	data, err := json.MarshalIndent(sum, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "" {
+		_, err = w.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("write summary file: %w", err)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
 	"os"
@@ -21,6 +22,7 @@ import (
 func newLsCmd() *cobra.Command {
 	var podFlag string
 	var allFlag bool
+	var jsonFlag bool
 
 	cmd := &cobra.Command{
 		Use:   "list",
@@ -29,6 +31,9 @@ func newLsCmd() *cobra.Command {
 			if allFlag && cmd.Flags().Changed("pod") {
 				return fmt.Errorf("--all and --pod are mutually exclusive")
 			}
+			if allFlag && jsonFlag {
+				return fmt.Errorf("--all and --json are mutually exclusive")
+			}
 
 			if allFlag {
 				return listAll()
@@ -38,7 +43,7 @@ func newLsCmd() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			if len(entries) == 0 {
+			if len(entries) == 0 && !jsonFlag {
 				fmt.Println("No running agents.")
 				return nil
 			}
@@ -68,6 +73,9 @@ func newLsCmd() *cobra.Command {
 			}
 
 			groups := groupAgentsByPod(agentInfos, podFilter)
+			if jsonFlag {
+				return printAgentsJSON(groups, unresponsive)
+			}
 			printPodGroups(groups, unresponsive)
 
 			// Bridges are always shown.
@@ -84,6 +92,7 @@ func newLsCmd() *cobra.Command {
 
 	cmd.Flags().StringVar(&podFlag, "pod", "", "Filter by pod name, or '*' to show all grouped by pod")
 	cmd.Flags().BoolVar(&allFlag, "all", false, "List agents from all discovered h2 directories")
+	cmd.Flags().BoolVar(&jsonFlag, "json", false, "Print agents as a JSON array")
 
 	return cmd
 }
@@ -191,8 +200,35 @@ func printPodGroups(groups []podGroup, unresponsive []string) {
 	}
 
 	for _, name := range unresponsive {
-		fmt.Printf("  %s %s %s\n", s.RedX(), name, s.Dim("(not responding)"))
+		fmt.Printf("  %s %s %s\n", s.RedX(), name, s.Dim("(stale: not responding)"))
+	}
+}
+
+// listAgentJSON is one element of list --json output. Stale marks a socket
+// whose agent didn't answer; only its name is known.
+type listAgentJSON struct {
+	message.AgentInfo
+	Stale bool `json:"stale,omitempty"`
+}
+
+// printAgentsJSON writes the grouped agents, then unresponsive ones, as a
+// JSON array.
+func printAgentsJSON(groups []podGroup, unresponsive []string) error {
+	out := []listAgentJSON{}
+	for _, g := range groups {
+		for _, info := range g.Agents {
+			out = append(out, listAgentJSON{AgentInfo: *info})
+		}
+	}
+	for _, name := range unresponsive {
+		out = append(out, listAgentJSON{AgentInfo: message.AgentInfo{Name: name, State: "stale"}, Stale: true})
 	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
 }
 
 func printAgentLine(info *message.AgentInfo) {
@@ -231,7 +267,13 @@ func printAgentLine(info *message.AgentInfo) {
 	// Queued suffix — only show if there are queued messages.
 	queued := ""
 	if info.QueuedCount > 0 {
-		queued = fmt.Sprintf(", %s", s.Cyan(fmt.Sprintf("%d queued", info.QueuedCount)))
+		label := fmt.Sprintf("%d queued", info.QueuedCount)
+		if info.QueuedAttachments == 1 {
+			label += " (1 file)"
+		} else if info.QueuedAttachments > 1 {
+			label += fmt.Sprintf(" (%d files)", info.QueuedAttachments)
+		}
+		queued = fmt.Sprintf(", %s", s.Cyan(label))
 	}
 
 	// OTEL metrics — tokens and cost (only if data received).
@@ -483,7 +525,7 @@ func printPodGroupsIndented(groups []podGroup, unresponsive []string) {
 	}
 
 	for _, name := range unresponsive {
-		fmt.Printf("    %s %s %s\n", s.RedX(), name, s.Dim("(not responding)"))
+		fmt.Printf("    %s %s %s\n", s.RedX(), name, s.Dim("(stale: not responding)"))
 	}
 }
 
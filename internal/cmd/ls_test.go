@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"encoding/json"
 	"testing"
 
 	"h2/internal/config"
@@ -285,3 +286,45 @@ func TestOrderRoutes_CurrentNotInRoutes(t *testing.T) {
 		t.Errorf("ordered[1] = %q, want project-a", ordered[1].route.Prefix)
 	}
 }
+
+func TestPrintAgentsJSON(t *testing.T) {
+	groups := groupAgentsByPod([]*message.AgentInfo{
+		makeAgent("a1", "p1"),
+		makeAgent("a2", ""),
+	}, "")
+	var err error
+	output := captureStdout(func() {
+		err = printAgentsJSON(groups, []string{"gone"})
+	})
+	if err != nil {
+		t.Fatalf("printAgentsJSON: %v", err)
+	}
+
+	var got []map[string]any
+	if err := json.Unmarshal([]byte(output), &got); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 entries, got %d: %s", len(got), output)
+	}
+	if got[0]["name"] != "a1" || got[0]["pod"] != "p1" || got[0]["state"] != "idle" {
+		t.Errorf("first entry = %v", got[0])
+	}
+	if _, ok := got[0]["stale"]; ok {
+		t.Errorf("live agent should not be marked stale: %v", got[0])
+	}
+	if got[2]["name"] != "gone" || got[2]["stale"] != true || got[2]["state"] != "stale" {
+		t.Errorf("stale entry = %v", got[2])
+	}
+}
+
+func TestPrintAgentsJSON_Empty(t *testing.T) {
+	output := captureStdout(func() {
+		if err := printAgentsJSON(nil, nil); err != nil {
+			t.Fatalf("printAgentsJSON: %v", err)
+		}
+	})
+	if output != "[]\n" {
+		t.Errorf("empty output = %q, want []", output)
+	}
+}
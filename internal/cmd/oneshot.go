@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"h2/internal/session/message"
+	"h2/internal/socketdir"
+)
+
+// oneShotPollInterval is how often a one-shot run polls the agent.
+// Var so tests can override it.
+var oneShotPollInterval = 200 * time.Millisecond
+
+// runOneShot drives a freshly launched agent through a single prompt: it
+// waits for the agent to settle, sends the prompt, waits until the agent
+// has gone idle again (or exited) after delivery, writes the agent's output
+// history as plain text to w, and stops the agent. timeout bounds the whole
+// run (0 = no limit).
+func runOneShot(name, prompt string, timeout time.Duration, w io.Writer) error {
+	sockPath, err := socketdir.Find(name)
+	if err != nil {
+		return agentConnError(name, err)
+	}
+	defer stopOneShotAgent(sockPath, name)
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	timedOut := func() bool { return !deadline.IsZero() && time.Now().After(deadline) }
+
+	if _, err := waitForAgentState(sockPath, name, "idle", timeout, defaultSocketTimeout); err != nil {
+		return err
+	}
+
+	sentAt := time.Now()
+	resp, err := agentRequest(sockPath, name, &message.Request{
+		Type: "send",
+		From: resolveActor(),
+		Body: prompt,
+		Raw:  true,
+	})
+	if err != nil {
+		return err
+	}
+
+	for {
+		done, err := oneShotFinished(sockPath, name, resp.MessageID, sentAt)
+		if err != nil {
+			return err
+		}
+		if done {
+			break
+		}
+		if timedOut() {
+			return fmt.Errorf("timed out after %s waiting for agent %q to finish", timeout, name)
+		}
+		time.Sleep(oneShotPollInterval)
+	}
+
+	resp, err = agentRequest(sockPath, name, &message.Request{Type: "screen", History: true})
+	if err != nil {
+		return err
+	}
+	if resp.Screen == nil {
+		return fmt.Errorf("no screen in response from agent %q", name)
+	}
+	fmt.Fprintln(w, resp.Screen.Text)
+	return nil
+}
+
+// oneShotFinished reports whether the prompt has been delivered and the
+// agent has since gone idle or exited. An idle state that began before the
+// prompt was sent doesn't count.
+func oneShotFinished(sockPath, name, messageID string, sentAt time.Time) (bool, error) {
+	resp, err := agentRequest(sockPath, name, &message.Request{Type: "show", MessageID: messageID})
+	if err != nil {
+		return false, err
+	}
+	if resp.Message == nil || resp.Message.Status != string(message.StatusDelivered) {
+		return false, nil
+	}
+	info, err := queryAgentStatus(sockPath, name, defaultSocketTimeout)
+	if err != nil {
+		return false, err
+	}
+	switch info.State {
+	case "exited":
+		return true, nil
+	case "idle":
+		changedAt, err := time.Parse(time.RFC3339Nano, info.StateChangedAt)
+		return err == nil && changedAt.After(sentAt), nil
+	default:
+		return false, nil
+	}
+}
+
+// stopOneShotAgent stops the agent and waits briefly for its daemon to go
+// away, so the one-shot run exits with nothing left running.
+func stopOneShotAgent(sockPath, name string) {
+	if _, err := agentRequest(sockPath, name, &message.Request{Type: "stop"}); err != nil {
+		return
+	}
+	waitForAgentState(sockPath, name, "exited", 5*time.Second, defaultSocketTimeout)
+}
+
+// agentRequest sends req to the agent socket and returns the response,
+// turning transport failures and error responses into errors.
+func agentRequest(sockPath, name string, req *message.Request) (*message.Response, error) {
+	conn, err := dialSocket(sockPath, defaultSocketTimeout)
+	if err != nil {
+		return nil, agentConnError(name, err)
+	}
+	defer conn.Close()
+
+	resp, err := exchangeSocket(conn, req, defaultSocketTimeout)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.OK {
+		return nil, fmt.Errorf("%s failed: %s", req.Type, resp.Error)
+	}
+	return resp, nil
+}
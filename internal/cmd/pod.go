@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -25,10 +28,16 @@ func newPodCmd() *cobra.Command {
 	return cmd
 }
 
+// podReplaceTimeout bounds how long --replace-running waits for stopped
+// agents to exit before launching. Var so tests can override it.
+var podReplaceTimeout = 10 * time.Second
+
 func newPodLaunchCmd() *cobra.Command {
 	var podName string
 	var dryRun bool
+	var dryRunJSON bool
 	var varFlags []string
+	var replaceRunning bool
 
 	cmd := &cobra.Command{
 		Use:   "launch <template>",
@@ -36,6 +45,9 @@ func newPodLaunchCmd() *cobra.Command {
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			templateName := args[0]
+			if dryRunJSON && !dryRun {
+				return fmt.Errorf("--json requires --dry-run")
+			}
 
 			// Parse --var flags.
 			cliVars, err := parseVarFlags(varFlags)
@@ -77,12 +89,43 @@ func newPodLaunchCmd() *cobra.Command {
 				return fmt.Errorf("template %q has no agents", templateName)
 			}
 
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if err := config.CheckPodSize(pt, expanded, cfg.MaxPodAgents); err != nil {
+				return fmt.Errorf("template %q: %w", templateName, err)
+			}
+			if err := config.CheckPodRoles(expanded); err != nil {
+				return fmt.Errorf("template %q: %w", templateName, err)
+			}
+
 			if dryRun {
-				return podDryRun(templateName, pod, expanded, cliVars)
+				return podDryRun(templateName, pod, expanded, cliVars, dryRunJSON)
 			}
 
 			// Build a set of already-running agents in this pod.
 			running := podRunningAgents(pod)
+			if len(running) > 0 {
+				if replaceRunning {
+					stopped := stopPodAgents(pod)
+					for _, name := range stopped {
+						fmt.Fprintf(os.Stderr, "  %s stopped\n", name)
+					}
+					if err := waitForAgentsGone(stopped, podReplaceTimeout); err != nil {
+						return err
+					}
+					running = podRunningAgents(pod)
+				} else {
+					names := make([]string, 0, len(running))
+					for name := range running {
+						names = append(names, name)
+					}
+					sort.Strings(names)
+					fmt.Fprintf(os.Stderr, "Warning: pod %q already has %d running agents (%s); use --replace-running to restart them\n",
+						pod, len(names), strings.Join(names, ", "))
+				}
+			}
 
 			var started, skipped int
 			for _, agent := range expanded {
@@ -97,7 +140,7 @@ func newPodLaunchCmd() *cobra.Command {
 					roleName = "default"
 				}
 
-				// Merge vars: pod template agent vars < CLI vars.
+				// Merge vars: pod vars < agent vars (merged on expansion) < CLI vars.
 				mergedVars := make(map[string]string)
 				for k, v := range agent.Vars {
 					mergedVars[k] = v
@@ -121,8 +164,11 @@ func newPodLaunchCmd() *cobra.Command {
 				if err != nil {
 					return fmt.Errorf("load role %q for agent %q: %w", roleName, agent.Name, err)
 				}
+				if err := agent.ApplyTo(role); err != nil {
+					return fmt.Errorf("agent %q: %w", agent.Name, err)
+				}
 
-				if err := setupAndForkAgentQuiet(agent.Name, role, pod, nil); err != nil {
+				if err := setupAndForkAgentQuiet(agentLaunch{Name: agent.Name, Role: role, Pod: pod, Overrides: agent.Overrides}); err != nil {
 					return fmt.Errorf("start agent %q: %w", agent.Name, err)
 				}
 				fmt.Fprintf(os.Stderr, "  %s started\n", agent.Name)
@@ -144,13 +190,16 @@ func newPodLaunchCmd() *cobra.Command {
 
 	cmd.Flags().StringVar(&podName, "pod", "", "Override pod name (default: template's pod_name or template name)")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show resolved pod config without launching")
+	cmd.Flags().BoolVar(&dryRunJSON, "json", false, "With --dry-run, print the resolved pod config as JSON")
 	cmd.Flags().StringArrayVar(&varFlags, "var", nil, "Set template variable (key=value, repeatable)")
+	cmd.Flags().BoolVar(&replaceRunning, "replace-running", false, "Stop agents already running in the pod before launching")
 
 	return cmd
 }
 
-// podDryRun resolves all agent configs in a pod and prints them without launching.
-func podDryRun(templateName string, pod string, expanded []config.ExpandedAgent, cliVars map[string]string) error {
+// podDryRun resolves all agent configs in a pod and prints them without
+// launching, as JSON when jsonOut is set.
+func podDryRun(templateName string, pod string, expanded []config.ExpandedAgent, cliVars map[string]string, jsonOut bool) error {
 	var resolved []*ResolvedAgentConfig
 
 	for _, agent := range expanded {
@@ -159,7 +208,7 @@ func podDryRun(templateName string, pod string, expanded []config.ExpandedAgent,
 			roleName = "default"
 		}
 
-		// Merge vars: pod template agent vars < CLI vars.
+		// Merge vars: pod vars < agent vars (merged on expansion) < CLI vars.
 		mergedVars := make(map[string]string)
 		for k, v := range agent.Vars {
 			mergedVars[k] = v
@@ -183,14 +232,18 @@ func podDryRun(templateName string, pod string, expanded []config.ExpandedAgent,
 		if err != nil {
 			return fmt.Errorf("load role %q for agent %q: %w", roleName, agent.Name, err)
 		}
+		if err := agent.ApplyTo(role); err != nil {
+			return fmt.Errorf("agent %q: %w", agent.Name, err)
+		}
 
-		rc, err := resolveAgentConfig(agent.Name, role, pod, nil)
+		rc, err := resolveAgentConfig(agent.Name, role, pod, agent.Overrides)
 		if err != nil {
 			return fmt.Errorf("resolve agent %q: %w", agent.Name, err)
 		}
 
 		// Annotate with pod-specific info.
 		rc.MergedVars = mergedVars
+		rc.AgentExtraArgs = agent.ExtraArgs
 		if config.IsPodScopedRole(roleName) {
 			rc.RoleScope = "pod"
 		} else {
@@ -200,6 +253,9 @@ func podDryRun(templateName string, pod string, expanded []config.ExpandedAgent,
 		resolved = append(resolved, rc)
 	}
 
+	if jsonOut {
+		return printPodDryRunJSON(templateName, pod, resolved)
+	}
 	printPodDryRun(templateName, pod, resolved)
 	return nil
 }
@@ -212,41 +268,12 @@ func newPodStopCmd() *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			podName := args[0]
 
-			entries, err := socketdir.ListByType(socketdir.TypeAgent)
-			if err != nil {
-				return err
-			}
-
-			stopped := 0
-			for _, e := range entries {
-				info := queryAgent(e.Path)
-				if info == nil || info.Pod != podName {
-					continue
-				}
-
-				conn, err := net.Dial("unix", e.Path)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Warning: cannot connect to %q: %v\n", e.Name, err)
-					continue
-				}
-
-				if err := message.SendRequest(conn, &message.Request{Type: "stop"}); err != nil {
-					conn.Close()
-					fmt.Fprintf(os.Stderr, "Warning: cannot stop %q: %v\n", e.Name, err)
-					continue
-				}
-
-				resp, err := message.ReadResponse(conn)
-				conn.Close()
-				if err != nil || !resp.OK {
-					fmt.Fprintf(os.Stderr, "Warning: stop failed for %q\n", e.Name)
-					continue
-				}
-
-				fmt.Printf("Stopped %s\n", e.Name)
-				stopped++
+			names := stopPodAgents(podName)
+			for _, name := range names {
+				fmt.Printf("Stopped %s\n", name)
 			}
 
+			stopped := len(names)
 			if stopped == 0 {
 				fmt.Printf("No agents found in pod %q\n", podName)
 			} else {
@@ -257,6 +284,67 @@ func newPodStopCmd() *cobra.Command {
 	}
 }
 
+// stopPodAgents asks every running agent in the pod to stop and returns the
+// names of those that acknowledged. Failures are reported as warnings.
+func stopPodAgents(pod string) []string {
+	entries, err := socketdir.ListByType(socketdir.TypeAgent)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: list agents: %v\n", err)
+		return nil
+	}
+
+	var stopped []string
+	for _, e := range entries {
+		info := queryAgent(e.Path)
+		if info == nil || info.Pod != pod {
+			continue
+		}
+
+		conn, err := net.Dial("unix", e.Path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: cannot connect to %q: %v\n", e.Name, err)
+			continue
+		}
+
+		if err := message.SendRequest(conn, &message.Request{Type: "stop"}); err != nil {
+			conn.Close()
+			fmt.Fprintf(os.Stderr, "Warning: cannot stop %q: %v\n", e.Name, err)
+			continue
+		}
+
+		resp, err := message.ReadResponse(conn)
+		conn.Close()
+		if err != nil || !resp.OK {
+			fmt.Fprintf(os.Stderr, "Warning: stop failed for %q\n", e.Name)
+			continue
+		}
+
+		stopped = append(stopped, e.Name)
+	}
+	return stopped
+}
+
+// waitForAgentsGone polls until none of the named agents' sockets remain,
+// so a replacement daemon doesn't find its predecessor still listening.
+func waitForAgentsGone(names []string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		var alive []string
+		for _, name := range names {
+			if _, err := socketdir.Find(name); err == nil {
+				alive = append(alive, name)
+			}
+		}
+		if len(alive) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for agents to stop: %s", timeout, strings.Join(alive, ", "))
+		}
+		time.Sleep(statusPollInterval)
+	}
+}
+
 // podRunningAgents returns a set of agent names currently running in the given pod.
 func podRunningAgents(pod string) map[string]bool {
 	running := make(map[string]bool)
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 
 	"h2/internal/config"
@@ -14,6 +15,20 @@ import (
 	"h2/internal/socketdir"
 )
 
+// stubForkDaemon replaces forkDaemonFunc for the test so no daemon is
+// spawned, and returns the options of every fork attempted.
+func stubForkDaemon(t *testing.T) *[]session.ForkDaemonOpts {
+	t.Helper()
+	var forks []session.ForkDaemonOpts
+	orig := forkDaemonFunc
+	forkDaemonFunc = func(opts session.ForkDaemonOpts) error {
+		forks = append(forks, opts)
+		return nil
+	}
+	t.Cleanup(func() { forkDaemonFunc = orig })
+	return &forks
+}
+
 func setupPodTestEnv(t *testing.T) string {
 	t.Helper()
 	config.ResetResolveCache()
@@ -311,14 +326,7 @@ agents:
 func TestPodLaunchCmd_TemplateWithVarRendering(t *testing.T) {
 	h2Root := setupPodTestEnv(t)
 
-	// Mock ForkDaemon to prevent spawning real processes.
-	var forkOpts []session.ForkDaemonOpts
-	origFork := forkDaemonFunc
-	forkDaemonFunc = func(opts session.ForkDaemonOpts) error {
-		forkOpts = append(forkOpts, opts)
-		return nil
-	}
-	t.Cleanup(func() { forkDaemonFunc = origFork })
+	forkOpts := stubForkDaemon(t)
 
 	// Template that uses a variable in an agent name.
 	tmplContent := `variables:
@@ -343,25 +351,18 @@ agents:
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if len(forkOpts) != 1 {
-		t.Fatalf("expected 1 fork call, got %d", len(forkOpts))
+	if len(*forkOpts) != 1 {
+		t.Fatalf("expected 1 fork call, got %d", len(*forkOpts))
 	}
-	if forkOpts[0].Name != "dev-worker" {
-		t.Errorf("expected agent name 'dev-worker', got %q", forkOpts[0].Name)
+	if (*forkOpts)[0].Name != "dev-worker" {
+		t.Errorf("expected agent name 'dev-worker', got %q", (*forkOpts)[0].Name)
 	}
 }
 
 func TestPodLaunchCmd_CLIVarOverridesDefault(t *testing.T) {
 	h2Root := setupPodTestEnv(t)
 
-	// Mock ForkDaemon to prevent spawning real processes.
-	var forkOpts []session.ForkDaemonOpts
-	origFork := forkDaemonFunc
-	forkDaemonFunc = func(opts session.ForkDaemonOpts) error {
-		forkOpts = append(forkOpts, opts)
-		return nil
-	}
-	t.Cleanup(func() { forkDaemonFunc = origFork })
+	forkOpts := stubForkDaemon(t)
 
 	tmplContent := `variables:
   prefix:
@@ -384,10 +385,354 @@ agents:
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if len(forkOpts) != 1 {
-		t.Fatalf("expected 1 fork call, got %d", len(forkOpts))
+	if len(*forkOpts) != 1 {
+		t.Fatalf("expected 1 fork call, got %d", len(*forkOpts))
+	}
+	if (*forkOpts)[0].Name != "staging-worker" {
+		t.Errorf("expected agent name 'staging-worker', got %q", (*forkOpts)[0].Name)
+	}
+}
+
+func TestPodLaunchCmd_PodVarsReachRoleTemplate(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+
+	forkOpts := stubForkDaemon(t)
+
+	// The pod sets team and env; neither agent lists team.
+	tmplContent := `pod_name: test
+vars:
+  team: backend
+  env: dev
+agents:
+  - name: lead
+    role: teamrole
+  - name: worker
+    role: teamrole
+    vars:
+      env: prod
+`
+	os.WriteFile(filepath.Join(h2Root, "pods", "templates", "podvars.yaml"), []byte(tmplContent), 0o644)
+
+	roleContent := `name: teamrole
+variables:
+  team:
+    description: "Team name"
+  env:
+    description: "Environment"
+instructions: |
+  Team {{ .Var.team }} in {{ .Var.env }}.
+`
+	os.WriteFile(filepath.Join(h2Root, "roles", "teamrole.yaml"), []byte(roleContent), 0o644)
+
+	cmd := newPodLaunchCmd()
+	cmd.SetArgs([]string{"--var", "team=platform", "podvars"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(*forkOpts) != 2 {
+		t.Fatalf("expected 2 fork calls, got %d", len(*forkOpts))
+	}
+	// CLI vars beat pod vars; agent vars beat pod vars.
+	want := map[string]string{
+		"lead":   "Team platform in dev.",
+		"worker": "Team platform in prod.",
+	}
+	for _, opts := range *forkOpts {
+		if got := strings.TrimSpace(opts.Instructions); got != want[opts.Name] {
+			t.Errorf("%s: instructions = %q, want %q", opts.Name, got, want[opts.Name])
+		}
+	}
+}
+
+func TestPodLaunchCmd_AgentOverridesAndExtraArgs(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+
+	forkOpts := stubForkDaemon(t)
+
+	// Both agents share the coding role; only the reviewer overrides it.
+	tmplContent := `pod_name: test
+agents:
+  - name: coder
+    role: coding
+  - name: reviewer
+    role: coding
+    overrides:
+      - model=opus
+    extra_args:
+      - --verbose
+`
+	os.WriteFile(filepath.Join(h2Root, "pods", "templates", "mixed.yaml"), []byte(tmplContent), 0o644)
+
+	roleContent := `name: coding
+model: sonnet
+extra_args:
+  - --debug
+instructions: Write code.
+`
+	os.WriteFile(filepath.Join(h2Root, "roles", "coding.yaml"), []byte(roleContent), 0o644)
+
+	cmd := newPodLaunchCmd()
+	cmd.SetArgs([]string{"mixed"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(*forkOpts) != 2 {
+		t.Fatalf("expected 2 fork calls, got %d", len(*forkOpts))
+	}
+	byName := make(map[string]session.ForkDaemonOpts)
+	for _, opts := range *forkOpts {
+		byName[opts.Name] = opts
+	}
+
+	coder := byName["coder"]
+	if coder.Model != "sonnet" {
+		t.Errorf("coder model = %q, want role default sonnet", coder.Model)
+	}
+	if got := strings.Join(coder.ExtraArgs, " "); got != "--debug" {
+		t.Errorf("coder extra args = %q, want %q", got, "--debug")
+	}
+	if len(coder.Overrides) != 0 {
+		t.Errorf("coder overrides = %v, want none", coder.Overrides)
+	}
+
+	reviewer := byName["reviewer"]
+	if reviewer.Model != "opus" {
+		t.Errorf("reviewer model = %q, want opus", reviewer.Model)
+	}
+	if got := strings.Join(reviewer.ExtraArgs, " "); got != "--debug --verbose" {
+		t.Errorf("reviewer extra args = %q, want %q", got, "--debug --verbose")
+	}
+	if len(reviewer.Overrides) != 1 || reviewer.Overrides[0] != "model=opus" {
+		t.Errorf("reviewer overrides = %v, want [model=opus]", reviewer.Overrides)
+	}
+}
+
+func TestPodLaunchCmd_AgentOverrideInvalid(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+
+	stubForkDaemon(t)
+
+	tmplContent := `pod_name: test
+agents:
+  - name: coder
+    role: coding
+    overrides:
+      - no_such_field=1
+`
+	os.WriteFile(filepath.Join(h2Root, "pods", "templates", "bad.yaml"), []byte(tmplContent), 0o644)
+	os.WriteFile(filepath.Join(h2Root, "roles", "coding.yaml"), []byte("name: coding\ninstructions: x\n"), 0o644)
+
+	cmd := newPodLaunchCmd()
+	cmd.SetArgs([]string{"bad"})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), `agent "coder"`) {
+		t.Fatalf("expected agent override error, got %v", err)
+	}
+}
+
+func TestPodLaunchCmd_PodVarsWithoutCLI(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+
+	forkOpts := stubForkDaemon(t)
+
+	tmplContent := `pod_name: test
+vars:
+  team: backend
+agents:
+  - name: solo
+    role: teamrole
+`
+	os.WriteFile(filepath.Join(h2Root, "pods", "templates", "solo.yaml"), []byte(tmplContent), 0o644)
+	roleContent := `name: teamrole
+variables:
+  team:
+    description: "Team name"
+instructions: |
+  Team {{ .Var.team }}.
+`
+	os.WriteFile(filepath.Join(h2Root, "roles", "teamrole.yaml"), []byte(roleContent), 0o644)
+
+	cmd := newPodLaunchCmd()
+	cmd.SetArgs([]string{"solo"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("required role var should be satisfied by the pod var: %v", err)
+	}
+	if len(*forkOpts) != 1 || strings.TrimSpace((*forkOpts)[0].Instructions) != "Team backend." {
+		t.Fatalf("unexpected fork opts: %+v", *forkOpts)
+	}
+}
+
+func TestPodLaunchCmd_ExceedsMaxAgents(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+
+	forkOpts := stubForkDaemon(t)
+
+	tmplContent := `variables:
+  num_coders:
+    default: "1"
+pod_name: test
+agents:
+  - name: coder
+    role: default
+    count: {{ .Var.num_coders }}
+`
+	os.WriteFile(filepath.Join(h2Root, "pods", "templates", "coders.yaml"), []byte(tmplContent), 0o644)
+	os.WriteFile(filepath.Join(h2Root, "roles", "default.yaml"), []byte("name: default\ninstructions: |\n  test\n"), 0o644)
+	os.WriteFile(filepath.Join(h2Root, "config.yaml"), []byte("max_pod_agents: 3\n"), 0o644)
+
+	cmd := newPodLaunchCmd()
+	cmd.SetArgs([]string{"coders", "--var", "num_coders=4"})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "4 agents") || !strings.Contains(err.Error(), "max_pod_agents of 3") {
+		t.Fatalf("expected max_pod_agents error, got %v", err)
+	}
+	if len(*forkOpts) != 0 {
+		t.Fatalf("no agents should launch when the cap is exceeded, got %d", len(*forkOpts))
+	}
+
+	cmd = newPodLaunchCmd()
+	cmd.SetArgs([]string{"coders", "--var", "num_coders=3"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("3 agents should fit max_pod_agents 3: %v", err)
+	}
+	if len(*forkOpts) != 3 {
+		t.Fatalf("expected 3 fork calls, got %d", len(*forkOpts))
+	}
+}
+
+// captureStderr captures stderr from a function call.
+func captureStderr(fn func()) string {
+	old := os.Stderr
+	r, w, _ := os.Pipe()
+	os.Stderr = w
+
+	fn()
+
+	w.Close()
+	os.Stderr = old
+
+	var buf bytes.Buffer
+	buf.ReadFrom(r)
+	return buf.String()
+}
+
+// startPodMockAgent serves status/stop on a mock agent socket. A stop
+// request closes the listener (removing the socket) and sets the returned flag.
+func startPodMockAgent(t *testing.T, h2Root, name, pod string) *atomic.Bool {
+	t.Helper()
+	sockPath := filepath.Join(h2Root, "sockets", socketdir.Format(socketdir.TypeAgent, name))
+	ln, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatalf("listen %s: %v", name, err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	stopped := new(atomic.Bool)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			req, err := message.ReadRequest(conn)
+			if err != nil {
+				conn.Close()
+				continue
+			}
+			switch req.Type {
+			case "status":
+				message.SendResponse(conn, &message.Response{
+					OK:    true,
+					Agent: &message.AgentInfo{Name: name, State: "idle", Pod: pod},
+				})
+			case "stop":
+				stopped.Store(true)
+				message.SendResponse(conn, &message.Response{OK: true})
+				conn.Close()
+				ln.Close()
+				return
+			}
+			conn.Close()
+		}
+	}()
+	return stopped
+}
+
+func writeReplaceTestPod(t *testing.T, h2Root string) {
+	t.Helper()
+	tmplContent := `pod_name: team
+agents:
+  - name: coder
+    role: default
+  - name: reviewer
+    role: default
+`
+	os.WriteFile(filepath.Join(h2Root, "pods", "templates", "team.yaml"), []byte(tmplContent), 0o644)
+	roleContent := "name: default\ninstructions: |\n  test\n"
+	os.WriteFile(filepath.Join(h2Root, "roles", "default.yaml"), []byte(roleContent), 0o644)
+}
+
+func TestPodLaunchCmd_ReplaceRunning(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	writeReplaceTestPod(t, h2Root)
+
+	forkOpts := stubForkDaemon(t)
+
+	oldCoder := startPodMockAgent(t, h2Root, "coder", "team")
+	straggler := startPodMockAgent(t, h2Root, "old-helper", "team")
+	outsider := startPodMockAgent(t, h2Root, "outsider", "other")
+
+	cmd := newPodLaunchCmd()
+	cmd.SetArgs([]string{"team", "--replace-running"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !oldCoder.Load() || !straggler.Load() {
+		t.Error("expected all prior agents in the pod to be stopped")
+	}
+	if outsider.Load() {
+		t.Error("agents in other pods must not be stopped")
+	}
+	var forked []string
+	for _, opts := range *forkOpts {
+		forked = append(forked, opts.Name)
+	}
+	if strings.Join(forked, ",") != "coder,reviewer" {
+		t.Errorf("forked = %v, want [coder reviewer]", forked)
+	}
+}
+
+func TestPodLaunchCmd_RunningConflictWarns(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	writeReplaceTestPod(t, h2Root)
+
+	forkOpts := stubForkDaemon(t)
+
+	oldCoder := startPodMockAgent(t, h2Root, "coder", "team")
+
+	cmd := newPodLaunchCmd()
+	cmd.SetArgs([]string{"team"})
+	var execErr error
+	stderr := captureStderr(func() { execErr = cmd.Execute() })
+	if execErr != nil {
+		t.Fatalf("unexpected error: %v", execErr)
+	}
+
+	if !strings.Contains(stderr, `pod "team" already has 1 running agents (coder)`) ||
+		!strings.Contains(stderr, "--replace-running") {
+		t.Errorf("expected conflict warning, got:\n%s", stderr)
+	}
+	if oldCoder.Load() {
+		t.Error("running agent should not be stopped without --replace-running")
+	}
+	var forked []string
+	for _, opts := range *forkOpts {
+		forked = append(forked, opts.Name)
 	}
-	if forkOpts[0].Name != "staging-worker" {
-		t.Errorf("expected agent name 'staging-worker', got %q", forkOpts[0].Name)
+	if strings.Join(forked, ",") != "reviewer" {
+		t.Errorf("forked = %v, want only [reviewer]", forked)
 	}
 }
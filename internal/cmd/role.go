@@ -397,6 +397,9 @@ func newRoleCheckCmd() *cobra.Command {
 			fmt.Printf("Role %q is valid.\n", role.Name)
 
 			fmt.Printf("  Agent type:  %s\n", role.GetAgentType())
+			if role.Command != "" {
+				fmt.Printf("  Command:     %s\n", strings.Join(append([]string{role.Command}, role.Args...), " "))
+			}
 			if role.Model != "" {
 				fmt.Printf("  Model:       %s\n", role.Model)
 			}
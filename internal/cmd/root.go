@@ -31,6 +31,7 @@ func NewRootCmd() *cobra.Command {
 		newLsAlias(listCmd),
 		newShowCmd(),
 		newStatusCmd(),
+		newDashboardCmd(),
 		newDaemonCmd(),
 		newWhoamiCmd(),
 		newBridgeCmd(),
@@ -42,6 +43,7 @@ func NewRootCmd() *cobra.Command {
 		newSessionCmd(),
 		newAuthCmd(),
 		newPeekCmd(),
+		newTailCmd(),
 		newStopCmd(),
 		newVersionCmd(),
 		newInitCmd(),
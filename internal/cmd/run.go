@@ -3,7 +3,11 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/spf13/cobra"
@@ -17,12 +21,22 @@ func newRunCmd() *cobra.Command {
 	var name string
 	var detach bool
 	var dryRun bool
+	var dryRunJSON bool
 	var roleName string
 	var agentType string
 	var command string
 	var pod string
 	var overrides []string
 	var varFlags []string
+	var appendInstructions []string
+	var statusFile string
+	var noHooks bool
+	var drain bool
+	var output bool
+	var prompt string
+	var timeout time.Duration
+	var summary bool
+	var summaryFile string
 
 	cmd := &cobra.Command{
 		Use:   "run [flags]",
@@ -34,15 +48,82 @@ By default, uses the "default" role from ~/.h2/roles/default.yaml.
   h2 run                        Use the default role
   h2 run --role concierge       Use a specific role
   h2 run --agent-type claude    Run an agent type without a role
-  h2 run --command "vim"        Run an explicit command`,
+  h2 run --command "vim"        Run an explicit command
+
+Use --append-instructions (repeatable) to layer extra instruction blocks on
+top of the role's instructions. Each value is literal text, or @path to read
+the block from a file. Blocks are rendered like the role and appended in
+order, separated by blank lines.
+
+Use --status-file to have the agent keep a one-line status (mode, state,
+idle time, queue) in a file, e.g. for a tmux status line:
+
+  set -g status-right '#(cat /tmp/h2-agent.status)'
+
+Use --no-hooks when debugging to launch against a copy of the Claude config
+dir whose settings.json has none of h2's hooks. Hook-driven state tracking
+and the permission reviewer are unavailable in that mode, so it cannot be
+combined with a role that sets permissions.agent.
+
+Use --output for a non-interactive one-shot run: the agent is launched,
+given one prompt (--prompt, or stdin when omitted), and once it goes idle
+its output is printed to stdout as plain text and the agent is stopped.
+
+  h2 run --role coder --output --prompt "summarize README.md" > summary.txt
+
+After a detached launch (or with --summary), a JSON summary of the new agent
+(name, pod, working dir, model, socket path, PIDs) is printed to stdout;
+--summary-file writes it to a file instead.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Safety check: when running inside a Claude Code session,
 			// require --detach to prevent hijacking the parent's terminal.
 			// Skip for --dry-run since it doesn't launch anything.
-			if os.Getenv("CLAUDECODE") != "" && !detach && !dryRun {
+			if os.Getenv("CLAUDECODE") != "" && !detach && !dryRun && !output {
 				return fmt.Errorf("running inside a Claude Code session (CLAUDECODE is set); use --detach to avoid hijacking the parent terminal")
 			}
 
+			if summaryFile != "" {
+				abs, err := filepath.Abs(summaryFile)
+				if err != nil {
+					return fmt.Errorf("resolve --summary-file: %w", err)
+				}
+				summaryFile = abs
+			}
+			// afterFork reports the launch summary, when one is wanted,
+			// before any attach takes over the terminal.
+			afterFork := func(opts session.ForkDaemonOpts) error {
+				if output || (!detach && !summary && summaryFile == "") {
+					return nil
+				}
+				sum, err := buildLaunchSummary(opts)
+				if err != nil {
+					return err
+				}
+				return emitLaunchSummary(sum, summaryFile, cmd.OutOrStdout())
+			}
+
+			if dryRunJSON && !dryRun {
+				return fmt.Errorf("--json requires --dry-run")
+			}
+			if cmd.Flags().Changed("prompt") && !output {
+				return fmt.Errorf("--prompt requires --output")
+			}
+			if output {
+				if dryRun {
+					return fmt.Errorf("--output cannot be combined with --dry-run")
+				}
+				if prompt == "" {
+					data, err := io.ReadAll(cmd.InOrStdin())
+					if err != nil {
+						return fmt.Errorf("read prompt from stdin: %w", err)
+					}
+					prompt = strings.TrimRight(string(data), "\n")
+				}
+				if prompt == "" {
+					return fmt.Errorf("--output needs a prompt (--prompt or stdin)")
+				}
+			}
+
 			// Validate pod name if provided.
 			if pod != "" {
 				if err := config.ValidatePodName(pod); err != nil {
@@ -89,11 +170,11 @@ By default, uses the "default" role from ~/.h2/roles/default.yaml.
 				}
 
 				// Build template context for role rendering.
-				agentName := name
-				if agentName == "" {
-					agentName = session.GenerateName()
-					name = agentName
+				agentName, err := resolveAgentName(name, roleName)
+				if err != nil {
+					return err
 				}
+				name = agentName
 				ctx := &tmpl.Context{
 					AgentName: agentName,
 					RoleName:  roleName,
@@ -125,15 +206,53 @@ By default, uses the "default" role from ~/.h2/roles/default.yaml.
 						return fmt.Errorf("apply overrides: %w", err)
 					}
 				}
+				if err := appendInstructionFragments(role, appendInstructions, ctx); err != nil {
+					return err
+				}
+				if statusFile != "" {
+					role.StatusFile = statusFile
+				}
+				if drain {
+					role.DrainOnQuit = true
+				}
+				if noHooks {
+					role.NoHooks = true
+					if err := role.Validate(); err != nil {
+						return fmt.Errorf("--no-hooks: %w", err)
+					}
+				}
 				if dryRun {
 					rc, err := resolveAgentConfig(name, role, pod, overrides)
 					if err != nil {
 						return err
 					}
+					if dryRunJSON {
+						return printDryRunJSON(rc)
+					}
 					printDryRun(rc)
 					return nil
 				}
-				return setupAndForkAgent(name, role, detach, pod, overrides)
+				if output {
+					if err := setupAndForkAgentQuiet(agentLaunch{Name: name, Role: role, Pod: pod, Overrides: overrides}); err != nil {
+						return err
+					}
+					return runOneShot(name, prompt, timeout, cmd.OutOrStdout())
+				}
+				return setupAndForkAgent(agentLaunch{
+					Name:      name,
+					Role:      role,
+					Pod:       pod,
+					Overrides: overrides,
+					Detach:    detach,
+					AfterFork: afterFork,
+				})
+			}
+
+			if len(appendInstructions) > 0 {
+				return fmt.Errorf("--append-instructions requires a role")
+			}
+			if noHooks {
+				return fmt.Errorf("--no-hooks requires a role")
 			}
 
 			// Agent-type or command mode: --dry-run requires a role.
@@ -142,43 +261,104 @@ By default, uses the "default" role from ~/.h2/roles/default.yaml.
 			}
 
 			// Agent-type or command mode: fork without a role.
-			if name == "" {
-				name = session.GenerateName()
+			agentName, err := resolveAgentName(name, "")
+			if err != nil {
+				return err
 			}
+			name = agentName
 
 			sessionID := uuid.New().String()
 
+			if statusFile != "" {
+				abs, err := filepath.Abs(statusFile)
+				if err != nil {
+					return fmt.Errorf("resolve --status-file: %w", err)
+				}
+				statusFile = abs
+			}
+
 			// Fork a daemon process.
-			if err := forkDaemonFunc(session.ForkDaemonOpts{
+			forkOpts := session.ForkDaemonOpts{
 				Name:      name,
 				SessionID: sessionID,
 				Command:   cmdCommand,
 				Args:      cmdArgs,
 				Heartbeat: heartbeat,
 				Pod:       pod,
-			}); err != nil {
+
+				StatusFile:  statusFile,
+				DrainOnQuit: drain,
+			}
+			if err := forkDaemonFunc(forkOpts); err != nil {
+				return err
+			}
+			if err := afterFork(forkOpts); err != nil {
 				return err
 			}
 
+			if output {
+				return runOneShot(name, prompt, timeout, cmd.OutOrStdout())
+			}
 			if detach {
 				fmt.Fprintf(os.Stderr, "Agent %q started (detached). Use 'h2 attach %s' to connect.\n", name, name)
 				return nil
 			}
 
 			fmt.Fprintf(os.Stderr, "Agent %q started. Attaching...\n", name)
-			return doAttach(name)
+			return doAttach(name, attachOptions{})
 		},
 	}
 
 	cmd.Flags().StringVar(&name, "name", "", "Agent name (auto-generated if omitted)")
 	cmd.Flags().BoolVar(&detach, "detach", false, "Don't auto-attach after starting")
 	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show resolved config without launching")
+	cmd.Flags().BoolVar(&dryRunJSON, "json", false, "With --dry-run, print the resolved config as JSON")
 	cmd.Flags().StringVar(&roleName, "role", "", "Role to use (defaults to 'default')")
 	cmd.Flags().StringVar(&agentType, "agent-type", "", "Agent type to run without a role (e.g. claude)")
 	cmd.Flags().StringVar(&command, "command", "", "Explicit command to run without a role")
 	cmd.Flags().StringVar(&pod, "pod", "", "Pod name for the agent (sets H2_POD env var)")
 	cmd.Flags().StringArrayVar(&overrides, "override", nil, "Override role field (key=value, e.g. worktree.enabled=true)")
 	cmd.Flags().StringArrayVar(&varFlags, "var", nil, "Set template variable (key=value, repeatable)")
+	cmd.Flags().StringVar(&statusFile, "status-file", "", "Keep a one-line agent status in this file (for tmux status lines)")
+	cmd.Flags().BoolVar(&drain, "drain", false, "On menu Quit, deliver queued messages before stopping the agent")
+	cmd.Flags().BoolVar(&noHooks, "no-hooks", false, "Launch without h2's hooks in settings.json (debugging; excludes permissions.agent)")
+	cmd.Flags().BoolVar(&output, "output", false, "One-shot: send one prompt, print the agent's output once idle, then stop it")
+	cmd.Flags().StringVar(&prompt, "prompt", "", "Prompt for --output (default: read from stdin)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "With --output, give up after this long (0 = no limit)")
+	cmd.Flags().BoolVar(&summary, "summary", false, "Print a JSON launch summary even when attaching (detached launches always print one)")
+	cmd.Flags().StringVar(&summaryFile, "summary-file", "", "Write the JSON launch summary to this file instead of stdout")
+	cmd.Flags().StringArrayVar(&appendInstructions, "append-instructions", nil, "Append an instructions block (<text> or @file, repeatable)")
 
 	return cmd
 }
+
+// appendInstructionFragments renders each --append-instructions value and
+// appends it to role.Instructions, separated by blank lines. Values starting
+// with @ are read from the named file.
+func appendInstructionFragments(role *config.Role, fragments []string, ctx *tmpl.Context) error {
+	blocks := []string{}
+	if role.Instructions != "" {
+		blocks = append(blocks, strings.TrimRight(role.Instructions, "\n"))
+	}
+	for _, frag := range fragments {
+		text := frag
+		if path, ok := strings.CutPrefix(frag, "@"); ok {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return fmt.Errorf("read --append-instructions file: %w", err)
+			}
+			text = string(data)
+		}
+		rendered, err := tmpl.Render(text, ctx)
+		if err != nil {
+			return fmt.Errorf("render --append-instructions %q: %w", frag, err)
+		}
+		if rendered = strings.TrimSpace(rendered); rendered != "" {
+			blocks = append(blocks, rendered)
+		}
+	}
+	if len(fragments) > 0 {
+		role.Instructions = strings.Join(blocks, "\n\n")
+	}
+	return nil
+}
@@ -0,0 +1,418 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"h2/internal/session"
+	"h2/internal/session/message"
+	"h2/internal/socketdir"
+)
+
+func TestRunCmd_AppendInstructionsInOrder(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+
+	forkOpts := stubForkDaemon(t)
+
+	roleContent := "name: default\ninstructions: |\n  base instructions\n"
+	os.WriteFile(filepath.Join(h2Root, "roles", "default.yaml"), []byte(roleContent), 0o644)
+
+	fragFile := filepath.Join(t.TempDir(), "task.md")
+	os.WriteFile(fragFile, []byte("task for {{ .AgentName }}\n"), 0o644)
+
+	cmd := newRunCmd()
+	cmd.SetArgs([]string{"--name", "worker", "--detach",
+		"--append-instructions", "first fragment",
+		"--append-instructions", "@" + fragFile,
+		"--append-instructions", "last fragment",
+	})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(*forkOpts) != 1 {
+		t.Fatalf("expected 1 fork call, got %d", len(*forkOpts))
+	}
+	want := "base instructions\n\nfirst fragment\n\ntask for worker\n\nlast fragment"
+	if got := (*forkOpts)[0].Instructions; got != want {
+		t.Errorf("Instructions = %q, want %q", got, want)
+	}
+}
+
+func TestRunCmd_AppendInstructionsDryRunChildArgs(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+
+	roleContent := "name: default\ninstructions: base\n"
+	os.WriteFile(filepath.Join(h2Root, "roles", "default.yaml"), []byte(roleContent), 0o644)
+
+	cmd := newRunCmd()
+	cmd.SetArgs([]string{"--name", "worker", "--dry-run",
+		"--append-instructions", "one",
+		"--append-instructions", "two",
+	})
+	out := captureStdout(func() {
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+	for _, line := range []string{"base", "one", "two"} {
+		if !strings.Contains(out, "  "+line+"\n") {
+			t.Errorf("expected instruction line %q in dry-run output:\n%s", line, out)
+		}
+	}
+	if !strings.Contains(out, "Instructions: (5 lines)") {
+		t.Errorf("expected fragments joined with blank lines, got:\n%s", out)
+	}
+}
+
+func TestRunCmd_AppendInstructionsMissingFile(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	os.WriteFile(filepath.Join(h2Root, "roles", "default.yaml"), []byte("name: default\ninstructions: base\n"), 0o644)
+
+	cmd := newRunCmd()
+	cmd.SetArgs([]string{"--detach", "--append-instructions", "@/nonexistent/frag.md"})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "append-instructions") {
+		t.Fatalf("expected append-instructions file error, got %v", err)
+	}
+}
+
+func TestRunCmd_ClaudeProfile(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+
+	forkOpts := stubForkDaemon(t)
+
+	roleContent := "name: default\nclaude_profile: work\ninstructions: |\n  test\n"
+	os.WriteFile(filepath.Join(h2Root, "roles", "default.yaml"), []byte(roleContent), 0o644)
+
+	// Unauthenticated profile is rejected before the daemon is forked.
+	cmd := newRunCmd()
+	cmd.SetArgs([]string{"--name", "worker", "--detach"})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), `claude profile "work" is not authenticated`) {
+		t.Fatalf("expected unauthenticated profile error, got %v", err)
+	}
+	if len(*forkOpts) != 0 {
+		t.Fatalf("daemon should not be forked for an unauthenticated profile")
+	}
+
+	profileDir := filepath.Join(h2Root, "claude-config", "work")
+	os.WriteFile(filepath.Join(profileDir, ".claude.json"),
+		[]byte(`{"oauthAccount":{"accountUuid":"u1","emailAddress":"me@work.example"}}`), 0o644)
+
+	cmd = newRunCmd()
+	cmd.SetArgs([]string{"--name", "worker", "--detach"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*forkOpts) != 1 {
+		t.Fatalf("expected 1 fork call, got %d", len(*forkOpts))
+	}
+	if got := (*forkOpts)[0].ClaudeConfigDir; got != profileDir {
+		t.Errorf("ClaudeConfigDir = %q, want %q", got, profileDir)
+	}
+}
+
+func TestRunCmd_RequiresBinaries(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+
+	forkOpts := stubForkDaemon(t)
+
+	// A bogus (template-rendered) binary fails before the daemon is forked.
+	roleContent := "name: default\ninstructions: test\nrequires:\n  - sh\n  - \"h2-missing-{{ .AgentName }}\"\n"
+	os.WriteFile(filepath.Join(h2Root, "roles", "default.yaml"), []byte(roleContent), 0o644)
+
+	cmd := newRunCmd()
+	cmd.SetArgs([]string{"--name", "worker", "--detach"})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "requires h2-missing-worker, not found on PATH") {
+		t.Fatalf("expected missing binary error, got %v", err)
+	}
+	if len(*forkOpts) != 0 {
+		t.Fatalf("daemon should not be forked when a required binary is missing")
+	}
+
+	// Binaries that are present launch normally.
+	roleContent = "name: default\ninstructions: test\nrequires: [sh]\n"
+	os.WriteFile(filepath.Join(h2Root, "roles", "default.yaml"), []byte(roleContent), 0o644)
+
+	cmd = newRunCmd()
+	cmd.SetArgs([]string{"--name", "worker", "--detach"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*forkOpts) != 1 {
+		t.Fatalf("expected 1 fork call, got %d", len(*forkOpts))
+	}
+}
+
+func TestRunCmd_StartMessageRendered(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+
+	forkOpts := stubForkDaemon(t)
+
+	roleContent := "name: default\ninstructions: test\non_start_message: \"{{ .AgentName }} in {{ .PodName }}: review the open tasks and begin\"\n"
+	os.WriteFile(filepath.Join(h2Root, "roles", "default.yaml"), []byte(roleContent), 0o644)
+
+	cmd := newRunCmd()
+	cmd.SetArgs([]string{"--name", "worker", "--pod", "team", "--detach"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*forkOpts) != 1 {
+		t.Fatalf("expected 1 fork call, got %d", len(*forkOpts))
+	}
+	want := "worker in team: review the open tasks and begin"
+	if got := (*forkOpts)[0].StartMessage; got != want {
+		t.Errorf("StartMessage = %q, want %q", got, want)
+	}
+}
+
+func TestRunCmd_ExtraArgsForwarded(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+
+	forkOpts := stubForkDaemon(t)
+
+	roleContent := "name: default\ninstructions: test\nextra_args:\n  - --mcp-config\n  - \"{{ .AgentName }}.json\"\n  - --verbose\n"
+	os.WriteFile(filepath.Join(h2Root, "roles", "default.yaml"), []byte(roleContent), 0o644)
+
+	cmd := newRunCmd()
+	cmd.SetArgs([]string{"--name", "worker", "--detach"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*forkOpts) != 1 {
+		t.Fatalf("expected 1 fork call, got %d", len(*forkOpts))
+	}
+	want := "--mcp-config worker.json --verbose"
+	if got := strings.Join((*forkOpts)[0].ExtraArgs, " "); got != want {
+		t.Errorf("ExtraArgs = %q, want %q", got, want)
+	}
+}
+
+func TestRunCmd_NoHooks(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+
+	forkOpts := stubForkDaemon(t)
+
+	roleContent := "name: default\ninstructions: test\nmodel: opus\npermissions:\n  allow:\n    - Read\n"
+	os.WriteFile(filepath.Join(h2Root, "roles", "default.yaml"), []byte(roleContent), 0o644)
+	baseDir := filepath.Join(h2Root, "claude-config", "default")
+	os.WriteFile(filepath.Join(baseDir, ".claude.json"), []byte(`{"oauthAccount":{}}`), 0o644)
+
+	cmd := newRunCmd()
+	cmd.SetArgs([]string{"--name", "worker", "--detach", "--no-hooks"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*forkOpts) != 1 {
+		t.Fatalf("expected 1 fork call, got %d", len(*forkOpts))
+	}
+	opts := (*forkOpts)[0]
+	if !opts.NoHooks {
+		t.Error("NoHooks should be forwarded to the daemon")
+	}
+	if want := baseDir + "-nohooks"; opts.ClaudeConfigDir != want {
+		t.Errorf("ClaudeConfigDir = %q, want %q", opts.ClaudeConfigDir, want)
+	}
+	settings, err := os.ReadFile(filepath.Join(opts.ClaudeConfigDir, "settings.json"))
+	if err != nil {
+		t.Fatalf("read settings.json: %v", err)
+	}
+	if strings.Contains(string(settings), "h2 hook collect") || strings.Contains(string(settings), "h2 permission-request") {
+		t.Errorf("no-hooks settings.json should not contain h2 hooks, got %s", settings)
+	}
+	if _, err := os.Stat(filepath.Join(opts.ClaudeConfigDir, ".claude.json")); err != nil {
+		t.Errorf("auth file should be linked into the no-hooks dir: %v", err)
+	}
+
+	// The shared config dir keeps its hooks for everyone else.
+	base, _ := os.ReadFile(filepath.Join(baseDir, "settings.json"))
+	if !strings.Contains(string(base), "h2 hook collect") {
+		t.Errorf("base settings.json should keep h2 hooks, got %s", base)
+	}
+
+	// Everything else is launched as usual.
+	if opts.Model != "opus" {
+		t.Errorf("Model = %q, want %q", opts.Model, "opus")
+	}
+	if strings.Join(opts.AllowedTools, ",") != "Read" {
+		t.Errorf("AllowedTools = %v, want [Read]", opts.AllowedTools)
+	}
+}
+
+func TestRunCmd_NoHooksRejectsPermissionAgent(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+
+	origFork := forkDaemonFunc
+	forkDaemonFunc = func(opts session.ForkDaemonOpts) error {
+		t.Fatal("should not fork")
+		return nil
+	}
+	t.Cleanup(func() { forkDaemonFunc = origFork })
+
+	roleContent := "name: default\ninstructions: test\npermissions:\n  agent:\n    instructions: review carefully\n"
+	os.WriteFile(filepath.Join(h2Root, "roles", "default.yaml"), []byte(roleContent), 0o644)
+
+	cmd := newRunCmd()
+	cmd.SetArgs([]string{"--name", "worker", "--detach", "--no-hooks"})
+	err := cmd.Execute()
+	if err == nil {
+		t.Fatal("expected error combining --no-hooks with permissions.agent")
+	}
+	if !strings.Contains(err.Error(), "permissions.agent") {
+		t.Errorf("error = %q, want mention of permissions.agent", err.Error())
+	}
+}
+
+func TestRunCmd_CreateWorkingDir(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+
+	forkOpts := stubForkDaemon(t)
+
+	workDir := filepath.Join(t.TempDir(), "projects", "new-app")
+	roleContent := "name: default\ninstructions: test\nworking_dir: " + workDir + "\ncreate_working_dir: true\n"
+	os.WriteFile(filepath.Join(h2Root, "roles", "default.yaml"), []byte(roleContent), 0o644)
+
+	cmd := newRunCmd()
+	cmd.SetArgs([]string{"--name", "worker", "--detach"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info, err := os.Stat(workDir); err != nil || !info.IsDir() {
+		t.Fatalf("working_dir should have been created: %v", err)
+	}
+	if len(*forkOpts) != 1 || (*forkOpts)[0].CWD != workDir {
+		t.Fatalf("expected launch in %s, got %+v", workDir, *forkOpts)
+	}
+}
+
+func TestRunCmd_MissingWorkingDir(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+
+	forkOpts := stubForkDaemon(t)
+
+	workDir := filepath.Join(t.TempDir(), "missing")
+	roleContent := "name: default\ninstructions: test\nworking_dir: " + workDir + "\n"
+	os.WriteFile(filepath.Join(h2Root, "roles", "default.yaml"), []byte(roleContent), 0o644)
+
+	cmd := newRunCmd()
+	cmd.SetArgs([]string{"--name", "worker", "--detach"})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "does not exist (set create_working_dir: true") {
+		t.Fatalf("expected missing working_dir error, got %v", err)
+	}
+	if len(*forkOpts) > 0 {
+		t.Error("agent should not be launched when working_dir is missing")
+	}
+	if _, err := os.Stat(workDir); !os.IsNotExist(err) {
+		t.Error("working_dir should not be created without create_working_dir")
+	}
+}
+
+// mockLaunchedAgent stands in for a freshly forked daemon: it answers
+// status requests on the agent's socket with fixed PIDs.
+func mockLaunchedAgent(t *testing.T, h2Root, name string) {
+	t.Helper()
+	sockPath := filepath.Join(h2Root, "sockets", socketdir.Format(socketdir.TypeAgent, name))
+	ln, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if _, err := message.ReadRequest(conn); err == nil {
+				message.SendResponse(conn, &message.Response{
+					OK:    true,
+					Agent: &message.AgentInfo{Name: name, State: "idle", PID: 4242, DaemonPID: 4241},
+				})
+			}
+			conn.Close()
+		}
+	}()
+}
+
+func TestRunCmd_DetachedLaunchSummary(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+
+	origFork := forkDaemonFunc
+	forkDaemonFunc = func(opts session.ForkDaemonOpts) error {
+		mockLaunchedAgent(t, h2Root, opts.Name)
+		return nil
+	}
+	t.Cleanup(func() { forkDaemonFunc = origFork })
+
+	workDir := t.TempDir()
+	roleContent := "name: default\ninstructions: test\nmodel: opus\nworking_dir: " + workDir + "\n"
+	os.WriteFile(filepath.Join(h2Root, "roles", "default.yaml"), []byte(roleContent), 0o644)
+
+	var out bytes.Buffer
+	cmd := newRunCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--name", "worker", "--pod", "team", "--detach"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var sum launchSummary
+	if err := json.Unmarshal(out.Bytes(), &sum); err != nil {
+		t.Fatalf("summary is not valid JSON: %v\n%s", err, out.String())
+	}
+	want := launchSummary{
+		Name:       "worker",
+		Pod:        "team",
+		WorkingDir: workDir,
+		Model:      "opus",
+		Socket:     filepath.Join(h2Root, "sockets", socketdir.Format(socketdir.TypeAgent, "worker")),
+		PID:        4242,
+		DaemonPID:  4241,
+	}
+	if sum != want {
+		t.Errorf("summary = %+v, want %+v", sum, want)
+	}
+}
+
+func TestRunCmd_SummaryFile(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+
+	origFork := forkDaemonFunc
+	forkDaemonFunc = func(opts session.ForkDaemonOpts) error {
+		mockLaunchedAgent(t, h2Root, opts.Name)
+		return nil
+	}
+	t.Cleanup(func() { forkDaemonFunc = origFork })
+
+	summaryPath := filepath.Join(t.TempDir(), "launch.json")
+	var out bytes.Buffer
+	cmd := newRunCmd()
+	cmd.SetOut(&out)
+	cmd.SetArgs([]string{"--command", "cat", "--name", "catter", "--detach", "--summary-file", summaryPath})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("--summary-file should keep stdout quiet, got %q", out.String())
+	}
+	data, err := os.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatalf("read summary file: %v", err)
+	}
+	var sum launchSummary
+	if err := json.Unmarshal(data, &sum); err != nil {
+		t.Fatalf("summary file is not valid JSON: %v", err)
+	}
+	cwd, _ := os.Getwd()
+	if sum.Name != "catter" || sum.WorkingDir != cwd || sum.PID != 4242 {
+		t.Errorf("unexpected summary %+v", sum)
+	}
+}
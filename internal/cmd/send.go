@@ -2,12 +2,14 @@ package cmd
 
 import (
 	"fmt"
-	"net"
 	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"h2/internal/session"
 	"h2/internal/session/message"
 	"h2/internal/socketdir"
 )
@@ -15,17 +17,61 @@ import (
 func newSendCmd() *cobra.Command {
 	var priority string
 	var file string
+	var attach string
 	var allowSelf bool
 	var raw bool
+	var unsafe bool
+	var escalateAfter string
+	var timeout time.Duration
+	var cid string
+	var from string
+	var interruptFirst bool
 
 	cmd := &cobra.Command{
-		Use:   "send <name> [--priority=normal] [--file=path] [--raw] [message...]",
+		Use:   "send <name> [--priority=normal] [--file=path] [--attach=path] [--raw [--unsafe]] [message...]",
 		Short: "Send a message to an agent",
-		Long:  "Send a message to a running agent. The message body can be provided as arguments or read from a file.\nWith --raw, the body is sent directly to the agent's PTY without the [h2 message from: ...] prefix. This is useful for responding to permission prompts remotely.",
-		Args:  cobra.MinimumNArgs(1),
+		Long: `Send a message to a running agent. The message body can be provided as arguments or read from a file.
+
+Delivery modes:
+  (default)        Body is delivered as "[h2 message from: <sender>] <body>".
+  --raw / --quiet  Body is typed into the agent's PTY exactly, with no sender
+                   prefix. Control characters other than tab and newline are
+                   stripped. Useful for responding to permission prompts.
+  --raw --unsafe   Like --raw, but the bytes are delivered untouched, including
+                   control characters and escape sequences, and without the
+                   backslash cleanup applied to message arguments.
+
+The sender defaults to $H2_ACTOR (then git user.name, then $USER). Use
+--from to send on behalf of another named actor, e.g. from a script; it must
+be a valid agent name.
+
+Use --interrupt-first for "stop what you're doing and do this instead": the
+agent is interrupted (Ctrl+C), given a moment to settle back at its prompt,
+and then handed the message at interrupt priority, as one queued delivery.
+It also works with --raw.
+
+Use --attach to send a file along with the message (the body is then
+optional). The agent is pointed at the file's absolute path; Claude agents
+get it as an @path mention so the file is read into the conversation.
+
+If the agent's role sets dedupe_window, a message with the same sender and
+body as one sent within that window is dropped: the original message's ID is
+printed and a note on stderr says it was not queued again.`,
+		Args: cobra.MinimumNArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			name := args[0]
 
+			var attachment string
+			if attach != "" {
+				if raw {
+					return fmt.Errorf("--attach can't be combined with --raw")
+				}
+				var err error
+				if attachment, err = resolveAttachment(attach); err != nil {
+					return err
+				}
+			}
+
 			var body string
 			if file != "" {
 				data, err := os.ReadFile(file)
@@ -34,16 +80,42 @@ func newSendCmd() *cobra.Command {
 				}
 				body = string(data)
 			} else if len(args) > 1 {
-				body = cleanLLMEscapes(strings.Join(args[1:], " "))
-			} else {
-				return fmt.Errorf("message body is required (provide as arguments or --file)")
+				body = strings.Join(args[1:], " ")
+				if !unsafe {
+					body = cleanLLMEscapes(body)
+				}
+			} else if attachment == "" {
+				return fmt.Errorf("message body is required (provide as arguments, --file, or --attach)")
 			}
 
 			if priority == "" {
 				priority = "normal"
 			}
 
-			from := resolveActor()
+			if unsafe && !raw {
+				return fmt.Errorf("--unsafe requires --raw")
+			}
+
+			if interruptFirst {
+				if cmd.Flags().Changed("priority") && priority != "interrupt" {
+					return fmt.Errorf("--interrupt-first delivers at interrupt priority; drop --priority=%s", priority)
+				}
+				priority = "interrupt"
+			}
+
+			if escalateAfter != "" {
+				if d, err := time.ParseDuration(escalateAfter); err != nil || d <= 0 {
+					return fmt.Errorf("invalid --escalate-after %q: must be a positive duration like \"5m\"", escalateAfter)
+				}
+			}
+
+			if cmd.Flags().Changed("from") {
+				if err := session.ValidateAgentName(from); err != nil {
+					return fmt.Errorf("invalid --from: %w", err)
+				}
+			} else {
+				from = resolveActor()
+			}
 
 			if !allowSelf {
 				if actor := os.Getenv("H2_ACTOR"); actor != "" && actor == name {
@@ -55,30 +127,35 @@ func newSendCmd() *cobra.Command {
 			if findErr != nil {
 				return agentConnError(name, findErr)
 			}
-			conn, err := net.Dial("unix", sockPath)
+			conn, err := dialSocket(sockPath, timeout)
 			if err != nil {
 				return agentConnError(name, err)
 			}
 			defer conn.Close()
 
-			if err := message.SendRequest(conn, &message.Request{
+			resp, err := exchangeSocket(conn, &message.Request{
 				Type:     "send",
 				Priority: priority,
 				From:     from,
 				Body:     body,
 				Raw:      raw,
-			}); err != nil {
-				return fmt.Errorf("send request: %w", err)
-			}
+				Unsafe:   unsafe,
 
-			resp, err := message.ReadResponse(conn)
+				InterruptFirst: interruptFirst,
+				EscalateAfter:  escalateAfter,
+				CorrelationID:  cid,
+				Attachment:     attachment,
+			}, timeout)
 			if err != nil {
-				return fmt.Errorf("read response: %w", err)
+				return err
 			}
 			if !resp.OK {
 				return fmt.Errorf("send failed: %s", resp.Error)
 			}
 
+			if resp.Deduped {
+				fmt.Fprintf(os.Stderr, "Duplicate of message %s sent within the dedupe window; not queued again\n", resp.MessageID)
+			}
 			fmt.Println(resp.MessageID)
 			return nil
 		},
@@ -86,12 +163,43 @@ func newSendCmd() *cobra.Command {
 
 	cmd.Flags().StringVar(&priority, "priority", "normal", "Message priority (interrupt|normal|idle-first|idle)")
 	cmd.Flags().StringVar(&file, "file", "", "Read message body from file")
+	cmd.Flags().StringVar(&attach, "attach", "", "Attach a file to the message (referenced by absolute path)")
 	cmd.Flags().BoolVar(&allowSelf, "allow-self", false, "Allow sending a message to yourself")
+	cmd.Flags().StringVar(&escalateAfter, "escalate-after", "", "Promote an idle/idle-first message to interrupt if still queued after this duration (e.g. 10m)")
 	cmd.Flags().BoolVar(&raw, "raw", false, "Send body directly to PTY without [h2 message from: ...] prefix (useful for permission prompts)")
+	cmd.Flags().BoolVar(&raw, "quiet", false, "Alias for --raw")
+	cmd.Flags().BoolVar(&unsafe, "unsafe", false, "With --raw, deliver control characters and escape sequences untouched")
+	cmd.Flags().BoolVar(&interruptFirst, "interrupt-first", false, "Interrupt the agent's current work, then deliver the message at interrupt priority")
+	cmd.Flags().StringVar(&from, "from", "", "Sender name shown to the agent (default $H2_ACTOR)")
+	cmd.Flags().StringVar(&cid, "cid", "", "Correlation ID stamped on the message and the agent's activity-log events while it processes it")
+	cmd.Flags().DurationVar(&timeout, "timeout", defaultSocketTimeout, "How long to keep retrying a busy agent socket (0 = single attempt)")
 
 	return cmd
 }
 
+// resolveAttachment returns the absolute path of an --attach file, checking
+// that it is a regular file the sender can read. The path is resolved here
+// because the agent's daemon runs in a different working directory.
+func resolveAttachment(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("attach: %w", err)
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return "", fmt.Errorf("attach: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("attach: not a regular file: %s", path)
+	}
+	f, err := os.Open(abs)
+	if err != nil {
+		return "", fmt.Errorf("attach: %w", err)
+	}
+	f.Close()
+	return abs, nil
+}
+
 // cleanLLMEscapes removes spurious backslash escapes that LLMs insert into
 // shell command arguments. For example, Claude Code often writes \! or \?
 // in strings even though these characters don't need escaping. We only strip
@@ -1,9 +1,14 @@
 package cmd
 
 import (
+	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"h2/internal/session/message"
+	"h2/internal/socketdir"
 )
 
 func TestSendCmd_SelfSendBlocked(t *testing.T) {
@@ -76,3 +81,133 @@ func TestSendCmd_SelfSendAllowedWithFlag(t *testing.T) {
 		t.Fatal("--allow-self flag did not bypass self-send check")
 	}
 }
+
+func TestSendCmd_UnsafeRequiresRaw(t *testing.T) {
+	t.Setenv("H2_ACTOR", "")
+
+	cmd := newSendCmd()
+	cmd.SetArgs([]string{"someone", "--unsafe", "hello"})
+
+	err := cmd.Execute()
+	if err == nil || err.Error() != "--unsafe requires --raw" {
+		t.Fatalf("expected --unsafe requires --raw error, got %v", err)
+	}
+}
+
+func TestSendCmd_FromOverridesActor(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	t.Setenv("H2_ACTOR", "someone-else")
+
+	sockPath := filepath.Join(h2Root, "sockets", socketdir.Format(socketdir.TypeAgent, "worker"))
+	ln, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	got := make(chan *message.Request, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		req, err := message.ReadRequest(conn)
+		if err != nil {
+			return
+		}
+		got <- req
+		message.SendResponse(conn, &message.Response{OK: true, MessageID: "m1"})
+	}()
+
+	cmd := newSendCmd()
+	cmd.SetArgs([]string{"worker", "--from", "deploy-bot", "ship it"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	req := <-got
+	if req.From != "deploy-bot" {
+		t.Errorf("From = %q, want %q", req.From, "deploy-bot")
+	}
+}
+
+func TestSendCmd_FromValidated(t *testing.T) {
+	setupPodTestEnv(t)
+
+	cmd := newSendCmd()
+	cmd.SetArgs([]string{"worker", "--from", "../evil", "hello"})
+	err := cmd.Execute()
+	if err == nil {
+		t.Fatal("expected error for invalid --from")
+	}
+	if !strings.Contains(err.Error(), "invalid --from") {
+		t.Errorf("error = %q, want invalid --from", err.Error())
+	}
+}
+
+func TestSendCmd_AttachSendsAbsolutePath(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	t.Setenv("H2_ACTOR", "")
+
+	sockPath := filepath.Join(h2Root, "sockets", socketdir.Format(socketdir.TypeAgent, "worker"))
+	ln, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer ln.Close()
+	got := make(chan *message.Request, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		req, err := message.ReadRequest(conn)
+		if err != nil {
+			return
+		}
+		got <- req
+		message.SendResponse(conn, &message.Response{OK: true, MessageID: "m1"})
+	}()
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "shot.png"), []byte("png"), 0o644)
+	wd, _ := os.Getwd()
+	os.Chdir(dir)
+	defer os.Chdir(wd)
+
+	// No body needed when a file is attached; the path is made absolute.
+	cmd := newSendCmd()
+	cmd.SetArgs([]string{"worker", "--attach", "shot.png"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	req := <-got
+	want, _ := filepath.Abs(filepath.Join(dir, "shot.png"))
+	if req.Attachment != want {
+		t.Errorf("Attachment = %q, want %q", req.Attachment, want)
+	}
+}
+
+func TestSendCmd_AttachValidated(t *testing.T) {
+	t.Setenv("H2_ACTOR", "")
+	dir := t.TempDir()
+	file := filepath.Join(dir, "notes.txt")
+	os.WriteFile(file, []byte("x"), 0o644)
+
+	for _, tc := range []struct {
+		args []string
+		want string
+	}{
+		{[]string{"someone", "--attach", filepath.Join(dir, "missing.png")}, "no such file"},
+		{[]string{"someone", "--attach", dir}, "not a regular file"},
+		{[]string{"someone", "--raw", "--attach", file, "hi"}, "--attach can't be combined with --raw"},
+	} {
+		cmd := newSendCmd()
+		cmd.SetArgs(tc.args)
+		cmd.SilenceUsage = true
+		cmd.SilenceErrors = true
+		if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%v: expected error containing %q, got %v", tc.args, tc.want, err)
+		}
+	}
+}
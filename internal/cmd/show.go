@@ -33,6 +33,9 @@ func newShowCmd() *cobra.Command {
 					fmt.Printf("Priority:    %s\n", info.Priority)
 					fmt.Printf("Status:      %s\n", info.Status)
 					fmt.Printf("File:        %s\n", info.FilePath)
+					if info.Attachment != "" {
+						fmt.Printf("Attachment:  %s\n", info.Attachment)
+					}
 					fmt.Printf("Created:     %s\n", info.CreatedAt)
 					if info.DeliveredAt != "" {
 						fmt.Printf("Delivered:   %s\n", info.DeliveredAt)
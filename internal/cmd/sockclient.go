@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"syscall"
+	"time"
+
+	"h2/internal/session/message"
+)
+
+// defaultSocketTimeout bounds how long control commands keep retrying a
+// daemon socket that is momentarily not accepting connections.
+const defaultSocketTimeout = 5 * time.Second
+
+// socketRetryInterval is the pause between connection attempts.
+// Var so tests can override it.
+var socketRetryInterval = 100 * time.Millisecond
+
+// isRetryableDialError reports whether a failed dial may succeed shortly:
+// the daemon is restarting its listener or its accept backlog is full.
+func isRetryableDialError(err error) bool {
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.EAGAIN) {
+		return true
+	}
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// isAgentGone reports whether a failed dial means the daemon is no longer
+// running: its socket was removed, or nothing is listening on it.
+func isAgentGone(err error) bool {
+	return errors.Is(err, syscall.ENOENT) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// dialSocket connects to a daemon socket, retrying transient failures until
+// timeout elapses. A timeout of zero makes a single attempt.
+func dialSocket(sockPath string, timeout time.Duration) (net.Conn, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.DialTimeout("unix", sockPath, max(time.Until(deadline), socketRetryInterval))
+		if err == nil {
+			return conn, nil
+		}
+		if !isRetryableDialError(err) {
+			return nil, err
+		}
+		if time.Now().Add(socketRetryInterval).After(deadline) {
+			if timeout > 0 {
+				return nil, fmt.Errorf("gave up after %s: %w", timeout, err)
+			}
+			return nil, err
+		}
+		time.Sleep(socketRetryInterval)
+	}
+}
+
+// exchangeSocket sends req on an open daemon connection and reads the
+// response, failing if the daemon doesn't answer within timeout (zero means
+// no limit). The request is never resent, so non-idempotent requests like
+// "send" are delivered at most once.
+func exchangeSocket(conn net.Conn, req *message.Request, timeout time.Duration) (*message.Response, error) {
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+	if err := message.SendRequest(conn, req); err != nil {
+		return nil, fmt.Errorf("send request: %w", err)
+	}
+	resp, err := message.ReadResponse(conn)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	return resp, nil
+}
@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"h2/internal/session/message"
+)
+
+// shortSockPath returns a socket path short enough for macOS's limit.
+func shortSockPath(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("/tmp", "h2t-sock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "agent.sock")
+}
+
+// leaveStaleSocket creates a socket file with no listener behind it, so
+// dials fail with connection refused.
+func leaveStaleSocket(t *testing.T, path string) {
+	t.Helper()
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+}
+
+func TestQueryAgentStatus_RetriesUntilDaemonListens(t *testing.T) {
+	oldInterval := socketRetryInterval
+	socketRetryInterval = 20 * time.Millisecond
+	t.Cleanup(func() { socketRetryInterval = oldInterval })
+
+	path := shortSockPath(t)
+	leaveStaleSocket(t, path)
+
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		os.Remove(path)
+		ln, err := net.Listen("unix", path)
+		if err != nil {
+			return
+		}
+		defer ln.Close()
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		if _, err := message.ReadRequest(conn); err == nil {
+			message.SendResponse(conn, &message.Response{
+				OK:    true,
+				Agent: &message.AgentInfo{Name: "late", State: "idle"},
+			})
+		}
+	}()
+
+	start := time.Now()
+	info, err := queryAgentStatus(path, "late", 3*time.Second)
+	if err != nil {
+		t.Fatalf("expected retry to succeed, got %v", err)
+	}
+	if info.Name != "late" {
+		t.Errorf("Name = %q, want late", info.Name)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("returned after %s; expected to wait for the delayed listener", elapsed)
+	}
+}
+
+func TestDialSocket_FailsPastTimeout(t *testing.T) {
+	oldInterval := socketRetryInterval
+	socketRetryInterval = 20 * time.Millisecond
+	t.Cleanup(func() { socketRetryInterval = oldInterval })
+
+	path := shortSockPath(t)
+	leaveStaleSocket(t, path)
+
+	start := time.Now()
+	_, err := dialSocket(path, 200*time.Millisecond)
+	elapsed := time.Since(start)
+	if err == nil {
+		t.Fatal("expected error when the daemon never listens")
+	}
+	if !strings.Contains(err.Error(), "gave up after 200ms") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if elapsed < 150*time.Millisecond || elapsed > 2*time.Second {
+		t.Errorf("gave up after %s; want about 200ms", elapsed)
+	}
+}
+
+func TestDialSocket_ZeroTimeoutSingleAttempt(t *testing.T) {
+	path := shortSockPath(t)
+	leaveStaleSocket(t, path)
+
+	start := time.Now()
+	if _, err := dialSocket(path, 0); err == nil {
+		t.Fatal("expected error")
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("zero timeout should not retry, took %s", elapsed)
+	}
+}
+
+func TestExchangeSocket_ReadTimeout(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	defer client.Close()
+	go message.ReadRequest(server) // read the request but never answer
+
+	_, err := exchangeSocket(client, &message.Request{Type: "status"}, 100*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "read response") {
+		t.Fatalf("expected read timeout, got %v", err)
+	}
+}
@@ -1,52 +1,121 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"h2/internal/config"
 	"h2/internal/session/message"
 	"h2/internal/socketdir"
 )
 
+// statusPollInterval is how often --wait-for re-queries the agent.
+// Var so tests can override it.
+var statusPollInterval = 500 * time.Millisecond
+
+// watchReconnectWindow is how long --watch waits for an agent whose daemon
+// went away to come back before reporting it exited. Var so tests can
+// override it.
+var watchReconnectWindow = 10 * time.Second
+
+// waitableStates are the states accepted by --wait-for.
+var waitableStates = []string{"initialized", "active", "idle", "exited"}
+
 func newStatusCmd() *cobra.Command {
-	return &cobra.Command{
+	var waitFor string
+	var wait time.Duration
+	var timeout time.Duration
+	var fast bool
+	var watch bool
+
+	cmd := &cobra.Command{
 		Use:   "status <name>",
 		Short: "Show agent status",
-		Long:  "Query a single agent's status and print it as JSON.",
-		Args:  cobra.ExactArgs(1),
+		Long: `Query a single agent's status and print it as JSON.
+
+With --wait-for, block until the agent reaches the given state (initialized,
+active, idle, or exited), then print its status. Exits non-zero if --wait
+elapses first; without --wait, --timeout also bounds the whole wait.
+
+With --fast, print the agent's lifecycle state file (daemon and child PIDs,
+socket, role, pod, start time) without contacting the daemon.
+
+With --watch, print a timestamped line for the current state and each state
+change until Ctrl+C. If the agent's daemon restarts, the watch reconnects;
+once the agent is gone for good it prints a final "exited" line and exits.`,
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			name := args[0]
 
-			sockPath, err := socketdir.Find(name)
-			if err != nil {
-				return agentConnError(name, err)
+			if watch {
+				if fast || waitFor != "" {
+					return fmt.Errorf("--watch cannot be combined with --fast or --wait-for")
+				}
+				ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+				defer stop()
+				return watchAgentState(ctx, name, cmd.OutOrStdout())
+			}
+
+			if fast {
+				if waitFor != "" {
+					return fmt.Errorf("--fast cannot be combined with --wait-for")
+				}
+				st, err := readLifecycleState(name)
+				if err != nil {
+					return err
+				}
+				out, err := json.MarshalIndent(st, "", "  ")
+				if err != nil {
+					return fmt.Errorf("marshal: %w", err)
+				}
+				fmt.Println(string(out))
+				return nil
 			}
 
-			conn, err := net.Dial("unix", sockPath)
+			sockPath, err := socketdir.Find(name)
 			if err != nil {
 				return agentConnError(name, err)
 			}
-			defer conn.Close()
 
-			if err := message.SendRequest(conn, &message.Request{Type: "status"}); err != nil {
-				return fmt.Errorf("send request: %w", err)
+			queryTimeout := timeout
+			if queryTimeout == 0 {
+				queryTimeout = defaultSocketTimeout
 			}
-
-			resp, err := message.ReadResponse(conn)
-			if err != nil {
-				return fmt.Errorf("read response: %w", err)
+			var info *message.AgentInfo
+			if waitFor != "" {
+				if !isWaitableState(waitFor) {
+					return fmt.Errorf("invalid --wait-for state %q; valid states: initialized, active, idle, exited", waitFor)
+				}
+				if !cmd.Flags().Changed("wait") {
+					// h2 status <name> --wait-for idle --timeout 60s
+					wait = timeout
+				}
+				info, err = waitForAgentState(sockPath, name, waitFor, wait, queryTimeout)
+			} else {
+				if wait != 0 {
+					return fmt.Errorf("--wait requires --wait-for")
+				}
+				info, err = queryAgentStatus(sockPath, name, queryTimeout)
 			}
-			if !resp.OK {
-				return fmt.Errorf("status failed: %s", resp.Error)
+			if err != nil {
+				return err
 			}
-			if resp.Agent == nil {
-				return fmt.Errorf("no agent info in response")
+			if info == nil {
+				// Agent exited while waiting for "exited"; nothing more to report.
+				fmt.Printf("agent %q exited\n", name)
+				return nil
 			}
 
-			out, err := json.MarshalIndent(resp.Agent, "", "  ")
+			out, err := json.MarshalIndent(info, "", "  ")
 			if err != nil {
 				return fmt.Errorf("marshal: %w", err)
 			}
@@ -54,4 +123,210 @@ func newStatusCmd() *cobra.Command {
 			return nil
 		},
 	}
+
+	cmd.Flags().StringVar(&waitFor, "wait-for", "", "Block until the agent reaches this state (initialized, active, idle, exited)")
+	cmd.Flags().DurationVar(&wait, "wait", 0, "Maximum time to wait with --wait-for (0 = wait forever)")
+	cmd.Flags().DurationVar(&timeout, "timeout", 0, "How long to retry a busy agent socket and to wait for each answer (0 = 5s); with --wait-for and no --wait, also the maximum wait")
+	cmd.Flags().BoolVar(&fast, "fast", false, "Read the agent's lifecycle state file instead of querying its socket")
+	cmd.Flags().BoolVar(&watch, "watch", false, "Stream the agent's state changes until Ctrl+C")
+
+	return cmd
+}
+
+func isWaitableState(state string) bool {
+	for _, s := range waitableStates {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+// readLifecycleState loads an agent's lifecycle.json, rejecting files left
+// behind by a daemon that is no longer running.
+func readLifecycleState(name string) (*config.LifecycleState, error) {
+	st, err := config.ReadLifecycleState(config.SessionDir(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no lifecycle state for agent %q (is it running?)", name)
+		}
+		return nil, err
+	}
+	if !st.Alive() {
+		return nil, fmt.Errorf("agent %q is not running (stale lifecycle state for pid %d)", name, st.DaemonPID)
+	}
+	return st, nil
+}
+
+// queryAgentStatus sends a status request to the agent socket and returns
+// the agent info, surfacing connection and protocol errors. Transient
+// connection failures are retried for up to timeout (0 = single attempt),
+// and the daemon must answer within timeout (0 = no limit).
+func queryAgentStatus(sockPath, name string, timeout time.Duration) (*message.AgentInfo, error) {
+	conn, err := dialSocket(sockPath, timeout)
+	if err != nil {
+		return nil, agentConnError(name, err)
+	}
+	return readAgentStatus(conn, timeout)
+}
+
+// readAgentStatus sends a status request on conn, waiting at most timeout
+// for the answer, and closes conn.
+func readAgentStatus(conn net.Conn, timeout time.Duration) (*message.AgentInfo, error) {
+	defer conn.Close()
+
+	resp, err := exchangeSocket(conn, &message.Request{Type: "status"}, timeout)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.OK {
+		return nil, fmt.Errorf("status failed: %s", resp.Error)
+	}
+	if resp.Agent == nil {
+		return nil, fmt.Errorf("no agent info in response")
+	}
+	return resp.Agent, nil
+}
+
+// waitForAgentState polls the agent until its state matches target or wait
+// elapses. A wait of zero waits indefinitely. Each poll must be answered
+// within queryTimeout, so a hung daemon fails the wait instead of blocking
+// it. When waiting for "exited", the agent's socket going away (removed, or
+// refusing connections) counts as reaching the state and a nil info is
+// returned; any other error is reported as is.
+func waitForAgentState(sockPath, name, target string, wait, queryTimeout time.Duration) (*message.AgentInfo, error) {
+	var deadline time.Time
+	if wait > 0 {
+		deadline = time.Now().Add(wait)
+	}
+
+	lastState := ""
+	for {
+		// Single connection attempt per poll: a vanished socket means the
+		// agent exited.
+		conn, err := dialSocket(sockPath, 0)
+		if err != nil {
+			if target == "exited" && isAgentGone(err) {
+				return nil, nil
+			}
+			return nil, agentConnError(name, err)
+		}
+		info, err := readAgentStatus(conn, queryTimeout)
+		if err != nil {
+			return nil, err
+		}
+		if info.State == target {
+			return info, nil
+		}
+		lastState = info.State
+
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out after %s waiting for agent %q to become %s (current state: %s)",
+				wait, name, target, lastState)
+		}
+		time.Sleep(statusPollInterval)
+	}
+}
+
+// watchAgentState prints a line for the agent's state and each transition
+// until ctx is done or the agent exits. When the daemon goes away, it waits
+// up to watchReconnectWindow for the agent to come back and resumes the
+// stream; otherwise it prints a final "exited" line and returns nil.
+func watchAgentState(ctx context.Context, name string, w io.Writer) error {
+	sockPath, err := socketdir.Find(name)
+	if err != nil {
+		return agentConnError(name, err)
+	}
+	conn, err := dialSocket(sockPath, defaultSocketTimeout)
+	if err != nil {
+		return agentConnError(name, err)
+	}
+
+	last := ""
+	for {
+		last, err = streamAgentStates(ctx, conn, w, last)
+		if err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		conn = redialAgent(ctx, name)
+		if conn == nil {
+			if ctx.Err() == nil && last != "exited" {
+				fmt.Fprintf(w, "%s  exited\n", time.Now().Format(time.RFC3339))
+			}
+			return nil
+		}
+		fmt.Fprintf(w, "%s  reconnected\n", time.Now().Format(time.RFC3339))
+	}
+}
+
+// streamAgentStates sends a watch_state request on conn and prints each
+// status the daemon streams back until the connection ends, returning the
+// last state printed. last is the state printed before this connection, so
+// a reconnect doesn't repeat an unchanged line. It closes conn.
+func streamAgentStates(ctx context.Context, conn net.Conn, w io.Writer, last string) (string, error) {
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if err := message.SendRequest(conn, &message.Request{Type: "watch_state"}); err != nil {
+		return last, nil // the daemon went away
+	}
+	first := true
+	dec := json.NewDecoder(conn)
+	for {
+		var resp message.Response
+		if err := dec.Decode(&resp); err != nil {
+			return last, nil
+		}
+		if !resp.OK {
+			return last, fmt.Errorf("watch failed: %s", resp.Error)
+		}
+		if resp.Agent == nil {
+			return last, fmt.Errorf("no agent info in response")
+		}
+		if first && resp.Agent.State == last {
+			// Unchanged since before a reconnect.
+			first = false
+			continue
+		}
+		first = false
+		fmt.Fprintln(w, formatStateLine(resp.Agent))
+		last = resp.Agent.State
+	}
+}
+
+// redialAgent polls for the agent's socket for up to watchReconnectWindow,
+// returning a connection once it answers, or nil.
+func redialAgent(ctx context.Context, name string) net.Conn {
+	deadline := time.Now().Add(watchReconnectWindow)
+	for time.Now().Before(deadline) {
+		if sockPath, err := socketdir.Find(name); err == nil {
+			if conn, err := dialSocket(sockPath, 0); err == nil {
+				return conn
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(statusPollInterval):
+		}
+	}
+	return nil
+}
+
+// formatStateLine renders one --watch line: when the agent entered the
+// state, the state, and its sub-state if any.
+func formatStateLine(info *message.AgentInfo) string {
+	ts := time.Now()
+	if t, err := time.Parse(time.RFC3339Nano, info.StateChangedAt); err == nil {
+		ts = t.Local()
+	}
+	line := ts.Format(time.RFC3339) + "  " + info.State
+	if info.SubState != "" {
+		line += " (" + info.SubState + ")"
+	}
+	return line
 }
@@ -0,0 +1,334 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"h2/internal/config"
+	"h2/internal/session/message"
+	"h2/internal/socketdir"
+)
+
+// startStatusAgent serves status requests on a mock agent socket, reporting
+// whatever state stateFn returns for each query.
+func startStatusAgent(t *testing.T, h2Root, name string, stateFn func() string) {
+	t.Helper()
+	sockPath := filepath.Join(h2Root, "sockets", socketdir.Format(socketdir.TypeAgent, name))
+	ln, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if req, err := message.ReadRequest(conn); err == nil && req.Type == "status" {
+				message.SendResponse(conn, &message.Response{
+					OK:    true,
+					Agent: &message.AgentInfo{Name: name, State: stateFn()},
+				})
+			}
+			conn.Close()
+		}
+	}()
+}
+
+func TestStatusWaitFor_AlreadyInState(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	startStatusAgent(t, h2Root, "coder", func() string { return "idle" })
+
+	cmd := newStatusCmd()
+	cmd.SetArgs([]string{"coder", "--wait-for", "idle", "--wait", "5s"})
+
+	start := time.Now()
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected prompt return, took %s", elapsed)
+	}
+}
+
+func TestStatusWaitFor_ReachesStateAfterPolling(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	old := statusPollInterval
+	statusPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { statusPollInterval = old })
+
+	var queries atomic.Int32
+	startStatusAgent(t, h2Root, "coder", func() string {
+		if queries.Add(1) < 3 {
+			return "active"
+		}
+		return "idle"
+	})
+
+	info, err := waitForAgentState(filepath.Join(h2Root, "sockets", socketdir.Format(socketdir.TypeAgent, "coder")), "coder", "idle", 5*time.Second, time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.State != "idle" {
+		t.Errorf("state = %q, want idle", info.State)
+	}
+	if n := queries.Load(); n < 3 {
+		t.Errorf("expected at least 3 queries, got %d", n)
+	}
+}
+
+func TestStatusWaitFor_Timeout(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	old := statusPollInterval
+	statusPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { statusPollInterval = old })
+
+	startStatusAgent(t, h2Root, "coder", func() string { return "active" })
+
+	cmd := newStatusCmd()
+	cmd.SetArgs([]string{"coder", "--wait-for", "idle", "--wait", "100ms"})
+	err := cmd.Execute()
+	if err == nil {
+		t.Fatal("expected timeout error")
+	}
+	if !strings.Contains(err.Error(), "timed out") || !strings.Contains(err.Error(), "active") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestStatusWaitFor_TimeoutBoundsWaitWithoutWait(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	old := statusPollInterval
+	statusPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { statusPollInterval = old })
+
+	startStatusAgent(t, h2Root, "coder", func() string { return "active" })
+
+	cmd := newStatusCmd()
+	cmd.SetArgs([]string{"coder", "--wait-for", "idle", "--timeout", "200ms"})
+	start := time.Now()
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "timed out after 200ms") {
+		t.Fatalf("expected the wait to time out after --timeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("wait took %s, want it bounded by --timeout", elapsed)
+	}
+}
+
+func TestStatusWaitFor_HungAgentFailsEachQuery(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	sockPath := filepath.Join(h2Root, "sockets", socketdir.Format(socketdir.TypeAgent, "coder"))
+	ln, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { conn.Close() }) // accept, never answer
+		}
+	}()
+
+	start := time.Now()
+	_, err = waitForAgentState(sockPath, "coder", "idle", 0, 100*time.Millisecond)
+	if err == nil {
+		t.Fatal("expected an error from an agent that never answers")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("wait blocked for %s; each query should give up after its timeout", elapsed)
+	}
+}
+
+func TestStatusWaitFor_ExitedWhenSocketGone(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	sockPath := filepath.Join(h2Root, "sockets", socketdir.Format(socketdir.TypeAgent, "coder"))
+
+	info, err := waitForAgentState(sockPath, "coder", "exited", time.Second, time.Second)
+	if err != nil || info != nil {
+		t.Fatalf("missing socket: got %+v, %v; want exited", info, err)
+	}
+}
+
+func TestStatusWaitFor_ExitedReportsOtherErrors(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	sockPath := filepath.Join(h2Root, "sockets", socketdir.Format(socketdir.TypeAgent, "coder"))
+	ln, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			message.ReadRequest(conn)
+			message.SendResponse(conn, &message.Response{Error: "internal error"})
+			conn.Close()
+		}
+	}()
+
+	_, err = waitForAgentState(sockPath, "coder", "exited", time.Second, time.Second)
+	if err == nil || !strings.Contains(err.Error(), "internal error") {
+		t.Fatalf("a failed query of a running agent should be reported, got %v", err)
+	}
+}
+
+func TestStatus_WaitRequiresWaitFor(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	startStatusAgent(t, h2Root, "coder", func() string { return "idle" })
+
+	cmd := newStatusCmd()
+	cmd.SetArgs([]string{"coder", "--wait", "5s"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "--wait requires --wait-for") {
+		t.Fatalf("expected --wait usage error, got %v", err)
+	}
+}
+
+func TestStatusWaitFor_InvalidState(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	startStatusAgent(t, h2Root, "coder", func() string { return "idle" })
+
+	cmd := newStatusCmd()
+	cmd.SetArgs([]string{"coder", "--wait-for", "sleeping"})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "invalid --wait-for state") {
+		t.Fatalf("expected invalid state error, got %v", err)
+	}
+}
+
+func TestStatusFast_ReadsLifecycleState(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	sessionDir := filepath.Join(h2Root, "sessions", "coder")
+	os.MkdirAll(sessionDir, 0o755)
+	if err := config.WriteLifecycleState(sessionDir, config.LifecycleState{
+		AgentName: "coder",
+		DaemonPID: os.Getpid(),
+		ChildPID:  4242,
+		Role:      "coder",
+		StartedAt: "2026-01-02T03:04:05Z",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := newStatusCmd()
+	cmd.SetArgs([]string{"coder", "--fast"})
+	var execErr error
+	out := captureStdout(func() { execErr = cmd.Execute() })
+	if execErr != nil {
+		t.Fatalf("unexpected error: %v", execErr)
+	}
+	if !strings.Contains(out, `"child_pid": 4242`) || !strings.Contains(out, `"role": "coder"`) {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+}
+
+func TestStatusFast_StaleState(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	sessionDir := filepath.Join(h2Root, "sessions", "coder")
+	os.MkdirAll(sessionDir, 0o755)
+	config.WriteLifecycleState(sessionDir, config.LifecycleState{AgentName: "coder", DaemonPID: 1 << 30})
+
+	cmd := newStatusCmd()
+	cmd.SetArgs([]string{"coder", "--fast"})
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "not running") {
+		t.Fatalf("expected stale state error, got %v", err)
+	}
+}
+
+func TestStatusWatch_StreamsUntilAgentGone(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	oldPoll, oldWindow := statusPollInterval, watchReconnectWindow
+	statusPollInterval = 10 * time.Millisecond
+	watchReconnectWindow = 100 * time.Millisecond
+	t.Cleanup(func() { statusPollInterval, watchReconnectWindow = oldPoll, oldWindow })
+
+	sockPath := filepath.Join(h2Root, "sockets", socketdir.Format(socketdir.TypeAgent, "coder"))
+	ln, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		// Serve one watch, then take the agent away entirely.
+		ln.Close()
+		defer conn.Close()
+		if req, err := message.ReadRequest(conn); err != nil || req.Type != "watch_state" {
+			return
+		}
+		message.SendResponse(conn, &message.Response{OK: true, Agent: &message.AgentInfo{Name: "coder", State: "active", SubState: "thinking"}})
+		message.SendResponse(conn, &message.Response{OK: true, Agent: &message.AgentInfo{Name: "coder", State: "idle"}})
+	}()
+
+	var out bytes.Buffer
+	if err := watchAgentState(context.Background(), "coder", &out); err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3:\n%s", len(lines), out.String())
+	}
+	for i, want := range []string{"active (thinking)", "idle", "exited"} {
+		if !strings.HasSuffix(lines[i], "  "+want) {
+			t.Errorf("line %d = %q, want suffix %q", i, lines[i], want)
+		}
+	}
+}
+
+func TestStatusWatch_StopsOnCancel(t *testing.T) {
+	h2Root := setupPodTestEnv(t)
+	sockPath := filepath.Join(h2Root, "sockets", socketdir.Format(socketdir.TypeAgent, "coder"))
+	ln, err := net.Listen("unix", sockPath)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		message.ReadRequest(conn)
+		message.SendResponse(conn, &message.Response{OK: true, Agent: &message.AgentInfo{Name: "coder", State: "idle"}})
+		// Hold the stream open as a live daemon would.
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var out bytes.Buffer
+	if err := watchAgentState(ctx, "coder", &out); err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	if got := strings.TrimSpace(out.String()); strings.Count(got, "\n") != 0 || !strings.HasSuffix(got, "  idle") {
+		t.Errorf("output = %q, want a single idle line", got)
+	}
+}
+
+func TestStatusWatch_RejectsFast(t *testing.T) {
+	setupPodTestEnv(t)
+	cmd := newStatusCmd()
+	cmd.SetArgs([]string{"coder", "--watch", "--fast"})
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	if err := cmd.Execute(); err == nil || !strings.Contains(err.Error(), "--watch") {
+		t.Fatalf("expected --watch conflict error, got %v", err)
+	}
+}
@@ -2,7 +2,7 @@ package cmd
 
 import (
 	"fmt"
-	"net"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -11,7 +11,9 @@ import (
 )
 
 func newStopCmd() *cobra.Command {
-	return &cobra.Command{
+	var timeout time.Duration
+
+	cmd := &cobra.Command{
 		Use:   "stop <name>",
 		Short: "Stop a running agent or bridge",
 		Args:  cobra.ExactArgs(1),
@@ -23,19 +25,15 @@ func newStopCmd() *cobra.Command {
 				return fmt.Errorf("cannot find %q: %w", name, err)
 			}
 
-			conn, err := net.Dial("unix", sockPath)
+			conn, err := dialSocket(sockPath, timeout)
 			if err != nil {
 				return fmt.Errorf("cannot connect to %q: %w", name, err)
 			}
 			defer conn.Close()
 
-			if err := message.SendRequest(conn, &message.Request{Type: "stop"}); err != nil {
-				return fmt.Errorf("send stop request: %w", err)
-			}
-
-			resp, err := message.ReadResponse(conn)
+			resp, err := exchangeSocket(conn, &message.Request{Type: "stop"}, timeout)
 			if err != nil {
-				return fmt.Errorf("read response: %w", err)
+				return err
 			}
 			if !resp.OK {
 				return fmt.Errorf("stop failed: %s", resp.Error)
@@ -45,4 +43,8 @@ func newStopCmd() *cobra.Command {
 			return nil
 		},
 	}
+
+	cmd.Flags().DurationVar(&timeout, "timeout", defaultSocketTimeout, "How long to keep retrying a busy socket (0 = single attempt)")
+
+	return cmd
 }
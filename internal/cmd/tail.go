@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"h2/internal/session/message"
+	"h2/internal/socketdir"
+)
+
+func newTailCmd() *cobra.Command {
+	var screen bool
+	var interval time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "tail --screen <name>",
+		Short: "Stream an agent's screen as plain-text lines",
+		Long: `Follow what an agent's screen shows without attaching to it. The
+screen is polled and rendered as plain text, and only new or changed
+lines are printed, so the output reads like a log and can be piped.
+
+  h2 tail --screen coder
+  h2 tail --screen coder --interval 2s | grep -i error`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !screen {
+				return fmt.Errorf("--screen is required")
+			}
+			if interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
+			}
+			return tailScreen(args[0], interval, cmd.OutOrStdout())
+		},
+	}
+
+	cmd.Flags().BoolVar(&screen, "screen", false, "Stream the agent's rendered screen")
+	cmd.Flags().DurationVar(&interval, "interval", 500*time.Millisecond, "How often to poll the screen")
+
+	return cmd
+}
+
+// tailScreen polls the agent's screen every interval and writes the lines
+// that changed since the previous frame to w, until the agent goes away.
+func tailScreen(name string, interval time.Duration, w io.Writer) error {
+	sockPath, err := socketdir.Find(name)
+	if err != nil {
+		return agentConnError(name, err)
+	}
+
+	var tailer screenTailer
+	for {
+		resp, err := agentRequest(sockPath, name, &message.Request{Type: "screen"})
+		if err != nil {
+			return err
+		}
+		if resp.Screen == nil {
+			return fmt.Errorf("no screen in response from agent %q", name)
+		}
+		for _, line := range tailer.Next(resp.Screen.Plain) {
+			fmt.Fprintln(w, line)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// screenTailer turns successive screen frames into a stream of new and
+// changed lines. Identical frames emit nothing; a frame that is the previous
+// one scrolled up emits only the lines scrolled in; anything else emits the
+// rows that differ from the same row of the previous frame.
+type screenTailer struct {
+	prev []string
+	seen bool
+}
+
+// Next records frame (plain text, newline-separated rows) and returns the
+// lines to emit for it.
+func (t *screenTailer) Next(frame string) []string {
+	var cur []string
+	if frame != "" {
+		cur = strings.Split(frame, "\n")
+	}
+	prev := t.prev
+	first := !t.seen
+	t.prev, t.seen = cur, true
+
+	if first {
+		return nonBlank(cur)
+	}
+	if equalLines(prev, cur) {
+		return nil
+	}
+	if k := scrollOffset(prev, cur); k > 0 {
+		return nonBlank(cur[len(prev)-k:])
+	}
+
+	var out []string
+	for i, line := range cur {
+		if i < len(prev) && prev[i] == line {
+			continue
+		}
+		if strings.TrimSpace(line) != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+// scrollOffset returns the smallest k > 0 such that cur starts with
+// prev[k:] (prev scrolled up by k rows), or 0 if there is none. Overlaps
+// made only of blank rows don't count as a scroll.
+func scrollOffset(prev, cur []string) int {
+	for k := 1; k < len(prev); k++ {
+		overlap := len(prev) - k
+		if overlap > len(cur) || len(nonBlank(prev[k:])) == 0 {
+			continue
+		}
+		if equalLines(prev[k:], cur[:overlap]) {
+			return k
+		}
+	}
+	return 0
+}
+
+func equalLines(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func nonBlank(lines []string) []string {
+	var out []string
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			out = append(out, line)
+		}
+	}
+	return out
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScreenTailer_EmitsNewAndChangedLines(t *testing.T) {
+	frames := []struct {
+		screen string
+		want   []string
+	}{
+		// First frame: everything visible.
+		{"$ make\nbuilding...", []string{"$ make", "building..."}},
+		// Same frame again: nothing.
+		{"$ make\nbuilding...", nil},
+		// Output appended below.
+		{"$ make\nbuilding...\nok", []string{"ok"}},
+		// A row rewritten in place (progress line).
+		{"$ make\nbuilding... done\nok", []string{"building... done"}},
+		// Scrolled up by two rows: only the rows scrolled in.
+		{"ok\nline a\nline b", []string{"line a", "line b"}},
+		// Screen cleared and redrawn.
+		{"Welcome\n\n> ", []string{"Welcome", "> "}},
+		// Cleared to blank.
+		{"", nil},
+	}
+
+	var tailer screenTailer
+	for i, f := range frames {
+		got := tailer.Next(f.screen)
+		if !reflect.DeepEqual(got, f.want) {
+			t.Errorf("frame %d (%q): emitted %q, want %q", i, f.screen, got, f.want)
+		}
+	}
+}
+
+func TestScreenTailer_BlankFirstFrame(t *testing.T) {
+	var tailer screenTailer
+	if got := tailer.Next(""); got != nil {
+		t.Errorf("blank first frame emitted %q", got)
+	}
+	if got := tailer.Next("ready"); !reflect.DeepEqual(got, []string{"ready"}) {
+		t.Errorf("emitted %q, want [ready]", got)
+	}
+}
+
+func TestTailCmd_RequiresScreen(t *testing.T) {
+	cmd := newTailCmd()
+	cmd.SetArgs([]string{"coder"})
+	err := cmd.Execute()
+	if err == nil || !strings.Contains(err.Error(), "--screen") {
+		t.Fatalf("expected --screen error, got %v", err)
+	}
+}
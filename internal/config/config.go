@@ -17,6 +17,19 @@ const markerFile = ".h2-dir.txt"
 
 type Config struct {
 	Users map[string]*UserConfig `yaml:"users"`
+
+	// SandboxRoot, when set, confines every agent's working dir to this
+	// directory. A role's own sandbox_root can only narrow it.
+	SandboxRoot string `yaml:"sandbox_root,omitempty"`
+
+	// MaxPodAgents caps how many agents a pod launch may start. A template's
+	// max_agents takes precedence; 0 uses DefaultMaxPodAgents.
+	MaxPodAgents int `yaml:"max_pod_agents,omitempty"`
+
+	// NameScheme picks how agents launched without --name are named:
+	// adjective-animal (default, e.g. "calm-brook"), role-n (e.g.
+	// "coder-3"), or wordlist:<path> for random words from a file.
+	NameScheme string `yaml:"name_scheme,omitempty"`
 }
 
 type UserConfig struct {
@@ -32,6 +45,9 @@ type TelegramConfig struct {
 	BotToken        string   `yaml:"bot_token"`
 	ChatID          int64    `yaml:"chat_id"`
 	AllowedCommands []string `yaml:"allowed_commands,omitempty"`
+	// Streaming edits one message per agent in place while the agent is
+	// active, instead of posting each outbound message separately.
+	Streaming bool `yaml:"streaming,omitempty"`
 }
 
 type MacOSNotifyConfig struct {
@@ -189,6 +205,15 @@ func LoadFrom(path string) (*Config, error) {
 var allowedCommandRe = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
 
 func (c *Config) validate() error {
+	if c.MaxPodAgents < 0 {
+		return fmt.Errorf("max_pod_agents must not be negative (got %d)", c.MaxPodAgents)
+	}
+	switch {
+	case c.NameScheme == "", c.NameScheme == "adjective-animal", c.NameScheme == "role-n":
+	case strings.HasPrefix(c.NameScheme, "wordlist:") && c.NameScheme != "wordlist:":
+	default:
+		return fmt.Errorf("name_scheme must be adjective-animal, role-n, or wordlist:<path> (got %q)", c.NameScheme)
+	}
 	for username, u := range c.Users {
 		if u == nil || u.Bridges.Telegram == nil {
 			continue
@@ -160,6 +160,31 @@ func TestLoadFrom_AllowedCommands_Invalid(t *testing.T) {
 	}
 }
 
+func TestLoadFrom_NameScheme(t *testing.T) {
+	for _, tt := range []struct {
+		scheme string
+		ok     bool
+	}{
+		{"adjective-animal", true},
+		{"role-n", true},
+		{"wordlist:/tmp/words.txt", true},
+		{"wordlist:", false},
+		{"uuid", false},
+	} {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		if err := os.WriteFile(path, []byte("name_scheme: "+tt.scheme+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		cfg, err := LoadFrom(path)
+		if tt.ok && (err != nil || cfg.NameScheme != tt.scheme) {
+			t.Errorf("name_scheme %q: got %v, want it accepted", tt.scheme, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("name_scheme %q: expected an error", tt.scheme)
+		}
+	}
+}
+
 func TestLoadFrom_AllowedCommands_NotSet(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "config.yaml")
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// KnownPermissionTools are the tool names a permission spec may name.
+// Unknown names are reported as warnings rather than errors so roles keep
+// working when the agent gains new tools; add to this set as they appear.
+var KnownPermissionTools = map[string]bool{
+	"Bash":         true,
+	"BashOutput":   true,
+	"Edit":         true,
+	"ExitPlanMode": true,
+	"Glob":         true,
+	"Grep":         true,
+	"KillShell":    true,
+	"LS":           true,
+	"MultiEdit":    true,
+	"NotebookEdit": true,
+	"NotebookRead": true,
+	"Read":         true,
+	"SlashCommand": true,
+	"Skill":        true,
+	"Task":         true,
+	"TodoWrite":    true,
+	"WebFetch":     true,
+	"WebSearch":    true,
+	"Write":        true,
+}
+
+// pathPatternTools take a file path glob as their argument.
+var pathPatternTools = map[string]bool{
+	"Edit":         true,
+	"Glob":         true,
+	"Grep":         true,
+	"LS":           true,
+	"MultiEdit":    true,
+	"NotebookEdit": true,
+	"NotebookRead": true,
+	"Read":         true,
+	"Write":        true,
+}
+
+// PermissionSpec is a parsed permission entry: a tool name with an optional
+// argument pattern, written Tool or Tool(arg).
+type PermissionSpec struct {
+	Tool   string
+	Arg    string
+	HasArg bool
+}
+
+// String returns the canonical form of the spec.
+func (p PermissionSpec) String() string {
+	if !p.HasArg {
+		return p.Tool
+	}
+	return p.Tool + "(" + p.Arg + ")"
+}
+
+// Known reports whether the tool is in KnownPermissionTools or is an MCP
+// tool (mcp__server or mcp__server__tool).
+func (p PermissionSpec) Known() bool {
+	return KnownPermissionTools[p.Tool] || strings.HasPrefix(p.Tool, "mcp__")
+}
+
+// ParsePermissionSpec parses a Tool or Tool(arg) entry, trimming
+// surrounding whitespace, and checks the argument's pattern syntax.
+func ParsePermissionSpec(s string) (PermissionSpec, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return PermissionSpec{}, fmt.Errorf("empty entry")
+	}
+
+	var spec PermissionSpec
+	open := strings.IndexByte(s, '(')
+	if open < 0 {
+		if strings.ContainsRune(s, ')') {
+			return PermissionSpec{}, fmt.Errorf("unbalanced parentheses")
+		}
+		spec.Tool = s
+	} else {
+		if !strings.HasSuffix(s, ")") {
+			return PermissionSpec{}, fmt.Errorf("missing closing parenthesis")
+		}
+		spec.Tool = strings.TrimSpace(s[:open])
+		spec.Arg = strings.TrimSpace(s[open+1 : len(s)-1])
+		spec.HasArg = true
+	}
+
+	if !isToolName(spec.Tool) {
+		return PermissionSpec{}, fmt.Errorf("invalid tool name %q", spec.Tool)
+	}
+	if spec.HasArg {
+		if err := checkPermissionArg(spec.Tool, spec.Arg); err != nil {
+			return PermissionSpec{}, err
+		}
+	}
+	return spec, nil
+}
+
+// isToolName reports whether s is a letter followed by letters, digits,
+// underscores, or hyphens.
+func isToolName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case i > 0 && (c >= '0' && c <= '9' || c == '_' || c == '-'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// checkPermissionArg validates the argument pattern of a Tool(arg) spec.
+func checkPermissionArg(tool, arg string) error {
+	if arg == "" {
+		return fmt.Errorf("empty argument in %s()", tool)
+	}
+	if !parensBalanced(arg) {
+		return fmt.Errorf("unbalanced parentheses in argument %q", arg)
+	}
+	switch {
+	case tool == "Bash":
+		// Prefix rules end in ":*"; the wildcard can't appear mid-command.
+		if i := strings.Index(arg, ":*"); i >= 0 && i != len(arg)-2 {
+			return fmt.Errorf("bash prefix wildcard :* must end the pattern in %q", arg)
+		}
+	case tool == "WebFetch":
+		domain, ok := strings.CutPrefix(arg, "domain:")
+		if !ok || domain == "" {
+			return fmt.Errorf("WebFetch argument %q must be domain:<host>", arg)
+		}
+	case pathPatternTools[tool]:
+		// path.Match reports malformed globs (e.g. an unclosed "[").
+		if _, err := path.Match(arg, ""); err != nil {
+			return fmt.Errorf("invalid path pattern %q: %w", arg, err)
+		}
+	}
+	return nil
+}
+
+// parensBalanced reports whether the parentheses in s pair up, skipping
+// any inside single or double quotes so Bash(echo ")") is accepted. An
+// unterminated quote is taken literally and every parenthesis counts.
+func parensBalanced(s string) bool {
+	depth, quoted := 0, 0
+	var quote rune
+	escaped := false
+	for _, c := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && quote != '\'':
+			escaped = true
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			} else if c == '(' || c == ')' {
+				quoted++
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	if quote != 0 && quoted > 0 {
+		return strings.Count(s, "(") == strings.Count(s, ")")
+	}
+	return depth == 0
+}
+
+// normalizePermissionSpecs parses each entry and returns the list in
+// canonical form, leaving specs untouched. field names the list in errors
+// (e.g. "permissions.allow").
+func normalizePermissionSpecs(field string, specs []string) ([]string, error) {
+	if specs == nil {
+		return nil, nil
+	}
+	out := make([]string, len(specs))
+	for i, s := range specs {
+		spec, err := ParsePermissionSpec(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s entry %q: %w", field, s, err)
+		}
+		out[i] = spec.String()
+	}
+	return out, nil
+}
+
+// UnknownPermissionTools returns the allow/deny entries whose tool name
+// isn't known. Call after Validate, which rejects malformed entries.
+func (r *Role) UnknownPermissionTools() []string {
+	var unknown []string
+	for _, list := range [][]string{r.Permissions.Allow, r.Permissions.Deny} {
+		for _, s := range list {
+			spec, err := ParsePermissionSpec(s)
+			if err == nil && !spec.Known() {
+				unknown = append(unknown, s)
+			}
+		}
+	}
+	return unknown
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParsePermissionSpec_WellFormed(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Read", "Read"},
+		{"Write(docs/**)", "Write(docs/**)"},
+		{"  Edit ( src/*.go ) ", "Edit(src/*.go)"},
+		{"Bash(npm run test:*)", "Bash(npm run test:*)"},
+		{"Bash(git log)", "Bash(git log)"},
+		{"WebFetch(domain:example.com)", "WebFetch(domain:example.com)"},
+		{"mcp__github__create_issue", "mcp__github__create_issue"},
+		{"Bash(echo (hi))", "Bash(echo (hi))"},
+		{`Bash(echo ")")`, `Bash(echo ")")`},
+		{`Bash(echo '(' \))`, `Bash(echo '(' \))`},
+	}
+	for _, tt := range tests {
+		spec, err := ParsePermissionSpec(tt.in)
+		if err != nil {
+			t.Errorf("ParsePermissionSpec(%q): %v", tt.in, err)
+			continue
+		}
+		if got := spec.String(); got != tt.want {
+			t.Errorf("ParsePermissionSpec(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		if !spec.Known() {
+			t.Errorf("ParsePermissionSpec(%q): tool %q should be known", tt.in, spec.Tool)
+		}
+	}
+}
+
+func TestParsePermissionSpec_Malformed(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantErr string
+	}{
+		{"", "empty entry"},
+		{"Write(docs/**", "missing closing parenthesis"},
+		{"Write docs/**)", "unbalanced parentheses"},
+		{"(docs/**)", "invalid tool name"},
+		{"Write x(docs)", "invalid tool name"},
+		{"Read()", "empty argument"},
+		{"Read([abc)", "invalid path pattern"},
+		{"Bash(npm:* run)", ":* must end the pattern"},
+		{"WebFetch(example.com)", "must be domain:<host>"},
+		{"Bash(echo (hi)", "unbalanced parentheses"},
+		{"Bash(echo) (hi)", "unbalanced parentheses"},
+		{`Bash(echo "(" ))`, "unbalanced parentheses"},
+	}
+	for _, tt := range tests {
+		_, err := ParsePermissionSpec(tt.in)
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ParsePermissionSpec(%q) err = %v, want containing %q", tt.in, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidate_NormalizesPermissionSpecs(t *testing.T) {
+	role := &Role{
+		Name:         "test",
+		Instructions: "x",
+		Permissions: Permissions{
+			Allow: []string{" Read ", "Write ( docs/** )"},
+			Deny:  []string{"Bash(rm:*)"},
+		},
+	}
+	if err := role.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if want := []string{"Read", "Write(docs/**)"}; !reflect.DeepEqual(role.Permissions.Allow, want) {
+		t.Errorf("Allow = %q, want %q", role.Permissions.Allow, want)
+	}
+	if want := []string{"Bash(rm:*)"}; !reflect.DeepEqual(role.Permissions.Deny, want) {
+		t.Errorf("Deny = %q, want %q", role.Permissions.Deny, want)
+	}
+}
+
+func TestValidate_LeavesSharedPermissionListsAlone(t *testing.T) {
+	shared := []string{" Read ", "Write ( docs/** )"}
+	role := &Role{
+		Name:         "test",
+		Instructions: "x",
+		Permissions:  Permissions{Allow: shared},
+	}
+	if err := role.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+	if want := []string{" Read ", "Write ( docs/** )"}; !reflect.DeepEqual(shared, want) {
+		t.Errorf("shared list = %q, want it unchanged", shared)
+	}
+	if want := []string{"Read", "Write(docs/**)"}; !reflect.DeepEqual(role.Permissions.Allow, want) {
+		t.Errorf("Allow = %q, want %q", role.Permissions.Allow, want)
+	}
+}
+
+func TestValidate_RejectsMalformedPermissionSpec(t *testing.T) {
+	role := &Role{
+		Name:         "test",
+		Instructions: "x",
+		Permissions:  Permissions{Deny: []string{"Read", "Write(docs/**"}},
+	}
+	err := role.Validate()
+	if err == nil || !strings.Contains(err.Error(), `permissions.deny entry "Write(docs/**"`) {
+		t.Fatalf("expected malformed deny entry error, got %v", err)
+	}
+}
+
+func TestUnknownPermissionTools_WarnsOnly(t *testing.T) {
+	role := &Role{
+		Name:         "test",
+		Instructions: "x",
+		Permissions: Permissions{
+			Allow: []string{"Wrte(docs/**)", "Read", "mcp__jira"},
+			Deny:  []string{"FutureTool"},
+		},
+	}
+	if err := role.Validate(); err != nil {
+		t.Fatalf("unknown tools should not fail validation: %v", err)
+	}
+	want := []string{"Wrte(docs/**)", "FutureTool"}
+	if got := role.UnknownPermissionTools(); !reflect.DeepEqual(got, want) {
+		t.Errorf("UnknownPermissionTools = %q, want %q", got, want)
+	}
+}
+
+func TestKnownPermissionTools_Extensible(t *testing.T) {
+	spec, _ := ParsePermissionSpec("Frobnicate")
+	if spec.Known() {
+		t.Fatal("Frobnicate should not be known by default")
+	}
+	KnownPermissionTools["Frobnicate"] = true
+	t.Cleanup(func() { delete(KnownPermissionTools, "Frobnicate") })
+	if !spec.Known() {
+		t.Error("Frobnicate should be known after registering it")
+	}
+}
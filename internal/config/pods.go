@@ -8,8 +8,6 @@ import (
 	"strings"
 
 	"h2/internal/tmpl"
-
-	"gopkg.in/yaml.v3"
 )
 
 var podNameRe = regexp.MustCompile(`^[a-z0-9-]+$`)
@@ -104,15 +102,78 @@ func ListPodRoles() ([]*Role, error) {
 type PodTemplate struct {
 	PodName   string                  `yaml:"pod_name"`
 	Variables map[string]tmpl.VarDef  `yaml:"variables"`
+	Consts    map[string]string       `yaml:"consts,omitempty"`
+	Vars      map[string]string       `yaml:"vars,omitempty"` // passed to every member's role render
+	MaxAgents int                     `yaml:"max_agents,omitempty"` // cap on expanded agent count (overrides global max_pod_agents)
 	Agents    []PodTemplateAgent      `yaml:"agents"`
 }
 
+// DefaultMaxPodAgents caps pod size when neither the template nor
+// config.yaml sets a limit.
+const DefaultMaxPodAgents = 50
+
+// CheckPodSize returns an error if the expanded agent count exceeds the
+// pod's cap: the template's max_agents if set, else globalMax (config.yaml
+// max_pod_agents), else DefaultMaxPodAgents.
+func CheckPodSize(pt *PodTemplate, expanded []ExpandedAgent, globalMax int) error {
+	limit, source := DefaultMaxPodAgents, "default limit"
+	if pt.MaxAgents > 0 {
+		limit, source = pt.MaxAgents, "template max_agents"
+	} else if globalMax > 0 {
+		limit, source = globalMax, "config max_pod_agents"
+	}
+	if len(expanded) > limit {
+		return fmt.Errorf("pod would launch %d agents, exceeding the %s of %d; raise max_agents in the template or max_pod_agents in config.yaml",
+			len(expanded), source, limit)
+	}
+	return nil
+}
+
+// CheckPodRoles verifies that every expanded agent's role exists in the pod
+// roles dir or the global roles dir, so a bad template fails before anything
+// is launched. The error lists each missing role with the agents needing it.
+func CheckPodRoles(expanded []ExpandedAgent) error {
+	var missing []string
+	needs := make(map[string][]string)
+	for _, agent := range expanded {
+		name := agent.Role
+		if name == "" {
+			name = "default"
+		}
+		if IsPodScopedRole(name) {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(RolesDir(), name+".yaml")); err == nil {
+			continue
+		}
+		if _, seen := needs[name]; !seen {
+			missing = append(missing, name)
+		}
+		needs[name] = append(needs[name], agent.Name)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "missing roles:")
+	for _, name := range missing {
+		fmt.Fprintf(&b, "\n  %s (needed by %s)", name, strings.Join(needs[name], ", "))
+	}
+	fmt.Fprintf(&b, "\nsearched %s and %s", PodRolesDir(), RolesDir())
+	return fmt.Errorf("%s", b.String())
+}
+
 // PodTemplateAgent defines a single agent within a pod template.
 type PodTemplateAgent struct {
 	Name  string            `yaml:"name"`
 	Role  string            `yaml:"role"`
 	Count *int              `yaml:"count,omitempty"` // nil = default (1 agent), 0 = skip, N = N agents
 	Vars  map[string]string `yaml:"vars"`
+
+	// Overrides and ExtraArgs adjust this agent's resolved role only, so one
+	// template can mix configurations of a shared role.
+	Overrides []string `yaml:"overrides,omitempty"`  // key=value pairs, as with h2 run --override
+	ExtraArgs []string `yaml:"extra_args,omitempty"` // appended after the role's extra_args
 }
 
 // GetCount returns the effective count for this agent.
@@ -130,13 +191,33 @@ type ExpandedAgent struct {
 	Role  string
 	Index int
 	Count int
-	Vars  map[string]string
+	Vars  map[string]string // pod-level vars overlaid with the agent's own vars
+
+	Overrides []string // agent-level role overrides from the template
+	ExtraArgs []string // agent-level extra_args from the template
+}
+
+// ApplyTo applies the agent's template-level overrides and extra_args to
+// its loaded role.
+func (a ExpandedAgent) ApplyTo(role *Role) error {
+	if len(a.Overrides) > 0 {
+		if err := ApplyOverrides(role, a.Overrides); err != nil {
+			return fmt.Errorf("apply overrides: %w", err)
+		}
+	}
+	if len(a.ExtraArgs) > 0 {
+		role.ExtraArgs = append(append([]string(nil), role.ExtraArgs...), a.ExtraArgs...)
+	}
+	return nil
 }
 
 // ExpandPodAgents expands count groups in a pod template into a flat list of agents.
 // It handles count-based multiplication, auto-suffix for names without {{ .Index }},
 // and detects name collisions after expansion.
 //
+// Each expanded agent's Vars combine the pod-level vars with the agent's
+// own vars (agent vars win), so every member's role sees pod vars.
+//
 // Count semantics:
 //   - count omitted (nil): produce 1 agent with Index=0, Count=0
 //   - count == 0: skip (produce 0 agents)
@@ -148,6 +229,7 @@ func ExpandPodAgents(pt *PodTemplate) ([]ExpandedAgent, error) {
 
 	for _, a := range pt.Agents {
 		count := a.GetCount()
+		vars := mergeVars(pt.Vars, a.Vars)
 
 		if count == 0 {
 			// Explicit count: 0 — skip this agent.
@@ -167,7 +249,10 @@ func ExpandPodAgents(pt *PodTemplate) ([]ExpandedAgent, error) {
 				Role:  a.Role,
 				Index: 0,
 				Count: 0,
-				Vars:  a.Vars,
+				Vars:  vars,
+
+				Overrides: a.Overrides,
+				ExtraArgs: a.ExtraArgs,
 			})
 			continue
 		}
@@ -191,7 +276,10 @@ func ExpandPodAgents(pt *PodTemplate) ([]ExpandedAgent, error) {
 				Role:  a.Role,
 				Index: i,
 				Count: count,
-				Vars:  a.Vars,
+				Vars:  vars,
+
+				Overrides: a.Overrides,
+				ExtraArgs: a.ExtraArgs,
 			})
 		}
 	}
@@ -204,6 +292,22 @@ func ExpandPodAgents(pt *PodTemplate) ([]ExpandedAgent, error) {
 	return agents, nil
 }
 
+// mergeVars returns a new map with base overlaid by override.
+// Returns nil when both are empty.
+func mergeVars(base, override map[string]string) map[string]string {
+	if len(base) == 0 && len(override) == 0 {
+		return nil
+	}
+	merged := make(map[string]string, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
+
 // checkNameCollisions detects duplicate agent names after expansion.
 func checkNameCollisions(agents []ExpandedAgent) error {
 	seen := make(map[string]int) // name → first index in agents slice
@@ -225,8 +329,8 @@ func LoadPodTemplate(name string) (*PodTemplate, error) {
 	}
 
 	var pt PodTemplate
-	if err := yaml.Unmarshal(data, &pt); err != nil {
-		return nil, fmt.Errorf("parse pod template: %w", err)
+	if err := unmarshalStrict(data, &pt); err != nil {
+		return nil, fmt.Errorf("parse pod template %q: %w", path, err)
 	}
 	return &pt, nil
 }
@@ -251,6 +355,10 @@ func ParsePodTemplateRendered(yamlText string, name string, ctx *tmpl.Context) (
 	if err != nil {
 		return nil, fmt.Errorf("pod template %q: %w", name, err)
 	}
+	consts, remaining, err := tmpl.ParseConsts(remaining)
+	if err != nil {
+		return nil, fmt.Errorf("pod template %q: %w", name, err)
+	}
 
 	// Clone ctx.Var so we don't mutate the caller's map.
 	vars := make(map[string]string, len(ctx.Var))
@@ -267,6 +375,7 @@ func ParsePodTemplateRendered(yamlText string, name string, ctx *tmpl.Context) (
 	if err := tmpl.ValidateVars(varDefs, vars); err != nil {
 		return nil, fmt.Errorf("pod template %q: %w", name, err)
 	}
+	tmpl.MergeConsts(vars, consts)
 
 	// Render template with cloned vars.
 	renderCtx := *ctx
@@ -278,10 +387,11 @@ func ParsePodTemplateRendered(yamlText string, name string, ctx *tmpl.Context) (
 
 	// Parse rendered YAML.
 	var pt PodTemplate
-	if err := yaml.Unmarshal([]byte(rendered), &pt); err != nil {
+	if err := unmarshalStrict([]byte(rendered), &pt); err != nil {
 		return nil, fmt.Errorf("pod template %q produced invalid YAML after rendering: %w", name, err)
 	}
 	pt.Variables = varDefs
+	pt.Consts = consts
 
 	return &pt, nil
 }
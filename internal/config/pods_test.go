@@ -183,6 +183,37 @@ func TestExpandPodAgents_SingleAgentNoCount(t *testing.T) {
 	}
 }
 
+func TestExpandPodAgents_PodVarsFlowToEveryAgent(t *testing.T) {
+	pt := &PodTemplate{
+		Vars: map[string]string{"team": "backend", "env": "dev"},
+		Agents: []PodTemplateAgent{
+			{Name: "lead", Role: "lead"},
+			{Name: "coder", Role: "coding", Count: intPtr(2), Vars: map[string]string{"env": "prod"}},
+		},
+	}
+	agents, err := ExpandPodAgents(pt)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(agents) != 3 {
+		t.Fatalf("expected 3 agents, got %d", len(agents))
+	}
+	for _, a := range agents {
+		if a.Vars["team"] != "backend" {
+			t.Errorf("%s: team = %q, want pod var backend", a.Name, a.Vars["team"])
+		}
+	}
+	if agents[0].Vars["env"] != "dev" {
+		t.Errorf("lead: env = %q, want pod var dev", agents[0].Vars["env"])
+	}
+	if agents[1].Vars["env"] != "prod" {
+		t.Errorf("coder: env = %q, want agent var prod to beat pod var", agents[1].Vars["env"])
+	}
+	if pt.Vars["env"] != "dev" {
+		t.Error("expansion must not mutate pod vars")
+	}
+}
+
 func TestExpandPodAgents_CountGreaterThanOne(t *testing.T) {
 	pt := &PodTemplate{
 		Agents: []PodTemplateAgent{
@@ -234,6 +265,29 @@ func TestExpandPodAgents_CountWithIndexTemplate(t *testing.T) {
 	}
 }
 
+func TestExpandPodAgents_ZeroPaddedIndex(t *testing.T) {
+	for _, nameTmpl := range []string{
+		"coder-{{ zeropad .Index 2 }}",
+		`coder-{{ printf "%02d" .Index }}`,
+	} {
+		pt := &PodTemplate{
+			Agents: []PodTemplateAgent{
+				{Name: nameTmpl, Role: "coding", Count: intPtr(10)},
+			},
+		}
+		agents, err := ExpandPodAgents(pt)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", nameTmpl, err)
+		}
+		if len(agents) != 10 {
+			t.Fatalf("%s: expected 10 agents, got %d", nameTmpl, len(agents))
+		}
+		if agents[0].Name != "coder-01" || agents[9].Name != "coder-10" {
+			t.Errorf("%s: names = %q..%q, want coder-01..coder-10", nameTmpl, agents[0].Name, agents[9].Name)
+		}
+	}
+}
+
 func TestExpandPodAgents_CountOneWithIndexTemplate(t *testing.T) {
 	pt := &PodTemplate{
 		Agents: []PodTemplateAgent{
@@ -450,6 +504,134 @@ agents:
 	}
 }
 
+func TestExpandPodAgents_CarriesAgentOverrides(t *testing.T) {
+	pt := &PodTemplate{
+		Agents: []PodTemplateAgent{
+			{Name: "coder", Role: "coding", Count: intPtr(2), Overrides: []string{"model=opus"}, ExtraArgs: []string{"--verbose"}},
+			{Name: "lead", Role: "coding"},
+		},
+	}
+	agents, err := ExpandPodAgents(pt)
+	if err != nil {
+		t.Fatalf("ExpandPodAgents: %v", err)
+	}
+	if len(agents) != 3 {
+		t.Fatalf("expected 3 agents, got %d", len(agents))
+	}
+	for _, a := range agents[:2] {
+		if len(a.Overrides) != 1 || len(a.ExtraArgs) != 1 {
+			t.Errorf("%s: overrides=%v extra_args=%v, want template values", a.Name, a.Overrides, a.ExtraArgs)
+		}
+	}
+	if agents[2].Overrides != nil || agents[2].ExtraArgs != nil {
+		t.Errorf("lead should have no overrides, got %v / %v", agents[2].Overrides, agents[2].ExtraArgs)
+	}
+}
+
+func TestExpandedAgent_ApplyTo(t *testing.T) {
+	shared := []string{"--debug"}
+	a := ExpandedAgent{Name: "coder", Overrides: []string{"model=opus"}, ExtraArgs: []string{"--verbose"}}
+	role := &Role{Name: "coding", Model: "sonnet", ExtraArgs: shared}
+	if err := a.ApplyTo(role); err != nil {
+		t.Fatalf("ApplyTo: %v", err)
+	}
+	if role.Model != "opus" {
+		t.Errorf("Model = %q, want opus", role.Model)
+	}
+	if got := strings.Join(role.ExtraArgs, " "); got != "--debug --verbose" {
+		t.Errorf("ExtraArgs = %q, want %q", got, "--debug --verbose")
+	}
+	if len(shared) != 1 {
+		t.Errorf("ApplyTo must not modify the role's original extra_args slice")
+	}
+
+	bad := ExpandedAgent{Overrides: []string{"bogus"}}
+	if err := bad.ApplyTo(&Role{}); err == nil {
+		t.Error("expected error for malformed override")
+	}
+}
+
+// --- CheckPodSize tests ---
+
+func TestCheckPodSize_WithinTemplateCap(t *testing.T) {
+	pt := &PodTemplate{
+		MaxAgents: 4,
+		Agents: []PodTemplateAgent{
+			{Name: "concierge", Role: "concierge"},
+			{Name: "coder", Role: "coding", Count: intPtr(3)},
+		},
+	}
+	agents, err := ExpandPodAgents(pt)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := CheckPodSize(pt, agents, 0); err != nil {
+		t.Fatalf("4 agents with max_agents 4 should pass: %v", err)
+	}
+}
+
+func TestCheckPodSize_TemplatedCountExceedsCap(t *testing.T) {
+	yamlText := `variables:
+  num_coders:
+    default: "2"
+pod_name: big
+max_agents: 5
+agents:
+  - name: concierge
+    role: concierge
+  - name: coder
+    role: coding
+    count: {{ .Var.num_coders }}
+`
+	ctx := &tmpl.Context{H2Dir: "/tmp/h2", Var: map[string]string{"num_coders": "200"}}
+	pt, err := ParsePodTemplateRendered(yamlText, "big", ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	agents, err := ExpandPodAgents(pt)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err = CheckPodSize(pt, agents, 0)
+	if err == nil {
+		t.Fatal("expected error for 201 agents with max_agents 5")
+	}
+	for _, want := range []string{"201 agents", "template max_agents of 5"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q should mention %q", err, want)
+		}
+	}
+}
+
+func TestCheckPodSize_GlobalAndDefaultCaps(t *testing.T) {
+	pt := &PodTemplate{
+		Agents: []PodTemplateAgent{{Name: "coder", Role: "coding", Count: intPtr(10)}},
+	}
+	agents, err := ExpandPodAgents(pt)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := CheckPodSize(pt, agents, 8); err == nil || !strings.Contains(err.Error(), "config max_pod_agents of 8") {
+		t.Errorf("expected global cap error, got %v", err)
+	}
+	if err := CheckPodSize(pt, agents, 0); err != nil {
+		t.Errorf("10 agents should fit the default cap: %v", err)
+	}
+
+	// The template's own cap takes precedence over the global one.
+	pt.MaxAgents = 10
+	if err := CheckPodSize(pt, agents, 8); err != nil {
+		t.Errorf("template max_agents should override the global cap: %v", err)
+	}
+
+	pt.MaxAgents = 0
+	pt.Agents[0].Count = intPtr(DefaultMaxPodAgents + 1)
+	agents, _ = ExpandPodAgents(pt)
+	if err := CheckPodSize(pt, agents, 0); err == nil || !strings.Contains(err.Error(), "default limit") {
+		t.Errorf("expected default cap error, got %v", err)
+	}
+}
+
 // --- ParsePodTemplateRendered tests ---
 
 func TestParsePodTemplateRendered_Basic(t *testing.T) {
@@ -573,6 +755,38 @@ agents:
 	}
 }
 
+func TestParsePodTemplateRendered_Consts(t *testing.T) {
+	yamlText := `consts:
+  prefix: team
+
+pod_name: test
+agents:
+  - name: {{ .Var.prefix }}-coder
+    role: coder
+`
+	pt, err := ParsePodTemplateRendered(yamlText, "test", &tmpl.Context{PodName: "test"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pt.Agents[0].Name != "team-coder" {
+		t.Errorf("name = %q, want team-coder", pt.Agents[0].Name)
+	}
+	if pt.Consts["prefix"] != "team" {
+		t.Errorf("Consts = %v, want prefix=team", pt.Consts)
+	}
+
+	pt, err = ParsePodTemplateRendered(yamlText, "test", &tmpl.Context{
+		PodName: "test",
+		Var:     map[string]string{"prefix": "ops"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pt.Agents[0].Name != "ops-coder" {
+		t.Errorf("name = %q, want ops-coder (--var overrides const)", pt.Agents[0].Name)
+	}
+}
+
 func TestParsePodTemplateRendered_VariablesStoredOnStruct(t *testing.T) {
 	yamlText := `variables:
   team:
@@ -1275,3 +1489,76 @@ instructions: |
 		t.Error("original var should be preserved")
 	}
 }
+
+func TestCheckPodRoles(t *testing.T) {
+	h2Dir := setupTestH2Dir(t)
+	os.WriteFile(filepath.Join(h2Dir, "roles", "coding.yaml"), []byte("name: coding\ninstructions: hi\n"), 0o644)
+	os.WriteFile(filepath.Join(h2Dir, "pods", "roles", "concierge.yaml"), []byte("name: concierge\ninstructions: hi\n"), 0o644)
+
+	ok := []ExpandedAgent{
+		{Name: "concierge", Role: "concierge"},
+		{Name: "coder-1", Role: "coding"},
+	}
+	if err := CheckPodRoles(ok); err != nil {
+		t.Fatalf("existing pod and global roles should pass: %v", err)
+	}
+
+	bad := []ExpandedAgent{
+		{Name: "coder-1", Role: "coding"},
+		{Name: "reviewer-1", Role: "reviewer"},
+		{Name: "reviewer-2", Role: "reviewer"},
+		{Name: "helper"},
+	}
+	err := CheckPodRoles(bad)
+	if err == nil {
+		t.Fatal("expected missing roles error")
+	}
+	msg := err.Error()
+	for _, want := range []string{
+		"reviewer (needed by reviewer-1, reviewer-2)",
+		"default (needed by helper)",
+		filepath.Join(h2Dir, "pods", "roles"),
+		filepath.Join(h2Dir, "roles"),
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("error should contain %q, got:\n%s", want, msg)
+		}
+	}
+	if strings.Contains(msg, "coding") {
+		t.Errorf("existing role should not be reported:\n%s", msg)
+	}
+}
+
+func TestParsePodTemplateRendered_UnknownKey(t *testing.T) {
+	yamlText := `variables:
+  team:
+    default: core
+pod_name: test
+agents:
+  - name: coder
+    role: coding
+    cuont: 2
+`
+	ctx := &tmpl.Context{PodName: "test"}
+	_, err := ParsePodTemplateRendered(yamlText, "test", ctx)
+	if err == nil {
+		t.Fatal("expected error for unknown key")
+	}
+	if !strings.Contains(err.Error(), "cuont") || !strings.Contains(err.Error(), `"test"`) {
+		t.Errorf("error should name the field and template, got: %v", err)
+	}
+}
+
+func TestParsePodTemplateRendered_UnknownKeyLax(t *testing.T) {
+	t.Setenv("H2_YAML_LAX", "1")
+	yamlText := `pod_name: test
+future_option: true
+agents:
+  - name: coder
+    role: coding
+`
+	ctx := &tmpl.Context{PodName: "test"}
+	if _, err := ParsePodTemplateRendered(yamlText, "test", ctx); err != nil {
+		t.Fatalf("H2_YAML_LAX=1 should accept unknown keys: %v", err)
+	}
+}
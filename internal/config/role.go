@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -18,6 +20,16 @@ type HeartbeatConfig struct {
 	IdleTimeout string `yaml:"idle_timeout"`
 	Message     string `yaml:"message"`
 	Condition   string `yaml:"condition,omitempty"`
+	// ConditionTimeout bounds each run of Condition; a condition that runs
+	// longer is killed and counts as not met (default 5s).
+	ConditionTimeout string `yaml:"condition_timeout,omitempty"`
+	// EscalateAfter escalates the next nudge once this many nudges in a row
+	// got no output from the agent (0 = never). Escalation sends
+	// EscalateMessage (default Message) at interrupt priority and runs
+	// EscalateCommand; with only a command set, no message is sent.
+	EscalateAfter   int    `yaml:"escalate_after,omitempty"`
+	EscalateMessage string `yaml:"escalate_message,omitempty"`
+	EscalateCommand string `yaml:"escalate_command,omitempty"`
 }
 
 // ParseIdleTimeout parses the IdleTimeout string as a Go duration.
@@ -25,6 +37,49 @@ func (k *HeartbeatConfig) ParseIdleTimeout() (time.Duration, error) {
 	return time.ParseDuration(k.IdleTimeout)
 }
 
+// ParseConditionTimeout parses ConditionTimeout as a Go duration. Returns 0
+// (use the 5s default) if unset.
+func (k *HeartbeatConfig) ParseConditionTimeout() (time.Duration, error) {
+	if k.ConditionTimeout == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(k.ConditionTimeout)
+}
+
+// ActivityLogConfig sets when the agent's activity log is rotated. Unset
+// fields keep the defaults: 10MB, daily, and 5 rotated files.
+type ActivityLogConfig struct {
+	MaxSizeMB int    `yaml:"max_size_mb,omitempty"` // rotate once the file would exceed this many megabytes
+	MaxAge    string `yaml:"max_age,omitempty"`     // rotate once the file is this old
+	MaxFiles  int    `yaml:"max_files,omitempty"`   // rotated files to keep
+}
+
+// ParseMaxAge parses MaxAge as a Go duration. Returns 0 (use the daily
+// default) if unset.
+func (a *ActivityLogConfig) ParseMaxAge() (time.Duration, error) {
+	if a.MaxAge == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(a.MaxAge)
+}
+
+// MessagePriority sets the queue priority of the messages h2 sends on the
+// role's behalf. Each field is interrupt, normal, idle-first, or idle.
+type MessagePriority struct {
+	Start     string `yaml:"start,omitempty"`     // on_start_message (default normal)
+	Heartbeat string `yaml:"heartbeat,omitempty"` // heartbeat.message (default idle)
+}
+
+// validMessagePriority reports whether s names a message priority. It
+// mirrors message.ParsePriority, which config can't import.
+func validMessagePriority(s string) bool {
+	switch s {
+	case "interrupt", "normal", "idle-first", "idle":
+		return true
+	}
+	return false
+}
+
 // WorktreeConfig defines git worktree settings for an agent.
 // Presence of this block implies worktree is enabled (no separate "enabled" flag).
 // Mutually exclusive with Role.WorkingDir.
@@ -95,19 +150,56 @@ var ValidPermissionModes = []string{
 type Role struct {
 	Name            string                  `yaml:"name"`
 	Description     string                  `yaml:"description,omitempty"`
+	Extends         string                  `yaml:"extends,omitempty"` // parent role this one inherits from
 	AgentType       string                  `yaml:"agent_type,omitempty"` // "claude" (default), future: other agent types
+	Command         string                  `yaml:"command,omitempty"`    // executable to run (default: agent_type)
+	Args            []string                `yaml:"args,omitempty"`       // base args passed to command
 	Model           string                  `yaml:"model,omitempty"`
 	ClaudeConfigDir string                  `yaml:"claude_config_dir,omitempty"`
+	ClaudeProfile   string                  `yaml:"claude_profile,omitempty"` // named auth profile under ~/.h2/claude-config/
 	WorkingDir      string                  `yaml:"working_dir,omitempty"`  // agent CWD (default ".")
+	SandboxRoot     string                  `yaml:"sandbox_root,omitempty"` // working_dir must resolve inside this dir
+	CreateWorkingDir bool                   `yaml:"create_working_dir,omitempty"` // create a missing working_dir (with parents) at launch
 	Worktree        *WorktreeConfig         `yaml:"worktree,omitempty"`    // git worktree settings
 	SystemPrompt    string                  `yaml:"system_prompt,omitempty"` // replaces Claude's entire default system prompt (--system-prompt)
 	Instructions    string                  `yaml:"instructions"`           // appended to default system prompt (--append-system-prompt)
+	InstructionsFile string                 `yaml:"instructions_file,omitempty"` // load instructions from this file when instructions is empty
 	PermissionMode  string                  `yaml:"permission_mode,omitempty"` // Claude CLI --permission-mode flag
 	Permissions     Permissions             `yaml:"permissions,omitempty"`
 	Heartbeat       *HeartbeatConfig        `yaml:"heartbeat,omitempty"`
+	ConfirmQuit     *bool                   `yaml:"confirm_quit,omitempty"` // require confirming menu Quit (default true)
+	AllowPassthrough *bool                  `yaml:"allow_passthrough,omitempty"` // allow raw passthrough to the child (default true)
+	MaxInputBytes   int                     `yaml:"max_input_bytes,omitempty"` // input bar length cap (default 16KiB)
+	SubmitNewline   string                  `yaml:"submit_newline,omitempty"` // bytes sent on submit: cr (default), lf, crlf
+	SubmitDelay     string                  `yaml:"submit_delay,omitempty"` // pause before the submit bytes (default 50ms, "0" disables)
+	ModifierEnter   string                  `yaml:"modifier_enter,omitempty"` // Shift/Alt+Enter: insert_newline (default), forward_cr, forward_lf
+	InputPlaceholder string                 `yaml:"input_placeholder,omitempty"` // dim hint shown in the empty input bar
+	DurationPrecision string                `yaml:"duration_precision,omitempty"` // status bar idle time: compact (30s, 2h; default) or full (1h30m05s)
+	ScrollOnOutput  string                  `yaml:"scroll_on_output,omitempty"` // scroll mode on new output: stay (default) or follow
+	Rows            int                     `yaml:"rows,omitempty"` // initial daemon PTY rows before the first attach (default 24)
+	Cols            int                     `yaml:"cols,omitempty"` // initial daemon PTY columns before the first attach (default 80)
+	ReadyRegex      string                  `yaml:"ready_regex,omitempty"` // screen pattern meaning the agent is idle at its prompt
+	OnMessage       string                  `yaml:"on_message,omitempty"` // shell command run after each delivered message
+	OnStartMessage  string                  `yaml:"on_start_message,omitempty"` // message sent once when the agent first goes idle
+	MessagePriority MessagePriority         `yaml:"message_priority,omitempty"` // priorities for the start and heartbeat messages
+	EscalateAfter   string                  `yaml:"escalate_after,omitempty"` // promote idle messages to interrupt after this long
+	MessageAging    string                  `yaml:"message_aging,omitempty"`  // promote waiting idle messages to normal after this long
+	DedupeWindow    string                  `yaml:"dedupe_window,omitempty"`  // drop a message identical to one queued this recently
+	PassthroughIdleTimeout string           `yaml:"passthrough_idle_timeout,omitempty"` // release an idle passthrough lock after this long
+	IdleThreshold   string                  `yaml:"idle_threshold,omitempty"` // quiet time before the agent counts as idle (default 2s)
+	DrainOnQuit     bool                    `yaml:"drain_on_quit,omitempty"` // deliver queued messages before menu Quit stops the agent
+	ShutdownGrace   string                  `yaml:"shutdown_grace,omitempty"` // bound on draining and on SIGTERM before SIGKILL (default 10s)
+	WriteLock       bool                    `yaml:"write_lock,omitempty"` // only one attached client at a time may send input
+	ExtraArgs       []string                `yaml:"extra_args,omitempty"` // appended to the agent command after h2's own flags
+	Requires        []string                `yaml:"requires,omitempty"`   // binaries that must be on PATH to launch
+	StatusFile      string                  `yaml:"status_file,omitempty"` // one-line status rewritten every second (e.g. for tmux)
+	ActivityLog     *ActivityLogConfig      `yaml:"activity_log,omitempty"` // activity log rotation limits
+	Env             map[string]string       `yaml:"env,omitempty"` // extra environment for the child; overrides h2's derived values except H2_*
+	NoHooks         bool                    `yaml:"no_hooks,omitempty"`  // launch without h2's hooks in settings.json (debugging)
 	Hooks           yaml.Node               `yaml:"hooks,omitempty"`      // passed through as-is to settings.json
 	Settings        yaml.Node               `yaml:"settings,omitempty"`   // extra settings.json keys
 	Variables       map[string]tmpl.VarDef  `yaml:"variables,omitempty"`  // template variable definitions
+	Consts          map[string]string       `yaml:"consts,omitempty"`     // fixed template values (lowest precedence)
 }
 
 // ResolveWorkingDir returns the absolute path for the agent's working directory.
@@ -129,6 +221,150 @@ func (r *Role) ResolveWorkingDir(invocationCWD string) (string, error) {
 	return filepath.Join(h2Dir, dir), nil
 }
 
+// ResolveSandboxRoot returns the sandbox root that applies to the role:
+// its own sandbox_root, which must lie inside globalRoot (the sandbox_root
+// from config.yaml) when that is set, or else globalRoot. A role can only
+// narrow the global sandbox, never move it. Relative roots are resolved
+// against the h2 dir. Returns "" when neither is set.
+func (r *Role) ResolveSandboxRoot(globalRoot string) (string, error) {
+	global, err := absSandboxRoot(globalRoot)
+	if err != nil {
+		return "", err
+	}
+	if r.SandboxRoot == "" {
+		return global, nil
+	}
+	root, err := absSandboxRoot(r.SandboxRoot)
+	if err != nil {
+		return "", err
+	}
+	if global != "" {
+		within, err := isWithin(root, global)
+		if err != nil {
+			return "", err
+		}
+		if !within {
+			return "", fmt.Errorf("role sandbox_root %q is outside the global sandbox_root %q", r.SandboxRoot, globalRoot)
+		}
+	}
+	return root, nil
+}
+
+// absSandboxRoot resolves a relative sandbox root against the h2 dir.
+func absSandboxRoot(root string) (string, error) {
+	if root == "" || filepath.IsAbs(root) {
+		return root, nil
+	}
+	h2Dir, err := ResolveDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve h2 dir for sandbox_root: %w", err)
+	}
+	return filepath.Join(h2Dir, root), nil
+}
+
+// CheckSandboxed returns an error unless dir lies within the sandbox root
+// that applies to the role (see ResolveSandboxRoot). It is a no-op when no
+// sandbox root is set.
+func (r *Role) CheckSandboxed(dir, globalRoot string) error {
+	root, err := r.ResolveSandboxRoot(globalRoot)
+	if err != nil || root == "" {
+		return err
+	}
+	return CheckWithinSandbox(dir, root)
+}
+
+// ResolveSandboxedWorkingDir resolves the working dir like ResolveWorkingDir
+// and, when a sandbox root applies, verifies the result lies within it.
+func (r *Role) ResolveSandboxedWorkingDir(invocationCWD, globalRoot string) (string, error) {
+	dir, err := r.ResolveWorkingDir(invocationCWD)
+	if err != nil {
+		return "", err
+	}
+	if err := r.CheckSandboxed(dir, globalRoot); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// PrepareWorkingDir makes sure the resolved working dir exists before
+// launch. With create_working_dir it creates a missing dir (with parents);
+// otherwise a missing dir is reported here rather than as an exec failure.
+func (r *Role) PrepareWorkingDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err == nil {
+		if !info.IsDir() {
+			return fmt.Errorf("working_dir %q is not a directory", dir)
+		}
+		return nil
+	}
+	if !os.IsNotExist(err) {
+		return fmt.Errorf("working_dir %q: %w", dir, err)
+	}
+	if !r.CreateWorkingDir {
+		return fmt.Errorf("working_dir %q does not exist (set create_working_dir: true to create it)", dir)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create working_dir %q: %w", dir, err)
+	}
+	return nil
+}
+
+// CheckWithinSandbox returns an error unless dir is root or a descendant of
+// it. Symlinks in both paths are resolved first, so a link pointing outside
+// the root is rejected. A dir that doesn't exist yet is checked through its
+// nearest existing ancestor, so it can be vetted before being created.
+func CheckWithinSandbox(dir, root string) error {
+	within, err := isWithin(dir, root)
+	if err != nil {
+		return err
+	}
+	if !within {
+		return fmt.Errorf("working dir %q is outside sandbox_root %q", dir, root)
+	}
+	return nil
+}
+
+// isWithin reports whether path, with symlinks resolved, is root or a
+// descendant of it.
+func isWithin(path, root string) (bool, error) {
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return false, fmt.Errorf("resolve sandbox_root %q: %w", root, err)
+	}
+	realPath, err := evalExistingSymlinks(path)
+	if err != nil {
+		return false, fmt.Errorf("resolve %q: %w", path, err)
+	}
+	rel, err := filepath.Rel(realRoot, realPath)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)), nil
+}
+
+// evalExistingSymlinks is filepath.EvalSymlinks for a path whose trailing
+// components may not exist yet: the longest existing prefix is resolved and
+// the missing remainder appended to it.
+func evalExistingSymlinks(path string) (string, error) {
+	path = filepath.Clean(path)
+	var missing []string
+	for {
+		real, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			for i := len(missing) - 1; i >= 0; i-- {
+				real = filepath.Join(real, missing[i])
+			}
+			return real, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return "", err
+		}
+		missing = append(missing, filepath.Base(path))
+		path = parent
+	}
+}
+
 // GetAgentType returns the agent type for this role, defaulting to "claude".
 func (r *Role) GetAgentType() string {
 	if r.AgentType != "" {
@@ -137,6 +373,164 @@ func (r *Role) GetAgentType() string {
 	return "claude"
 }
 
+// GetCommand returns the executable to launch for this role: Command when
+// set, otherwise the agent type (so existing roles keep running claude).
+func (r *Role) GetCommand() string {
+	if r.Command != "" {
+		return r.Command
+	}
+	return r.GetAgentType()
+}
+
+// ParseEscalateAfter parses EscalateAfter as a Go duration. Returns 0 if unset.
+func (r *Role) ParseEscalateAfter() (time.Duration, error) {
+	if r.EscalateAfter == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(r.EscalateAfter)
+}
+
+// ParseMessageAging parses MessageAging as a Go duration. Returns 0 (aging
+// off) if unset.
+func (r *Role) ParseMessageAging() (time.Duration, error) {
+	if r.MessageAging == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(r.MessageAging)
+}
+
+// ParseDedupeWindow parses DedupeWindow as a Go duration. Returns 0
+// (deduplication off) if unset.
+func (r *Role) ParseDedupeWindow() (time.Duration, error) {
+	if r.DedupeWindow == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(r.DedupeWindow)
+}
+
+// ParseIdleThreshold parses IdleThreshold as a Go duration, like the
+// heartbeat's idle_timeout. Returns 0 (use the 2s default) if unset.
+func (r *Role) ParseIdleThreshold() (time.Duration, error) {
+	if r.IdleThreshold == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(r.IdleThreshold)
+}
+
+// ParseShutdownGrace parses ShutdownGrace as a Go duration. Returns 0 (use
+// the 10s default) if unset.
+func (r *Role) ParseShutdownGrace() (time.Duration, error) {
+	if r.ShutdownGrace == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(r.ShutdownGrace)
+}
+
+// ParseSubmitDelay parses SubmitDelay as a Go duration. The delay only
+// matters for Ink-based TUIs (like Claude Code), which can drop a submit
+// that arrives with the typed text; plain shells can set it to "0".
+// Returns 0 if unset; the session then applies its own default.
+func (r *Role) ParseSubmitDelay() (time.Duration, error) {
+	if r.SubmitDelay == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(r.SubmitDelay)
+}
+
+// ParsePassthroughIdleTimeout parses PassthroughIdleTimeout as a Go duration.
+// Returns 0 (never auto-release) if unset.
+func (r *Role) ParsePassthroughIdleTimeout() (time.Duration, error) {
+	if r.PassthroughIdleTimeout == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(r.PassthroughIdleTimeout)
+}
+
+// ManagedChildFlags are the agent command flags h2 sets itself. Passing them
+// again through extra_args leads to duplicated or conflicting values.
+var ManagedChildFlags = []string{
+	"--session-id",
+	"--system-prompt",
+	"--append-system-prompt",
+	"--model",
+	"--permission-mode",
+	"--allowedTools",
+	"--disallowedTools",
+}
+
+// ExtraArgConflicts returns the extra_args entries that repeat a flag h2
+// already manages (either "--flag" or "--flag=value").
+func (r *Role) ExtraArgConflicts() []string {
+	var conflicts []string
+	for _, arg := range r.ExtraArgs {
+		flag, _, _ := strings.Cut(arg, "=")
+		for _, managed := range ManagedChildFlags {
+			if flag == managed {
+				conflicts = append(conflicts, flag)
+				break
+			}
+		}
+	}
+	return conflicts
+}
+
+// MissingRequirements returns the requires entries that can't be found on
+// the PATH the agent will run with: the role's env PATH when set, otherwise
+// the launcher's. Entries containing a slash are checked as paths.
+func (r *Role) MissingRequirements() []string {
+	pathList, ok := r.Env["PATH"]
+	if !ok {
+		pathList = os.Getenv("PATH")
+	}
+	var missing []string
+	for _, bin := range r.Requires {
+		if !lookPathIn(bin, pathList) {
+			missing = append(missing, bin)
+		}
+	}
+	return missing
+}
+
+// lookPathIn reports whether bin resolves to an executable file, searching
+// the directories in pathList like exec.LookPath does with $PATH.
+func lookPathIn(bin, pathList string) bool {
+	if strings.Contains(bin, "/") {
+		return isExecutableFile(bin)
+	}
+	for _, dir := range filepath.SplitList(pathList) {
+		if dir == "" {
+			dir = "."
+		}
+		if isExecutableFile(filepath.Join(dir, bin)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isExecutableFile reports whether path is a regular file with an execute bit set.
+func isExecutableFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0o111 != 0
+}
+
+// GetConfirmQuit returns whether menu Quit requires confirmation, defaulting to true.
+func (r *Role) GetConfirmQuit() bool {
+	if r.ConfirmQuit != nil {
+		return *r.ConfirmQuit
+	}
+	return true
+}
+
+// GetAllowPassthrough returns whether clients may enter passthrough mode,
+// defaulting to true.
+func (r *Role) GetAllowPassthrough() bool {
+	if r.AllowPassthrough != nil {
+		return *r.AllowPassthrough
+	}
+	return true
+}
+
 // Permissions defines the permission configuration for a role.
 type Permissions struct {
 	Allow []string         `yaml:"allow,omitempty"`
@@ -169,11 +563,21 @@ func DefaultClaudeConfigDir() string {
 	return filepath.Join(ConfigDir(), "claude-config", "default")
 }
 
+// ClaudeProfileDir returns the config directory for a named Claude auth
+// profile (~/.h2/claude-config/<profile>).
+func ClaudeProfileDir(profile string) string {
+	return filepath.Join(ConfigDir(), "claude-config", profile)
+}
+
 // GetClaudeConfigDir returns the Claude config directory for this role.
-// If not specified in the role, returns the default shared config dir.
+// A claude_profile resolves to that profile's directory. If neither it nor
+// claude_config_dir is specified, returns the default shared config dir.
 // If set to "~/" (the home directory), returns "" to indicate that
 // CLAUDE_CONFIG_DIR should not be overridden (use system default).
 func (r *Role) GetClaudeConfigDir() string {
+	if r.ClaudeProfile != "" {
+		return ClaudeProfileDir(r.ClaudeProfile)
+	}
 	if r.ClaudeConfigDir != "" {
 		// Expand ~ to home directory if present.
 		if strings.HasPrefix(r.ClaudeConfigDir, "~/") {
@@ -235,23 +639,31 @@ func LoadRole(name string) (*Role, error) {
 	return LoadRoleFrom(path)
 }
 
-// LoadRoleFrom loads a role from the given file path.
+// LoadRoleFrom loads a role from the given file path, layering it over any
+// roles it extends.
 func LoadRoleFrom(path string) (*Role, error) {
-	data, err := os.ReadFile(path)
+	chain, err := loadRoleChain(path)
 	if err != nil {
-		return nil, fmt.Errorf("read role file: %w", err)
+		return nil, err
 	}
 
-	var role Role
-	if err := yaml.Unmarshal(data, &role); err != nil {
-		return nil, fmt.Errorf("parse role YAML: %w", err)
+	var role *Role
+	for _, layer := range chain {
+		var r Role
+		if err := unmarshalStrict([]byte(layer.data), &r); err != nil {
+			return nil, fmt.Errorf("parse role YAML %q: %w", layer.path, err)
+		}
+		if err := r.loadInstructionsFile(layer.path, nil); err != nil {
+			return nil, fmt.Errorf("role %q: %w", filepath.Base(layer.path), err)
+		}
+		role = mergeRoles(role, &r)
 	}
 
 	if err := role.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid role %q: %w", path, err)
 	}
 
-	return &role, nil
+	return role, nil
 }
 
 // LoadRoleRendered loads a role by name, rendering it with the given template context.
@@ -268,15 +680,37 @@ func LoadRoleRenderedFrom(path string, ctx *tmpl.Context) (*Role, error) {
 		return LoadRoleFrom(path)
 	}
 
-	data, err := os.ReadFile(path)
+	chain, err := loadRoleChain(path)
 	if err != nil {
-		return nil, fmt.Errorf("read role file: %w", err)
+		return nil, err
 	}
 
-	// Extract variables section before rendering.
-	defs, remaining, err := tmpl.ParseVarDefs(string(data))
-	if err != nil {
-		return nil, fmt.Errorf("parse variables in role %q: %w", path, err)
+	// Extract each layer's variables and consts before rendering, so a child
+	// can reference variables its parents define.
+	defs := map[string]tmpl.VarDef{}
+	var consts map[string]string
+	remaining := make([]string, len(chain))
+	for i, layer := range chain {
+		layerDefs, rest, err := tmpl.ParseVarDefs(layer.data)
+		if err != nil {
+			return nil, fmt.Errorf("parse variables in role %q: %w", layer.path, err)
+		}
+		layerConsts, rest, err := tmpl.ParseConsts(rest)
+		if err != nil {
+			return nil, fmt.Errorf("parse consts in role %q: %w", layer.path, err)
+		}
+		for k, v := range layerDefs {
+			defs[k] = v
+		}
+		if layerConsts != nil {
+			if consts == nil {
+				consts = map[string]string{}
+			}
+			for k, v := range layerConsts {
+				consts[k] = v
+			}
+		}
+		remaining[i] = rest
 	}
 
 	// Clone ctx.Var so we don't mutate the caller's map.
@@ -294,27 +728,70 @@ func LoadRoleRenderedFrom(path string, ctx *tmpl.Context) (*Role, error) {
 	if err := tmpl.ValidateVars(defs, vars); err != nil {
 		return nil, fmt.Errorf("role %q: %w", filepath.Base(path), err)
 	}
+	tmpl.MergeConsts(vars, consts)
 
-	// Render template with cloned vars.
+	// Render each layer with the cloned vars and merge child over parent.
 	renderCtx := *ctx
 	renderCtx.Var = vars
-	rendered, err := tmpl.Render(remaining, &renderCtx)
-	if err != nil {
-		return nil, fmt.Errorf("template error in role %q (%s): %w", filepath.Base(path), path, err)
-	}
+	var role *Role
+	for i, layer := range chain {
+		rendered, err := tmpl.Render(remaining[i], &renderCtx)
+		if err != nil {
+			return nil, fmt.Errorf("template error in role %q (%s): %w", filepath.Base(layer.path), layer.path, err)
+		}
 
-	var role Role
-	if err := yaml.Unmarshal([]byte(rendered), &role); err != nil {
-		return nil, fmt.Errorf("parse rendered role YAML %q: %w", path, err)
+		var r Role
+		if err := unmarshalStrict([]byte(rendered), &r); err != nil {
+			return nil, fmt.Errorf("parse rendered role YAML %q: %w", layer.path, err)
+		}
+		if err := r.loadInstructionsFile(layer.path, &renderCtx); err != nil {
+			return nil, fmt.Errorf("role %q: %w", filepath.Base(layer.path), err)
+		}
+		role = mergeRoles(role, &r)
 	}
 
-	role.Variables = defs
+	if len(defs) > 0 {
+		role.Variables = defs
+	} else {
+		role.Variables = nil
+	}
+	role.Consts = consts
 
 	if err := role.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid role %q: %w", path, err)
 	}
 
-	return &role, nil
+	return role, nil
+}
+
+// loadInstructionsFile reads InstructionsFile (relative paths resolve against
+// the role file's directory) and uses it as Instructions when no inline
+// instructions are set. The file must exist even when inline instructions
+// take precedence. If ctx is non-nil the file is rendered as a template.
+func (r *Role) loadInstructionsFile(rolePath string, ctx *tmpl.Context) error {
+	if r.InstructionsFile == "" {
+		return nil
+	}
+	path := r.InstructionsFile
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(rolePath), path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read instructions_file: %w", err)
+	}
+	if r.Instructions != "" {
+		return nil
+	}
+	text := string(data)
+	if ctx != nil {
+		text, err = tmpl.Render(text, ctx)
+		if err != nil {
+			return fmt.Errorf("template error in instructions_file %q: %w", path, err)
+		}
+	}
+	r.Instructions = text
+	return nil
 }
 
 // ListRoles returns all available roles from ~/.h2/roles/.
@@ -361,6 +838,14 @@ func (r *Role) Validate() error {
 	if r.Instructions == "" && r.SystemPrompt == "" {
 		return fmt.Errorf("at least one of instructions or system_prompt is required")
 	}
+	if r.ClaudeProfile != "" {
+		if r.ClaudeConfigDir != "" {
+			return fmt.Errorf("claude_profile and claude_config_dir are mutually exclusive")
+		}
+		if r.ClaudeProfile == "." || r.ClaudeProfile == ".." || strings.ContainsAny(r.ClaudeProfile, `/\`) {
+			return fmt.Errorf("invalid claude_profile %q: must be a plain directory name", r.ClaudeProfile)
+		}
+	}
 	if r.PermissionMode != "" {
 		valid := false
 		for _, mode := range ValidPermissionModes {
@@ -383,5 +868,139 @@ func (r *Role) Validate() error {
 			return err
 		}
 	}
+	// Normalize into fresh slices: the lists may share backing arrays with
+	// a parent role or the caller's config.
+	allow, err := normalizePermissionSpecs("permissions.allow", r.Permissions.Allow)
+	if err != nil {
+		return err
+	}
+	deny, err := normalizePermissionSpecs("permissions.deny", r.Permissions.Deny)
+	if err != nil {
+		return err
+	}
+	r.Permissions.Allow, r.Permissions.Deny = allow, deny
+	// The permission reviewer is invoked from the PermissionRequest hook.
+	if r.NoHooks && r.Permissions.Agent != nil && r.Permissions.Agent.IsEnabled() {
+		return fmt.Errorf("no_hooks and permissions.agent are mutually exclusive: the permission reviewer runs from h2's hooks")
+	}
+	if r.MaxInputBytes < 0 {
+		return fmt.Errorf("invalid max_input_bytes %d: must not be negative", r.MaxInputBytes)
+	}
+	switch r.SubmitNewline {
+	case "", "cr", "lf", "crlf":
+	default:
+		return fmt.Errorf("invalid submit_newline %q: must be cr, lf, or crlf", r.SubmitNewline)
+	}
+	switch r.ModifierEnter {
+	case "", "insert_newline", "forward_cr", "forward_lf":
+	default:
+		return fmt.Errorf("invalid modifier_enter %q: must be insert_newline, forward_cr, or forward_lf", r.ModifierEnter)
+	}
+	if strings.ContainsAny(r.InputPlaceholder, "\n\r\t\x1b") {
+		return fmt.Errorf("invalid input_placeholder: must be a single line of plain text")
+	}
+	for _, bin := range r.Requires {
+		if strings.TrimSpace(bin) == "" {
+			return fmt.Errorf("requires entries must not be empty")
+		}
+	}
+	switch r.DurationPrecision {
+	case "", "compact", "full":
+	default:
+		return fmt.Errorf("invalid duration_precision %q: must be compact or full", r.DurationPrecision)
+	}
+	for _, mp := range []struct{ field, value string }{
+		{"message_priority.start", r.MessagePriority.Start},
+		{"message_priority.heartbeat", r.MessagePriority.Heartbeat},
+	} {
+		if mp.value != "" && !validMessagePriority(mp.value) {
+			return fmt.Errorf("invalid %s %q: must be interrupt, normal, idle-first, or idle", mp.field, mp.value)
+		}
+	}
+	// The client reserves two rows (input and status bars) below the child.
+	if r.Rows < 0 || (r.Rows > 0 && r.Rows <= 2) {
+		return fmt.Errorf("invalid rows %d: must be greater than 2 (the rows reserved for the input and status bars)", r.Rows)
+	}
+	if r.Cols < 0 {
+		return fmt.Errorf("invalid cols %d: must not be negative", r.Cols)
+	}
+	switch r.ScrollOnOutput {
+	case "", "stay", "follow":
+	default:
+		return fmt.Errorf("invalid scroll_on_output %q: must be stay or follow", r.ScrollOnOutput)
+	}
+	if r.ReadyRegex != "" {
+		if _, err := regexp.Compile(r.ReadyRegex); err != nil {
+			return fmt.Errorf("invalid ready_regex %q: %w", r.ReadyRegex, err)
+		}
+	}
+	if d, err := r.ParseEscalateAfter(); err != nil || d < 0 || (r.EscalateAfter != "" && d == 0) {
+		return fmt.Errorf("invalid escalate_after %q: must be a positive duration like \"10m\"", r.EscalateAfter)
+	}
+	if d, err := r.ParseMessageAging(); err != nil || d < 0 {
+		return fmt.Errorf("invalid message_aging %q: must be a positive duration like \"5m\"", r.MessageAging)
+	}
+	if d, err := r.ParseDedupeWindow(); err != nil || d < 0 {
+		return fmt.Errorf("invalid dedupe_window %q: must be a positive duration like \"10s\"", r.DedupeWindow)
+	}
+	if d, err := r.ParsePassthroughIdleTimeout(); err != nil || d < 0 {
+		return fmt.Errorf("invalid passthrough_idle_timeout %q: must be a positive duration like \"5m\"", r.PassthroughIdleTimeout)
+	}
+	if d, err := r.ParseSubmitDelay(); err != nil || d < 0 {
+		return fmt.Errorf("invalid submit_delay %q: must be a duration like \"50ms\", or \"0\" to disable", r.SubmitDelay)
+	}
+	if err := r.validateEnv(); err != nil {
+		return err
+	}
+	if d, err := r.ParseIdleThreshold(); err != nil || d < 0 || (r.IdleThreshold != "" && d == 0) {
+		return fmt.Errorf("invalid idle_threshold %q: must be a positive duration like \"10s\"", r.IdleThreshold)
+	}
+	if d, err := r.ParseShutdownGrace(); err != nil || d < 0 || (r.ShutdownGrace != "" && d == 0) {
+		return fmt.Errorf("invalid shutdown_grace %q: must be a positive duration like \"30s\"", r.ShutdownGrace)
+	}
+	if a := r.ActivityLog; a != nil {
+		if a.MaxSizeMB < 0 {
+			return fmt.Errorf("invalid activity_log.max_size_mb %d: must not be negative", a.MaxSizeMB)
+		}
+		if d, err := a.ParseMaxAge(); err != nil || d < 0 {
+			return fmt.Errorf("invalid activity_log.max_age %q: must be a positive duration like \"12h\"", a.MaxAge)
+		}
+		if a.MaxFiles < 0 {
+			return fmt.Errorf("invalid activity_log.max_files %d: must not be negative", a.MaxFiles)
+		}
+	}
+	if r.Heartbeat != nil {
+		if d, err := r.Heartbeat.ParseConditionTimeout(); err != nil || d < 0 || (r.Heartbeat.ConditionTimeout != "" && d == 0) {
+			return fmt.Errorf("invalid heartbeat condition_timeout %q: must be a positive duration like \"5s\"", r.Heartbeat.ConditionTimeout)
+		}
+		if r.Heartbeat.EscalateAfter < 0 {
+			return fmt.Errorf("invalid heartbeat escalate_after %d: must be a positive count", r.Heartbeat.EscalateAfter)
+		}
+		if r.Heartbeat.EscalateAfter == 0 && (r.Heartbeat.EscalateMessage != "" || r.Heartbeat.EscalateCommand != "") {
+			return fmt.Errorf("heartbeat escalate_message and escalate_command need a positive escalate_after")
+		}
+	}
+	return nil
+}
+
+// envKeyRe matches a valid environment variable name.
+var envKeyRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateEnv checks Env keys: each must be a valid identifier, and the
+// H2_ prefix is reserved for the variables h2 sets itself.
+func (r *Role) validateEnv() error {
+	keys := make([]string, 0, len(r.Env))
+	for k := range r.Env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if !envKeyRe.MatchString(k) {
+			return fmt.Errorf("invalid env key %q: must be letters, digits, and underscores, not starting with a digit", k)
+		}
+		if strings.HasPrefix(k, "H2_") {
+			return fmt.Errorf("invalid env key %q: the H2_ prefix is reserved", k)
+		}
+	}
 	return nil
 }
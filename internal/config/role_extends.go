@@ -0,0 +1,153 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// roleLayer is one role file in an extends chain.
+type roleLayer struct {
+	path string
+	data string
+}
+
+// extendsRe matches a top-level extends key in raw (unrendered) role YAML.
+var extendsRe = regexp.MustCompile(`(?m)^extends:[ \t]*(.*?)[ \t]*$`)
+
+// parseExtends returns the parent role name declared in raw role YAML, or ""
+// if the role doesn't extend another. The value must be a plain role name:
+// the chain is resolved before rendering, so it can't be a template.
+func parseExtends(data string) (string, error) {
+	m := extendsRe.FindStringSubmatch(data)
+	if m == nil {
+		return "", nil
+	}
+	name := strings.Trim(m[1], `"'`)
+	if name == "" {
+		return "", fmt.Errorf("extends is empty")
+	}
+	if strings.Contains(name, "{{") || strings.ContainsAny(name, `/\`) {
+		return "", fmt.Errorf("extends %q must be a plain role name", name)
+	}
+	return name, nil
+}
+
+// resolveParentRole finds the file for a parent role: next to the child first
+// (so pod roles can extend other pod roles), then in the global roles dir.
+func resolveParentRole(childPath, name string) (string, error) {
+	candidates := []string{filepath.Join(filepath.Dir(childPath), name+".yaml")}
+	if global := filepath.Join(RolesDir(), name+".yaml"); global != candidates[0] {
+		candidates = append(candidates, global)
+	}
+	for _, p := range candidates {
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("role %q extends %q, which was not found", filepath.Base(childPath), name)
+}
+
+// loadRoleChain reads the role file at path and every role it extends,
+// returning them root ancestor first.
+func loadRoleChain(path string) ([]roleLayer, error) {
+	var chain []roleLayer
+	var names []string
+	seen := map[string]bool{}
+	for {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			abs = path
+		}
+		names = append(names, strings.TrimSuffix(filepath.Base(path), ".yaml"))
+		if seen[abs] {
+			return nil, fmt.Errorf("role inheritance cycle: %s", strings.Join(names, " -> "))
+		}
+		seen[abs] = true
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read role file: %w", err)
+		}
+		chain = append(chain, roleLayer{path: path, data: string(data)})
+
+		parent, err := parseExtends(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("role %q: %w", filepath.Base(path), err)
+		}
+		if parent == "" {
+			break
+		}
+		if path, err = resolveParentRole(path, parent); err != nil {
+			return nil, err
+		}
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain, nil
+}
+
+// mergeRoles layers child over parent. Non-zero child fields override;
+// permissions.allow and permissions.deny concatenate without duplicates;
+// env, variables, and consts maps merge with the child winning per key.
+// Plain booleans can only be turned on by a child, not back off.
+func mergeRoles(parent, child *Role) *Role {
+	if parent == nil {
+		return child
+	}
+	merged := *parent
+	mv := reflect.ValueOf(&merged).Elem()
+	cv := reflect.ValueOf(child).Elem()
+	for i := 0; i < cv.NumField(); i++ {
+		f := cv.Field(i)
+		if f.IsZero() {
+			continue
+		}
+		if f.Kind() == reflect.Map {
+			mv.Field(i).Set(mergeMaps(mv.Field(i), f))
+			continue
+		}
+		mv.Field(i).Set(f)
+	}
+
+	merged.Permissions = parent.Permissions
+	merged.Permissions.Allow = appendUnique(parent.Permissions.Allow, child.Permissions.Allow)
+	merged.Permissions.Deny = appendUnique(parent.Permissions.Deny, child.Permissions.Deny)
+	if child.Permissions.Agent != nil {
+		merged.Permissions.Agent = child.Permissions.Agent
+	}
+	return &merged
+}
+
+// mergeMaps returns a new map holding base's entries overlaid with over's.
+func mergeMaps(base, over reflect.Value) reflect.Value {
+	out := reflect.MakeMapWithSize(over.Type(), base.Len()+over.Len())
+	for _, m := range []reflect.Value{base, over} {
+		iter := m.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), iter.Value())
+		}
+	}
+	return out
+}
+
+// appendUnique returns a followed by the entries of b not already present,
+// dropping duplicates within either list.
+func appendUnique(a, b []string) []string {
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(a)+len(b))
+	seen := make(map[string]bool, len(a)+len(b))
+	for _, s := range append(append([]string{}, a...), b...) {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"h2/internal/tmpl"
+)
+
+// writeRoles writes each name -> YAML pair as <name>.yaml in one temp dir and
+// returns the dir.
+func writeRoles(t *testing.T, roles map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range roles {
+		if err := os.WriteFile(filepath.Join(dir, name+".yaml"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadRoleFrom_ExtendsOverridesScalars(t *testing.T) {
+	dir := writeRoles(t, map[string]string{
+		"base": `
+name: base
+model: sonnet
+permission_mode: plan
+instructions: shared instructions
+`,
+		"coder": `
+name: coder
+extends: base
+model: opus
+`,
+	})
+
+	role, err := LoadRoleFrom(filepath.Join(dir, "coder.yaml"))
+	if err != nil {
+		t.Fatalf("LoadRoleFrom: %v", err)
+	}
+	if role.Name != "coder" || role.Model != "opus" {
+		t.Errorf("name/model = %q/%q, want the child's coder/opus", role.Name, role.Model)
+	}
+	if role.PermissionMode != "plan" || role.Instructions != "shared instructions" {
+		t.Errorf("inherited fields = %q/%q, want the parent's", role.PermissionMode, role.Instructions)
+	}
+	if role.Extends != "base" {
+		t.Errorf("Extends = %q, want base", role.Extends)
+	}
+}
+
+func TestLoadRoleFrom_ExtendsMergesPermissionsAndMaps(t *testing.T) {
+	dir := writeRoles(t, map[string]string{
+		"base": `
+name: base
+instructions: hi
+permissions:
+  allow: ["Read", "Bash(ls:*)"]
+  deny: ["WebFetch"]
+env:
+  LOG_LEVEL: info
+  REGION: eu
+`,
+		"mid": `
+name: mid
+extends: base
+permissions:
+  allow: ["Bash(ls:*)", "Edit"]
+env:
+  LOG_LEVEL: debug
+`,
+		"coder": `
+name: coder
+extends: mid
+permissions:
+  allow: ["Write"]
+  deny: ["WebFetch", "Bash(rm:*)"]
+`,
+	})
+
+	role, err := LoadRoleFrom(filepath.Join(dir, "coder.yaml"))
+	if err != nil {
+		t.Fatalf("LoadRoleFrom: %v", err)
+	}
+	if want := []string{"Read", "Bash(ls:*)", "Edit", "Write"}; !reflect.DeepEqual(role.Permissions.Allow, want) {
+		t.Errorf("Allow = %v, want %v", role.Permissions.Allow, want)
+	}
+	if want := []string{"WebFetch", "Bash(rm:*)"}; !reflect.DeepEqual(role.Permissions.Deny, want) {
+		t.Errorf("Deny = %v, want %v", role.Permissions.Deny, want)
+	}
+	if want := map[string]string{"LOG_LEVEL": "debug", "REGION": "eu"}; !reflect.DeepEqual(role.Env, want) {
+		t.Errorf("Env = %v, want %v", role.Env, want)
+	}
+}
+
+func TestLoadRoleRenderedFrom_ExtendsMergesVariables(t *testing.T) {
+	dir := writeRoles(t, map[string]string{
+		"base": `
+name: base
+variables:
+  team:
+    description: "Team name"
+  env:
+    default: "dev"
+instructions: base for {{ .Var.team }}
+`,
+		"coder": `
+name: coder
+extends: base
+variables:
+  env:
+    default: "prod"
+  lang:
+    default: "go"
+instructions: |
+  {{ .Var.team }} writes {{ .Var.lang }} in {{ .Var.env }}.
+`,
+	})
+
+	ctx := &tmpl.Context{Var: map[string]string{"team": "backend"}}
+	role, err := LoadRoleRenderedFrom(filepath.Join(dir, "coder.yaml"), ctx)
+	if err != nil {
+		t.Fatalf("LoadRoleRenderedFrom: %v", err)
+	}
+	if want := "backend writes go in prod."; strings.TrimSpace(role.Instructions) != want {
+		t.Errorf("Instructions = %q, want %q", role.Instructions, want)
+	}
+	for _, name := range []string{"team", "env", "lang"} {
+		if _, ok := role.Variables[name]; !ok {
+			t.Errorf("Variables missing %q: %v", name, role.Variables)
+		}
+	}
+
+	// A variable the parent requires is still required through the child.
+	if _, err := LoadRoleRenderedFrom(filepath.Join(dir, "coder.yaml"), &tmpl.Context{}); err == nil || !strings.Contains(err.Error(), "team") {
+		t.Errorf("expected missing required var error, got %v", err)
+	}
+}
+
+func TestLoadRoleFrom_ExtendsMissingParent(t *testing.T) {
+	setupFakeHome(t)
+	dir := writeRoles(t, map[string]string{
+		"coder": "name: coder\nextends: nope\ninstructions: hi\n",
+	})
+	_, err := LoadRoleFrom(filepath.Join(dir, "coder.yaml"))
+	if err == nil || !strings.Contains(err.Error(), `extends "nope", which was not found`) {
+		t.Fatalf("expected missing parent error, got %v", err)
+	}
+}
+
+func TestLoadRoleFrom_ExtendsCycle(t *testing.T) {
+	dir := writeRoles(t, map[string]string{
+		"a": "name: a\nextends: b\ninstructions: hi\n",
+		"b": "name: b\nextends: a\ninstructions: hi\n",
+	})
+	_, err := LoadRoleFrom(filepath.Join(dir, "a.yaml"))
+	if err == nil || !strings.Contains(err.Error(), "cycle: a -> b -> a") {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+}
+
+func TestLoadRoleFrom_ExtendsGlobalRole(t *testing.T) {
+	setupFakeHome(t)
+	if err := os.MkdirAll(RolesDir(), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(RolesDir(), "base.yaml"), []byte("name: base\nmodel: haiku\ninstructions: hi\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dir := writeRoles(t, map[string]string{
+		"pod-coder": "name: pod-coder\nextends: base\n",
+	})
+
+	role, err := LoadRoleFrom(filepath.Join(dir, "pod-coder.yaml"))
+	if err != nil {
+		t.Fatalf("LoadRoleFrom: %v", err)
+	}
+	if role.Model != "haiku" {
+		t.Errorf("Model = %q, want haiku from the global parent", role.Model)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"h2/internal/tmpl"
 )
@@ -266,6 +267,64 @@ func TestEnsureClaudeConfigDir(t *testing.T) {
 	}
 }
 
+func TestEnsureNoHooksConfigDir(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "claude-config")
+	if err := EnsureClaudeConfigDir(base); err != nil {
+		t.Fatalf("EnsureClaudeConfigDir: %v", err)
+	}
+	os.WriteFile(filepath.Join(base, ".claude.json"), []byte(`{"oauthAccount":{}}`), 0o644)
+
+	dir, err := EnsureNoHooksConfigDir(base)
+	if err != nil {
+		t.Fatalf("EnsureNoHooksConfigDir: %v", err)
+	}
+	if dir != NoHooksConfigDir(base) {
+		t.Errorf("dir = %q, want %q", dir, NoHooksConfigDir(base))
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "settings.json"))
+	if err != nil {
+		t.Fatalf("read settings.json: %v", err)
+	}
+	var settings map[string]any
+	if err := json.Unmarshal(data, &settings); err != nil {
+		t.Fatalf("parse settings.json: %v", err)
+	}
+	if _, ok := settings["hooks"]; ok {
+		t.Errorf("no-hooks settings.json should have no hooks, got %s", data)
+	}
+
+	if target, err := os.Readlink(filepath.Join(dir, ".claude.json")); err != nil || target != filepath.Join(base, ".claude.json") {
+		t.Errorf(".claude.json link = %q (%v), want link to base", target, err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, ".credentials.json")); !os.IsNotExist(err) {
+		t.Error(".credentials.json should not be linked when the base has none")
+	}
+
+	// A second call is a no-op.
+	if _, err := EnsureNoHooksConfigDir(base); err != nil {
+		t.Fatalf("EnsureNoHooksConfigDir (2nd call): %v", err)
+	}
+}
+
+func TestValidate_NoHooksExcludesPermissionAgent(t *testing.T) {
+	role := &Role{
+		Name:         "test",
+		Instructions: "x",
+		NoHooks:      true,
+		Permissions:  Permissions{Agent: &PermissionAgent{Instructions: "review"}},
+	}
+	if err := role.Validate(); err == nil {
+		t.Fatal("expected no_hooks with permissions.agent to be rejected")
+	}
+
+	disabled := false
+	role.Permissions.Agent.Enabled = &disabled
+	if err := role.Validate(); err != nil {
+		t.Errorf("no_hooks with a disabled reviewer should be valid: %v", err)
+	}
+}
+
 func TestSetupSessionDir_NoAgent(t *testing.T) {
 	setupFakeHome(t)
 
@@ -371,6 +430,290 @@ func TestIsClaudeConfigAuthenticated(t *testing.T) {
 	})
 }
 
+func TestRole_GetConfirmQuit(t *testing.T) {
+	path := writeTempFile(t, "default.yaml", "name: default\ninstructions: hi\n")
+	role, err := LoadRoleFrom(path)
+	if err != nil {
+		t.Fatalf("LoadRoleFrom: %v", err)
+	}
+	if !role.GetConfirmQuit() {
+		t.Error("confirm_quit should default to true")
+	}
+
+	path = writeTempFile(t, "fast.yaml", "name: fast\ninstructions: hi\nconfirm_quit: false\n")
+	role, err = LoadRoleFrom(path)
+	if err != nil {
+		t.Fatalf("LoadRoleFrom: %v", err)
+	}
+	if role.GetConfirmQuit() {
+		t.Error("confirm_quit: false should disable confirmation")
+	}
+}
+
+func TestRole_GetAllowPassthrough(t *testing.T) {
+	path := writeTempFile(t, "default.yaml", "name: default\ninstructions: hi\n")
+	role, err := LoadRoleFrom(path)
+	if err != nil {
+		t.Fatalf("LoadRoleFrom: %v", err)
+	}
+	if !role.GetAllowPassthrough() {
+		t.Error("allow_passthrough should default to true")
+	}
+
+	path = writeTempFile(t, "locked.yaml", "name: locked\ninstructions: hi\nallow_passthrough: false\n")
+	role, err = LoadRoleFrom(path)
+	if err != nil {
+		t.Fatalf("LoadRoleFrom: %v", err)
+	}
+	if role.GetAllowPassthrough() {
+		t.Error("allow_passthrough: false should disable passthrough")
+	}
+}
+
+func TestValidate_MaxInputBytes(t *testing.T) {
+	role := &Role{Name: "r", Instructions: "hi", MaxInputBytes: 4096}
+	if err := role.Validate(); err != nil {
+		t.Fatalf("expected valid max_input_bytes, got %v", err)
+	}
+	role.MaxInputBytes = -1
+	if err := role.Validate(); err == nil {
+		t.Fatal("expected error for negative max_input_bytes")
+	}
+}
+
+func TestValidate_SubmitNewline(t *testing.T) {
+	for _, v := range []string{"", "cr", "lf", "crlf"} {
+		role := &Role{Name: "r", Instructions: "hi", SubmitNewline: v}
+		if err := role.Validate(); err != nil {
+			t.Errorf("submit_newline %q: expected valid, got %v", v, err)
+		}
+	}
+	role := &Role{Name: "r", Instructions: "hi", SubmitNewline: "CR\n"}
+	if err := role.Validate(); err == nil {
+		t.Fatal("expected error for invalid submit_newline")
+	}
+}
+
+func TestValidate_ModifierEnter(t *testing.T) {
+	for _, v := range []string{"", "insert_newline", "forward_cr", "forward_lf"} {
+		role := &Role{Name: "r", Instructions: "hi", ModifierEnter: v}
+		if err := role.Validate(); err != nil {
+			t.Errorf("modifier_enter %q: expected valid, got %v", v, err)
+		}
+	}
+	role := &Role{Name: "r", Instructions: "hi", ModifierEnter: "newline"}
+	if err := role.Validate(); err == nil {
+		t.Fatal("expected error for invalid modifier_enter")
+	}
+}
+
+func TestValidate_InputPlaceholder(t *testing.T) {
+	role := &Role{Name: "r", Instructions: "hi", InputPlaceholder: "Type a message, / for passthrough…"}
+	if err := role.Validate(); err != nil {
+		t.Errorf("expected valid placeholder, got %v", err)
+	}
+	for _, bad := range []string{"two\nlines", "\x1b[31mred"} {
+		role.InputPlaceholder = bad
+		if err := role.Validate(); err == nil {
+			t.Errorf("input_placeholder %q: expected error", bad)
+		}
+	}
+}
+
+func TestRole_MissingRequirements(t *testing.T) {
+	role := &Role{Name: "r", Instructions: "hi", Requires: []string{"sh", "h2-no-such-binary", "/no/such/path/tool"}}
+	got := role.MissingRequirements()
+	if len(got) != 2 || got[0] != "h2-no-such-binary" || got[1] != "/no/such/path/tool" {
+		t.Errorf("MissingRequirements = %v, want [h2-no-such-binary /no/such/path/tool]", got)
+	}
+
+	role.Requires = []string{""}
+	if err := role.Validate(); err == nil {
+		t.Fatal("expected error for empty requires entry")
+	}
+}
+
+func TestRole_MissingRequirements_UsesRoleEnvPath(t *testing.T) {
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "h2-role-tool"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	role := &Role{
+		Name:         "r",
+		Instructions: "hi",
+		Requires:     []string{"h2-role-tool", "sh"},
+		Env:          map[string]string{"PATH": binDir},
+	}
+	// sh is on the launcher's PATH but not on the role's.
+	got := role.MissingRequirements()
+	if len(got) != 1 || got[0] != "sh" {
+		t.Errorf("MissingRequirements = %v, want [sh]", got)
+	}
+}
+
+func TestValidate_DurationPrecision(t *testing.T) {
+	for _, v := range []string{"", "compact", "full"} {
+		role := &Role{Name: "r", Instructions: "hi", DurationPrecision: v}
+		if err := role.Validate(); err != nil {
+			t.Errorf("duration_precision %q: expected valid, got %v", v, err)
+		}
+	}
+	role := &Role{Name: "r", Instructions: "hi", DurationPrecision: "seconds"}
+	if err := role.Validate(); err == nil {
+		t.Fatal("expected error for invalid duration_precision")
+	}
+}
+
+func TestValidate_MessagePriority(t *testing.T) {
+	for _, v := range []string{"", "interrupt", "normal", "idle-first", "idle"} {
+		role := &Role{Name: "r", Instructions: "hi", MessagePriority: MessagePriority{Start: v, Heartbeat: v}}
+		if err := role.Validate(); err != nil {
+			t.Errorf("message_priority %q: expected valid, got %v", v, err)
+		}
+	}
+	role := &Role{Name: "r", Instructions: "hi", MessagePriority: MessagePriority{Heartbeat: "urgent"}}
+	err := role.Validate()
+	if err == nil || !strings.Contains(err.Error(), "message_priority.heartbeat") {
+		t.Fatalf("expected message_priority.heartbeat error, got %v", err)
+	}
+	role = &Role{Name: "r", Instructions: "hi", MessagePriority: MessagePriority{Start: "later"}}
+	if err := role.Validate(); err == nil || !strings.Contains(err.Error(), "message_priority.start") {
+		t.Fatalf("expected message_priority.start error, got %v", err)
+	}
+}
+
+func TestLoadRoleFrom_MessagePriority(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "r.yaml")
+	os.WriteFile(path, []byte("name: r\ninstructions: hi\nmessage_priority:\n  start: interrupt\n  heartbeat: idle-first\n"), 0o644)
+	role, err := LoadRoleFrom(path)
+	if err != nil {
+		t.Fatalf("LoadRoleFrom: %v", err)
+	}
+	if role.MessagePriority.Start != "interrupt" || role.MessagePriority.Heartbeat != "idle-first" {
+		t.Fatalf("MessagePriority = %+v", role.MessagePriority)
+	}
+
+	os.WriteFile(path, []byte("name: r\ninstructions: hi\nmessage_priority:\n  heartbeat: soon\n"), 0o644)
+	if _, err := LoadRoleFrom(path); err == nil {
+		t.Fatal("expected load error for invalid heartbeat priority")
+	}
+}
+
+func TestValidate_SubmitDelay(t *testing.T) {
+	for _, tc := range []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"0", 0},
+		{"120ms", 120 * time.Millisecond},
+	} {
+		role := &Role{Name: "r", Instructions: "hi", SubmitDelay: tc.value}
+		if err := role.Validate(); err != nil {
+			t.Fatalf("submit_delay %q: expected valid, got %v", tc.value, err)
+		}
+		if d, _ := role.ParseSubmitDelay(); d != tc.want {
+			t.Errorf("submit_delay %q: ParseSubmitDelay = %v, want %v", tc.value, d, tc.want)
+		}
+	}
+	for _, bad := range []string{"fast", "-5ms"} {
+		role := &Role{Name: "r", Instructions: "hi", SubmitDelay: bad}
+		if err := role.Validate(); err == nil || !strings.Contains(err.Error(), "submit_delay") {
+			t.Errorf("submit_delay %q: expected error, got %v", bad, err)
+		}
+	}
+}
+
+func TestValidate_IdleThreshold(t *testing.T) {
+	role := &Role{Name: "r", Instructions: "hi", IdleThreshold: "10s"}
+	if err := role.Validate(); err != nil {
+		t.Fatalf("expected valid idle_threshold, got %v", err)
+	}
+	if d, _ := role.ParseIdleThreshold(); d != 10*time.Second {
+		t.Errorf("ParseIdleThreshold = %v, want 10s", d)
+	}
+	for _, bad := range []string{"soon", "-1s", "0s"} {
+		role := &Role{Name: "r", Instructions: "hi", IdleThreshold: bad}
+		if err := role.Validate(); err == nil || !strings.Contains(err.Error(), "idle_threshold") {
+			t.Errorf("idle_threshold %q: expected error, got %v", bad, err)
+		}
+	}
+	if d, err := (&Role{}).ParseIdleThreshold(); err != nil || d != 0 {
+		t.Errorf("unset ParseIdleThreshold = %v, %v; want 0", d, err)
+	}
+}
+
+func TestValidate_ActivityLog(t *testing.T) {
+	role := &Role{Name: "r", Instructions: "hi", ActivityLog: &ActivityLogConfig{MaxSizeMB: 50, MaxAge: "12h", MaxFiles: 10}}
+	if err := role.Validate(); err != nil {
+		t.Fatalf("expected valid activity_log, got %v", err)
+	}
+	if d, _ := role.ActivityLog.ParseMaxAge(); d != 12*time.Hour {
+		t.Errorf("ParseMaxAge = %v, want 12h", d)
+	}
+	for _, bad := range []ActivityLogConfig{{MaxSizeMB: -1}, {MaxAge: "later"}, {MaxAge: "-1h"}, {MaxFiles: -2}} {
+		role := &Role{Name: "r", Instructions: "hi", ActivityLog: &bad}
+		if err := role.Validate(); err == nil || !strings.Contains(err.Error(), "activity_log") {
+			t.Errorf("activity_log %+v: expected error, got %v", bad, err)
+		}
+	}
+}
+
+func TestValidate_InitialSize(t *testing.T) {
+	role := &Role{Name: "r", Instructions: "hi", Rows: 50, Cols: 200}
+	if err := role.Validate(); err != nil {
+		t.Fatalf("expected valid size, got %v", err)
+	}
+	role = &Role{Name: "r", Instructions: "hi", Rows: 2}
+	if err := role.Validate(); err == nil || !strings.Contains(err.Error(), "rows") {
+		t.Fatalf("expected rows error, got %v", err)
+	}
+	role = &Role{Name: "r", Instructions: "hi", Cols: -1}
+	if err := role.Validate(); err == nil || !strings.Contains(err.Error(), "cols") {
+		t.Fatalf("expected cols error, got %v", err)
+	}
+}
+
+func TestValidate_ScrollOnOutput(t *testing.T) {
+	for _, v := range []string{"", "stay", "follow"} {
+		role := &Role{Name: "r", Instructions: "hi", ScrollOnOutput: v}
+		if err := role.Validate(); err != nil {
+			t.Errorf("scroll_on_output %q: expected valid, got %v", v, err)
+		}
+	}
+	role := &Role{Name: "r", Instructions: "hi", ScrollOnOutput: "bottom"}
+	if err := role.Validate(); err == nil {
+		t.Fatal("expected error for invalid scroll_on_output")
+	}
+}
+
+func TestValidate_ReadyRegex(t *testing.T) {
+	role := &Role{Name: "r", Instructions: "hi", ReadyRegex: `(?m)^> $`}
+	if err := role.Validate(); err != nil {
+		t.Fatalf("expected valid ready_regex, got %v", err)
+	}
+	role.ReadyRegex = "(unclosed"
+	if err := role.Validate(); err == nil {
+		t.Fatal("expected error for invalid ready_regex")
+	}
+}
+
+func TestValidate_PassthroughIdleTimeout(t *testing.T) {
+	role := &Role{Name: "r", Instructions: "hi", PassthroughIdleTimeout: "5m"}
+	if err := role.Validate(); err != nil {
+		t.Fatalf("expected valid passthrough_idle_timeout, got %v", err)
+	}
+	if d, _ := role.ParsePassthroughIdleTimeout(); d != 5*time.Minute {
+		t.Fatalf("ParsePassthroughIdleTimeout = %v, want 5m", d)
+	}
+	for _, bad := range []string{"soon", "-1m"} {
+		role.PassthroughIdleTimeout = bad
+		if err := role.Validate(); err == nil {
+			t.Errorf("expected error for passthrough_idle_timeout %q", bad)
+		}
+	}
+}
+
 func TestRole_GetClaudeConfigDir(t *testing.T) {
 	ResetResolveCache()
 	t.Cleanup(ResetResolveCache)
@@ -433,6 +776,37 @@ func TestRole_GetClaudeConfigDir(t *testing.T) {
 	}
 }
 
+func TestRole_ClaudeProfile(t *testing.T) {
+	ResetResolveCache()
+	t.Cleanup(ResetResolveCache)
+
+	h2Dir := t.TempDir()
+	WriteMarker(h2Dir)
+	t.Setenv("H2_DIR", h2Dir)
+
+	role := &Role{Name: "test", Instructions: "test", ClaudeProfile: "work"}
+	if err := role.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	want := filepath.Join(h2Dir, "claude-config", "work")
+	if got := role.GetClaudeConfigDir(); got != want {
+		t.Errorf("GetClaudeConfigDir() = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"../personal", "a/b", ".."} {
+		role.ClaudeProfile = bad
+		if err := role.Validate(); err == nil {
+			t.Errorf("expected error for claude_profile %q", bad)
+		}
+	}
+
+	role.ClaudeProfile = "work"
+	role.ClaudeConfigDir = "/custom"
+	if err := role.Validate(); err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+		t.Errorf("expected mutually exclusive error, got %v", err)
+	}
+}
+
 func TestLoadRoleFrom_WithHeartbeat(t *testing.T) {
 	yaml := `
 name: scheduler
@@ -514,6 +888,47 @@ func TestHeartbeatConfig_ParseIdleTimeout(t *testing.T) {
 	}
 }
 
+func TestValidate_HeartbeatConditionTimeout(t *testing.T) {
+	hb := &HeartbeatConfig{IdleTimeout: "30s", Message: "nudge", Condition: "true", ConditionTimeout: "2s"}
+	role := &Role{Name: "r", Instructions: "hi", Heartbeat: hb}
+	if err := role.Validate(); err != nil {
+		t.Fatalf("expected valid condition_timeout, got %v", err)
+	}
+	if d, _ := hb.ParseConditionTimeout(); d != 2*time.Second {
+		t.Errorf("ParseConditionTimeout = %v, want 2s", d)
+	}
+	for _, bad := range []string{"later", "-1s", "0s"} {
+		hb := &HeartbeatConfig{IdleTimeout: "30s", Message: "nudge", ConditionTimeout: bad}
+		role := &Role{Name: "r", Instructions: "hi", Heartbeat: hb}
+		if err := role.Validate(); err == nil || !strings.Contains(err.Error(), "condition_timeout") {
+			t.Errorf("condition_timeout %q: expected error, got %v", bad, err)
+		}
+	}
+	if d, err := (&HeartbeatConfig{}).ParseConditionTimeout(); err != nil || d != 0 {
+		t.Errorf("unset ParseConditionTimeout = %v, %v; want 0", d, err)
+	}
+}
+
+func TestValidate_HeartbeatEscalation(t *testing.T) {
+	hb := &HeartbeatConfig{IdleTimeout: "30s", Message: "nudge", EscalateAfter: 3, EscalateCommand: "notify-me"}
+	role := &Role{Name: "r", Instructions: "hi", Heartbeat: hb}
+	if err := role.Validate(); err != nil {
+		t.Fatalf("expected valid escalation, got %v", err)
+	}
+
+	bad := []*HeartbeatConfig{
+		{IdleTimeout: "30s", Message: "nudge", EscalateAfter: -1},
+		{IdleTimeout: "30s", Message: "nudge", EscalateMessage: "WAKE UP"},
+		{IdleTimeout: "30s", Message: "nudge", EscalateCommand: "notify-me"},
+	}
+	for _, hb := range bad {
+		role := &Role{Name: "r", Instructions: "hi", Heartbeat: hb}
+		if err := role.Validate(); err == nil || !strings.Contains(err.Error(), "escalate_after") {
+			t.Errorf("%+v: expected escalate_after error, got %v", *hb, err)
+		}
+	}
+}
+
 func TestResolveWorkingDir_Default(t *testing.T) {
 	role := &Role{Name: "test", Instructions: "test"}
 	got, err := role.ResolveWorkingDir("/my/cwd")
@@ -567,6 +982,188 @@ func TestResolveWorkingDir_Relative(t *testing.T) {
 	}
 }
 
+func TestResolveSandboxedWorkingDir_InRoot(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "project")
+	os.MkdirAll(dir, 0o755)
+
+	role := &Role{Name: "test", WorkingDir: dir, SandboxRoot: root}
+	got, err := role.ResolveSandboxedWorkingDir("/my/cwd", "")
+	if err != nil {
+		t.Fatalf("ResolveSandboxedWorkingDir: %v", err)
+	}
+	if got != dir {
+		t.Errorf("got %q, want %q", got, dir)
+	}
+}
+
+func TestResolveSandboxedWorkingDir_RootItself(t *testing.T) {
+	root := t.TempDir()
+	role := &Role{Name: "test", WorkingDir: root, SandboxRoot: root}
+	if _, err := role.ResolveSandboxedWorkingDir("/my/cwd", ""); err != nil {
+		t.Fatalf("expected sandbox root itself to be allowed: %v", err)
+	}
+}
+
+func TestResolveSandboxedWorkingDir_DotDotEscape(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "sandbox")
+	os.MkdirAll(root, 0o755)
+	os.MkdirAll(filepath.Join(base, "outside"), 0o755)
+
+	role := &Role{Name: "test", WorkingDir: filepath.Join(root, "..", "outside"), SandboxRoot: root}
+	_, err := role.ResolveSandboxedWorkingDir("/my/cwd", "")
+	if err == nil || !strings.Contains(err.Error(), "outside sandbox_root") {
+		t.Fatalf("expected outside sandbox_root error, got %v", err)
+	}
+}
+
+func TestResolveSandboxedWorkingDir_SymlinkEscape(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "sandbox")
+	outside := filepath.Join(base, "outside")
+	os.MkdirAll(root, 0o755)
+	os.MkdirAll(outside, 0o755)
+	link := filepath.Join(root, "link")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	role := &Role{Name: "test", WorkingDir: link, SandboxRoot: root}
+	_, err := role.ResolveSandboxedWorkingDir("/my/cwd", "")
+	if err == nil || !strings.Contains(err.Error(), "outside sandbox_root") {
+		t.Fatalf("expected outside sandbox_root error, got %v", err)
+	}
+}
+
+func TestResolveSandboxedWorkingDir_GlobalRoot(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	role := &Role{Name: "test", WorkingDir: outside}
+	if _, err := role.ResolveSandboxedWorkingDir("/my/cwd", root); err == nil {
+		t.Fatal("expected global sandbox_root to reject dir outside it")
+	}
+
+	// A role-level sandbox_root narrows the global one.
+	inner := filepath.Join(root, "inner")
+	os.MkdirAll(filepath.Join(inner, "project"), 0o755)
+	os.MkdirAll(filepath.Join(root, "other"), 0o755)
+	role = &Role{Name: "test", WorkingDir: filepath.Join(inner, "project"), SandboxRoot: inner}
+	if _, err := role.ResolveSandboxedWorkingDir("/my/cwd", root); err != nil {
+		t.Fatalf("expected dir inside the role sandbox_root to pass: %v", err)
+	}
+	role.WorkingDir = filepath.Join(root, "other")
+	if _, err := role.ResolveSandboxedWorkingDir("/my/cwd", root); err == nil {
+		t.Fatal("expected role sandbox_root to reject a dir outside it but inside the global root")
+	}
+}
+
+func TestResolveSandboxRoot_RoleRootOutsideGlobal(t *testing.T) {
+	global := t.TempDir()
+	outside := t.TempDir()
+
+	for _, roleRoot := range []string{outside, "/"} {
+		role := &Role{Name: "test", WorkingDir: outside, SandboxRoot: roleRoot}
+		_, err := role.ResolveSandboxedWorkingDir("/my/cwd", global)
+		if err == nil || !strings.Contains(err.Error(), "outside the global sandbox_root") {
+			t.Errorf("sandbox_root %q: expected outside the global sandbox_root error, got %v", roleRoot, err)
+		}
+	}
+}
+
+func TestResolveSandboxRoot_RoleRootSymlinkEscape(t *testing.T) {
+	global := t.TempDir()
+	outside := t.TempDir()
+	link := filepath.Join(global, "escape")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	role := &Role{Name: "test", WorkingDir: outside, SandboxRoot: link}
+	_, err := role.ResolveSandboxedWorkingDir("/my/cwd", global)
+	if err == nil || !strings.Contains(err.Error(), "outside the global sandbox_root") {
+		t.Fatalf("expected symlinked role root to be rejected, got %v", err)
+	}
+}
+
+func TestCheckSandboxed_WorktreeSymlinkEscape(t *testing.T) {
+	global := t.TempDir()
+	outside := t.TempDir()
+	worktrees := filepath.Join(global, "worktrees")
+	if err := os.Symlink(outside, worktrees); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	role := &Role{Name: "test"}
+	err := role.CheckSandboxed(filepath.Join(worktrees, "feature"), global)
+	if err == nil || !strings.Contains(err.Error(), "outside sandbox_root") {
+		t.Fatalf("expected worktree path through a symlink to be rejected, got %v", err)
+	}
+	if err := role.CheckSandboxed(filepath.Join(global, "wt", "feature"), global); err != nil {
+		t.Fatalf("worktree path inside the sandbox should pass: %v", err)
+	}
+}
+
+func TestResolveSandboxedWorkingDir_NoRoot(t *testing.T) {
+	role := &Role{Name: "test", WorkingDir: "/some/absolute/path"}
+	got, err := role.ResolveSandboxedWorkingDir("/my/cwd", "")
+	if err != nil {
+		t.Fatalf("ResolveSandboxedWorkingDir: %v", err)
+	}
+	if got != "/some/absolute/path" {
+		t.Errorf("got %q, want %q", got, "/some/absolute/path")
+	}
+}
+
+func TestResolveSandboxedWorkingDir_MissingDirChecked(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "sandbox")
+	os.MkdirAll(root, 0o755)
+
+	role := &Role{Name: "test", WorkingDir: filepath.Join(root, "a", "b"), SandboxRoot: root}
+	if _, err := role.ResolveSandboxedWorkingDir("/my/cwd", ""); err != nil {
+		t.Fatalf("missing dir inside the sandbox should pass: %v", err)
+	}
+
+	role.WorkingDir = filepath.Join(base, "elsewhere", "new")
+	_, err := role.ResolveSandboxedWorkingDir("/my/cwd", "")
+	if err == nil || !strings.Contains(err.Error(), "outside sandbox_root") {
+		t.Fatalf("expected outside sandbox_root error for missing dir, got %v", err)
+	}
+}
+
+func TestPrepareWorkingDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "a", "b")
+
+	role := &Role{Name: "test", WorkingDir: dir}
+	err := role.PrepareWorkingDir(dir)
+	if err == nil || !strings.Contains(err.Error(), "does not exist") {
+		t.Fatalf("expected missing dir error, got %v", err)
+	}
+	if _, statErr := os.Stat(dir); !os.IsNotExist(statErr) {
+		t.Fatal("dir should not be created without create_working_dir")
+	}
+
+	role.CreateWorkingDir = true
+	if err := role.PrepareWorkingDir(dir); err != nil {
+		t.Fatalf("PrepareWorkingDir: %v", err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Fatalf("expected dir to be created: %v", err)
+	}
+	// Existing dir: nothing to do.
+	if err := role.PrepareWorkingDir(dir); err != nil {
+		t.Errorf("PrepareWorkingDir on existing dir: %v", err)
+	}
+
+	file := filepath.Join(t.TempDir(), "file")
+	os.WriteFile(file, nil, 0o644)
+	if err := role.PrepareWorkingDir(file); err == nil || !strings.Contains(err.Error(), "not a directory") {
+		t.Errorf("expected not-a-directory error, got %v", err)
+	}
+}
+
 func TestResolveWorkingDir_FromYAML(t *testing.T) {
 	yaml := `
 name: worker
@@ -710,6 +1307,173 @@ instructions: |
 	}
 }
 
+func TestLoadRoleRenderedFrom_ExtraArgs(t *testing.T) {
+	yamlContent := `
+name: coder
+instructions: hi
+extra_args:
+  - --dangerously-skip-permissions
+  - --mcp-config
+  - "/etc/mcp/{{ .AgentName }}.json"
+`
+	path := writeTempFile(t, "coder.yaml", yamlContent)
+	role, err := LoadRoleRenderedFrom(path, &tmpl.Context{AgentName: "coder-1"})
+	if err != nil {
+		t.Fatalf("LoadRoleRenderedFrom: %v", err)
+	}
+	want := []string{"--dangerously-skip-permissions", "--mcp-config", "/etc/mcp/coder-1.json"}
+	if strings.Join(role.ExtraArgs, " ") != strings.Join(want, " ") {
+		t.Errorf("ExtraArgs = %v, want %v", role.ExtraArgs, want)
+	}
+	if c := role.ExtraArgConflicts(); len(c) != 0 {
+		t.Errorf("unexpected conflicts: %v", c)
+	}
+}
+
+func TestLoadRoleRenderedFrom_Env(t *testing.T) {
+	yamlContent := `
+name: coder
+instructions: hi
+env:
+  ANTHROPIC_MODEL: opus
+  PROJECT_AGENT: "{{ .AgentName }}"
+`
+	path := writeTempFile(t, "coder.yaml", yamlContent)
+	role, err := LoadRoleRenderedFrom(path, &tmpl.Context{AgentName: "coder-1"})
+	if err != nil {
+		t.Fatalf("LoadRoleRenderedFrom: %v", err)
+	}
+	if role.Env["ANTHROPIC_MODEL"] != "opus" || role.Env["PROJECT_AGENT"] != "coder-1" {
+		t.Errorf("Env = %v, want rendered values", role.Env)
+	}
+}
+
+func TestValidate_EnvKeys(t *testing.T) {
+	role := &Role{Name: "r", Instructions: "hi", Env: map[string]string{"_OK": "1", "Mixed_Case9": "2"}}
+	if err := role.Validate(); err != nil {
+		t.Fatalf("expected valid env, got %v", err)
+	}
+	for _, bad := range []string{"9LIVES", "HAS-DASH", "WITH SPACE", "", "H2_ACTOR"} {
+		role := &Role{Name: "r", Instructions: "hi", Env: map[string]string{bad: "x"}}
+		if err := role.Validate(); err == nil || !strings.Contains(err.Error(), "env key") {
+			t.Errorf("env key %q: expected error, got %v", bad, err)
+		}
+	}
+}
+
+func TestRole_ExtraArgConflicts(t *testing.T) {
+	role := &Role{ExtraArgs: []string{"--verbose", "--session-id", "abc", "--model=opus", "--modelish"}}
+	got := role.ExtraArgConflicts()
+	if strings.Join(got, ",") != "--session-id,--model" {
+		t.Errorf("ExtraArgConflicts() = %v, want [--session-id --model]", got)
+	}
+}
+
+func TestLoadRoleRenderedFrom_Consts(t *testing.T) {
+	yamlContent := `
+name: coder
+consts:
+  repo: github.com/acme/app
+  region: us-east-1
+  env: const-env
+variables:
+  env:
+    default: "dev"
+instructions: |
+  Repo {{ .Var.repo }} in {{ .Var.region }} ({{ .Var.env }}).
+`
+	path := writeTempFile(t, "coder.yaml", yamlContent)
+
+	role, err := LoadRoleRenderedFrom(path, &tmpl.Context{AgentName: "coder-1"})
+	if err != nil {
+		t.Fatalf("LoadRoleRenderedFrom: %v", err)
+	}
+	// Consts render, but a variable default beats a const of the same name.
+	want := "Repo github.com/acme/app in us-east-1 (dev)."
+	if strings.TrimSpace(role.Instructions) != want {
+		t.Errorf("Instructions = %q, want %q", strings.TrimSpace(role.Instructions), want)
+	}
+	if role.Consts["region"] != "us-east-1" {
+		t.Errorf("Consts not stored on role: %v", role.Consts)
+	}
+	if _, ok := role.Variables["repo"]; ok {
+		t.Error("consts should not be treated as variables")
+	}
+
+	// --var overrides a const.
+	role, err = LoadRoleRenderedFrom(path, &tmpl.Context{
+		AgentName: "coder-1",
+		Var:       map[string]string{"region": "eu-west-1"},
+	})
+	if err != nil {
+		t.Fatalf("LoadRoleRenderedFrom: %v", err)
+	}
+	if !strings.Contains(role.Instructions, "eu-west-1") {
+		t.Errorf("expected --var to override const, got: %s", role.Instructions)
+	}
+}
+
+func TestLoadRoleFrom_InstructionsFile(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "prompts"), 0o755)
+	os.WriteFile(filepath.Join(dir, "prompts", "shared.md"), []byte("Shared instructions.\n"), 0o644)
+	path := filepath.Join(dir, "coder.yaml")
+	os.WriteFile(path, []byte("name: coder\ninstructions_file: prompts/shared.md\n"), 0o644)
+
+	role, err := LoadRoleFrom(path)
+	if err != nil {
+		t.Fatalf("LoadRoleFrom: %v", err)
+	}
+	if role.Instructions != "Shared instructions.\n" {
+		t.Errorf("Instructions = %q, want file contents", role.Instructions)
+	}
+}
+
+func TestLoadRoleRenderedFrom_InstructionsFileRendered(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "shared.md"), []byte("You are {{ .AgentName }} on {{ .Var.team }}."), 0o644)
+	path := filepath.Join(dir, "coder.yaml")
+	os.WriteFile(path, []byte(`
+name: coder
+variables:
+  team:
+    default: backend
+instructions_file: shared.md
+`), 0o644)
+
+	role, err := LoadRoleRenderedFrom(path, &tmpl.Context{AgentName: "coder-1"})
+	if err != nil {
+		t.Fatalf("LoadRoleRenderedFrom: %v", err)
+	}
+	if role.Instructions != "You are coder-1 on backend." {
+		t.Errorf("Instructions = %q, want rendered file contents", role.Instructions)
+	}
+}
+
+func TestLoadRoleFrom_InlineInstructionsBeatFile(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "shared.md"), []byte("From file."), 0o644)
+	path := filepath.Join(dir, "coder.yaml")
+	os.WriteFile(path, []byte("name: coder\ninstructions: Inline.\ninstructions_file: shared.md\n"), 0o644)
+
+	role, err := LoadRoleFrom(path)
+	if err != nil {
+		t.Fatalf("LoadRoleFrom: %v", err)
+	}
+	if role.Instructions != "Inline." {
+		t.Errorf("Instructions = %q, want inline instructions", role.Instructions)
+	}
+}
+
+func TestLoadRoleFrom_InstructionsFileMissing(t *testing.T) {
+	path := writeTempFile(t, "coder.yaml", "name: coder\ninstructions: Inline.\ninstructions_file: nope.md\n")
+
+	_, err := LoadRoleFrom(path)
+	if err == nil || !strings.Contains(err.Error(), "instructions_file") {
+		t.Fatalf("expected instructions_file error, got %v", err)
+	}
+}
+
 func TestLoadRoleRenderedFrom_WorktreeRendering(t *testing.T) {
 	yamlContent := `
 name: coder
@@ -1243,6 +2007,44 @@ func TestValidate_PermissionMode_Empty(t *testing.T) {
 	}
 }
 
+func TestValidate_EscalateAfter(t *testing.T) {
+	role := &Role{Name: "test", Instructions: "Do stuff", EscalateAfter: "15m"}
+	if err := role.Validate(); err != nil {
+		t.Fatalf("valid escalate_after rejected: %v", err)
+	}
+	if d, _ := role.ParseEscalateAfter(); d != 15*time.Minute {
+		t.Errorf("ParseEscalateAfter = %v, want 15m", d)
+	}
+
+	for _, bad := range []string{"later", "-1m", "0s"} {
+		role.EscalateAfter = bad
+		if err := role.Validate(); err == nil || !strings.Contains(err.Error(), "invalid escalate_after") {
+			t.Errorf("escalate_after %q: expected invalid escalate_after error, got: %v", bad, err)
+		}
+	}
+}
+
+func TestValidate_MessageAging(t *testing.T) {
+	role := &Role{Name: "test", Instructions: "Do stuff"}
+	if d, _ := role.ParseMessageAging(); d != 0 {
+		t.Errorf("unset ParseMessageAging = %v, want 0 (off)", d)
+	}
+	role.MessageAging = "5m"
+	if err := role.Validate(); err != nil {
+		t.Fatalf("valid message_aging rejected: %v", err)
+	}
+	if d, _ := role.ParseMessageAging(); d != 5*time.Minute {
+		t.Errorf("ParseMessageAging = %v, want 5m", d)
+	}
+
+	for _, bad := range []string{"soon", "-1m"} {
+		role.MessageAging = bad
+		if err := role.Validate(); err == nil || !strings.Contains(err.Error(), "invalid message_aging") {
+			t.Errorf("%q: expected invalid message_aging error, got: %v", bad, err)
+		}
+	}
+}
+
 func TestLoadRoleFrom_SystemPromptField(t *testing.T) {
 	yaml := `
 name: custom
@@ -1304,3 +2106,84 @@ func writeTempFile(t *testing.T, name, content string) string {
 	}
 	return path
 }
+
+func TestLoadRoleFrom_CommandArgs(t *testing.T) {
+	yamlContent := `
+name: repl
+instructions: hi
+command: aider
+args: ["--no-auto-commits", "--model", "sonnet"]
+`
+	path := writeTempFile(t, "repl.yaml", yamlContent)
+	role, err := LoadRoleFrom(path)
+	if err != nil {
+		t.Fatalf("LoadRoleFrom: %v", err)
+	}
+	if role.GetCommand() != "aider" {
+		t.Errorf("GetCommand = %q, want aider", role.GetCommand())
+	}
+	if len(role.Args) != 3 || role.Args[0] != "--no-auto-commits" {
+		t.Errorf("Args = %v", role.Args)
+	}
+
+	// Without command, roles keep launching their agent type.
+	if got := (&Role{}).GetCommand(); got != "claude" {
+		t.Errorf("default GetCommand = %q, want claude", got)
+	}
+}
+
+func TestLoadRoleFrom_UnknownKey(t *testing.T) {
+	path := writeTempFile(t, "typo.yaml", `
+name: typo
+instuctions: |
+  Misspelled.
+`)
+	_, err := LoadRoleFrom(path)
+	if err == nil {
+		t.Fatal("expected error for unknown key")
+	}
+	if !strings.Contains(err.Error(), "instuctions") || !strings.Contains(err.Error(), path) {
+		t.Errorf("error should name the field and file, got: %v", err)
+	}
+}
+
+func TestLoadRoleFrom_UnknownNestedKey(t *testing.T) {
+	path := writeTempFile(t, "nested.yaml", `
+name: nested
+instructions: Hi.
+permissions:
+  alow:
+    - "Read"
+`)
+	_, err := LoadRoleFrom(path)
+	if err == nil || !strings.Contains(err.Error(), "alow") {
+		t.Fatalf("expected error naming alow, got: %v", err)
+	}
+}
+
+func TestLoadRoleFrom_UnknownKeyLax(t *testing.T) {
+	t.Setenv("H2_YAML_LAX", "1")
+	path := writeTempFile(t, "lax.yaml", `
+name: lax
+instructions: Hi.
+future_option: true
+`)
+	if _, err := LoadRoleFrom(path); err != nil {
+		t.Fatalf("H2_YAML_LAX=1 should accept unknown keys: %v", err)
+	}
+}
+
+func TestLoadRoleRenderedFrom_UnknownKey(t *testing.T) {
+	path := writeTempFile(t, "rendered.yaml", `
+variables:
+  team:
+    default: core
+name: rendered
+instructions: Team {{ .Var.team }}.
+permision_mode: plan
+`)
+	_, err := LoadRoleRenderedFrom(path, &tmpl.Context{})
+	if err == nil || !strings.Contains(err.Error(), "permision_mode") {
+		t.Fatalf("expected error naming permision_mode, got: %v", err)
+	}
+}
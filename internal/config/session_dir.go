@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 )
 
@@ -43,15 +44,15 @@ func SetupSessionDir(agentName string, role *Role) (string, error) {
 // SessionMetadata holds metadata about a running session, written to
 // ~/.h2/sessions/<name>/session.metadata.json for use by h2 peek and other tools.
 type SessionMetadata struct {
-	AgentName              string            `json:"agent_name"`
-	SessionID              string            `json:"session_id"`
-	ClaudeConfigDir        string            `json:"claude_config_dir"`
-	CWD                    string            `json:"cwd"`
-	ClaudeCodeSessionLogPath string          `json:"claude_code_session_log_path"`
-	Command                string            `json:"command"`
-	Role                   string            `json:"role,omitempty"`
-	Overrides              map[string]string `json:"overrides,omitempty"`
-	StartedAt              string            `json:"started_at"`
+	AgentName                string            `json:"agent_name"`
+	SessionID                string            `json:"session_id"`
+	ClaudeConfigDir          string            `json:"claude_config_dir"`
+	CWD                      string            `json:"cwd"`
+	ClaudeCodeSessionLogPath string            `json:"claude_code_session_log_path"`
+	Command                  string            `json:"command"`
+	Role                     string            `json:"role,omitempty"`
+	Overrides                map[string]string `json:"overrides,omitempty"`
+	StartedAt                string            `json:"started_at"`
 }
 
 // ClaudeCodeSessionLogPath computes the path to Claude Code's session transcript JSONL.
@@ -93,6 +94,81 @@ func ReadSessionMetadata(sessionDir string) (*SessionMetadata, error) {
 	return &meta, nil
 }
 
+// lifecycleFile is the per-agent supervisor state file inside the session dir.
+const lifecycleFile = "lifecycle.json"
+
+// LifecycleState describes a running agent daemon for external process
+// supervisors. The daemon writes it to ~/.h2/sessions/<name>/lifecycle.json
+// on launch (and again whenever the child is relaunched) and removes it on
+// clean exit.
+type LifecycleState struct {
+	AgentName      string `json:"agent_name"`
+	DaemonPID      int    `json:"daemon_pid"`
+	ChildPID       int    `json:"child_pid,omitempty"`
+	Socket         string `json:"socket,omitempty"`
+	Role           string `json:"role,omitempty"`
+	Pod            string `json:"pod,omitempty"`
+	StartedAt      string `json:"started_at"`
+	ChildStartedAt string `json:"child_started_at,omitempty"`
+}
+
+// Alive reports whether the daemon process recorded in the state still exists.
+func (l *LifecycleState) Alive() bool {
+	if l.DaemonPID <= 0 {
+		return false
+	}
+	err := syscall.Kill(l.DaemonPID, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+// WriteLifecycleState writes lifecycle.json to the session directory,
+// replacing it atomically so readers never see a partial file.
+func WriteLifecycleState(sessionDir string, st LifecycleState) error {
+	if sessionDir == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshal lifecycle state: %w", err)
+	}
+	path := filepath.Join(sessionDir, lifecycleFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write lifecycle state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write lifecycle state: %w", err)
+	}
+	return nil
+}
+
+// ReadLifecycleState reads lifecycle.json from a session directory.
+func ReadLifecycleState(sessionDir string) (*LifecycleState, error) {
+	data, err := os.ReadFile(filepath.Join(sessionDir, lifecycleFile))
+	if err != nil {
+		return nil, err
+	}
+	var st LifecycleState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, fmt.Errorf("parse lifecycle state: %w", err)
+	}
+	return &st, nil
+}
+
+// RemoveLifecycleState deletes lifecycle.json from a session directory.
+// A missing file is not an error.
+func RemoveLifecycleState(sessionDir string) error {
+	if sessionDir == "" {
+		return nil
+	}
+	err := os.Remove(filepath.Join(sessionDir, lifecycleFile))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
 // EnsureClaudeConfigDir creates the shared Claude config directory and writes
 // the h2 standard settings.json (hooks + permissions) if it doesn't exist yet.
 func EnsureClaudeConfigDir(configDir string) error {
@@ -116,6 +192,48 @@ func EnsureClaudeConfigDir(configDir string) error {
 	return nil
 }
 
+// NoHooksConfigDir returns the sibling of configDir used for agents launched
+// with no_hooks: same auth, but a settings.json without h2's hooks.
+func NoHooksConfigDir(configDir string) string {
+	return filepath.Clean(configDir) + "-nohooks"
+}
+
+// noHooksSharedFiles are linked from the base config dir so a no-hooks
+// launch stays logged in to the same account.
+var noHooksSharedFiles = []string{".claude.json", ".credentials.json"}
+
+// EnsureNoHooksConfigDir creates the no-hooks sibling of configDir and
+// returns its path. Its settings.json never contains h2 hooks, and the auth
+// files of configDir are symlinked in when present.
+func EnsureNoHooksConfigDir(configDir string) (string, error) {
+	dir := NoHooksConfigDir(configDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", fmt.Errorf("create no-hooks claude config dir: %w", err)
+	}
+
+	settingsPath := filepath.Join(dir, "settings.json")
+	if _, err := os.Stat(settingsPath); os.IsNotExist(err) {
+		if err := os.WriteFile(settingsPath, []byte("{}\n"), 0o644); err != nil {
+			return "", fmt.Errorf("write settings.json: %w", err)
+		}
+	}
+
+	for _, name := range noHooksSharedFiles {
+		src := filepath.Join(configDir, name)
+		if _, err := os.Stat(src); err != nil {
+			continue
+		}
+		dst := filepath.Join(dir, name)
+		if _, err := os.Lstat(dst); err == nil {
+			continue
+		}
+		if err := os.Symlink(src, dst); err != nil {
+			return "", fmt.Errorf("link %s: %w", name, err)
+		}
+	}
+	return dir, nil
+}
+
 // hookEntry represents a single hook in the settings.json hooks array.
 type hookEntry struct {
 	Type    string `json:"type"`
@@ -176,4 +294,3 @@ func buildH2Hooks() map[string][]hookMatcher {
 
 	return hooks
 }
-
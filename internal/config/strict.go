@@ -0,0 +1,31 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlLax reports whether H2_YAML_LAX=1 disables strict decoding, so configs
+// written for a newer h2 still load on an older one.
+func yamlLax() bool {
+	return os.Getenv("H2_YAML_LAX") == "1"
+}
+
+// unmarshalStrict decodes YAML into v like yaml.Unmarshal, but rejects keys
+// that don't map to a field so typos like "instuctions:" fail loudly instead
+// of parsing to a zero value.
+func unmarshalStrict(data []byte, v any) error {
+	if yamlLax() {
+		return yaml.Unmarshal(data, v)
+	}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
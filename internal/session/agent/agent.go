@@ -63,6 +63,8 @@ type Agent struct {
 	otelCollector    *collector.OtelCollector
 	hooksCollector   *collector.HookCollector
 	primaryCollector collector.StateCollector
+	hooksDisabled    bool
+	idleThreshold    time.Duration // 0 = collector.IdleThreshold
 
 	// Activity logger (nil-safe; Nop logger when not set)
 	activityLog *activitylog.Logger
@@ -79,6 +81,12 @@ type Agent struct {
 	stateChangedAt time.Time
 	stateCh        chan struct{} // closed on state change
 
+	// Ready-pattern override: while ready is true the agent is idle no
+	// matter what the primary collector reports. lastPrimary holds the
+	// collector's most recent update so it can be restored when ready clears.
+	ready       bool
+	lastPrimary *StateUpdate
+
 	// Signals
 	stopCh chan struct{}
 }
@@ -109,6 +117,20 @@ func (a *Agent) ActivityLog() *activitylog.Logger {
 	return activitylog.Nop()
 }
 
+// SetHooksDisabled skips the hook collector even when the agent type
+// supports hooks, for agents launched without h2's hooks installed.
+// Must be called before StartCollectors.
+func (a *Agent) SetHooksDisabled(disabled bool) {
+	a.hooksDisabled = disabled
+}
+
+// SetIdleThreshold sets how long the output and OTEL collectors wait
+// without activity before reporting idle (0 = IdleThreshold).
+// Must be called before StartCollectors.
+func (a *Agent) SetIdleThreshold(d time.Duration) {
+	a.idleThreshold = d
+}
+
 // SetOtelLogFiles opens the raw OTEL log files for appending.
 // Must be called before StartCollectors.
 func (a *Agent) SetOtelLogFiles(dir string) error {
@@ -133,17 +155,17 @@ func (a *Agent) SetOtelLogFiles(dir string) error {
 // launches the internal watchState goroutine.
 func (a *Agent) StartCollectors() error {
 	cfg := a.agentType.Collectors()
-	a.outputCollector = collector.NewOutputCollector()
+	a.outputCollector = collector.NewOutputCollectorWithThreshold(a.idleThreshold)
 	var primary collector.StateCollector = a.outputCollector
 
 	if cfg.Otel {
 		if err := a.StartOtelCollector(); err != nil {
 			return err
 		}
-		a.otelCollector = collector.NewOtelCollector()
+		a.otelCollector = collector.NewOtelCollectorWithThreshold(a.idleThreshold)
 		primary = a.otelCollector
 	}
-	if cfg.Hooks {
+	if cfg.Hooks && !a.hooksDisabled {
 		a.hooksCollector = collector.NewHookCollector(a.activityLog)
 		primary = a.hooksCollector
 	}
@@ -195,6 +217,13 @@ func (a *Agent) StateDuration() time.Duration {
 	return time.Since(a.stateChangedAt)
 }
 
+// StateChangedAt returns when the agent entered its current state.
+func (a *Agent) StateChangedAt() time.Time {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.stateChangedAt
+}
+
 // setState updates the state and notifies waiters. Caller must NOT hold mu.
 func (a *Agent) setState(newState State, newSubState SubState) {
 	a.mu.Lock()
@@ -235,6 +264,36 @@ func (a *Agent) NoteInterrupt() {
 	}
 }
 
+// NoteReady reports whether the child's screen currently matches the
+// role's ready pattern. A match marks the agent idle immediately, ahead of
+// the output timeout; when the match clears, the primary collector's
+// latest state applies again. No-op while the hook collector is active:
+// hooks report the agent's state directly and take precedence over the
+// screen.
+func (a *Agent) NoteReady(ready bool) {
+	if a.hooksCollector != nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.ready == ready {
+		return
+	}
+	a.ready = ready
+	if a.state == StateExited {
+		return
+	}
+	switch {
+	case ready:
+		a.setStateLocked(StateIdle, SubStateNone)
+	case a.lastPrimary != nil:
+		a.setStateLocked(a.lastPrimary.State, a.lastPrimary.SubState)
+	default:
+		// No collector update yet, but output is flowing.
+		a.setStateLocked(StateActive, SubStateNone)
+	}
+}
+
 // SetExited transitions the agent to the Exited state.
 // Called by Session when the child process exits.
 func (a *Agent) SetExited() {
@@ -249,7 +308,8 @@ func (a *Agent) watchState() {
 		select {
 		case su := <-a.primaryCollector.StateCh():
 			a.mu.Lock()
-			if a.state != StateExited {
+			a.lastPrimary = &su
+			if a.state != StateExited && !a.ready {
 				a.setStateLocked(su.State, su.SubState)
 			}
 			a.mu.Unlock()
@@ -274,6 +334,15 @@ func (a *Agent) PrependArgs(sessionID string) []string {
 	return a.agentType.PrependArgs(sessionID)
 }
 
+// SupportsRoleFlags reports whether role settings can be passed to the
+// child as CLI flags.
+func (a *Agent) SupportsRoleFlags() bool {
+	if a.agentType == nil {
+		return false
+	}
+	return a.agentType.SupportsRoleFlags()
+}
+
 // ChildEnv returns environment variables to inject into the child process.
 func (a *Agent) ChildEnv() map[string]string {
 	if a.agentType == nil {
@@ -32,6 +32,10 @@ type AgentType interface {
 
 	// DisplayCommand returns the command name for display purposes.
 	DisplayCommand() string
+
+	// SupportsRoleFlags reports whether the agent accepts role settings
+	// (system prompt, instructions, model, permissions) as CLI flags.
+	SupportsRoleFlags() bool
 }
 
 // CollectorPorts holds connection info for active collectors,
@@ -61,6 +65,7 @@ func (t *ClaudeCodeType) Command() string         { return "claude" }
 func (t *ClaudeCodeType) DisplayCommand() string   { return "claude" }
 func (t *ClaudeCodeType) Collectors() CollectorSet { return CollectorSet{Otel: true, Hooks: true} }
 func (t *ClaudeCodeType) OtelParser() OtelParser   { return t.parser }
+func (t *ClaudeCodeType) SupportsRoleFlags() bool  { return true }
 
 func (t *ClaudeCodeType) PrependArgs(sessionID string) []string {
 	if sessionID != "" {
@@ -101,6 +106,7 @@ func (t *GenericType) Command() string                                   { retur
 func (t *GenericType) DisplayCommand() string                             { return t.command }
 func (t *GenericType) Collectors() CollectorSet                           { return CollectorSet{} }
 func (t *GenericType) OtelParser() OtelParser                             { return nil }
+func (t *GenericType) SupportsRoleFlags() bool                            { return false }
 func (t *GenericType) PrependArgs(sessionID string) []string              { return nil }
 func (t *GenericType) ChildEnv(cp *CollectorPorts) map[string]string      { return nil }
 
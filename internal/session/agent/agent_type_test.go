@@ -112,3 +112,12 @@ func TestGenericType_DisplayCommand(t *testing.T) {
 		t.Fatalf("expected display command '/usr/bin/python3', got %q", gt.DisplayCommand())
 	}
 }
+
+func TestSupportsRoleFlags(t *testing.T) {
+	if !NewClaudeCodeType().SupportsRoleFlags() {
+		t.Fatal("claude should accept role flags")
+	}
+	if NewGenericType("aider").SupportsRoleFlags() {
+		t.Fatal("generic commands should not receive claude role flags")
+	}
+}
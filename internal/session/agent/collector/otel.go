@@ -3,20 +3,31 @@ package collector
 import "time"
 
 // OtelCollector derives state from OTEL log events.
-// It goes active on each NoteEvent signal and idle after IdleThreshold
-// with no further events.
+// It goes active on each NoteEvent signal and idle after its idle
+// threshold (IdleThreshold by default) with no further events.
 type OtelCollector struct {
-	notifyCh chan struct{}
-	stateCh  chan StateUpdate
-	stopCh   chan struct{}
+	notifyCh  chan struct{}
+	stateCh   chan StateUpdate
+	stopCh    chan struct{}
+	threshold time.Duration
 }
 
-// NewOtelCollector creates and starts an OtelCollector.
+// NewOtelCollector creates and starts an OtelCollector that uses IdleThreshold.
 func NewOtelCollector() *OtelCollector {
+	return NewOtelCollectorWithThreshold(0)
+}
+
+// NewOtelCollectorWithThreshold is like NewOtelCollector but goes idle after
+// threshold instead; 0 selects IdleThreshold.
+func NewOtelCollectorWithThreshold(threshold time.Duration) *OtelCollector {
+	if threshold <= 0 {
+		threshold = IdleThreshold
+	}
 	c := &OtelCollector{
-		notifyCh: make(chan struct{}, 1),
-		stateCh:  make(chan StateUpdate, 1),
-		stopCh:   make(chan struct{}),
+		threshold: threshold,
+		notifyCh:  make(chan struct{}, 1),
+		stateCh:   make(chan StateUpdate, 1),
+		stopCh:    make(chan struct{}),
 	}
 	go c.run()
 	return c
@@ -45,14 +56,14 @@ func (c *OtelCollector) Stop() {
 }
 
 func (c *OtelCollector) run() {
-	idleTimer := time.NewTimer(IdleThreshold)
+	idleTimer := time.NewTimer(c.threshold)
 	defer idleTimer.Stop()
 
 	for {
 		select {
 		case <-c.notifyCh:
 			c.send(StateActive)
-			resetTimer(idleTimer, IdleThreshold)
+			resetTimer(idleTimer, c.threshold)
 		case <-idleTimer.C:
 			c.send(StateIdle)
 		case <-c.stopCh:
@@ -3,20 +3,31 @@ package collector
 import "time"
 
 // OutputCollector derives state from child PTY output.
-// It goes active on each NoteOutput signal and idle after IdleThreshold
-// with no further output.
+// It goes active on each NoteOutput signal and idle after its idle
+// threshold (IdleThreshold by default) with no further output.
 type OutputCollector struct {
-	notifyCh chan struct{}
-	stateCh  chan StateUpdate
-	stopCh   chan struct{}
+	notifyCh  chan struct{}
+	stateCh   chan StateUpdate
+	stopCh    chan struct{}
+	threshold time.Duration
 }
 
-// NewOutputCollector creates and starts an OutputCollector.
+// NewOutputCollector creates and starts an OutputCollector that uses IdleThreshold.
 func NewOutputCollector() *OutputCollector {
+	return NewOutputCollectorWithThreshold(0)
+}
+
+// NewOutputCollectorWithThreshold is like NewOutputCollector but goes idle after
+// threshold instead; 0 selects IdleThreshold.
+func NewOutputCollectorWithThreshold(threshold time.Duration) *OutputCollector {
+	if threshold <= 0 {
+		threshold = IdleThreshold
+	}
 	c := &OutputCollector{
-		notifyCh: make(chan struct{}, 1),
-		stateCh:  make(chan StateUpdate, 1),
-		stopCh:   make(chan struct{}),
+		threshold: threshold,
+		notifyCh:  make(chan struct{}, 1),
+		stateCh:   make(chan StateUpdate, 1),
+		stopCh:    make(chan struct{}),
 	}
 	go c.run()
 	return c
@@ -45,14 +56,14 @@ func (c *OutputCollector) Stop() {
 }
 
 func (c *OutputCollector) run() {
-	idleTimer := time.NewTimer(IdleThreshold)
+	idleTimer := time.NewTimer(c.threshold)
 	defer idleTimer.Stop()
 
 	for {
 		select {
 		case <-c.notifyCh:
 			c.send(StateActive)
-			resetTimer(idleTimer, IdleThreshold)
+			resetTimer(idleTimer, c.threshold)
 		case <-idleTimer.C:
 			c.send(StateIdle)
 		case <-c.stopCh:
@@ -48,6 +48,28 @@ func TestOutputCollector_IdleAfterThreshold(t *testing.T) {
 	}
 }
 
+func TestOutputCollector_CustomThreshold(t *testing.T) {
+	setFastIdle(t)
+	c := NewOutputCollectorWithThreshold(300 * time.Millisecond)
+	defer c.Stop()
+
+	start := time.Now()
+	c.NoteOutput()
+	<-c.StateCh()
+
+	select {
+	case su := <-c.StateCh():
+		if su.State != StateIdle {
+			t.Fatalf("expected StateIdle, got %v", su.State)
+		}
+		if elapsed := time.Since(start); elapsed < 250*time.Millisecond {
+			t.Fatalf("went idle after %v, want the 300ms threshold rather than IdleThreshold", elapsed)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for StateIdle")
+	}
+}
+
 func TestOutputCollector_ResetTimerOnOutput(t *testing.T) {
 	setFastIdle(t)
 	c := NewOutputCollector()
@@ -3,8 +3,10 @@ package session
 import (
 	"encoding/json"
 	"io"
+	"log"
 	"net"
 
+	"h2/internal/session/agent"
 	"h2/internal/session/client"
 	"h2/internal/session/message"
 )
@@ -42,14 +44,26 @@ func (d *Daemon) handleAttach(conn net.Conn, req *message.Request) {
 
 	// Set up per-client output for this connection.
 	vt.Mu.Lock()
-	cl.Output = &frameWriter{conn: conn}
+	cl.Output = &frameWriter{conn: conn, compress: req.Compress}
+	cl.TrimLines = req.TrimLines
+	cl.ReadOnly = req.ReadOnly
+	if req.ScrollStep > 0 {
+		cl.ScrollStep = req.ScrollStep
+	}
+	if err := cl.LoadTheme(req.Theme, !req.LightBackground); err != nil {
+		log.Printf("warning: %v; using the default theme", err)
+	}
 
 	// Resize PTY to client's terminal size, but only if dimensions actually
 	// changed. Unnecessary resizes send SIGWINCH to the child, which can
 	// cause a screen clear + redraw race that produces a blank screen.
+	// Read-only observers never size the PTY; they render at whatever size
+	// the interactive clients chose.
 	if req.Cols > 0 && req.Rows > 0 {
 		cl.TermRows = req.Rows
 		cl.TermCols = req.Cols
+	}
+	if req.Cols > 0 && req.Rows > 0 && !cl.ReadOnly {
 		childRows := req.Rows - cl.ReservedRows()
 		if req.Rows != vt.Rows || req.Cols != vt.Cols || childRows != vt.ChildRows {
 			vt.Resize(req.Rows, req.Cols, childRows)
@@ -57,32 +71,47 @@ func (d *Daemon) handleAttach(conn net.Conn, req *message.Request) {
 			// a ghost status bar from the old (larger) dimensions.
 			d.Session.ForEachClient(func(existing *client.Client) {
 				if existing != cl {
-					existing.Output.Write([]byte("\033[2J"))
-					existing.RenderScreen()
-					existing.RenderBar()
+					existing.Repaint()
 				}
 			})
 		}
 	}
 
-	// Set detach callback to close the client connection.
-	cl.OnDetach = func() { conn.Close() }
+	// Set detach callback to close the client connection. Both tell the
+	// remote client the disconnect is deliberate so it won't reconnect.
+	cl.OnEnd = func(reason string) {
+		ctrl, _ := json.Marshal(message.DetachControl{Type: "detach", Reason: reason})
+		message.WriteFrame(conn, message.FrameTypeControl, ctrl)
+	}
+	cl.OnDetach = func() {
+		cl.OnEnd("detach")
+		conn.Close()
+	}
 
-	// Enable mouse reporting and render the current screen.
+	// Enable mouse reporting and bracketed paste, and render the current screen.
 	// RenderScreen clears each line individually (\033[2K), so a full
 	// screen clear (\033[2J) is unnecessary and would cause a visible flash.
-	cl.Output.Write([]byte("\033[?1000h\033[?1006h"))
+	cl.Output.Write([]byte(client.TerminalModesOn))
 	cl.RenderScreen()
 	cl.RenderBar()
 	vt.Mu.Unlock()
 
+	// Optionally detach once the agent finishes its current work.
+	stopIdleWatch := make(chan struct{})
+	if req.DetachOnIdle {
+		go s.detachOnIdle(cl, stopIdleWatch)
+	}
+
 	// Read input frames from client until disconnect.
 	d.readClientInput(conn, cl)
+	close(stopIdleWatch)
 
-	// Client disconnected — detach. Disable mouse on this client's output.
+	// Client disconnected — detach. Disable mouse and paste mode on this
+	// client's output.
 	vt.Mu.Lock()
 	cl.OnDetach = nil
-	cl.Output.Write([]byte("\033[?1000l\033[?1006l"))
+	cl.OnEnd = nil
+	cl.Output.Write([]byte(client.TerminalModesOff))
 
 	// Release passthrough ownership if this client held it.
 	if s.PassthroughOwner == cl {
@@ -90,32 +119,20 @@ func (d *Daemon) handleAttach(conn net.Conn, req *message.Request) {
 		s.Queue.Unpause()
 	}
 
-	// Remove this client from the session.
+	// Remove this client from the session, handing control to anyone
+	// waiting for it.
 	s.RemoveClient(cl)
+	s.releaseControl(cl)
 
 	// Resize VT to fit remaining clients and re-render. Use the minimum
 	// dimensions so all clients can display the full content (standard
 	// terminal multiplexer behavior). When the smaller window detaches,
 	// the remaining clients reclaim their full terminal area.
-	var minRows, minCols, reservedRows int
-	s.ForEachClient(func(c *client.Client) {
-		if c.TermRows <= 0 || c.TermCols <= 0 {
-			return // skip clients without known dimensions (e.g. daemon placeholder)
-		}
-		if minRows == 0 || c.TermRows < minRows {
-			minRows = c.TermRows
-		}
-		if minCols == 0 || c.TermCols < minCols {
-			minCols = c.TermCols
-		}
-		reservedRows = c.ReservedRows()
-	})
+	minRows, minCols, reservedRows := s.minClientSize()
 	if minRows > 0 && minCols > 0 && (minRows != vt.Rows || minCols != vt.Cols) {
 		vt.Resize(minRows, minCols, minRows-reservedRows)
 		s.ForEachClient(func(c *client.Client) {
-			c.Output.Write([]byte("\033[2J"))
-			c.RenderScreen()
-			c.RenderBar()
+			c.Repaint()
 		})
 	}
 	vt.Mu.Unlock()
@@ -123,6 +140,60 @@ func (d *Daemon) handleAttach(conn net.Conn, req *message.Request) {
 	_ = attach // keep reference alive for the duration
 }
 
+// minClientSize returns the smallest terminal size among the attached
+// interactive clients, and the rows reserved below the child. Clients
+// without known dimensions (e.g. the daemon placeholder) and read-only
+// observers are skipped, so zeros mean no client constrains the size.
+// Must be called with VT.Mu held.
+func (s *Session) minClientSize() (rows, cols, reserved int) {
+	s.ForEachClient(func(c *client.Client) {
+		if c.TermRows <= 0 || c.TermCols <= 0 || c.ReadOnly {
+			return
+		}
+		if rows == 0 || c.TermRows < rows {
+			rows = c.TermRows
+		}
+		if cols == 0 || c.TermCols < cols {
+			cols = c.TermCols
+		}
+		reserved = c.ReservedRows()
+	})
+	return rows, cols, reserved
+}
+
+// detachOnIdle watches agent state and detaches cl on the first active →
+// idle transition, using the same path as the menu detach action. If the
+// agent never goes active, the client stays attached. Returns when stop is
+// closed, the agent exits, or the client has been detached.
+func (s *Session) detachOnIdle(cl *client.Client, stop <-chan struct{}) {
+	sawActive := false
+	for {
+		// Grab the change channel before reading state so a transition
+		// between the two can't be missed.
+		changed := s.StateChanged()
+		switch st, _ := s.State(); st {
+		case agent.StateActive:
+			sawActive = true
+		case agent.StateIdle:
+			if sawActive {
+				s.VT.Mu.Lock()
+				if cl.OnDetach != nil {
+					cl.OnDetach()
+				}
+				s.VT.Mu.Unlock()
+				return
+			}
+		case agent.StateExited:
+			return
+		}
+		select {
+		case <-changed:
+		case <-stop:
+			return
+		}
+	}
+}
+
 // readClientInput reads framed input from the attach client and dispatches
 // it to the given client.
 func (d *Daemon) readClientInput(conn net.Conn, cl *client.Client) {
@@ -165,20 +236,20 @@ func (d *Daemon) readClientInput(conn net.Conn, cl *client.Client) {
 				vt.Mu.Lock()
 				cl.TermRows = ctrl.Rows
 				cl.TermCols = ctrl.Cols
+				if cl.ReadOnly {
+					// An observer's window doesn't size the PTY;
+					// just redraw it at the current size.
+					cl.Repaint()
+					vt.Mu.Unlock()
+					continue
+				}
 				childRows := ctrl.Rows - cl.ReservedRows()
 				vt.Resize(ctrl.Rows, ctrl.Cols, childRows)
-				if cl.IsScrollMode() {
-					cl.ClampScrollOffset()
-				}
-				cl.Output.Write([]byte("\033[2J"))
-				cl.RenderScreen()
-				cl.RenderBar()
+				cl.Repaint()
 				// Clear and re-render other clients at the new dimensions.
 				d.Session.ForEachClient(func(existing *client.Client) {
 					if existing != cl {
-						existing.Output.Write([]byte("\033[2J"))
-						existing.RenderScreen()
-						existing.RenderBar()
+						existing.Repaint()
 					}
 				})
 				vt.Mu.Unlock()
@@ -187,13 +258,16 @@ func (d *Daemon) readClientInput(conn net.Conn, cl *client.Client) {
 	}
 }
 
-// frameWriter wraps a net.Conn for writing attach data frames.
+// frameWriter wraps a net.Conn for writing attach data frames. When
+// compress is set (negotiated in the attach request), large frames are
+// sent DEFLATE-compressed.
 type frameWriter struct {
-	conn net.Conn
+	conn     net.Conn
+	compress bool
 }
 
 func (fw *frameWriter) Write(p []byte) (int, error) {
-	if err := message.WriteFrame(fw.conn, message.FrameTypeData, p); err != nil {
+	if err := message.WriteDataFrame(fw.conn, p, fw.compress); err != nil {
 		return 0, err
 	}
 	return len(p), nil
@@ -0,0 +1,366 @@
+package session
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"h2/internal/session/agent"
+	"h2/internal/session/client"
+	"h2/internal/session/message"
+)
+
+func TestDetachOnIdle_DetachesAfterActiveToIdle(t *testing.T) {
+	setFastIdle(t)
+	s := newTestSession()
+	defer s.Stop()
+
+	detached := make(chan struct{})
+	cl := &client.Client{OnDetach: func() { close(detached) }}
+	stop := make(chan struct{})
+	defer close(stop)
+	go s.detachOnIdle(cl, stop)
+
+	startWatchState(t, s)
+	s.NoteOutput()
+	waitForState(t, s, agent.StateActive, 2*time.Second)
+
+	select {
+	case <-detached:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected client to detach on active → idle transition")
+	}
+}
+
+func TestDetachOnIdle_StaysAttachedWithoutActivePeriod(t *testing.T) {
+	setFastIdle(t)
+	s := newTestSession()
+	defer s.Stop()
+
+	startWatchState(t, s)
+	waitForState(t, s, agent.StateIdle, 2*time.Second)
+
+	var detached atomic.Bool
+	cl := &client.Client{OnDetach: func() { detached.Store(true) }}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		s.detachOnIdle(cl, stop)
+		close(done)
+	}()
+
+	// Stay idle well past the idle threshold.
+	time.Sleep(100 * time.Millisecond)
+	close(stop)
+	<-done
+
+	if detached.Load() {
+		t.Fatal("client should stay attached when the agent never went active")
+	}
+}
+
+// waitForFrame reads attach data frames until one contains want.
+func waitForFrame(t *testing.T, frames <-chan string, want string) {
+	t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case f, ok := <-frames:
+			if !ok {
+				t.Fatalf("attach connection closed before %q was rendered", want)
+			}
+			if strings.Contains(f, want) {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for %q to be rendered", want)
+		}
+	}
+}
+
+func TestInitDaemonVT_InitialSizeOverride(t *testing.T) {
+	s := New("wide", "true", nil)
+	s.InitialRows = 50
+	s.InitialCols = 200
+	s.initDaemonVT()
+
+	if s.VT.Rows != 50 || s.VT.Cols != 200 {
+		t.Fatalf("VT size = %dx%d, want 200x50", s.VT.Cols, s.VT.Rows)
+	}
+	if s.VT.ChildRows != 48 {
+		t.Fatalf("ChildRows = %d, want 48", s.VT.ChildRows)
+	}
+	if h, w := s.VT.Vt.Height, s.VT.Vt.Width; h != 48 || w != 200 {
+		t.Fatalf("Vt size = %dx%d, want 200x48", w, h)
+	}
+
+	// A later attach still resizes to the client's terminal.
+	d := &Daemon{Session: s}
+	server, conn := net.Pipe()
+	defer conn.Close()
+	go d.handleAttach(server, &message.Request{Type: "attach", Rows: 30, Cols: 100})
+	resp, err := message.ReadResponse(conn)
+	if err != nil || !resp.OK {
+		t.Fatalf("attach failed: %v %+v", err, resp)
+	}
+	go io.Copy(io.Discard, conn)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		s.VT.Mu.Lock()
+		rows, cols, childRows := s.VT.Rows, s.VT.Cols, s.VT.ChildRows
+		s.VT.Mu.Unlock()
+		if rows == 30 && cols == 100 && childRows == 28 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("after attach VT = %dx%d (child %d), want 100x30 (child 28)", cols, rows, childRows)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestInitDaemonVT_DefaultSize(t *testing.T) {
+	s := New("plain", "true", nil)
+	s.initDaemonVT()
+	if s.VT.Rows != DefaultDaemonRows || s.VT.Cols != DefaultDaemonCols || s.VT.ChildRows != DefaultDaemonRows-2 {
+		t.Fatalf("VT = %dx%d (child %d), want defaults", s.VT.Cols, s.VT.Rows, s.VT.ChildRows)
+	}
+}
+
+func TestValidateDaemonSize(t *testing.T) {
+	for _, tc := range []struct {
+		rows, cols int
+		ok         bool
+	}{
+		{0, 0, true},
+		{3, 0, true},
+		{60, 240, true},
+		{2, 80, false},
+		{-1, 0, false},
+		{0, -5, false},
+	} {
+		err := ValidateDaemonSize(tc.rows, tc.cols)
+		if (err == nil) != tc.ok {
+			t.Errorf("ValidateDaemonSize(%d, %d) = %v, want ok=%v", tc.rows, tc.cols, err, tc.ok)
+		}
+	}
+}
+
+func TestAttach_DeliberateEndsSendDetachFrame(t *testing.T) {
+	for _, reason := range []string{"detach", "quit"} {
+		s := New("worker", "true", nil)
+		s.initDaemonVT()
+		d := &Daemon{Session: s}
+
+		server, conn := net.Pipe()
+		go d.handleAttach(server, &message.Request{Type: "attach", Rows: 12, Cols: 80})
+		if resp, err := message.ReadResponse(conn); err != nil || !resp.OK {
+			t.Fatalf("attach failed: %v %+v", err, resp)
+		}
+
+		got := make(chan message.DetachControl, 1)
+		go func() {
+			for {
+				ft, payload, err := message.ReadFrame(conn)
+				if err != nil {
+					close(got)
+					return
+				}
+				var ctrl message.DetachControl
+				if ft == message.FrameTypeControl && json.Unmarshal(payload, &ctrl) == nil {
+					got <- ctrl
+					return
+				}
+			}
+		}()
+
+		// Wait until handleAttach has wired the connection's callbacks.
+		var cl *client.Client
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			s.VT.Mu.Lock()
+			s.ForEachClient(func(c *client.Client) { cl = c })
+			if cl != nil && cl.OnDetach != nil {
+				break
+			}
+			s.VT.Mu.Unlock()
+			if time.Now().After(deadline) {
+				t.Fatal("attached client not wired")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		if reason == "detach" {
+			cl.OnDetach()
+		} else {
+			s.endAttaches("quit")
+		}
+		s.VT.Mu.Unlock()
+
+		select {
+		case ctrl, ok := <-got:
+			if !ok || ctrl.Type != "detach" || ctrl.Reason != reason {
+				t.Errorf("%s: control frame = %+v (ok=%v)", reason, ctrl, ok)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("%s: no detach control frame", reason)
+		}
+		conn.Close()
+	}
+}
+
+func TestAttach_BeforeChildStarted(t *testing.T) {
+	s := New("slow", "true", nil)
+	s.initDaemonVT()
+	d := &Daemon{Session: s}
+
+	server, conn := net.Pipe()
+	defer conn.Close()
+	go d.handleAttach(server, &message.Request{Type: "attach", Rows: 12, Cols: 80})
+
+	resp, err := message.ReadResponse(conn)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	if !resp.OK {
+		t.Fatalf("attach before child start failed: %s", resp.Error)
+	}
+
+	frames := make(chan string, 64)
+	go func() {
+		defer close(frames)
+		for {
+			_, payload, err := message.ReadFrame(conn)
+			if err != nil {
+				return
+			}
+			frames <- string(payload)
+		}
+	}()
+
+	waitForFrame(t, frames, "starting slow")
+
+	// The child comes up: PipeOutput starts delivering bytes.
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	s.VT.Mu.Lock()
+	s.VT.Ptm = r
+	s.VT.Mu.Unlock()
+	go s.VT.PipeOutput(s.pipeOutputCallback())
+
+	w.Write([]byte("hello from child"))
+	waitForFrame(t, frames, "hello from child")
+
+	s.VT.Mu.Lock()
+	starting := s.VT.Starting
+	s.VT.Mu.Unlock()
+	if starting {
+		t.Fatal("expected VT to leave the starting state after child output")
+	}
+}
+
+func TestAttach_ReadOnlyObserverDoesNotSizePTY(t *testing.T) {
+	s := New("watched", "true", nil)
+	s.InitialRows = 40
+	s.InitialCols = 120
+	s.initDaemonVT()
+	d := &Daemon{Session: s}
+
+	server, conn := net.Pipe()
+	defer conn.Close()
+	go d.handleAttach(server, &message.Request{Type: "attach", Rows: 10, Cols: 50, ReadOnly: true})
+	if resp, err := message.ReadResponse(conn); err != nil || !resp.OK {
+		t.Fatalf("attach failed: %v %+v", err, resp)
+	}
+	go io.Copy(io.Discard, conn)
+
+	ctrl, _ := json.Marshal(message.ResizeControl{Type: "resize", Rows: 8, Cols: 30})
+	if err := message.WriteFrame(conn, message.FrameTypeControl, ctrl); err != nil {
+		t.Fatalf("write resize: %v", err)
+	}
+
+	// Wait until the observer's resize has been recorded.
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var observer *client.Client
+		s.VT.Mu.Lock()
+		s.ForEachClient(func(c *client.Client) {
+			if c.ReadOnly {
+				observer = c
+			}
+		})
+		done := observer != nil && observer.TermRows == 8 && observer.TermCols == 30
+		rows, cols := s.VT.Rows, s.VT.Cols
+		minRows, minCols, _ := s.minClientSize()
+		s.VT.Mu.Unlock()
+		if done {
+			if rows != 40 || cols != 120 {
+				t.Fatalf("VT = %dx%d after observer attach/resize, want 120x40", cols, rows)
+			}
+			if minRows != 0 || minCols != 0 {
+				t.Fatalf("minClientSize = %dx%d, want observers skipped", minCols, minRows)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("observer resize not processed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestAttach_UsesAttachingTerminalSettings(t *testing.T) {
+	// The daemon's own environment must not leak into attached clients.
+	t.Setenv("H2_THEME", "default")
+	t.Setenv("COLORFGBG", "15;0")
+	t.Setenv("H2_SCROLL_STEP", "9")
+	t.Setenv("HOME", t.TempDir())
+	s := New("themed", "true", nil)
+	s.initDaemonVT()
+	d := &Daemon{Session: s}
+
+	server, conn := net.Pipe()
+	defer conn.Close()
+	go d.handleAttach(server, &message.Request{
+		Type: "attach", Rows: 12, Cols: 80,
+		ScrollStep: 5, Theme: "auto", LightBackground: true,
+	})
+	if resp, err := message.ReadResponse(conn); err != nil || !resp.OK {
+		t.Fatalf("attach failed: %v %+v", err, resp)
+	}
+	go io.Copy(io.Discard, conn)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var cl *client.Client
+		s.VT.Mu.Lock()
+		s.ForEachClient(func(c *client.Client) { cl = c })
+		var theme client.Theme
+		step := 0
+		if cl != nil && cl.Theme != nil {
+			theme, step = *cl.Theme, cl.ScrollStep
+		}
+		s.VT.Mu.Unlock()
+		if step != 0 {
+			if theme != client.LightTheme {
+				t.Errorf("theme = %+v, want the light theme for a light attaching terminal", theme)
+			}
+			if step != 5 {
+				t.Errorf("ScrollStep = %d, want the attaching terminal's 5", step)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("attach settings not applied")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,49 @@
+package client
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAgentStatusOSC_ShownInBar(t *testing.T) {
+	o := newTestClient(10, 120)
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	o.VT.Ptm = r
+
+	fed := make(chan struct{}, 1)
+	go o.VT.PipeOutput(func() {
+		select {
+		case fed <- struct{}{}:
+		default:
+		}
+	})
+	w.Write([]byte("working...\033]1337;h2-status=waiting for review\007\r\n"))
+	select {
+	case <-fed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for output to be piped")
+	}
+
+	if line := string(o.VT.Vt.Content[0]); strings.Contains(line, "h2-status") {
+		t.Fatalf("sentinel should not reach the screen, got %q", line)
+	}
+
+	var out bytes.Buffer
+	o.Output = &out
+	o.VT.Mu.Lock()
+	o.RenderBar()
+	o.VT.Mu.Unlock()
+	if !strings.Contains(out.String(), "waiting for review") {
+		t.Fatalf("expected agent status in bar, got %q", out.String())
+	}
+	if strings.Contains(out.String(), "h2-status") {
+		t.Fatalf("raw sentinel leaked into bar: %q", out.String())
+	}
+}
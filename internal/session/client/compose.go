@@ -0,0 +1,112 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ComposeRows is the height of the input area in compose mode.
+const ComposeRows = 5
+
+// StartCompose enters multi-line compose mode: the input bar grows to
+// ComposeRows rows (covering the top of this client's view of the child),
+// Enter inserts a line break, and Ctrl+D or Shift+Enter sends the whole
+// buffer.
+func (c *Client) StartCompose() {
+	c.Composing = true
+	c.relayout()
+}
+
+// EndCompose leaves compose mode and restores the single-row input bar.
+// The buffer is kept.
+func (c *Client) EndCompose() {
+	c.Composing = false
+	c.relayout()
+}
+
+// submitCompose sends the compose buffer and leaves compose mode. An empty
+// buffer just leaves compose mode. It returns false if the write failed.
+func (c *Client) submitCompose() bool {
+	ok := true
+	if len(c.Input) > 0 {
+		ok = c.submitInput()
+	}
+	c.EndCompose()
+	return ok
+}
+
+// composeExtraRows returns how many rows compose mode adds to the overlay,
+// leaving at least one row of the child in view.
+func (c *Client) composeExtraRows() int {
+	if !c.Composing {
+		return 0
+	}
+	return max(min(ComposeRows-1, c.VT.Rows-c.ReservedRows()-1), 0)
+}
+
+// overlayRows returns the height of this client's overlay: ReservedRows
+// plus any compose rows.
+func (c *Client) overlayRows() int {
+	return c.ReservedRows() + c.composeExtraRows()
+}
+
+// viewRows returns how many rows of the child this client shows. The
+// child's size is shared by every client, so a taller overlay (compose
+// mode) shows fewer rows instead of resizing it.
+func (c *Client) viewRows() int {
+	return max(min(c.VT.ChildRows, c.VT.Rows-c.overlayRows()), 1)
+}
+
+// relayout redraws this client after its overlay height changes. Only
+// this client's layout changes; the child and other clients are untouched.
+func (c *Client) relayout() {
+	c.Repaint()
+}
+
+// renderComposeLines draws the compose buffer on nRows rows starting at
+// firstRow, scrolled to keep the cursor line in view.
+func (c *Client) renderComposeLines(buf *bytes.Buffer, firstRow, nRows int) {
+	prompt := c.InputPriority.String() + " > "
+	cont := strings.Repeat(" ", len(prompt))
+	maxWidth := max(c.VT.Cols-len(prompt), 1)
+
+	lines := strings.Split(string(c.Input), "\n")
+	before := c.Input[:c.CursorPos]
+	curLine := bytes.Count(before, []byte{'\n'})
+	curCol := utf8.RuneCount(before[bytes.LastIndexByte(before, '\n')+1:])
+
+	top := 0
+	if curLine >= nRows {
+		top = curLine - nRows + 1
+	}
+	promptColor := c.promptStyle()
+
+	cursorShift := 0
+	for r := 0; r < nRows; r++ {
+		fmt.Fprintf(buf, "\033[%d;1H\033[2K", firstRow+r)
+		idx := top + r
+		if idx == 0 {
+			fmt.Fprintf(buf, "%s%s\033[0m", promptColor, prompt)
+		} else {
+			buf.WriteString(cont)
+		}
+		if idx >= len(lines) {
+			continue
+		}
+		runes := []rune(lines[idx])
+		start := 0
+		if idx == curLine && curCol >= maxWidth {
+			start = curCol - maxWidth + 1
+			cursorShift = start
+		}
+		end := min(start+maxWidth, len(runes))
+		if start < end {
+			buf.WriteString(controlPictures(runes[start:end]))
+		}
+	}
+
+	cursorCol := min(len(prompt)+curCol-cursorShift+1, c.VT.Cols)
+	fmt.Fprintf(buf, "\033[%d;%dH", firstRow+curLine-top, cursorCol)
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"h2/internal/session/message"
+)
+
+func newComposeClient(t *testing.T) *Client {
+	t.Helper()
+	o := newTestClient(10, 80)
+	o.TermRows, o.TermCols = 12, 80
+	o.InputPriority = message.PriorityNormal
+	return o
+}
+
+func TestCompose_ReservesRowsAndRestores(t *testing.T) {
+	o := newComposeClient(t)
+	o.StartCompose()
+	if got := o.overlayRows(); got != BaseReservedRows+ComposeRows-1 {
+		t.Fatalf("overlayRows = %d, want %d", got, BaseReservedRows+ComposeRows-1)
+	}
+	if got := o.viewRows(); got != 12-o.overlayRows() {
+		t.Fatalf("viewRows = %d, want %d", got, 12-o.overlayRows())
+	}
+	if o.VT.ChildRows != 10 || o.VT.Rows != 12 {
+		t.Fatalf("VT = %d rows (child %d), want compose to leave the shared size alone", o.VT.Rows, o.VT.ChildRows)
+	}
+	o.EndCompose()
+	if got := o.viewRows(); got != 10 {
+		t.Errorf("viewRows = %d after compose, want 10", got)
+	}
+}
+
+func TestCompose_LiveViewKeepsCursorRow(t *testing.T) {
+	o := newComposeClient(t)
+	for i := 1; i <= 10; i++ {
+		o.VT.Vt.Write([]byte(fmt.Sprintf("line%d", i)))
+		if i < 10 {
+			o.VT.Vt.Write([]byte("\r\n"))
+		}
+	}
+	o.StartCompose()
+
+	var buf bytes.Buffer
+	o.renderLiveView(&buf)
+	out := buf.String()
+	if !strings.Contains(out, "line10") || strings.Contains(out, "line4") {
+		t.Errorf("live view = %q, want the rows nearest the cursor (line5-line10)", out)
+	}
+	if strings.Contains(out, fmt.Sprintf("\033[%d;1H", o.viewRows()+1)) {
+		t.Errorf("live view drew past row %d, into the compose area", o.viewRows())
+	}
+}
+
+func TestCompose_EnterInsertsNewlineCtrlDSends(t *testing.T) {
+	o := newComposeClient(t)
+	r := pipePTY(t, o)
+	o.StartCompose()
+
+	typeKeys(o, "first\rsecond")
+	if string(o.Input) != "first\nsecond" {
+		t.Fatalf("Input = %q, want Enter to insert a line break", o.Input)
+	}
+	typeKeys(o, "\x04")
+	want := "first\nsecond\r"
+	if got := readPTY(t, r, len(want)); string(got) != want {
+		t.Errorf("PTY got %q, want %q", got, want)
+	}
+	if o.Composing || len(o.Input) != 0 {
+		t.Errorf("after send: Composing=%v Input=%q, want layout restored and bar cleared", o.Composing, o.Input)
+	}
+	if got := o.viewRows(); got != 10 {
+		t.Errorf("viewRows = %d after send, want 10", got)
+	}
+}
+
+func TestCompose_ShiftEnterSends(t *testing.T) {
+	o := newComposeClient(t)
+	r := pipePTY(t, o)
+	o.StartCompose()
+
+	typeKeys(o, "a\rb\x1b[13;2u")
+	if got := readPTY(t, r, 4); string(got) != "a\nb\r" {
+		t.Errorf("PTY got %q, want %q", got, "a\nb\r")
+	}
+	if o.Composing {
+		t.Error("Shift+Enter should leave compose mode")
+	}
+}
+
+func TestCompose_MenuToggle(t *testing.T) {
+	o := newComposeClient(t)
+	o.Mode = ModeMenu
+	o.HandleMenuBytes([]byte("m"), 0, 1)
+	if !o.Composing || o.Mode != ModeNormal {
+		t.Fatalf("Composing=%v Mode=%v, want compose in normal mode", o.Composing, o.Mode)
+	}
+	if o.ModeLabel() != "Compose" {
+		t.Errorf("ModeLabel = %q, want Compose", o.ModeLabel())
+	}
+	o.Mode = ModeMenu
+	o.HandleMenuBytes([]byte("m"), 0, 1)
+	if o.Composing {
+		t.Error("second m should end compose")
+	}
+}
+
+func TestCompose_ResizeKeepsChildRowFree(t *testing.T) {
+	o := newComposeClient(t)
+	o.StartCompose()
+	// A tiny terminal can't fit the full compose area.
+	o.VT.Rows, o.VT.ChildRows = 5, 3
+	if got := o.overlayRows(); got != 4 {
+		t.Errorf("overlayRows = %d on a 5-row terminal, want 4", got)
+	}
+	if got := o.viewRows(); got != 1 {
+		t.Errorf("viewRows = %d on a 5-row terminal, want 1", got)
+	}
+}
+
+func TestCompose_UpDownMoveBetweenLines(t *testing.T) {
+	o := newComposeClient(t)
+	o.StartCompose()
+	typeKeys(o, "hello\rhi\rworld")
+	typeKeys(o, "\x1b[A")
+	if o.CursorPos != len("hello\nhi") {
+		t.Fatalf("Up: CursorPos = %d, want end of the shorter line", o.CursorPos)
+	}
+	typeKeys(o, "\x1b[A")
+	if o.CursorPos != 2 {
+		t.Fatalf("Up: CursorPos = %d, want column 2 of line 1", o.CursorPos)
+	}
+	typeKeys(o, "\x1b[B\x1b[B")
+	if o.CursorPos != len("hello\nhi\nwo") {
+		t.Fatalf("Down: CursorPos = %d, want column 2 of line 3", o.CursorPos)
+	}
+}
+
+func TestCompose_RenderShowsLines(t *testing.T) {
+	o := newComposeClient(t)
+	o.StartCompose()
+	o.Input = []byte("one\ntwo")
+	o.CursorPos = len(o.Input)
+
+	var buf bytes.Buffer
+	o.renderComposeLines(&buf, 8, ComposeRows)
+	out := buf.String()
+	if !strings.Contains(out, "\033[8;1H") || !strings.Contains(out, "one") || !strings.Contains(out, "\033[9;1H\033[2K"+strings.Repeat(" ", len("normal > "))+"two") {
+		t.Errorf("render = %q, want one line per row", out)
+	}
+	if !strings.HasSuffix(out, "\033[9;13H") {
+		t.Errorf("render = %q, want cursor after \"two\" on row 9", out)
+	}
+}
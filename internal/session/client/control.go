@@ -0,0 +1,66 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"h2/internal/session/virtualterminal"
+)
+
+// ControlStatus describes the session's write lock as seen by one client.
+// Only the holder may send input to the agent; everyone else is read-only.
+type ControlStatus struct {
+	Holder    string        // label of the client holding control ("" = free)
+	Held      bool          // this client holds control
+	Requester string        // label of the client waiting for control ("" = none)
+	Requested bool          // this client has a pending request
+	ForceIn   time.Duration // with Requested: time until the request may be forced
+}
+
+// mayWrite reports whether this client may send input to the agent,
+// claiming the write lock if nobody holds it. Without a session wired in,
+// writes are always allowed. Read-only clients never write.
+func (c *Client) mayWrite() bool {
+	if c.ReadOnly {
+		return false
+	}
+	return c.ClaimControl == nil || c.ClaimControl()
+}
+
+// control returns the current write-lock status (zero when not wired).
+func (c *Client) control() ControlStatus {
+	if c.Control == nil {
+		return ControlStatus{}
+	}
+	return c.Control()
+}
+
+// readOnlyWarning is shown when a non-holder tries to send.
+func (c *Client) readOnlyWarning() string {
+	if c.ReadOnly {
+		return "read-only: attached as an observer"
+	}
+	return fmt.Sprintf("read-only: %s has control (menu w: request)", c.control().Holder)
+}
+
+// controlLabel returns the status-bar segment describing the write lock,
+// or "" when there is nothing worth showing.
+func (c *Client) controlLabel() string {
+	if c.ReadOnly {
+		return "" // the mode label already says read-only
+	}
+	st := c.control()
+	switch {
+	case st.Holder == "" || (st.Held && st.Requester == ""):
+		return ""
+	case st.Held:
+		return st.Requester + " requests control (menu g: grant)"
+	case st.Requested && st.ForceIn > 0:
+		return fmt.Sprintf("read-only: %s has control | requested, w to force in %s",
+			st.Holder, virtualterminal.FormatIdleDuration(st.ForceIn))
+	case st.Requested:
+		return fmt.Sprintf("read-only: %s has control | requested, w to force", st.Holder)
+	default:
+		return "read-only: " + st.Holder + " has control"
+	}
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"bytes"
 	"unicode"
 	"unicode/utf8"
 )
@@ -31,6 +32,46 @@ func (c *Client) CursorToEnd() {
 	c.CursorPos = len(c.Input)
 }
 
+// CursorUpLine moves the cursor to the same column on the previous line of
+// a multi-line input, or to the end of that line if it is shorter.
+func (c *Client) CursorUpLine() {
+	lineStart := bytes.LastIndexByte(c.Input[:c.CursorPos], '\n') + 1
+	if lineStart == 0 {
+		return
+	}
+	col := utf8.RuneCount(c.Input[lineStart:c.CursorPos])
+	prevStart := bytes.LastIndexByte(c.Input[:lineStart-1], '\n') + 1
+	c.CursorPos = prevStart + runeOffset(c.Input[prevStart:lineStart-1], col)
+}
+
+// CursorDownLine moves the cursor to the same column on the next line of a
+// multi-line input, or to the end of that line if it is shorter.
+func (c *Client) CursorDownLine() {
+	next := bytes.IndexByte(c.Input[c.CursorPos:], '\n')
+	if next < 0 {
+		return
+	}
+	lineStart := bytes.LastIndexByte(c.Input[:c.CursorPos], '\n') + 1
+	col := utf8.RuneCount(c.Input[lineStart:c.CursorPos])
+	nextStart := c.CursorPos + next + 1
+	nextEnd := len(c.Input)
+	if i := bytes.IndexByte(c.Input[nextStart:], '\n'); i >= 0 {
+		nextEnd = nextStart + i
+	}
+	c.CursorPos = nextStart + runeOffset(c.Input[nextStart:nextEnd], col)
+}
+
+// runeOffset returns the byte offset of the n-th rune in line, or len(line)
+// if it has fewer runes.
+func runeOffset(line []byte, n int) int {
+	off := 0
+	for i := 0; i < n && off < len(line); i++ {
+		_, size := utf8.DecodeRune(line[off:])
+		off += size
+	}
+	return off
+}
+
 // CursorForwardWord moves the cursor forward to the end of the next word.
 func (c *Client) CursorForwardWord() {
 	i := c.CursorPos
@@ -101,8 +142,19 @@ func (c *Client) DeleteBackward() bool {
 	return true
 }
 
-// InsertByte inserts a single byte at the cursor position.
+// InsertByte inserts a single byte at the cursor position. In overwrite
+// mode, a byte that starts a character first removes the character under
+// the cursor. Once Input reaches MaxInputLen (when set), further bytes are
+// dropped with a warning.
 func (c *Client) InsertByte(b byte) {
+	if c.Overwrite && c.CursorPos < len(c.Input) && utf8.RuneStart(b) {
+		_, size := utf8.DecodeRune(c.Input[c.CursorPos:])
+		c.Input = append(c.Input[:c.CursorPos], c.Input[c.CursorPos+size:]...)
+	}
+	if c.MaxInputLen > 0 && len(c.Input) >= c.MaxInputLen {
+		c.warn("input limit reached")
+		return
+	}
 	c.Input = append(c.Input, 0)
 	copy(c.Input[c.CursorPos+1:], c.Input[c.CursorPos:])
 	c.Input[c.CursorPos] = b
@@ -336,3 +336,115 @@ func TestIsWordChar(t *testing.T) {
 		}
 	}
 }
+
+// --- Insert / overwrite ---
+
+func TestInsertByte_InsertModeShifts(t *testing.T) {
+	o := &Client{Input: []byte("abcd"), CursorPos: 1}
+	o.InsertByte('X')
+	if string(o.Input) != "aXbcd" || o.CursorPos != 2 {
+		t.Fatalf("got %q cursor %d, want %q cursor 2", o.Input, o.CursorPos, "aXbcd")
+	}
+	o.InsertByte('Y')
+	if string(o.Input) != "aXYbcd" || o.CursorPos != 3 {
+		t.Fatalf("got %q cursor %d, want %q cursor 3", o.Input, o.CursorPos, "aXYbcd")
+	}
+}
+
+func TestInsertByte_OverwriteModeReplaces(t *testing.T) {
+	o := &Client{Input: []byte("abcd"), CursorPos: 1, Overwrite: true}
+	o.InsertByte('X')
+	if string(o.Input) != "aXcd" || o.CursorPos != 2 {
+		t.Fatalf("got %q cursor %d, want %q cursor 2", o.Input, o.CursorPos, "aXcd")
+	}
+	o.InsertByte('Y')
+	if string(o.Input) != "aXYd" || o.CursorPos != 3 {
+		t.Fatalf("got %q cursor %d, want %q cursor 3", o.Input, o.CursorPos, "aXYd")
+	}
+	// Past the end, overwrite appends.
+	o.InsertByte('Z')
+	o.InsertByte('!')
+	if string(o.Input) != "aXYZ!" || o.CursorPos != 5 {
+		t.Fatalf("got %q cursor %d, want %q cursor 5", o.Input, o.CursorPos, "aXYZ!")
+	}
+}
+
+func TestInsertByte_OverwriteMultibyte(t *testing.T) {
+	// Overwriting 'é' (2 bytes) with 'x' and 'x' with 'ü' (2 bytes).
+	o := &Client{Input: []byte("aéx"), CursorPos: 1, Overwrite: true}
+	o.InsertByte('x')
+	if string(o.Input) != "axx" || o.CursorPos != 2 {
+		t.Fatalf("got %q cursor %d, want %q cursor 2", o.Input, o.CursorPos, "axx")
+	}
+	for _, b := range []byte("ü") {
+		o.InsertByte(b)
+	}
+	if string(o.Input) != "axü" || o.CursorPos != 4 {
+		t.Fatalf("got %q cursor %d, want %q cursor 4", o.Input, o.CursorPos, "axü")
+	}
+}
+
+func TestInsertKey_TogglesOverwrite(t *testing.T) {
+	o := newTestClient(10, 80)
+	o.Input = []byte("abc")
+	insertKey := []byte("\x1b[2~")
+
+	o.HandleDefaultBytes(insertKey, 0, len(insertKey))
+	if !o.Overwrite {
+		t.Fatal("expected Insert key to enable overwrite mode")
+	}
+	if o.ModeLabel() != "Normal (OVR)" {
+		t.Errorf("ModeLabel = %q, want overwrite shown", o.ModeLabel())
+	}
+	o.HandleDefaultBytes(insertKey, 0, len(insertKey))
+	if o.Overwrite {
+		t.Fatal("expected second Insert key to return to insert mode")
+	}
+	if string(o.Input) != "abc" {
+		t.Errorf("Insert key should not change input, got %q", o.Input)
+	}
+}
+
+func TestHomeEndKeys_MoveCursor(t *testing.T) {
+	for _, tc := range []struct {
+		name, home, end string
+	}{
+		{"xterm", "\x1b[H", "\x1b[F"},
+		{"vt220", "\x1b[1~", "\x1b[4~"},
+		{"rxvt", "\x1b[7~", "\x1b[8~"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			o := newTestClient(10, 80)
+			o.Input = []byte("hello")
+			o.CursorPos = 2
+
+			o.HandleDefaultBytes([]byte(tc.home), 0, len(tc.home))
+			if o.CursorPos != 0 {
+				t.Fatalf("Home: CursorPos = %d, want 0", o.CursorPos)
+			}
+			o.HandleDefaultBytes([]byte(tc.end), 0, len(tc.end))
+			if o.CursorPos != 5 {
+				t.Fatalf("End: CursorPos = %d, want 5", o.CursorPos)
+			}
+		})
+	}
+}
+
+func TestMidLineEditing_ArrowsInsertAndBackspace(t *testing.T) {
+	o := newTestClient(10, 80)
+	keys := []byte("helo\x1b[D")
+	o.HandleDefaultBytes(keys, 0, len(keys))
+	keys = []byte("l")
+	o.HandleDefaultBytes(keys, 0, len(keys))
+	if string(o.Input) != "hello" {
+		t.Fatalf("after mid-line insert Input = %q, want %q", o.Input, "hello")
+	}
+	if o.CursorPos != 4 {
+		t.Fatalf("CursorPos = %d, want 4", o.CursorPos)
+	}
+	keys = []byte("\x1b[D\x1b[D\x7f")
+	o.HandleDefaultBytes(keys, 0, len(keys))
+	if string(o.Input) != "hllo" || o.CursorPos != 1 {
+		t.Fatalf("after mid-line backspace Input = %q pos %d, want %q pos 1", o.Input, o.CursorPos, "hllo")
+	}
+}
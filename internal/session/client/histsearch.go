@@ -0,0 +1,150 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// historySearch is the state of a reverse-incremental (Ctrl+R) history
+// search. The input bar shows the most recent History entry containing term.
+type historySearch struct {
+	term     []byte
+	match    int    // index into History of the shown match; -1 if none
+	saved    []byte // Input before the search began, restored on cancel
+	savedPos int
+}
+
+// StartHistorySearch enters reverse-incremental search mode.
+func (c *Client) StartHistorySearch() {
+	c.search = &historySearch{
+		match:    -1,
+		saved:    append([]byte(nil), c.Input...),
+		savedPos: c.CursorPos,
+	}
+}
+
+// findHistoryMatch returns the index of the most recent History entry at or
+// before from that contains term, ignoring case. An empty term matches
+// nothing.
+func (c *Client) findHistoryMatch(term []byte, from int) int {
+	if len(term) == 0 {
+		return -1
+	}
+	needle := strings.ToLower(string(term))
+	if from >= len(c.History) {
+		from = len(c.History) - 1
+	}
+	for i := from; i >= 0; i-- {
+		if strings.Contains(strings.ToLower(c.History[i]), needle) {
+			return i
+		}
+	}
+	return -1
+}
+
+// HistorySearchNext moves to the next older match for the current term.
+// The current match is kept when there is no older one.
+func (c *Client) HistorySearchNext() {
+	s := c.search
+	if s == nil || s.match <= 0 {
+		return
+	}
+	if i := c.findHistoryMatch(s.term, s.match-1); i >= 0 {
+		s.match = i
+	}
+}
+
+// historySearchType appends b to the search term and re-runs the search from
+// the most recent entry.
+func (c *Client) historySearchType(b byte) {
+	s := c.search
+	s.term = append(s.term, b)
+	s.match = c.findHistoryMatch(s.term, len(c.History)-1)
+}
+
+// historySearchBackspace drops the last rune of the search term.
+func (c *Client) historySearchBackspace() {
+	s := c.search
+	if len(s.term) == 0 {
+		return
+	}
+	r := []rune(string(s.term))
+	s.term = []byte(string(r[:len(r)-1]))
+	s.match = c.findHistoryMatch(s.term, len(c.History)-1)
+}
+
+// AcceptHistorySearch leaves search mode with the current match in Input.
+// With no match, the input from before the search is kept.
+func (c *Client) AcceptHistorySearch() {
+	s := c.search
+	if s == nil {
+		return
+	}
+	c.search = nil
+	if s.match < 0 {
+		c.Input = s.saved
+		c.CursorPos = s.savedPos
+		return
+	}
+	c.Input = []byte(c.History[s.match])
+	c.CursorPos = len(c.Input)
+	c.HistIdx = -1
+	c.Saved = nil
+}
+
+// CancelHistorySearch leaves search mode and restores the prior input.
+func (c *Client) CancelHistorySearch() {
+	s := c.search
+	if s == nil {
+		return
+	}
+	c.search = nil
+	c.Input = s.saved
+	c.CursorPos = s.savedPos
+}
+
+// handleHistorySearchByte processes one input byte while a search is
+// active. rest holds the bytes after b in the same read. It returns false
+// when the search was accepted and b should be handled as a normal key.
+func (c *Client) handleHistorySearchByte(b byte, rest []byte) bool {
+	switch {
+	case b == 0x12: // ctrl+r — next older match
+		c.HistorySearchNext()
+	case b == 0x0D || b == 0x0A:
+		c.AcceptHistorySearch()
+	case b == 0x07: // ctrl+g — cancel
+		c.CancelHistorySearch()
+	case b == 0x1B && len(rest) == 0: // bare Esc — cancel
+		c.CancelHistorySearch()
+	case b == 0x7F || b == 0x08:
+		c.historySearchBackspace()
+	case b >= 0x20:
+		c.historySearchType(b)
+	default:
+		// Any other key (arrows, ctrl+a, ...) accepts the match and then
+		// acts on it as usual.
+		c.AcceptHistorySearch()
+		return false
+	}
+	c.RenderBar()
+	return true
+}
+
+// renderSearchLine draws the reverse-i-search prompt and current match on
+// inputRow.
+func (c *Client) renderSearchLine(buf *bytes.Buffer, inputRow int) {
+	s := c.search
+	prompt := fmt.Sprintf("(reverse-i-search)'%s': ", controlPictures([]rune(string(s.term))))
+	match := ""
+	if s.match >= 0 {
+		match = controlPictures([]rune(c.History[s.match]))
+	}
+	line := []rune(prompt + match)
+	if len(line) > c.VT.Cols {
+		line = line[:max(c.VT.Cols, 0)]
+	}
+	fmt.Fprintf(buf, "\033[%d;1H\033[2K\033[%sm%s\033[0m", inputRow, c.theme().Prompt, string(line))
+	cursorCol := min(len([]rune(prompt))+1, c.VT.Cols)
+	fmt.Fprintf(buf, "\033[%d;%dH", inputRow, max(cursorCol, 1))
+}
@@ -0,0 +1,100 @@
+package client
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func typeKeys(c *Client, keys string) {
+	c.HandleDefaultBytes([]byte(keys), 0, len(keys))
+}
+
+func newSearchClient() *Client {
+	c := newTestClient(10, 80)
+	c.History = []string{"git status", "make test", "Git push", "ls"}
+	return c
+}
+
+func TestHistorySearch_FindsMostRecentCaseInsensitive(t *testing.T) {
+	c := newSearchClient()
+	typeKeys(c, "\x12gi")
+	if c.search == nil {
+		t.Fatal("expected Ctrl+R to start a search")
+	}
+	if c.search.match != 2 {
+		t.Fatalf("match = %d, want 2 (\"Git push\")", c.search.match)
+	}
+	if c.ModeLabel() != "Search" {
+		t.Errorf("ModeLabel = %q, want Search", c.ModeLabel())
+	}
+}
+
+func TestHistorySearch_CtrlRCyclesOlder(t *testing.T) {
+	c := newSearchClient()
+	typeKeys(c, "\x12git\x12")
+	if c.search.match != 0 {
+		t.Fatalf("match = %d, want 0 after second Ctrl+R", c.search.match)
+	}
+	// No older match: stay on the oldest one.
+	typeKeys(c, "\x12")
+	if c.search.match != 0 {
+		t.Fatalf("match = %d, want 0 to be kept", c.search.match)
+	}
+}
+
+func TestHistorySearch_EnterAccepts(t *testing.T) {
+	c := newSearchClient()
+	typeKeys(c, "draft\x12make\r")
+	if c.search != nil {
+		t.Fatal("expected Enter to end the search")
+	}
+	if string(c.Input) != "make test" || c.CursorPos != len("make test") {
+		t.Fatalf("Input = %q pos %d, want accepted match", c.Input, c.CursorPos)
+	}
+	if len(c.History) != 4 {
+		t.Errorf("accepting should not submit, History = %v", c.History)
+	}
+}
+
+func TestHistorySearch_EscCancelsAndRestores(t *testing.T) {
+	c := newSearchClient()
+	typeKeys(c, "draft\x01")
+	typeKeys(c, "\x12make")
+	typeKeys(c, "\x1b")
+	if c.search != nil {
+		t.Fatal("expected Esc to end the search")
+	}
+	if string(c.Input) != "draft" || c.CursorPos != 0 {
+		t.Fatalf("Input = %q pos %d, want restored %q pos 0", c.Input, c.CursorPos, "draft")
+	}
+}
+
+func TestHistorySearch_EmptyTermShowsNothing(t *testing.T) {
+	c := newSearchClient()
+	typeKeys(c, "\x12")
+	if c.search.match != -1 {
+		t.Fatalf("match = %d, want -1 for empty term", c.search.match)
+	}
+	typeKeys(c, "ls\x7f\x7f")
+	if c.search.match != -1 {
+		t.Fatalf("match = %d, want -1 after erasing the term", c.search.match)
+	}
+
+	var buf bytes.Buffer
+	c.renderSearchLine(&buf, 10)
+	if !strings.Contains(buf.String(), "(reverse-i-search)'': ") || strings.Contains(buf.String(), "git") {
+		t.Errorf("render = %q, want empty search line", buf.String())
+	}
+}
+
+func TestHistorySearch_ArrowAcceptsThenMoves(t *testing.T) {
+	c := newSearchClient()
+	typeKeys(c, "\x12push\x1b[D")
+	if c.search != nil {
+		t.Fatal("expected an arrow key to accept the search")
+	}
+	if string(c.Input) != "Git push" || c.CursorPos != len("Git push")-1 {
+		t.Fatalf("Input = %q pos %d, want match with cursor moved left", c.Input, c.CursorPos)
+	}
+}
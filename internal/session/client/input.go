@@ -1,6 +1,8 @@
 package client
 
 import (
+	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 	"syscall"
@@ -11,10 +13,19 @@ import (
 )
 
 const ptyWriteTimeout = 3 * time.Second
-const scrollStep = 3
+
+// DefaultScrollStep is how many lines one mouse wheel notch scrolls when
+// the client's ScrollStep is unset.
+const DefaultScrollStep = 3
 
 func (c *Client) setMode(mode InputMode) {
+	if mode == ModePassthrough && c.Mode != ModePassthroughScroll {
+		c.PassthroughActiveAt = time.Now()
+	}
 	c.Mode = mode
+	if mode != ModeScroll && mode != ModePassthroughScroll {
+		c.scrollSearch = nil
+	}
 	if c.OnModeChange != nil {
 		c.OnModeChange(mode)
 	}
@@ -23,7 +34,15 @@ func (c *Client) setMode(mode InputMode) {
 // writePTYOrHang writes to the child PTY with a timeout. If the write times
 // out (child not reading), it marks the child as hung, kills it, and returns
 // false. The caller should stop processing input when this returns false.
+//
+// Writes from a client without control are refused with a bar warning and
+// also return false.
 func (c *Client) writePTYOrHang(p []byte) bool {
+	if !c.mayWrite() {
+		c.warn(c.readOnlyWarning())
+		c.RenderBar()
+		return false
+	}
 	// Detect Ctrl+C (0x03) before writing so the agent can track interrupts.
 	if c.OnInterrupt != nil {
 		for _, b := range p {
@@ -33,6 +52,10 @@ func (c *Client) writePTYOrHang(p []byte) bool {
 			}
 		}
 	}
+	if c.VT.Ptm == nil {
+		// Child not started yet — nothing to write to.
+		return false
+	}
 	_, err := c.VT.WritePTY(p, ptyWriteTimeout)
 	if err != nil {
 		c.VT.ChildHung = true
@@ -43,12 +66,95 @@ func (c *Client) writePTYOrHang(p []byte) bool {
 	return true
 }
 
+// submitWarning describes why queued input was dropped, for the bar.
+func submitWarning(err error) string {
+	var dup *message.DuplicateError
+	if errors.As(err, &dup) {
+		return "duplicate of a message sent moments ago; not queued again"
+	}
+	return err.Error()
+}
+
+// submitInput sends the input bar to the child (an empty bar sends a bare
+// submit), records it in History, and resets the bar. If the session
+// rejects queued input the bar is kept and the reason shown. It returns
+// false if the write failed and input handling should stop.
+func (c *Client) submitInput() bool {
+	if len(c.Input) > 0 {
+		cmd := string(c.Input)
+		if c.InputPriority == message.PriorityNormal {
+			// Normal: direct PTY write.
+			c.VT.MarkPrompt()
+			if !c.writePTYOrHang(c.Input) {
+				return false
+			}
+			if !c.writeSubmit() {
+				return false
+			}
+			if c.OnUserInput != nil {
+				c.OnUserInput(cmd, c.InputPriority)
+			}
+		} else if c.OnSubmit != nil {
+			// Non-normal: route through session for priority-aware delivery.
+			if !c.mayWrite() {
+				c.warn(c.readOnlyWarning())
+				c.RenderBar()
+				return false
+			}
+			if err := c.OnSubmit(cmd, c.InputPriority); err != nil {
+				// Keep the input so the user can edit or resend it.
+				c.warn(submitWarning(err))
+				c.RenderBar()
+				return true
+			}
+		}
+		c.History = append(c.History, cmd)
+		c.Input = c.Input[:0]
+		c.CursorPos = 0
+		c.InputPriority = message.PriorityNormal
+	} else {
+		if !c.writePTYOrHang(c.SubmitNewline.Bytes()) {
+			return false
+		}
+	}
+	c.HistIdx = -1
+	c.Saved = nil
+	c.RenderBar()
+	return true
+}
+
+// writeSubmit writes the submit bytes after typed input. With a SubmitDelay
+// the write happens on a goroutine that takes VT.Mu, so it is ordered with
+// other PTY writes instead of racing them; otherwise it is written inline.
+func (c *Client) writeSubmit() bool {
+	submit := c.SubmitNewline.Bytes()
+	if c.SubmitDelay <= 0 {
+		return c.writePTYOrHang(submit)
+	}
+	vt := c.VT
+	ptm := vt.Ptm
+	delay := c.SubmitDelay
+	go func() {
+		time.Sleep(delay)
+		vt.Mu.Lock()
+		defer vt.Mu.Unlock()
+		if vt.Ptm != ptm {
+			return // child relaunched in the meantime
+		}
+		vt.WritePTY(submit, ptyWriteTimeout)
+	}()
+	return true
+}
+
 // HandleExitedBytes processes input when the child has exited or is hung.
 // Enter relaunches, q quits. ESC sequences are processed for mouse scroll.
 func (c *Client) HandleExitedBytes(buf []byte, start, n int) int {
 	for i := start; i < n; {
 		b := buf[i]
 		i++
+		if c.Pasting && b != 0x1B {
+			continue
+		}
 		switch b {
 		case '\r', '\n':
 			if c.OnRelaunch != nil {
@@ -103,12 +209,27 @@ func (c *Client) CancelPendingEsc() {
 }
 
 func (c *Client) HandlePassthroughBytes(buf []byte, start, n int) int {
+	if c.ReadOnly {
+		return c.HandleReadOnlyBytes(buf, start, n)
+	}
+	c.PassthroughActiveAt = time.Now()
 	for i := start; i < n; {
 		if c.VT.ChildExited || c.VT.ChildHung {
 			return n
 		}
 		b := buf[i]
 		if c.PendingEsc {
+			if b == '\r' || b == '\n' {
+				// Alt+Enter.
+				c.CancelPendingEsc()
+				c.PassthroughEsc = c.PassthroughEsc[:0]
+				c.modifierEnter()
+				if c.VT.ChildHung {
+					return n
+				}
+				i++
+				continue
+			}
 			if b != '[' && b != 'O' {
 				// Not a CSI/SS3 introducer — pass ESC + this byte through to the child.
 				c.CancelPendingEsc()
@@ -137,11 +258,19 @@ func (c *Client) HandlePassthroughBytes(buf []byte, start, n int) int {
 			i++
 			continue
 		}
+		if c.Pasting && b != 0x1B {
+			// Pasted text goes to the child verbatim.
+			if !c.writePTYOrHang([]byte{b}) {
+				return n
+			}
+			i++
+			continue
+		}
 		switch b {
 		case 0x0D, 0x0A:
 			c.CancelPendingEsc()
 			c.PassthroughEsc = c.PassthroughEsc[:0]
-			if !c.writePTYOrHang([]byte{'\r'}) {
+			if !c.writePTYOrHang(c.SubmitNewline.Bytes()) {
 				return n
 			}
 			i++
@@ -186,8 +315,30 @@ func (c *Client) HandleMenuBytes(buf []byte, start, n int) int {
 			}
 			continue
 		}
+		if c.Pasting {
+			// Text pasted while the menu is open is not a menu key.
+			continue
+		}
+		if c.ReadOnly && !readOnlyMenuKey(b) {
+			continue
+		}
+		if c.QuitPending {
+			// Awaiting quit confirmation: q confirms, anything else cancels.
+			c.QuitPending = false
+			if b == 'q' || b == 'Q' {
+				c.quitChild()
+				continue
+			}
+			c.setMode(ModeNormal)
+			c.RenderBar()
+			continue
+		}
 		switch b {
 		case 'p', 'P': // passthrough mode
+			if c.PassthroughDisabled {
+				c.RenderBar()
+				continue
+			}
 			if c.TryPassthrough != nil && !c.TryPassthrough() {
 				// Locked by another client — stay in menu.
 				c.RenderBar()
@@ -196,21 +347,47 @@ func (c *Client) HandleMenuBytes(buf []byte, start, n int) int {
 			c.setMode(ModePassthrough)
 			c.RenderBar()
 		case 't', 'T': // take over passthrough from another client
-			if c.TakePassthrough != nil {
-				c.TakePassthrough()
+			if c.PassthroughDisabled {
+				c.RenderBar()
+				continue
+			}
+			if c.TakePassthrough != nil && !c.TakePassthrough() {
+				c.RenderBar()
+				continue
 			}
 			c.setMode(ModePassthrough)
 			c.RenderBar()
+		case 'w', 'W': // request (or force) control of the agent
+			if c.RequestControl != nil {
+				c.RequestControl()
+			}
+			c.setMode(ModeNormal)
+			c.RenderBar()
+		case 'g', 'G': // grant control to the client that requested it
+			if c.GrantControl != nil && !c.GrantControl() {
+				c.warn("no pending control request")
+			}
+			c.setMode(ModeNormal)
+			c.RenderBar()
+		case 'f', 'F': // send the file whose path is in the input bar
+			c.sendFile()
+		case 'y', 'Y': // copy the agent's last response to the clipboard
+			c.copyLastResponse()
+		case 'm', 'M': // toggle multi-line compose
+			c.setMode(ModeNormal)
+			if c.Composing {
+				c.EndCompose()
+			} else {
+				c.StartCompose()
+			}
 		case 'c', 'C': // clear input
 			c.Input = c.Input[:0]
 			c.CursorPos = 0
 			c.setMode(ModeNormal)
 			c.RenderBar()
 		case 'r', 'R': // redraw screen
-			c.Output.Write([]byte("\033[2J\033[H"))
-			c.RenderScreen()
 			c.setMode(ModeNormal)
-			c.RenderBar()
+			c.Redraw()
 		case 'd', 'D': // detach
 			if c.OnDetach != nil {
 				c.setMode(ModeNormal)
@@ -219,17 +396,101 @@ func (c *Client) HandleMenuBytes(buf []byte, start, n int) int {
 				return n
 			}
 		case 'q', 'Q': // quit
-			c.Quit = true
-			c.VT.Cmd.Process.Signal(syscall.SIGTERM)
-			if c.OnQuit != nil {
-				c.OnQuit()
+			if c.ConfirmQuit {
+				c.QuitPending = true
+				c.RenderBar()
+				continue
 			}
+			c.quitChild()
 		}
 	}
 	return n
 }
 
+// readOnlyMenuKey reports whether menu key b is available to a read-only
+// client: only actions that leave the agent untouched.
+func readOnlyMenuKey(b byte) bool {
+	switch b {
+	case 'r', 'R', 'y', 'Y', 'd', 'D':
+		return true
+	}
+	return false
+}
+
+// sendFile hands the agent the file named by the input bar text, via
+// OnSendFile, and clears the bar on success. A rejected path is shown as a
+// bar warning and the text is kept so it can be corrected.
+func (c *Client) sendFile() {
+	c.setMode(ModeNormal)
+	path := strings.TrimSpace(string(c.Input))
+	switch {
+	case c.OnSendFile == nil:
+	case path == "":
+		c.warn("type a file path in the input bar first")
+	case !c.mayWrite():
+		c.warn(c.readOnlyWarning())
+	default:
+		if err := c.OnSendFile(path, c.InputPriority); err != nil {
+			c.warn(err.Error())
+			break
+		}
+		c.History = append(c.History, string(c.Input))
+		c.Input = c.Input[:0]
+		c.CursorPos = 0
+		c.InputPriority = message.PriorityNormal
+		c.HistIdx = -1
+		c.Saved = nil
+	}
+	c.RenderBar()
+}
+
+// copyLastResponse copies the agent's most recent response from the
+// scrollback to the user's clipboard with OSC 52. Terminals without OSC 52
+// support ignore the sequence, so the bar notes what was sent.
+func (c *Client) copyLastResponse() {
+	c.setMode(ModeNormal)
+	text := c.VT.LastResponse()
+	switch {
+	case !virtualterminal.ClipboardEnabled():
+		c.warn("clipboard disabled (H2_CLIPBOARD=0)")
+	case text == "":
+		c.warn("no agent response to copy yet")
+	case len(text) > virtualterminal.MaxClipboardCopy:
+		c.warn(fmt.Sprintf("response too large to copy (%d bytes, max %d)", len(text), virtualterminal.MaxClipboardCopy))
+	default:
+		c.Output.Write(virtualterminal.ClipboardSequence(text))
+		lines := strings.Count(text, "\n") + 1
+		c.warn(fmt.Sprintf("copied %d line(s) to clipboard (needs OSC 52 support)", lines))
+	}
+	c.RenderBar()
+}
+
+// quitChild notifies the session, which shuts the child down gracefully.
+// Without a session the child is sent SIGTERM directly.
+func (c *Client) quitChild() {
+	c.Quit = true
+	if c.OnQuit != nil {
+		c.OnQuit()
+		return
+	}
+	c.VT.Cmd.Process.Signal(syscall.SIGTERM)
+}
+
 func (c *Client) HandleDefaultBytes(buf []byte, start, n int) int {
+	if c.ReadOnly {
+		return c.HandleReadOnlyBytes(buf, start, n)
+	}
+	if !c.QuotedInsert && isBinaryPaste(buf[start:n]) {
+		c.warn("binary paste rejected")
+		c.RenderBar()
+		return n
+	}
+	pasted := false
+	defer func() {
+		if pasted {
+			c.RenderBar()
+		}
+	}()
 	for i := start; i < n; {
 		if c.VT.ChildExited || c.VT.ChildHung {
 			return c.HandleExitedBytes(buf, i, n)
@@ -238,6 +499,36 @@ func (c *Client) HandleDefaultBytes(buf []byte, start, n int) int {
 		b := buf[i]
 		i++
 
+		if c.QuotedInsert {
+			// Quoted insert: take this byte literally, bypassing any
+			// key binding or mode switch.
+			c.QuotedInsert = false
+			c.InsertByte(b)
+			c.RenderBar()
+			continue
+		}
+
+		if c.Pasting && b != 0x1B {
+			// Bracketed paste: take bytes literally. Line breaks become
+			// newlines in the input instead of submitting it.
+			if c.search != nil {
+				c.AcceptHistorySearch()
+			}
+			if b == '\r' {
+				b = '\n'
+				if i < n && buf[i] == '\n' {
+					i++
+				}
+			}
+			c.InsertByte(b)
+			pasted = true
+			continue
+		}
+
+		if c.search != nil && c.handleHistorySearchByte(b, buf[i:n]) {
+			continue
+		}
+
 		if b == 0x1B {
 			consumed, handled := c.HandleEscape(buf[i:n])
 			i += consumed
@@ -252,39 +543,39 @@ func (c *Client) HandleDefaultBytes(buf []byte, start, n int) int {
 			c.setMode(ModeMenu)
 			c.RenderBar()
 
+		case 0x16: // ctrl+v — quoted insert (next byte is literal)
+			c.QuotedInsert = true
+
+		case 0x0C: // ctrl+l — clear and redraw
+			c.Redraw()
+
+		case 0x12: // ctrl+r — reverse-incremental history search
+			c.StartHistorySearch()
+			c.RenderBar()
+
 		case 0x09:
 			c.CyclePriority()
 			c.RenderBar()
 
 		case 0x0D, 0x0A:
-			if len(c.Input) > 0 {
-				cmd := string(c.Input)
-				if c.InputPriority == message.PriorityNormal {
-					// Normal: direct PTY write.
-					if !c.writePTYOrHang(c.Input) {
-						return n
-					}
-					ptm := c.VT.Ptm
-					go func() {
-						time.Sleep(50 * time.Millisecond)
-						ptm.Write([]byte{'\r'})
-					}()
-				} else if c.OnSubmit != nil {
-					// Non-normal: route through session for priority-aware delivery.
-					c.OnSubmit(cmd, c.InputPriority)
-				}
-				c.History = append(c.History, cmd)
-				c.Input = c.Input[:0]
-				c.CursorPos = 0
-				c.InputPriority = message.PriorityNormal
-			} else {
-				if !c.writePTYOrHang([]byte{'\r'}) {
+			if c.Composing {
+				// Compose mode: Enter is a line break; Ctrl+D sends.
+				c.InsertByte('\n')
+				c.RenderBar()
+				break
+			}
+			if !c.submitInput() {
+				return n
+			}
+
+		case 0x04: // ctrl+d — send the compose buffer (pass through otherwise)
+			if c.Composing {
+				if !c.submitCompose() {
 					return n
 				}
+			} else if !c.writePTYOrHang([]byte{b}) {
+				return n
 			}
-			c.HistIdx = -1
-			c.Saved = nil
-			c.RenderBar()
 
 		case 0x7F, 0x08:
 			if c.CursorPos > 0 {
@@ -346,6 +637,72 @@ func (c *Client) HandleDefaultBytes(buf []byte, start, n int) int {
 	return n
 }
 
+// HandleReadOnlyBytes processes input for a read-only (observer) client.
+// Nothing reaches the agent: the mouse wheel, Up and PageUp start scrolling,
+// ctrl+\ opens the (reduced) menu, ctrl+l redraws, and everything else is
+// discarded.
+func (c *Client) HandleReadOnlyBytes(buf []byte, start, n int) int {
+	for i := start; i < n; {
+		b := buf[i]
+		i++
+		if c.Pasting && b != 0x1B {
+			continue
+		}
+		switch b {
+		case 0x1B:
+			i += c.readOnlyEscape(buf[i:n])
+		case 0x1C: // ctrl+\ — open menu
+			c.setMode(ModeMenu)
+			c.RenderBar()
+		case 0x0C: // ctrl+l — clear and redraw
+			c.Redraw()
+		}
+	}
+	return n
+}
+
+// readOnlyEscape handles the bytes after an ESC for a read-only client and
+// returns how many it consumed. Mouse events, Up, PageUp and Ctrl+Enter
+// are acted on; any other sequence is swallowed.
+func (c *Client) readOnlyEscape(remaining []byte) int {
+	if len(remaining) == 0 {
+		return 0
+	}
+	switch remaining[0] {
+	case '[':
+	case 'O':
+		return min(2, len(remaining))
+	default:
+		return 1
+	}
+	i := 1
+	for i < len(remaining) && remaining[i] >= 0x20 && remaining[i] <= 0x3F {
+		i++
+	}
+	if i >= len(remaining) {
+		return i
+	}
+	params := string(remaining[1:i])
+	switch final := remaining[i]; final {
+	case 'M', 'm':
+		c.HandleSGRMouse(remaining[1:i], final == 'M')
+	case 'A':
+		c.EnterScrollMode()
+		c.ScrollUp(1)
+	case 'u', '~':
+		switch params {
+		case "200", "201": // bracketed paste start/end
+			c.Pasting = params == "200"
+		case "5": // PageUp
+			c.pageUpDown(true)
+		case "13;5", "27;5;13": // Ctrl+Enter (kitty / modifyOtherKeys) — open menu
+			c.setMode(ModeMenu)
+			c.RenderBar()
+		}
+	}
+	return i + 1
+}
+
 func (c *Client) FlushPassthroughEscIfComplete() bool {
 	if len(c.PassthroughEsc) == 0 {
 		return false
@@ -369,8 +726,17 @@ func (c *Client) FlushPassthroughEscIfComplete() bool {
 		c.HandleSGRMouse(params, press)
 		return true
 	}
-	if virtualterminal.IsShiftEnterSequence(c.PassthroughEsc) {
-		c.writePTYOrHang([]byte{'\n'})
+	if virtualterminal.IsPasteMarker(c.PassthroughEsc) {
+		c.Pasting = string(c.PassthroughEsc) == virtualterminal.PasteStart
+		if !c.VT.BracketedPaste {
+			// The child didn't ask for bracketed paste; drop the markers
+			// and forward only the pasted text.
+			c.PassthroughEsc = c.PassthroughEsc[:0]
+			return true
+		}
+	}
+	if virtualterminal.IsModifierEnterSequence(c.PassthroughEsc) {
+		c.modifierEnter()
 	} else {
 		c.writePTYOrHang(c.PassthroughEsc)
 	}
@@ -378,6 +744,29 @@ func (c *Client) FlushPassthroughEscIfComplete() bool {
 	return true
 }
 
+// modifierEnter applies the configured ModifierEnter action for a
+// Shift+Enter or Alt+Enter.
+func (c *Client) modifierEnter() {
+	if c.Mode == ModeNormal && c.Composing {
+		// In compose mode Shift+Enter / Alt+Enter send the buffer.
+		c.submitCompose()
+		return
+	}
+	switch c.ModifierEnter {
+	case virtualterminal.EnterForwardCR:
+		c.writePTYOrHang([]byte{'\r'})
+	case virtualterminal.EnterForwardLF:
+		c.writePTYOrHang([]byte{'\n'})
+	default:
+		if c.Mode == ModeNormal {
+			c.InsertByte('\n')
+			c.RenderBar()
+			return
+		}
+		c.writePTYOrHang(c.SubmitNewline.SoftNewline())
+	}
+}
+
 // HandleEscape processes bytes following an ESC (0x1B).
 func (c *Client) HandleEscape(remaining []byte) (consumed int, handled bool) {
 	if len(remaining) == 0 {
@@ -387,6 +776,12 @@ func (c *Client) HandleEscape(remaining []byte) (consumed int, handled bool) {
 	switch remaining[0] {
 	case '[':
 		return c.HandleCSI(remaining[1:])
+	case '\r', '\n': // Alt+Enter
+		if c.Mode == ModeNormal {
+			c.modifierEnter()
+			return 1, true
+		}
+		return 0, false
 	case 'O':
 		if len(remaining) >= 2 {
 			return 2, true
@@ -432,6 +827,11 @@ func (c *Client) HandleCSI(remaining []byte) (consumed int, handled bool) {
 
 	params := string(remaining[:i])
 
+	if c.Mode == ModeNormal && virtualterminal.IsModifierEnterSequence(append([]byte{0x1B, '['}, remaining[:i+1]...)) {
+		c.modifierEnter()
+		return totalConsumed, true
+	}
+
 	switch final {
 	case 'A', 'B':
 		if c.Mode == ModePassthrough {
@@ -446,6 +846,15 @@ func (c *Client) HandleCSI(remaining []byte) (consumed int, handled bool) {
 			}
 			break
 		}
+		if c.Mode == ModeNormal && c.Composing {
+			if final == 'A' {
+				c.CursorUpLine()
+			} else {
+				c.CursorDownLine()
+			}
+			c.RenderBar()
+			break
+		}
 		if c.Mode == ModeNormal {
 			// Pass up/down arrow through to PTY (e.g. shell history).
 			c.writePTYOrHang(append([]byte{0x1B, '['}, remaining[:i+1]...))
@@ -467,6 +876,9 @@ func (c *Client) HandleCSI(remaining []byte) (consumed int, handled bool) {
 				c.writePTYOrHang(append([]byte{0x1B, '['}, remaining[:i+1]...))
 			}
 		}
+	case 'H', 'F':
+		// Home/End — jump to the ends of the input bar.
+		c.homeEnd(final == 'H', remaining[:i+1])
 	case 'u':
 		// Kitty keyboard protocol: CSI <code>;<modifiers> u
 		if params == "13;5" {
@@ -477,6 +889,33 @@ func (c *Client) HandleCSI(remaining []byte) (consumed int, handled bool) {
 			}
 		}
 	case '~':
+		if params == "200" || params == "201" {
+			// Bracketed paste start/end from the real terminal. Tracked in
+			// every mode so pasted text is never read as key bindings.
+			c.Pasting = params == "200"
+			c.RenderBar()
+			break
+		}
+		if params == "1" || params == "7" || params == "4" || params == "8" {
+			// Home/End in the rxvt/vt220 encodings.
+			c.homeEnd(params == "1" || params == "7", remaining[:i+1])
+			break
+		}
+		if params == "5" || params == "6" {
+			// PageUp/PageDown — scroll by a full window.
+			c.pageUpDown(params == "5")
+			break
+		}
+		if params == "2" {
+			// Insert key — toggle overwrite editing in the input bar.
+			if c.Mode == ModePassthrough {
+				c.writePTYOrHang(append([]byte{0x1B, '['}, remaining[:i+1]...))
+			} else if c.Mode == ModeNormal {
+				c.Overwrite = !c.Overwrite
+				c.RenderBar()
+			}
+			break
+		}
 		// xterm modifyOtherKeys format: CSI 27;<modifiers>;<code> ~
 		if params == "27;5;13" {
 			// Ctrl+Enter — open menu in normal mode.
@@ -492,6 +931,48 @@ func (c *Client) HandleCSI(remaining []byte) (consumed int, handled bool) {
 	return totalConsumed, true
 }
 
+// homeEnd moves the input cursor to the start (home) or end of the input
+// bar. Like the arrow keys, it passes through to the PTY when the input is
+// empty or in passthrough mode. In scroll mode Home jumps to the top of the
+// scrollback and End returns to the live view.
+func (c *Client) homeEnd(home bool, seq []byte) {
+	if c.IsScrollMode() {
+		if home {
+			c.ScrollToTop()
+		} else {
+			c.ExitScrollMode()
+		}
+		return
+	}
+	if c.Mode == ModePassthrough || (c.Mode == ModeNormal && len(c.Input) == 0) {
+		c.writePTYOrHang(append([]byte{0x1B, '['}, seq...))
+		return
+	}
+	if c.Mode != ModeNormal {
+		return
+	}
+	if home {
+		c.CursorToStart()
+	} else {
+		c.CursorToEnd()
+	}
+	c.RenderBar()
+}
+
+// pageUpDown scrolls by a full window: PageUp enters scroll mode from the
+// live view, like the mouse wheel, and PageDown past the bottom leaves it.
+func (c *Client) pageUpDown(up bool) {
+	switch {
+	case up && c.Mode == ModeNormal:
+		c.EnterScrollMode()
+		c.ScrollUp(c.viewRows())
+	case up && c.IsScrollMode():
+		c.ScrollUp(c.viewRows())
+	case c.IsScrollMode():
+		c.ScrollDown(c.viewRows())
+	}
+}
+
 // priorityOrder defines the Tab cycling order for input priorities.
 var priorityOrder = []message.Priority{
 	message.PriorityNormal,
@@ -512,7 +993,9 @@ func (c *Client) CyclePriority() {
 }
 
 // HandleScrollBytes processes input when in scroll mode.
-// Esc or q exits scroll mode. Arrow keys scroll. All other input is ignored.
+// Esc or q exits scroll mode. Arrow keys scroll by a line, PageUp/PageDown
+// by a window, and Home/End jump to the top and bottom. / searches the scrollback
+// and n/N move between matches. All other input is ignored.
 func (c *Client) HandleScrollBytes(buf []byte, start, n int) int {
 	for i := start; i < n; {
 		b := buf[i]
@@ -531,7 +1014,19 @@ func (c *Client) HandleScrollBytes(buf []byte, start, n int) int {
 		}
 
 		i++
+		if c.Pasting && b != 0x1B {
+			continue
+		}
+		if c.scrollSearch != nil && c.scrollSearch.editing && c.handleScrollSearchByte(b, buf[i:n]) {
+			continue
+		}
 		switch b {
+		case '/':
+			c.StartScrollSearch()
+			c.RenderBar()
+		case 'n', 'N':
+			c.ScrollSearchNext(b == 'n')
+			c.RenderBar()
 		case 0x1B:
 			if i < n {
 				// More data in buffer — try to parse escape sequence.
@@ -547,7 +1042,7 @@ func (c *Client) HandleScrollBytes(buf []byte, start, n int) int {
 			}
 		default:
 			// Pass control characters through to the PTY.
-			if b < 0x20 && !c.VT.ChildExited && !c.VT.ChildHung {
+			if b < 0x20 && !c.ReadOnly && !c.VT.ChildExited && !c.VT.ChildHung {
 				if !c.writePTYOrHang([]byte{b}) {
 					return n
 				}
@@ -566,10 +1061,31 @@ func (c *Client) EnterScrollMode() {
 		c.setMode(ModeScroll)
 	}
 	c.ScrollOffset = 0
+	c.markScrollBottom()
+	c.RenderScreen()
+	c.RenderBar()
+}
+
+// ReleaseIdlePassthrough drops this client out of passthrough (or
+// passthrough scroll) back to default mode because the lock sat idle.
+func (c *Client) ReleaseIdlePassthrough() {
+	c.ScrollOffset = 0
+	c.PassthroughEsc = c.PassthroughEsc[:0]
+	c.setMode(ModeNormal)
+	c.warn("passthrough released (idle)")
 	c.RenderScreen()
 	c.RenderBar()
 }
 
+// LeavePassthrough drops this client out of passthrough (or passthrough
+// scroll) back to default mode because another client took control.
+func (c *Client) LeavePassthrough() {
+	c.ScrollOffset = 0
+	c.PassthroughEsc = c.PassthroughEsc[:0]
+	c.setMode(ModeNormal)
+	c.RenderBar()
+}
+
 // ExitScrollMode returns to the appropriate mode and re-renders the live view.
 // ModePassthroughScroll restores ModePassthrough; ModeScroll restores ModeNormal.
 func (c *Client) ExitScrollMode() {
@@ -596,6 +1112,11 @@ func (c *Client) ScrollUp(lines int) {
 	c.RenderBar()
 }
 
+// ScrollToTop moves the scroll view to the oldest scrollback line.
+func (c *Client) ScrollToTop() {
+	c.ScrollUp(c.maxScrollOffset())
+}
+
 // ScrollDown moves the scroll view down by the given number of lines.
 // If we reach the bottom (offset 0), exits scroll mode.
 func (c *Client) ScrollDown(lines int) {
@@ -609,16 +1130,43 @@ func (c *Client) ScrollDown(lines int) {
 	c.RenderBar()
 }
 
+// FollowOutput applies the ScrollOnOutput policy after new child output
+// while the client is in scroll mode. ScrollFollow returns to the live
+// view; ScrollStay grows ScrollOffset by the lines added below the view
+// so the same history stays on screen. Called with VT.Mu held.
+func (c *Client) FollowOutput() {
+	if !c.IsScrollMode() {
+		return
+	}
+	if c.ScrollOnOutput == virtualterminal.ScrollFollow {
+		c.ExitScrollMode()
+		return
+	}
+	if c.VT.Scrollback != nil {
+		if added := c.VT.Scrollback.Cursor.Y - c.scrollBottom; added > 0 {
+			c.ScrollOffset += added
+		}
+	}
+	c.ClampScrollOffset()
+	c.markScrollBottom()
+}
+
+// markScrollBottom records the scrollback bottom that ScrollOffset is
+// currently measured from.
+func (c *Client) markScrollBottom() {
+	c.scrollBottom = 0
+	if c.VT.Scrollback != nil {
+		c.scrollBottom = c.VT.Scrollback.Cursor.Y
+	}
+}
+
 // ClampScrollOffset ensures ScrollOffset is within valid bounds.
 func (c *Client) ClampScrollOffset() {
 	if c.VT.Scrollback == nil {
 		c.ScrollOffset = 0
 		return
 	}
-	maxOffset := c.VT.Scrollback.Cursor.Y - c.VT.ChildRows + 1
-	if maxOffset < 0 {
-		maxOffset = 0
-	}
+	maxOffset := c.maxScrollOffset()
 	if c.ScrollOffset > maxOffset {
 		c.ScrollOffset = maxOffset
 	}
@@ -627,6 +1175,15 @@ func (c *Client) ClampScrollOffset() {
 	}
 }
 
+// maxScrollOffset returns the ScrollOffset that shows the top of the
+// scrollback.
+func (c *Client) maxScrollOffset() int {
+	if c.VT.Scrollback == nil {
+		return 0
+	}
+	return max(c.VT.Scrollback.Cursor.Y-c.viewRows()+1, 0)
+}
+
 // isSGRMouseSequence returns true if seq is an SGR mouse event
 // (ESC [ < Cb;Cx;Cy M/m).
 func isSGRMouseSequence(seq []byte) bool {
@@ -667,14 +1224,22 @@ func (c *Client) HandleSGRMouse(params []byte, press bool) {
 		if !c.IsScrollMode() {
 			c.EnterScrollMode()
 		}
-		c.ScrollUp(scrollStep)
+		c.ScrollUp(c.wheelStep())
 	case 65: // scroll down
 		if c.IsScrollMode() {
-			c.ScrollDown(scrollStep)
+			c.ScrollDown(c.wheelStep())
 		}
 	}
 }
 
+// wheelStep returns the lines scrolled per mouse wheel notch.
+func (c *Client) wheelStep() int {
+	if c.ScrollStep > 0 {
+		return c.ScrollStep
+	}
+	return DefaultScrollStep
+}
+
 // ShowSelectHint displays a transient hint about using shift for text selection.
 func (c *Client) ShowSelectHint() {
 	c.SelectHint = true
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCtrlL_RedrawsInDefaultMode(t *testing.T) {
+	o := newTestClient(10, 80)
+	var out bytes.Buffer
+	o.Output = &out
+	o.Input = []byte("draft")
+	o.CursorPos = 3
+
+	o.HandleDefaultBytes([]byte{0x0C}, 0, 1)
+
+	if !strings.Contains(out.String(), "\033[2J") {
+		t.Fatalf("expected screen clear in output, got %q", out.String())
+	}
+	if string(o.Input) != "draft" || o.CursorPos != 3 {
+		t.Errorf("input changed to %q (cursor %d), want %q (cursor 3)", o.Input, o.CursorPos, "draft")
+	}
+	if o.Mode != ModeNormal {
+		t.Errorf("mode = %v, want ModeNormal", o.Mode)
+	}
+}
+
+func TestCtrlL_ForwardedInPassthrough(t *testing.T) {
+	o := newTestClient(10, 80)
+	o.Mode = ModePassthrough
+	var out bytes.Buffer
+	o.Output = &out
+	r := pipePTY(t, o)
+
+	o.HandlePassthroughBytes([]byte{0x0C}, 0, 1)
+
+	if got := readPTY(t, r, 1); got[0] != 0x0C {
+		t.Fatalf("PTY got %q, want ctrl+l", got)
+	}
+	if strings.Contains(out.String(), "\033[2J") {
+		t.Error("passthrough ctrl+l should not redraw locally")
+	}
+}
+
+// writeRecorder records each Write call separately.
+type writeRecorder struct {
+	writes []string
+}
+
+func (w *writeRecorder) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, string(p))
+	return len(p), nil
+}
+
+func TestRedraw_FullRepaintInOneWrite(t *testing.T) {
+	o := newTestClient(10, 80)
+	var out writeRecorder
+	o.Output = &out
+
+	o.Redraw()
+
+	if len(out.writes) != 1 {
+		t.Fatalf("redraw made %d writes, want 1", len(out.writes))
+	}
+	got := out.writes[0]
+	for _, want := range []string{"\033[?1049l", "\033[r", TerminalModesOn, "\033[2J\033[H"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("redraw output missing %q", want)
+		}
+	}
+	if strings.Contains(got, "\033[>1u") {
+		t.Error("kitty keyboard mode pushed on a terminal without it")
+	}
+	if o.Output != &out {
+		t.Error("redraw did not restore the client's output")
+	}
+}
+
+func TestRedraw_KittyKeyboardRestored(t *testing.T) {
+	o := newTestClient(10, 80)
+	o.KittyKeyboard = true
+	var out bytes.Buffer
+	o.Output = &out
+
+	o.Redraw()
+
+	if !strings.Contains(out.String(), "\033[<u\033[>1u") {
+		t.Fatalf("expected kitty keyboard mode re-pushed, got %q", out.String())
+	}
+}
+
+func TestRedraw_LeavesScrollMode(t *testing.T) {
+	o := newTestClient(10, 80)
+	o.Mode = ModeScroll
+	o.ScrollOffset = 4
+
+	o.Redraw()
+
+	if o.Mode != ModeNormal || o.ScrollOffset != 0 {
+		t.Fatalf("mode=%v offset=%d, want ModeNormal at offset 0", o.Mode, o.ScrollOffset)
+	}
+
+	o.Mode = ModePassthroughScroll
+	o.ScrollOffset = 2
+	o.Redraw()
+	if o.Mode != ModePassthrough || o.ScrollOffset != 0 {
+		t.Fatalf("mode=%v offset=%d, want ModePassthrough at offset 0", o.Mode, o.ScrollOffset)
+	}
+}
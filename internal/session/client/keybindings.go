@@ -17,16 +17,19 @@ const (
 type KeybindingHelp struct {
 	NormalMode      string
 	PassthroughMode string
+	ReadOnlyMode    string
 }
 
 var keybindingHelpText = map[KeybindingMode]KeybindingHelp{
 	KeybindingsLegacy: {
 		NormalMode:      `Enter send | Ctrl+\ menu`,
 		PassthroughMode: `Ctrl+\ exit`,
+		ReadOnlyMode:    `Wheel/Up scroll | Ctrl+\ menu`,
 	},
 	KeybindingsKitty: {
 		NormalMode:      "Enter send | Ctrl+Enter menu",
 		PassthroughMode: "Ctrl+Esc exit",
+		ReadOnlyMode:    "Wheel/Up scroll | Ctrl+Enter menu",
 	},
 }
 
@@ -0,0 +1,49 @@
+package client
+
+import "time"
+
+// DefaultMaxInputLen caps the input bar so an accidental huge paste can't
+// grow Input without bound.
+const DefaultMaxInputLen = 16 * 1024
+
+// warningDuration is how long a bar warning stays visible.
+const warningDuration = 3 * time.Second
+
+// Binary paste detection: a read chunk at least binaryPasteMinLen bytes long
+// where more than binaryPasteRatio of the bytes are control characters
+// (other than tab/CR/LF/ESC) is treated as binary and refused.
+const (
+	binaryPasteMinLen = 32
+	binaryPasteRatio  = 0.3
+)
+
+// warn shows a brief warning in the status bar.
+func (c *Client) warn(msg string) {
+	c.Warning = msg
+	c.WarningAt = time.Now()
+}
+
+// activeWarning returns the current bar warning, or "" once it has expired.
+func (c *Client) activeWarning() string {
+	if c.Warning == "" || time.Since(c.WarningAt) > warningDuration {
+		return ""
+	}
+	return c.Warning
+}
+
+// isBinaryPaste reports whether chunk looks like pasted binary data rather
+// than typed keys or text.
+func isBinaryPaste(chunk []byte) bool {
+	if len(chunk) < binaryPasteMinLen {
+		return false
+	}
+	control := 0
+	for _, b := range chunk {
+		switch {
+		case b == '\t' || b == '\r' || b == '\n' || b == 0x1B:
+		case b < 0x20 || b == 0x7F:
+			control++
+		}
+	}
+	return float64(control) > binaryPasteRatio*float64(len(chunk))
+}
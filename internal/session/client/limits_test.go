@@ -0,0 +1,94 @@
+package client
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestInputCap_StopsAtLimit(t *testing.T) {
+	o := newTestClient(10, 80)
+	o.MaxInputLen = 8
+	buf := []byte("hello world")
+	o.HandleDefaultBytes(buf, 0, len(buf))
+
+	if string(o.Input) != "hello wo" {
+		t.Fatalf("expected input capped to %q, got %q", "hello wo", string(o.Input))
+	}
+	if o.activeWarning() == "" {
+		t.Fatal("expected a warning after hitting the limit")
+	}
+}
+
+func TestInputCap_LargeTextPasteAcceptedUpToCap(t *testing.T) {
+	o := newTestClient(10, 80)
+	o.MaxInputLen = 100
+	paste := []byte(strings.Repeat("lorem ipsum ", 20)) // 240 bytes of text
+	o.HandleDefaultBytes(paste, 0, len(paste))
+
+	if len(o.Input) != 100 {
+		t.Fatalf("expected input length 100, got %d", len(o.Input))
+	}
+	if !bytes.Equal(o.Input, paste[:100]) {
+		t.Fatalf("expected input to be the first 100 bytes of the paste")
+	}
+}
+
+func TestInputCap_ZeroIsUnlimited(t *testing.T) {
+	o := newTestClient(10, 80)
+	o.MaxInputLen = 0
+	paste := []byte(strings.Repeat("x", 5000))
+	o.HandleDefaultBytes(paste, 0, len(paste))
+	if len(o.Input) != 5000 {
+		t.Fatalf("expected 5000 bytes, got %d", len(o.Input))
+	}
+}
+
+func TestBinaryPaste_Rejected(t *testing.T) {
+	o := newTestClient(10, 80)
+	o.MaxInputLen = DefaultMaxInputLen
+	blob := make([]byte, 64)
+	for i := range blob {
+		blob[i] = byte(i % 8) // mostly NUL and other control bytes
+	}
+	blob[10], blob[20], blob[30] = 'a', 'b', 'c'
+	o.HandleDefaultBytes(blob, 0, len(blob))
+
+	if len(o.Input) != 0 {
+		t.Fatalf("expected binary paste to be refused, got %d bytes of input", len(o.Input))
+	}
+	if o.Mode != ModeNormal {
+		t.Fatalf("binary paste should not change mode, got %d", o.Mode)
+	}
+	if got := o.activeWarning(); got != "binary paste rejected" {
+		t.Fatalf("expected binary paste warning, got %q", got)
+	}
+}
+
+func TestBinaryPaste_ShortControlBurstAllowed(t *testing.T) {
+	// A few control keys typed in one read are not a paste.
+	if isBinaryPaste([]byte{0x01, 0x05, 0x0B}) {
+		t.Fatal("short control sequences should not be treated as binary")
+	}
+}
+
+func TestBinaryPaste_MultilineTextAllowed(t *testing.T) {
+	text := []byte(strings.Repeat("line of text\r\n\tindented\n", 4))
+	if isBinaryPaste(text) {
+		t.Fatal("multiline text should not be treated as binary")
+	}
+}
+
+func TestInputCap_WarningShownInBar(t *testing.T) {
+	o := newTestClient(10, 120)
+	o.MaxInputLen = 1
+	var out bytes.Buffer
+	o.Output = &out
+	o.HandleDefaultBytes([]byte("ab"), 0, 2)
+	out.Reset()
+
+	o.RenderBar()
+	if !strings.Contains(out.String(), "input limit reached") {
+		t.Fatalf("expected warning in bar, got %q", out.String())
+	}
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// newMenuTestClient returns a client in menu mode whose VT has a running
+// child, so quit can be observed via the child's exit.
+func newMenuTestClient(t *testing.T) (*Client, *exec.Cmd, *bool) {
+	t.Helper()
+	o := newTestClient(10, 80)
+	o.ConfirmQuit = true
+	o.Mode = ModeMenu
+
+	cmd := exec.Command("sleep", "60")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	o.VT.Cmd = cmd
+
+	quitCalled := false
+	o.OnQuit = func() { quitCalled = true }
+	return o, cmd, &quitCalled
+}
+
+func TestMenuQuit_SingleQDoesNotSignal(t *testing.T) {
+	o, cmd, quitCalled := newMenuTestClient(t)
+
+	o.HandleMenuBytes([]byte{'q'}, 0, 1)
+
+	if *quitCalled || o.Quit {
+		t.Fatal("single q should not quit when confirmation is enabled")
+	}
+	if !o.QuitPending {
+		t.Fatal("expected QuitPending after first q")
+	}
+	if o.Mode != ModeMenu {
+		t.Fatalf("expected to remain in menu mode, got %v", o.Mode)
+	}
+	if !strings.Contains(o.MenuLabel(), "confirm") {
+		t.Errorf("menu label should prompt for confirmation, got %q", o.MenuLabel())
+	}
+	if cmd.ProcessState != nil {
+		t.Fatal("child should still be running")
+	}
+}
+
+func TestMenuQuit_SecondQConfirms(t *testing.T) {
+	o, cmd, quitCalled := newMenuTestClient(t)
+
+	o.HandleMenuBytes([]byte{'q', 'q'}, 0, 2)
+
+	if !*quitCalled || !o.Quit {
+		t.Fatal("expected quit after confirming q")
+	}
+	// The session owns the graceful shutdown; the client must not signal.
+	if cmd.ProcessState != nil {
+		t.Fatal("child should be left for the session to stop")
+	}
+}
+
+func TestMenuQuit_WithoutSessionSignalsChild(t *testing.T) {
+	o, cmd, _ := newMenuTestClient(t)
+	o.OnQuit = nil
+
+	o.HandleMenuBytes([]byte{'q', 'q'}, 0, 2)
+
+	if err := cmd.Wait(); err == nil || !strings.Contains(err.Error(), "terminated") {
+		t.Fatalf("expected child terminated by SIGTERM, got %v", err)
+	}
+}
+
+func TestMenuQuit_OtherKeyCancels(t *testing.T) {
+	o, _, quitCalled := newMenuTestClient(t)
+
+	o.HandleMenuBytes([]byte{'q'}, 0, 1)
+	o.HandleMenuBytes([]byte{'x'}, 0, 1)
+
+	if *quitCalled || o.Quit {
+		t.Fatal("cancelling key should not quit")
+	}
+	if o.QuitPending {
+		t.Fatal("expected QuitPending cleared after cancel")
+	}
+	if o.Mode != ModeNormal {
+		t.Fatalf("expected normal mode after cancel, got %v", o.Mode)
+	}
+}
+
+func TestMenuQuit_ConfirmDisabledQuitsImmediately(t *testing.T) {
+	o, _, quitCalled := newMenuTestClient(t)
+	o.ConfirmQuit = false
+
+	o.HandleMenuBytes([]byte{'q'}, 0, 1)
+
+	if !*quitCalled || !o.Quit {
+		t.Fatal("expected immediate quit with confirm_quit disabled")
+	}
+}
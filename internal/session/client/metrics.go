@@ -0,0 +1,60 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// RenderStats counts render-path work, for diagnosing slow renders (e.g.
+// over SSH). Fields are updated under VT.Mu along with the rest of the
+// render state; read them under the same lock.
+type RenderStats struct {
+	Frames       uint64        // full-screen renders (RenderScreen calls)
+	BytesWritten uint64        // bytes written by RenderScreen and RenderBar
+	RenderTime   time.Duration // cumulative time spent in RenderScreen
+}
+
+// AvgRenderTime returns the mean RenderScreen duration, or 0 before the
+// first frame.
+func (s RenderStats) AvgRenderTime() time.Duration {
+	if s.Frames == 0 {
+		return 0
+	}
+	return s.RenderTime / time.Duration(s.Frames)
+}
+
+// Add returns the sum of two stats, for aggregating across clients.
+func (s RenderStats) Add(o RenderStats) RenderStats {
+	return RenderStats{
+		Frames:       s.Frames + o.Frames,
+		BytesWritten: s.BytesWritten + o.BytesWritten,
+		RenderTime:   s.RenderTime + o.RenderTime,
+	}
+}
+
+// recordFrame accounts for one RenderScreen call.
+func (c *Client) recordFrame(start time.Time, n int) {
+	c.Stats.Frames++
+	c.Stats.BytesWritten += uint64(n)
+	c.Stats.RenderTime += time.Since(start)
+}
+
+// RenderStatsLabel returns the render stats line for the debug overlay.
+// It is plain ASCII so its byte length equals its display width.
+func (c *Client) RenderStatsLabel() string {
+	s := c.Stats
+	return fmt.Sprintf(" render: %d frames | %s written | avg %s",
+		s.Frames, formatBytes(s.BytesWritten), s.AvgRenderTime().Round(time.Microsecond))
+}
+
+// formatBytes renders a byte count with a binary unit suffix.
+func formatBytes(n uint64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1fMB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1fKB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%dB", n)
+	}
+}
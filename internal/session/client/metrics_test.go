@@ -0,0 +1,109 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderStats_CountsFrames(t *testing.T) {
+	o := newTestClient(5, 40)
+	var out bytes.Buffer
+	o.Output = &out
+	o.VT.Vt.Write([]byte("hello"))
+
+	for i := 0; i < 3; i++ {
+		o.RenderScreen()
+	}
+
+	if o.Stats.Frames != 3 {
+		t.Fatalf("Frames = %d, want 3", o.Stats.Frames)
+	}
+	if o.Stats.BytesWritten != uint64(out.Len()) {
+		t.Fatalf("BytesWritten = %d, want %d", o.Stats.BytesWritten, out.Len())
+	}
+	if o.Stats.RenderTime <= 0 {
+		t.Fatal("expected RenderTime to accumulate")
+	}
+}
+
+func TestRenderStats_BarBytesCounted(t *testing.T) {
+	o := newTestClient(5, 40)
+	var out bytes.Buffer
+	o.Output = &out
+
+	o.RenderBar()
+
+	if o.Stats.Frames != 0 {
+		t.Fatalf("RenderBar should not count as a frame, got %d", o.Stats.Frames)
+	}
+	if o.Stats.BytesWritten != uint64(out.Len()) {
+		t.Fatalf("BytesWritten = %d, want %d", o.Stats.BytesWritten, out.Len())
+	}
+}
+
+func TestRenderStats_AvgRenderTime(t *testing.T) {
+	s := RenderStats{Frames: 4, RenderTime: 8 * time.Millisecond}
+	if got := s.AvgRenderTime(); got != 2*time.Millisecond {
+		t.Fatalf("AvgRenderTime = %v, want 2ms", got)
+	}
+	if got := (RenderStats{}).AvgRenderTime(); got != 0 {
+		t.Fatalf("AvgRenderTime with no frames = %v, want 0", got)
+	}
+}
+
+// debugRowText extracts the text written to the debug row by RenderBar.
+func debugRowText(t *testing.T, o *Client, out string) string {
+	t.Helper()
+	marker := fmt.Sprintf("\033[%d;1H\033[2K", o.VT.Rows)
+	i := strings.LastIndex(out, marker)
+	if i < 0 {
+		t.Fatalf("debug row not rendered in %q", out)
+	}
+	row := out[i+len(marker):]
+	if j := strings.Index(row, "\033["); j >= 0 {
+		row = row[:j]
+	}
+	return row
+}
+
+func TestDebugRender_OverlayShowsStats(t *testing.T) {
+	o := newTestClient(5, 80)
+	o.DebugRender = true
+	var out bytes.Buffer
+	o.Output = &out
+	o.RenderScreen()
+	o.RenderScreen()
+	out.Reset()
+
+	o.RenderBar()
+
+	if o.ReservedRows() != 3 {
+		t.Fatalf("ReservedRows = %d, want 3 with debug overlay", o.ReservedRows())
+	}
+	row := debugRowText(t, o, out.String())
+	if !strings.Contains(row, "render: 2 frames") {
+		t.Fatalf("debug row missing stats: %q", row)
+	}
+	if len(row) != o.VT.Cols {
+		t.Fatalf("debug row width = %d, want %d", len(row), o.VT.Cols)
+	}
+}
+
+func TestDebugRender_OverlayFitsNarrowTerminal(t *testing.T) {
+	o := newTestClient(5, 20)
+	o.DebugRender = true
+	o.DebugKeys = true
+	o.DebugKeyBuf = []string{"a", "b"}
+	var out bytes.Buffer
+	o.Output = &out
+
+	o.RenderBar()
+
+	row := debugRowText(t, o, out.String())
+	if len(row) != o.VT.Cols {
+		t.Fatalf("debug row width = %d, want %d (%q)", len(row), o.VT.Cols, row)
+	}
+}
@@ -0,0 +1,208 @@
+package client
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+
+	"h2/internal/session/message"
+	"h2/internal/session/virtualterminal"
+)
+
+// pipePTY points the client's PTY at a pipe and returns the read end.
+func pipePTY(t *testing.T, o *Client) *os.File {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close(); w.Close() })
+	o.VT.Ptm = w
+	return r
+}
+
+// readPTY reads exactly want bytes from the PTY pipe, failing on timeout.
+func readPTY(t *testing.T, r *os.File, want int) []byte {
+	t.Helper()
+	r.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 0, want)
+	tmp := make([]byte, 64)
+	for len(buf) < want {
+		n, err := r.Read(tmp)
+		if err != nil {
+			t.Fatalf("read PTY after %q: %v", buf, err)
+		}
+		buf = append(buf, tmp[:n]...)
+	}
+	return buf
+}
+
+var submitNewlineCases = []struct {
+	setting virtualterminal.SubmitNewline
+	submit  string
+	soft    string
+}{
+	{"", "\r", "\n"},
+	{virtualterminal.SubmitCR, "\r", "\n"},
+	{virtualterminal.SubmitLF, "\n", "\n"},
+	{virtualterminal.SubmitCRLF, "\r\n", "\r\n"},
+}
+
+func TestSubmitNewline_NormalSubmit(t *testing.T) {
+	for _, tc := range submitNewlineCases {
+		o := newTestClient(10, 80)
+		o.SubmitNewline = tc.setting
+		o.InputPriority = message.PriorityNormal
+		r := pipePTY(t, o)
+
+		buf := []byte("hi\r")
+		o.HandleDefaultBytes(buf, 0, len(buf))
+
+		want := "hi" + tc.submit
+		if got := readPTY(t, r, len(want)); !bytes.Equal(got, []byte(want)) {
+			t.Errorf("setting %q: PTY got %q, want %q", tc.setting, got, want)
+		}
+	}
+}
+
+func TestSubmitNewline_EmptyEnter(t *testing.T) {
+	for _, tc := range submitNewlineCases {
+		o := newTestClient(10, 80)
+		o.SubmitNewline = tc.setting
+		r := pipePTY(t, o)
+
+		o.HandleDefaultBytes([]byte{'\r'}, 0, 1)
+
+		if got := readPTY(t, r, len(tc.submit)); string(got) != tc.submit {
+			t.Errorf("setting %q: PTY got %q, want %q", tc.setting, got, tc.submit)
+		}
+	}
+}
+
+func TestSubmitNewline_PassthroughEnterAndShiftEnter(t *testing.T) {
+	for _, tc := range submitNewlineCases {
+		o := newTestClient(10, 80)
+		o.SubmitNewline = tc.setting
+		o.Mode = ModePassthrough
+		r := pipePTY(t, o)
+
+		// Kitty-style Shift+Enter followed by a plain Enter.
+		buf := []byte("\x1b[13;2u\r")
+		o.HandlePassthroughBytes(buf, 0, len(buf))
+
+		want := tc.soft + tc.submit
+		if got := readPTY(t, r, len(want)); string(got) != want {
+			t.Errorf("setting %q: PTY got %q, want %q", tc.setting, got, want)
+		}
+	}
+}
+
+// modifierEnterEncodings are the Shift+Enter and Alt+Enter byte sequences
+// bound to the modifier_enter action.
+var modifierEnterEncodings = []string{
+	"\x1b[13;2u",
+	"\x1b[27;2;13~",
+	"\x1b[13;3u",
+	"\x1b[27;3;13~",
+	"\x1b\r",
+}
+
+func TestModifierEnter_NormalMode(t *testing.T) {
+	for _, enc := range modifierEnterEncodings {
+		for _, tc := range []struct {
+			action virtualterminal.EnterAction
+			pty    string // bytes written to the child ("" = none)
+			input  string // resulting input bar contents
+		}{
+			{"", "", "a\nb"},
+			{virtualterminal.EnterInsertNewline, "", "a\nb"},
+			{virtualterminal.EnterForwardCR, "\r", "ab"},
+			{virtualterminal.EnterForwardLF, "\n", "ab"},
+		} {
+			o := newTestClient(10, 80)
+			o.ModifierEnter = tc.action
+			r := pipePTY(t, o)
+
+			buf := []byte("a" + enc + "b")
+			o.HandleDefaultBytes(buf, 0, len(buf))
+
+			if got := string(o.Input); got != tc.input {
+				t.Errorf("%q action %q: input = %q, want %q", enc, tc.action, got, tc.input)
+			}
+			if tc.pty != "" {
+				if got := readPTY(t, r, len(tc.pty)); string(got) != tc.pty {
+					t.Errorf("%q action %q: PTY got %q, want %q", enc, tc.action, got, tc.pty)
+				}
+			}
+		}
+	}
+}
+
+func TestModifierEnter_Passthrough(t *testing.T) {
+	for _, enc := range modifierEnterEncodings {
+		for _, tc := range []struct {
+			action virtualterminal.EnterAction
+			pty    string
+		}{
+			{"", "\n"}, // no input bar: the soft newline reaches the child
+			{virtualterminal.EnterInsertNewline, "\n"},
+			{virtualterminal.EnterForwardCR, "\r"},
+			{virtualterminal.EnterForwardLF, "\n"},
+		} {
+			o := newTestClient(10, 80)
+			o.ModifierEnter = tc.action
+			o.Mode = ModePassthrough
+			r := pipePTY(t, o)
+
+			buf := []byte("a" + enc + "b")
+			o.HandlePassthroughBytes(buf, 0, len(buf))
+
+			want := "a" + tc.pty + "b"
+			if got := readPTY(t, r, len(want)); string(got) != want {
+				t.Errorf("%q action %q: PTY got %q, want %q", enc, tc.action, got, want)
+			}
+		}
+	}
+}
+
+func TestSubmitDelay_ZeroWritesInline(t *testing.T) {
+	o := newTestClient(10, 80)
+	o.InputPriority = message.PriorityNormal
+	r := pipePTY(t, o)
+
+	buf := []byte("hi\r")
+	o.HandleDefaultBytes(buf, 0, len(buf))
+
+	// The submit must already be in the pipe when HandleDefaultBytes returns.
+	r.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	got := make([]byte, 8)
+	n, err := r.Read(got)
+	if err != nil {
+		t.Fatalf("read PTY: %v", err)
+	}
+	if string(got[:n]) != "hi\r" {
+		t.Errorf("PTY got %q, want %q written inline", got[:n], "hi\r")
+	}
+}
+
+func TestSubmitDelay_WaitsBeforeSubmit(t *testing.T) {
+	o := newTestClient(10, 80)
+	o.SubmitDelay = 100 * time.Millisecond
+	o.InputPriority = message.PriorityNormal
+	r := pipePTY(t, o)
+
+	start := time.Now()
+	buf := []byte("hi\r")
+	o.HandleDefaultBytes(buf, 0, len(buf))
+
+	if got := readPTY(t, r, 2); string(got) != "hi" {
+		t.Fatalf("PTY got %q, want %q", got, "hi")
+	}
+	if got := readPTY(t, r, 1); string(got) != "\r" {
+		t.Fatalf("PTY got %q, want submit", got)
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("submit arrived after %v, want the 100ms delay", elapsed)
+	}
+}
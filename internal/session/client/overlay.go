@@ -7,16 +7,24 @@ import (
 	"syscall"
 	"time"
 
-	"github.com/muesli/termenv"
 	"golang.org/x/term"
 
 	"h2/internal/session/message"
 	"h2/internal/session/virtualterminal"
 )
 
-// newTermOutput wraps termenv.NewOutput for testability.
-var newTermOutput = func(w *os.File) *termenv.Output {
-	return termenv.NewOutput(w)
+// applyTermColors records detected colors for OSC replies and sets
+// COLORFGBG for the child unless the user already set it.
+func (c *Client) applyTermColors(tc termColors) {
+	c.VT.OscFg = tc.fg
+	c.VT.OscBg = tc.bg
+	if os.Getenv("COLORFGBG") == "" {
+		colorfgbg := "0;15"
+		if tc.dark {
+			colorfgbg = "15;0"
+		}
+		os.Setenv("COLORFGBG", colorfgbg)
+	}
 }
 
 // InputMode represents the current input mode of the overlay.
@@ -44,26 +52,60 @@ type Client struct {
 	History     []string
 	HistIdx     int
 	Saved       []byte
+	search      *historySearch // active Ctrl+R search; nil otherwise
 	Quit        bool
+	ConfirmQuit bool // require a second q before quitting from the menu
+	QuitPending bool // menu quit selected, awaiting confirmation
+	QuotedInsert bool // ctrl+v pressed; next byte is inserted verbatim
+	Pasting      bool // inside a bracketed paste (any mode); in normal mode bytes go into Input literally
+	Composing    bool // multi-line compose: Enter inserts a line break, Ctrl+D sends
+	Overwrite    bool // typing replaces the character at the cursor (Insert key toggles)
+	PassthroughDisabled bool // role forbids passthrough; p/t are no-ops
+	ReadOnly     bool // observer: never writes to the agent; only scrolling, redraw, copy and detach work
+	MaxInputLen int       // cap on len(Input); 0 means unlimited
+	SubmitNewline virtualterminal.SubmitNewline // bytes written to the PTY on submit ("" = CR)
+	SubmitDelay   time.Duration                 // pause before the submit bytes; 0 writes them inline
+	ModifierEnter virtualterminal.EnterAction   // Shift+Enter / Alt+Enter behavior ("" = insert_newline)
+	Placeholder string    // dim hint shown after the prompt while Input is empty
+	Theme       *Theme    // bar and prompt colors (nil = DefaultTheme)
+	ThemeFile   string    // optional theme.yaml read by LoadTheme
+	DurationPrecision virtualterminal.DurationPrecision // idle-time format in the status label ("" = compact)
+	ScrollOnOutput virtualterminal.ScrollPolicy // scroll mode on new output: stay or follow ("" = stay)
+	Warning     string    // brief status-bar warning (e.g. rejected paste)
+	WarningAt   time.Time // when Warning was set
+	spinnerFrame int      // activity spinner position, advanced by TickSpinner
 	Mode        InputMode
 	PendingEsc     bool
 	EscTimer       *time.Timer
 	PassthroughEsc []byte
+	PassthroughActiveAt time.Time // last keystroke (or entry) while holding passthrough
 	ScrollOffset    int
+	scrollBottom    int // Scrollback.Cursor.Y the ScrollOffset was measured from
+	scrollSearch    *scrollSearch // search in scroll mode (/); nil otherwise
+	ScrollStep      int           // lines per mouse wheel notch; 0 = DefaultScrollStep
 	SelectHint      bool
 	SelectHintTimer *time.Timer
 	InputPriority   message.Priority
 	DebugKeys     bool
+	DebugRender   bool        // show render stats in the debug row (H2_DEBUG_RENDER)
+	TrimLines     bool        // omit plain trailing blanks from rendered rows
+	Stats         RenderStats // render-path counters
 	DebugKeyBuf  []string
 	AgentName    string
+	Label        string // identifies this client to others (e.g. who holds control)
 	OnModeChange func(mode InputMode)
 	QueueStatus  func() (int, bool)
+	QueueSummary func() message.PendingSummary // per-priority counts and the next message, for the bar preview
+	PassthroughCountdown func() (time.Duration, bool) // time left before an idle passthrough lock is released
 	OtelMetrics  func() (inputTokens int64, outputTokens int64, totalCostUSD float64, connected bool, port int) // returns OTEL metrics for status bar
 	AgentState   func() (state string, subState string, duration string)                       // returns Agent's derived state + sub-state
 	HookState    func() (lastToolName string)                                                // returns hook collector state
 	OnInterrupt func()                                    // called when Ctrl+C is written to the PTY
-	OnSubmit func(text string, priority message.Priority) // called for non-normal input
+	OnSubmit func(text string, priority message.Priority) error // called for non-normal input; errors are shown as a bar warning
+	OnUserInput func(text string, priority message.Priority) // called after typed input is written straight to the PTY; queued input is logged on delivery
 	OnDetach func()                                       // called when user selects detach from menu
+	OnEnd    func(reason string)                          // tells the remote client its attach is ending on purpose
+	OnSendFile func(path string, priority message.Priority) error // enqueues a file-backed message (menu f)
 
 	// Child process lifecycle callbacks (set by Session).
 	OnRelaunch func() // called when user presses Enter after child exits
@@ -72,9 +114,16 @@ type Client struct {
 	// Passthrough locking callbacks (set by Session).
 	TryPassthrough     func() bool // attempt to acquire passthrough; returns false if locked
 	ReleasePassthrough func()      // release passthrough ownership
-	TakePassthrough    func()      // force-take passthrough from current owner
+	TakePassthrough    func() bool // force-take passthrough from current owner; false if not allowed
 	IsPassthroughLocked func() bool // returns true if another client owns passthrough
 
+	// Write-lock callbacks (set by Session). Only the control holder may
+	// send input; others are read-only until control is handed over.
+	ClaimControl   func() bool          // true if this client may write (claims a free lock)
+	RequestControl func()               // ask the holder for control, or force once allowed
+	GrantControl   func() bool          // hand control to the pending requester
+	Control        func() ControlStatus // current write-lock status
+
 	// Per-client terminal dimensions (used to resize VT on detach).
 	TermRows int
 	TermCols int
@@ -89,7 +138,11 @@ type Client struct {
 func (c *Client) InitClient() {
 	c.HistIdx = -1
 	c.DebugKeys = virtualterminal.IsTruthyEnv("H2_DEBUG_KEYS")
+	c.DebugRender = virtualterminal.IsTruthyEnv("H2_DEBUG_RENDER")
 	c.Mode = ModeNormal
+	c.ConfirmQuit = true
+	c.MaxInputLen = DefaultMaxInputLen
+	c.SubmitDelay = message.DefaultSubmitDelay
 	c.ScrollOffset = 0
 	c.InputPriority = message.PriorityNormal
 }
@@ -145,10 +198,7 @@ func (c *Client) WatchResize(sigCh <-chan os.Signal) {
 	for range sigCh {
 		fd := int(os.Stdin.Fd())
 		cols, rows, err := term.GetSize(fd)
-		minRows := 3
-		if c.DebugKeys {
-			minRows = 4
-		}
+		minRows := c.ReservedRows() + 1
 		if err != nil || rows < minRows {
 			continue
 		}
@@ -157,12 +207,7 @@ func (c *Client) WatchResize(sigCh <-chan os.Signal) {
 		c.TermRows = rows
 		c.TermCols = cols
 		c.VT.Resize(rows, cols, rows-c.ReservedRows())
-		if c.IsScrollMode() {
-			c.ClampScrollOffset()
-		}
-		c.Output.Write([]byte("\033[2J"))
-		c.RenderScreen()
-		c.RenderBar()
+		c.Repaint()
 		c.VT.Mu.Unlock()
 	}
 }
@@ -175,20 +220,13 @@ func (c *Client) SetupInteractiveTerminal() (cleanup func(), stopStatus chan str
 	fd := int(os.Stdin.Fd())
 
 	// Detect the real terminal's colors before entering raw mode.
-	output := newTermOutput(os.Stdout)
-	if fg := output.ForegroundColor(); fg != nil {
-		c.VT.OscFg = virtualterminal.ColorToX11(fg)
-	}
-	if bg := output.BackgroundColor(); bg != nil {
-		c.VT.OscBg = virtualterminal.ColorToX11(bg)
-	}
-	if os.Getenv("COLORFGBG") == "" {
-		colorfgbg := "0;15"
-		if output.HasDarkBackground() {
-			colorfgbg = "15;0"
-		}
-		os.Setenv("COLORFGBG", colorfgbg)
+	tc := detectTermColors(os.Stdin, os.Stdout, colorDetectTimeout)
+	c.applyTermColors(tc)
+	// This client is shown on the local terminal, so its settings apply.
+	if err := c.LoadTheme(os.Getenv("H2_THEME"), tc.dark); err != nil {
+		c.warn(err.Error() + "; using the default theme")
 	}
+	c.ScrollStep = virtualterminal.ScrollStepEnv()
 
 	// Put our terminal into raw mode.
 	c.VT.Restore, err = term.MakeRaw(fd)
@@ -199,14 +237,15 @@ func (c *Client) SetupInteractiveTerminal() (cleanup func(), stopStatus chan str
 	// Detect kitty keyboard protocol support.
 	c.detectKittyKeyboard()
 
-	// Enable SGR mouse reporting for scroll wheel support.
-	os.Stdout.Write([]byte("\033[?1000h\033[?1006h"))
+	// Enable SGR mouse reporting for scroll wheel support, and bracketed
+	// paste so pasted newlines don't submit the input bar.
+	os.Stdout.Write([]byte(TerminalModesOn))
 
 	cleanup = func() {
 		if c.KittyKeyboard {
 			os.Stdout.Write([]byte("\033[<u")) // pop kitty keyboard mode
 		}
-		os.Stdout.Write([]byte("\033[?1000l\033[?1006l"))
+		os.Stdout.Write([]byte(TerminalModesOff))
 		term.Restore(fd, c.VT.Restore)
 		os.Stdout.Write([]byte("\033[?25h\033[0m\r\n"))
 	}
@@ -233,10 +272,19 @@ func (c *Client) SetupInteractiveTerminal() (cleanup func(), stopStatus chan str
 	return cleanup, stopStatus, nil
 }
 
+// hasDebugRow reports whether a debug row is drawn below the input bar.
+func (c *Client) hasDebugRow() bool {
+	return c.DebugKeys || c.DebugRender
+}
+
+// BaseReservedRows is the overlay height without the debug row: the
+// input bar and the status bar.
+const BaseReservedRows = 2
+
 // ReservedRows returns the number of rows reserved for the overlay UI.
 func (c *Client) ReservedRows() int {
-	if c.DebugKeys {
-		return 3
+	if c.hasDebugRow() {
+		return BaseReservedRows + 1
 	}
-	return 2
+	return BaseReservedRows
 }
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"os"
 	"os/exec"
 	"strings"
 	"testing"
@@ -174,3 +175,22 @@ func TestExitMessage_Signal(t *testing.T) {
 		t.Fatalf("expected message containing signal name, got %q", msg)
 	}
 }
+
+func TestApplyTermColors_PreservesCOLORFGBG(t *testing.T) {
+	c := newTestClient(10, 40)
+
+	t.Setenv("COLORFGBG", "7;0")
+	c.applyTermColors(termColors{fg: "rgb:0000/0000/0000", dark: false})
+	if got := os.Getenv("COLORFGBG"); got != "7;0" {
+		t.Errorf("COLORFGBG = %q, want user value preserved", got)
+	}
+	if c.VT.OscFg != "rgb:0000/0000/0000" {
+		t.Errorf("OscFg = %q, want detected color", c.VT.OscFg)
+	}
+
+	t.Setenv("COLORFGBG", "")
+	c.applyTermColors(termColors{dark: false})
+	if got := os.Getenv("COLORFGBG"); got != "0;15" {
+		t.Errorf("COLORFGBG = %q, want 0;15 for a light background", got)
+	}
+}
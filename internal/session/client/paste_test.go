@@ -0,0 +1,115 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"h2/internal/session/message"
+)
+
+func TestBracketedPaste_NewlinesDoNotSubmit(t *testing.T) {
+	o := newTestClient(10, 80)
+	o.InputPriority = message.PriorityNormal
+	r := pipePTY(t, o)
+
+	typeKeys(o, "\x1b[200~one\r\ntwo\rthree\x1b[201~")
+	if o.Pasting {
+		t.Fatal("expected the end marker to finish the paste")
+	}
+	if string(o.Input) != "one\ntwo\nthree" {
+		t.Fatalf("Input = %q, want pasted lines joined by newlines", o.Input)
+	}
+	r.SetReadDeadline(time.Now().Add(20 * time.Millisecond))
+	if n, _ := r.Read(make([]byte, 64)); n > 0 {
+		t.Fatalf("paste wrote %d bytes to the PTY, want none before Enter", n)
+	}
+
+	typeKeys(o, "\r")
+	want := "one\ntwo\nthree\r"
+	if got := readPTY(t, r, len(want)); string(got) != want {
+		t.Errorf("PTY got %q, want %q", got, want)
+	}
+}
+
+func TestBracketedPaste_SplitAcrossReads(t *testing.T) {
+	o := newTestClient(10, 80)
+	typeKeys(o, "\x1b[200~tab\there")
+	if !o.Pasting {
+		t.Fatal("expected paste to continue into the next read")
+	}
+	typeKeys(o, "\rend\x1b[201~")
+	if string(o.Input) != "tab\there\nend" {
+		t.Errorf("Input = %q, want literal tab and newline", o.Input)
+	}
+	if o.InputPriority != 0 {
+		t.Errorf("pasted tab should not cycle priority, got %v", o.InputPriority)
+	}
+}
+
+func TestBracketedPaste_PassthroughMarkersFollowChildMode(t *testing.T) {
+	for _, tc := range []struct {
+		childMode bool
+		want      string
+	}{
+		{false, "hi"},
+		{true, "\x1b[200~hi\x1b[201~"},
+	} {
+		o := newTestClient(10, 80)
+		o.Mode = ModePassthrough
+		o.VT.BracketedPaste = tc.childMode
+		r := pipePTY(t, o)
+
+		buf := []byte("\x1b[200~hi\x1b[201~")
+		o.HandlePassthroughBytes(buf, 0, len(buf))
+
+		if got := readPTY(t, r, len(tc.want)); string(got) != tc.want {
+			t.Errorf("child bracketed paste %v: PTY got %q, want %q", tc.childMode, got, tc.want)
+		}
+	}
+}
+
+func TestBracketedPaste_MenuIgnoresPastedKeys(t *testing.T) {
+	o := newTestClient(10, 80)
+	o.Mode = ModeMenu
+
+	buf := []byte("\x1b[200~pq\x1b[201~")
+	o.HandleMenuBytes(buf, 0, len(buf))
+
+	if o.Mode != ModeMenu {
+		t.Errorf("Mode = %v, want the pasted p not to leave the menu", o.Mode)
+	}
+	if o.Quit || o.QuitPending {
+		t.Error("pasted q should not quit")
+	}
+	if o.Pasting {
+		t.Error("expected the end marker to finish the paste")
+	}
+}
+
+func TestBracketedPaste_PassthroughTracksPaste(t *testing.T) {
+	o := newTestClient(10, 80)
+	o.Mode = ModePassthrough
+	r := pipePTY(t, o)
+
+	buf := []byte("\x1b[200~a\x1cb")
+	o.HandlePassthroughBytes(buf, 0, len(buf))
+	if !o.Pasting {
+		t.Fatal("expected passthrough to track the open paste")
+	}
+	if o.Mode != ModePassthrough {
+		t.Fatalf("Mode = %v, want a pasted Ctrl+\\ forwarded, not exiting passthrough", o.Mode)
+	}
+	if got := readPTY(t, r, 3); string(got) != "a\x1cb" {
+		t.Errorf("PTY got %q, want the pasted bytes verbatim", got)
+	}
+
+	// The paste ends after the client drops back to normal mode.
+	o.setMode(ModeNormal)
+	typeKeys(o, "\rc\x1b[201~")
+	if o.Pasting {
+		t.Error("expected the end marker to finish the paste in normal mode")
+	}
+	if string(o.Input) != "\nc" {
+		t.Errorf("Input = %q, want the rest of the paste taken literally", o.Input)
+	}
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"errors"
 	"testing"
 
 	"h2/internal/session/message"
@@ -61,3 +62,26 @@ func TestPromptShowsPriority(t *testing.T) {
 		t.Fatalf("expected 'interrupt', got %q", o.InputPriority.String())
 	}
 }
+
+func TestSubmitInput_RejectedKeepsInput(t *testing.T) {
+	o := newTestClient(10, 80)
+	o.InputPriority = message.PriorityInterrupt
+	o.Input = []byte("stop")
+	o.CursorPos = 4
+	o.OnSubmit = func(string, message.Priority) error { return errors.New("queue closed") }
+	recorded := false
+	o.OnUserInput = func(string, message.Priority) { recorded = true }
+
+	if !o.submitInput() {
+		t.Fatal("a rejected submit should not stop input handling")
+	}
+	if string(o.Input) != "stop" || o.InputPriority != message.PriorityInterrupt {
+		t.Errorf("input = %q (%s), want it kept", o.Input, o.InputPriority)
+	}
+	if recorded || len(o.History) != 0 {
+		t.Error("rejected input should not be recorded")
+	}
+	if o.Warning != "queue closed" {
+		t.Errorf("warning = %q, want the submit error", o.Warning)
+	}
+}
@@ -0,0 +1,119 @@
+package client
+
+import (
+	"strings"
+	"testing"
+
+	"h2/internal/session/message"
+)
+
+func newReadOnlyClient() *Client {
+	o := newTestClient(10, 120)
+	o.ReadOnly = true
+	for i := 0; i < 30; i++ {
+		o.VT.Scrollback.Write([]byte("line\n"))
+	}
+	return o
+}
+
+func TestReadOnly_TypingIsDiscarded(t *testing.T) {
+	o := newReadOnlyClient()
+	submitted := false
+	o.OnSubmit = func(string, message.Priority) error { submitted = true; return nil }
+	o.OnUserInput = func(string, message.Priority) { submitted = true }
+
+	buf := []byte("hello\r\t\x7f")
+	o.HandleDefaultBytes(buf, 0, len(buf))
+
+	if len(o.Input) != 0 {
+		t.Errorf("input = %q, want it discarded", o.Input)
+	}
+	if submitted {
+		t.Error("read-only client should not submit input")
+	}
+	if o.Mode != ModeNormal {
+		t.Errorf("mode = %v, want ModeNormal", o.Mode)
+	}
+}
+
+func TestReadOnly_PassthroughBytesDiscarded(t *testing.T) {
+	o := newReadOnlyClient()
+	o.Mode = ModePassthrough
+	buf := []byte("ls\r")
+	if got := o.HandlePassthroughBytes(buf, 0, len(buf)); got != len(buf) {
+		t.Fatalf("consumed %d bytes, want %d", got, len(buf))
+	}
+	if o.Warning != "" {
+		t.Errorf("discarded input should not try the PTY, got warning %q", o.Warning)
+	}
+}
+
+func TestReadOnly_WheelScrollsOwnOffset(t *testing.T) {
+	o := newReadOnlyClient()
+	buf := []byte("\x1b[<64;1;1M")
+	o.HandleDefaultBytes(buf, 0, len(buf))
+	if o.Mode != ModeScroll {
+		t.Fatalf("mode = %v, want ModeScroll", o.Mode)
+	}
+	if o.ScrollOffset != DefaultScrollStep {
+		t.Fatalf("offset = %d, want %d", o.ScrollOffset, DefaultScrollStep)
+	}
+
+	// Arrows navigate and Esc leaves scroll mode as usual.
+	up := []byte("\x1b[A")
+	o.HandleScrollBytes(up, 0, len(up))
+	if o.ScrollOffset != DefaultScrollStep+1 {
+		t.Fatalf("offset after Up = %d, want %d", o.ScrollOffset, DefaultScrollStep+1)
+	}
+	o.ExitScrollMode()
+	if o.Mode != ModeNormal || o.ScrollOffset != 0 {
+		t.Fatalf("after exit: mode %v offset %d", o.Mode, o.ScrollOffset)
+	}
+
+	// Up arrow from the live view starts scrolling too.
+	o.HandleDefaultBytes(up, 0, len(up))
+	if o.Mode != ModeScroll || o.ScrollOffset != 1 {
+		t.Fatalf("Up from live view: mode %v offset %d", o.Mode, o.ScrollOffset)
+	}
+}
+
+func TestReadOnly_MenuOffersOnlySafeActions(t *testing.T) {
+	o := newReadOnlyClient()
+	detached := false
+	o.OnDetach = func() { detached = true }
+	o.OnQuit = func() { t.Fatal("read-only client must not quit the agent") }
+	o.TryPassthrough = func() bool { t.Fatal("read-only client must not try passthrough"); return false }
+
+	o.HandleDefaultBytes([]byte{0x1C}, 0, 1)
+	if o.Mode != ModeMenu {
+		t.Fatalf("ctrl+\\ mode = %v, want ModeMenu", o.Mode)
+	}
+	label := o.MenuLabel()
+	for _, hidden := range []string{"q:quit", "p:", "m:compose", "w:"} {
+		if strings.Contains(label, hidden) {
+			t.Errorf("menu %q should not offer %q", label, hidden)
+		}
+	}
+
+	o.HandleMenuBytes([]byte("qqptwm"), 0, 6)
+	if o.Quit || o.QuitPending || o.Composing || o.Mode != ModeMenu {
+		t.Fatalf("ignored keys changed state: quit %v pending %v compose %v mode %v",
+			o.Quit, o.QuitPending, o.Composing, o.Mode)
+	}
+
+	o.HandleMenuBytes([]byte{'d'}, 0, 1)
+	if !detached {
+		t.Fatal("d should still detach")
+	}
+}
+
+func TestReadOnly_BarShowsIndicator(t *testing.T) {
+	o := newReadOnlyClient()
+	if bar := renderBarText(o); !strings.Contains(bar, "Read-only") {
+		t.Errorf("bar should show read-only indicator, got %q", bar)
+	}
+	o.EnterScrollMode()
+	if bar := renderBarText(o); !strings.Contains(bar, "Scroll (RO)") {
+		t.Errorf("scroll bar should show read-only indicator, got %q", bar)
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 	"syscall"
 	"time"
 	"unicode/utf8"
 
+	"github.com/mattn/go-runewidth"
 	"github.com/vito/midterm"
 
 	"h2/internal/session/agent"
@@ -19,15 +21,75 @@ import (
 
 // RenderScreen renders the virtual terminal buffer to the output.
 func (c *Client) RenderScreen() {
+	start := time.Now()
 	var buf bytes.Buffer
+	c.renderScreen(&buf)
+	c.Output.Write(buf.Bytes())
+	c.recordFrame(start, buf.Len())
+}
+
+// renderScreen appends the virtual terminal buffer to buf.
+func (c *Client) renderScreen(buf *bytes.Buffer) {
 	buf.WriteString("\033[?25l")
 	if c.IsScrollMode() {
-		c.renderScrollView(&buf)
+		c.renderScrollView(buf)
 	} else {
-		c.renderLiveView(&buf)
+		c.renderLiveView(buf)
 	}
-	c.renderSelectHint(&buf)
+	c.renderSelectHint(buf)
+}
+
+// TerminalModesOn enables the input modes h2 needs on the user's terminal:
+// SGR mouse reporting (for the scroll wheel) and bracketed paste.
+// TerminalModesOff turns them back off.
+const (
+	TerminalModesOn  = "\033[?1000h\033[?1006h\033[?2004h"
+	TerminalModesOff = "\033[?1000l\033[?1006l\033[?2004l"
+)
+
+// terminalReset undoes state another program may have left behind on the
+// user's terminal: the alternate screen, a scroll region, and attributes.
+const terminalReset = "\033[?1049l\033[r\033[0m"
+
+// Redraw fully repaints the terminal, e.g. after another program corrupted
+// it. It leaves scroll mode, resets the terminal and re-enables h2's input
+// modes, then clears and re-renders the screen and status bar in a single
+// write so no partial frame is shown. Called with VT.Mu held.
+func (c *Client) Redraw() {
+	if c.IsScrollMode() {
+		if c.Mode == ModePassthroughScroll {
+			c.setMode(ModePassthrough)
+		} else {
+			c.setMode(ModeNormal)
+		}
+	}
+	c.ScrollOffset = 0
+
+	start := time.Now()
+	var buf bytes.Buffer
+	buf.WriteString(terminalReset + TerminalModesOn)
+	if c.KittyKeyboard {
+		// Pop and re-push so the mode is restored without growing the stack.
+		buf.WriteString("\033[<u\033[>1u")
+	}
+	buf.WriteString("\033[2J\033[H")
+	c.renderScreen(&buf)
+	c.renderBar(&buf)
 	c.Output.Write(buf.Bytes())
+	c.recordFrame(start, buf.Len())
+}
+
+// Repaint clears the screen and re-renders this client's view and status
+// bar, e.g. after the VT was resized. A scrolled view keeps its own offset,
+// clamped to the new bounds, so one client resizing never moves another's
+// scroll position. Called with VT.Mu held.
+func (c *Client) Repaint() {
+	if c.IsScrollMode() {
+		c.ClampScrollOffset()
+	}
+	c.Output.Write([]byte("\033[2J"))
+	c.RenderScreen()
+	c.RenderBar()
 }
 
 // renderSelectHint draws the "hold shift to select" hint when active.
@@ -48,19 +110,43 @@ func (c *Client) renderSelectHint(buf *bytes.Buffer) {
 }
 
 // renderLiveView renders the live terminal content, anchored to the cursor.
-// midterm can grow Content/Height beyond ChildRows (via ensureHeight), so
+// midterm can grow Content/Height beyond viewRows (via ensureHeight), so
 // the cursor position—not row 0 or len(Content)—determines the visible window.
 func (c *Client) renderLiveView(buf *bytes.Buffer) {
-	startRow := c.VT.Vt.Cursor.Y - c.VT.ChildRows + 1
+	if c.VT.Starting {
+		c.renderStartingView(buf)
+		return
+	}
+	startRow := c.VT.Vt.Cursor.Y - c.viewRows() + 1
 	if startRow < 0 {
 		startRow = 0
 	}
-	for i := 0; i < c.VT.ChildRows; i++ {
+	for i := 0; i < c.viewRows(); i++ {
 		fmt.Fprintf(buf, "\033[%d;1H\033[2K", i+1)
 		c.RenderLineFrom(buf, c.VT.Vt, startRow+i)
 	}
 }
 
+// renderStartingView renders a placeholder while the child process is
+// starting up and hasn't written anything yet.
+func (c *Client) renderStartingView(buf *bytes.Buffer) {
+	msg := "starting…"
+	if c.AgentName != "" {
+		msg = "starting " + c.AgentName + "…"
+	}
+	mid := c.viewRows() / 2
+	for i := 0; i < c.viewRows(); i++ {
+		fmt.Fprintf(buf, "\033[%d;1H\033[2K", i+1)
+		if i == mid {
+			col := (c.VT.Cols-runewidth.StringWidth(msg))/2 + 1
+			if col < 1 {
+				col = 1
+			}
+			fmt.Fprintf(buf, "\033[%d;%dH\033[2m%s\033[0m", i+1, col, msg)
+		}
+	}
+}
+
 // renderScrollView renders the scrollback buffer at the current ScrollOffset.
 func (c *Client) renderScrollView(buf *bytes.Buffer) {
 	sb := c.VT.Scrollback
@@ -69,14 +155,15 @@ func (c *Client) renderScrollView(buf *bytes.Buffer) {
 		return
 	}
 	bottom := sb.Cursor.Y
-	startRow := bottom - c.VT.ChildRows + 1 - c.ScrollOffset
+	startRow := bottom - c.viewRows() + 1 - c.ScrollOffset
 	if startRow < 0 {
 		startRow = 0
 	}
-	for i := 0; i < c.VT.ChildRows; i++ {
+	for i := 0; i < c.viewRows(); i++ {
 		fmt.Fprintf(buf, "\033[%d;1H\033[2K", i+1)
 		c.RenderLineFrom(buf, sb, startRow+i)
 	}
+	c.renderSearchMatch(buf, startRow)
 	// Draw "(scrolling)" indicator at row 1, right-aligned, in inverse video.
 	indicator := "(scrolling)"
 	col := c.VT.Cols - len(indicator) + 1
@@ -87,20 +174,34 @@ func (c *Client) renderScrollView(buf *bytes.Buffer) {
 }
 
 // RenderLineFrom writes one row of the given terminal to buf.
+// midterm stores one rune per cell regardless of display width, so output is
+// measured in display columns: wide glyphs count as two, and content and
+// padding are clipped so the rendered line is exactly vt.Width columns.
+//
+// With TrimLines set, blanks after the last visible cell are dropped; callers
+// always clear the row with \033[2K first, so only padding that paints
+// something (a background, reverse video, underline) has to be sent.
 func (c *Client) RenderLineFrom(buf *bytes.Buffer, vt *midterm.Terminal, row int) {
 	if row >= len(vt.Content) {
 		return
 	}
 	line := vt.Content[row]
-	var pos int
+	width := vt.Width
+	var pos, col int
 	var lastFormat midterm.Format
+	// keep is the buffer length up to the last byte that must be sent.
+	keep := buf.Len()
 	for region := range vt.Format.Regions(row) {
+		if col >= width {
+			break
+		}
 		f := region.F
 		if f != lastFormat {
 			buf.WriteString("\033[0m")
 			buf.WriteString(f.Render())
 			lastFormat = f
 		}
+		visibleBlank := paintsBlank(f)
 		end := pos + region.Size
 
 		if pos < len(line) {
@@ -108,22 +209,83 @@ func (c *Client) RenderLineFrom(buf *bytes.Buffer, vt *midterm.Terminal, row int
 			if contentEnd > len(line) {
 				contentEnd = len(line)
 			}
-			buf.WriteString(string(line[pos:contentEnd]))
+			for _, r := range line[pos:contentEnd] {
+				w := runewidth.RuneWidth(r)
+				if col+w > width {
+					// A wide glyph that doesn't fit in the last column.
+					buf.WriteString(strings.Repeat(" ", width-col))
+					col = width
+					if visibleBlank {
+						keep = buf.Len()
+					}
+					break
+				}
+				buf.WriteRune(r)
+				col += w
+				if r != ' ' || visibleBlank {
+					keep = buf.Len()
+				}
+			}
 		}
 
 		padStart := len(line)
 		if padStart < pos {
 			padStart = pos
 		}
-		if padStart < end {
-			buf.WriteString(strings.Repeat(" ", end-padStart))
+		if pad := min(end-padStart, width-col); pad > 0 {
+			buf.WriteString(strings.Repeat(" ", pad))
+			col += pad
+			if visibleBlank {
+				keep = buf.Len()
+			}
 		}
 
 		pos = end
 	}
+	// Zero-width runes occupy a cell but no column; fill the remainder so
+	// backgrounds still reach the edge.
+	if col < width && pos > 0 {
+		buf.WriteString(strings.Repeat(" ", width-col))
+		if paintsBlank(lastFormat) {
+			keep = buf.Len()
+		}
+	}
+	if c.TrimLines {
+		buf.Truncate(keep)
+	}
 	buf.WriteString("\033[0m")
 }
 
+// paintsBlank reports whether a blank cell in format f looks different from
+// a cleared cell, so trailing padding in it can't be trimmed.
+func paintsBlank(f midterm.Format) bool {
+	return f.Bg != nil || f.IsReverse() || f.IsUnderline()
+}
+
+// noColor reports whether the NO_COLOR convention (https://no-color.org)
+// asks for output without color or text styling.
+func noColor() bool {
+	return os.Getenv("NO_COLOR") != ""
+}
+
+// controlPictures renders runes for display in the input bar, replacing
+// control characters (inserted via quoted insert) with their single-width
+// Unicode control pictures so they can't drive the real terminal.
+func controlPictures(runes []rune) string {
+	out := make([]rune, len(runes))
+	for i, r := range runes {
+		switch {
+		case r < 0x20:
+			out[i] = 0x2400 + r
+		case r == 0x7F:
+			out[i] = 0x2421
+		default:
+			out[i] = r
+		}
+	}
+	return string(out)
+}
+
 // RenderLine writes one row of the primary virtual terminal to buf.
 func (c *Client) RenderLine(buf *bytes.Buffer, row int) {
 	c.RenderLineFrom(buf, c.VT.Vt, row)
@@ -132,24 +294,32 @@ func (c *Client) RenderLine(buf *bytes.Buffer, row int) {
 // RenderBar draws the separator line and input bar.
 func (c *Client) RenderBar() {
 	var buf bytes.Buffer
+	c.renderBar(&buf)
+	c.Output.Write(buf.Bytes())
+	c.Stats.BytesWritten += uint64(buf.Len())
+}
 
-	sepRow := c.VT.Rows - 1
-	inputRow := c.VT.Rows
+// renderBar appends the separator line and input bar to buf.
+func (c *Client) renderBar(buf *bytes.Buffer) {
+
+	sepRow := c.VT.Rows - c.overlayRows() + 1
+	inputRow := sepRow + 1
 	debugRow := 0
-	if c.DebugKeys {
-		sepRow = c.VT.Rows - 2
-		inputRow = c.VT.Rows - 1
+	if c.hasDebugRow() {
 		debugRow = c.VT.Rows
 	}
 
 	// --- Separator line ---
-	fmt.Fprintf(&buf, "\033[%d;1H\033[2K", sepRow)
+	fmt.Fprintf(buf, "\033[%d;1H\033[2K", sepRow)
 
 	var style, label string
+	var queueFull, queueCompact string
 	if c.VT.ChildExited {
 		style = "\033[7m\033[31m" // red inverse
 		if c.IsScrollMode() {
 			label = " Scroll | " + c.exitMessage() + " | Esc exit"
+		} else if c.ReadOnly {
+			label = " " + c.exitMessage() + " | read-only"
 		} else {
 			label = " " + c.exitMessage() + " | [Enter] relaunch \u00b7 [q] quit"
 		}
@@ -160,7 +330,22 @@ func (c *Client) RenderBar() {
 
 		if c.Mode != ModeMenu {
 			status := c.StatusLabel()
+			if spin := c.spinnerGlyph(); spin != "" {
+				status = spin + " " + status
+			}
 			label += " | " + status
+			if w := c.activeWarning(); w != "" {
+				label += " | " + w
+			}
+			if err := c.VT.ReadErr; err != nil {
+				label += " | output stalled: " + err.Error()
+			}
+			if st := c.VT.AgentStatus; st != "" {
+				label += " | " + st
+			}
+			if ctl := c.controlLabel(); ctl != "" {
+				label += " | " + ctl
+			}
 
 			// OTEL metrics (tokens and cost)
 			if c.OtelMetrics != nil {
@@ -176,11 +361,23 @@ func (c *Client) RenderBar() {
 			if c.QueueStatus != nil {
 				count, paused := c.QueueStatus()
 				if count > 0 {
+					state := "queued"
 					if paused {
-						label += fmt.Sprintf(" | [%d paused]", count)
-					} else {
-						label += fmt.Sprintf(" | [%d queued]", count)
+						state = "paused"
 					}
+					queueCompact = fmt.Sprintf(" | [%d %s]", count, state)
+					queueFull = queueCompact
+					if c.QueueSummary != nil {
+						if sum := c.QueueSummary(); len(sum.Head) > 0 {
+							queueFull = queueDetail(count, sum, state)
+						}
+					}
+					label += queueFull
+				}
+			}
+			if c.PassthroughCountdown != nil {
+				if left, ok := c.PassthroughCountdown(); ok {
+					label += " | resumes in " + virtualterminal.FormatIdleDuration(left)
 				}
 			}
 		}
@@ -195,12 +392,19 @@ func (c *Client) RenderBar() {
 		right = c.AgentName + " "
 	}
 
+	if len(label)+len(right) > c.VT.Cols && queueFull != queueCompact {
+		// Fall back to the bare queue count before dropping anything else.
+		label = strings.Replace(label, queueFull, queueCompact, 1)
+	}
 	if len(label)+len(right) > c.VT.Cols {
 		if !c.VT.ChildExited {
 			// Tight on space - drop help first, then right-align.
 			label = " " + c.ModeLabel()
 			if c.Mode != ModeMenu {
 				label += " | " + c.StatusLabel()
+				if w := c.activeWarning(); w != "" {
+					label += " | " + w
+				}
 			}
 		}
 		if len(label)+len(right) > c.VT.Cols {
@@ -221,6 +425,68 @@ func (c *Client) RenderBar() {
 	buf.WriteString("\033[0m")
 
 	// --- Input line ---
+	if c.search != nil {
+		c.renderSearchLine(buf, inputRow)
+	} else if c.scrollSearch != nil {
+		c.renderScrollSearchLine(buf, inputRow)
+	} else if c.Composing {
+		c.renderComposeLines(buf, inputRow, 1+c.composeExtraRows())
+	} else {
+		c.renderInputLine(buf, inputRow)
+	}
+
+	if c.hasDebugRow() {
+		fmt.Fprintf(buf, "\033[%d;1H\033[2K", debugRow)
+		debugLabel := c.DebugLabel()
+		if c.DebugRender {
+			// Stats lead; keystrokes (if enabled) follow and are trimmed first.
+			debugLabel = c.RenderStatsLabel()
+			if c.DebugKeys {
+				debugLabel += " |" + c.DebugLabel()
+			}
+			if len(debugLabel) > c.VT.Cols {
+				debugLabel = debugLabel[:max(c.VT.Cols, 0)]
+			}
+		} else if len(debugLabel) > c.VT.Cols {
+			debugLabel = virtualterminal.TrimLeftToWidth(debugLabel, c.VT.Cols)
+		}
+		buf.WriteString(debugLabel)
+		if pad := c.VT.Cols - len(debugLabel); pad > 0 {
+			buf.WriteString(strings.Repeat(" ", pad))
+		}
+	}
+
+	if c.Mode == ModePassthrough || c.Mode == ModePassthroughScroll {
+		buf.WriteString("\033[?25l")
+	} else {
+		buf.WriteString("\033[?25h")
+	}
+}
+
+// barPreviewLen caps the next queued message's body in the status bar.
+const barPreviewLen = 24
+
+// queueDetail returns the expanded queue indicator: the count, a
+// per-priority breakdown when more than one priority is waiting, and the
+// next message's priority and a preview of its body. sum.Head is non-empty.
+func queueDetail(count int, sum message.PendingSummary, state string) string {
+	label := fmt.Sprintf(" | [%d %s", count, state)
+	if len(sum.Counts) > 1 {
+		var parts []string
+		for _, p := range []message.Priority{message.PriorityInterrupt, message.PriorityNormal, message.PriorityIdleFirst, message.PriorityIdle} {
+			if n := sum.Counts[p]; n > 0 {
+				parts = append(parts, fmt.Sprintf("%d %s", n, p))
+			}
+		}
+		label += ": " + strings.Join(parts, ", ")
+	}
+	next := sum.Head[0]
+	return label + "] next " + next.Priority.String() + ": " + message.Preview(next.Body, barPreviewLen)
+}
+
+// renderInputLine draws the prompt and input buffer on inputRow, keeping the
+// cursor in view and placing the terminal cursor at CursorPos.
+func (c *Client) renderInputLine(buf *bytes.Buffer, inputRow int) {
 	prompt := c.InputPriority.String() + " > "
 	maxInput := c.VT.Cols - len(prompt)
 
@@ -247,39 +513,27 @@ func (c *Client) RenderBar() {
 		displayEnd = totalRunes
 	}
 
-	displayInput := string(inputRunes[displayStart:displayEnd])
+	displayInput := controlPictures(inputRunes[displayStart:displayEnd])
 
-	fmt.Fprintf(&buf, "\033[%d;1H\033[2K", inputRow)
-	promptColor := "\033[36m" // cyan
-	if c.InputPriority == message.PriorityInterrupt {
-		promptColor = "\033[31m" // red
+	fmt.Fprintf(buf, "\033[%d;1H\033[2K", inputRow)
+	fmt.Fprintf(buf, "%s%s\033[0m%s", c.promptStyle(), prompt, displayInput)
+	if len(c.Input) == 0 && c.Placeholder != "" && maxInput > 0 {
+		hint := []rune(c.Placeholder)
+		if len(hint) > maxInput {
+			hint = hint[:maxInput]
+		}
+		if noColor() {
+			buf.WriteString(string(hint))
+		} else {
+			fmt.Fprintf(buf, "\033[2m%s\033[0m", string(hint))
+		}
 	}
-	fmt.Fprintf(&buf, "%s%s\033[0m%s", promptColor, prompt, displayInput)
 
 	cursorCol := len(prompt) + (cursorRunePos - displayStart) + 1
 	if cursorCol > c.VT.Cols {
 		cursorCol = c.VT.Cols
 	}
-	fmt.Fprintf(&buf, "\033[%d;%dH", inputRow, cursorCol)
-
-	if c.DebugKeys {
-		fmt.Fprintf(&buf, "\033[%d;1H\033[2K", debugRow)
-		debugLabel := c.DebugLabel()
-		if len(debugLabel) > c.VT.Cols {
-			debugLabel = virtualterminal.TrimLeftToWidth(debugLabel, c.VT.Cols)
-		}
-		buf.WriteString(debugLabel)
-		if pad := c.VT.Cols - len(debugLabel); pad > 0 {
-			buf.WriteString(strings.Repeat(" ", pad))
-		}
-	}
-
-	if c.Mode == ModePassthrough || c.Mode == ModePassthroughScroll {
-		buf.WriteString("\033[?25l")
-	} else {
-		buf.WriteString("\033[?25h")
-	}
-	c.Output.Write(buf.Bytes())
+	fmt.Fprintf(buf, "\033[%d;%dH", inputRow, cursorCol)
 }
 
 // ModeLabel returns the display name for the current mode.
@@ -290,26 +544,43 @@ func (c *Client) ModeLabel() string {
 	case ModeMenu:
 		return c.MenuLabel()
 	case ModeScroll:
+		if c.ReadOnly {
+			return "Scroll (RO)"
+		}
 		return "Scroll"
 	case ModePassthroughScroll:
 		return "Scroll (PT)"
 	default:
+		if c.ReadOnly {
+			return "Read-only"
+		}
+		if c.search != nil {
+			return "Search"
+		}
+		if c.Composing {
+			return "Compose"
+		}
+		if c.Overwrite {
+			return "Normal (OVR)"
+		}
 		return "Normal"
 	}
 }
 
-// ModeBarStyle returns the ANSI style for the current mode.
+// ModeBarStyle returns the ANSI style for the current mode: reverse video
+// in the theme's color for that mode.
 func (c *Client) ModeBarStyle() string {
+	theme := c.theme()
+	color := theme.Normal
 	switch c.Mode {
 	case ModePassthrough, ModePassthroughScroll:
-		return "\033[7m\033[33m"
+		color = theme.Passthrough
 	case ModeMenu:
-		return "\033[7m\033[34m"
+		color = theme.Menu
 	case ModeScroll:
-		return "\033[7m\033[36m"
-	default:
-		return "\033[7m\033[36m"
+		color = theme.Scroll
 	}
+	return "\033[7m\033[" + color + "m"
 }
 
 // HelpLabel returns context-sensitive help text.
@@ -320,8 +591,23 @@ func (c *Client) HelpLabel() string {
 	case ModeMenu:
 		return "esc exit"
 	case ModeScroll, ModePassthroughScroll:
-		return "Scroll/Up/Down navigate | Esc exit scroll"
+		switch {
+		case c.scrollSearch != nil && c.scrollSearch.editing:
+			return "Enter search | Esc cancel"
+		case c.scrollSearch != nil:
+			return "n older | N newer | / search | Esc exit scroll"
+		}
+		return "Scroll/Up/Down navigate | / search | Esc exit scroll"
 	default:
+		if c.search != nil {
+			return "^R older | Enter accept | Esc cancel"
+		}
+		if c.ReadOnly {
+			return c.keybindingHelp().ReadOnlyMode
+		}
+		if c.Composing {
+			return "Enter newline | ^D or Shift+Enter send"
+		}
 		return c.keybindingHelp().NormalMode
 	}
 }
@@ -343,25 +629,48 @@ func (c *Client) StatusLabel() string {
 	}
 
 	// Fallback: PTY output timing.
-	const idleThreshold = 2 * time.Second
 	if c.VT.LastOut.IsZero() {
 		return "Active"
 	}
 	idleFor := time.Since(c.VT.LastOut)
-	if idleFor <= idleThreshold {
+	if idleFor <= c.VT.IdleAfter() {
 		return "Active"
 	}
-	return "Idle " + virtualterminal.FormatIdleDuration(idleFor)
+	return "Idle " + virtualterminal.FormatDuration(idleFor, c.DurationPrecision)
 }
 
 // MenuLabel returns the formatted menu display.
 func (c *Client) MenuLabel() string {
+	if c.QuitPending {
+		return "Press q again to confirm quit / any key to cancel"
+	}
+	if c.ReadOnly {
+		return c.readOnlyMenuLabel()
+	}
 	var items string
-	if c.IsPassthroughLocked != nil && c.IsPassthroughLocked() {
+	if c.PassthroughDisabled {
+		items = "Menu | passthrough disabled | c:clear | r:redraw"
+	} else if c.IsPassthroughLocked != nil && c.IsPassthroughLocked() {
 		items = "Menu | p:LOCKED | t:take over | c:clear | r:redraw"
 	} else {
 		items = "Menu | p:passthrough | c:clear | r:redraw"
 	}
+	if c.Composing {
+		items += " | m:end compose"
+	} else {
+		items += " | m:compose"
+	}
+	if c.OnSendFile != nil {
+		items += " | f:send file"
+	}
+	if len(c.VT.PromptMarks) > 0 {
+		items += " | y:copy response"
+	}
+	if st := c.control(); st.Holder != "" && !st.Held {
+		items += " | w:request control"
+	} else if st.Held && st.Requester != "" {
+		items += " | g:grant control"
+	}
 	if c.OnDetach != nil {
 		items += " | d:detach"
 	}
@@ -369,6 +678,19 @@ func (c *Client) MenuLabel() string {
 	return items
 }
 
+// readOnlyMenuLabel returns the menu for a read-only client, which only
+// offers actions that leave the agent untouched.
+func (c *Client) readOnlyMenuLabel() string {
+	items := "Menu | read-only | r:redraw"
+	if len(c.VT.PromptMarks) > 0 {
+		items += " | y:copy response"
+	}
+	if c.OnDetach != nil {
+		items += " | d:detach"
+	}
+	return items
+}
+
 // DebugLabel returns the debug keystroke display.
 func (c *Client) DebugLabel() string {
 	prefix := " debug keystrokes: "
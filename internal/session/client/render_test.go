@@ -0,0 +1,333 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-runewidth"
+
+	"h2/internal/session/message"
+	"h2/internal/session/virtualterminal"
+)
+
+var sgrPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// renderedWidth renders one row and returns its visible text and column width.
+func renderedWidth(o *Client, row int) (string, int) {
+	var buf bytes.Buffer
+	o.RenderLine(&buf, row)
+	text := sgrPattern.ReplaceAllString(buf.String(), "")
+	return text, runewidth.StringWidth(text)
+}
+
+func TestRenderLine_ASCIIWidthEqualsCols(t *testing.T) {
+	o := newTestClient(5, 20)
+	o.VT.Vt.Write([]byte("hello"))
+
+	text, w := renderedWidth(o, 0)
+	if w != 20 {
+		t.Fatalf("expected width 20, got %d (%q)", w, text)
+	}
+	if !strings.HasPrefix(text, "hello") {
+		t.Fatalf("expected line to start with hello, got %q", text)
+	}
+}
+
+func TestRenderLine_WideCharsWidthEqualsCols(t *testing.T) {
+	o := newTestClient(5, 20)
+	o.VT.Vt.Write([]byte("日本語 ok 🎉"))
+
+	text, w := renderedWidth(o, 0)
+	if w != 20 {
+		t.Fatalf("expected width 20, got %d (%q)", w, text)
+	}
+	if !strings.HasPrefix(text, "日本語 ok 🎉") {
+		t.Fatalf("expected wide content preserved, got %q", text)
+	}
+}
+
+func TestRenderLine_WideCharsWithBackground(t *testing.T) {
+	o := newTestClient(5, 12)
+	// Background color spanning wide glyphs, then default.
+	o.VT.Vt.Write([]byte("\033[44m中文字\033[0m tail"))
+
+	text, w := renderedWidth(o, 0)
+	if w != 12 {
+		t.Fatalf("expected width 12, got %d (%q)", w, text)
+	}
+}
+
+func TestRenderLine_FullRowOfWideCharsClipped(t *testing.T) {
+	o := newTestClient(5, 5)
+	// Five wide glyphs occupy five cells but ten columns.
+	o.VT.Vt.Write([]byte("一二三四五"))
+
+	text, w := renderedWidth(o, 0)
+	if w != 5 {
+		t.Fatalf("expected width 5, got %d (%q)", w, text)
+	}
+	// Two glyphs fit, the third would straddle the edge and is blanked.
+	if text != "一二 " {
+		t.Fatalf("expected %q, got %q", "一二 ", text)
+	}
+}
+
+func TestRenderLine_TrimLinesDropsPlainPadding(t *testing.T) {
+	o := newTestClient(5, 20)
+	o.VT.Vt.Write([]byte("hello  "))
+
+	var full bytes.Buffer
+	o.RenderLine(&full, 0)
+
+	o.TrimLines = true
+	text, w := renderedWidth(o, 0)
+	if text != "hello" {
+		t.Fatalf("expected trailing blanks trimmed, got %q", text)
+	}
+	if w != 5 {
+		t.Fatalf("expected width 5, got %d", w)
+	}
+	var trimmed bytes.Buffer
+	o.RenderLine(&trimmed, 0)
+	if trimmed.Len() >= full.Len() {
+		t.Fatalf("trimmed render (%d bytes) should be smaller than full (%d bytes)", trimmed.Len(), full.Len())
+	}
+	if !strings.HasSuffix(trimmed.String(), "\033[0m") {
+		t.Fatalf("trimmed render should still end with a reset, got %q", trimmed.String())
+	}
+}
+
+func TestRenderLine_TrimLinesKeepsColoredPadding(t *testing.T) {
+	o := newTestClient(5, 12)
+	o.TrimLines = true
+	// Background-colored blanks after the text must still be painted.
+	o.VT.Vt.Write([]byte("\033[44mhi    \033[0m"))
+
+	text, w := renderedWidth(o, 0)
+	if !strings.HasPrefix(text, "hi    ") {
+		t.Fatalf("expected colored padding kept, got %q", text)
+	}
+	if w != 6 {
+		t.Fatalf("expected width 6 (colored cells only), got %d (%q)", w, text)
+	}
+}
+
+func TestRenderLine_TrimLinesKeepsReversePadding(t *testing.T) {
+	o := newTestClient(5, 12)
+	o.TrimLines = true
+	o.VT.Vt.Write([]byte("ab\033[7m   \033[0m"))
+
+	text, _ := renderedWidth(o, 0)
+	if text != "ab   " {
+		t.Fatalf("expected reverse-video blanks kept, got %q", text)
+	}
+}
+
+func TestRenderLine_TrimLinesBlankRow(t *testing.T) {
+	o := newTestClient(5, 20)
+	o.TrimLines = true
+	o.VT.Vt.Write([]byte("x\r\n"))
+
+	text, _ := renderedWidth(o, 1)
+	if text != "" {
+		t.Fatalf("expected empty blank row, got %q", text)
+	}
+}
+
+func TestStatusLabel_IdleThreshold(t *testing.T) {
+	o := newTestClient(5, 40)
+	o.VT.LastOut = time.Now().Add(-5 * time.Second)
+
+	// Default 2s threshold: five quiet seconds is idle.
+	if got := o.StatusLabel(); got != "Idle 5s" {
+		t.Errorf("default StatusLabel = %q, want %q", got, "Idle 5s")
+	}
+	// A 10s role threshold keeps the agent active through the same gap.
+	o.VT.IdleThreshold = 10 * time.Second
+	if got := o.StatusLabel(); got != "Active" {
+		t.Errorf("StatusLabel with 10s threshold = %q, want Active", got)
+	}
+}
+
+func TestStatusLabel_DurationPrecision(t *testing.T) {
+	o := newTestClient(5, 40)
+	o.VT.LastOut = time.Now().Add(-(time.Hour + 30*time.Minute + 5*time.Second))
+
+	if got := o.StatusLabel(); got != "Idle 1h" {
+		t.Errorf("compact StatusLabel = %q, want %q", got, "Idle 1h")
+	}
+	o.DurationPrecision = virtualterminal.DurationFull
+	if got := o.StatusLabel(); got != "Idle 1h30m05s" {
+		t.Errorf("full StatusLabel = %q, want %q", got, "Idle 1h30m05s")
+	}
+}
+
+var spinnerPattern = regexp.MustCompile(`\| ([|/\\-]) Active`)
+
+// barSpinner renders the bar and returns the spinner glyph shown before the
+// status label, or "" when none is shown.
+func barSpinner(o *Client) string {
+	var out bytes.Buffer
+	o.Output = &out
+	o.RenderBar()
+	m := spinnerPattern.FindStringSubmatch(out.String())
+	if m == nil {
+		return ""
+	}
+	return m[1]
+}
+
+func TestRenderBar_SpinnerAdvancesWhileActive(t *testing.T) {
+	o := newTestClient(5, 100)
+	state := "active"
+	o.AgentState = func() (string, string, string) { return state, "", "" }
+
+	var seen []string
+	for i := 0; i < len(spinnerFrames); i++ {
+		o.TickSpinner()
+		glyph := barSpinner(o)
+		if glyph == "" {
+			t.Fatalf("tick %d: no spinner in active bar", i)
+		}
+		if i > 0 && glyph == seen[i-1] {
+			t.Errorf("tick %d: spinner did not advance from %q", i, glyph)
+		}
+		seen = append(seen, glyph)
+	}
+
+	state = "idle"
+	o.TickSpinner()
+	if glyph := barSpinner(o); glyph != "" {
+		t.Errorf("idle bar should have no spinner, got %q", glyph)
+	}
+	frame := o.spinnerFrame
+	o.TickSpinner()
+	if o.spinnerFrame != frame {
+		t.Error("spinner should stay frozen while idle")
+	}
+}
+
+func TestRenderBar_SpinnerDroppedWhenNarrow(t *testing.T) {
+	o := newTestClient(5, 24)
+	o.AgentState = func() (string, string, string) { return "active", "", "" }
+	o.AgentName = "worker"
+	o.TickSpinner()
+
+	var out bytes.Buffer
+	o.Output = &out
+	o.RenderBar()
+	if spinnerPattern.MatchString(out.String()) {
+		t.Errorf("narrow bar should drop the spinner, got %q", out.String())
+	}
+}
+
+func TestRenderBar_PlaceholderWhenEmpty(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	o := newTestClient(5, 60)
+	o.Placeholder = "Type a message"
+
+	var out bytes.Buffer
+	o.Output = &out
+	o.RenderBar()
+	s := out.String()
+	if !strings.Contains(s, "\033[2mType a message\033[0m") {
+		t.Fatalf("expected dim placeholder in empty input bar, got %q", s)
+	}
+	// The cursor stays right after the prompt, not after the hint.
+	prompt := o.InputPriority.String() + " > "
+	if !strings.HasSuffix(s, fmt.Sprintf(";%dH\033[?25h", len(prompt)+1)) {
+		t.Errorf("cursor should sit after the prompt, got %q", s)
+	}
+
+	// The first typed character replaces the placeholder.
+	o.HandleDefaultBytes([]byte("h"), 0, 1)
+	out.Reset()
+	o.RenderBar()
+	if strings.Contains(out.String(), "Type a message") {
+		t.Errorf("placeholder should disappear once input is typed, got %q", out.String())
+	}
+	if string(o.Input) != "h" {
+		t.Errorf("Input = %q, want %q (placeholder must not become input)", o.Input, "h")
+	}
+}
+
+func TestRenderBar_PlaceholderNoColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	o := newTestClient(5, 60)
+	o.Placeholder = "Type a message"
+
+	var out bytes.Buffer
+	o.Output = &out
+	o.RenderBar()
+	s := out.String()
+	if !strings.Contains(s, "Type a message") {
+		t.Fatalf("expected placeholder, got %q", s)
+	}
+	if strings.Contains(s, "\033[2m") {
+		t.Errorf("NO_COLOR should drop the dim styling, got %q", s)
+	}
+}
+
+// withQueue wires a client's queue callbacks to the given pending messages.
+func withQueue(o *Client, pending []message.Message, paused bool) {
+	o.QueueStatus = func() (int, bool) { return len(pending), paused }
+	o.QueueSummary = func() message.PendingSummary {
+		sum := message.PendingSummary{Counts: map[message.Priority]int{}, Head: pending[:1]}
+		for _, msg := range pending {
+			sum.Counts[msg.Priority]++
+		}
+		return sum
+	}
+}
+
+func renderBarText(o *Client) string {
+	var out bytes.Buffer
+	o.Output = &out
+	o.RenderBar()
+	return out.String()
+}
+
+func TestRenderBar_QueuePreviewShowsNextAndBreakdown(t *testing.T) {
+	o := newTestClient(5, 160)
+	withQueue(o, []message.Message{
+		{Priority: message.PriorityInterrupt, Body: "stop and look at the failing build now please"},
+		{Priority: message.PriorityNormal, Body: "b"},
+		{Priority: message.PriorityNormal, Body: "c"},
+	}, false)
+
+	bar := renderBarText(o)
+	want := "[3 queued: 1 interrupt, 2 normal] next interrupt: stop and look at the fa…"
+	if !strings.Contains(bar, want) {
+		t.Fatalf("bar missing %q, got %q", want, bar)
+	}
+}
+
+func TestRenderBar_QueuePreviewSinglePriority(t *testing.T) {
+	o := newTestClient(5, 160)
+	withQueue(o, []message.Message{{Priority: message.PriorityIdle, Body: "line one\nline two"}}, true)
+
+	bar := renderBarText(o)
+	if !strings.Contains(bar, "[1 paused] next idle: line one line two") {
+		t.Fatalf("expected single-priority preview without breakdown, got %q", bar)
+	}
+}
+
+func TestRenderBar_QueuePreviewFallsBackToCountWhenTight(t *testing.T) {
+	o := newTestClient(5, 60)
+	withQueue(o, []message.Message{
+		{Priority: message.PriorityNormal, Body: "a fairly long message body"},
+		{Priority: message.PriorityIdle, Body: "another"},
+	}, false)
+
+	bar := renderBarText(o)
+	if strings.Contains(bar, "next") {
+		t.Fatalf("tight bar should drop the preview, got %q", bar)
+	}
+	if !strings.Contains(bar, "[2 queued]") {
+		t.Fatalf("tight bar should keep the compact count, got %q", bar)
+	}
+}
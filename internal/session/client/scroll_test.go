@@ -3,10 +3,12 @@ package client
 import (
 	"bytes"
 	"io"
+	"strings"
 	"testing"
 
 	"github.com/vito/midterm"
 
+	"h2/internal/session/message"
 	"h2/internal/session/virtualterminal"
 )
 
@@ -178,6 +180,75 @@ func TestScrollDown_ExitsAtZero(t *testing.T) {
 	}
 }
 
+// --- FollowOutput ---
+
+func TestFollowOutput_StayKeepsViewAnchored(t *testing.T) {
+	o := newTestClient(10, 80)
+	for i := 0; i < 30; i++ {
+		o.VT.Scrollback.Write([]byte("line\n"))
+	}
+	o.EnterScrollMode()
+	o.ScrollUp(5)
+
+	// Five more lines arrive below the view.
+	for i := 0; i < 5; i++ {
+		o.VT.Scrollback.Write([]byte("more\n"))
+	}
+	o.FollowOutput()
+
+	if o.Mode != ModeScroll {
+		t.Fatalf("expected to stay in ModeScroll, got %d", o.Mode)
+	}
+	if o.ScrollOffset != 10 {
+		t.Fatalf("expected offset 10 (5 + 5 new lines), got %d", o.ScrollOffset)
+	}
+
+	// Output that does not add lines leaves the offset alone.
+	o.VT.Scrollback.Write([]byte("partial"))
+	o.FollowOutput()
+	if o.ScrollOffset != 10 {
+		t.Fatalf("expected offset to stay 10, got %d", o.ScrollOffset)
+	}
+}
+
+func TestFollowOutput_FollowJumpsToLive(t *testing.T) {
+	o := newTestClient(10, 80)
+	o.ScrollOnOutput = virtualterminal.ScrollFollow
+	for i := 0; i < 30; i++ {
+		o.VT.Scrollback.Write([]byte("line\n"))
+	}
+	o.EnterScrollMode()
+	o.ScrollUp(5)
+
+	o.VT.Scrollback.Write([]byte("more\n"))
+	o.FollowOutput()
+
+	if o.Mode != ModeNormal {
+		t.Fatalf("expected ModeNormal after new output, got %d", o.Mode)
+	}
+	if o.ScrollOffset != 0 {
+		t.Fatalf("expected offset 0, got %d", o.ScrollOffset)
+	}
+}
+
+func TestFollowOutput_FollowRestoresPassthrough(t *testing.T) {
+	o := newTestClient(10, 80)
+	o.ScrollOnOutput = virtualterminal.ScrollFollow
+	for i := 0; i < 30; i++ {
+		o.VT.Scrollback.Write([]byte("line\n"))
+	}
+	o.Mode = ModePassthrough
+	o.EnterScrollMode()
+	o.ScrollUp(5)
+
+	o.VT.Scrollback.Write([]byte("more\n"))
+	o.FollowOutput()
+
+	if o.Mode != ModePassthrough {
+		t.Fatalf("expected ModePassthrough after new output, got %d", o.Mode)
+	}
+}
+
 // --- HandleSGRMouse ---
 
 func TestHandleSGRMouse_ScrollUpEntersMode(t *testing.T) {
@@ -191,8 +262,8 @@ func TestHandleSGRMouse_ScrollUpEntersMode(t *testing.T) {
 	if o.Mode != ModeScroll {
 		t.Fatalf("expected ModeScroll, got %d", o.Mode)
 	}
-	if o.ScrollOffset != scrollStep {
-		t.Fatalf("expected offset %d, got %d", scrollStep, o.ScrollOffset)
+	if o.ScrollOffset != DefaultScrollStep {
+		t.Fatalf("expected offset %d, got %d", DefaultScrollStep, o.ScrollOffset)
 	}
 }
 
@@ -207,8 +278,123 @@ func TestHandleSGRMouse_ScrollDownInMode(t *testing.T) {
 
 	before := o.ScrollOffset
 	o.HandleSGRMouse([]byte("<65;1;1"), true)
-	if o.ScrollOffset != before-scrollStep {
-		t.Fatalf("expected offset %d, got %d", before-scrollStep, o.ScrollOffset)
+	if o.ScrollOffset != before-DefaultScrollStep {
+		t.Fatalf("expected offset %d, got %d", before-DefaultScrollStep, o.ScrollOffset)
+	}
+}
+
+func TestHandleSGRMouse_ConfiguredStep(t *testing.T) {
+	o := newTestClient(10, 80)
+	for i := 0; i < 40; i++ {
+		o.VT.Scrollback.Write([]byte("line\n"))
+	}
+	o.ScrollStep = 7
+
+	o.HandleSGRMouse([]byte("<64;1;1"), true)
+	o.HandleSGRMouse([]byte("<64;1;1"), true)
+	if o.ScrollOffset != 14 {
+		t.Fatalf("expected offset 14 after two notches, got %d", o.ScrollOffset)
+	}
+	o.HandleSGRMouse([]byte("<65;1;1"), true)
+	if o.ScrollOffset != 7 {
+		t.Fatalf("expected offset 7, got %d", o.ScrollOffset)
+	}
+}
+
+func TestInitClient_IgnoresDaemonScrollStep(t *testing.T) {
+	// InitClient runs in the daemon; the step comes from the terminal the
+	// client is shown on, not the daemon's environment.
+	t.Setenv("H2_SCROLL_STEP", "5")
+	o := newTestClient(10, 80)
+	o.InitClient()
+	if o.wheelStep() != DefaultScrollStep {
+		t.Fatalf("wheel step = %d, want default %d", o.wheelStep(), DefaultScrollStep)
+	}
+}
+
+// --- PageUp / PageDown / Home / End ---
+
+func TestPageUpDown_MovesByWindow(t *testing.T) {
+	o := newTestClient(10, 80)
+	for i := 0; i < 40; i++ {
+		o.VT.Scrollback.Write([]byte("line\n"))
+	}
+
+	// PageUp from the live view enters scroll mode a window back.
+	pgUp := []byte("\x1b[5~")
+	o.HandleDefaultBytes(pgUp, 0, len(pgUp))
+	if o.Mode != ModeScroll || o.ScrollOffset != 10 {
+		t.Fatalf("after PageUp: mode %d offset %d, want scroll mode offset 10", o.Mode, o.ScrollOffset)
+	}
+	o.HandleScrollBytes(pgUp, 0, len(pgUp))
+	if o.ScrollOffset != 20 {
+		t.Fatalf("after second PageUp: offset %d, want 20", o.ScrollOffset)
+	}
+
+	// Clamped at the top: 40 lines - 10 rows + 1.
+	o.HandleScrollBytes(pgUp, 0, len(pgUp))
+	o.HandleScrollBytes(pgUp, 0, len(pgUp))
+	if o.ScrollOffset != 31 {
+		t.Fatalf("PageUp past the top: offset %d, want 31", o.ScrollOffset)
+	}
+
+	pgDn := []byte("\x1b[6~")
+	o.HandleScrollBytes(pgDn, 0, len(pgDn))
+	if o.ScrollOffset != 21 {
+		t.Fatalf("after PageDown: offset %d, want 21", o.ScrollOffset)
+	}
+	o.HandleScrollBytes(pgDn, 0, len(pgDn))
+	o.HandleScrollBytes(pgDn, 0, len(pgDn))
+	o.HandleScrollBytes(pgDn, 0, len(pgDn))
+	if o.Mode != ModeNormal || o.ScrollOffset != 0 {
+		t.Fatalf("PageDown past the bottom: mode %d offset %d, want normal mode", o.Mode, o.ScrollOffset)
+	}
+}
+
+func TestHomeEnd_InScrollMode(t *testing.T) {
+	for _, tc := range []struct{ name, home, end string }{
+		{"xterm", "\x1b[H", "\x1b[F"},
+		{"vt220", "\x1b[1~", "\x1b[4~"},
+		{"rxvt", "\x1b[7~", "\x1b[8~"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			o := newTestClient(10, 80)
+			for i := 0; i < 40; i++ {
+				o.VT.Scrollback.Write([]byte("line\n"))
+			}
+			o.EnterScrollMode()
+			o.ScrollUp(4)
+
+			o.HandleScrollBytes([]byte(tc.home), 0, len(tc.home))
+			if o.ScrollOffset != 31 || !o.IsScrollMode() {
+				t.Fatalf("Home: offset %d, want the top (31)", o.ScrollOffset)
+			}
+			// Home at the very top stays put.
+			o.HandleScrollBytes([]byte(tc.home), 0, len(tc.home))
+			if o.ScrollOffset != 31 {
+				t.Fatalf("Home at the top: offset %d, want 31", o.ScrollOffset)
+			}
+
+			o.HandleScrollBytes([]byte(tc.end), 0, len(tc.end))
+			if o.Mode != ModeNormal || o.ScrollOffset != 0 {
+				t.Fatalf("End: mode %d offset %d, want the live view", o.Mode, o.ScrollOffset)
+			}
+		})
+	}
+}
+
+func TestEnd_InPassthroughScrollRestoresPassthrough(t *testing.T) {
+	o := newTestClient(10, 80)
+	for i := 0; i < 40; i++ {
+		o.VT.Scrollback.Write([]byte("line\n"))
+	}
+	o.Mode = ModePassthrough
+	o.EnterScrollMode()
+	o.ScrollUp(5)
+	end := []byte("\x1b[F")
+	o.HandleScrollBytes(end, 0, len(end))
+	if o.Mode != ModePassthrough {
+		t.Fatalf("End from passthrough scroll: mode %d, want passthrough", o.Mode)
 	}
 }
 
@@ -472,7 +658,7 @@ func TestHelpLabel_Scroll(t *testing.T) {
 	o := newTestClient(10, 80)
 	o.Mode = ModeScroll
 	got := o.HelpLabel()
-	if got != "Scroll/Up/Down navigate | Esc exit scroll" {
+	if got != "Scroll/Up/Down navigate | / search | Esc exit scroll" {
 		t.Fatalf("unexpected help label: %q", got)
 	}
 }
@@ -604,6 +790,70 @@ func TestCtrlBackslash_EntersMenuWithInput(t *testing.T) {
 	}
 }
 
+// --- ctrl+v quoted insert ---
+
+func TestQuotedInsert_MenuChordInsertedLiterally(t *testing.T) {
+	o := newTestClient(10, 80)
+	buf := []byte{0x16, 0x1C} // ctrl+v, ctrl+backslash
+	o.HandleDefaultBytes(buf, 0, len(buf))
+	if o.Mode != ModeNormal {
+		t.Fatalf("expected ModeNormal, got %d", o.Mode)
+	}
+	if string(o.Input) != "\x1c" {
+		t.Fatalf("expected input %q, got %q", "\x1c", string(o.Input))
+	}
+}
+
+func TestQuotedInsert_SlashInsertedLiterally(t *testing.T) {
+	o := newTestClient(10, 80)
+	buf := []byte{0x16, '/'}
+	o.HandleDefaultBytes(buf, 0, len(buf))
+	if string(o.Input) != "/" {
+		t.Fatalf("expected input %q, got %q", "/", string(o.Input))
+	}
+	if o.QuotedInsert {
+		t.Fatal("quoted insert should apply to one byte only")
+	}
+}
+
+func TestQuotedInsert_ControlByteNotInterpreted(t *testing.T) {
+	o := newTestClient(10, 80)
+	o.Input = []byte("ab")
+	o.CursorPos = 2
+	o.InputPriority = message.PriorityNormal
+	// Split across reads: the chord and the quoted byte arrive separately.
+	o.HandleDefaultBytes([]byte{0x16}, 0, 1)
+	if !o.QuotedInsert {
+		t.Fatal("expected QuotedInsert after ctrl+v")
+	}
+	o.HandleDefaultBytes([]byte{0x09}, 0, 1) // tab normally cycles priority
+	if string(o.Input) != "ab\t" {
+		t.Fatalf("expected input %q, got %q", "ab\t", string(o.Input))
+	}
+	if o.InputPriority != message.PriorityNormal {
+		t.Fatalf("tab should not cycle priority when quoted, got %v", o.InputPriority)
+	}
+}
+
+func TestQuotedInsert_NextByteResumesNormalHandling(t *testing.T) {
+	o := newTestClient(10, 80)
+	buf := []byte{0x16, 'x', 0x1C}
+	o.HandleDefaultBytes(buf, 0, len(buf))
+	if string(o.Input) != "x" {
+		t.Fatalf("expected input %q, got %q", "x", string(o.Input))
+	}
+	if o.Mode != ModeMenu {
+		t.Fatalf("expected ModeMenu after unquoted ctrl+backslash, got %d", o.Mode)
+	}
+}
+
+func TestControlPictures(t *testing.T) {
+	got := controlPictures([]rune("a\x1c\t\x7fb"))
+	if got != "a\u241c\u2409\u2421b" {
+		t.Fatalf("controlPictures = %q", got)
+	}
+}
+
 func TestCtrlPN_PassedThroughInNormalMode(t *testing.T) {
 	// Ctrl+P and Ctrl+N no longer navigate history — they pass through to PTY.
 	// Without a real PTY, we just verify they don't trigger history navigation.
@@ -822,7 +1072,7 @@ func TestMenu_DetachIgnoredWithoutCallback(t *testing.T) {
 func TestMenuLabel(t *testing.T) {
 	o := newTestClient(10, 80)
 	got := o.MenuLabel()
-	if got != "Menu | p:passthrough | c:clear | r:redraw | q:quit" {
+	if got != "Menu | p:passthrough | c:clear | r:redraw | m:compose | q:quit" {
 		t.Fatalf("unexpected menu label: %q", got)
 	}
 }
@@ -831,11 +1081,89 @@ func TestMenuLabel_WithDetach(t *testing.T) {
 	o := newTestClient(10, 80)
 	o.OnDetach = func() {}
 	got := o.MenuLabel()
-	if got != "Menu | p:passthrough | c:clear | r:redraw | d:detach | q:quit" {
+	if got != "Menu | p:passthrough | c:clear | r:redraw | m:compose | d:detach | q:quit" {
 		t.Fatalf("unexpected menu label: %q", got)
 	}
 }
 
+func TestMenuLabel_WithSendFile(t *testing.T) {
+	o := newTestClient(10, 80)
+	o.OnSendFile = func(string, message.Priority) error { return nil }
+	got := o.MenuLabel()
+	if got != "Menu | p:passthrough | c:clear | r:redraw | m:compose | f:send file | q:quit" {
+		t.Fatalf("unexpected menu label: %q", got)
+	}
+}
+
+func TestMenuSendFile_NoCallbackIsNoop(t *testing.T) {
+	o := newTestClient(10, 80)
+	o.Mode = ModeMenu
+	o.Input = []byte("notes.md")
+	o.HandleMenuBytes([]byte{'f'}, 0, 1)
+	if o.Mode != ModeNormal || string(o.Input) != "notes.md" {
+		t.Fatalf("mode=%d input=%q, want ModeNormal with input kept", o.Mode, o.Input)
+	}
+}
+
+func TestMenuLabel_WithResponse(t *testing.T) {
+	o := newTestClient(10, 80)
+	o.VT.MarkPrompt()
+	if got := o.MenuLabel(); !strings.Contains(got, "y:copy response") {
+		t.Fatalf("menu label should offer copy once a prompt was sent, got %q", got)
+	}
+}
+
+func TestMenuCopyResponse_WritesOSC52(t *testing.T) {
+	o := newTestClient(10, 80)
+	var out bytes.Buffer
+	o.Output = &out
+	o.VT.MarkPrompt()
+	o.VT.Scrollback.Write([]byte("> hi\r\nhello\r\n"))
+	o.Mode = ModeMenu
+
+	o.HandleMenuBytes([]byte{'y'}, 0, 1)
+
+	if o.Mode != ModeNormal {
+		t.Fatalf("mode = %d, want ModeNormal", o.Mode)
+	}
+	if want := "\033]52;c;aGVsbG8=\a"; !strings.Contains(out.String(), want) {
+		t.Fatalf("output %q missing OSC 52 sequence %q", out.String(), want)
+	}
+	if !strings.Contains(o.Warning, "copied 1 line") {
+		t.Fatalf("warning = %q", o.Warning)
+	}
+}
+
+func TestMenuCopyResponse_NothingToCopy(t *testing.T) {
+	o := newTestClient(10, 80)
+	var out bytes.Buffer
+	o.Output = &out
+	o.Mode = ModeMenu
+
+	o.HandleMenuBytes([]byte{'y'}, 0, 1)
+
+	if strings.Contains(out.String(), "\033]52;") {
+		t.Fatalf("no OSC 52 expected, got %q", out.String())
+	}
+	if o.Warning != "no agent response to copy yet" {
+		t.Fatalf("warning = %q", o.Warning)
+	}
+}
+
+func TestMenuCopyResponse_ClipboardDisabled(t *testing.T) {
+	t.Setenv("H2_CLIPBOARD", "0")
+	o := newTestClient(10, 80)
+	o.VT.MarkPrompt()
+	o.VT.Scrollback.Write([]byte("> hi\r\nhello\r\n"))
+	o.Mode = ModeMenu
+
+	o.HandleMenuBytes([]byte{'y'}, 0, 1)
+
+	if !strings.Contains(o.Warning, "clipboard disabled") {
+		t.Fatalf("warning = %q", o.Warning)
+	}
+}
+
 // --- Passthrough mode input changes ---
 
 func TestPassthrough_EnterStaysInPassthrough(t *testing.T) {
@@ -1003,7 +1331,7 @@ func TestHelpLabel_PassthroughScroll(t *testing.T) {
 	o := newTestClient(10, 80)
 	o.Mode = ModePassthroughScroll
 	got := o.HelpLabel()
-	if got != "Scroll/Up/Down navigate | Esc exit scroll" {
+	if got != "Scroll/Up/Down navigate | / search | Esc exit scroll" {
 		t.Fatalf("unexpected help label: %q", got)
 	}
 }
@@ -1041,8 +1369,8 @@ func TestPassthrough_SGRMouseScroll_EntersPassthroughScroll(t *testing.T) {
 	if o.Mode != ModePassthroughScroll {
 		t.Fatalf("expected ModePassthroughScroll, got %d", o.Mode)
 	}
-	if o.ScrollOffset != scrollStep {
-		t.Fatalf("expected offset %d, got %d", scrollStep, o.ScrollOffset)
+	if o.ScrollOffset != DefaultScrollStep {
+		t.Fatalf("expected offset %d, got %d", DefaultScrollStep, o.ScrollOffset)
 	}
 }
 
@@ -0,0 +1,154 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// scrollSearch is the state of a search in the scrollback, opened with / in
+// scroll mode. The view is scrolled so the current match is on screen.
+type scrollSearch struct {
+	query   []byte
+	editing bool // the prompt is open; keys edit query until Enter
+	row     int  // Scrollback row of the current match; -1 if none
+	col     int  // rune index of the match within row
+}
+
+// StartScrollSearch opens the scrollback search prompt.
+func (c *Client) StartScrollSearch() {
+	c.scrollSearch = &scrollSearch{editing: true, row: -1}
+}
+
+// CancelScrollSearch closes the prompt and drops the match highlight,
+// leaving the view where it is.
+func (c *Client) CancelScrollSearch() {
+	c.scrollSearch = nil
+	c.RenderScreen()
+}
+
+// RunScrollSearch closes the prompt and jumps to the most recent
+// scrollback line containing the query. An empty query cancels.
+func (c *Client) RunScrollSearch() {
+	s := c.scrollSearch
+	if s == nil {
+		return
+	}
+	s.editing = false
+	if len(s.query) == 0 {
+		c.CancelScrollSearch()
+		return
+	}
+	s.row = -1
+	c.findScrollMatch(c.scrollbackBottom(), true)
+}
+
+// ScrollSearchNext moves to the next match for the current query: an older
+// line if older is set (n), a newer one otherwise (N). The current match is
+// kept when there is no further one.
+func (c *Client) ScrollSearchNext(older bool) {
+	s := c.scrollSearch
+	if s == nil || s.editing || len(s.query) == 0 {
+		return
+	}
+	from := c.scrollbackBottom()
+	if s.row >= 0 {
+		from = s.row + 1
+		if older {
+			from = s.row - 1
+		}
+	}
+	c.findScrollMatch(from, older)
+}
+
+// findScrollMatch searches from row in the given direction and scrolls the
+// match into view, or warns that there is none.
+func (c *Client) findScrollMatch(from int, older bool) {
+	s := c.scrollSearch
+	row, col := c.VT.SearchScrollback(string(s.query), from, older)
+	if row < 0 {
+		c.warn("not found: " + string(s.query))
+		return
+	}
+	s.row, s.col = row, col
+	// Center the match in the window, as far as the scrollback allows.
+	bottom := c.scrollbackBottom()
+	c.ScrollOffset = bottom - c.viewRows() + 1 - (row - c.viewRows()/2)
+	c.ClampScrollOffset()
+	c.markScrollBottom()
+	c.RenderScreen()
+}
+
+// scrollbackBottom returns the last Scrollback row, which the scroll view's
+// ScrollOffset is measured from.
+func (c *Client) scrollbackBottom() int {
+	if c.VT.Scrollback == nil {
+		return 0
+	}
+	return c.VT.Scrollback.Cursor.Y
+}
+
+// handleScrollSearchByte processes one input byte while the search prompt
+// is open. rest holds the bytes after b in the same read. It returns false
+// for an escape sequence, which the caller handles as usual (arrow keys
+// still scroll).
+func (c *Client) handleScrollSearchByte(b byte, rest []byte) bool {
+	s := c.scrollSearch
+	switch {
+	case b == 0x0D || b == 0x0A:
+		c.RunScrollSearch()
+	case b == 0x07: // ctrl+g — cancel
+		c.CancelScrollSearch()
+	case b == 0x1B && len(rest) == 0: // bare Esc — cancel
+		c.CancelScrollSearch()
+	case b == 0x1B:
+		return false
+	case b == 0x7F || b == 0x08:
+		if r := []rune(string(s.query)); len(r) > 0 {
+			s.query = []byte(string(r[:len(r)-1]))
+		}
+	case b >= 0x20:
+		s.query = append(s.query, b)
+	}
+	c.RenderBar()
+	return true
+}
+
+// renderScrollSearchLine draws the search prompt on inputRow.
+func (c *Client) renderScrollSearchLine(buf *bytes.Buffer, inputRow int) {
+	s := c.scrollSearch
+	prompt := "/" + controlPictures([]rune(string(s.query)))
+	line := prompt
+	if !s.editing && s.row < 0 {
+		line += "  (not found)"
+	}
+	line = runewidth.Truncate(line, max(c.VT.Cols, 0), "")
+	fmt.Fprintf(buf, "\033[%d;1H\033[2K\033[%sm%s\033[0m", inputRow, c.theme().Prompt, line)
+	cursorCol := min(runewidth.StringWidth(prompt)+1, c.VT.Cols)
+	fmt.Fprintf(buf, "\033[%d;%dH", inputRow, max(cursorCol, 1))
+}
+
+// renderSearchMatch highlights the current search match if it is within
+// the scroll view that starts at scrollback row startRow.
+func (c *Client) renderSearchMatch(buf *bytes.Buffer, startRow int) {
+	s := c.scrollSearch
+	if s == nil || s.row < 0 || s.row >= len(c.VT.Scrollback.Content) {
+		return
+	}
+	screenRow := s.row - startRow
+	if screenRow < 0 || screenRow >= c.viewRows() {
+		return
+	}
+	line := c.VT.Scrollback.Content[s.row]
+	end := min(s.col+len([]rune(string(s.query))), len(line))
+	if s.col >= end {
+		return
+	}
+	col := runewidth.StringWidth(string(line[:s.col])) + 1
+	if col > c.VT.Cols {
+		return
+	}
+	text := runewidth.Truncate(string(line[s.col:end]), c.VT.Cols-col+1, "")
+	fmt.Fprintf(buf, "\033[%d;%dH\033[7m%s\033[0m", screenRow+1, col, text)
+}
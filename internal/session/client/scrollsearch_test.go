@@ -0,0 +1,136 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func scrollKeys(c *Client, keys string) {
+	c.HandleScrollBytes([]byte(keys), 0, len(keys))
+}
+
+// newScrollSearchClient returns a client in scroll mode over 50 numbered
+// scrollback lines, with "Needle" on lines 10 and 30.
+func newScrollSearchClient() *Client {
+	c := newTestClient(10, 80)
+	for i := 0; i < 50; i++ {
+		line := fmt.Sprintf("line %02d", i)
+		if i == 10 || i == 30 {
+			line += " Needle"
+		}
+		c.VT.Scrollback.Write([]byte(line + "\r\n"))
+	}
+	c.EnterScrollMode()
+	return c
+}
+
+// visibleRows returns the scrollback rows shown at the current offset.
+func visibleRows(c *Client) (first, last int) {
+	last = c.VT.Scrollback.Cursor.Y - c.ScrollOffset
+	return last - c.VT.ChildRows + 1, last
+}
+
+func TestScrollSearch_EnterJumpsToMostRecentMatch(t *testing.T) {
+	c := newScrollSearchClient()
+	scrollKeys(c, "/needle")
+	if c.scrollSearch == nil || !c.scrollSearch.editing {
+		t.Fatal("/ should open the search prompt")
+	}
+	if got := c.HelpLabel(); got != "Enter search | Esc cancel" {
+		t.Errorf("HelpLabel = %q", got)
+	}
+	scrollKeys(c, "\r")
+
+	if c.scrollSearch.row != 30 {
+		t.Fatalf("match row = %d, want 30", c.scrollSearch.row)
+	}
+	if first, last := visibleRows(c); 30 < first || 30 > last {
+		t.Fatalf("match row 30 not in view (%d-%d)", first, last)
+	}
+	if !c.IsScrollMode() {
+		t.Fatal("search should stay in scroll mode")
+	}
+}
+
+func TestScrollSearch_NextAndPrevious(t *testing.T) {
+	c := newScrollSearchClient()
+	scrollKeys(c, "/needle\r")
+
+	scrollKeys(c, "n")
+	if c.scrollSearch.row != 10 {
+		t.Fatalf("n: match row = %d, want 10", c.scrollSearch.row)
+	}
+	if first, last := visibleRows(c); 10 < first || 10 > last {
+		t.Fatalf("match row 10 not in view (%d-%d)", first, last)
+	}
+
+	// No older match: keep the current one and say so.
+	scrollKeys(c, "n")
+	if c.scrollSearch.row != 10 || !strings.Contains(c.Warning, "not found") {
+		t.Fatalf("n past the oldest: row %d, warning %q", c.scrollSearch.row, c.Warning)
+	}
+
+	scrollKeys(c, "N")
+	if c.scrollSearch.row != 30 {
+		t.Fatalf("N: match row = %d, want 30", c.scrollSearch.row)
+	}
+}
+
+func TestScrollSearch_NotFound(t *testing.T) {
+	c := newScrollSearchClient()
+	before := c.ScrollOffset
+	scrollKeys(c, "/haystack\r")
+	if !strings.Contains(c.Warning, "not found") {
+		t.Errorf("warning = %q, want not found", c.Warning)
+	}
+	if c.ScrollOffset != before {
+		t.Errorf("offset moved to %d on no match", c.ScrollOffset)
+	}
+}
+
+func TestScrollSearch_OffsetClamped(t *testing.T) {
+	c := newScrollSearchClient()
+	// A match on the first row can't be centered: the view stops at the top.
+	scrollKeys(c, "/line 00\r")
+	if want := c.VT.Scrollback.Cursor.Y - c.VT.ChildRows + 1; c.ScrollOffset != want {
+		t.Fatalf("offset = %d, want the maximum %d", c.ScrollOffset, want)
+	}
+
+	// Likewise at the bottom, without leaving scroll mode.
+	scrollKeys(c, "/line 49\r")
+	if c.ScrollOffset != 0 || !c.IsScrollMode() {
+		t.Fatalf("offset = %d, scroll mode %v; want 0 in scroll mode", c.ScrollOffset, c.IsScrollMode())
+	}
+}
+
+func TestScrollSearch_HighlightsMatch(t *testing.T) {
+	c := newScrollSearchClient()
+	scrollKeys(c, "/NEEDLE\r")
+	var out bytes.Buffer
+	c.Output = &out
+	c.RenderScreen()
+	// The original text is highlighted, after the "line 30 " prefix.
+	if !strings.Contains(out.String(), "\033[7mNeedle\033[0m") {
+		t.Errorf("rendered screen has no highlighted match:\n%q", out.String())
+	}
+}
+
+func TestScrollSearch_EscCancelsPromptOnly(t *testing.T) {
+	c := newScrollSearchClient()
+	scrollKeys(c, "/nee")
+	scrollKeys(c, "\x1b")
+	if c.scrollSearch != nil {
+		t.Fatal("Esc should close the search prompt")
+	}
+	if !c.IsScrollMode() {
+		t.Fatal("Esc in the prompt should not leave scroll mode")
+	}
+
+	scrollKeys(c, "/needle\r")
+	c.ExitScrollMode()
+	if c.scrollSearch != nil {
+		t.Fatal("leaving scroll mode should end the search")
+	}
+}
@@ -0,0 +1,38 @@
+package client
+
+import "time"
+
+// spinnerFrames cycle in the status bar while the agent is active. They are
+// single-byte so the bar's byte-based width math stays exact.
+var spinnerFrames = []string{"|", "/", "-", `\`}
+
+// TickSpinner advances the activity spinner by one frame. It only moves
+// while the agent is active, so an idle or exited agent leaves it frozen.
+// Called by the session on each status tick.
+func (c *Client) TickSpinner() {
+	if c.agentActive() {
+		c.spinnerFrame = (c.spinnerFrame + 1) % len(spinnerFrames)
+	}
+}
+
+// spinnerGlyph returns the current spinner frame, or "" when the agent is
+// not active.
+func (c *Client) spinnerGlyph() string {
+	if !c.agentActive() {
+		return ""
+	}
+	return spinnerFrames[c.spinnerFrame]
+}
+
+// agentActive reports whether the agent is working, using the Agent's
+// derived state when available and PTY output timing otherwise.
+func (c *Client) agentActive() bool {
+	if c.VT.ChildExited {
+		return false
+	}
+	if c.AgentState != nil {
+		state, _, _ := c.AgentState()
+		return state == "active"
+	}
+	return c.VT.LastOut.IsZero() || time.Since(c.VT.LastOut) <= c.VT.IdleAfter()
+}
@@ -0,0 +1,122 @@
+package client
+
+import (
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"golang.org/x/term"
+)
+
+// termColors is what color detection learned about the local terminal.
+// Empty fg/bg mean unknown.
+type termColors struct {
+	fg, bg string // X11 rgb: form, for answering the child's OSC 10/11 queries
+	dark   bool
+}
+
+// colorDetectTimeout bounds terminal color detection. Some terminals (CI
+// ptys, multiplexers) never answer the OSC queries, and startup must not
+// wait long on them.
+var colorDetectTimeout = 500 * time.Millisecond
+
+// termColorQuery asks for the foreground and background colors (OSC 10/11),
+// then for the cursor position, which every terminal answers; its reply
+// ends the wait early on terminals that ignore the OSC queries.
+const termColorQuery = "\033]10;?\033\\\033]11;?\033\\\033[6n"
+
+var (
+	oscColorRe     = regexp.MustCompile(`\x1b\](1[01]);(rgb:[0-9a-fA-F]{1,4}/[0-9a-fA-F]{1,4}/[0-9a-fA-F]{1,4})`)
+	cursorReportRe = regexp.MustCompile(`\x1b\[\d+;\d+R`)
+)
+
+// detectTermColors queries the terminal on in/out for its colors, waiting
+// at most timeout. It runs synchronously and puts the terminal back as it
+// found it before returning, so it must be called before entering raw
+// mode: a query still running later would restore its saved mode over
+// ours. Without an answer the background comes from COLORFGBG.
+func detectTermColors(in, out *os.File, timeout time.Duration) termColors {
+	tc := termColors{dark: EnvDarkBackground()}
+	if t := os.Getenv("TERM"); strings.HasPrefix(t, "screen") || strings.HasPrefix(t, "tmux") {
+		return tc // may be shown on several terminals at once
+	}
+	fd := int(in.Fd())
+	if !term.IsTerminal(fd) || !term.IsTerminal(int(out.Fd())) {
+		return tc
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return tc
+	}
+	defer term.Restore(fd, state)
+
+	if _, err := out.WriteString(termColorQuery); err != nil {
+		return tc
+	}
+	return parseTermColors(readTermReply(fd, timeout), tc)
+}
+
+// readTermReply reads from fd until the cursor position report arrives or
+// timeout has passed.
+func readTermReply(fd int, timeout time.Duration) []byte {
+	deadline := time.Now().Add(timeout)
+	var reply []byte
+	buf := make([]byte, 256)
+	for !cursorReportRe.Match(reply) {
+		left := time.Until(deadline)
+		if left <= 0 {
+			break
+		}
+		fds := []unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}
+		n, err := unix.Poll(fds, int(left/time.Millisecond)+1)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil || n == 0 {
+			break
+		}
+		m, err := unix.Read(fd, buf)
+		if err != nil || m <= 0 {
+			break
+		}
+		reply = append(reply, buf[:m]...)
+	}
+	return reply
+}
+
+// parseTermColors fills tc from the OSC 10/11 answers in reply, judging
+// the background dark by its luminance.
+func parseTermColors(reply []byte, tc termColors) termColors {
+	for _, m := range oscColorRe.FindAllSubmatch(reply, -1) {
+		if string(m[1]) == "10" {
+			tc.fg = string(m[2])
+			continue
+		}
+		tc.bg = string(m[2])
+		if l, ok := x11Luminance(tc.bg); ok {
+			tc.dark = l < 0.5
+		}
+	}
+	return tc
+}
+
+// x11Luminance returns the relative luminance (0-1) of an X11
+// "rgb:r/g/b" color, whose components have 1 to 4 hex digits each.
+func x11Luminance(color string) (float64, bool) {
+	parts := strings.Split(strings.TrimPrefix(color, "rgb:"), "/")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	var rgb [3]float64
+	for i, p := range parts {
+		v, err := strconv.ParseUint(p, 16, 16)
+		if err != nil || p == "" {
+			return 0, false
+		}
+		rgb[i] = float64(v) / float64(uint64(1)<<(4*len(p))-1)
+	}
+	return 0.2126*rgb[0] + 0.7152*rgb[1] + 0.0722*rgb[2], true
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/creack/pty"
+	"golang.org/x/term"
+)
+
+// openTestPTY returns a pty pair; tty plays the local terminal and ptm the
+// terminal emulator behind it.
+func openTestPTY(t *testing.T) (ptm, tty *os.File) {
+	t.Helper()
+	ptm, tty, err := pty.Open()
+	if err != nil {
+		t.Skipf("no pty: %v", err)
+	}
+	t.Cleanup(func() {
+		ptm.Close()
+		tty.Close()
+	})
+	return ptm, tty
+}
+
+func TestDetectTermColors_SilentTerminalFallsBack(t *testing.T) {
+	t.Setenv("TERM", "xterm-256color")
+	t.Setenv("COLORFGBG", "")
+	ptm, tty := openTestPTY(t)
+	go func() {
+		buf := make([]byte, 256)
+		for {
+			if _, err := ptm.Read(buf); err != nil {
+				return // a terminal that never answers
+			}
+		}
+	}()
+	before, err := term.GetState(int(tty.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	tc := detectTermColors(tty, tty, 50*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("detection took %v, want it bounded by the timeout", elapsed)
+	}
+	if tc.fg != "" || tc.bg != "" || !tc.dark {
+		t.Errorf("unanswered detection = %+v, want unknown colors on a dark background", tc)
+	}
+	after, _ := term.GetState(int(tty.Fd()))
+	if *after != *before {
+		t.Error("terminal mode should be restored before detection returns")
+	}
+}
+
+func TestDetectTermColors_ReadsReplies(t *testing.T) {
+	t.Setenv("TERM", "xterm-256color")
+	ptm, tty := openTestPTY(t)
+	go func() {
+		buf := make([]byte, 256)
+		if _, err := ptm.Read(buf); err != nil {
+			return
+		}
+		ptm.WriteString("\033]10;rgb:0000/0000/0000\033\\\033]11;rgb:ffff/ffff/ffff\033\\\033[5;1R")
+	}()
+
+	tc := detectTermColors(tty, tty, 2*time.Second)
+	want := termColors{fg: "rgb:0000/0000/0000", bg: "rgb:ffff/ffff/ffff", dark: false}
+	if tc != want {
+		t.Errorf("detectTermColors = %+v, want %+v", tc, want)
+	}
+}
+
+func TestDetectTermColors_NotATerminal(t *testing.T) {
+	t.Setenv("COLORFGBG", "0;15")
+	f, err := os.Open(os.DevNull)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if tc := detectTermColors(f, f, time.Second); tc != (termColors{dark: false}) {
+		t.Errorf("detectTermColors = %+v, want the COLORFGBG background", tc)
+	}
+}
+
+func TestParseTermColors(t *testing.T) {
+	for _, tc := range []struct {
+		reply string
+		want  termColors
+	}{
+		{"\033[1;1R", termColors{dark: true}},
+		{"\033]11;rgb:1e1e/1e1e/1e1e\a\033[1;1R", termColors{bg: "rgb:1e1e/1e1e/1e1e", dark: true}},
+		{"\033]11;rgb:fd/f6/e3\033\\", termColors{bg: "rgb:fd/f6/e3", dark: false}},
+		{"\033]10;rgb:c/c/c\033\\\033]11;rgb:0/0/0\033\\", termColors{fg: "rgb:c/c/c", bg: "rgb:0/0/0", dark: true}},
+		{"\033]11;rgb:zz/00/00\033\\", termColors{dark: true}},
+	} {
+		if got := parseTermColors([]byte(tc.reply), termColors{dark: true}); got != tc.want {
+			t.Errorf("parseTermColors(%q) = %+v, want %+v", tc.reply, got, tc.want)
+		}
+	}
+}
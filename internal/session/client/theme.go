@@ -0,0 +1,151 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"h2/internal/session/message"
+)
+
+// Theme maps each bar mode and the input prompt to SGR color parameters
+// (e.g. "36" or "38;5;24"). The bar is drawn in reverse video, so mode
+// colors become the bar's background.
+type Theme struct {
+	Normal          string `yaml:"normal,omitempty"`
+	Passthrough     string `yaml:"passthrough,omitempty"`
+	Menu            string `yaml:"menu,omitempty"`
+	Scroll          string `yaml:"scroll,omitempty"`
+	Prompt          string `yaml:"prompt,omitempty"`
+	PromptInterrupt string `yaml:"prompt_interrupt,omitempty"`
+}
+
+// DefaultTheme is h2's original palette, tuned for dark backgrounds.
+var DefaultTheme = Theme{
+	Normal:          "36", // cyan
+	Passthrough:     "33", // yellow
+	Menu:            "34", // blue
+	Scroll:          "36", // cyan
+	Prompt:          "36", // cyan
+	PromptInterrupt: "31", // red
+}
+
+// LightTheme uses darker colors that stay readable on light backgrounds.
+var LightTheme = Theme{
+	Normal:          "34", // blue
+	Passthrough:     "35", // magenta
+	Menu:            "30", // black
+	Scroll:          "32", // green
+	Prompt:          "34", // blue
+	PromptInterrupt: "31", // red
+}
+
+var sgrParamsRe = regexp.MustCompile(`^[0-9]{1,3}(;[0-9]{1,3})*$`)
+
+// themeFile is the on-disk form of a theme: a base theme name plus
+// per-field overrides.
+type themeFile struct {
+	Base  string `yaml:"base,omitempty"`
+	Theme `yaml:",inline"`
+}
+
+// baseTheme returns the built-in theme for name: "default" (or "dark"),
+// "light", or "auto", which picks by the detected background.
+func baseTheme(name string, dark bool) (Theme, error) {
+	switch name {
+	case "", "default", "dark":
+		return DefaultTheme, nil
+	case "light":
+		return LightTheme, nil
+	case "auto":
+		if dark {
+			return DefaultTheme, nil
+		}
+		return LightTheme, nil
+	default:
+		return DefaultTheme, fmt.Errorf("unknown theme %q (want default, dark, light, or auto)", name)
+	}
+}
+
+// ResolveTheme builds the bar theme from the optional theme file at path
+// (missing is fine) and envName (H2_THEME), which overrides the file's base.
+// On error it returns DefaultTheme.
+func ResolveTheme(path, envName string, dark bool) (Theme, error) {
+	var tf themeFile
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return DefaultTheme, fmt.Errorf("read theme: %w", err)
+		}
+		if err := yaml.Unmarshal(data, &tf); err != nil {
+			return DefaultTheme, fmt.Errorf("parse theme %s: %w", path, err)
+		}
+	}
+	name := tf.Base
+	if envName != "" {
+		name = envName
+	}
+	theme, err := baseTheme(name, dark)
+	if err != nil {
+		return DefaultTheme, err
+	}
+	overrides := []struct {
+		key string
+		val string
+		dst *string
+	}{
+		{"normal", tf.Normal, &theme.Normal},
+		{"passthrough", tf.Passthrough, &theme.Passthrough},
+		{"menu", tf.Menu, &theme.Menu},
+		{"scroll", tf.Scroll, &theme.Scroll},
+		{"prompt", tf.Prompt, &theme.Prompt},
+		{"prompt_interrupt", tf.PromptInterrupt, &theme.PromptInterrupt},
+	}
+	for _, o := range overrides {
+		if o.val == "" {
+			continue
+		}
+		if !sgrParamsRe.MatchString(o.val) {
+			return DefaultTheme, fmt.Errorf("invalid theme %s %q: want SGR parameters like \"36\" or \"38;5;24\"", o.key, o.val)
+		}
+		*o.dst = o.val
+	}
+	return theme, nil
+}
+
+// LoadTheme resolves the client's theme from ThemeFile and spec (the
+// H2_THEME of the terminal the client is shown on), with dark saying
+// whether that terminal's background is dark (for "auto"). On error the
+// default theme is kept.
+func (c *Client) LoadTheme(spec string, dark bool) error {
+	theme, err := ResolveTheme(c.ThemeFile, spec, dark)
+	c.Theme = &theme
+	return err
+}
+
+// EnvDarkBackground guesses the background from COLORFGBG ("fg;bg"), for
+// clients without a local terminal to query. Unknown means dark.
+func EnvDarkBackground() bool {
+	v := os.Getenv("COLORFGBG")
+	bg := v[strings.LastIndexByte(v, ';')+1:]
+	return bg != "7" && bg != "15"
+}
+
+// theme returns the client's theme, or DefaultTheme when none was loaded.
+func (c *Client) theme() *Theme {
+	if c.Theme == nil {
+		return &DefaultTheme
+	}
+	return c.Theme
+}
+
+// promptStyle returns the SGR sequence for the input prompt.
+func (c *Client) promptStyle() string {
+	if c.InputPriority == message.PriorityInterrupt {
+		return "\033[" + c.theme().PromptInterrupt + "m"
+	}
+	return "\033[" + c.theme().Prompt + "m"
+}
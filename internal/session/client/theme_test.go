@@ -0,0 +1,144 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"h2/internal/session/message"
+)
+
+func TestModeBarStyle_DefaultThemeUnchanged(t *testing.T) {
+	c := newTestClient(10, 80)
+	cases := []struct {
+		mode InputMode
+		want string
+	}{
+		{ModeNormal, "\033[7m\033[36m"},
+		{ModePassthrough, "\033[7m\033[33m"},
+		{ModeMenu, "\033[7m\033[34m"},
+		{ModeScroll, "\033[7m\033[36m"},
+	}
+	for _, tc := range cases {
+		c.Mode = tc.mode
+		if got := c.ModeBarStyle(); got != tc.want {
+			t.Errorf("mode %v: got %q, want %q", tc.mode, got, tc.want)
+		}
+	}
+}
+
+func TestPromptStyle_Interrupt(t *testing.T) {
+	c := newTestClient(10, 80)
+	if got := c.promptStyle(); got != "\033[36m" {
+		t.Errorf("normal prompt = %q", got)
+	}
+	c.InputPriority = message.PriorityInterrupt
+	if got := c.promptStyle(); got != "\033[31m" {
+		t.Errorf("interrupt prompt = %q", got)
+	}
+}
+
+func TestResolveTheme_BaseNames(t *testing.T) {
+	cases := []struct {
+		name string
+		dark bool
+		want Theme
+	}{
+		{"", true, DefaultTheme},
+		{"default", false, DefaultTheme},
+		{"dark", false, DefaultTheme},
+		{"light", true, LightTheme},
+		{"auto", true, DefaultTheme},
+		{"auto", false, LightTheme},
+	}
+	for _, tc := range cases {
+		got, err := ResolveTheme("", tc.name, tc.dark)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%q dark=%v: got %+v, want %+v", tc.name, tc.dark, got, tc.want)
+		}
+	}
+}
+
+func TestResolveTheme_FileOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "theme.yaml")
+	os.WriteFile(path, []byte("base: light\nnormal: \"38;5;24\"\nmenu: \"35\"\n"), 0o644)
+
+	got, err := ResolveTheme(path, "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := LightTheme
+	want.Normal = "38;5;24"
+	want.Menu = "35"
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// H2_THEME replaces the file's base; overrides still apply.
+	got, err = ResolveTheme(path, "default", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = DefaultTheme
+	want.Normal = "38;5;24"
+	want.Menu = "35"
+	if got != want {
+		t.Errorf("env base: got %+v, want %+v", got, want)
+	}
+}
+
+func TestResolveTheme_MissingFile(t *testing.T) {
+	got, err := ResolveTheme(filepath.Join(t.TempDir(), "nope.yaml"), "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != DefaultTheme {
+		t.Errorf("got %+v, want default", got)
+	}
+}
+
+func TestResolveTheme_Errors(t *testing.T) {
+	if _, err := ResolveTheme("", "solarized", true); err == nil || !strings.Contains(err.Error(), "unknown theme") {
+		t.Errorf("unknown name: err = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "theme.yaml")
+	os.WriteFile(path, []byte("passthrough: \"33m\\033[0\"\n"), 0o644)
+	got, err := ResolveTheme(path, "", true)
+	if err == nil || !strings.Contains(err.Error(), "passthrough") {
+		t.Errorf("invalid SGR: err = %v", err)
+	}
+	if got != DefaultTheme {
+		t.Errorf("on error got %+v, want default", got)
+	}
+}
+
+func TestLoadTheme_UsesSpec(t *testing.T) {
+	c := newTestClient(10, 80)
+	if err := c.LoadTheme("light", true); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.ModeBarStyle(); got != "\033[7m\033[34m" {
+		t.Errorf("light normal bar = %q", got)
+	}
+}
+
+func TestEnvDarkBackground(t *testing.T) {
+	cases := map[string]bool{
+		"":             true,
+		"15;0":         true,
+		"0;15":         false,
+		"0;7":          false,
+		"0;default;15": false,
+	}
+	for v, want := range cases {
+		t.Setenv("COLORFGBG", v)
+		if got := EnvDarkBackground(); got != want {
+			t.Errorf("COLORFGBG=%q: got %v, want %v", v, got, want)
+		}
+	}
+}
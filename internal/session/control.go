@@ -0,0 +1,126 @@
+package session
+
+import (
+	"time"
+
+	"h2/internal/session/client"
+)
+
+// defaultControlForceAfter is how long a control request waits for the
+// holder to grant it before the requester may take control anyway.
+const defaultControlForceAfter = 30 * time.Second
+
+// controlForceAfter returns the configured force timeout or the default.
+func (s *Session) controlForceAfter() time.Duration {
+	if s.ControlForceAfter > 0 {
+		return s.ControlForceAfter
+	}
+	return defaultControlForceAfter
+}
+
+// claimControl reports whether cl may send input, taking the write lock if
+// nobody holds it. Read-only clients never get control; without WriteLock
+// every other client may write. Must be called with VT.Mu held.
+func (s *Session) claimControl(cl *client.Client) bool {
+	if cl.ReadOnly {
+		return false
+	}
+	if !s.WriteLock {
+		return true
+	}
+	if s.ControlOwner == nil {
+		s.ControlOwner = cl
+		s.renderControlChange()
+	}
+	return s.ControlOwner == cl
+}
+
+// requestControl asks the current holder to hand control to cl. A repeated
+// request made after the force timeout takes control without a grant.
+// Must be called with VT.Mu held.
+func (s *Session) requestControl(cl *client.Client, now time.Time) {
+	if cl.ReadOnly || !s.WriteLock {
+		return
+	}
+	if s.claimControl(cl) {
+		return
+	}
+	if s.controlRequester == cl && now.Sub(s.controlRequestedAt) >= s.controlForceAfter() {
+		s.transferControl(cl)
+		return
+	}
+	if s.controlRequester != cl {
+		s.controlRequester = cl
+		s.controlRequestedAt = now
+	}
+	s.renderControlChange()
+}
+
+// grantControl hands control from cl to the pending requester. Returns
+// false if cl doesn't hold control or nobody is waiting for it.
+// Must be called with VT.Mu held.
+func (s *Session) grantControl(cl *client.Client) bool {
+	if s.ControlOwner != cl || s.controlRequester == nil {
+		return false
+	}
+	s.transferControl(s.controlRequester)
+	return true
+}
+
+// transferControl makes to the control holder and clears any request. A
+// previous holder in passthrough is returned to normal mode, since it can
+// no longer write; leaving passthrough releases the passthrough lock. Must
+// be called with VT.Mu held.
+func (s *Session) transferControl(to *client.Client) {
+	s.ControlOwner = to
+	s.controlRequester = nil
+	if prev := s.PassthroughOwner; prev != nil && prev != to {
+		prev.LeavePassthrough()
+	}
+	s.renderControlChange()
+}
+
+// releaseControl drops cl's hold on (or request for) control, e.g. when it
+// detaches. A waiting requester inherits control. Must be called with
+// VT.Mu held.
+func (s *Session) releaseControl(cl *client.Client) {
+	if s.controlRequester == cl {
+		s.controlRequester = nil
+	}
+	if s.ControlOwner == cl {
+		s.ControlOwner = nil
+		if next := s.controlRequester; next != nil {
+			s.transferControl(next)
+			return
+		}
+	}
+	s.renderControlChange()
+}
+
+// controlStatus describes the write lock as seen by cl.
+// Must be called with VT.Mu held.
+func (s *Session) controlStatus(cl *client.Client, now time.Time) client.ControlStatus {
+	var st client.ControlStatus
+	if s.ControlOwner == nil {
+		return st
+	}
+	st.Holder = s.ControlOwner.Label
+	st.Held = s.ControlOwner == cl
+	if s.controlRequester != nil {
+		st.Requester = s.controlRequester.Label
+		st.Requested = s.controlRequester == cl
+		if st.Requested {
+			if left := s.controlForceAfter() - now.Sub(s.controlRequestedAt); left > 0 {
+				st.ForceIn = left
+			}
+		}
+	}
+	return st
+}
+
+// renderControlChange redraws every client's bar so all see who holds control.
+func (s *Session) renderControlChange() {
+	s.ForEachClient(func(c *client.Client) {
+		c.RenderBar()
+	})
+}
@@ -0,0 +1,215 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"h2/internal/session/client"
+	"h2/internal/session/message"
+)
+
+// newLockedSession returns a test session with the write lock turned on.
+func newLockedSession() *Session {
+	s := newTestSession()
+	s.WriteLock = true
+	return s
+}
+
+func TestControl_FirstWriterClaimsLock(t *testing.T) {
+	s := newLockedSession()
+	cl1 := s.NewClient()
+	cl2 := s.NewClient()
+
+	if s.ControlOwner != nil {
+		t.Fatal("no client should hold control before anyone writes")
+	}
+	if !cl1.ClaimControl() {
+		t.Fatal("cl1 should claim the free lock")
+	}
+	if cl2.ClaimControl() {
+		t.Fatal("cl2 should be read-only while cl1 holds control")
+	}
+	if st := cl2.Control(); st.Holder != cl1.Label || st.Held {
+		t.Errorf("cl2 status = %+v, want holder %q", st, cl1.Label)
+	}
+}
+
+func TestControl_NonHolderCannotSend(t *testing.T) {
+	s := newLockedSession()
+	cl1 := s.NewClient()
+	cl2 := s.NewClient()
+	cl1.ClaimControl()
+
+	cl2.Input = []byte("hello")
+	cl2.CursorPos = len(cl2.Input)
+	cl2.InputPriority = message.PriorityIdle
+	cl2.HandleDefaultBytes([]byte{'\r'}, 0, 1)
+
+	if n := s.Queue.PendingCount(); n != 0 {
+		t.Fatalf("read-only client queued %d messages, want 0", n)
+	}
+	if string(cl2.Input) != "hello" {
+		t.Errorf("input should be kept for later, got %q", cl2.Input)
+	}
+	if !strings.Contains(cl2.Warning, "read-only") {
+		t.Errorf("expected read-only warning, got %q", cl2.Warning)
+	}
+
+	// Passthrough is a write too.
+	cl2.Mode = client.ModeMenu
+	cl2.HandleMenuBytes([]byte{'p'}, 0, 1)
+	if cl2.Mode == client.ModePassthrough || s.PassthroughOwner != nil {
+		t.Fatal("read-only client should not enter passthrough")
+	}
+
+	// The holder sends normally.
+	cl1.Input = []byte("hi")
+	cl1.CursorPos = len(cl1.Input)
+	cl1.InputPriority = message.PriorityIdle
+	cl1.HandleDefaultBytes([]byte{'\r'}, 0, 1)
+	if n := s.Queue.PendingCount(); n != 1 {
+		t.Fatalf("holder queued %d messages, want 1", n)
+	}
+}
+
+func TestControl_RequestAndGrant(t *testing.T) {
+	s := newLockedSession()
+	cl1 := s.NewClient()
+	cl2 := s.NewClient()
+	cl1.ClaimControl()
+
+	cl2.Mode = client.ModeMenu
+	cl2.HandleMenuBytes([]byte{'w'}, 0, 1)
+	if s.ControlOwner != cl1 {
+		t.Fatal("a request alone should not move control")
+	}
+	if st := cl1.Control(); st.Requester != cl2.Label {
+		t.Fatalf("holder should see the request, got %+v", st)
+	}
+	if got := cl1.MenuLabel(); !strings.Contains(got, "g:grant control") {
+		t.Errorf("holder menu = %q, want grant item", got)
+	}
+
+	cl1.Mode = client.ModeMenu
+	cl1.HandleMenuBytes([]byte{'g'}, 0, 1)
+	if s.ControlOwner != cl2 {
+		t.Fatal("grant should hand control to the requester")
+	}
+	if cl1.ClaimControl() {
+		t.Fatal("previous holder should be read-only after granting")
+	}
+	if st := cl2.Control(); !st.Held || st.Requester != "" {
+		t.Errorf("cl2 status after grant = %+v", st)
+	}
+	if cl1.GrantControl() {
+		t.Error("grant without holding control should fail")
+	}
+}
+
+func TestControl_ForceAfterTimeout(t *testing.T) {
+	s := newLockedSession()
+	s.ControlForceAfter = time.Minute
+	cl1 := s.NewClient()
+	cl2 := s.NewClient()
+	cl1.ClaimControl()
+
+	now := time.Now()
+	s.requestControl(cl2, now)
+
+	// Repeating the request early doesn't force it.
+	s.requestControl(cl2, now.Add(30*time.Second))
+	if s.ControlOwner != cl1 {
+		t.Fatal("request should not force before the timeout")
+	}
+	if st := s.controlStatus(cl2, now.Add(30*time.Second)); st.ForceIn != 30*time.Second {
+		t.Errorf("ForceIn = %v, want 30s", st.ForceIn)
+	}
+
+	s.requestControl(cl2, now.Add(time.Minute))
+	if s.ControlOwner != cl2 {
+		t.Fatal("repeated request after the timeout should force control")
+	}
+}
+
+func TestControl_DetachedHolderHandsOffToRequester(t *testing.T) {
+	s := newLockedSession()
+	cl1 := s.NewClient()
+	cl2 := s.NewClient()
+	cl1.ClaimControl()
+	cl2.RequestControl()
+
+	s.releaseControl(cl1)
+	if s.ControlOwner != cl2 {
+		t.Fatal("waiting requester should inherit control when the holder leaves")
+	}
+}
+
+func TestControl_ReadOnlyObserverNeverTakesControl(t *testing.T) {
+	s := newLockedSession()
+	observer := s.NewClient()
+	observer.ReadOnly = true
+	writer := s.NewClient()
+
+	if observer.ClaimControl() || s.ControlOwner != nil {
+		t.Fatal("read-only client should not claim the free lock")
+	}
+	if observer.TryPassthrough() || observer.TakePassthrough() || s.PassthroughOwner != nil {
+		t.Fatal("read-only client should not get passthrough")
+	}
+	observer.RequestControl()
+	if st := writer.Control(); st.Requester != "" {
+		t.Errorf("read-only request should be ignored, requester = %q", st.Requester)
+	}
+
+	// An interactive client is unaffected.
+	if !writer.ClaimControl() {
+		t.Fatal("interactive client should claim the lock")
+	}
+}
+
+func TestControl_LockOffEveryClientWrites(t *testing.T) {
+	s := newTestSession()
+	cl1 := s.NewClient()
+	cl2 := s.NewClient()
+
+	if !cl1.ClaimControl() || !cl2.ClaimControl() {
+		t.Fatal("without the write lock every client should be able to write")
+	}
+	if s.ControlOwner != nil {
+		t.Fatal("no client should hold control without the write lock")
+	}
+	cl2.RequestControl()
+	if st := cl1.Control(); st.Requester != "" {
+		t.Errorf("request should be ignored without the write lock, requester = %q", st.Requester)
+	}
+	if st := cl2.Control(); st.Holder != "" {
+		t.Errorf("no holder should be reported without the write lock, got %q", st.Holder)
+	}
+}
+
+func TestControl_GrantKicksPassthroughHolderViaSetMode(t *testing.T) {
+	s := newLockedSession()
+	cl1 := s.NewClient()
+	cl2 := s.NewClient()
+	if !cl1.TryPassthrough() {
+		t.Fatal("cl1 should get passthrough")
+	}
+	cl1.Mode = client.ModePassthrough
+
+	cl2.RequestControl()
+	cl1.GrantControl()
+
+	if cl1.Mode != client.ModeNormal {
+		t.Fatalf("cl1 should be returned to ModeNormal, got %v", cl1.Mode)
+	}
+	if s.PassthroughOwner != nil {
+		t.Fatal("passthrough lock should be released")
+	}
+	if s.Queue.IsPaused() {
+		t.Fatal("queue should be unpaused once passthrough is released")
+	}
+	if !cl2.TakePassthrough() {
+		t.Fatal("cl2 should take passthrough once it has control")
+	}
+}
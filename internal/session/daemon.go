@@ -1,14 +1,18 @@
 package session
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"net"
 	"os"
 	"os/exec"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
+	"h2/internal/activitylog"
 	"h2/internal/config"
 	"h2/internal/session/agent"
 	"h2/internal/session/message"
@@ -25,9 +29,14 @@ type Daemon struct {
 
 // DaemonHeartbeat holds heartbeat configuration for the daemon.
 type DaemonHeartbeat struct {
-	IdleTimeout time.Duration
-	Message     string
-	Condition   string
+	IdleTimeout      time.Duration
+	Message          string
+	Condition        string
+	ConditionTimeout time.Duration // bound on each condition run (0 = 5s default)
+	Priority         string        // queue priority of the nudge ("" = idle)
+	EscalateAfter    int           // unanswered nudges before escalating (0 = never)
+	EscalateMessage  string        // sent at interrupt priority on escalation ("" = Message)
+	EscalateCommand  string        // run on escalation ("" = none)
 }
 
 // RunDaemonOpts holds all options for running a daemon.
@@ -45,7 +54,35 @@ type RunDaemonOpts struct {
 	PermissionMode  string   // permission mode → --permission-mode
 	AllowedTools    []string // allowed tools → --allowedTools (comma-joined)
 	DisallowedTools []string // disallowed tools → --disallowedTools (comma-joined)
+	ExtraArgs       []string // role extra_args appended to the child args
 	Heartbeat       DaemonHeartbeat
+	EscalateAfter   time.Duration     // default idle-message escalation window
+	MessageAging    time.Duration     // queue priority aging threshold (0 = off)
+	DedupeWindow    time.Duration     // drop messages identical to one queued this recently (0 = off)
+	NoConfirmQuit   bool              // menu Quit acts immediately
+	DrainOnQuit     bool              // deliver queued messages before quitting
+	ShutdownGrace   time.Duration     // bound on draining and SIGTERM before SIGKILL (0 = 10s)
+	NoPassthrough   bool              // clients may not enter passthrough mode
+	WriteLock       bool              // only the control holder may send input
+	MaxInputLen     int               // input bar length cap (0 = default)
+	SubmitNewline   string            // cr, lf, or crlf ("" = cr)
+	SubmitDelay     string            // pause before the submit bytes ("" = 50ms, "0" = none)
+	ModifierEnter   string            // insert_newline, forward_cr, or forward_lf ("" = insert_newline)
+	InputPlaceholder string           // hint shown in the empty input bar ("" = none)
+	DurationPrecision string          // status-bar idle time: compact or full ("" = compact)
+	ScrollOnOutput  string            // scroll mode on new output: stay or follow ("" = stay)
+	Rows            int               // initial PTY rows before the first attach (0 = 24)
+	Cols            int               // initial PTY cols before the first attach (0 = 80)
+	ReadyRegex      string            // screen pattern that marks the agent idle
+	OnMessage       string            // command run after each delivered message
+	StartMessage    string            // message enqueued on the agent's first idle
+	StartMessagePriority string       // queue priority of StartMessage ("" = normal)
+	NoHooks         bool              // config dir has no h2 hooks; skip the hook collector
+	PassthroughIdle time.Duration     // auto-release idle passthrough after this long (0 = never)
+	IdleThreshold   time.Duration     // quiet time before the agent counts as idle (0 = 2s)
+	StatusFile      string            // one-line status file rewritten on each status tick
+	ActivityLog     activitylog.Rotation // activity log rotation limits (zero fields = defaults)
+	Env             map[string]string // role env for the child, applied over h2's own values
 	Overrides       map[string]string // --override key=value pairs for metadata
 }
 
@@ -63,9 +100,58 @@ func RunDaemon(opts RunDaemonOpts) error {
 	s.PermissionMode = opts.PermissionMode
 	s.AllowedTools = opts.AllowedTools
 	s.DisallowedTools = opts.DisallowedTools
+	s.ExtraArgs = opts.ExtraArgs
 	s.HeartbeatIdleTimeout = opts.Heartbeat.IdleTimeout
 	s.HeartbeatMessage = opts.Heartbeat.Message
 	s.HeartbeatCondition = opts.Heartbeat.Condition
+	s.HeartbeatConditionTimeout = opts.Heartbeat.ConditionTimeout
+	s.HeartbeatEscalateAfter = opts.Heartbeat.EscalateAfter
+	s.HeartbeatEscalateMessage = opts.Heartbeat.EscalateMessage
+	s.HeartbeatEscalateCommand = opts.Heartbeat.EscalateCommand
+	if p, ok := message.ParsePriority(opts.Heartbeat.Priority); ok {
+		s.HeartbeatPriority = p
+	}
+	s.EscalateAfter = opts.EscalateAfter
+	s.Queue.SetAgeAfter(opts.MessageAging)
+	s.Queue.SetDedupeWindow(opts.DedupeWindow)
+	s.NoConfirmQuit = opts.NoConfirmQuit
+	s.DrainOnQuit = opts.DrainOnQuit
+	s.ShutdownGrace = opts.ShutdownGrace
+	s.NoPassthrough = opts.NoPassthrough
+	s.WriteLock = opts.WriteLock
+	s.MaxInputLen = opts.MaxInputLen
+	s.SubmitNewline = virtualterminal.SubmitNewline(opts.SubmitNewline)
+	if opts.SubmitDelay != "" {
+		d, err := time.ParseDuration(opts.SubmitDelay)
+		if err != nil || d < 0 {
+			return fmt.Errorf("invalid submit delay %q", opts.SubmitDelay)
+		}
+		s.SubmitDelay = d
+	}
+	s.ModifierEnter = virtualterminal.EnterAction(opts.ModifierEnter)
+	s.InputPlaceholder = opts.InputPlaceholder
+	s.DurationPrecision = virtualterminal.DurationPrecision(opts.DurationPrecision)
+	s.ScrollOnOutput = virtualterminal.ScrollPolicy(opts.ScrollOnOutput)
+	s.InitialRows = opts.Rows
+	s.InitialCols = opts.Cols
+	s.OnMessageCmd = opts.OnMessage
+	s.StartMessage = opts.StartMessage
+	if p, ok := message.ParsePriority(opts.StartMessagePriority); ok {
+		s.StartMessagePriority = p
+	}
+	s.NoHooks = opts.NoHooks
+	s.PassthroughIdleTimeout = opts.PassthroughIdle
+	s.IdleThreshold = opts.IdleThreshold
+	s.StatusFile = opts.StatusFile
+	s.ActivityLogRotation = opts.ActivityLog
+	s.RoleEnv = opts.Env
+	if opts.ReadyRegex != "" {
+		re, err := regexp.Compile(opts.ReadyRegex)
+		if err != nil {
+			return fmt.Errorf("invalid ready regex: %w", err)
+		}
+		s.ReadyPattern = re
+	}
 	s.StartTime = time.Now()
 
 	// Create socket directory.
@@ -121,6 +207,12 @@ func RunDaemon(opts RunDaemonOpts) error {
 	}
 	s.Daemon = d
 
+	s.writeLifecycleState()
+
+	// Create the VT before accepting connections so an early attach sees a
+	// placeholder screen instead of racing the child's startup.
+	s.initDaemonVT()
+
 	// Start socket listener.
 	go d.acceptLoop()
 
@@ -150,7 +242,13 @@ func (d *Daemon) AgentInfo() *message.AgentInfo {
 		SubState:         sub.String(),
 		StateDisplayText: agent.FormatStateLabel(st.String(), sub.String(), toolName),
 		StateDuration:    virtualterminal.FormatIdleDuration(s.StateDuration()),
+		StateChangedAt:   s.Agent.StateChangedAt().UTC().Format(time.RFC3339Nano),
 		QueuedCount:      s.Queue.PendingCount(),
+		QueuedAttachments: s.Queue.PendingAttachmentCount(),
+		DaemonPID:        os.Getpid(),
+	}
+	if s.VT != nil && s.VT.Cmd != nil && s.VT.Cmd.Process != nil {
+		info.PID = s.VT.Cmd.Process.Pid
 	}
 
 	// Pull from OTEL collector if active.
@@ -184,9 +282,46 @@ func (d *Daemon) AgentInfo() *message.AgentInfo {
 		info.BlockedToolName = hs.BlockedToolName
 	}
 
+	info.Render = s.RenderMetrics()
+	sum := s.Queue.Summary(message.StatusQueueLimit)
+	info.Queue = queueInfo(sum.Head, time.Now())
+	if len(sum.Counts) > 0 {
+		info.QueueByPriority = make(map[string]int, len(sum.Counts))
+		for p, n := range sum.Counts {
+			info.QueueByPriority[p.String()] = n
+		}
+	}
+
+	startedAt, runUptime, restarts := s.RunStats()
+	if !startedAt.IsZero() {
+		info.StartedAt = startedAt.UTC().Format(time.RFC3339)
+	}
+	info.UptimeSeconds = int64(runUptime / time.Second)
+	info.RestartCount = restarts
+
 	return info
 }
 
+// queueInfo describes pending messages for a status response.
+func queueInfo(pending []message.Message, now time.Time) []message.QueuedMessageInfo {
+	if len(pending) == 0 {
+		return nil
+	}
+	out := make([]message.QueuedMessageInfo, len(pending))
+	for i, msg := range pending {
+		age := now.Sub(msg.CreatedAt)
+		out[i] = message.QueuedMessageInfo{
+			ID:         msg.ID,
+			From:       msg.From,
+			Priority:   msg.Priority.String(),
+			Body:       message.Preview(msg.Body, message.StatusPreviewLen),
+			Age:        virtualterminal.FormatIdleDuration(age),
+			AgeSeconds: int64(age / time.Second),
+		}
+	}
+	return out
+}
+
 // buildModelStats converts per-model maps into a sorted slice of ModelStat.
 func buildModelStats(m agent.OtelMetricsSnapshot) []message.ModelStat {
 	if len(m.ModelCosts) == 0 && len(m.ModelTokens) == 0 {
@@ -246,7 +381,35 @@ type ForkDaemonOpts struct {
 	PermissionMode  string   // permission mode → --permission-mode
 	AllowedTools    []string // allowed tools → --allowedTools (comma-joined)
 	DisallowedTools []string // disallowed tools → --disallowedTools (comma-joined)
+	ExtraArgs       []string // role extra_args (→ --extra-arg, repeatable)
 	Heartbeat       DaemonHeartbeat
+	EscalateAfter   time.Duration // idle-message escalation window (→ --escalate-after)
+	MessageAging    time.Duration // queue priority aging threshold (→ --message-aging)
+	DedupeWindow    time.Duration // duplicate message window (→ --dedupe-window)
+	NoConfirmQuit   bool     // menu Quit acts immediately (→ --no-confirm-quit)
+	DrainOnQuit     bool     // deliver queued messages before quitting (→ --drain)
+	ShutdownGrace   time.Duration // graceful shutdown bound (→ --shutdown-grace)
+	NoPassthrough   bool     // clients may not enter passthrough (→ --no-passthrough)
+	WriteLock       bool     // only the control holder may send input (→ --write-lock)
+	MaxInputLen     int      // input bar length cap (→ --max-input-bytes)
+	SubmitNewline   string   // submit newline: cr, lf, crlf (→ --submit-newline)
+	SubmitDelay     string   // pause before submitting, as a duration (→ --submit-delay)
+	ModifierEnter   string   // Shift/Alt+Enter action (→ --modifier-enter)
+	InputPlaceholder string  // empty input bar hint (→ --input-placeholder)
+	DurationPrecision string // status-bar idle time format (→ --duration-precision)
+	ScrollOnOutput  string   // scroll mode on new output (→ --scroll-on-output)
+	Rows            int      // initial PTY rows (→ --rows)
+	Cols            int      // initial PTY cols (→ --cols)
+	ReadyRegex      string   // prompt-ready screen pattern (→ --ready-regex)
+	OnMessage       string   // post-delivery hook command (→ --on-message)
+	StartMessage    string   // kickoff message for the first idle (→ --start-message)
+	StartMessagePriority string // kickoff message priority (→ --start-message-priority)
+	NoHooks         bool     // launched without h2 hooks (→ --no-hooks)
+	PassthroughIdle time.Duration // idle passthrough release (→ --passthrough-idle-timeout)
+	IdleThreshold   time.Duration // per-agent idle threshold (→ --idle-threshold)
+	StatusFile      string   // one-line status file path (→ --status-file)
+	ActivityLog     activitylog.Rotation // log rotation limits (→ --activity-log-max-*)
+	Env             map[string]string // role env vars for the child (→ RoleEnvVar in the daemon's env)
 	CWD             string   // working directory for the child process
 	Pod             string   // pod name (set as H2_POD env var)
 	Overrides       []string // --override key=value pairs (recorded in session metadata)
@@ -276,6 +439,21 @@ func ForkDaemon(opts ForkDaemonOpts) error {
 		if opts.Heartbeat.Condition != "" {
 			daemonArgs = append(daemonArgs, "--heartbeat-condition", opts.Heartbeat.Condition)
 		}
+		if opts.Heartbeat.ConditionTimeout > 0 {
+			daemonArgs = append(daemonArgs, "--heartbeat-condition-timeout", opts.Heartbeat.ConditionTimeout.String())
+		}
+		if opts.Heartbeat.EscalateAfter > 0 {
+			daemonArgs = append(daemonArgs, "--heartbeat-escalate-after", strconv.Itoa(opts.Heartbeat.EscalateAfter))
+			if opts.Heartbeat.EscalateMessage != "" {
+				daemonArgs = append(daemonArgs, "--heartbeat-escalate-message", opts.Heartbeat.EscalateMessage)
+			}
+			if opts.Heartbeat.EscalateCommand != "" {
+				daemonArgs = append(daemonArgs, "--heartbeat-escalate-command", opts.Heartbeat.EscalateCommand)
+			}
+		}
+		if opts.Heartbeat.Priority != "" {
+			daemonArgs = append(daemonArgs, "--heartbeat-priority", opts.Heartbeat.Priority)
+		}
 	}
 	if opts.Instructions != "" {
 		daemonArgs = append(daemonArgs, "--instructions", opts.Instructions)
@@ -295,6 +473,93 @@ func ForkDaemon(opts ForkDaemonOpts) error {
 	for _, tool := range opts.DisallowedTools {
 		daemonArgs = append(daemonArgs, "--disallowed-tool", tool)
 	}
+	for _, arg := range opts.ExtraArgs {
+		daemonArgs = append(daemonArgs, "--extra-arg="+arg)
+	}
+	if opts.EscalateAfter > 0 {
+		daemonArgs = append(daemonArgs, "--escalate-after", opts.EscalateAfter.String())
+	}
+	if opts.MessageAging > 0 {
+		daemonArgs = append(daemonArgs, "--message-aging", opts.MessageAging.String())
+	}
+	if opts.DedupeWindow > 0 {
+		daemonArgs = append(daemonArgs, "--dedupe-window", opts.DedupeWindow.String())
+	}
+	if opts.NoConfirmQuit {
+		daemonArgs = append(daemonArgs, "--no-confirm-quit")
+	}
+	if opts.DrainOnQuit {
+		daemonArgs = append(daemonArgs, "--drain")
+	}
+	if opts.ShutdownGrace > 0 {
+		daemonArgs = append(daemonArgs, "--shutdown-grace", opts.ShutdownGrace.String())
+	}
+	if opts.NoPassthrough {
+		daemonArgs = append(daemonArgs, "--no-passthrough")
+	}
+	if opts.WriteLock {
+		daemonArgs = append(daemonArgs, "--write-lock")
+	}
+	if opts.MaxInputLen > 0 {
+		daemonArgs = append(daemonArgs, "--max-input-bytes", strconv.Itoa(opts.MaxInputLen))
+	}
+	if opts.SubmitNewline != "" {
+		daemonArgs = append(daemonArgs, "--submit-newline", opts.SubmitNewline)
+	}
+	if opts.SubmitDelay != "" {
+		daemonArgs = append(daemonArgs, "--submit-delay", opts.SubmitDelay)
+	}
+	if opts.ModifierEnter != "" {
+		daemonArgs = append(daemonArgs, "--modifier-enter", opts.ModifierEnter)
+	}
+	if opts.InputPlaceholder != "" {
+		daemonArgs = append(daemonArgs, "--input-placeholder", opts.InputPlaceholder)
+	}
+	if opts.DurationPrecision != "" {
+		daemonArgs = append(daemonArgs, "--duration-precision", opts.DurationPrecision)
+	}
+	if opts.ScrollOnOutput != "" {
+		daemonArgs = append(daemonArgs, "--scroll-on-output", opts.ScrollOnOutput)
+	}
+	if opts.Rows > 0 {
+		daemonArgs = append(daemonArgs, "--rows", strconv.Itoa(opts.Rows))
+	}
+	if opts.Cols > 0 {
+		daemonArgs = append(daemonArgs, "--cols", strconv.Itoa(opts.Cols))
+	}
+	if opts.ReadyRegex != "" {
+		daemonArgs = append(daemonArgs, "--ready-regex", opts.ReadyRegex)
+	}
+	if opts.OnMessage != "" {
+		daemonArgs = append(daemonArgs, "--on-message", opts.OnMessage)
+	}
+	if opts.StartMessage != "" {
+		daemonArgs = append(daemonArgs, "--start-message", opts.StartMessage)
+	}
+	if opts.StartMessagePriority != "" {
+		daemonArgs = append(daemonArgs, "--start-message-priority", opts.StartMessagePriority)
+	}
+	if opts.NoHooks {
+		daemonArgs = append(daemonArgs, "--no-hooks")
+	}
+	if opts.PassthroughIdle > 0 {
+		daemonArgs = append(daemonArgs, "--passthrough-idle-timeout", opts.PassthroughIdle.String())
+	}
+	if opts.IdleThreshold > 0 {
+		daemonArgs = append(daemonArgs, "--idle-threshold", opts.IdleThreshold.String())
+	}
+	if opts.StatusFile != "" {
+		daemonArgs = append(daemonArgs, "--status-file", opts.StatusFile)
+	}
+	if opts.ActivityLog.MaxSize > 0 {
+		daemonArgs = append(daemonArgs, "--activity-log-max-size", strconv.FormatInt(opts.ActivityLog.MaxSize, 10))
+	}
+	if opts.ActivityLog.MaxAge > 0 {
+		daemonArgs = append(daemonArgs, "--activity-log-max-age", opts.ActivityLog.MaxAge.String())
+	}
+	if opts.ActivityLog.MaxFiles > 0 {
+		daemonArgs = append(daemonArgs, "--activity-log-max-files", strconv.Itoa(opts.ActivityLog.MaxFiles))
+	}
 	for _, ov := range opts.Overrides {
 		daemonArgs = append(daemonArgs, "--override", ov)
 	}
@@ -305,17 +570,7 @@ func ForkDaemon(opts ForkDaemonOpts) error {
 	cmd := exec.Command(exe, daemonArgs...)
 	cmd.SysProcAttr = NewSysProcAttr()
 
-	// Explicitly build environment: inherit parent env + additions.
-	// Filter CLAUDECODE to prevent "nested session" errors when an agent
-	// (running inside Claude Code) spawns another agent.
-	env := filteredEnv(os.Environ(), "CLAUDECODE")
-	if h2Dir, err := config.ResolveDir(); err == nil {
-		env = append(env, "H2_DIR="+h2Dir)
-	}
-	if opts.Pod != "" {
-		env = append(env, "H2_POD="+opts.Pod)
-	}
-	cmd.Env = env
+	cmd.Env = daemonEnv(opts)
 
 	// Set working directory for the child process.
 	if opts.CWD != "" {
@@ -358,6 +613,45 @@ func ForkDaemon(opts ForkDaemonOpts) error {
 	return fmt.Errorf("daemon did not start (socket %s not found)", sockPath)
 }
 
+// RoleEnvVar carries the role's env map from ForkDaemon to the daemon as
+// JSON in the daemon's environment, keeping values such as API keys off
+// its command line, where any local user could read them.
+const RoleEnvVar = "H2_ROLE_ENV"
+
+// daemonEnv builds the forked daemon's environment: the parent's, plus
+// h2's own variables and the role env. CLAUDECODE is filtered to prevent
+// "nested session" errors when an agent (running inside Claude Code)
+// spawns another agent.
+func daemonEnv(opts ForkDaemonOpts) []string {
+	env := filteredEnv(os.Environ(), "CLAUDECODE", RoleEnvVar)
+	if h2Dir, err := config.ResolveDir(); err == nil {
+		env = append(env, "H2_DIR="+h2Dir)
+	}
+	if opts.Pod != "" {
+		env = append(env, "H2_POD="+opts.Pod)
+	}
+	if len(opts.Env) > 0 {
+		data, _ := json.Marshal(opts.Env)
+		env = append(env, RoleEnvVar+"="+string(data))
+	}
+	return env
+}
+
+// TakeRoleEnv returns the role env ForkDaemon passed in RoleEnvVar and
+// removes the variable, so it doesn't reach the child as well.
+func TakeRoleEnv() (map[string]string, error) {
+	data, ok := os.LookupEnv(RoleEnvVar)
+	if !ok {
+		return nil, nil
+	}
+	os.Unsetenv(RoleEnvVar)
+	var env map[string]string
+	if err := json.Unmarshal([]byte(data), &env); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", RoleEnvVar, err)
+	}
+	return env, nil
+}
+
 // filteredEnv returns a copy of env with entries matching any of the given
 // keys removed. This prevents env vars like CLAUDECODE from leaking into
 // child agent processes and triggering nested-session detection.
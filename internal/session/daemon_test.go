@@ -1,6 +1,8 @@
 package session
 
 import (
+	"os"
+	"strings"
 	"testing"
 )
 
@@ -166,3 +168,40 @@ func TestForkDaemonOpts_AllNewFields(t *testing.T) {
 		t.Fatalf("DisallowedTools not preserved: got %v", opts.DisallowedTools)
 	}
 }
+
+func TestDaemonEnv_RoleEnvRoundTrip(t *testing.T) {
+	t.Setenv(RoleEnvVar, `{"STALE":"1"}`) // inherited from a parent daemon
+	opts := ForkDaemonOpts{Env: map[string]string{"API_KEY": "s3cret", "EMPTY": ""}}
+
+	env := daemonEnv(opts)
+	var roleEnv []string
+	for _, e := range env {
+		if strings.HasPrefix(e, RoleEnvVar+"=") {
+			roleEnv = append(roleEnv, e)
+		}
+	}
+	if len(roleEnv) != 1 || strings.Contains(roleEnv[0], "STALE") {
+		t.Fatalf("role env entries = %q, want just this role's", roleEnv)
+	}
+
+	// The daemon reads it back and drops it from its own environment.
+	t.Setenv(RoleEnvVar, strings.TrimPrefix(roleEnv[0], RoleEnvVar+"="))
+	got, err := TakeRoleEnv()
+	if err != nil {
+		t.Fatalf("TakeRoleEnv: %v", err)
+	}
+	if len(got) != 2 || got["API_KEY"] != "s3cret" || got["EMPTY"] != "" {
+		t.Errorf("TakeRoleEnv = %v, want the role env", got)
+	}
+	if _, ok := os.LookupEnv(RoleEnvVar); ok {
+		t.Errorf("%s should be unset after TakeRoleEnv", RoleEnvVar)
+	}
+}
+
+func TestTakeRoleEnv_Unset(t *testing.T) {
+	t.Setenv(RoleEnvVar, "")
+	os.Unsetenv(RoleEnvVar)
+	if env, err := TakeRoleEnv(); env != nil || err != nil {
+		t.Errorf("TakeRoleEnv = %v, %v; want nil, nil", env, err)
+	}
+}
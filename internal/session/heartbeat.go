@@ -1,10 +1,14 @@
 package session
 
 import (
+	"context"
+	"log"
+	"os"
 	"os/exec"
 	"time"
 
 	"h2/internal/session/agent"
+	"h2/internal/session/agent/collector"
 	"h2/internal/session/message"
 )
 
@@ -12,7 +16,24 @@ import (
 type HeartbeatConfig struct {
 	IdleTimeout time.Duration
 	Message     string
-	Condition   string // optional shell command; nudge only if exit code 0
+	Condition   string           // optional shell command; nudge only if exit code 0
+	Priority    message.Priority // nudge priority (0 = idle)
+
+	// ConditionTimeout bounds each Condition run (0 = DefaultConditionTimeout).
+	ConditionTimeout time.Duration
+
+	// EscalateAfter is how many nudges in a row may go unanswered (no
+	// output from the agent) before the next one escalates (0 = never).
+	// Escalation sends EscalateMessage (default Message) at interrupt
+	// priority and runs EscalateCommand; with only a command, no message.
+	EscalateAfter   int
+	EscalateMessage string
+	EscalateCommand string
+
+	// IdleThreshold is the agent's idle threshold (0 = the collector
+	// default). Typing a nudge echoes into the PTY and keeps the agent
+	// active this long, which must not count as an answer.
+	IdleThreshold time.Duration
 
 	Agent     *agent.Agent
 	Queue     *message.MessageQueue
@@ -20,10 +41,27 @@ type HeartbeatConfig struct {
 	Stop      <-chan struct{}
 }
 
+// DefaultConditionTimeout bounds a heartbeat condition command when the
+// role doesn't set condition_timeout.
+const DefaultConditionTimeout = 5 * time.Second
+
+// nudgeEchoGrace is how long past the idle threshold a delivered nudge's
+// echo may still hold the agent active. Var so tests can override it.
+var nudgeEchoGrace = time.Second
+
 // RunHeartbeat monitors agent state and sends a nudge message when the agent
 // has been idle for the configured duration. If a condition command is set,
-// the nudge is only sent when the command exits 0.
+// the nudge is only sent when the command exits 0 within ConditionTimeout;
+// a non-zero exit, a failure to start, or a timeout skips the nudge until
+// the agent's state next changes.
+//
+// Nudges the agent doesn't answer with output are counted; once
+// EscalateAfter have gone unanswered, the next heartbeat escalates instead
+// and the count starts over. Any output from the agent once the last nudge
+// has been delivered and its echo has settled resets it.
 func RunHeartbeat(cfg HeartbeatConfig) {
+	unanswered := 0
+	var lastNudge nudge
 	for {
 		// Wait for agent to become idle.
 		if !waitForIdle(cfg.Agent, cfg.Stop) {
@@ -35,13 +73,17 @@ func RunHeartbeat(cfg HeartbeatConfig) {
 		fired := waitForTimer(timer, cfg.Agent, cfg.Stop)
 		if !fired {
 			timer.Stop()
-			continue // agent went active or stop signaled
+			select {
+			case <-cfg.Stop:
+				return
+			default:
+			}
+			continue // agent went active
 		}
 
 		// Timer fired and agent is still idle. Check condition if set.
 		if cfg.Condition != "" {
-			cmd := exec.Command("sh", "-c", cfg.Condition)
-			if err := cmd.Run(); err != nil {
+			if !conditionMet(cfg.Condition, cfg.ConditionTimeout) {
 				// Condition not met — wait for next state change before retrying.
 				select {
 				case <-cfg.Agent.StateChanged():
@@ -52,9 +94,92 @@ func RunHeartbeat(cfg HeartbeatConfig) {
 			}
 		}
 
+		// Any state change since the last nudge settled means the agent
+		// produced output, answering the nudges so far.
+		if cfg.Agent.StateChangedAt().After(lastNudge.settledAt(cfg)) {
+			unanswered = 0
+		}
+		lastNudge = nudge{sentAt: time.Now()}
+		if cfg.EscalateAfter > 0 && unanswered >= cfg.EscalateAfter {
+			unanswered = 0
+			escalate(cfg)
+			continue
+		}
+		unanswered++
+
 		// Send the nudge.
-		message.PrepareMessage(cfg.Queue, cfg.AgentName, "h2-heartbeat", cfg.Message, message.PriorityIdle)
+		priority := cfg.Priority
+		if priority == 0 {
+			priority = message.PriorityIdle
+		}
+		lastNudge.id, _ = message.PrepareMessage(cfg.Queue, cfg.AgentName, "h2-heartbeat", cfg.Message, priority)
+	}
+}
+
+// nudge records the last heartbeat message sent.
+type nudge struct {
+	id     string // queued message ID ("" if none was queued)
+	sentAt time.Time
+}
+
+// settledAt returns the time after which agent activity answers the nudge:
+// once it has been delivered and its echo has had time to die down, or its
+// send time while it is still queued.
+func (n nudge) settledAt(cfg HeartbeatConfig) time.Time {
+	if n.id == "" {
+		return n.sentAt
+	}
+	msg := cfg.Queue.Lookup(n.id)
+	if msg == nil || msg.DeliveredAt == nil {
+		return n.sentAt
+	}
+	threshold := cfg.IdleThreshold
+	if threshold <= 0 {
+		threshold = collector.IdleThreshold
+	}
+	return msg.DeliveredAt.Add(threshold + nudgeEchoGrace)
+}
+
+// escalationTimeout bounds how long an escalate_command may run. Var so
+// tests can override it.
+var escalationTimeout = 30 * time.Second
+
+// escalate handles a heartbeat after EscalateAfter unanswered nudges: it
+// sends the escalation message at interrupt priority (unless only a command
+// is configured) and runs EscalateCommand, if set, with sh -c.
+func escalate(cfg HeartbeatConfig) {
+	if cfg.EscalateMessage != "" || cfg.EscalateCommand == "" {
+		body := cfg.EscalateMessage
+		if body == "" {
+			body = cfg.Message
+		}
+		message.PrepareMessage(cfg.Queue, cfg.AgentName, "h2-heartbeat", body, message.PriorityInterrupt)
+	}
+	if cfg.EscalateCommand != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), escalationTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, "sh", "-c", cfg.EscalateCommand)
+		cmd.Env = append(os.Environ(), "H2_ACTOR="+cfg.AgentName)
+		cmd.WaitDelay = time.Second
+		if err := cmd.Run(); err != nil {
+			log.Printf("heartbeat escalate_command failed: %v", err)
+		}
+	}
+}
+
+// conditionMet runs condition with sh -c and reports whether it exited 0
+// within timeout (0 = DefaultConditionTimeout). A command still running at
+// the deadline is killed.
+func conditionMet(condition string, timeout time.Duration) bool {
+	if timeout <= 0 {
+		timeout = DefaultConditionTimeout
 	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", condition)
+	// Don't wait on output pipes held open by a killed command's children.
+	cmd.WaitDelay = time.Second
+	return cmd.Run() == nil
 }
 
 // waitForIdle blocks until the agent is idle. Returns false if stop is signaled.
@@ -1,6 +1,12 @@
 package session
 
 import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -71,6 +77,39 @@ func TestHeartbeat_NudgeAfterIdleTimeout(t *testing.T) {
 	close(stop)
 }
 
+func TestHeartbeat_ConfiguredPriority(t *testing.T) {
+	setFastIdleHeartbeat(t)
+	a := newTestAgent()
+	defer a.Stop()
+	a.StartCollectors()
+	q := message.NewMessageQueue()
+	stop := make(chan struct{})
+	defer close(stop)
+
+	go RunHeartbeat(HeartbeatConfig{
+		IdleTimeout: 50 * time.Millisecond,
+		Message:     "watchdog",
+		Priority:    message.PriorityInterrupt,
+		Agent:       a,
+		Queue:       q,
+		AgentName:   "test-agent",
+		Stop:        stop,
+	})
+
+	deadline := time.After(2 * time.Second)
+	for q.PendingCount() == 0 {
+		select {
+		case <-deadline:
+			t.Fatal("timed out waiting for heartbeat nudge")
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	msg := q.Dequeue(true, false)
+	if msg == nil || msg.Priority != message.PriorityInterrupt {
+		t.Fatalf("expected interrupt-priority nudge, got %+v", msg)
+	}
+}
+
 func TestHeartbeat_CancelledWhenAgentGoesActive(t *testing.T) {
 	setFastIdleHeartbeat(t)
 	a := newTestAgent()
@@ -243,3 +282,306 @@ func TestHeartbeat_StopTerminatesLoop(t *testing.T) {
 		t.Error("expected no messages after stop")
 	}
 }
+
+func TestHeartbeat_StopWhileIdleTerminatesLoop(t *testing.T) {
+	setFastIdleHeartbeat(t)
+	a := newTestAgent()
+	defer a.Stop()
+	a.StartCollectors()
+	stop := make(chan struct{})
+
+	if !a.WaitForState(context.Background(), agent.StateIdle) {
+		t.Fatal("agent never went idle")
+	}
+	done := make(chan struct{})
+	go func() {
+		RunHeartbeat(HeartbeatConfig{
+			IdleTimeout: 10 * time.Second,
+			Message:     "should not arrive",
+			Agent:       a,
+			Queue:       message.NewMessageQueue(),
+			AgentName:   "test-agent",
+			Stop:        stop,
+		})
+		close(done)
+	}()
+
+	time.Sleep(50 * time.Millisecond) // let it start the idle timer
+	close(stop)
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("RunHeartbeat kept running after stop while the agent was idle")
+	}
+}
+
+// fakeCondition writes a condition script to a temp dir and returns the
+// command that runs it. The script exits with its first argument, after
+// sleeping for its second (if given).
+func fakeCondition(t *testing.T, args string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cond")
+	script := "#!/bin/sh\n[ -n \"$2\" ] && sleep \"$2\"\nexit \"$1\"\n"
+	if err := os.WriteFile(path, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path + " " + args
+}
+
+func TestConditionMet(t *testing.T) {
+	tests := []struct {
+		name string
+		args string
+		want bool
+	}{
+		{"exit 0", "0", true},
+		{"exit 1", "1", false},
+		{"exit 3", "3", false},
+		{"timeout", "0 5", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := time.Now()
+			if got := conditionMet(fakeCondition(t, tt.args), 200*time.Millisecond); got != tt.want {
+				t.Errorf("conditionMet = %v, want %v", got, tt.want)
+			}
+			if elapsed := time.Since(start); elapsed > 3*time.Second {
+				t.Errorf("conditionMet took %v, want it bounded by the timeout", elapsed)
+			}
+		})
+	}
+}
+
+func TestConditionMet_MissingCommand(t *testing.T) {
+	if conditionMet(filepath.Join(t.TempDir(), "nope"), time.Second) {
+		t.Error("a command that can't run should not count as met")
+	}
+}
+
+func TestHeartbeat_ConditionTimeoutGates(t *testing.T) {
+	setFastIdleHeartbeat(t)
+	a := newTestAgent()
+	defer a.Stop()
+	a.StartCollectors()
+	q := message.NewMessageQueue()
+	stop := make(chan struct{})
+	defer close(stop)
+
+	// The condition would succeed, but only after the timeout.
+	go RunHeartbeat(HeartbeatConfig{
+		IdleTimeout:      100 * time.Millisecond,
+		Message:          "slow nudge",
+		Condition:        fakeCondition(t, "0 5"),
+		ConditionTimeout: 100 * time.Millisecond,
+		Agent:            a,
+		Queue:            q,
+		AgentName:        "test-agent",
+		Stop:             stop,
+	})
+
+	time.Sleep(1500 * time.Millisecond)
+	if q.PendingCount() != 0 {
+		t.Error("expected no messages; a timed-out condition should gate the nudge")
+	}
+}
+
+// drainHeartbeats waits until the queue holds n messages and returns them
+// in priority order.
+func drainHeartbeats(t *testing.T, q *message.MessageQueue, n int) []*message.Message {
+	t.Helper()
+	deadline := time.After(3 * time.Second)
+	for q.PendingCount() < n {
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for %d heartbeat messages (have %d)", n, q.PendingCount())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	var msgs []*message.Message
+	for msg := q.Dequeue(true, false); msg != nil; msg = q.Dequeue(true, false) {
+		msgs = append(msgs, msg)
+	}
+	return msgs
+}
+
+func TestHeartbeat_EscalatesAfterUnansweredNudges(t *testing.T) {
+	setFastIdleHeartbeat(t)
+	a := newTestAgent()
+	defer a.Stop()
+	a.StartCollectors()
+	q := message.NewMessageQueue()
+	stop := make(chan struct{})
+
+	go RunHeartbeat(HeartbeatConfig{
+		IdleTimeout:     50 * time.Millisecond,
+		Message:         "nudge",
+		EscalateAfter:   2,
+		EscalateMessage: "WAKE UP",
+		Agent:           a,
+		Queue:           q,
+		AgentName:       "test-agent",
+		Stop:            stop,
+	})
+
+	msgs := drainHeartbeats(t, q, 3)
+	close(stop)
+
+	// Interrupt-priority messages dequeue first.
+	if msgs[0].Priority != message.PriorityInterrupt || msgs[0].Body != "WAKE UP" {
+		t.Errorf("first message = %v %q, want interrupt %q", msgs[0].Priority, msgs[0].Body, "WAKE UP")
+	}
+	for _, msg := range msgs[1:3] {
+		if msg.Priority != message.PriorityIdle || msg.Body != "nudge" {
+			t.Errorf("message = %v %q, want idle %q", msg.Priority, msg.Body, "nudge")
+		}
+	}
+}
+
+func TestHeartbeat_EscalateCommandOnly(t *testing.T) {
+	setFastIdleHeartbeat(t)
+	a := newTestAgent()
+	defer a.Stop()
+	a.StartCollectors()
+	q := message.NewMessageQueue()
+	stop := make(chan struct{})
+	defer close(stop)
+
+	marker := filepath.Join(t.TempDir(), "escalated")
+	go RunHeartbeat(HeartbeatConfig{
+		IdleTimeout:     50 * time.Millisecond,
+		Message:         "nudge",
+		EscalateAfter:   1,
+		EscalateCommand: "echo \"$H2_ACTOR\" > " + marker + " 2>/dev/null || true",
+		Agent:           a,
+		Queue:           q,
+		AgentName:       "test-agent",
+		Stop:            stop,
+	})
+
+	deadline := time.After(3 * time.Second)
+	for {
+		data, err := os.ReadFile(marker)
+		if err == nil && len(data) > 0 {
+			if got := string(data); got != "test-agent\n" {
+				t.Errorf("escalate_command saw H2_ACTOR=%q", got)
+			}
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatal("timed out waiting for escalate_command")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	for msg := q.Dequeue(true, false); msg != nil; msg = q.Dequeue(true, false) {
+		if msg.Priority == message.PriorityInterrupt {
+			t.Errorf("command-only escalation sent an interrupt message %q", msg.Body)
+		}
+	}
+}
+
+func TestHeartbeat_OutputResetsEscalation(t *testing.T) {
+	setFastIdleHeartbeat(t)
+	a := newTestAgent()
+	defer a.Stop()
+	a.StartCollectors()
+	q := message.NewMessageQueue()
+	stop := make(chan struct{})
+
+	go RunHeartbeat(HeartbeatConfig{
+		IdleTimeout:   200 * time.Millisecond,
+		Message:       "nudge",
+		EscalateAfter: 1,
+		Agent:         a,
+		Queue:         q,
+		AgentName:     "test-agent",
+		Stop:          stop,
+	})
+
+	// Answer each nudge with output, so none go unanswered.
+	for i := 0; i < 3; i++ {
+		for _, msg := range drainHeartbeats(t, q, 1) {
+			if msg.Priority == message.PriorityInterrupt {
+				t.Fatalf("nudge %d escalated although the agent answered", i)
+			}
+		}
+		a.NoteOutput()
+	}
+	close(stop)
+}
+
+// echoPTY stands in for the child PTY: everything typed into it is echoed
+// back as agent output, as a real terminal would.
+type echoPTY struct {
+	mu    sync.Mutex
+	buf   bytes.Buffer
+	agent *agent.Agent
+}
+
+func (p *echoPTY) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	p.buf.Write(b)
+	p.mu.Unlock()
+	p.agent.NoteOutput()
+	return len(b), nil
+}
+
+func (p *echoPTY) String() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.buf.String()
+}
+
+func TestHeartbeat_DeliveredNudgeEchoIsNotAnAnswer(t *testing.T) {
+	setFastIdleHeartbeat(t)
+	old := nudgeEchoGrace
+	nudgeEchoGrace = 50 * time.Millisecond
+	t.Cleanup(func() { nudgeEchoGrace = old })
+	t.Setenv("HOME", t.TempDir())
+
+	a := newTestAgent()
+	defer a.Stop()
+	a.StartCollectors()
+	q := message.NewMessageQueue()
+	stop := make(chan struct{})
+	defer close(stop)
+	pty := &echoPTY{agent: a}
+
+	go message.RunDelivery(message.DeliveryConfig{
+		Queue:     q,
+		AgentName: "test-agent",
+		PtyWriter: pty,
+		IsIdle: func() bool {
+			st, _ := a.State()
+			return st == agent.StateIdle
+		},
+		WaitForIdle: func(ctx context.Context) bool {
+			return a.WaitForState(ctx, agent.StateIdle)
+		},
+		Stop: stop,
+	})
+	go RunHeartbeat(HeartbeatConfig{
+		IdleTimeout:     150 * time.Millisecond,
+		Message:         "nudge",
+		EscalateAfter:   1,
+		EscalateMessage: "WAKE UP",
+		Agent:           a,
+		Queue:           q,
+		AgentName:       "test-agent",
+		Stop:            stop,
+	})
+
+	// The agent never answers; only the nudge's own echo reaches the PTY.
+	deadline := time.After(5 * time.Second)
+	for !strings.Contains(pty.String(), "WAKE UP") {
+		select {
+		case <-deadline:
+			t.Fatalf("nudge was never escalated; PTY got %q", pty.String())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	if !strings.Contains(pty.String(), "[h2 message from: h2-heartbeat] nudge") {
+		t.Errorf("expected the nudge to be delivered before escalating, PTY got %q", pty.String())
+	}
+}
@@ -0,0 +1,44 @@
+package session
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"h2/internal/config"
+)
+
+// writeLifecycleState records the daemon and child PIDs in the session
+// dir's lifecycle.json so external supervisors can find this agent without
+// scanning sockets. No-op when the session has no session dir.
+func (s *Session) writeLifecycleState() {
+	if s.SessionDir == "" {
+		return
+	}
+	st := config.LifecycleState{
+		AgentName: s.Name,
+		DaemonPID: os.Getpid(),
+		Role:      s.RoleName,
+		Pod:       os.Getenv("H2_POD"),
+		StartedAt: s.StartTime.UTC().Format(time.RFC3339),
+	}
+	if s.Daemon != nil && s.Daemon.Listener != nil {
+		st.Socket = s.Daemon.Listener.Addr().String()
+	}
+	if s.VT != nil && s.VT.Cmd != nil && s.VT.Cmd.Process != nil {
+		st.ChildPID = s.VT.Cmd.Process.Pid
+	}
+	if startedAt, _, _ := s.RunStats(); !startedAt.IsZero() {
+		st.ChildStartedAt = startedAt.UTC().Format(time.RFC3339)
+	}
+	if err := config.WriteLifecycleState(s.SessionDir, st); err != nil {
+		log.Printf("warning: %v", err)
+	}
+}
+
+// removeLifecycleState deletes lifecycle.json on clean shutdown.
+func (s *Session) removeLifecycleState() {
+	if err := config.RemoveLifecycleState(s.SessionDir); err != nil {
+		log.Printf("warning: remove lifecycle state: %v", err)
+	}
+}
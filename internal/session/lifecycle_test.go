@@ -0,0 +1,61 @@
+package session
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"h2/internal/config"
+)
+
+func TestLifecycleState_WrittenOnLaunchRemovedOnStop(t *testing.T) {
+	t.Setenv("H2_POD", "backend")
+	s := newTestSession()
+	s.SessionDir = t.TempDir()
+	s.RoleName = "coder"
+	s.StartTime = time.Now()
+
+	child := exec.Command("sleep", "5")
+	if err := child.Start(); err != nil {
+		t.Fatalf("start child: %v", err)
+	}
+	t.Cleanup(func() {
+		child.Process.Kill()
+		child.Wait()
+	})
+	s.VT.Cmd = child
+
+	s.noteChildStarted()
+	s.writeLifecycleState()
+
+	st, err := config.ReadLifecycleState(s.SessionDir)
+	if err != nil {
+		t.Fatalf("read lifecycle state: %v", err)
+	}
+	if st.AgentName != "test" || st.Role != "coder" || st.Pod != "backend" {
+		t.Errorf("unexpected identity: %+v", st)
+	}
+	if st.DaemonPID != os.Getpid() {
+		t.Errorf("DaemonPID = %d, want %d", st.DaemonPID, os.Getpid())
+	}
+	if st.ChildPID != child.Process.Pid {
+		t.Errorf("ChildPID = %d, want %d", st.ChildPID, child.Process.Pid)
+	}
+	if _, err := time.Parse(time.RFC3339, st.StartedAt); err != nil {
+		t.Errorf("StartedAt %q not RFC3339: %v", st.StartedAt, err)
+	}
+	if st.ChildStartedAt == "" {
+		t.Error("ChildStartedAt should be set once the child has started")
+	}
+	if !st.Alive() {
+		t.Error("state for the current process should be alive")
+	}
+
+	s.Stop()
+
+	if _, err := os.Stat(filepath.Join(s.SessionDir, "lifecycle.json")); !os.IsNotExist(err) {
+		t.Fatalf("lifecycle.json should be removed on clean stop, stat err = %v", err)
+	}
+}
@@ -1,7 +1,13 @@
 package session
 
 import (
+	"errors"
+	"fmt"
+	"io"
 	"net"
+	"os"
+	"path/filepath"
+	"time"
 
 	"h2/internal/session/message"
 )
@@ -31,6 +37,10 @@ func (d *Daemon) handleConn(conn net.Conn) {
 		d.handleShow(conn, req)
 	case "status":
 		d.handleStatus(conn)
+	case "watch_state":
+		d.handleWatchState(conn)
+	case "screen":
+		d.handleScreen(conn, req)
 	case "attach":
 		d.handleAttach(conn, req)
 	case "hook_event":
@@ -50,11 +60,31 @@ func (d *Daemon) handleSend(conn net.Conn, req *message.Request) {
 
 	s := d.Session
 
+	if req.Attachment != "" {
+		if err := checkAttachment(req); err != nil {
+			message.SendResponse(conn, &message.Response{Error: err.Error()})
+			return
+		}
+	}
+
 	if req.Raw {
 		// Raw mode: send body directly to PTY without prefix.
 		// Uses interrupt priority so it bypasses the blocked-agent check
 		// (the main use case is responding to permission prompts).
-		id := message.EnqueueRaw(s.Queue, req.Body)
+		// Control characters are stripped unless the sender opted out.
+		body := req.Body
+		if !req.Unsafe {
+			body = message.SanitizeRaw(body)
+		}
+		enqueue := message.EnqueueRaw
+		if req.InterruptFirst {
+			enqueue = message.EnqueueRawInterrupt
+		}
+		id, err := enqueue(s.Queue, body, req.CorrelationID)
+		if err != nil {
+			message.SendResponse(conn, &message.Response{Error: err.Error()})
+			return
+		}
 		message.SendResponse(conn, &message.Response{
 			OK:        true,
 			MessageID: id,
@@ -69,13 +99,47 @@ func (d *Daemon) handleSend(conn net.Conn, req *message.Request) {
 		})
 		return
 	}
+	if req.InterruptFirst {
+		// Interrupt delivery already stops the agent and waits for it to
+		// settle before typing the message.
+		priority = message.PriorityInterrupt
+	}
 
 	from := req.From
 	if from == "" {
 		from = "unknown"
 	}
 
-	id, err := message.PrepareMessage(s.Queue, s.Name, from, req.Body, priority)
+	escalateAfter := s.EscalateAfter
+	if req.EscalateAfter != "" {
+		d, err := time.ParseDuration(req.EscalateAfter)
+		if err != nil || d <= 0 {
+			message.SendResponse(conn, &message.Response{
+				Error: "invalid escalate_after " + req.EscalateAfter + ": must be a positive duration",
+			})
+			return
+		}
+		escalateAfter = d
+	}
+
+	id, err := message.Prepare(s.Queue, message.PrepareOpts{
+		AgentName:     s.Name,
+		From:          from,
+		Body:          req.Body,
+		Priority:      priority,
+		Attachment:    req.Attachment,
+		EscalateAfter: escalateAfter,
+		CorrelationID: req.CorrelationID,
+	})
+	var dup *message.DuplicateError
+	if errors.As(err, &dup) {
+		message.SendResponse(conn, &message.Response{
+			OK:        true,
+			MessageID: dup.OriginalID,
+			Deduped:   true,
+		})
+		return
+	}
 	if err != nil {
 		message.SendResponse(conn, &message.Response{
 			Error: err.Error(),
@@ -89,6 +153,25 @@ func (d *Daemon) handleSend(conn net.Conn, req *message.Request) {
 	})
 }
 
+// checkAttachment validates a send request's attachment: it must be an
+// absolute path to an existing regular file, and raw sends can't carry one.
+func checkAttachment(req *message.Request) error {
+	if req.Raw {
+		return fmt.Errorf("attachments can't be sent raw")
+	}
+	if !filepath.IsAbs(req.Attachment) {
+		return fmt.Errorf("attachment path must be absolute: %s", req.Attachment)
+	}
+	info, err := os.Stat(req.Attachment)
+	if err != nil {
+		return fmt.Errorf("attachment: %w", err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("attachment is not a regular file: %s", req.Attachment)
+	}
+	return nil
+}
+
 func (d *Daemon) handleShow(conn net.Conn, req *message.Request) {
 	defer conn.Close()
 
@@ -108,6 +191,9 @@ func (d *Daemon) handleShow(conn net.Conn, req *message.Request) {
 		Status:    string(msg.Status),
 		FilePath:  msg.FilePath,
 		CreatedAt: msg.CreatedAt.Format("2006-01-02 15:04:05"),
+
+		CorrelationID: msg.CorrelationID,
+		Attachment:    msg.Attachment,
 	}
 	if msg.DeliveredAt != nil {
 		info.DeliveredAt = msg.DeliveredAt.Format("2006-01-02 15:04:05")
@@ -127,12 +213,59 @@ func (d *Daemon) handleStatus(conn net.Conn) {
 	})
 }
 
+// handleWatchState streams the agent's status: one response right away and
+// another on every state or sub-state change, until the client disconnects
+// or the daemon stops.
+func (d *Daemon) handleWatchState(conn net.Conn) {
+	defer conn.Close()
+	s := d.Session
+
+	// The client never sends more; a read returning means it hung up.
+	gone := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, conn)
+		close(gone)
+	}()
+
+	last := ""
+	for {
+		// Take the channel before reading the state so a transition in
+		// between isn't missed.
+		changed := s.Agent.StateChanged()
+		info := d.AgentInfo()
+		if key := info.State + "/" + info.SubState; key != last {
+			if err := message.SendResponse(conn, &message.Response{OK: true, Agent: info}); err != nil {
+				return
+			}
+			last = key
+		}
+		select {
+		case <-changed:
+		case <-gone:
+			return
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+func (d *Daemon) handleScreen(conn net.Conn, req *message.Request) {
+	defer conn.Close()
+	message.SendResponse(conn, &message.Response{
+		OK:     true,
+		Screen: d.Session.ScreenInfo(req.History),
+	})
+}
+
 func (d *Daemon) handleStop(conn net.Conn) {
 	defer conn.Close()
 	message.SendResponse(conn, &message.Response{OK: true})
 
 	// Trigger graceful shutdown: set Quit so lifecycleLoop exits.
 	s := d.Session
+	s.VT.Mu.Lock()
+	s.endAttaches("stop")
+	s.VT.Mu.Unlock()
 	s.Quit = true
 	s.VT.KillChild()
 	// Signal quitCh so lifecycleLoop unblocks if the child already exited
@@ -1,8 +1,16 @@
 package session
 
 import (
+	"encoding/json"
+	"io"
 	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/vito/midterm"
 
 	"h2/internal/session/message"
 	"h2/internal/session/virtualterminal"
@@ -41,3 +49,277 @@ func TestHandleStop_SetsQuitAndRespondsOK(t *testing.T) {
 		t.Error("expected Session.Quit to be true after stop")
 	}
 }
+
+// sendViaDaemon runs handleSend for req over a pipe and returns the response.
+func sendViaDaemon(t *testing.T, d *Daemon, req *message.Request) *message.Response {
+	t.Helper()
+	server, client := net.Pipe()
+	defer client.Close()
+
+	go d.handleSend(server, req)
+
+	resp, err := message.ReadResponse(client)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	return resp
+}
+
+func TestHandleSend_EscalateAfter(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	s := New("test", "true", nil)
+	s.EscalateAfter = 10 * time.Minute
+	d := &Daemon{Session: s}
+
+	// Role default applies when the request doesn't specify a window.
+	resp := sendViaDaemon(t, d, &message.Request{Type: "send", Priority: "idle", From: "a", Body: "hi"})
+	if !resp.OK {
+		t.Fatalf("send failed: %s", resp.Error)
+	}
+	if got := s.Queue.Lookup(resp.MessageID).EscalateAfter; got != 10*time.Minute {
+		t.Errorf("EscalateAfter = %v, want role default 10m", got)
+	}
+
+	// Per-send value overrides the role default.
+	resp = sendViaDaemon(t, d, &message.Request{Type: "send", Priority: "idle", From: "a", Body: "hi", EscalateAfter: "30s"})
+	if !resp.OK {
+		t.Fatalf("send failed: %s", resp.Error)
+	}
+	if got := s.Queue.Lookup(resp.MessageID).EscalateAfter; got != 30*time.Second {
+		t.Errorf("EscalateAfter = %v, want 30s", got)
+	}
+
+	for _, bad := range []string{"soon", "0s"} {
+		resp = sendViaDaemon(t, d, &message.Request{Type: "send", Priority: "idle", From: "a", Body: "hi", EscalateAfter: bad})
+		if resp.OK {
+			t.Errorf("expected error for escalate_after %q", bad)
+		}
+	}
+}
+
+func TestHandleSend_Attachment(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	s := New("test", "true", nil)
+	d := &Daemon{Session: s}
+	file := filepath.Join(home, "shot.png")
+	os.WriteFile(file, []byte("png"), 0o644)
+
+	resp := sendViaDaemon(t, d, &message.Request{Type: "send", Priority: "normal", From: "a", Attachment: file})
+	if !resp.OK {
+		t.Fatalf("send failed: %s", resp.Error)
+	}
+	if got := s.Queue.Lookup(resp.MessageID).Attachment; got != file {
+		t.Errorf("Attachment = %q, want %q", got, file)
+	}
+
+	for _, req := range []*message.Request{
+		{Type: "send", Priority: "normal", From: "a", Attachment: "shot.png"},
+		{Type: "send", Priority: "normal", From: "a", Attachment: filepath.Join(home, "missing.png")},
+		{Type: "send", Priority: "normal", From: "a", Attachment: home},
+		{Type: "send", From: "a", Body: "x", Raw: true, Attachment: file},
+	} {
+		if resp := sendViaDaemon(t, d, req); resp.OK {
+			t.Errorf("attachment %q (raw=%v): expected error", req.Attachment, req.Raw)
+		}
+	}
+	if n := s.Queue.PendingCount(); n != 1 {
+		t.Errorf("PendingCount = %d, want only the valid message queued", n)
+	}
+}
+
+func TestHandleSend_RawModes(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	s := New("test", "true", nil)
+	d := &Daemon{Session: s}
+	body := "yes\x1b[31m\x03 <ok>\n"
+
+	// Normal: queued as an inter-agent message, prefixed at delivery.
+	resp := sendViaDaemon(t, d, &message.Request{Type: "send", Priority: "normal", From: "a", Body: body})
+	if !resp.OK {
+		t.Fatalf("send failed: %s", resp.Error)
+	}
+	msg := s.Queue.Lookup(resp.MessageID)
+	if msg.Raw || msg.From != "a" || msg.FilePath == "" {
+		t.Errorf("normal send: Raw=%v From=%q FilePath=%q, want prefixed file-backed message", msg.Raw, msg.From, msg.FilePath)
+	}
+
+	// Raw: no prefix, control characters stripped.
+	resp = sendViaDaemon(t, d, &message.Request{Type: "send", From: "a", Body: body, Raw: true})
+	if !resp.OK {
+		t.Fatalf("raw send failed: %s", resp.Error)
+	}
+	msg = s.Queue.Lookup(resp.MessageID)
+	if !msg.Raw || msg.FilePath != "" {
+		t.Errorf("raw send: Raw=%v FilePath=%q, want raw message", msg.Raw, msg.FilePath)
+	}
+	if msg.Body != "yes[31m <ok>\n" {
+		t.Errorf("raw body = %q, want control characters stripped", msg.Body)
+	}
+
+	// Raw unsafe: exact bytes.
+	resp = sendViaDaemon(t, d, &message.Request{Type: "send", From: "a", Body: body, Raw: true, Unsafe: true})
+	if !resp.OK {
+		t.Fatalf("raw unsafe send failed: %s", resp.Error)
+	}
+	if got := s.Queue.Lookup(resp.MessageID).Body; got != body {
+		t.Errorf("raw unsafe body = %q, want %q", got, body)
+	}
+}
+
+func TestHandleSend_ClosedQueue(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	s := New("test", "true", nil)
+	d := &Daemon{Session: s}
+	s.Queue.Close()
+
+	for _, req := range []*message.Request{
+		{Type: "send", Priority: "normal", From: "a", Body: "hi"},
+		{Type: "send", From: "a", Body: "y", Raw: true},
+		{Type: "send", From: "a", Body: "y", Raw: true, InterruptFirst: true},
+	} {
+		resp := sendViaDaemon(t, d, req)
+		if resp.OK || resp.Error != message.ErrQueueClosed.Error() {
+			t.Errorf("send %+v on a closed queue: OK=%v Error=%q, want ErrQueueClosed", req, resp.OK, resp.Error)
+		}
+	}
+}
+
+func TestHandleScreen_ReturnsVisibleRows(t *testing.T) {
+	s := New("test", "true", nil)
+	s.VT = &virtualterminal.VT{Rows: 4, Cols: 10, ChildRows: 2, Vt: midterm.NewTerminal(2, 10)}
+	s.Client = s.NewClient()
+	s.VT.Vt.Write([]byte("hello\r\nworld"))
+	d := &Daemon{Session: s}
+
+	server, client := net.Pipe()
+	defer client.Close()
+	go d.handleScreen(server, &message.Request{Type: "screen"})
+
+	resp, err := message.ReadResponse(client)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	if !resp.OK || resp.Screen == nil {
+		t.Fatalf("expected screen in response, got %+v", resp)
+	}
+	if resp.Screen.Rows != 2 || resp.Screen.Cols != 10 {
+		t.Errorf("size = %dx%d, want 2x10", resp.Screen.Rows, resp.Screen.Cols)
+	}
+	lines := strings.Split(resp.Screen.ANSI, "\n")
+	if len(lines) != 2 || !strings.Contains(lines[0], "hello") || !strings.Contains(lines[1], "world") {
+		t.Errorf("unexpected screen: %q", resp.Screen.ANSI)
+	}
+	if resp.Screen.Plain != "hello\nworld" {
+		t.Errorf("plain = %q, want %q", resp.Screen.Plain, "hello\nworld")
+	}
+	if resp.Screen.CursorRow != 1 || resp.Screen.CursorCol != 5 {
+		t.Errorf("cursor = %d,%d, want 1,5", resp.Screen.CursorRow, resp.Screen.CursorCol)
+	}
+	if resp.Screen.Text != "" {
+		t.Errorf("text = %q, want no history unless requested", resp.Screen.Text)
+	}
+
+	server, client = net.Pipe()
+	defer client.Close()
+	go d.handleScreen(server, &message.Request{Type: "screen", History: true})
+	resp, err = message.ReadResponse(client)
+	if err != nil {
+		t.Fatalf("read response: %v", err)
+	}
+	if resp.Screen.Text != "hello\nworld" {
+		t.Errorf("history text = %q, want %q", resp.Screen.Text, "hello\nworld")
+	}
+}
+
+func TestHandleWatchState_StreamsTransitions(t *testing.T) {
+	s := newTestSession()
+	defer s.Stop()
+	d := &Daemon{Session: s, StartTime: time.Now()}
+
+	server, client := net.Pipe()
+	done := make(chan struct{})
+	go func() {
+		d.handleWatchState(server)
+		close(done)
+	}()
+
+	dec := json.NewDecoder(client)
+	var first message.Response
+	if err := dec.Decode(&first); err != nil {
+		t.Fatalf("read first status: %v", err)
+	}
+	if !first.OK || first.Agent == nil || first.Agent.State == "exited" {
+		t.Fatalf("first status = %+v, want the current state", first)
+	}
+
+	s.Agent.SetExited()
+	var next message.Response
+	if err := dec.Decode(&next); err != nil {
+		t.Fatalf("read transition: %v", err)
+	}
+	if next.Agent == nil || next.Agent.State != "exited" {
+		t.Fatalf("transition = %+v, want exited", next.Agent)
+	}
+
+	client.Close()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("watch handler kept running after the client hung up")
+	}
+}
+
+func TestHandleWatchState_EndsOnStop(t *testing.T) {
+	s := newTestSession()
+	d := &Daemon{Session: s, StartTime: time.Now()}
+
+	server, client := net.Pipe()
+	defer client.Close()
+	go io.Copy(io.Discard, client)
+	done := make(chan struct{})
+	go func() {
+		d.handleWatchState(server)
+		close(done)
+	}()
+
+	s.Stop()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("watch handler kept running after the daemon stopped")
+	}
+}
+
+func TestHandleSend_DedupeWindow(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	s := New("test", "true", nil)
+	s.Queue.SetDedupeWindow(time.Minute)
+	d := &Daemon{Session: s}
+
+	req := &message.Request{Type: "send", Priority: "normal", From: "bot", Body: "run the tests"}
+	first := sendViaDaemon(t, d, req)
+	if !first.OK || first.Deduped {
+		t.Fatalf("first send = %+v, want a fresh delivery", first)
+	}
+	retry := sendViaDaemon(t, d, req)
+	if !retry.OK || !retry.Deduped {
+		t.Fatalf("retry = %+v, want OK and Deduped", retry)
+	}
+	if retry.MessageID != first.MessageID {
+		t.Errorf("retry MessageID = %q, want the original %q", retry.MessageID, first.MessageID)
+	}
+	if n := s.Queue.PendingCount(); n != 1 {
+		t.Errorf("PendingCount = %d, want 1", n)
+	}
+	files, _ := os.ReadDir(filepath.Join(home, ".h2", "messages", "test"))
+	if len(files) != 1 {
+		t.Errorf("message files = %d, want 1 (the duplicate's file is removed)", len(files))
+	}
+
+	other := sendViaDaemon(t, d, &message.Request{Type: "send", Priority: "normal", From: "someone-else", Body: "run the tests"})
+	if other.Deduped {
+		t.Error("same body from a different sender should not be deduped")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,6 +23,11 @@ type WaitForIdleFunc func(ctx context.Context) bool
 // permission approval) and normal-priority messages should not be delivered.
 type IsBlockedFunc func() bool
 
+// DefaultSubmitDelay is the pause between typing a message and submitting it.
+// Ink-based TUIs batch input with React, so a submit keystroke that arrives
+// in the same read as the text can be lost; plain shells don't need it.
+const DefaultSubmitDelay = 50 * time.Millisecond
+
 // DeliveryConfig holds configuration for the delivery goroutine.
 type DeliveryConfig struct {
 	Queue       *MessageQueue
@@ -32,6 +38,18 @@ type DeliveryConfig struct {
 	WaitForIdle WaitForIdleFunc  // blocks until idle (for interrupt retry)
 	NoteInterrupt func()         // called when sending Ctrl+C for interrupt delivery
 	OnDeliver   func()           // called after each delivery (e.g. to render)
+	SubmitBytes []byte           // written after each message to submit it (nil = CR)
+	SubmitDelay time.Duration    // pause between the text and SubmitBytes (0 = none)
+	OnMessage   func(*Message)   // called with each delivered message (nil = none)
+	OnPrompt    func(*Message)   // called just before a message's text is written (nil = none)
+	// MentionAttachments refers to message attachments as "@path", the file
+	// mention syntax Claude Code expands; otherwise the bare path is used.
+	MentionAttachments bool
+	// StrictIdle holds every non-interrupt message (including normal
+	// priority) until IsIdle reports true, so nothing is typed into an agent
+	// mid-generation. WaitForIdle is used to wake delivery as soon as the
+	// agent goes idle.
+	StrictIdle bool
 	Stop        <-chan struct{}
 
 }
@@ -39,8 +57,20 @@ type DeliveryConfig struct {
 // EnqueueRaw creates a raw Message (no file, no prefix) with interrupt priority
 // and enqueues it. The delivery loop will write the body directly to the PTY.
 // This is used for responding to permission prompts and other cases where
-// exact text needs to be typed into the agent's terminal.
-func EnqueueRaw(q *MessageQueue, body string) string {
+// exact text needs to be typed into the agent's terminal. It fails with
+// ErrQueueClosed once the queue has been closed.
+func EnqueueRaw(q *MessageQueue, body, correlationID string) (string, error) {
+	return enqueueRaw(q, body, correlationID, false)
+}
+
+// EnqueueRawInterrupt is EnqueueRaw for a body that should replace the
+// agent's current work: delivery interrupts the agent first and waits for
+// it to settle, as for a prefixed interrupt message.
+func EnqueueRawInterrupt(q *MessageQueue, body, correlationID string) (string, error) {
+	return enqueueRaw(q, body, correlationID, true)
+}
+
+func enqueueRaw(q *MessageQueue, body, correlationID string, interruptFirst bool) (string, error) {
 	id := uuid.New().String()
 	now := time.Now()
 	msg := &Message{
@@ -50,38 +80,84 @@ func EnqueueRaw(q *MessageQueue, body string) string {
 		Raw:       true,
 		Status:    StatusQueued,
 		CreatedAt: now,
+
+		InterruptFirst: interruptFirst,
+		CorrelationID:  correlationID,
+	}
+	if err := q.Enqueue(msg); err != nil {
+		return "", err
 	}
-	q.Enqueue(msg)
-	return id
+	return id, nil
+}
+
+// SanitizeRaw strips control characters other than tab and newline from a
+// raw body so it can't inject terminal escape sequences or signals (e.g.
+// Ctrl+C) into the agent's PTY. Unsafe raw sends skip this.
+func SanitizeRaw(body string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' {
+			return r
+		}
+		if r < 0x20 || r == 0x7F {
+			return -1
+		}
+		return r
+	}, body)
+}
+
+// PrepareOpts describes a message for Prepare.
+type PrepareOpts struct {
+	AgentName     string // recipient; names the directory the body is written to
+	From          string
+	Body          string
+	Priority      Priority
+	Attachment    string        // absolute path of a file referenced on delivery ("" = none)
+	EscalateAfter time.Duration // promote an idle or idle-first message to interrupt if still queued this long (0 = never)
+	CorrelationID string        // stamped on the message and its log entries ("" = none)
 }
 
 // PrepareMessage creates a Message, writes its body to disk, and enqueues it.
 // Returns the message ID.
 func PrepareMessage(q *MessageQueue, agentName, from, body string, priority Priority) (string, error) {
+	return Prepare(q, PrepareOpts{AgentName: agentName, From: from, Body: body, Priority: priority})
+}
+
+// Prepare is like PrepareMessage, with the optional settings of opts.
+func Prepare(q *MessageQueue, opts PrepareOpts) (string, error) {
+	if q.IsClosed() {
+		return "", ErrQueueClosed
+	}
 	id := uuid.New().String()
 	now := time.Now()
 
-	dir := filepath.Join(os.Getenv("HOME"), ".h2", "messages", agentName)
+	dir := filepath.Join(os.Getenv("HOME"), ".h2", "messages", opts.AgentName)
 	if err := os.MkdirAll(dir, 0o700); err != nil {
 		return "", fmt.Errorf("create message dir: %w", err)
 	}
 
 	filename := fmt.Sprintf("%s-%s.md", now.Format("20060102-150405"), id[:8])
 	filePath := filepath.Join(dir, filename)
-	if err := os.WriteFile(filePath, []byte(body), 0o600); err != nil {
+	if err := os.WriteFile(filePath, []byte(opts.Body), 0o600); err != nil {
 		return "", fmt.Errorf("write message file: %w", err)
 	}
 
 	msg := &Message{
 		ID:        id,
-		From:      from,
-		Priority:  priority,
-		Body:      body,
+		From:      opts.From,
+		Priority:  opts.Priority,
+		Body:      opts.Body,
 		FilePath:  filePath,
 		Status:    StatusQueued,
 		CreatedAt: now,
+
+		EscalateAfter: opts.EscalateAfter,
+		CorrelationID: opts.CorrelationID,
+		Attachment:    opts.Attachment,
+	}
+	if err := q.Enqueue(msg); err != nil {
+		os.Remove(filePath)
+		return "", err
 	}
-	q.Enqueue(msg)
 	return id, nil
 }
 
@@ -91,23 +167,57 @@ func RunDelivery(cfg DeliveryConfig) {
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
+	// idleCh fires when a pending strict-idle wait sees the agent go idle.
+	var idleCh chan struct{}
+	cancelWait := func() {}
+	defer func() { cancelWait() }()
+
 	for {
 		select {
 		case <-cfg.Stop:
 			return
 		case <-cfg.Queue.Notify():
 		case <-ticker.C:
+		case <-idleCh:
+			idleCh = nil
+			cancelWait()
 		}
 
 		for {
+			// Check between messages too, so a stop lands after the
+			// message in flight rather than after the whole queue.
+			select {
+			case <-cfg.Stop:
+				return
+			default:
+			}
 			idle := cfg.IsIdle != nil && cfg.IsIdle()
 			blocked := cfg.IsBlocked != nil && cfg.IsBlocked()
+			if cfg.StrictIdle && !idle {
+				// Only interrupts may go to a busy agent.
+				blocked = true
+			}
 			msg := cfg.Queue.Dequeue(idle, blocked)
 			if msg == nil {
 				break
 			}
 			deliver(cfg, msg)
 		}
+
+		// Messages held back for a busy agent: wake as soon as it goes idle
+		// rather than waiting for the next tick.
+		if cfg.StrictIdle && idleCh == nil && cfg.WaitForIdle != nil &&
+			cfg.Queue.PendingCount() > 0 && !(cfg.IsIdle != nil && cfg.IsIdle()) {
+			ctx, cancel := context.WithCancel(context.Background())
+			cancelWait = cancel
+			ch := make(chan struct{})
+			idleCh = ch
+			go func() {
+				if cfg.WaitForIdle(ctx) {
+					close(ch)
+				}
+			}()
+		}
 	}
 }
 
@@ -121,7 +231,7 @@ const (
 var interruptWaitTimeout = 5 * time.Second
 
 func deliver(cfg DeliveryConfig, msg *Message) {
-	if msg.Priority == PriorityInterrupt && !msg.Raw {
+	if msg.Priority == PriorityInterrupt && (!msg.Raw || msg.InterruptFirst) {
 		// Send Ctrl+C, wait for idle, retry up to 3 times.
 		// If still not idle after retries, send anyway (like normal).
 		for attempt := 0; attempt < interruptRetries; attempt++ {
@@ -143,6 +253,9 @@ func deliver(cfg DeliveryConfig, msg *Message) {
 		}
 	}
 
+	if cfg.OnPrompt != nil {
+		cfg.OnPrompt(msg)
+	}
 	if msg.FilePath == "" {
 		// Raw user input — send body directly.
 		cfg.PtyWriter.Write([]byte(msg.Body))
@@ -160,12 +273,28 @@ func deliver(cfg DeliveryConfig, msg *Message) {
 			line = fmt.Sprintf("[%s from: %s] Read %s",
 				prefix, msg.From, msg.FilePath)
 		}
+		if msg.Attachment != "" {
+			ref := msg.Attachment
+			if cfg.MentionAttachments {
+				ref = "@" + ref
+			}
+			if msg.Body != "" {
+				line += " "
+			}
+			line += ref
+		}
 		cfg.PtyWriter.Write([]byte(line))
 	}
 	// Delay before sending Enter so the child's UI framework can process
 	// the typed text before the submit (same pattern as user Enter).
-	time.Sleep(50 * time.Millisecond)
-	cfg.PtyWriter.Write([]byte{'\r'})
+	if cfg.SubmitDelay > 0 {
+		time.Sleep(cfg.SubmitDelay)
+	}
+	submit := cfg.SubmitBytes
+	if submit == nil {
+		submit = []byte{'\r'}
+	}
+	cfg.PtyWriter.Write(submit)
 
 	now := time.Now()
 	msg.Status = StatusDelivered
@@ -174,4 +303,7 @@ func deliver(cfg DeliveryConfig, msg *Message) {
 	if cfg.OnDeliver != nil {
 		cfg.OnDeliver()
 	}
+	if cfg.OnMessage != nil {
+		cfg.OnMessage(msg)
+	}
 }
@@ -75,6 +75,49 @@ func TestDeliver_RawInput(t *testing.T) {
 	}
 }
 
+func TestDeliver_SubmitBytes(t *testing.T) {
+	for _, submit := range []string{"\r", "\n", "\r\n"} {
+		var buf threadSafeBuffer
+		q := NewMessageQueue()
+		stop := make(chan struct{})
+
+		q.Enqueue(&Message{
+			ID:        "raw-1",
+			From:      "user",
+			Priority:  PriorityNormal,
+			Body:      "echo hello",
+			Status:    StatusQueued,
+			CreatedAt: time.Now(),
+		})
+
+		delivered := make(chan struct{}, 1)
+		go RunDelivery(DeliveryConfig{
+			Queue:       q,
+			PtyWriter:   &buf,
+			IsIdle:      func() bool { return true },
+			SubmitBytes: []byte(submit),
+			OnDeliver: func() {
+				select {
+				case delivered <- struct{}{}:
+				default:
+				}
+			},
+			Stop: stop,
+		})
+
+		select {
+		case <-delivered:
+		case <-time.After(3 * time.Second):
+			t.Fatal("delivery timed out")
+		}
+		close(stop)
+
+		if out := buf.String(); out != "echo hello"+submit {
+			t.Errorf("submit %q: got %q, want %q", submit, out, "echo hello"+submit)
+		}
+	}
+}
+
 func TestDeliver_InterAgentMessage(t *testing.T) {
 	var buf threadSafeBuffer
 	q := NewMessageQueue()
@@ -428,9 +471,9 @@ func TestEnqueueRaw_BypassesBlockedAndNoPrefix(t *testing.T) {
 	stop := make(chan struct{})
 
 	// EnqueueRaw should create a message with interrupt priority and no file path.
-	id := EnqueueRaw(q, "y")
-	if id == "" {
-		t.Fatal("expected non-empty message ID")
+	id, err := EnqueueRaw(q, "y", "")
+	if err != nil || id == "" {
+		t.Fatalf("expected non-empty message ID, got %q (%v)", id, err)
 	}
 
 	delivered := make(chan struct{}, 1)
@@ -476,6 +519,47 @@ func TestEnqueueRaw_BypassesBlockedAndNoPrefix(t *testing.T) {
 	}
 }
 
+func TestEnqueueRawInterrupt_InterruptsBeforeBody(t *testing.T) {
+	var buf threadSafeBuffer
+	q := NewMessageQueue()
+	stop := make(chan struct{})
+
+	EnqueueRawInterrupt(q, "do this instead", "")
+
+	waitCalls := 0
+	delivered := make(chan struct{}, 1)
+	go RunDelivery(DeliveryConfig{
+		Queue:     q,
+		PtyWriter: &buf,
+		IsIdle:    func() bool { return false },
+		WaitForIdle: func(ctx context.Context) bool {
+			waitCalls++
+			return true
+		},
+		OnDeliver: func() {
+			select {
+			case delivered <- struct{}{}:
+			default:
+			}
+		},
+		Stop: stop,
+	})
+
+	select {
+	case <-delivered:
+	case <-time.After(3 * time.Second):
+		t.Fatal("delivery timed out")
+	}
+	close(stop)
+
+	if out, want := buf.String(), "\x03do this instead\r"; out != want {
+		t.Fatalf("output = %q, want %q", out, want)
+	}
+	if waitCalls != 1 {
+		t.Errorf("WaitForIdle calls = %d, want 1 (settle after the interrupt)", waitCalls)
+	}
+}
+
 func TestDeliver_NormalNoWaitForIdle(t *testing.T) {
 	var buf threadSafeBuffer
 	q := NewMessageQueue()
@@ -521,3 +605,231 @@ func TestDeliver_NormalNoWaitForIdle(t *testing.T) {
 		t.Fatalf("WaitForIdle should not be called for normal priority, got %d calls", waitCalls)
 	}
 }
+
+func TestSanitizeRaw(t *testing.T) {
+	got := SanitizeRaw("a\x1b[31mb\x03\tc\nd\x7f")
+	if got != "a[31mb\tc\nd" {
+		t.Fatalf("SanitizeRaw = %q", got)
+	}
+}
+
+// fakeAgentState is a toggleable idle/busy agent for strict-idle tests.
+type fakeAgentState struct {
+	mu     sync.Mutex
+	idle   bool
+	idleCh chan struct{} // closed when the agent goes idle
+}
+
+func newFakeAgentState() *fakeAgentState {
+	return &fakeAgentState{idleCh: make(chan struct{})}
+}
+
+func (a *fakeAgentState) IsIdle() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.idle
+}
+
+func (a *fakeAgentState) SetIdle() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.idle {
+		a.idle = true
+		close(a.idleCh)
+	}
+}
+
+func (a *fakeAgentState) WaitForIdle(ctx context.Context) bool {
+	a.mu.Lock()
+	ch := a.idleCh
+	a.mu.Unlock()
+	select {
+	case <-ch:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// stateRecordingWriter records each PTY write along with whether the agent
+// was idle at the time.
+type stateRecordingWriter struct {
+	mu     sync.Mutex
+	agent  *fakeAgentState
+	writes []string
+	busy   []string // writes made while the agent was busy
+}
+
+func (w *stateRecordingWriter) Write(p []byte) (int, error) {
+	idle := w.agent.IsIdle()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.writes = append(w.writes, string(p))
+	if !idle {
+		w.busy = append(w.busy, string(p))
+	}
+	return len(p), nil
+}
+
+func (w *stateRecordingWriter) snapshot() (all, busy string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return strings.Join(w.writes, ""), strings.Join(w.busy, "")
+}
+
+func TestRunDelivery_StrictIdleGatesNonInterrupt(t *testing.T) {
+	agent := newFakeAgentState()
+	w := &stateRecordingWriter{agent: agent}
+	q := NewMessageQueue()
+	stop := make(chan struct{})
+	defer close(stop)
+
+	for _, m := range []struct {
+		id   string
+		prio Priority
+	}{{"tok-normal", PriorityNormal}, {"tok-idle-first", PriorityIdleFirst}, {"tok-idle", PriorityIdle}} {
+		q.Enqueue(&Message{ID: m.id, Priority: m.prio, Body: m.id, Status: StatusQueued, CreatedAt: time.Now()})
+	}
+
+	waiting := make(chan struct{})
+	var waitOnce sync.Once
+	go RunDelivery(DeliveryConfig{
+		Queue:     q,
+		PtyWriter: w,
+		IsIdle:    agent.IsIdle,
+		WaitForIdle: func(ctx context.Context) bool {
+			waitOnce.Do(func() { close(waiting) })
+			return agent.WaitForIdle(ctx)
+		},
+		StrictIdle: true,
+		Stop:       stop,
+	})
+
+	// While busy, only the raw interrupt gets through.
+	EnqueueRaw(q, "tok-raw", "")
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		all, _ := w.snapshot()
+		if strings.Contains(all, "tok-raw") {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("interrupt should be delivered while the agent is busy")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	<-waiting // the loop is now parked on WaitForIdle
+	time.Sleep(100 * time.Millisecond)
+	if all, _ := w.snapshot(); strings.Contains(all, "tok-normal") || strings.Contains(all, "tok-idle") {
+		t.Fatalf("non-interrupt messages delivered to a busy agent: %q", all)
+	}
+
+	agent.SetIdle()
+	deadline = time.Now().Add(3 * time.Second)
+	for q.PendingCount() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("queued messages not delivered once idle (%d pending)", q.PendingCount())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(100 * time.Millisecond) // let the last submit land
+
+	all, busy := w.snapshot()
+	for _, tok := range []string{"tok-normal", "tok-idle-first", "tok-idle"} {
+		if !strings.Contains(all, tok) {
+			t.Errorf("%s not delivered: %q", tok, all)
+		}
+		if strings.Contains(busy, tok) {
+			t.Errorf("%s was written while the agent was busy", tok)
+		}
+	}
+}
+
+func TestDeliver_Attachment(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		mention bool
+		want    string
+	}{
+		{"claude mention", "see this", true, "[h2 message from: agent-a] see this @/tmp/shot.png\r"},
+		{"bare path", "see this", false, "[h2 message from: agent-a] see this /tmp/shot.png\r"},
+		{"no body", "", true, "[h2 message from: agent-a] @/tmp/shot.png\r"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf threadSafeBuffer
+			q := NewMessageQueue()
+			stop := make(chan struct{})
+			q.Enqueue(&Message{
+				ID:         "msg-1",
+				From:       "agent-a",
+				Priority:   PriorityNormal,
+				Body:       tt.body,
+				FilePath:   "/tmp/test-msg.md",
+				Attachment: "/tmp/shot.png",
+				Status:     StatusQueued,
+				CreatedAt:  time.Now(),
+			})
+
+			delivered := make(chan struct{}, 1)
+			go RunDelivery(DeliveryConfig{
+				Queue:              q,
+				PtyWriter:          &buf,
+				IsIdle:             func() bool { return true },
+				MentionAttachments: tt.mention,
+				OnDeliver: func() {
+					select {
+					case delivered <- struct{}{}:
+					default:
+					}
+				},
+				Stop: stop,
+			})
+			select {
+			case <-delivered:
+			case <-time.After(3 * time.Second):
+				t.Fatal("delivery timed out")
+			}
+			close(stop)
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("output = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPrepare_Attachment(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	q := NewMessageQueue()
+	id, err := Prepare(q, PrepareOpts{AgentName: "coder", From: "user", Body: "look", Priority: PriorityNormal, Attachment: "/tmp/shot.png"})
+	if err != nil {
+		t.Fatalf("Prepare: %v", err)
+	}
+	if msg := q.Lookup(id); msg == nil || msg.Attachment != "/tmp/shot.png" {
+		t.Fatalf("attachment not recorded: %+v", msg)
+	}
+	if _, err := PrepareMessage(q, "coder", "user", "plain", PriorityNormal); err != nil {
+		t.Fatalf("PrepareMessage: %v", err)
+	}
+	if n := q.PendingAttachmentCount(); n != 1 {
+		t.Errorf("PendingAttachmentCount = %d, want 1", n)
+	}
+}
+
+func TestDeliver_OnPromptBeforeText(t *testing.T) {
+	var buf threadSafeBuffer
+	var before string
+	deliver(DeliveryConfig{
+		PtyWriter: &buf,
+		OnPrompt:  func(*Message) { before = buf.String() },
+	}, &Message{ID: "p-1", From: "user", Priority: PriorityNormal, Body: "hi"})
+
+	if before != "" {
+		t.Fatalf("OnPrompt ran after %q was written", before)
+	}
+	if !strings.HasPrefix(buf.String(), "hi") {
+		t.Fatalf("output = %q", buf.String())
+	}
+}
@@ -1,6 +1,7 @@
 package message
 
 import (
+	"strings"
 	"time"
 )
 
@@ -46,6 +47,19 @@ func (p Priority) String() string {
 	}
 }
 
+// Preview returns body on one line, with runs of whitespace collapsed,
+// truncated to at most max runes with a trailing ellipsis.
+func Preview(body string, max int) string {
+	r := []rune(strings.Join(strings.Fields(body), " "))
+	if max <= 0 {
+		return ""
+	}
+	if len(r) <= max {
+		return string(r)
+	}
+	return string(r[:max-1]) + "…"
+}
+
 // MessageStatus tracks the delivery state of a message.
 type MessageStatus string
 
@@ -56,13 +70,28 @@ const (
 
 // Message represents a queued inter-agent message.
 type Message struct {
-	ID          string
-	From        string
-	Priority    Priority
-	Body        string
-	FilePath    string
-	Raw         bool // send body directly to PTY, skip Ctrl+C interrupt loop
+	ID       string
+	From     string
+	Priority Priority
+	Body     string
+	FilePath string
+	Raw      bool // send body directly to PTY, skip Ctrl+C interrupt loop
+	// InterruptFirst runs the Ctrl+C interrupt loop even for a Raw message.
+	InterruptFirst bool
+	// EscalateAfter promotes an idle/idle-first message to interrupt
+	// priority once it has been queued this long (0 = never).
+	EscalateAfter time.Duration
+	// CorrelationID is an optional sender-supplied ID stamped onto the
+	// activity-log events generated while the agent processes this message.
+	CorrelationID string
+	// Attachment is the absolute path of a file sent along with the
+	// message (h2 send --attach); delivery references it after the body.
+	Attachment  string
 	Status      MessageStatus
 	CreatedAt   time.Time
 	DeliveredAt *time.Time
+
+	// levelSince is when the message entered its current priority level,
+	// for queue aging.
+	levelSince time.Time
 }
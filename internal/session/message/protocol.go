@@ -1,6 +1,8 @@
 package message
 
 import (
+	"bytes"
+	"compress/flate"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
@@ -10,21 +12,55 @@ import (
 
 // Request is the JSON request sent over the Unix socket.
 type Request struct {
-	Type string `json:"type"` // "send", "attach", "show", "status", "hook_event", "stop"
+	Type string `json:"type"` // "send", "attach", "show", "status", "watch_state", "screen", "hook_event", "stop"
 
 	// send fields
 	Priority string `json:"priority,omitempty"`
 	From     string `json:"from,omitempty"`
 	Body     string `json:"body,omitempty"`
 	Raw      bool   `json:"raw,omitempty"` // send body directly to PTY without prefix
+	Unsafe   bool   `json:"unsafe,omitempty"` // with Raw: skip control-character sanitization
+	InterruptFirst bool `json:"interrupt_first,omitempty"` // interrupt the agent, let it settle, then deliver at interrupt priority
+	// EscalateAfter is a duration string; idle messages still queued after
+	// it are promoted to interrupt. Empty uses the agent's role default.
+	EscalateAfter string `json:"escalate_after,omitempty"`
+	// CorrelationID is stamped onto the message and the activity-log events
+	// produced while the agent processes it.
+	CorrelationID string `json:"correlation_id,omitempty"`
+	// Attachment is the absolute path of a file to reference in the message.
+	Attachment string `json:"attachment,omitempty"`
 
 	// attach fields
 	Cols int `json:"cols,omitempty"`
 	Rows int `json:"rows,omitempty"`
+	// Compress requests DEFLATE-compressed render frames (for remote links).
+	Compress bool `json:"compress,omitempty"`
+	// TrimLines drops redundant trailing blanks from rendered rows, relying
+	// on the clear-to-EOL already sent before each row.
+	TrimLines bool `json:"trim_lines,omitempty"`
+	// DetachOnIdle detaches the client on the agent's first active → idle transition.
+	DetachOnIdle bool `json:"detach_on_idle,omitempty"`
+	// ReadOnly attaches as an observer that can watch and scroll but never
+	// writes to the agent or takes control.
+	ReadOnly bool `json:"read_only,omitempty"`
+	// ScrollStep is the attaching terminal's mouse wheel step in lines
+	// (H2_SCROLL_STEP); 0 keeps the default.
+	ScrollStep int `json:"scroll_step,omitempty"`
+	// Theme is the attaching terminal's H2_THEME; "" keeps the theme file's
+	// base.
+	Theme string `json:"theme,omitempty"`
+	// LightBackground reports that the attaching terminal has a light
+	// background, for Theme "auto".
+	LightBackground bool `json:"light_background,omitempty"`
 
 	// show fields
 	MessageID string `json:"message_id,omitempty"`
 
+	// screen fields
+	// History includes the full output history (ScreenInfo.Text), which
+	// can be large; without it only the visible screen is sent.
+	History bool `json:"history,omitempty"`
+
 	// hook_event fields
 	EventName string          `json:"event_name,omitempty"`
 	Payload   json.RawMessage `json:"payload,omitempty"`
@@ -35,9 +71,32 @@ type Response struct {
 	OK        bool         `json:"ok"`
 	Error     string       `json:"error,omitempty"`
 	MessageID string       `json:"message_id,omitempty"`
+	// Deduped is set on a send dropped as a duplicate; MessageID is then
+	// the earlier, identical message.
+	Deduped   bool         `json:"deduped,omitempty"`
 	Message   *MessageInfo `json:"message,omitempty"`
 	Agent     *AgentInfo   `json:"agent,omitempty"`
 	Bridge    *BridgeInfo  `json:"bridge,omitempty"`
+	Screen    *ScreenInfo  `json:"screen,omitempty"`
+}
+
+// ScreenInfo is a snapshot of an agent's visible terminal screen.
+type ScreenInfo struct {
+	Rows int    `json:"rows"`
+	Cols int    `json:"cols"`
+	ANSI string `json:"ansi"` // visible rows with SGR formatting, newline-separated
+	Text string `json:"text,omitempty"` // full output history (scrollback) as plain text; only with Request.History
+	Plain string `json:"plain,omitempty"` // visible rows as plain text, newline-separated
+	CursorRow int `json:"cursor_row"` // 0-based cursor position within the visible rows
+	CursorCol int `json:"cursor_col"`
+}
+
+// RenderMetrics summarizes render-path counters across attached clients.
+type RenderMetrics struct {
+	Clients      int     `json:"clients"`
+	Frames       uint64  `json:"frames"`
+	BytesWritten uint64  `json:"bytes_written"`
+	AvgRenderMs  float64 `json:"avg_render_ms"`
 }
 
 // BridgeInfo is the public representation of bridge status.
@@ -59,6 +118,9 @@ type MessageInfo struct {
 	FilePath    string `json:"file_path"`
 	CreatedAt   string `json:"created_at"`
 	DeliveredAt string `json:"delivered_at,omitempty"`
+
+	CorrelationID string `json:"correlation_id,omitempty"`
+	Attachment    string `json:"attachment,omitempty"`
 }
 
 // AgentInfo is the public representation of agent status.
@@ -73,7 +135,19 @@ type AgentInfo struct {
 	SubState         string `json:"sub_state,omitempty"`
 	StateDisplayText string `json:"state_display_text"`
 	StateDuration    string `json:"state_duration"`
+	StateChangedAt   string `json:"state_changed_at,omitempty"` // RFC 3339 time the current state began
 	QueuedCount   int    `json:"queued_count"`
+	QueuedAttachments int `json:"queued_attachments,omitempty"` // queued messages carrying a file
+	Queue         []QueuedMessageInfo `json:"queue,omitempty"` // the first StatusQueueLimit undelivered messages, next first
+	QueueByPriority map[string]int    `json:"queue_by_priority,omitempty"` // undelivered messages per priority
+	PID           int    `json:"pid,omitempty"`        // agent child process
+	DaemonPID     int    `json:"daemon_pid,omitempty"` // h2 daemon hosting the agent
+
+	// Current child run: start time, uptime in seconds (final uptime once
+	// the child has exited), and how many times the child was relaunched.
+	StartedAt     string `json:"started_at,omitempty"`
+	UptimeSeconds int64  `json:"uptime_seconds"`
+	RestartCount  int    `json:"restart_count"`
 
 	// Per-model cost and token breakdowns from OTEL metrics
 	ModelStats    []ModelStat `json:"model_stats,omitempty"`
@@ -99,8 +173,28 @@ type AgentInfo struct {
 	ToolUseCount        int64  `json:"tool_use_count,omitempty"`
 	BlockedOnPermission bool   `json:"blocked_on_permission,omitempty"`
 	BlockedToolName     string `json:"blocked_tool_name,omitempty"`
+
+	// Render-path counters summed across attached clients
+	Render *RenderMetrics `json:"render,omitempty"`
+}
+
+// QueuedMessageInfo describes an undelivered message in a status response.
+type QueuedMessageInfo struct {
+	ID         string `json:"id"`
+	From       string `json:"from"`
+	Priority   string `json:"priority"`
+	Body       string `json:"body"` // truncated to StatusPreviewLen runes
+	Age        string `json:"age"`
+	AgeSeconds int64  `json:"age_seconds"`
 }
 
+// StatusPreviewLen caps message bodies in status responses.
+const StatusPreviewLen = 80
+
+// StatusQueueLimit caps the queued messages listed in a status response;
+// QueuedCount and QueueByPriority still cover the whole queue.
+const StatusQueueLimit = 20
+
 // ModelStat holds per-model cost and token breakdown.
 type ModelStat struct {
 	Model        string  `json:"model"`
@@ -115,8 +209,16 @@ type ModelStat struct {
 const (
 	FrameTypeData    byte = 0x00
 	FrameTypeControl byte = 0x01
+	// FrameTypeCompressedData carries a DEFLATE-compressed data payload.
+	// Only sent to clients that set Compress in their attach request.
+	FrameTypeCompressedData byte = 0x02
 )
 
+// minCompressLen is the smallest payload worth compressing. Shorter
+// payloads (cursor moves, single keystroke echoes) are sent as plain data
+// frames since DEFLATE overhead would make them larger.
+const minCompressLen = 64
+
 // ResizeControl is the JSON payload for a resize control frame.
 type ResizeControl struct {
 	Type string `json:"type"` // "resize"
@@ -124,6 +226,14 @@ type ResizeControl struct {
 	Rows int    `json:"rows"`
 }
 
+// DetachControl is the JSON payload of the control frame the daemon sends
+// just before it ends an attach on purpose, so a reconnecting client knows
+// not to reattach.
+type DetachControl struct {
+	Type   string `json:"type"`   // "detach"
+	Reason string `json:"reason"` // "detach", "quit", or "stop"
+}
+
 // SendRequest sends a JSON-encoded request over a connection.
 func SendRequest(conn net.Conn, req *Request) error {
 	return json.NewEncoder(conn).Encode(req)
@@ -187,3 +297,48 @@ func ReadFrame(r io.Reader) (byte, []byte, error) {
 	}
 	return frameType, payload, nil
 }
+
+// WriteDataFrame writes a data frame, compressing the payload when compress
+// is set and the payload is large enough to benefit. Readers must accept
+// both FrameTypeData and FrameTypeCompressedData.
+func WriteDataFrame(w io.Writer, payload []byte, compress bool) error {
+	if !compress || len(payload) < minCompressLen {
+		return WriteFrame(w, FrameTypeData, payload)
+	}
+	compressed, err := CompressPayload(payload)
+	if err != nil {
+		return err
+	}
+	return WriteFrame(w, FrameTypeCompressedData, compressed)
+}
+
+// CompressPayload DEFLATE-compresses a single frame payload. Each frame is
+// compressed independently so frames can be decoded without shared state.
+func CompressPayload(p []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	fw, err := flate.NewWriter(&buf, flate.BestSpeed)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fw.Write(p); err != nil {
+		return nil, err
+	}
+	if err := fw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// DecompressPayload reverses CompressPayload.
+func DecompressPayload(p []byte) ([]byte, error) {
+	fr := flate.NewReader(bytes.NewReader(p))
+	defer fr.Close()
+	out, err := io.ReadAll(io.LimitReader(fr, 10*1024*1024+1))
+	if err != nil {
+		return nil, fmt.Errorf("decompress frame: %w", err)
+	}
+	if len(out) > 10*1024*1024 {
+		return nil, fmt.Errorf("decompressed frame too large")
+	}
+	return out, nil
+}
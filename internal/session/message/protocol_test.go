@@ -0,0 +1,66 @@
+package message
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWriteDataFrame_CompressedRoundTrip(t *testing.T) {
+	// A typical render payload: repeated SGR + padded lines compresses well.
+	render := []byte(strings.Repeat("\033[2K\033[36mhello world\033[0m"+strings.Repeat(" ", 60)+"\r\n", 40))
+
+	var buf bytes.Buffer
+	if err := WriteDataFrame(&buf, render, true); err != nil {
+		t.Fatalf("WriteDataFrame: %v", err)
+	}
+	wireLen := buf.Len()
+
+	frameType, payload, err := ReadFrame(&buf)
+	if err != nil {
+		t.Fatalf("ReadFrame: %v", err)
+	}
+	if frameType != FrameTypeCompressedData {
+		t.Fatalf("frame type = %#x, want %#x", frameType, FrameTypeCompressedData)
+	}
+	if wireLen >= len(render) {
+		t.Errorf("compressed frame (%d bytes) not smaller than raw render (%d bytes)", wireLen, len(render))
+	}
+
+	got, err := DecompressPayload(payload)
+	if err != nil {
+		t.Fatalf("DecompressPayload: %v", err)
+	}
+	if !bytes.Equal(got, render) {
+		t.Error("decompressed bytes differ from original render bytes")
+	}
+}
+
+func TestWriteDataFrame_SmallPayloadUncompressed(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteDataFrame(&buf, []byte("\033[5;3H"), true); err != nil {
+		t.Fatalf("WriteDataFrame: %v", err)
+	}
+	frameType, payload, err := ReadFrame(&buf)
+	if err != nil {
+		t.Fatalf("ReadFrame: %v", err)
+	}
+	if frameType != FrameTypeData || string(payload) != "\033[5;3H" {
+		t.Errorf("got type %#x payload %q, want plain data frame", frameType, payload)
+	}
+}
+
+func TestWriteDataFrame_DefaultUncompressed(t *testing.T) {
+	render := []byte(strings.Repeat("x", 500))
+	var buf bytes.Buffer
+	if err := WriteDataFrame(&buf, render, false); err != nil {
+		t.Fatalf("WriteDataFrame: %v", err)
+	}
+	frameType, payload, err := ReadFrame(&buf)
+	if err != nil {
+		t.Fatalf("ReadFrame: %v", err)
+	}
+	if frameType != FrameTypeData || !bytes.Equal(payload, render) {
+		t.Error("expected uncompressed data frame when compression is off")
+	}
+}
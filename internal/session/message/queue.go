@@ -1,9 +1,32 @@
 package message
 
 import (
+	"crypto/sha256"
+	"errors"
 	"sync"
+	"time"
 )
 
+// ErrQueueClosed is returned when a message is sent to an agent that is
+// shutting down.
+var ErrQueueClosed = errors.New("agent is shutting down")
+
+// DuplicateError reports a message dropped because an identical one (same
+// sender and body) was enqueued within the dedupe window.
+type DuplicateError struct {
+	OriginalID string // ID of the earlier, identical message
+}
+
+func (e *DuplicateError) Error() string {
+	return "duplicate of message " + e.OriginalID
+}
+
+// recentMessage records an enqueued message for deduplication.
+type recentMessage struct {
+	id string
+	at time.Time
+}
+
 // MessageQueue is a priority queue for inter-agent messages.
 // Messages are ordered by priority: interrupt > normal > idle-first > idle.
 // Within each priority level, messages are FIFO except idle-first which
@@ -16,7 +39,18 @@ type MessageQueue struct {
 	idle        []*Message        // priority 4 - FIFO
 	allMessages map[string]*Message
 	paused      bool
+	closed      bool
 	notify      chan struct{}
+
+	// ageAfter promotes an idle or idle-first message to normal once it
+	// has waited this long. 0 disables.
+	ageAfter time.Duration
+
+	// dedupeWindow drops a message identical to one enqueued this recently.
+	// recent maps a hash of sender and body to the last such message.
+	// 0 disables.
+	dedupeWindow time.Duration
+	recent       map[[sha256.Size]byte]recentMessage
 }
 
 // NewMessageQueue creates a new empty message queue.
@@ -28,12 +62,21 @@ func NewMessageQueue() *MessageQueue {
 }
 
 // Enqueue adds a message to the appropriate sub-queue and signals the
-// delivery goroutine.
-func (q *MessageQueue) Enqueue(msg *Message) {
+// delivery goroutine. The message is dropped with ErrQueueClosed once the
+// queue has been closed, or with a *DuplicateError if the dedupe window
+// catches it.
+func (q *MessageQueue) Enqueue(msg *Message) error {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
+	if q.closed {
+		return ErrQueueClosed
+	}
+	if err := q.dedupe(msg); err != nil {
+		return err
+	}
 	q.allMessages[msg.ID] = msg
+	msg.levelSince = time.Now()
 
 	switch msg.Priority {
 	case PriorityInterrupt:
@@ -47,6 +90,41 @@ func (q *MessageQueue) Enqueue(msg *Message) {
 	}
 
 	q.signal()
+	return nil
+}
+
+// SetDedupeWindow enables deduplication: a message with the same sender,
+// body, and attachment as one enqueued less than d ago is dropped. Raw
+// messages (e.g. permission answers) are never deduplicated. 0 (the
+// default) disables it.
+func (q *MessageQueue) SetDedupeWindow(d time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.dedupeWindow = d
+	q.recent = nil
+}
+
+// dedupe returns a *DuplicateError if msg repeats a message enqueued within
+// the dedupe window, and otherwise records it. Caller must hold q.mu.
+func (q *MessageQueue) dedupe(msg *Message) error {
+	if q.dedupeWindow <= 0 || msg.Raw {
+		return nil
+	}
+	now := time.Now()
+	for k, r := range q.recent {
+		if now.Sub(r.at) >= q.dedupeWindow {
+			delete(q.recent, k)
+		}
+	}
+	key := sha256.Sum256([]byte(msg.From + "\x00" + msg.Body + "\x00" + msg.Attachment))
+	if r, ok := q.recent[key]; ok {
+		return &DuplicateError{OriginalID: r.id}
+	}
+	if q.recent == nil {
+		q.recent = make(map[[sha256.Size]byte]recentMessage)
+	}
+	q.recent[key] = recentMessage{id: msg.ID, at: now}
+	return nil
 }
 
 // Dequeue returns the next message to deliver based on priority ordering.
@@ -58,6 +136,14 @@ func (q *MessageQueue) Dequeue(idle, blocked bool) *Message {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
+	now := time.Now()
+	q.escalate(now)
+	// Aging fights starvation by busier levels; a paused queue or a blocked
+	// agent is holding messages on purpose, so leave them where they are.
+	if !q.paused && !blocked {
+		q.age(now)
+	}
+
 	if q.paused {
 		// Interrupt bypasses pause.
 		if len(q.interrupt) > 0 {
@@ -96,6 +182,68 @@ func (q *MessageQueue) Dequeue(idle, blocked bool) *Message {
 	return nil
 }
 
+// escalate promotes idle and idle-first messages that have waited past
+// their EscalateAfter window to interrupt priority. Caller must hold q.mu.
+func (q *MessageQueue) escalate(now time.Time) {
+	q.idleFirst = q.promoteExpired(q.idleFirst, now)
+	q.idle = q.promoteExpired(q.idle, now)
+}
+
+func (q *MessageQueue) promoteExpired(msgs []*Message, now time.Time) []*Message {
+	kept := msgs[:0]
+	for _, msg := range msgs {
+		if msg.EscalateAfter > 0 && now.Sub(msg.CreatedAt) >= msg.EscalateAfter {
+			msg.Priority = PriorityInterrupt
+			q.interrupt = append(q.interrupt, msg)
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	return kept
+}
+
+// SetAgeAfter enables priority aging: an idle or idle-first message that
+// has waited d is promoted to normal, so a busy agent can't starve it
+// forever. Aging stops at normal: interrupt delivery sends Ctrl+C, which
+// only a sender may ask for. 0 (the default) disables aging.
+func (q *MessageQueue) SetAgeAfter(d time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.ageAfter = d
+}
+
+// age promotes idle and idle-first messages that have waited ageAfter to
+// normal. Normal messages are never aged. Caller must hold q.mu.
+func (q *MessageQueue) age(now time.Time) {
+	if q.ageAfter <= 0 {
+		return
+	}
+	var toNormal, idleAged []*Message
+	q.idleFirst, toNormal = q.splitAged(q.idleFirst, now)
+	q.idle, idleAged = q.splitAged(q.idle, now)
+	toNormal = append(toNormal, idleAged...)
+
+	for _, msg := range toNormal {
+		msg.Priority = PriorityNormal
+		msg.levelSince = now
+		q.normal = append(q.normal, msg)
+	}
+}
+
+// splitAged separates the messages that have waited ageAfter at their
+// level from the rest, preserving order in both.
+func (q *MessageQueue) splitAged(msgs []*Message, now time.Time) (kept, aged []*Message) {
+	kept = msgs[:0]
+	for _, msg := range msgs {
+		if now.Sub(msg.levelSince) >= q.ageAfter {
+			aged = append(aged, msg)
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	return kept, aged
+}
+
 // Pause pauses delivery of non-interrupt messages.
 func (q *MessageQueue) Pause() {
 	q.mu.Lock()
@@ -118,6 +266,21 @@ func (q *MessageQueue) IsPaused() bool {
 	return q.paused
 }
 
+// Close stops the queue accepting new messages, for shutdown. Messages
+// already queued can still be dequeued.
+func (q *MessageQueue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+}
+
+// IsClosed returns whether the queue has been closed.
+func (q *MessageQueue) IsClosed() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.closed
+}
+
 // Lookup returns a message by ID.
 func (q *MessageQueue) Lookup(id string) *Message {
 	q.mu.Lock()
@@ -132,6 +295,57 @@ func (q *MessageQueue) PendingCount() int {
 	return len(q.interrupt) + len(q.normal) + len(q.idleFirst) + len(q.idle)
 }
 
+// PendingSummary is a bounded view of the undelivered messages.
+type PendingSummary struct {
+	Counts map[Priority]int // undelivered messages per priority
+	Head   []Message        // copies of the first few, in delivery order
+}
+
+// Summary returns the number of undelivered messages at each priority and
+// copies of the first n in delivery order, without dequeuing them.
+func (q *MessageQueue) Summary(n int) PendingSummary {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	sum := PendingSummary{Counts: map[Priority]int{}}
+	levels := []struct {
+		p    Priority
+		msgs []*Message
+	}{
+		{PriorityInterrupt, q.interrupt},
+		{PriorityNormal, q.normal},
+		{PriorityIdleFirst, q.idleFirst},
+		{PriorityIdle, q.idle},
+	}
+	for _, level := range levels {
+		if len(level.msgs) > 0 {
+			sum.Counts[level.p] = len(level.msgs)
+		}
+		for _, msg := range level.msgs {
+			if len(sum.Head) >= n {
+				break
+			}
+			sum.Head = append(sum.Head, *msg)
+		}
+	}
+	return sum
+}
+
+// PendingAttachmentCount returns how many undelivered messages carry an
+// attachment.
+func (q *MessageQueue) PendingAttachmentCount() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	n := 0
+	for _, level := range [][]*Message{q.interrupt, q.normal, q.idleFirst, q.idle} {
+		for _, msg := range level {
+			if msg.Attachment != "" {
+				n++
+			}
+		}
+	}
+	return n
+}
+
 // Notify returns the channel that is signaled on enqueue or unpause.
 func (q *MessageQueue) Notify() <-chan struct{} {
 	return q.notify
@@ -1,6 +1,8 @@
 package message
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 )
@@ -280,3 +282,267 @@ func TestDequeue_BlockedOnlyReturnsInterrupt(t *testing.T) {
 		t.Fatalf("expected idle-1 after unblock, got %v", msg)
 	}
 }
+
+func TestDequeue_EscalatesExpiredIdleMessage(t *testing.T) {
+	q := NewMessageQueue()
+	msg := newMsg("idle-old", PriorityIdle)
+	msg.EscalateAfter = time.Minute
+	msg.CreatedAt = time.Now().Add(-2 * time.Minute)
+	q.Enqueue(msg)
+
+	// Agent busy and queue paused: an idle message would normally be held.
+	q.Pause()
+	got := q.Dequeue(false, false)
+	if got == nil || got.ID != "idle-old" {
+		t.Fatalf("expected escalated idle message, got %v", got)
+	}
+	if got.Priority != PriorityInterrupt {
+		t.Errorf("priority = %v, want interrupt", got.Priority)
+	}
+	if q.PendingCount() != 0 {
+		t.Errorf("PendingCount = %d, want 0", q.PendingCount())
+	}
+}
+
+func TestDequeue_DoesNotEscalateWithinWindow(t *testing.T) {
+	q := NewMessageQueue()
+	msg := newMsg("idle-new", PriorityIdleFirst)
+	msg.EscalateAfter = time.Hour
+	q.Enqueue(msg)
+	plain := newMsg("idle-plain", PriorityIdle)
+	plain.CreatedAt = time.Now().Add(-24 * time.Hour)
+	q.Enqueue(plain)
+
+	if got := q.Dequeue(false, false); got != nil {
+		t.Fatalf("expected nothing while busy, got %s", got.ID)
+	}
+	if got := q.Dequeue(true, false); got == nil || got.ID != "idle-new" || got.Priority != PriorityIdleFirst {
+		t.Fatalf("expected idle-new at idle-first priority, got %v", got)
+	}
+}
+
+func TestDequeue_AgingDeliversStarvedIdleMessage(t *testing.T) {
+	q := NewMessageQueue()
+	q.SetAgeAfter(20 * time.Millisecond)
+	q.Enqueue(newMsg("old-idle", PriorityIdle))
+
+	// Flood with normal messages: each round one arrives and one is
+	// delivered, so without aging the idle message would never be reached.
+	deadline := time.Now().Add(2 * time.Second)
+	for i := 0; time.Now().Before(deadline); i++ {
+		q.Enqueue(newMsg(fmt.Sprintf("flood-%d", i), PriorityNormal))
+		got := q.Dequeue(true, false)
+		if got != nil && got.ID == "old-idle" {
+			if got.Priority != PriorityNormal {
+				t.Errorf("priority = %v, want normal after aging", got.Priority)
+			}
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("aged idle message was never delivered")
+}
+
+func TestDequeue_NoAgingByDefault(t *testing.T) {
+	q := NewMessageQueue()
+	old := newMsg("old-idle", PriorityIdle)
+	q.Enqueue(old)
+	old.levelSince = time.Now().Add(-time.Hour)
+
+	for i := 0; i < 50; i++ {
+		q.Enqueue(newMsg(fmt.Sprintf("flood-%d", i), PriorityNormal))
+		if got := q.Dequeue(true, false); got == nil || got.ID == "old-idle" {
+			t.Fatalf("round %d: expected a flood message, got %v", i, got)
+		}
+	}
+	if old.Priority != PriorityIdle {
+		t.Errorf("priority = %v, want idle without aging", old.Priority)
+	}
+}
+
+func TestDequeue_AgingPromotesOneLevel(t *testing.T) {
+	q := NewMessageQueue()
+	q.SetAgeAfter(time.Minute)
+	idle := newMsg("idle", PriorityIdle)
+	normal := newMsg("normal", PriorityNormal)
+	q.Enqueue(idle)
+	q.Enqueue(normal)
+	idle.levelSince = time.Now().Add(-2 * time.Minute)
+	normal.levelSince = time.Now().Add(-2 * time.Minute)
+
+	// Busy agent: the aged idle message is now deliverable as normal,
+	// behind the normal one, which stays normal.
+	got := q.Dequeue(false, false)
+	if got == nil || got.ID != "normal" || got.Priority != PriorityNormal {
+		t.Fatalf("expected normal left at normal, got %v", got)
+	}
+	got = q.Dequeue(false, false)
+	if got == nil || got.ID != "idle" || got.Priority != PriorityNormal {
+		t.Fatalf("expected idle promoted to normal, got %v", got)
+	}
+}
+
+func TestDequeue_AgingNeverPromotesToInterrupt(t *testing.T) {
+	q := NewMessageQueue()
+	q.SetAgeAfter(time.Millisecond)
+	msgs := []*Message{
+		newMsg("idle", PriorityIdle),
+		newMsg("idle-first", PriorityIdleFirst),
+		newMsg("normal", PriorityNormal),
+	}
+	for _, msg := range msgs {
+		q.Enqueue(msg)
+		msg.levelSince = time.Now().Add(-time.Hour)
+	}
+
+	// Age repeatedly, as a long-busy agent would, without delivering.
+	for i := 0; i < 10; i++ {
+		q.mu.Lock()
+		q.age(time.Now().Add(time.Duration(i) * time.Hour))
+		q.mu.Unlock()
+	}
+	for _, msg := range msgs {
+		if msg.Priority != PriorityNormal {
+			t.Errorf("%s: priority = %v, want normal", msg.ID, msg.Priority)
+		}
+	}
+	if got := q.Dequeue(false, false); got == nil || got.Priority == PriorityInterrupt {
+		t.Fatalf("expected a normal message, got %v", got)
+	}
+}
+
+func TestDequeue_AgingHeldWhilePausedOrBlocked(t *testing.T) {
+	q := NewMessageQueue()
+	q.SetAgeAfter(time.Minute)
+	msg := newMsg("normal", PriorityNormal)
+	q.Enqueue(msg)
+	msg.levelSince = time.Now().Add(-time.Hour)
+
+	if got := q.Dequeue(true, true); got != nil {
+		t.Fatalf("blocked: expected nothing, got %s", got.ID)
+	}
+	q.Pause()
+	if got := q.Dequeue(true, false); got != nil {
+		t.Fatalf("paused: expected nothing, got %s", got.ID)
+	}
+	if msg.Priority != PriorityNormal {
+		t.Errorf("priority = %v, want normal while held", msg.Priority)
+	}
+}
+
+func TestClose_RejectsNewKeepsQueued(t *testing.T) {
+	q := NewMessageQueue()
+	q.Enqueue(newMsg("before", PriorityNormal))
+	q.Close()
+
+	if err := q.Enqueue(newMsg("after", PriorityNormal)); err != ErrQueueClosed {
+		t.Fatalf("Enqueue on a closed queue: got %v, want ErrQueueClosed", err)
+	}
+	if q.Lookup("after") != nil {
+		t.Fatal("rejected message should not be recorded")
+	}
+	if msg := q.Dequeue(true, false); msg == nil || msg.ID != "before" {
+		t.Fatalf("expected queued message still deliverable, got %v", msg)
+	}
+	if _, err := PrepareMessage(q, "agent", "sender", "hi", PriorityNormal); err != ErrQueueClosed {
+		t.Fatalf("PrepareMessage on closed queue: got %v, want ErrQueueClosed", err)
+	}
+	if _, err := EnqueueRaw(q, "y", ""); err != ErrQueueClosed {
+		t.Fatalf("EnqueueRaw on closed queue: got %v, want ErrQueueClosed", err)
+	}
+}
+
+func TestSummary_CountsAndHeadWithoutDequeuing(t *testing.T) {
+	q := NewMessageQueue()
+	q.Enqueue(newMsg("idle-1", PriorityIdle))
+	q.Enqueue(newMsg("normal-1", PriorityNormal))
+	q.Enqueue(newMsg("interrupt-1", PriorityInterrupt))
+	q.Enqueue(newMsg("normal-2", PriorityNormal))
+
+	sum := q.Summary(3)
+	var ids []string
+	for _, msg := range sum.Head {
+		ids = append(ids, msg.ID)
+	}
+	if got := fmt.Sprint(ids); got != "[interrupt-1 normal-1 normal-2]" {
+		t.Fatalf("Summary head = %s, want the first 3 in delivery order", got)
+	}
+	want := map[Priority]int{PriorityInterrupt: 1, PriorityNormal: 2, PriorityIdle: 1}
+	if fmt.Sprint(sum.Counts) != fmt.Sprint(want) {
+		t.Errorf("Summary counts = %v, want %v", sum.Counts, want)
+	}
+	if q.PendingCount() != 4 {
+		t.Fatal("Summary should not dequeue")
+	}
+	if head := q.Summary(0).Head; len(head) != 0 {
+		t.Errorf("Summary(0) head = %v, want none", head)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	tests := []struct {
+		body string
+		max  int
+		want string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"eleven chars", 10, "eleven ch…"},
+		{"multi\nline\t body", 40, "multi line body"},
+		{"héllo wörld", 6, "héllo…"},
+		{"anything", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := Preview(tt.body, tt.max); got != tt.want {
+			t.Errorf("Preview(%q, %d) = %q, want %q", tt.body, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestDedupe_WithinWindowDropped(t *testing.T) {
+	q := NewMessageQueue()
+	q.SetDedupeWindow(time.Minute)
+
+	first := &Message{ID: "m1", From: "bot", Body: "hello", Priority: PriorityNormal}
+	if err := q.Enqueue(first); err != nil {
+		t.Fatalf("first Enqueue: %v", err)
+	}
+	err := q.Enqueue(&Message{ID: "m2", From: "bot", Body: "hello", Priority: PriorityIdle})
+	var dup *DuplicateError
+	if !errors.As(err, &dup) || dup.OriginalID != "m1" {
+		t.Fatalf("duplicate Enqueue: got %v, want DuplicateError for m1", err)
+	}
+	if q.PendingCount() != 1 || q.Lookup("m2") != nil {
+		t.Fatal("duplicate should not be queued")
+	}
+
+	// Raw messages (e.g. repeated permission answers) are never deduped.
+	for _, id := range []string{"r1", "r2"} {
+		if err := q.Enqueue(&Message{ID: id, Body: "y", Raw: true, Priority: PriorityInterrupt}); err != nil {
+			t.Fatalf("raw Enqueue %s: %v", id, err)
+		}
+	}
+}
+
+func TestDedupe_OutsideWindowAccepted(t *testing.T) {
+	q := NewMessageQueue()
+	q.SetDedupeWindow(20 * time.Millisecond)
+
+	q.Enqueue(&Message{ID: "m1", From: "bot", Body: "hello", Priority: PriorityNormal})
+	time.Sleep(30 * time.Millisecond)
+	if err := q.Enqueue(&Message{ID: "m2", From: "bot", Body: "hello", Priority: PriorityNormal}); err != nil {
+		t.Fatalf("Enqueue after the window: %v", err)
+	}
+	if q.PendingCount() != 2 {
+		t.Fatalf("PendingCount = %d, want 2", q.PendingCount())
+	}
+}
+
+func TestDedupe_OffByDefault(t *testing.T) {
+	q := NewMessageQueue()
+	for _, id := range []string{"m1", "m2"} {
+		if err := q.Enqueue(&Message{ID: id, From: "bot", Body: "hello", Priority: PriorityNormal}); err != nil {
+			t.Fatalf("Enqueue %s: %v", id, err)
+		}
+	}
+}
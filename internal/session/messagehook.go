@@ -0,0 +1,117 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"time"
+
+	"h2/internal/session/agent"
+	"h2/internal/session/message"
+)
+
+// messageHookTimeout bounds how long an on_message command may run.
+// Var so tests can override it.
+var messageHookTimeout = 30 * time.Second
+
+// correlationStartWait bounds how long a delivered message's correlation ID
+// stays current if the agent never starts working on the message. Var so
+// tests can override it.
+var correlationStartWait = 30 * time.Second
+
+// maxMessageHookOutput caps how much hook output is kept in the activity log.
+const maxMessageHookOutput = 4096
+
+// messageHook returns the delivery callback that runs the role's on_message
+// command, or nil if none is configured. The command runs in the background
+// so a slow or failing hook never holds up delivery.
+func (s *Session) messageHook() func(*message.Message) {
+	if s.OnMessageCmd == "" {
+		return nil
+	}
+	return func(msg *message.Message) {
+		go s.runMessageHook(*msg)
+	}
+}
+
+// onMessageDelivered returns the delivery callback that records each
+// delivered message in the activity log and then calls next (if any). The
+// message's correlation ID becomes the log's current ID until the agent has
+// processed the message, so the hook events it produces meanwhile carry the
+// same ID; a message without one clears it.
+func (s *Session) onMessageDelivered(next func(*message.Message)) func(*message.Message) {
+	return func(msg *message.Message) {
+		log := s.Agent.ActivityLog()
+		reset := log.SetCorrelationID(msg.CorrelationID)
+		log.MessageDelivered(msg.ID, msg.From, msg.Priority.String())
+		if msg.CorrelationID != "" {
+			go s.endCorrelation(reset)
+		}
+		if next != nil {
+			next(msg)
+		}
+	}
+}
+
+// endCorrelation calls reset once the agent has picked up the delivered
+// message and gone idle again, or if it doesn't start on it within
+// correlationStartWait, or when the session stops.
+func (s *Session) endCorrelation(reset func()) {
+	defer reset()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-s.stopCh:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	startCtx, startCancel := context.WithTimeout(ctx, correlationStartWait)
+	started := s.Agent.WaitForState(startCtx, agent.StateActive)
+	startCancel()
+	if started {
+		s.Agent.WaitForState(ctx, agent.StateIdle)
+	}
+}
+
+// runMessageHook runs the on_message command for a delivered message with
+// the message details in its environment, and records the result in the
+// activity log. It runs in the daemon's working directory (the agent's).
+func (s *Session) runMessageHook(msg message.Message) {
+	ctx, cancel := context.WithTimeout(context.Background(), messageHookTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", s.OnMessageCmd)
+	cmd.Env = append(os.Environ(),
+		"H2_ACTOR="+s.Name,
+		"H2_MESSAGE_ID="+msg.ID,
+		"H2_MESSAGE_FROM="+msg.From,
+		"H2_MESSAGE_PRIORITY="+msg.Priority.String(),
+		"H2_MESSAGE_BODY="+msg.Body,
+		"H2_MESSAGE_FILE="+msg.FilePath,
+	)
+	out, err := cmd.CombinedOutput()
+	if len(out) > maxMessageHookOutput {
+		out = out[:maxMessageHookOutput]
+	}
+
+	exitCode := 0
+	var errText string
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			exitCode = exitErr.ExitCode()
+		} else {
+			exitCode = -1
+		}
+		if ctx.Err() == context.DeadlineExceeded {
+			errText = "timed out after " + messageHookTimeout.String()
+		} else {
+			errText = err.Error()
+		}
+	}
+	s.Agent.ActivityLog().MessageHook(msg.ID, exitCode, string(out), errText)
+}
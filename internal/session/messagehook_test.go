@@ -0,0 +1,326 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"h2/internal/activitylog"
+	"h2/internal/session/agent/collector"
+	"h2/internal/session/message"
+)
+
+// lockedBuffer is a bytes.Buffer safe for concurrent writes.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// waitForFile polls until path exists with content containing want.
+func waitForFile(t *testing.T, path, want string) string {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if data, err := os.ReadFile(path); err == nil && strings.Contains(string(data), want) {
+			return string(data)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	data, _ := os.ReadFile(path)
+	t.Fatalf("timed out waiting for %q in %s, got %q", want, path, data)
+	return ""
+}
+
+func TestMessageHook_RunsWithMessageEnv(t *testing.T) {
+	dir := t.TempDir()
+	envFile := filepath.Join(dir, "env.txt")
+
+	s := newTestSession()
+	s.OnMessageCmd = `env | grep '^H2_MESSAGE_' | sort > ` + envFile
+	hook := s.messageHook()
+	if hook == nil {
+		t.Fatal("expected a hook when OnMessageCmd is set")
+	}
+
+	hook(&message.Message{
+		ID:       "msg-1",
+		From:     "alice",
+		Priority: message.PriorityIdle,
+		Body:     "check the build",
+	})
+
+	env := waitForFile(t, envFile, "H2_MESSAGE_PRIORITY")
+	for _, want := range []string{
+		"H2_MESSAGE_BODY=check the build",
+		"H2_MESSAGE_FROM=alice",
+		"H2_MESSAGE_ID=msg-1",
+		"H2_MESSAGE_PRIORITY=idle",
+	} {
+		if !strings.Contains(env, want+"\n") {
+			t.Errorf("hook env missing %q:\n%s", want, env)
+		}
+	}
+}
+
+func TestMessageHook_UnsetReturnsNil(t *testing.T) {
+	s := newTestSession()
+	if s.messageHook() != nil {
+		t.Fatal("expected no hook when OnMessageCmd is empty")
+	}
+}
+
+func TestMessageHook_FailureLoggedAndDeliveryContinues(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "activity.jsonl")
+	s := newTestSession()
+	s.Agent.SetActivityLog(activitylog.New(true, logPath, "test", "sid"))
+	s.OnMessageCmd = "echo boom; exit 3"
+
+	var pty lockedBuffer
+	q := message.NewMessageQueue()
+	stop := make(chan struct{})
+	defer close(stop)
+
+	delivered := make(chan struct{}, 2)
+	go message.RunDelivery(message.DeliveryConfig{
+		Queue:     q,
+		PtyWriter: &pty,
+		IsIdle:    func() bool { return true },
+		OnMessage: s.messageHook(),
+		OnDeliver: func() { delivered <- struct{}{} },
+		Stop:      stop,
+	})
+
+	for _, id := range []string{"m1", "m2"} {
+		q.Enqueue(&message.Message{
+			ID:        id,
+			From:      "user",
+			Priority:  message.PriorityNormal,
+			Body:      "body " + id,
+			Raw:       true,
+			Status:    message.StatusQueued,
+			CreatedAt: time.Now(),
+		})
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-delivered:
+		case <-time.After(3 * time.Second):
+			t.Fatalf("only %d of 2 messages delivered with a failing hook", i)
+		}
+	}
+
+	logged := waitForFile(t, logPath, `"exit_code":3`)
+	if !strings.Contains(logged, `"event":"message_hook"`) || !strings.Contains(logged, "boom") {
+		t.Errorf("expected failing hook output in activity log, got:\n%s", logged)
+	}
+}
+
+func TestCorrelationID_SendToDeliveryAndHookEvents(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	logPath := filepath.Join(t.TempDir(), "activity.jsonl")
+	s := New("test", "true", nil)
+	s.Agent.SetActivityLog(activitylog.New(true, logPath, "test", "sid"))
+	d := &Daemon{Session: s}
+
+	resp := sendViaDaemon(t, d, &message.Request{
+		Type: "send", Priority: "normal", From: "alice", Body: "run the tests", CorrelationID: "req-7",
+	})
+	if !resp.OK {
+		t.Fatalf("send failed: %s", resp.Error)
+	}
+	if got := s.Queue.Lookup(resp.MessageID).CorrelationID; got != "req-7" {
+		t.Fatalf("message CorrelationID = %q, want %q", got, "req-7")
+	}
+
+	var pty lockedBuffer
+	stop := make(chan struct{})
+	defer close(stop)
+	delivered := make(chan struct{}, 1)
+	go message.RunDelivery(message.DeliveryConfig{
+		Queue:     s.Queue,
+		PtyWriter: &pty,
+		IsIdle:    func() bool { return true },
+		OnMessage: s.onMessageDelivered(nil),
+		OnDeliver: func() { delivered <- struct{}{} },
+		Stop:      stop,
+	})
+	select {
+	case <-delivered:
+	case <-time.After(3 * time.Second):
+		t.Fatal("message not delivered")
+	}
+
+	// The agent's hook events while processing the message share the CID.
+	hc := collector.NewHookCollector(s.Agent.ActivityLog())
+	defer hc.Stop()
+	hc.ProcessEvent("UserPromptSubmit", json.RawMessage(`{"session_id":"cs"}`))
+	hc.ProcessEvent("PreToolUse", json.RawMessage(`{"session_id":"cs","tool_name":"Bash"}`))
+
+	logged := waitForFile(t, logPath, `"hook_event":"PreToolUse"`)
+	events := map[string]string{}
+	for _, line := range strings.Split(strings.TrimSpace(logged), "\n") {
+		var e struct {
+			Event         string `json:"event"`
+			HookEvent     string `json:"hook_event"`
+			CorrelationID string `json:"correlation_id"`
+			MessageID     string `json:"message_id"`
+		}
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("unmarshal %q: %v", line, err)
+		}
+		key := e.Event
+		if e.HookEvent != "" {
+			key = e.HookEvent
+		}
+		if e.Event == "message_delivered" && e.MessageID != resp.MessageID {
+			t.Errorf("message_delivered message_id = %q, want %q", e.MessageID, resp.MessageID)
+		}
+		events[key] = e.CorrelationID
+	}
+	for _, key := range []string{"message_delivered", "UserPromptSubmit", "PreToolUse"} {
+		cid, ok := events[key]
+		if !ok {
+			t.Errorf("missing %s event in activity log:\n%s", key, logged)
+			continue
+		}
+		if cid != "req-7" {
+			t.Errorf("%s correlation_id = %q, want %q", key, cid, "req-7")
+		}
+	}
+}
+
+func TestCorrelationID_ClearedAfterDelivery(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	orig := correlationStartWait
+	correlationStartWait = 20 * time.Millisecond
+	defer func() { correlationStartWait = orig }()
+
+	logPath := filepath.Join(t.TempDir(), "activity.jsonl")
+	s := New("test", "true", nil)
+	log := activitylog.New(true, logPath, "test", "sid")
+	s.Agent.SetActivityLog(log)
+
+	s.onMessageDelivered(nil)(&message.Message{
+		ID: "m1", From: "alice", Priority: message.PriorityNormal, CorrelationID: "req-9",
+	})
+	// The agent never picks the message up, so the ID lapses after the wait.
+	time.Sleep(200 * time.Millisecond)
+	log.StateChange("idle", "active")
+
+	logged := waitForFile(t, logPath, `"event":"state_change"`)
+	for _, line := range strings.Split(strings.TrimSpace(logged), "\n") {
+		if strings.Contains(line, "state_change") && strings.Contains(line, "req-9") {
+			t.Errorf("event after the message was handled still carries its ID: %s", line)
+		}
+	}
+}
+
+func TestActivityLog_TypedInputVsDeliveredMessage(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	logPath := filepath.Join(t.TempDir(), "activity.jsonl")
+	s := newTestSession()
+	s.Agent.SetActivityLog(activitylog.New(true, logPath, "test", "sid"))
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+	go io.Copy(io.Discard, r)
+	s.VT.Ptm = w
+
+	// Typing into the input bar and pressing Enter writes straight to the PTY.
+	cl := s.NewClient()
+	cl.Input = []byte("hello agent")
+	cl.CursorPos = len(cl.Input)
+	cl.HandleDefaultBytes([]byte{'\r'}, 0, 1)
+
+	logged := waitForFile(t, logPath, `"event":"user_input"`)
+	if !strings.Contains(logged, `"client":"`+cl.Label+`"`) || !strings.Contains(logged, `"bytes":11`) {
+		t.Errorf("user_input should record the client and size, got:\n%s", logged)
+	}
+	if strings.Contains(logged, "message_delivered") {
+		t.Errorf("typed input should not log a delivery, got:\n%s", logged)
+	}
+
+	// A queued message is logged as a delivery, not as user input.
+	message.PrepareMessage(s.Queue, s.Name, "deploy-bot", "ship it", message.PriorityNormal)
+	var pty lockedBuffer
+	stop := make(chan struct{})
+	defer close(stop)
+	go message.RunDelivery(message.DeliveryConfig{
+		Queue:     s.Queue,
+		PtyWriter: &pty,
+		IsIdle:    func() bool { return true },
+		OnMessage: s.onMessageDelivered(nil),
+		Stop:      stop,
+	})
+	logged = waitForFile(t, logPath, `"event":"message_delivered"`)
+	if n := strings.Count(logged, `"event":"user_input"`); n != 1 {
+		t.Errorf("user_input entries = %d, want 1:\n%s", n, logged)
+	}
+
+	// Input typed at another priority is queued, and counted only when it
+	// is delivered.
+	cl.InputPriority = message.PriorityInterrupt
+	cl.Input = []byte("stop")
+	cl.CursorPos = len(cl.Input)
+	cl.HandleDefaultBytes([]byte{'\r'}, 0, 1)
+	logged = waitForFile(t, logPath, `"from":"user"`)
+	if n := strings.Count(logged, `"event":"user_input"`); n != 1 {
+		t.Errorf("queued typed input logged as user_input too (%d entries):\n%s", n, logged)
+	}
+}
+
+func TestSendFrom_DequeuedMessageAndDeliveryEvent(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	logPath := filepath.Join(t.TempDir(), "activity.jsonl")
+	s := New("test", "true", nil)
+	s.Agent.SetActivityLog(activitylog.New(true, logPath, "test", "sid"))
+	d := &Daemon{Session: s}
+
+	resp := sendViaDaemon(t, d, &message.Request{
+		Type: "send", Priority: "normal", From: "deploy-bot", Body: "ship it",
+	})
+	if !resp.OK {
+		t.Fatalf("send failed: %s", resp.Error)
+	}
+
+	var pty lockedBuffer
+	stop := make(chan struct{})
+	defer close(stop)
+	dequeued := make(chan message.Message, 1)
+	go message.RunDelivery(message.DeliveryConfig{
+		Queue:     s.Queue,
+		PtyWriter: &pty,
+		IsIdle:    func() bool { return true },
+		OnMessage: s.onMessageDelivered(func(msg *message.Message) { dequeued <- *msg }),
+		Stop:      stop,
+	})
+	var msg message.Message
+	select {
+	case msg = <-dequeued:
+	case <-time.After(3 * time.Second):
+		t.Fatal("message not delivered")
+	}
+	if msg.From != "deploy-bot" {
+		t.Errorf("dequeued From = %q, want %q", msg.From, "deploy-bot")
+	}
+
+	logged := waitForFile(t, logPath, `"event":"message_delivered"`)
+	if !strings.Contains(logged, `"from":"deploy-bot"`) {
+		t.Errorf("message_delivered should record the sender, got:\n%s", logged)
+	}
+}
@@ -1,7 +1,13 @@
 package session
 
 import (
+	"fmt"
 	"math/rand/v2"
+	"os"
+	"regexp"
+	"strings"
+
+	"h2/internal/socketdir"
 )
 
 // adjectives for name generation.
@@ -45,9 +51,141 @@ var nouns = []string{
 	"vine", "wren", "wolf", "wood", "yarn",
 }
 
-// GenerateName produces a random adjective-noun name like "calm-brook".
+// Naming schemes selectable via name_scheme in config.yaml.
+const (
+	NameSchemeAdjectiveAnimal = "adjective-animal" // default, e.g. "calm-brook"
+	NameSchemeRoleN           = "role-n"           // sequential per role, e.g. "coder-3"
+	NameSchemeWordlistPrefix  = "wordlist:"        // "wordlist:/path/to/words.txt"
+)
+
+// maxNameAttempts bounds random retries when a generated name is taken.
+const maxNameAttempts = 50
+
+var agentNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidateAgentName checks that name is usable as an agent name: it appears
+// in socket filenames and session directories, so it must be a short,
+// path-safe token.
+func ValidateAgentName(name string) error {
+	if len(name) > 64 {
+		return fmt.Errorf("invalid agent name %q: must be at most 64 characters", name)
+	}
+	if !agentNameRe.MatchString(name) {
+		return fmt.Errorf("invalid agent name %q: must match [A-Za-z0-9][A-Za-z0-9._-]*", name)
+	}
+	return nil
+}
+
+// nameInUse reports whether an agent with the given name is running.
+// Var so tests can override it.
+var nameInUse = func(name string) bool {
+	_, err := socketdir.Find(name)
+	return err == nil
+}
+
+// GenerateName produces a name using the default scheme, without a role.
 func GenerateName() string {
+	return uniqueName(adjectiveAnimal)
+}
+
+// GenerateNameForRole produces an agent name using scheme ("" means
+// adjective-animal). The result always passes ValidateAgentName and does
+// not collide with a running agent. role is used by the role-n scheme and
+// ignored otherwise. A wordlist that can't be read or has no usable words
+// is an error rather than a silent switch to another scheme.
+func GenerateNameForRole(scheme, role string) (string, error) {
+	switch {
+	case scheme == NameSchemeRoleN:
+		return sequentialName(role), nil
+	case strings.HasPrefix(scheme, NameSchemeWordlistPrefix):
+		path := strings.TrimPrefix(scheme, NameSchemeWordlistPrefix)
+		words, err := loadWordlist(path)
+		if err != nil {
+			return "", fmt.Errorf("name_scheme %q: %w", scheme, err)
+		}
+		if len(words) == 0 {
+			return "", fmt.Errorf("name_scheme %q: wordlist %s has no usable words", scheme, path)
+		}
+		return uniqueName(func() string { return words[rand.IntN(len(words))] }), nil
+	}
+	return uniqueName(adjectiveAnimal), nil
+}
+
+// adjectiveAnimal produces a random adjective-noun name like "calm-brook".
+func adjectiveAnimal() string {
 	adj := adjectives[rand.IntN(len(adjectives))]
 	noun := nouns[rand.IntN(len(nouns))]
 	return adj + "-" + noun
 }
+
+// uniqueName draws names from gen until one is free. If every attempt is
+// taken, a numeric suffix is appended to the last draw.
+func uniqueName(gen func() string) string {
+	var name string
+	for i := 0; i < maxNameAttempts; i++ {
+		name = gen()
+		if !nameInUse(name) {
+			return name
+		}
+	}
+	base := name
+	for n := 2; ; n++ {
+		name = fmt.Sprintf("%s-%d", base, n)
+		if !nameInUse(name) {
+			return name
+		}
+	}
+}
+
+// sequentialName returns "<role>-N" for the smallest N >= 1 not in use.
+func sequentialName(role string) string {
+	base := sanitizeNameWord(role)
+	if base == "" {
+		base = "agent"
+	}
+	for n := 1; ; n++ {
+		name := fmt.Sprintf("%s-%d", base, n)
+		if !nameInUse(name) {
+			return name
+		}
+	}
+}
+
+// loadWordlist reads one word per line, skipping blanks and # comments.
+// Words are lowercased and stripped of characters not allowed in agent names.
+func loadWordlist(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var words []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if w := sanitizeNameWord(line); w != "" {
+			words = append(words, w)
+		}
+	}
+	return words, nil
+}
+
+// sanitizeNameWord lowercases s and replaces disallowed characters with
+// dashes so it passes ValidateAgentName.
+func sanitizeNameWord(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('-')
+		}
+	}
+	w := strings.Trim(b.String(), "-_.")
+	if len(w) > 48 {
+		w = w[:48]
+	}
+	return w
+}
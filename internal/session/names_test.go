@@ -1,6 +1,8 @@
 package session
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -24,3 +26,129 @@ func TestGenerateName(t *testing.T) {
 		t.Fatalf("expected some variety in 20 names, got %d unique", len(seen))
 	}
 }
+
+// withNamesInUse makes nameInUse report the names in taken as running
+// agents. Tests add each generated name to taken so later draws see it.
+func withNamesInUse(t *testing.T, taken map[string]bool) {
+	t.Helper()
+	orig := nameInUse
+	nameInUse = func(name string) bool { return taken[name] }
+	t.Cleanup(func() { nameInUse = orig })
+}
+
+func TestGenerateNameForRole_AdjectiveAnimalUnique(t *testing.T) {
+	taken := map[string]bool{}
+	withNamesInUse(t, taken)
+
+	for i := 0; i < 500; i++ {
+		name, err := GenerateNameForRole("", "coder")
+		if err != nil {
+			t.Fatalf("GenerateNameForRole: %v", err)
+		}
+		if err := ValidateAgentName(name); err != nil {
+			t.Fatalf("generated invalid name: %v", err)
+		}
+		if taken[name] {
+			t.Fatalf("collision on %q after %d names", name, i)
+		}
+		taken[name] = true
+	}
+}
+
+func TestGenerateNameForRole_RoleN(t *testing.T) {
+	scheme := NameSchemeRoleN
+	taken := map[string]bool{"coder-2": true}
+	withNamesInUse(t, taken)
+
+	want := []string{"coder-1", "coder-3", "coder-4"}
+	for _, w := range want {
+		name, err := GenerateNameForRole(scheme, "coder")
+		if err != nil {
+			t.Fatalf("GenerateNameForRole: %v", err)
+		}
+		if name != w {
+			t.Fatalf("got %q, want %q", name, w)
+		}
+		taken[name] = true
+	}
+	for i := 0; i < 100; i++ {
+		name, err := GenerateNameForRole(scheme, "Code Reviewer")
+		if err != nil {
+			t.Fatalf("GenerateNameForRole: %v", err)
+		}
+		if err := ValidateAgentName(name); err != nil {
+			t.Fatalf("generated invalid name: %v", err)
+		}
+		if !strings.HasPrefix(name, "code-reviewer-") || taken[name] {
+			t.Fatalf("unexpected or colliding name %q", name)
+		}
+		taken[name] = true
+	}
+
+	if got, _ := GenerateNameForRole(scheme, ""); got != "agent-1" {
+		t.Errorf("no role: got %q, want agent-1", got)
+	}
+}
+
+func TestGenerateNameForRole_Wordlist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	os.WriteFile(path, []byte("# crew\nAlpha\nbravo\n\nCharlie Delta\n"), 0o644)
+	scheme := NameSchemeWordlistPrefix + path
+	taken := map[string]bool{}
+	withNamesInUse(t, taken)
+
+	allowed := map[string]bool{"alpha": true, "bravo": true, "charlie-delta": true}
+	for i := 0; i < 50; i++ {
+		name, err := GenerateNameForRole(scheme, "coder")
+		if err != nil {
+			t.Fatalf("GenerateNameForRole: %v", err)
+		}
+		if err := ValidateAgentName(name); err != nil {
+			t.Fatalf("generated invalid name: %v", err)
+		}
+		if taken[name] {
+			t.Fatalf("collision on %q", name)
+		}
+		// Once the list is exhausted, names get a numeric suffix.
+		base := name
+		if i := strings.LastIndex(name, "-"); i > 0 && !allowed[name] {
+			base = name[:i]
+		}
+		if !allowed[base] {
+			t.Fatalf("name %q not derived from wordlist", name)
+		}
+		taken[name] = true
+	}
+}
+
+func TestGenerateNameForRole_BadWordlistIsAnError(t *testing.T) {
+	empty := filepath.Join(t.TempDir(), "empty.txt")
+	os.WriteFile(empty, []byte("# nothing here\n\n"), 0o644)
+	withNamesInUse(t, map[string]bool{})
+
+	for _, tc := range []struct {
+		path    string
+		wantErr string
+	}{
+		{"/nonexistent/words.txt", "no such file"},
+		{empty, "no usable words"},
+	} {
+		name, err := GenerateNameForRole(NameSchemeWordlistPrefix+tc.path, "coder")
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("wordlist %s: got %q, %v; want an error containing %q", tc.path, name, err, tc.wantErr)
+		}
+	}
+}
+
+func TestValidateAgentName(t *testing.T) {
+	for _, name := range []string{"calm-brook", "coder-1", "a", "Agent_2.b"} {
+		if err := ValidateAgentName(name); err != nil {
+			t.Errorf("ValidateAgentName(%q) = %v, want nil", name, err)
+		}
+	}
+	for _, name := range []string{"", "-lead", "has space", "a/b", strings.Repeat("x", 65)} {
+		if err := ValidateAgentName(name); err == nil {
+			t.Errorf("ValidateAgentName(%q) = nil, want error", name)
+		}
+	}
+}
@@ -171,6 +171,7 @@ func (t otelOnlyAgentType) Collectors() agent.CollectorSet                     {
 func (t otelOnlyAgentType) OtelParser() agent.OtelParser                       { return nil }
 func (t otelOnlyAgentType) PrependArgs(sessionID string) []string              { return nil }
 func (t otelOnlyAgentType) ChildEnv(cp *agent.CollectorPorts) map[string]string { return nil }
+func (t otelOnlyAgentType) SupportsRoleFlags() bool                            { return false }
 
 func TestOtelCollector_StateTransitionOnEvent(t *testing.T) {
 	old := collector.IdleThreshold
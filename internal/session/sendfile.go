@@ -0,0 +1,68 @@
+package session
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"h2/internal/session/message"
+)
+
+// SendFile enqueues a message that points the agent at a file, for the
+// menu's send-file action. A relative path is resolved against the daemon's
+// working directory (the agent's), and the file must be a regular file
+// inside it, following symlinks.
+func (s *Session) SendFile(path string, priority message.Priority) error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("working dir: %w", err)
+	}
+	abs := path
+	if !filepath.IsAbs(abs) {
+		abs = filepath.Join(wd, abs)
+	}
+	abs = filepath.Clean(abs)
+
+	info, err := os.Stat(abs)
+	if err != nil {
+		return fmt.Errorf("no such file: %s", path)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("not a regular file: %s", path)
+	}
+	if !withinDir(wd, abs) {
+		return fmt.Errorf("outside the working dir: %s", path)
+	}
+
+	return s.Queue.Enqueue(&message.Message{
+		ID:        uuid.New().String(),
+		From:      "user",
+		Priority:  priority,
+		Body:      "Read " + abs,
+		FilePath:  abs,
+		Status:    message.StatusQueued,
+		CreatedAt: time.Now(),
+	})
+}
+
+// withinDir reports whether path, after resolving symlinks, is dir or
+// somewhere below it.
+func withinDir(dir, path string) bool {
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		return false
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(realDir, realPath)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
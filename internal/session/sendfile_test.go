@@ -0,0 +1,82 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"h2/internal/session/client"
+	"h2/internal/session/message"
+)
+
+func TestMenuSendFile_EnqueuesFileMessage(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.WriteFile(filepath.Join(dir, "notes.md"), []byte("todo"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s := newTestSession()
+	cl := s.NewClient()
+	if !strings.Contains(cl.MenuLabel(), "f:send file") {
+		t.Fatalf("menu label should offer send file, got %q", cl.MenuLabel())
+	}
+
+	cl.Input = []byte("notes.md")
+	cl.CursorPos = len(cl.Input)
+	cl.Mode = client.ModeMenu
+	cl.HandleMenuBytes([]byte{'f'}, 0, 1)
+
+	if cl.Mode != client.ModeNormal {
+		t.Errorf("mode = %d, want ModeNormal", cl.Mode)
+	}
+	if len(cl.Input) != 0 {
+		t.Errorf("input should be cleared after sending, got %q", cl.Input)
+	}
+	msg := s.Queue.Dequeue(true, false)
+	if msg == nil {
+		t.Fatal("expected a queued message")
+	}
+	real, _ := filepath.EvalSymlinks(filepath.Join(dir, "notes.md"))
+	got, _ := filepath.EvalSymlinks(msg.FilePath)
+	if got != real {
+		t.Errorf("FilePath = %q, want %q", msg.FilePath, real)
+	}
+	if msg.From != "user" || msg.Priority != message.PriorityNormal || !strings.HasPrefix(msg.Body, "Read ") {
+		t.Errorf("unexpected message: %+v", msg)
+	}
+}
+
+func TestMenuSendFile_InvalidPathWarns(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Mkdir(filepath.Join(dir, "sub"), 0o755)
+
+	for path, want := range map[string]string{
+		"missing.md": "no such file",
+		"sub":        "not a regular file",
+		outside:      "outside the working dir",
+		"":           "type a file path",
+	} {
+		s := newTestSession()
+		cl := s.NewClient()
+		cl.Input = []byte(path)
+		cl.CursorPos = len(cl.Input)
+		cl.Mode = client.ModeMenu
+		cl.HandleMenuBytes([]byte{'f'}, 0, 1)
+
+		if !strings.Contains(cl.Warning, want) {
+			t.Errorf("%q: warning = %q, want it to contain %q", path, cl.Warning, want)
+		}
+		if n := s.Queue.PendingCount(); n != 0 {
+			t.Errorf("%q: queued %d messages, want 0", path, n)
+		}
+		if string(cl.Input) != path {
+			t.Errorf("%q: input should be kept for correction, got %q", path, cl.Input)
+		}
+	}
+}
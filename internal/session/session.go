@@ -1,11 +1,14 @@
 package session
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"sync"
 	"time"
@@ -15,6 +18,7 @@ import (
 	"golang.org/x/term"
 
 	"h2/internal/activitylog"
+	"h2/internal/config"
 	"h2/internal/session/agent"
 	"h2/internal/session/client"
 	"h2/internal/session/message"
@@ -37,6 +41,7 @@ type Session struct {
 	PermissionMode  string   // Permission mode, passed via --permission-mode
 	AllowedTools    []string // Allowed tools, passed via --allowedTools (comma-joined)
 	DisallowedTools []string // Disallowed tools, passed via --disallowedTools (comma-joined)
+	ExtraArgs       []string // role extra_args, appended after all h2-managed flags
 	Queue      *message.MessageQueue
 	AgentName  string
 	Agent      *agent.Agent
@@ -45,14 +50,118 @@ type Session struct {
 	Clients          []*client.Client
 	clientsMu        sync.Mutex
 	PassthroughOwner *client.Client // which client owns passthrough mode (nil = none)
+	ControlOwner     *client.Client // which client may send input (nil = none yet)
+	WriteLock        bool           // only ControlOwner may send input; off = every client may
+	clientSeq        int            // numbers clients for their labels
+
+	// ControlForceAfter is how long a control request waits for a grant
+	// before the requester may force it (0 = defaultControlForceAfter).
+	ControlForceAfter time.Duration
+
+	controlRequester   *client.Client // client waiting for control (nil = none)
+	controlRequestedAt time.Time
 
 	// ExtraEnv holds additional environment variables to pass to the child process.
 	ExtraEnv map[string]string
 
+	// RoleEnv is the role's env map, applied to ExtraEnv after h2's own
+	// variables so explicit values win.
+	RoleEnv map[string]string
+
 	// Heartbeat nudge configuration.
-	HeartbeatIdleTimeout time.Duration
-	HeartbeatMessage     string
-	HeartbeatCondition   string
+	HeartbeatIdleTimeout      time.Duration
+	HeartbeatMessage          string
+	HeartbeatCondition        string
+	HeartbeatConditionTimeout time.Duration    // 0 = 5s default
+	HeartbeatPriority         message.Priority // 0 = idle
+	HeartbeatEscalateAfter    int              // unanswered nudges before escalating (0 = never)
+	HeartbeatEscalateMessage  string
+	HeartbeatEscalateCommand  string
+
+	// EscalateAfter is the default escalation window for idle-priority
+	// messages received over the socket (0 = never escalate).
+	EscalateAfter time.Duration
+
+	// NoConfirmQuit disables the menu Quit confirmation step.
+	NoConfirmQuit bool
+
+	// DrainOnQuit delivers queued messages before a menu Quit stops the
+	// child, instead of dropping them.
+	DrainOnQuit bool
+
+	// ShutdownGrace bounds draining on quit and the wait between SIGTERM
+	// and SIGKILL (0 = DefaultShutdownGrace).
+	ShutdownGrace time.Duration
+
+	// PassthroughIdleTimeout releases the passthrough lock (and resumes the
+	// queue) once its owner has sent no keystrokes for this long (0 = never).
+	PassthroughIdleTimeout time.Duration
+
+	// StatusFile, when set, is rewritten with a one-line status summary on
+	// each status tick so tools like tmux can display it.
+	StatusFile string
+	lastStatusLine string // last line written to StatusFile
+
+	// NoPassthrough forbids clients from entering passthrough mode,
+	// leaving only message composition.
+	NoPassthrough bool
+
+	// MaxInputLen overrides the client's input bar length cap when > 0.
+	MaxInputLen int
+
+	// SubmitNewline selects the bytes written to the child PTY on submit.
+	SubmitNewline virtualterminal.SubmitNewline
+
+	// SubmitDelay is the pause between typed text and the submit bytes,
+	// for Ink-based TUIs that batch input. Zero writes the submit inline.
+	SubmitDelay time.Duration
+
+	// ModifierEnter selects what Shift+Enter and Alt+Enter do.
+	ModifierEnter virtualterminal.EnterAction
+
+	// InputPlaceholder is the hint shown in the input bar while it is empty.
+	InputPlaceholder string
+
+	// DurationPrecision selects how idle times are shown in the status bar
+	// and status file.
+	DurationPrecision virtualterminal.DurationPrecision
+
+	// IdleThreshold is how long the agent must be quiet before it counts
+	// as idle, for both the state machine and the status bar (0 = 2s).
+	IdleThreshold time.Duration
+
+	// ActivityLogRotation holds the role's activity log rotation limits;
+	// zero fields take activitylog.DefaultRotation's.
+	ActivityLogRotation activitylog.Rotation
+
+	// InitialRows and InitialCols size the daemon PTY until the first
+	// client attaches (0 = DefaultDaemonRows/DefaultDaemonCols).
+	InitialRows int
+	InitialCols int
+
+	// ScrollOnOutput selects whether scrolled-back clients stay put or
+	// jump to the live view when new output arrives.
+	ScrollOnOutput virtualterminal.ScrollPolicy
+
+	// StartMessage is enqueued once, when the agent first goes idle after
+	// launch (role on_start_message).
+	StartMessage string
+
+	// StartMessagePriority is the queue priority of StartMessage (0 = normal).
+	StartMessagePriority message.Priority
+
+	// NoHooks means the agent was launched against a config dir without
+	// h2's hooks, so the hook collector is skipped (role no_hooks).
+	NoHooks bool
+
+	// OnMessageCmd is a shell command run after each message is delivered
+	// (role on_message).
+	OnMessageCmd string
+
+	// ReadyPattern, when set, marks the agent idle as soon as the visible
+	// screen matches it instead of waiting for the output timeout. Ignored
+	// when the agent reports its state through hooks.
+	ReadyPattern *regexp.Regexp
 
 	// Daemon holds the networking/attach layer (nil in interactive mode).
 	Daemon    *Daemon
@@ -67,8 +176,21 @@ type Session struct {
 	relaunchCh chan struct{}
 	quitCh     chan struct{}
 
+	// deliveryStop stops the delivery loop; deliveryDone is closed once it
+	// has returned.
+	deliveryStop     chan struct{}
+	deliveryStopOnce sync.Once
+	deliveryDone     chan struct{}
+
 	// OnDeliver is called after each message delivery (e.g. to re-render UI).
 	OnDeliver func()
+
+	// Child run tracking, guarded by runMu. runEnd is zero while the
+	// current child is running.
+	runMu        sync.Mutex
+	runStart     time.Time
+	runEnd       time.Time
+	restartCount int
 }
 
 // New creates a new Session with the given name and command.
@@ -80,11 +202,15 @@ func New(name string, command string, args []string) *Session {
 		Args:       args,
 		AgentName:  name,
 		Queue:      message.NewMessageQueue(),
+		SubmitDelay: message.DefaultSubmitDelay,
 		Agent:      agent.New(agentType),
 		exitNotify: make(chan struct{}, 1),
 		stopCh:     make(chan struct{}),
 		relaunchCh: make(chan struct{}, 1),
 		quitCh:     make(chan struct{}, 1),
+
+		deliveryStop: make(chan struct{}),
+		deliveryDone: make(chan struct{}),
 	}
 }
 
@@ -116,11 +242,174 @@ func (pw *sessionPtyWriter) Write(p []byte) (int, error) {
 	return n, err
 }
 
+// ScreenInfo returns a snapshot of the child's visible screen, rendered
+// with the same SGR formatting clients see, and the cursor position. With
+// history it also includes the output history as plain text.
+func (s *Session) ScreenInfo(history bool) *message.ScreenInfo {
+	s.VT.Mu.Lock()
+	defer s.VT.Mu.Unlock()
+
+	info := &message.ScreenInfo{Rows: s.VT.ChildRows, Cols: s.VT.Cols}
+	switch {
+	case !history:
+	case s.VT.Scrollback != nil:
+		info.Text = virtualterminal.PlainText(s.VT.Scrollback)
+	case s.VT.Vt != nil:
+		info.Text = virtualterminal.PlainText(s.VT.Vt)
+	}
+	if s.VT.Vt != nil {
+		info.Plain = virtualterminal.PlainText(s.VT.Vt)
+		snap := virtualterminal.SnapshotOf(s.VT.Vt)
+		info.CursorRow, info.CursorCol = snap.CursorRow, snap.CursorCol
+	}
+	if s.VT.Vt == nil || s.Client == nil {
+		return info
+	}
+	var buf bytes.Buffer
+	for row := 0; row < s.VT.ChildRows; row++ {
+		if row > 0 {
+			buf.WriteByte('\n')
+		}
+		s.Client.RenderLineFrom(&buf, s.VT.Vt, row)
+	}
+	info.ANSI = buf.String()
+	return info
+}
+
+// RenderMetrics sums render counters across all connected clients.
+func (s *Session) RenderMetrics() *message.RenderMetrics {
+	s.VT.Mu.Lock()
+	defer s.VT.Mu.Unlock()
+
+	var total client.RenderStats
+	n := 0
+	s.ForEachClient(func(cl *client.Client) {
+		total = total.Add(cl.Stats)
+		n++
+	})
+	return &message.RenderMetrics{
+		Clients:      n,
+		Frames:       total.Frames,
+		BytesWritten: total.BytesWritten,
+		AvgRenderMs:  float64(total.AvgRenderTime()) / float64(time.Millisecond),
+	}
+}
+
+// noteChildStarted records the start of a child run. Every start after the
+// first counts as a restart.
+func (s *Session) noteChildStarted() {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+	if !s.runStart.IsZero() {
+		s.restartCount++
+	}
+	s.runStart = time.Now()
+	s.runEnd = time.Time{}
+}
+
+// noteChildExited records the end of the current child run, freezing its
+// uptime, and signals exitNotify.
+func (s *Session) noteChildExited() {
+	s.runMu.Lock()
+	s.runEnd = time.Now()
+	s.runMu.Unlock()
+	select {
+	case s.exitNotify <- struct{}{}:
+	default:
+	}
+}
+
+// RunStats returns when the current (or last) child run started, how long
+// it has been up (final uptime if it has exited), and how many times the
+// child has been restarted.
+func (s *Session) RunStats() (startedAt time.Time, uptime time.Duration, restarts int) {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+	if s.runStart.IsZero() {
+		return time.Time{}, 0, s.restartCount
+	}
+	end := s.runEnd
+	if end.IsZero() {
+		end = time.Now()
+	}
+	return s.runStart, end.Sub(s.runStart), s.restartCount
+}
+
 // initVT creates and initializes the VT with default dimensions for daemon mode.
 func (s *Session) initVT(rows, cols int) {
 	s.VT = &virtualterminal.VT{}
 	s.VT.Rows = rows
 	s.VT.Cols = cols
+	s.VT.IdleThreshold = s.IdleThreshold
+	if virtualterminal.ClipboardEnabled() {
+		s.VT.OnClipboard = s.forwardClipboard
+	}
+}
+
+// forwardClipboard writes an OSC 52 clipboard sequence from the child to
+// every client, so it reaches the user's real terminal. Called with VT.Mu
+// held.
+func (s *Session) forwardClipboard(seq []byte) {
+	s.ForEachClient(func(cl *client.Client) {
+		cl.Output.Write(seq)
+	})
+}
+
+// markPrompt records a prompt boundary in the scrollback before a queued
+// message is typed into the child. Raw messages (e.g. permission answers)
+// are not prompts.
+func (s *Session) markPrompt(msg *message.Message) {
+	if msg.Raw {
+		return
+	}
+	s.VT.Mu.Lock()
+	defer s.VT.Mu.Unlock()
+	s.VT.MarkPrompt()
+}
+
+// Default daemon PTY geometry, used until the first client attaches.
+const (
+	DefaultDaemonRows = 24
+	DefaultDaemonCols = 80
+)
+
+// ValidateDaemonSize checks an initial daemon PTY size. Zero selects the
+// default for that dimension; rows must leave at least one child row below
+// the overlay's reserved rows.
+func ValidateDaemonSize(rows, cols int) error {
+	if rows < 0 || (rows > 0 && rows <= client.BaseReservedRows) {
+		return fmt.Errorf("rows must be greater than %d (the rows reserved for the input and status bars)", client.BaseReservedRows)
+	}
+	if cols < 0 {
+		return fmt.Errorf("cols must not be negative")
+	}
+	return nil
+}
+
+// initDaemonVT initializes the VT with the daemon's initial dimensions and marks
+// it as starting. It is safe to call more than once; the daemon calls it
+// before accepting connections so clients can attach while the child is
+// still being launched.
+func (s *Session) initDaemonVT() {
+	if s.VT != nil {
+		return
+	}
+	rows, cols := s.InitialRows, s.InitialCols
+	if rows <= 0 {
+		rows = DefaultDaemonRows
+	}
+	if cols <= 0 {
+		cols = DefaultDaemonCols
+	}
+	s.initVT(rows, cols)
+	s.VT.ChildRows = s.VT.Rows - client.BaseReservedRows
+	s.VT.Vt = midterm.NewTerminal(s.VT.ChildRows, s.VT.Cols)
+	s.VT.Scrollback = midterm.NewTerminal(s.VT.ChildRows, s.VT.Cols)
+	s.VT.Scrollback.AutoResizeY = true
+	s.VT.Scrollback.AppendOnly = true
+	s.VT.LastOut = time.Now()
+	s.VT.Output = io.Discard
+	s.VT.Starting = true
 }
 
 // childArgs returns the command args, prepending any agent-type-specific args
@@ -133,24 +422,29 @@ func (s *Session) childArgs() []string {
 	} else {
 		args = s.Args
 	}
-	if s.SystemPrompt != "" {
-		args = append(args, "--system-prompt", s.SystemPrompt)
-	}
-	if s.Instructions != "" {
-		args = append(args, "--append-system-prompt", s.Instructions)
-	}
-	if s.Model != "" {
-		args = append(args, "--model", s.Model)
-	}
-	if s.PermissionMode != "" {
-		args = append(args, "--permission-mode", s.PermissionMode)
-	}
-	if len(s.AllowedTools) > 0 {
-		args = append(args, "--allowedTools", strings.Join(s.AllowedTools, ","))
-	}
-	if len(s.DisallowedTools) > 0 {
-		args = append(args, "--disallowedTools", strings.Join(s.DisallowedTools, ","))
+	// Role settings only become flags for agents that understand them;
+	// generic commands get instructions as a queued message instead.
+	if s.Agent.SupportsRoleFlags() {
+		if s.SystemPrompt != "" {
+			args = append(args, "--system-prompt", s.SystemPrompt)
+		}
+		if s.Instructions != "" {
+			args = append(args, "--append-system-prompt", s.Instructions)
+		}
+		if s.Model != "" {
+			args = append(args, "--model", s.Model)
+		}
+		if s.PermissionMode != "" {
+			args = append(args, "--permission-mode", s.PermissionMode)
+		}
+		if len(s.AllowedTools) > 0 {
+			args = append(args, "--allowedTools", strings.Join(s.AllowedTools, ","))
+		}
+		if len(s.DisallowedTools) > 0 {
+			args = append(args, "--disallowedTools", strings.Join(s.DisallowedTools, ","))
+		}
 	}
+	args = append(args, s.ExtraArgs...)
 	return args
 }
 
@@ -161,7 +455,26 @@ func (s *Session) NewClient() *client.Client {
 		Output:    io.Discard, // overridden by caller (attach sets frameWriter, interactive sets os.Stdout)
 		AgentName: s.Name,
 	}
+	s.clientSeq++
+	cl.Label = fmt.Sprintf("client %d", s.clientSeq)
 	cl.InitClient()
+	cl.ConfirmQuit = !s.NoConfirmQuit
+	cl.PassthroughDisabled = s.NoPassthrough
+	if s.MaxInputLen > 0 {
+		cl.MaxInputLen = s.MaxInputLen
+	}
+	cl.SubmitNewline = s.SubmitNewline
+	cl.SubmitDelay = s.SubmitDelay
+	cl.ModifierEnter = s.ModifierEnter
+	cl.Placeholder = s.InputPlaceholder
+	cl.DurationPrecision = s.DurationPrecision
+	cl.ScrollOnOutput = s.ScrollOnOutput
+	cl.ThemeFile = filepath.Join(config.ConfigDir(), "theme.yaml")
+	// The terminal's own theme and background are applied once it is
+	// known: on attach, or when the interactive terminal is set up.
+	if err := cl.LoadTheme("", true); err != nil {
+		log.Printf("warning: %v; using the default theme", err)
+	}
 
 	// Wire lifecycle callbacks.
 	cl.OnRelaunch = func() {
@@ -171,7 +484,11 @@ func (s *Session) NewClient() *client.Client {
 		}
 	}
 	cl.OnQuit = func() {
+		s.endAttaches("quit")
 		s.Quit = true
+		if !s.VT.ChildExited && !s.VT.ChildHung {
+			go s.shutdown()
+		}
 		select {
 		case s.quitCh <- struct{}{}:
 		default:
@@ -188,6 +505,9 @@ func (s *Session) NewClient() *client.Client {
 
 	// Passthrough locking callbacks.
 	cl.TryPassthrough = func() bool {
+		if s.NoPassthrough || !s.claimControl(cl) {
+			return false
+		}
 		if s.PassthroughOwner != nil && s.PassthroughOwner != cl {
 			return false // locked by another client
 		}
@@ -201,7 +521,10 @@ func (s *Session) NewClient() *client.Client {
 			s.Queue.Unpause()
 		}
 	}
-	cl.TakePassthrough = func() {
+	cl.TakePassthrough = func() bool {
+		if s.NoPassthrough || !s.claimControl(cl) {
+			return false
+		}
 		prev := s.PassthroughOwner
 		if prev != nil && prev != cl {
 			// Kick the previous owner back to default mode.
@@ -210,20 +533,39 @@ func (s *Session) NewClient() *client.Client {
 		}
 		s.PassthroughOwner = cl
 		s.Queue.Pause()
+		return true
 	}
 	cl.IsPassthroughLocked = func() bool {
 		return s.PassthroughOwner != nil && s.PassthroughOwner != cl
 	}
+	cl.ClaimControl = func() bool {
+		return s.claimControl(cl)
+	}
+	cl.RequestControl = func() {
+		s.requestControl(cl, time.Now())
+	}
+	cl.GrantControl = func() bool {
+		return s.grantControl(cl)
+	}
+	cl.Control = func() client.ControlStatus {
+		return s.controlStatus(cl, time.Now())
+	}
 	cl.QueueStatus = func() (int, bool) {
 		return s.Queue.PendingCount(), s.Queue.IsPaused()
 	}
+	cl.QueueSummary = func() message.PendingSummary {
+		return s.Queue.Summary(1)
+	}
+	cl.PassthroughCountdown = func() (time.Duration, bool) {
+		return s.passthroughIdleRemaining(time.Now())
+	}
 	cl.OtelMetrics = func() (int64, int64, float64, bool, int) {
 		m := s.Agent.Metrics()
 		return m.InputTokens, m.OutputTokens, m.TotalCostUSD, m.EventsReceived, s.Agent.OtelPort()
 	}
 	cl.AgentState = func() (string, string, string) {
 		st, sub := s.State()
-		return st.String(), sub.String(), virtualterminal.FormatIdleDuration(s.StateDuration())
+		return st.String(), sub.String(), virtualterminal.FormatDuration(s.StateDuration(), s.DurationPrecision)
 	}
 	cl.HookState = func() string {
 		if hc := s.Agent.HookCollector(); hc != nil {
@@ -234,12 +576,25 @@ func (s *Session) NewClient() *client.Client {
 	cl.OnInterrupt = func() {
 		s.Agent.NoteInterrupt()
 	}
-	cl.OnSubmit = func(text string, pri message.Priority) {
-		s.SubmitInput(text, pri)
+	cl.OnSubmit = s.SubmitInput
+	cl.OnSendFile = s.SendFile
+	cl.OnUserInput = func(text string, pri message.Priority) {
+		s.Agent.ActivityLog().UserInput(cl.Label, pri.String(), len(text))
 	}
 	return cl
 }
 
+// endAttaches tells every attached client that the session is ending on
+// purpose, so clients attached with --reconnect exit instead of retrying.
+// Called with VT.Mu held.
+func (s *Session) endAttaches(reason string) {
+	s.ForEachClient(func(cl *client.Client) {
+		if cl.OnEnd != nil {
+			cl.OnEnd(reason)
+		}
+	})
+}
+
 // AddClient adds a client to the session's client list.
 func (s *Session) AddClient(cl *client.Client) {
 	s.clientsMu.Lock()
@@ -277,28 +632,58 @@ func (s *Session) pipeOutputCallback() func() {
 	return func() {
 		// NoteOutput for the session (only need to call once).
 		s.NoteOutput()
+		if s.ReadyPattern != nil {
+			s.Agent.NoteReady(s.screenMatchesReady())
+		}
 		s.ForEachClient(func(cl *client.Client) {
-			if !cl.IsScrollMode() {
-				cl.RenderScreen()
-				cl.RenderBar()
+			if cl.IsScrollMode() {
+				cl.FollowOutput()
+				return
 			}
+			cl.RenderScreen()
+			cl.RenderBar()
 		})
 	}
 }
 
+// screenMatchesReady reports whether the visible child screen matches
+// ReadyPattern. Rows are joined with newlines and trailing blanks trimmed,
+// so (?m)^...$ anchors work per line. Called with VT.Mu held.
+func (s *Session) screenMatchesReady() bool {
+	vt := s.VT.Vt
+	start := vt.Cursor.Y - s.VT.ChildRows + 1
+	if start < 0 {
+		start = 0
+	}
+	var buf strings.Builder
+	for row := start; row <= vt.Cursor.Y && row < len(vt.Content); row++ {
+		buf.WriteString(strings.TrimRight(string(vt.Content[row]), " "))
+		buf.WriteByte('\n')
+	}
+	return s.ReadyPattern.MatchString(buf.String())
+}
+
+// activityLogRotation returns the activity log rotation limits, filling
+// unset fields from activitylog.DefaultRotation.
+func (s *Session) activityLogRotation() activitylog.Rotation {
+	r := s.ActivityLogRotation
+	if r.MaxSize == 0 {
+		r.MaxSize = activitylog.DefaultRotation.MaxSize
+	}
+	if r.MaxAge == 0 {
+		r.MaxAge = activitylog.DefaultRotation.MaxAge
+	}
+	if r.MaxFiles == 0 {
+		r.MaxFiles = activitylog.DefaultRotation.MaxFiles
+	}
+	return r
+}
+
 // RunDaemon runs the session in daemon mode: creates VT, client, PTY,
 // starts collectors, socket listener, and manages the child process lifecycle.
 // Blocks until the child exits and the user quits.
 func (s *Session) RunDaemon() error {
-	// Initialize VT with default daemon dimensions.
-	s.initVT(24, 80)
-	s.VT.ChildRows = s.VT.Rows - 2 // default ReservedRows
-	s.VT.Vt = midterm.NewTerminal(s.VT.ChildRows, s.VT.Cols)
-	s.VT.Scrollback = midterm.NewTerminal(s.VT.ChildRows, s.VT.Cols)
-	s.VT.Scrollback.AutoResizeY = true
-	s.VT.Scrollback.AppendOnly = true
-	s.VT.LastOut = time.Now()
-	s.VT.Output = io.Discard
+	s.initDaemonVT()
 
 	// Initialize client and wire callbacks.
 	s.Client = s.NewClient()
@@ -316,10 +701,12 @@ func (s *Session) RunDaemon() error {
 	// Set up activity logger and raw OTEL log files.
 	logDir := filepath.Join(os.Getenv("HOME"), ".h2", "logs")
 	logPath := filepath.Join(logDir, "session-activity.jsonl")
-	s.Agent.SetActivityLog(activitylog.New(true, logPath, s.Name, s.SessionID))
+	s.Agent.SetActivityLog(activitylog.NewWithRotation(true, logPath, s.Name, s.SessionID, s.activityLogRotation()))
 	s.Agent.SetOtelLogFiles(logDir)
 
 	// Start collectors (OTEL, hooks) and Agent watchState goroutine.
+	s.Agent.SetHooksDisabled(s.NoHooks)
+	s.Agent.SetIdleThreshold(s.IdleThreshold)
 	if err := s.Agent.StartCollectors(); err != nil {
 		return fmt.Errorf("start collectors: %w", err)
 	}
@@ -337,27 +724,49 @@ func (s *Session) RunDaemon() error {
 	if s.ClaudeConfigDir != "" {
 		s.ExtraEnv["CLAUDE_CONFIG_DIR"] = s.ClaudeConfigDir
 	}
+	for k, v := range s.RoleEnv {
+		s.ExtraEnv[k] = v
+	}
 
-	// Start child in a PTY.
-	if err := s.VT.StartPTY(s.Command, s.childArgs(), s.VT.ChildRows, s.VT.Cols, s.ExtraEnv); err != nil {
+	// Start child in a PTY. Hold the lock so a concurrent attach resize
+	// can't race with the initial size.
+	s.VT.Mu.Lock()
+	err := s.VT.StartPTY(s.Command, s.childArgs(), s.VT.ChildRows, s.VT.Cols, s.ExtraEnv)
+	if err == nil {
+		// Don't forward requests to stdout in daemon mode - there's no terminal.
+		s.VT.Vt.ForwardResponses = s.VT.Ptm
+	}
+	s.VT.Mu.Unlock()
+	if err != nil {
 		return err
 	}
-	// Don't forward requests to stdout in daemon mode - there's no terminal.
-	s.VT.Vt.ForwardResponses = s.VT.Ptm
+	s.noteChildStarted()
+	s.writeLifecycleState()
 
 	// Start delivery loop.
 	go s.StartServices()
 
+	// Queue the role's kickoff message for the first idle.
+	if s.StartMessage != "" || s.instructionsMessage() != "" {
+		go s.sendStartMessage()
+	}
+
 	// Launch heartbeat nudge goroutine if configured.
 	if s.HeartbeatIdleTimeout > 0 {
 		go RunHeartbeat(HeartbeatConfig{
-			IdleTimeout: s.HeartbeatIdleTimeout,
-			Message:     s.HeartbeatMessage,
-			Condition:   s.HeartbeatCondition,
-			Agent:       s.Agent,
-			Queue:       s.Queue,
-			AgentName:   s.AgentName,
-			Stop:        s.stopCh,
+			IdleTimeout:      s.HeartbeatIdleTimeout,
+			Message:          s.HeartbeatMessage,
+			Condition:        s.HeartbeatCondition,
+			ConditionTimeout: s.HeartbeatConditionTimeout,
+			Priority:         s.HeartbeatPriority,
+			EscalateAfter:    s.HeartbeatEscalateAfter,
+			EscalateMessage:  s.HeartbeatEscalateMessage,
+			EscalateCommand:  s.HeartbeatEscalateCommand,
+			IdleThreshold:    s.IdleThreshold,
+			Agent:            s.Agent,
+			Queue:            s.Queue,
+			AgentName:        s.AgentName,
+			Stop:             s.stopCh,
 		})
 	}
 
@@ -392,10 +801,7 @@ func (s *Session) RunInteractive() error {
 	s.Client.TermCols = cols
 	s.AddClient(s.Client)
 
-	minRows := 3
-	if s.Client.DebugKeys {
-		minRows = 4
-	}
+	minRows := s.Client.ReservedRows() + 1
 	if rows < minRows {
 		return fmt.Errorf("terminal too small (need at least %d rows, have %d)", minRows, rows)
 	}
@@ -422,10 +828,12 @@ func (s *Session) RunInteractive() error {
 	// Set up activity logger and raw OTEL log files.
 	logDir := filepath.Join(os.Getenv("HOME"), ".h2", "logs")
 	logPath := filepath.Join(logDir, "session-activity.jsonl")
-	s.Agent.SetActivityLog(activitylog.New(true, logPath, s.Name, s.SessionID))
+	s.Agent.SetActivityLog(activitylog.NewWithRotation(true, logPath, s.Name, s.SessionID, s.activityLogRotation()))
 	s.Agent.SetOtelLogFiles(logDir)
 
 	// Start collectors (OTEL, hooks) and Agent watchState goroutine.
+	s.Agent.SetHooksDisabled(s.NoHooks)
+	s.Agent.SetIdleThreshold(s.IdleThreshold)
 	if err := s.Agent.StartCollectors(); err != nil {
 		return fmt.Errorf("start collectors: %w", err)
 	}
@@ -434,11 +842,16 @@ func (s *Session) RunInteractive() error {
 		s.ExtraEnv = make(map[string]string)
 	}
 	s.ExtraEnv["H2_ACTOR"] = s.Name
+	for k, v := range s.RoleEnv {
+		s.ExtraEnv[k] = v
+	}
 
 	// Start child in a PTY.
 	if err := s.VT.StartPTY(s.Command, s.childArgs(), s.VT.ChildRows, cols, s.ExtraEnv); err != nil {
 		return err
 	}
+	s.noteChildStarted()
+	s.writeLifecycleState()
 	s.VT.Vt.ForwardRequests = os.Stdout
 	s.VT.Vt.ForwardResponses = s.VT.Ptm
 
@@ -465,6 +878,7 @@ func (s *Session) RunInteractive() error {
 func (s *Session) lifecycleLoop(stopStatus chan struct{}, interactive bool) error {
 	for {
 		err := s.VT.Cmd.Wait()
+		s.noteChildExited()
 
 		// If the user explicitly chose Quit, exit immediately.
 		if s.Quit {
@@ -494,6 +908,8 @@ func (s *Session) lifecycleLoop(stopStatus chan struct{}, interactive bool) erro
 				s.Stop()
 				return err
 			}
+			s.noteChildStarted()
+			s.writeLifecycleState()
 			s.VT.Vt = midterm.NewTerminal(s.VT.ChildRows, s.VT.Cols)
 			if interactive {
 				s.VT.Vt.ForwardRequests = os.Stdout
@@ -507,6 +923,9 @@ func (s *Session) lifecycleLoop(stopStatus chan struct{}, interactive bool) erro
 			s.VT.ChildExited = false
 			s.VT.ChildHung = false
 			s.VT.ExitError = nil
+			s.VT.ReadErr = nil
+			s.VT.AgentStatus = ""
+			s.VT.PromptMarks = nil
 			s.VT.LastOut = time.Now()
 			s.ForEachClient(func(cl *client.Client) {
 				cl.ScrollOffset = 0
@@ -537,17 +956,54 @@ func (s *Session) TickStatus(stop <-chan struct{}) {
 	for {
 		select {
 		case <-ticker.C:
-			s.VT.Mu.Lock()
-			s.ForEachClient(func(cl *client.Client) {
-				cl.RenderBar()
-			})
-			s.VT.Mu.Unlock()
+			s.statusTick()
 		case <-stop:
 			return
 		}
 	}
 }
 
+// statusTick runs one status update: releases an idle passthrough lock,
+// re-renders every client's bar, and refreshes the status file.
+func (s *Session) statusTick() {
+	s.VT.Mu.Lock()
+	s.releaseIdlePassthrough(time.Now())
+	s.ForEachClient(func(cl *client.Client) {
+		cl.TickSpinner()
+		cl.RenderBar()
+	})
+	line := s.statusLine()
+	s.VT.Mu.Unlock()
+	s.writeStatusFile(line)
+}
+
+// passthroughIdleRemaining returns how long the current passthrough owner can
+// stay idle before its lock is released. ok is false when no client holds
+// passthrough or no idle timeout is configured. Must be called with VT.Mu held.
+func (s *Session) passthroughIdleRemaining(now time.Time) (time.Duration, bool) {
+	owner := s.PassthroughOwner
+	if owner == nil || s.PassthroughIdleTimeout <= 0 {
+		return 0, false
+	}
+	left := s.PassthroughIdleTimeout - now.Sub(owner.PassthroughActiveAt)
+	if left < 0 {
+		left = 0
+	}
+	return left, true
+}
+
+// releaseIdlePassthrough kicks the passthrough owner back to default mode if
+// it has held the lock idle past PassthroughIdleTimeout, which unpauses the
+// queue. Must be called with VT.Mu held.
+func (s *Session) releaseIdlePassthrough(now time.Time) {
+	left, ok := s.passthroughIdleRemaining(now)
+	if !ok || left > 0 {
+		return
+	}
+	// Leaving passthrough fires OnModeChange, which drops the lock.
+	s.PassthroughOwner.ReleaseIdlePassthrough()
+}
+
 // --- Delegators to Agent ---
 
 // StartOtelCollector delegates to the Agent.
@@ -606,8 +1062,10 @@ func (s *Session) NoteExit() {
 	s.Agent.SetExited()
 }
 
-// SubmitInput enqueues user-typed input for priority-aware delivery.
-func (s *Session) SubmitInput(text string, priority message.Priority) {
+// SubmitInput enqueues user-typed input for priority-aware delivery. It
+// returns a *message.DuplicateError if the dedupe window dropped the input,
+// or message.ErrQueueClosed during shutdown.
+func (s *Session) SubmitInput(text string, priority message.Priority) error {
 	msg := &message.Message{
 		ID:        uuid.New().String(),
 		From:      "user",
@@ -616,15 +1074,23 @@ func (s *Session) SubmitInput(text string, priority message.Priority) {
 		Status:    message.StatusQueued,
 		CreatedAt: time.Now(),
 	}
-	s.Queue.Enqueue(msg)
+	return s.Queue.Enqueue(msg)
 }
 
-// StartServices launches the delivery goroutine. Blocks until Stop is called.
+// StartServices launches the delivery goroutine. Blocks until Stop is called
+// or a shutdown stops delivery.
 func (s *Session) StartServices() {
+	defer close(s.deliveryDone)
 	message.RunDelivery(message.DeliveryConfig{
-		Queue:     s.Queue,
-		AgentName: s.AgentName,
-		PtyWriter: s.PtyWriter(),
+		Queue:       s.Queue,
+		AgentName:   s.AgentName,
+		PtyWriter:   s.PtyWriter(),
+		SubmitBytes: s.SubmitNewline.Bytes(),
+		SubmitDelay: s.SubmitDelay,
+		OnMessage:   s.onMessageDelivered(s.messageHook()),
+		OnPrompt:    s.markPrompt,
+		StrictIdle:  true,
+		MentionAttachments: s.Agent.AgentType() != nil && s.Agent.AgentType().Name() == "claude",
 		IsIdle: func() bool {
 			st, _ := s.Agent.State()
 			return st == agent.StateIdle
@@ -640,7 +1106,7 @@ func (s *Session) StartServices() {
 			s.Agent.NoteInterrupt()
 		},
 		OnDeliver: s.OnDeliver,
-		Stop:      s.stopCh,
+		Stop:      s.deliveryStop,
 	})
 }
 
@@ -652,12 +1118,15 @@ func (s *Session) Stop() {
 	default:
 		close(s.stopCh)
 	}
+	s.stopDelivery()
 
 	// Gather session summary data before stopping the agent (which closes files).
 	summary := s.buildSessionSummary()
 	s.Agent.ActivityLog().SessionSummary(summary)
 
 	s.Agent.Stop()
+	s.removeLifecycleState()
+	s.removeStatusFile()
 }
 
 // buildSessionSummary collects metrics from all available sources.
@@ -1,8 +1,14 @@
 package session
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -570,21 +576,36 @@ func TestChildArgs_InstructionsWithoutSessionID(t *testing.T) {
 func TestChildArgs_InstructionsNonClaude(t *testing.T) {
 	s := New("test", "bash", []string{"-c", "echo hi"})
 	s.Instructions = "Some instructions"
+	s.Model = "opus"
 
 	args := s.childArgs()
 
-	// Non-claude has no prepend args, but should still get --append-system-prompt
-	if len(args) != 4 {
-		t.Fatalf("expected 4 args, got %d: %v", len(args), args)
+	// Non-claude commands don't understand claude's flags, so only the
+	// original args are passed; instructions go out as a queued message.
+	if len(args) != 2 {
+		t.Fatalf("expected 2 args, got %d: %v", len(args), args)
 	}
 	if args[0] != "-c" || args[1] != "echo hi" {
-		t.Fatalf("expected original args first, got %v", args[:2])
+		t.Fatalf("expected original args, got %v", args)
 	}
-	if args[2] != "--append-system-prompt" {
-		t.Fatalf("expected '--append-system-prompt', got %q", args[2])
+	if got := s.instructionsMessage(); got != "Some instructions" {
+		t.Fatalf("instructionsMessage = %q, want %q", got, "Some instructions")
 	}
-	if args[3] != "Some instructions" {
-		t.Fatalf("expected 'Some instructions', got %q", args[3])
+}
+
+func TestInstructionsMessage_ClaudeUsesFlags(t *testing.T) {
+	s := New("test", "claude", nil)
+	s.SystemPrompt = "You are terse."
+	s.Instructions = "Some instructions"
+	if got := s.instructionsMessage(); got != "" {
+		t.Fatalf("claude should take instructions as flags, got message %q", got)
+	}
+
+	g := New("test", "aider", nil)
+	g.SystemPrompt = "You are terse."
+	g.Instructions = "Some instructions"
+	if got, want := g.instructionsMessage(), "You are terse.\n\nSome instructions"; got != want {
+		t.Fatalf("instructionsMessage = %q, want %q", got, want)
 	}
 }
 
@@ -771,3 +792,519 @@ func TestChildArgs_AllFieldsCombined(t *testing.T) {
 		}
 	}
 }
+
+func TestChildArgs_ExtraArgsAppendedInOrder(t *testing.T) {
+	s := New("test", "claude", nil)
+	s.SessionID = "test-uuid"
+	s.Model = "claude-opus-4-6"
+	s.ExtraArgs = []string{"--dangerously-skip-permissions", "--mcp-config", "/tmp/mcp.json"}
+
+	args := s.childArgs()
+
+	expected := []string{
+		"--session-id", "test-uuid",
+		"--model", "claude-opus-4-6",
+		"--dangerously-skip-permissions", "--mcp-config", "/tmp/mcp.json",
+	}
+	if strings.Join(args, " ") != strings.Join(expected, " ") {
+		t.Fatalf("args = %v, want %v", args, expected)
+	}
+}
+
+func TestRenderMetrics_SumsClients(t *testing.T) {
+	s := newTestSession()
+	for i := 0; i < 2; i++ {
+		cl := s.NewClient()
+		cl.Output = io.Discard
+		s.AddClient(cl)
+		s.VT.Mu.Lock()
+		cl.RenderScreen()
+		s.VT.Mu.Unlock()
+	}
+
+	m := s.RenderMetrics()
+	if m.Clients != 2 {
+		t.Fatalf("Clients = %d, want 2", m.Clients)
+	}
+	if m.Frames != 2 {
+		t.Fatalf("Frames = %d, want 2", m.Frames)
+	}
+	if m.BytesWritten == 0 {
+		t.Fatal("expected BytesWritten > 0")
+	}
+}
+
+func TestPassthrough_DisabledNeverAcquiresLock(t *testing.T) {
+	s := newTestSession()
+	s.NoPassthrough = true
+	cl := s.NewClient()
+	cl.Mode = client.ModeMenu
+
+	cl.HandleMenuBytes([]byte{'p'}, 0, 1)
+	if cl.Mode == client.ModePassthrough {
+		t.Fatal("p should not enter passthrough when disabled")
+	}
+	cl.HandleMenuBytes([]byte{'t'}, 0, 1)
+	if cl.Mode == client.ModePassthrough {
+		t.Fatal("t should not enter passthrough when disabled")
+	}
+	if s.PassthroughOwner != nil {
+		t.Fatal("passthrough lock should never be acquired")
+	}
+	if s.Queue.IsPaused() {
+		t.Fatal("queue should not be paused")
+	}
+}
+
+func TestPassthrough_IdleOwnerReleased(t *testing.T) {
+	s := newTestSession()
+	s.PassthroughIdleTimeout = time.Minute
+	cl := s.NewClient()
+	cl.Mode = client.ModeMenu
+	cl.HandleMenuBytes([]byte{'p'}, 0, 1)
+	if s.PassthroughOwner != cl || !s.Queue.IsPaused() {
+		t.Fatal("expected client to hold passthrough with the queue paused")
+	}
+
+	if left, ok := cl.PassthroughCountdown(); !ok || left <= 0 || left > time.Minute {
+		t.Fatalf("countdown = %v, %v; want (0, 1m], true", left, ok)
+	}
+
+	s.releaseIdlePassthrough(cl.PassthroughActiveAt.Add(2 * time.Minute))
+
+	if s.PassthroughOwner != nil {
+		t.Fatal("idle owner should lose the passthrough lock")
+	}
+	if s.Queue.IsPaused() {
+		t.Fatal("queue should resume after idle release")
+	}
+	if cl.Mode != client.ModeNormal {
+		t.Fatalf("idle owner should drop to ModeNormal, got %v", cl.Mode)
+	}
+	if _, ok := cl.PassthroughCountdown(); ok {
+		t.Fatal("countdown should disappear once nobody holds passthrough")
+	}
+}
+
+func TestPassthrough_ActiveOwnerKeepsLock(t *testing.T) {
+	s := newTestSession()
+	s.PassthroughIdleTimeout = time.Minute
+	cl := s.NewClient()
+	cl.Mode = client.ModeMenu
+	cl.HandleMenuBytes([]byte{'p'}, 0, 1)
+
+	// A keystroke long after entry resets the idle clock.
+	cl.PassthroughActiveAt = time.Now().Add(-10 * time.Minute)
+	cl.HandlePassthroughBytes([]byte{'a'}, 0, 1)
+	s.releaseIdlePassthrough(time.Now().Add(30 * time.Second))
+
+	if s.PassthroughOwner != cl {
+		t.Fatal("active owner should keep the passthrough lock")
+	}
+	if !s.Queue.IsPaused() {
+		t.Fatal("queue should stay paused while the owner is active")
+	}
+	if cl.Mode != client.ModePassthrough {
+		t.Fatalf("active owner should stay in passthrough, got %v", cl.Mode)
+	}
+}
+
+func TestPassthrough_NoIdleTimeoutNeverReleases(t *testing.T) {
+	s := newTestSession()
+	cl := s.NewClient()
+	cl.Mode = client.ModeMenu
+	cl.HandleMenuBytes([]byte{'p'}, 0, 1)
+
+	s.releaseIdlePassthrough(time.Now().Add(24 * time.Hour))
+	if s.PassthroughOwner != cl {
+		t.Fatal("lock should be held indefinitely without an idle timeout")
+	}
+	if _, ok := cl.PassthroughCountdown(); ok {
+		t.Fatal("no countdown expected without an idle timeout")
+	}
+}
+
+func TestPassthrough_DisabledCallbacksAreNoops(t *testing.T) {
+	s := newTestSession()
+	s.NoPassthrough = true
+	cl := s.NewClient()
+
+	if cl.TryPassthrough() {
+		t.Fatal("TryPassthrough should fail when passthrough is disabled")
+	}
+	cl.TakePassthrough()
+	if s.PassthroughOwner != nil {
+		t.Fatal("TakePassthrough should not acquire the lock when disabled")
+	}
+}
+
+func TestPassthrough_MenuLabelShowsDisabled(t *testing.T) {
+	s := newTestSession()
+	s.NoPassthrough = true
+	cl := s.NewClient()
+	cl.Mode = client.ModeMenu
+
+	if label := cl.MenuLabel(); !contains(label, "passthrough disabled") {
+		t.Fatalf("expected disabled note in menu label, got %q", label)
+	}
+}
+
+func TestRunStats_RestartCountIncrements(t *testing.T) {
+	s := newTestSession()
+
+	if started, _, restarts := s.RunStats(); !started.IsZero() || restarts != 0 {
+		t.Fatalf("before start: started=%v restarts=%d, want zero values", started, restarts)
+	}
+
+	s.noteChildStarted()
+	if _, _, restarts := s.RunStats(); restarts != 0 {
+		t.Fatalf("first start: restarts = %d, want 0", restarts)
+	}
+	for i := 1; i <= 3; i++ {
+		s.noteChildExited()
+		s.noteChildStarted()
+		if _, _, restarts := s.RunStats(); restarts != i {
+			t.Fatalf("after restart %d: restarts = %d", i, restarts)
+		}
+	}
+
+	info := (&Daemon{Session: s, StartTime: time.Now()}).AgentInfo()
+	if info.RestartCount != 3 {
+		t.Errorf("AgentInfo.RestartCount = %d, want 3", info.RestartCount)
+	}
+	if info.StartedAt == "" {
+		t.Error("AgentInfo.StartedAt should be set once the child has started")
+	}
+}
+
+func TestRunStats_UptimeReflectsCurrentRun(t *testing.T) {
+	s := newTestSession()
+
+	s.noteChildStarted()
+	s.runMu.Lock()
+	s.runStart = time.Now().Add(-time.Hour) // long first run
+	s.runMu.Unlock()
+	s.noteChildExited()
+
+	// Exited: uptime is frozen at the final value.
+	_, final, _ := s.RunStats()
+	time.Sleep(20 * time.Millisecond)
+	if _, again, _ := s.RunStats(); again != final {
+		t.Fatalf("exited uptime changed from %s to %s", final, again)
+	}
+	if final < time.Hour {
+		t.Fatalf("final uptime = %s, want >= 1h", final)
+	}
+
+	// Restart: uptime counts from the new run, not the first one.
+	s.noteChildStarted()
+	started, uptime, _ := s.RunStats()
+	if uptime >= time.Minute {
+		t.Fatalf("uptime after restart = %s, want current run only", uptime)
+	}
+	if time.Since(started) > time.Minute {
+		t.Fatalf("started_at = %v, want the restart time", started)
+	}
+}
+
+// feedScreen writes data to the session's VT and runs the output callback,
+// as PipeOutput would.
+func feedScreen(s *Session, data string) {
+	s.VT.Mu.Lock()
+	s.VT.Vt.Write([]byte(data))
+	s.pipeOutputCallback()()
+	s.VT.Mu.Unlock()
+}
+
+func TestReadyPattern_MarksIdleBeforeTimeout(t *testing.T) {
+	// A long idle threshold: only the ready pattern can make us idle quickly.
+	old := collector.IdleThreshold
+	collector.IdleThreshold = time.Minute
+	t.Cleanup(func() { collector.IdleThreshold = old })
+
+	s := newTestSession()
+	defer s.Stop()
+	s.ReadyPattern = regexp.MustCompile(`(?m)^>$`)
+	startWatchState(t, s)
+
+	feedScreen(s, "working on it...\r\n")
+	waitForState(t, s, agent.StateActive, 2*time.Second)
+
+	feedScreen(s, "done\r\n> ")
+	waitForState(t, s, agent.StateIdle, 500*time.Millisecond)
+
+	// New output that scrolls the prompt away makes the agent active again.
+	feedScreen(s, "\033[2J\033[Hthinking...\r\n")
+	waitForState(t, s, agent.StateActive, 500*time.Millisecond)
+}
+
+func TestReadyPattern_UnsetFallsBackToTimeout(t *testing.T) {
+	setFastIdle(t)
+	s := newTestSession()
+	defer s.Stop()
+	startWatchState(t, s)
+
+	feedScreen(s, "> ")
+	waitForState(t, s, agent.StateActive, 2*time.Second)
+	waitForState(t, s, agent.StateIdle, 2*time.Second)
+}
+
+func TestReadyPattern_IgnoredWithHookCollector(t *testing.T) {
+	s := newTestSession()
+	defer s.Stop()
+	s.Agent = agent.New(agent.NewClaudeCodeType())
+	s.ReadyPattern = regexp.MustCompile(`(?m)^>$`)
+	startWatchState(t, s)
+	hc := s.Agent.HookCollector()
+	if hc == nil {
+		t.Fatal("expected the hook collector to be active")
+	}
+
+	hc.ProcessEvent("UserPromptSubmit", json.RawMessage(`{"session_id":"cs"}`))
+	waitForState(t, s, agent.StateActive, 2*time.Second)
+
+	// The prompt is drawn while the hooks still say the agent is working.
+	feedScreen(s, "done\r\n> ")
+	time.Sleep(50 * time.Millisecond)
+	if st, _ := s.Agent.State(); st != agent.StateActive {
+		t.Fatalf("state = %v after ready screen, want hooks to keep it active", st)
+	}
+}
+
+func TestShutdown_DrainDeliversQueuedInOrder(t *testing.T) {
+	setFastIdle(t)
+	s := newTestSession()
+	s.DrainOnQuit = true
+	s.ShutdownGrace = 5 * time.Second
+	s.SubmitDelay = 0
+	defer s.Stop()
+	startWatchState(t, s)
+	waitForState(t, s, agent.StateIdle, 2*time.Second)
+
+	if err := s.VT.StartPTY("cat", nil, 10, 80, nil); err != nil {
+		t.Fatalf("StartPTY: %v", err)
+	}
+	var out strings.Builder
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		buf := make([]byte, 1024)
+		for {
+			n, err := s.VT.Ptm.Read(buf)
+			out.Write(buf[:n])
+			if err != nil {
+				return
+			}
+		}
+	}()
+	waitDone := make(chan error, 1)
+	go func() {
+		err := s.VT.Cmd.Wait()
+		s.noteChildExited()
+		waitDone <- err
+	}()
+
+	// Hold the messages until quit, as a passthrough lock would.
+	s.Queue.Pause()
+	for _, body := range []string{"first", "second", "third"} {
+		s.SubmitInput(body, message.PriorityNormal)
+	}
+	go s.StartServices()
+
+	s.shutdown()
+
+	select {
+	case <-waitDone:
+	case <-time.After(5 * time.Second):
+		t.Fatal("child did not exit after shutdown")
+	}
+	s.VT.Ptm.Close()
+	<-readDone
+
+	got := out.String()
+	i1, i2, i3 := strings.Index(got, "first"), strings.Index(got, "second"), strings.Index(got, "third")
+	if i1 < 0 || i2 < i1 || i3 < i2 {
+		t.Fatalf("expected first, second, third delivered in order, got %q", got)
+	}
+	if s.Queue.PendingCount() != 0 {
+		t.Fatalf("expected queue drained, %d pending", s.Queue.PendingCount())
+	}
+
+	s.SubmitInput("late", message.PriorityNormal)
+	if s.Queue.PendingCount() != 0 {
+		t.Fatal("closed queue should reject new input")
+	}
+}
+
+func TestShutdown_NoDrainSkipsQueuedAndKillsStubbornChild(t *testing.T) {
+	s := newTestSession()
+	s.ShutdownGrace = 200 * time.Millisecond
+
+	// The child ignores SIGTERM, so shutdown must fall back to SIGKILL.
+	if err := s.VT.StartPTY("sh", []string{"-c", "trap '' TERM; sleep 30"}, 10, 80, nil); err != nil {
+		t.Fatalf("StartPTY: %v", err)
+	}
+	defer s.VT.Ptm.Close()
+	go io.Copy(io.Discard, s.VT.Ptm)
+	waitDone := make(chan error, 1)
+	go func() {
+		err := s.VT.Cmd.Wait()
+		s.noteChildExited()
+		waitDone <- err
+	}()
+	time.Sleep(100 * time.Millisecond) // let the trap install
+
+	s.Queue.Pause()
+	s.SubmitInput("queued", message.PriorityNormal)
+	go s.StartServices()
+
+	s.shutdown()
+
+	select {
+	case err := <-waitDone:
+		if err == nil || !strings.Contains(err.Error(), "killed") {
+			t.Fatalf("expected child killed, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("child did not exit after shutdown")
+	}
+	if s.Queue.PendingCount() != 1 {
+		t.Fatalf("without drain the queued message should stay undelivered, %d pending", s.Queue.PendingCount())
+	}
+}
+
+func TestAgentInfo_QueueListsPendingWithoutDequeuing(t *testing.T) {
+	s := newTestSession()
+	s.Queue.Pause()
+	s.SubmitInput("later", message.PriorityIdle)
+	s.SubmitInput(strings.Repeat("x", 200), message.PriorityInterrupt)
+
+	info := (&Daemon{Session: s, StartTime: time.Now()}).AgentInfo()
+	if len(info.Queue) != 2 {
+		t.Fatalf("Queue len = %d, want 2", len(info.Queue))
+	}
+	first := info.Queue[0]
+	if first.Priority != "interrupt" || first.From != "user" {
+		t.Errorf("first queued = %+v, want the interrupt from user", first)
+	}
+	if n := len([]rune(first.Body)); n != message.StatusPreviewLen {
+		t.Errorf("body preview is %d runes, want %d", n, message.StatusPreviewLen)
+	}
+	if info.Queue[1].Body != "later" || info.Queue[1].Age == "" {
+		t.Errorf("second queued = %+v", info.Queue[1])
+	}
+	if s.Queue.PendingCount() != 2 {
+		t.Fatal("status query should not dequeue messages")
+	}
+	if info.QueueByPriority["interrupt"] != 1 || info.QueueByPriority["idle"] != 1 {
+		t.Errorf("QueueByPriority = %v", info.QueueByPriority)
+	}
+}
+
+func TestAgentInfo_QueueListIsCapped(t *testing.T) {
+	s := newTestSession()
+	s.Queue.Pause()
+	for i := 0; i < message.StatusQueueLimit+5; i++ {
+		s.SubmitInput(fmt.Sprintf("msg %d", i), message.PriorityNormal)
+	}
+
+	info := (&Daemon{Session: s, StartTime: time.Now()}).AgentInfo()
+	if len(info.Queue) != message.StatusQueueLimit {
+		t.Errorf("Queue len = %d, want %d", len(info.Queue), message.StatusQueueLimit)
+	}
+	if info.QueueByPriority["normal"] != message.StatusQueueLimit+5 {
+		t.Errorf("QueueByPriority = %v, want the whole queue counted", info.QueueByPriority)
+	}
+}
+
+func TestSubmitInput_DedupeReportsDuplicate(t *testing.T) {
+	s := New("test-agent", "true", nil)
+	s.Queue.SetDedupeWindow(time.Minute)
+
+	if err := s.SubmitInput("hello", message.PriorityIdle); err != nil {
+		t.Fatalf("first SubmitInput: %v", err)
+	}
+	err := s.SubmitInput("hello", message.PriorityIdle)
+	var dup *message.DuplicateError
+	if !errors.As(err, &dup) {
+		t.Fatalf("second SubmitInput: got %v, want DuplicateError", err)
+	}
+	if s.Queue.PendingCount() != 1 {
+		t.Fatalf("PendingCount = %d, want 1", s.Queue.PendingCount())
+	}
+}
+
+// scrolledClient attaches a client to s that renders into out and is
+// scrolled offset lines back from the bottom of the scrollback.
+func scrolledClient(s *Session, out *bytes.Buffer, offset int) *client.Client {
+	cl := s.NewClient()
+	cl.Output = out
+	s.AddClient(cl)
+	if offset > 0 {
+		cl.EnterScrollMode()
+		cl.ScrollUp(offset)
+	}
+	out.Reset()
+	return cl
+}
+
+func TestScroll_ClientsRenderIndependentWindows(t *testing.T) {
+	s := newTestSession()
+	for i := 0; i < 40; i++ {
+		s.VT.Scrollback.Write([]byte(fmt.Sprintf("line %02d\n", i)))
+	}
+
+	var out1, out2, outLive bytes.Buffer
+	cl1 := scrolledClient(s, &out1, 5)
+	cl2 := scrolledClient(s, &out2, 15)
+	scrolledClient(s, &outLive, 0)
+
+	cl1.RenderScreen()
+	cl2.RenderScreen()
+	// 10 child rows ending at scrollback row 40: offset 5 shows rows
+	// 26-35, offset 15 shows rows 16-25.
+	w1, w2 := out1.String(), out2.String()
+	if !strings.Contains(w1, "line 26") || !strings.Contains(w1, "line 35") || strings.Contains(w1, "line 25") {
+		t.Errorf("client 1 (offset 5) rendered the wrong window:\n%q", w1)
+	}
+	if !strings.Contains(w2, "line 16") || !strings.Contains(w2, "line 25") || strings.Contains(w2, "line 26") {
+		t.Errorf("client 2 (offset 15) rendered the wrong window:\n%q", w2)
+	}
+
+	// Scrolling one client redraws only that client.
+	out1.Reset()
+	out2.Reset()
+	outLive.Reset()
+	cl1.ScrollUp(3)
+	if cl1.ScrollOffset != 8 || cl2.ScrollOffset != 15 {
+		t.Fatalf("offsets = %d, %d; want 8, 15", cl1.ScrollOffset, cl2.ScrollOffset)
+	}
+	if out1.Len() == 0 {
+		t.Error("scrolled client should redraw")
+	}
+	if out2.Len() != 0 || outLive.Len() != 0 {
+		t.Errorf("other clients were redrawn: %d, %d bytes", out2.Len(), outLive.Len())
+	}
+}
+
+func TestScroll_RepaintClampsEachClientsOffset(t *testing.T) {
+	s := newTestSession()
+	for i := 0; i < 20; i++ {
+		s.VT.Scrollback.Write([]byte("line\n"))
+	}
+	var out1, out2 bytes.Buffer
+	cl1 := scrolledClient(s, &out1, 2)
+	cl2 := scrolledClient(s, &out2, 11) // the maximum: 20 - 10 + 1
+
+	// Another client grows the child window; each view keeps its own
+	// position within the new bounds.
+	s.VT.Rows, s.VT.ChildRows = 17, 15
+	cl1.Repaint()
+	cl2.Repaint()
+	if cl1.ScrollOffset != 2 {
+		t.Errorf("client 1 offset = %d, want 2", cl1.ScrollOffset)
+	}
+	if cl2.ScrollOffset != 6 {
+		t.Errorf("client 2 offset = %d, want clamped to 6", cl2.ScrollOffset)
+	}
+}
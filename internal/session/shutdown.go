@@ -0,0 +1,63 @@
+package session
+
+import (
+	"syscall"
+	"time"
+)
+
+// DefaultShutdownGrace bounds each phase of a graceful shutdown when the
+// session doesn't set ShutdownGrace.
+const DefaultShutdownGrace = 10 * time.Second
+
+// shutdownGrace returns ShutdownGrace, or DefaultShutdownGrace if unset.
+func (s *Session) shutdownGrace() time.Duration {
+	if s.ShutdownGrace > 0 {
+		return s.ShutdownGrace
+	}
+	return DefaultShutdownGrace
+}
+
+// shutdown stops the child gracefully after the user quits. The queue stops
+// accepting messages, queued messages are delivered first if DrainOnQuit is
+// set, and the message being typed into the child is allowed to finish.
+// Then the child gets SIGTERM, and SIGKILL if it hasn't exited within the
+// grace period. Draining and the final delivery share one grace period.
+func (s *Session) shutdown() {
+	grace := s.shutdownGrace()
+	deadline := time.Now().Add(grace)
+
+	s.Queue.Close()
+	if s.DrainOnQuit {
+		// A passthrough lock pauses the queue; the user is leaving anyway.
+		s.Queue.Unpause()
+		for s.Queue.PendingCount() > 0 && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	s.stopDelivery()
+	select {
+	case <-s.deliveryDone:
+	case <-time.After(time.Until(deadline)):
+	}
+
+	// Drop an exit notification left over from an earlier child run.
+	select {
+	case <-s.exitNotify:
+	default:
+	}
+	if s.VT.Cmd == nil || s.VT.Cmd.Process == nil {
+		return
+	}
+	s.VT.Cmd.Process.Signal(syscall.SIGTERM)
+	select {
+	case <-s.exitNotify:
+	case <-time.After(grace):
+		s.VT.KillChild()
+	}
+}
+
+// stopDelivery stops the delivery loop after the message in flight, if
+// any. Safe to call more than once.
+func (s *Session) stopDelivery() {
+	s.deliveryStopOnce.Do(func() { close(s.deliveryStop) })
+}
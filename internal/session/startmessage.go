@@ -0,0 +1,48 @@
+package session
+
+import (
+	"log"
+
+	"h2/internal/session/message"
+)
+
+// sendStartMessage waits for the agent's first idle after launch and then
+// enqueues the role's on_start_message at StartMessagePriority. It runs once per daemon, so
+// relaunching the child from the exit screen doesn't repeat the kickoff.
+// Agents that can't take instructions as flags get them queued first.
+func (s *Session) sendStartMessage() {
+	if !waitForIdle(s.Agent, s.stopCh) {
+		return
+	}
+	if body := s.instructionsMessage(); body != "" {
+		if _, err := message.PrepareMessage(s.Queue, s.Name, "h2-instructions", body, message.PriorityNormal); err != nil {
+			log.Printf("warning: enqueue instructions: %v", err)
+		}
+	}
+	if s.StartMessage == "" {
+		return
+	}
+	priority := s.StartMessagePriority
+	if priority == 0 {
+		priority = message.PriorityNormal
+	}
+	if _, err := message.PrepareMessage(s.Queue, s.Name, "h2-start", s.StartMessage, priority); err != nil {
+		log.Printf("warning: enqueue start message: %v", err)
+	}
+}
+
+// instructionsMessage returns the system prompt and instructions to deliver
+// as a message, or "" when the agent receives them as CLI flags.
+func (s *Session) instructionsMessage() string {
+	if s.Agent.SupportsRoleFlags() {
+		return ""
+	}
+	switch {
+	case s.SystemPrompt != "" && s.Instructions != "":
+		return s.SystemPrompt + "\n\n" + s.Instructions
+	case s.SystemPrompt != "":
+		return s.SystemPrompt
+	default:
+		return s.Instructions
+	}
+}
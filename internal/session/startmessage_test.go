@@ -0,0 +1,129 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"h2/internal/session/agent"
+	"h2/internal/session/message"
+)
+
+func TestStartMessage_EnqueuedOnceAfterFirstIdle(t *testing.T) {
+	setFastIdle(t)
+	t.Setenv("HOME", t.TempDir())
+	s := New("worker", "true", nil)
+	s.StartMessage = "Review the open tasks and begin"
+	defer s.Stop()
+
+	// Not queued while the agent is still starting up.
+	s.NoteOutput()
+	done := make(chan struct{})
+	go func() {
+		s.sendStartMessage()
+		close(done)
+	}()
+	if n := s.Queue.PendingCount(); n != 0 {
+		t.Fatalf("start message queued before idle (%d pending)", n)
+	}
+
+	startWatchState(t, s)
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("start message not queued after the agent went idle")
+	}
+	if n := s.Queue.PendingCount(); n != 1 {
+		t.Fatalf("pending = %d, want 1", n)
+	}
+
+	// Later idle transitions don't send it again.
+	s.NoteOutput()
+	waitForState(t, s, agent.StateActive, 2*time.Second)
+	waitForState(t, s, agent.StateIdle, 2*time.Second)
+	if n := s.Queue.PendingCount(); n != 1 {
+		t.Fatalf("pending after second idle = %d, want 1", n)
+	}
+
+	msg := s.Queue.Dequeue(true, false)
+	if msg == nil || msg.Body != s.StartMessage || msg.From != "h2-start" || msg.Priority != message.PriorityNormal {
+		t.Fatalf("unexpected start message: %+v", msg)
+	}
+}
+
+func TestStartMessage_ConfiguredPriority(t *testing.T) {
+	setFastIdle(t)
+	t.Setenv("HOME", t.TempDir())
+	s := New("worker", "true", nil)
+	s.StartMessage = "kickoff"
+	s.StartMessagePriority = message.PriorityIdle
+	defer s.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		s.sendStartMessage()
+		close(done)
+	}()
+	startWatchState(t, s)
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("start message not queued after the agent went idle")
+	}
+
+	msg := s.Queue.Dequeue(true, false)
+	if msg == nil || msg.Priority != message.PriorityIdle {
+		t.Fatalf("expected idle-priority start message, got %+v", msg)
+	}
+}
+
+func TestStartMessage_NotSentWhenStoppedFirst(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	s := New("worker", "true", nil)
+	s.StartMessage = "kickoff"
+	s.NoteOutput()
+
+	done := make(chan struct{})
+	go func() {
+		s.sendStartMessage()
+		close(done)
+	}()
+	s.Stop()
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("sendStartMessage did not return after Stop")
+	}
+	if n := s.Queue.PendingCount(); n != 0 {
+		t.Fatalf("pending = %d, want 0", n)
+	}
+}
+
+func TestStartMessage_InstructionsQueuedForGenericAgent(t *testing.T) {
+	setFastIdle(t)
+	t.Setenv("HOME", t.TempDir())
+	s := New("worker", "true", nil)
+	s.Instructions = "You maintain the docs."
+	s.StartMessage = "kickoff"
+	defer s.Stop()
+
+	done := make(chan struct{})
+	go func() {
+		s.sendStartMessage()
+		close(done)
+	}()
+	startWatchState(t, s)
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("messages not queued after the agent went idle")
+	}
+
+	first := s.Queue.Dequeue(true, false)
+	if first == nil || first.Body != s.Instructions || first.From != "h2-instructions" {
+		t.Fatalf("expected instructions first, got %+v", first)
+	}
+	second := s.Queue.Dequeue(true, false)
+	if second == nil || second.Body != "kickoff" {
+		t.Fatalf("expected start message second, got %+v", second)
+	}
+}
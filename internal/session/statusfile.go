@@ -0,0 +1,67 @@
+package session
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"h2/internal/session/agent"
+	"h2/internal/session/virtualterminal"
+)
+
+// statusLine returns the compact one-line status written to StatusFile:
+// agent name, input mode, agent state (with idle time), and queue depth.
+// Must be called with VT.Mu held.
+func (s *Session) statusLine() string {
+	mode := "normal"
+	if s.PassthroughOwner != nil {
+		mode = "passthrough"
+	}
+
+	st, sub := s.State()
+	state := agent.FormatStateLabel(st.String(), sub.String())
+	if st == agent.StateIdle {
+		state += " " + virtualterminal.FormatDuration(s.StateDuration(), s.DurationPrecision)
+	}
+
+	line := fmt.Sprintf("%s: %s | %s", s.Name, mode, state)
+	if count := s.Queue.PendingCount(); count > 0 {
+		if s.Queue.IsPaused() {
+			line += fmt.Sprintf(" | %d paused", count)
+		} else {
+			line += fmt.Sprintf(" | %d queued", count)
+		}
+	}
+	return line
+}
+
+// writeStatusFile replaces StatusFile with line if it changed since the last
+// write. The file is swapped in via rename so readers never see a partial
+// line. No-op when no status file is configured.
+func (s *Session) writeStatusFile(line string) {
+	if s.StatusFile == "" || line == s.lastStatusLine {
+		return
+	}
+	tmp := s.StatusFile + ".tmp"
+	if err := os.WriteFile(tmp, []byte(line+"\n"), 0o644); err != nil {
+		log.Printf("warning: write status file: %v", err)
+		return
+	}
+	if err := os.Rename(tmp, s.StatusFile); err != nil {
+		os.Remove(tmp)
+		log.Printf("warning: write status file: %v", err)
+		return
+	}
+	s.lastStatusLine = line
+}
+
+// removeStatusFile deletes StatusFile on shutdown so a stale status isn't
+// left behind.
+func (s *Session) removeStatusFile() {
+	if s.StatusFile == "" {
+		return
+	}
+	if err := os.Remove(s.StatusFile); err != nil && !os.IsNotExist(err) {
+		log.Printf("warning: remove status file: %v", err)
+	}
+}
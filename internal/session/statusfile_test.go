@@ -0,0 +1,84 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"h2/internal/session/agent"
+	"h2/internal/session/message"
+)
+
+func readStatusFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read status file: %v", err)
+	}
+	return strings.TrimSuffix(string(data), "\n")
+}
+
+func TestStatusFile_WrittenAndUpdatedOnTick(t *testing.T) {
+	setFastIdle(t)
+	s := newTestSession()
+	s.Name = "worker"
+	s.StatusFile = filepath.Join(t.TempDir(), "worker.status")
+	defer s.Stop()
+
+	startWatchState(t, s)
+	waitForState(t, s, agent.StateIdle, 2*time.Second)
+
+	s.statusTick()
+	got := readStatusFile(t, s.StatusFile)
+	if !strings.HasPrefix(got, "worker: normal | Idle") {
+		t.Errorf("status = %q, want idle normal-mode line", got)
+	}
+	if strings.Contains(got, "queued") {
+		t.Errorf("status = %q, want no queue segment when empty", got)
+	}
+	if strings.Contains(got, "\n") {
+		t.Errorf("status = %q, want a single line", got)
+	}
+
+	// Queue a message while the queue is paused: the line reflects both.
+	s.Queue.Pause()
+	s.Queue.Enqueue(&message.Message{ID: "m1", Priority: message.PriorityNormal, Status: message.StatusQueued, CreatedAt: time.Now()})
+	s.statusTick()
+	if got := readStatusFile(t, s.StatusFile); !strings.HasSuffix(got, "| 1 paused") {
+		t.Errorf("status = %q, want paused queue count", got)
+	}
+
+	// State change: output makes the agent active.
+	s.NoteOutput()
+	waitForState(t, s, agent.StateActive, 2*time.Second)
+	s.statusTick()
+	if got := readStatusFile(t, s.StatusFile); !strings.HasPrefix(got, "worker: normal | Active") {
+		t.Errorf("status = %q, want active state", got)
+	}
+
+	// Passthrough mode is reported too.
+	cl := s.NewClient()
+	if !cl.TryPassthrough() {
+		t.Fatal("TryPassthrough failed")
+	}
+	s.statusTick()
+	if got := readStatusFile(t, s.StatusFile); !strings.HasPrefix(got, "worker: passthrough |") {
+		t.Errorf("status = %q, want passthrough mode", got)
+	}
+}
+
+func TestStatusFile_RemovedOnStop(t *testing.T) {
+	s := newTestSession()
+	s.StatusFile = filepath.Join(t.TempDir(), "agent.status")
+
+	s.statusTick()
+	if _, err := os.Stat(s.StatusFile); err != nil {
+		t.Fatalf("status file not written: %v", err)
+	}
+	s.Stop()
+	if _, err := os.Stat(s.StatusFile); !os.IsNotExist(err) {
+		t.Errorf("status file still present after Stop: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package virtualterminal
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"strings"
+)
+
+// osc52Prefix starts an OSC 52 clipboard sequence: ESC ] 52 ; <sel> ; <base64>.
+var osc52Prefix = []byte("\033]52;")
+
+// maxClipboardSeqLen caps a buffered partial OSC 52 sequence. A sequence
+// that grows past it without a terminator is dropped.
+const maxClipboardSeqLen = 1 << 20
+
+// ClipboardEnabled reports whether OSC 52 sequences from the child are
+// forwarded to the user's terminal. H2_CLIPBOARD=0 turns it off.
+func ClipboardEnabled() bool {
+	return strings.TrimSpace(os.Getenv("H2_CLIPBOARD")) != "0"
+}
+
+// MaxClipboardCopy caps text h2 itself copies with OSC 52; many terminals
+// drop larger sequences.
+const MaxClipboardCopy = 100 << 10
+
+// ClipboardSequence returns the OSC 52 sequence that sets the system
+// clipboard to text.
+func ClipboardSequence(text string) []byte {
+	return []byte("\033]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a")
+}
+
+// clipboardScanner finds OSC 52 sequences in the child's output stream,
+// buffering a sequence that is split across reads.
+type clipboardScanner struct {
+	pending []byte
+}
+
+// Scan returns copies of the complete OSC 52 sequences (BEL or ST
+// terminated) in data that set the clipboard, carrying any unterminated
+// tail over to the next call. Queries ("?") and sequences without valid
+// base64 data are dropped: forwarding a query would let the child read
+// the user's clipboard through the terminal's reply.
+func (s *clipboardScanner) Scan(data []byte) [][]byte {
+	if len(s.pending) > 0 {
+		data = append(s.pending, data...)
+		s.pending = nil
+	}
+	var seqs [][]byte
+	for {
+		idx := bytes.Index(data, osc52Prefix)
+		if idx < 0 {
+			s.keepPartialPrefix(data)
+			return seqs
+		}
+		rest := data[idx+len(osc52Prefix):]
+		end, termLen := osc52End(rest)
+		if end >= 0 && termLen == 0 {
+			// Malformed: a stray ESC ended the sequence. Drop it.
+			data = rest[end:]
+			continue
+		}
+		if end < 0 {
+			if len(data)-idx <= maxClipboardSeqLen {
+				s.pending = append([]byte(nil), data[idx:]...)
+			}
+			return seqs
+		}
+		seqEnd := idx + len(osc52Prefix) + end + termLen
+		if isClipboardSet(rest[:end]) {
+			seqs = append(seqs, append([]byte(nil), data[idx:seqEnd]...))
+		}
+		data = data[seqEnd:]
+	}
+}
+
+// isClipboardSet reports whether body, an OSC 52 sequence after "52;",
+// is "<selection>;<base64 data>" with non-empty, decodable data.
+func isClipboardSet(body []byte) bool {
+	_, payload, ok := bytes.Cut(body, []byte(";"))
+	if !ok || len(payload) == 0 {
+		return false
+	}
+	_, err := base64.StdEncoding.DecodeString(string(payload))
+	return err == nil
+}
+
+// osc52End returns the offset of the terminator in rest and its length, or
+// -1 if the sequence is not yet terminated. The selection and base64
+// payload never contain ESC or BEL, so the first of either ends it; an ESC
+// that doesn't start ST is reported with length 0.
+func osc52End(rest []byte) (int, int) {
+	for i, b := range rest {
+		switch b {
+		case '\a':
+			return i, 1
+		case 0x1B:
+			if i+1 == len(rest) {
+				return -1, 0 // ST may be split across reads
+			}
+			if rest[i+1] == '\\' {
+				return i, 2
+			}
+			return i, 0
+		}
+	}
+	return -1, 0
+}
+
+// keepPartialPrefix buffers a trailing fragment of data that could be the
+// start of an OSC 52 prefix completed by the next read.
+func (s *clipboardScanner) keepPartialPrefix(data []byte) {
+	for n := len(osc52Prefix) - 1; n > 0; n-- {
+		if len(data) >= n && bytes.Equal(data[len(data)-n:], osc52Prefix[:n]) {
+			s.pending = append([]byte(nil), data[len(data)-n:]...)
+			return
+		}
+	}
+}
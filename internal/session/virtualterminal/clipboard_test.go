@@ -0,0 +1,104 @@
+package virtualterminal
+
+import (
+	"strings"
+	"testing"
+)
+
+const osc52Seq = "\033]52;c;aGVsbG8=\a"
+
+func scanAll(s *clipboardScanner, chunks ...string) []string {
+	var got []string
+	for _, c := range chunks {
+		for _, seq := range s.Scan([]byte(c)) {
+			got = append(got, string(seq))
+		}
+	}
+	return got
+}
+
+func TestClipboardScanner_CompleteSequences(t *testing.T) {
+	st := "\033]52;p;d29ybGQ=\033\\"
+	got := scanAll(&clipboardScanner{}, "before"+osc52Seq+"mid"+st+"after")
+	if len(got) != 2 || got[0] != osc52Seq || got[1] != st {
+		t.Errorf("Scan = %q, want BEL and ST sequences unchanged", got)
+	}
+}
+
+func TestClipboardScanner_SplitAcrossReads(t *testing.T) {
+	seq := "\033]52;c;aGVsbG8=\033\\"
+	for split := 1; split < len(seq); split++ {
+		got := scanAll(&clipboardScanner{}, "x"+seq[:split], seq[split:]+"y")
+		if len(got) != 1 || got[0] != seq {
+			t.Errorf("split at %d: Scan = %q, want %q", split, got, seq)
+		}
+	}
+}
+
+func TestClipboardScanner_IgnoresOtherOSC(t *testing.T) {
+	got := scanAll(&clipboardScanner{}, "\033]10;?\a\033]1337;h2-status=hi\a\033]5")
+	if len(got) != 0 {
+		t.Errorf("Scan = %q, want no clipboard sequences", got)
+	}
+}
+
+func TestClipboardScanner_DropsMalformedAndOversized(t *testing.T) {
+	s := &clipboardScanner{}
+	if got := scanAll(s, "\033]52;c;abc\033[0m"+osc52Seq); len(got) != 1 || got[0] != osc52Seq {
+		t.Errorf("Scan = %q, want only the well-formed sequence", got)
+	}
+	scanAll(s, "\033]52;c;"+strings.Repeat("A", maxClipboardSeqLen))
+	if len(s.pending) != 0 {
+		t.Errorf("pending = %d bytes, want an oversized sequence dropped", len(s.pending))
+	}
+}
+
+func TestClipboardScanner_DropsQueries(t *testing.T) {
+	for _, seq := range []string{
+		"\033]52;c;?\a",
+		"\033]52;c;?\033\\",
+		"\033]52;;?\a",
+		"\033]52;c;\a",
+		"\033]52;c;not base64!\a",
+		"\033]52;c\a",
+	} {
+		if got := scanAll(&clipboardScanner{}, seq+osc52Seq); len(got) != 1 || got[0] != osc52Seq {
+			t.Errorf("Scan(%q) = %q, want only the set sequence", seq, got)
+		}
+	}
+}
+
+func TestClipboardEnabled(t *testing.T) {
+	t.Setenv("H2_CLIPBOARD", "")
+	if !ClipboardEnabled() {
+		t.Error("clipboard passthrough should be on by default")
+	}
+	t.Setenv("H2_CLIPBOARD", "0")
+	if ClipboardEnabled() {
+		t.Error("H2_CLIPBOARD=0 should disable clipboard passthrough")
+	}
+}
+
+func TestPipeOutput_ForwardsClipboard(t *testing.T) {
+	vt := newPipeTestVT()
+	var got []string
+	vt.OnClipboard = func(seq []byte) { got = append(got, string(seq)) }
+	r := &flakyReader{steps: []readStep{
+		{data: "copy\033]52;c;aGVs"},
+		{data: "bG8=\a done"},
+	}}
+	vt.pipeOutputFrom(r, func() {})
+
+	if len(got) != 1 || got[0] != osc52Seq {
+		t.Errorf("OnClipboard got %q, want %q", got, osc52Seq)
+	}
+	if !strings.Contains(string(vt.Vt.Content[0]), "copy done") {
+		t.Errorf("screen = %q, want the surrounding output", string(vt.Vt.Content[0]))
+	}
+}
+
+func TestClipboardSequence(t *testing.T) {
+	if got := string(ClipboardSequence("hello")); got != osc52Seq {
+		t.Fatalf("got %q, want %q", got, osc52Seq)
+	}
+}
@@ -0,0 +1,71 @@
+package virtualterminal
+
+// SubmitNewline selects the byte sequence written to the child PTY to
+// submit a line of input.
+type SubmitNewline string
+
+const (
+	SubmitCR   SubmitNewline = "cr"
+	SubmitLF   SubmitNewline = "lf"
+	SubmitCRLF SubmitNewline = "crlf"
+)
+
+// ParseSubmitNewline validates a submit_newline setting. An empty string
+// selects the default (cr).
+func ParseSubmitNewline(s string) (SubmitNewline, bool) {
+	switch SubmitNewline(s) {
+	case "", SubmitCR:
+		return SubmitCR, true
+	case SubmitLF, SubmitCRLF:
+		return SubmitNewline(s), true
+	default:
+		return "", false
+	}
+}
+
+// Bytes returns the bytes written to the PTY on submit.
+func (n SubmitNewline) Bytes() []byte {
+	switch n {
+	case SubmitLF:
+		return []byte{'\n'}
+	case SubmitCRLF:
+		return []byte{'\r', '\n'}
+	default:
+		return []byte{'\r'}
+	}
+}
+
+// SoftNewline returns the bytes written for Shift+Enter when there is no
+// input bar to hold the newline: LF for cr and lf, CRLF for crlf. Children
+// that submit on CR read LF as a line break inside the prompt.
+func (n SubmitNewline) SoftNewline() []byte {
+	if n == SubmitCRLF {
+		return []byte{'\r', '\n'}
+	}
+	return []byte{'\n'}
+}
+
+// EnterAction selects what a modifier+Enter (Shift+Enter, Alt+Enter) does.
+type EnterAction string
+
+const (
+	// EnterInsertNewline adds a newline to the input bar without
+	// submitting; in passthrough, where there is no input bar, the child
+	// gets the soft newline instead.
+	EnterInsertNewline EnterAction = "insert_newline"
+	EnterForwardCR     EnterAction = "forward_cr" // write CR to the child
+	EnterForwardLF     EnterAction = "forward_lf" // write LF to the child
+)
+
+// ParseEnterAction validates a modifier_enter setting. An empty string
+// selects the default (insert_newline).
+func ParseEnterAction(s string) (EnterAction, bool) {
+	switch EnterAction(s) {
+	case "", EnterInsertNewline:
+		return EnterInsertNewline, true
+	case EnterForwardCR, EnterForwardLF:
+		return EnterAction(s), true
+	default:
+		return "", false
+	}
+}
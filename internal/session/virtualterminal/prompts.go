@@ -0,0 +1,69 @@
+package virtualterminal
+
+import "strings"
+
+// maxPromptMarks caps how many prompt boundaries are remembered.
+const maxPromptMarks = 256
+
+// MarkPrompt records the current Scrollback row as a prompt boundary, just
+// before input is written to the child. Callers must hold Mu.
+func (vt *VT) MarkPrompt() {
+	if vt.Scrollback == nil {
+		return
+	}
+	y := vt.Scrollback.Cursor.Y
+	if n := len(vt.PromptMarks); n > 0 {
+		switch last := vt.PromptMarks[n-1]; {
+		case last == y:
+			return // nothing was output since the last prompt
+		case last > y:
+			vt.PromptMarks = nil // the scrollback was replaced
+		}
+	}
+	vt.PromptMarks = append(vt.PromptMarks, y)
+	if len(vt.PromptMarks) > maxPromptMarks {
+		vt.PromptMarks = vt.PromptMarks[len(vt.PromptMarks)-maxPromptMarks:]
+	}
+}
+
+// LastResponse returns the plain text of the child's most recent response:
+// the Scrollback rows after the last prompt boundary, or, if nothing has
+// been output since then, between the last two. It returns "" when no
+// prompt has been marked. Callers must hold Mu.
+func (vt *VT) LastResponse() string {
+	if vt.Scrollback == nil {
+		return ""
+	}
+	end := len(vt.Scrollback.Content)
+	for i := len(vt.PromptMarks) - 1; i >= 0; i-- {
+		// The boundary row holds the prompt line itself.
+		start := vt.PromptMarks[i] + 1
+		if start > end {
+			start = end
+		}
+		if text := rowsText(vt.Scrollback.Content[start:end]); text != "" {
+			return text
+		}
+		end = vt.PromptMarks[i]
+		if end > len(vt.Scrollback.Content) {
+			end = len(vt.Scrollback.Content)
+		}
+	}
+	return ""
+}
+
+// rowsText joins rows as plain text, trimming trailing blanks on each row
+// and blank rows at either end.
+func rowsText(rows [][]rune) string {
+	lines := make([]string, 0, len(rows))
+	for _, row := range rows {
+		lines = append(lines, strings.TrimRight(string(row), " \x00"))
+	}
+	for len(lines) > 0 && lines[0] == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,64 @@
+package virtualterminal
+
+import (
+	"testing"
+
+	"github.com/vito/midterm"
+)
+
+func newPromptVT() *VT {
+	sb := midterm.NewTerminal(5, 40)
+	sb.AutoResizeY = true
+	sb.AppendOnly = true
+	return &VT{Scrollback: sb}
+}
+
+func TestLastResponse_NoPrompt(t *testing.T) {
+	vt := newPromptVT()
+	vt.Scrollback.Write([]byte("banner\r\n"))
+	if got := vt.LastResponse(); got != "" {
+		t.Fatalf("got %q, want empty", got)
+	}
+}
+
+func TestLastResponse_AfterLastPrompt(t *testing.T) {
+	vt := newPromptVT()
+	vt.Scrollback.Write([]byte("banner\r\n"))
+	vt.MarkPrompt()
+	vt.Scrollback.Write([]byte("> first\r\nanswer one\r\n"))
+	vt.MarkPrompt()
+	vt.Scrollback.Write([]byte("> second\r\nanswer two\r\nmore\r\n"))
+
+	if got, want := vt.LastResponse(), "answer two\nmore"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestLastResponse_FallsBackToPreviousBlock(t *testing.T) {
+	vt := newPromptVT()
+	vt.MarkPrompt()
+	vt.Scrollback.Write([]byte("> first\r\nanswer one\r\n"))
+	vt.MarkPrompt()
+	vt.Scrollback.Write([]byte("> second"))
+
+	if got, want := vt.LastResponse(), "answer one"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestMarkPrompt_DedupesAndResets(t *testing.T) {
+	vt := newPromptVT()
+	vt.Scrollback.Write([]byte("a\r\nb\r\n"))
+	vt.MarkPrompt()
+	vt.MarkPrompt()
+	if len(vt.PromptMarks) != 1 {
+		t.Fatalf("marks = %v, want one", vt.PromptMarks)
+	}
+
+	// A replaced (shorter) scrollback drops the stale marks.
+	vt.Scrollback = midterm.NewTerminal(5, 40)
+	vt.MarkPrompt()
+	if len(vt.PromptMarks) != 1 || vt.PromptMarks[0] != 0 {
+		t.Fatalf("marks = %v, want [0]", vt.PromptMarks)
+	}
+}
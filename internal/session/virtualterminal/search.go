@@ -0,0 +1,50 @@
+package virtualterminal
+
+import (
+	"slices"
+	"unicode"
+)
+
+// SearchScrollback finds the nearest Scrollback row at or beyond from that
+// contains query, ignoring case. older searches toward the top of the
+// scrollback, otherwise toward the bottom. It returns the row and the cell
+// (rune index) where the match starts, or -1, -1 if there is none or query
+// is empty. Callers must hold Mu.
+func (vt *VT) SearchScrollback(query string, from int, older bool) (row, col int) {
+	if vt.Scrollback == nil || query == "" {
+		return -1, -1
+	}
+	needle := foldRunes([]rune(query))
+	last := min(vt.Scrollback.Cursor.Y, len(vt.Scrollback.Content)-1)
+	step := 1
+	if older {
+		step = -1
+	}
+	for r := min(max(from, 0), last); r >= 0 && r <= last; r += step {
+		if c := indexRunes(foldRunes(vt.Scrollback.Content[r]), needle); c >= 0 {
+			return r, c
+		}
+	}
+	return -1, -1
+}
+
+// foldRunes returns a lowercased copy of runes. Folding rune by rune keeps
+// indexes aligned with the scrollback cells.
+func foldRunes(runes []rune) []rune {
+	out := make([]rune, len(runes))
+	for i, r := range runes {
+		out[i] = unicode.ToLower(r)
+	}
+	return out
+}
+
+// indexRunes returns the index of the first occurrence of needle in hay, or
+// -1 if it isn't present.
+func indexRunes(hay, needle []rune) int {
+	for i := 0; i+len(needle) <= len(hay); i++ {
+		if slices.Equal(hay[i:i+len(needle)], needle) {
+			return i
+		}
+	}
+	return -1
+}
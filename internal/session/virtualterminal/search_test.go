@@ -0,0 +1,31 @@
+package virtualterminal
+
+import "testing"
+
+func TestSearchScrollback(t *testing.T) {
+	vt := newPromptVT()
+	vt.Scrollback.Write([]byte("build ok\r\nfirst Error here\r\nnothing\r\n  error again\r\ndone\r\n"))
+	bottom := vt.Scrollback.Cursor.Y
+
+	for _, tc := range []struct {
+		name     string
+		query    string
+		from     int
+		older    bool
+		row, col int
+	}{
+		{"most recent, case-insensitive", "ERROR", bottom, true, 3, 2},
+		{"older from before the latest", "error", 2, true, 1, 6},
+		{"newer from the top", "error", 0, false, 1, 6},
+		{"no match", "panic", bottom, true, -1, -1},
+		{"empty query", "", bottom, true, -1, -1},
+		{"from past the end is clamped", "done", bottom + 10, true, 4, 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			row, col := vt.SearchScrollback(tc.query, tc.from, tc.older)
+			if row != tc.row || col != tc.col {
+				t.Errorf("got (%d, %d), want (%d, %d)", row, col, tc.row, tc.col)
+			}
+		})
+	}
+}
@@ -64,6 +64,48 @@ func IsShiftEnterSequence(seq []byte) bool {
 	}
 }
 
+// IsAltEnterSequence reports whether the escape sequence represents
+// Alt+Enter: ESC followed by CR or LF, or the kitty (ESC[13;3u) and xterm
+// (ESC[27;3;13~) encodings.
+func IsAltEnterSequence(seq []byte) bool {
+	if len(seq) == 2 && seq[0] == 0x1B && (seq[1] == '\r' || seq[1] == '\n') {
+		return true
+	}
+	if len(seq) < 3 || seq[1] != '[' {
+		return false
+	}
+	final := seq[len(seq)-1]
+	params := string(seq[2 : len(seq)-1])
+	switch final {
+	case 'u':
+		return params == "13;3"
+	case '~':
+		return params == "27;3;13"
+	default:
+		return false
+	}
+}
+
+// IsModifierEnterSequence reports whether the escape sequence is a
+// Shift+Enter or Alt+Enter, the keys bound to the modifier_enter action.
+func IsModifierEnterSequence(seq []byte) bool {
+	return IsShiftEnterSequence(seq) || IsAltEnterSequence(seq)
+}
+
+// Bracketed paste markers a terminal wraps pasted text in once paste mode
+// (CSI ?2004h) is enabled.
+const (
+	PasteStart = "\033[200~"
+	PasteEnd   = "\033[201~"
+)
+
+// IsPasteMarker reports whether the escape sequence is a bracketed paste
+// start or end marker.
+func IsPasteMarker(seq []byte) bool {
+	s := string(seq)
+	return s == PasteStart || s == PasteEnd
+}
+
 // IsCtrlEnterSequence reports whether the escape sequence represents Ctrl+Enter.
 // Matches kitty format (ESC[13;5u) and xterm format (ESC[27;5;13~).
 func IsCtrlEnterSequence(seq []byte) bool {
@@ -110,6 +152,16 @@ func IsTruthyEnv(key string) bool {
 	}
 }
 
+// ScrollStepEnv returns the mouse wheel step set with H2_SCROLL_STEP, or 0
+// when it is unset or not a positive number of lines.
+func ScrollStepEnv() int {
+	n, err := strconv.Atoi(strings.TrimSpace(os.Getenv("H2_SCROLL_STEP")))
+	if err != nil || n < 1 {
+		return 0
+	}
+	return n
+}
+
 // FormatDebugKey formats a single byte for debug display.
 func FormatDebugKey(b byte) string {
 	switch b {
@@ -142,8 +194,62 @@ func TrimLeftToWidth(s string, width int) string {
 	return s[start:]
 }
 
+// DurationPrecision selects how FormatDuration renders durations.
+type DurationPrecision string
+
+const (
+	// DurationCompact shows only the largest unit: 30s, 5m, 2h, 3d.
+	DurationCompact DurationPrecision = "compact"
+	// DurationFull shows every unit down to seconds: 5m03s, 1h30m05s.
+	DurationFull DurationPrecision = "full"
+)
+
+// ParseDurationPrecision validates a duration_precision setting. An empty
+// string selects the default (compact).
+func ParseDurationPrecision(s string) (DurationPrecision, bool) {
+	switch DurationPrecision(s) {
+	case "", DurationCompact:
+		return DurationCompact, true
+	case DurationFull:
+		return DurationFull, true
+	default:
+		return "", false
+	}
+}
+
+// ScrollPolicy selects what a client in scroll mode does when the child
+// produces new output.
+type ScrollPolicy string
+
+const (
+	// ScrollStay keeps the scrolled-back view on the same lines while
+	// output arrives below it.
+	ScrollStay ScrollPolicy = "stay"
+	// ScrollFollow leaves scroll mode and jumps to the live view.
+	ScrollFollow ScrollPolicy = "follow"
+)
+
+// ParseScrollPolicy validates a scroll_on_output setting. An empty string
+// selects the default (stay).
+func ParseScrollPolicy(s string) (ScrollPolicy, bool) {
+	switch ScrollPolicy(s) {
+	case "", ScrollStay:
+		return ScrollStay, true
+	case ScrollFollow:
+		return ScrollFollow, true
+	default:
+		return "", false
+	}
+}
+
 // FormatIdleDuration formats a duration into a compact human-readable string.
 func FormatIdleDuration(d time.Duration) string {
+	return FormatDuration(d, DurationCompact)
+}
+
+// FormatDuration formats a duration at the given precision: compact, or
+// with every unit down to seconds for DurationFull.
+func FormatDuration(d time.Duration, precision DurationPrecision) string {
 	if d < time.Minute {
 		secs := int(d.Seconds())
 		if secs < 1 {
@@ -151,6 +257,9 @@ func FormatIdleDuration(d time.Duration) string {
 		}
 		return fmt.Sprintf("%ds", secs)
 	}
+	if precision == DurationFull {
+		return formatFullDuration(d)
+	}
 	if d < time.Hour {
 		mins := int(d.Minutes())
 		return fmt.Sprintf("%dm", mins)
@@ -162,3 +271,20 @@ func FormatIdleDuration(d time.Duration) string {
 	days := int(d.Hours() / 24)
 	return fmt.Sprintf("%dd", days)
 }
+
+// formatFullDuration renders d (at least a minute) as e.g. 5m03s, 1h30m05s,
+// or 2d04h00m09s, with every unit below the largest zero-padded.
+func formatFullDuration(d time.Duration) string {
+	total := int(d.Seconds())
+	days, rem := total/86400, total%86400
+	hrs, rem := rem/3600, rem%3600
+	mins, secs := rem/60, rem%60
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%02dh%02dm%02ds", days, hrs, mins, secs)
+	case hrs > 0:
+		return fmt.Sprintf("%dh%02dm%02ds", hrs, mins, secs)
+	default:
+		return fmt.Sprintf("%dm%02ds", mins, secs)
+	}
+}
@@ -1,6 +1,9 @@
 package virtualterminal
 
-import "testing"
+import (
+	"testing"
+	"time"
+)
 
 func TestIsCtrlEnterSequence(t *testing.T) {
 	tests := []struct {
@@ -67,3 +70,144 @@ func TestIsShiftEnterSequence(t *testing.T) {
 		})
 	}
 }
+
+func TestIsModifierEnterSequence(t *testing.T) {
+	tests := []struct {
+		name  string
+		seq   []byte
+		alt   bool
+		match bool
+	}{
+		{"shift kitty", []byte("\x1b[13;2u"), false, true},
+		{"shift xterm", []byte("\x1b[27;2;13~"), false, true},
+		{"alt esc-cr", []byte("\x1b\r"), true, true},
+		{"alt esc-lf", []byte("\x1b\n"), true, true},
+		{"alt kitty", []byte("\x1b[13;3u"), true, true},
+		{"alt xterm", []byte("\x1b[27;3;13~"), true, true},
+		{"ctrl+enter", []byte("\x1b[13;5u"), false, false},
+		{"esc-a", []byte("\x1ba"), false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsAltEnterSequence(tt.seq); got != tt.alt {
+				t.Errorf("IsAltEnterSequence(%q) = %v, want %v", tt.seq, got, tt.alt)
+			}
+			if got := IsModifierEnterSequence(tt.seq); got != tt.match {
+				t.Errorf("IsModifierEnterSequence(%q) = %v, want %v", tt.seq, got, tt.match)
+			}
+		})
+	}
+}
+
+func TestParseEnterAction(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   EnterAction
+		wantOK bool
+	}{
+		{"", EnterInsertNewline, true},
+		{"insert_newline", EnterInsertNewline, true},
+		{"forward_cr", EnterForwardCR, true},
+		{"forward_lf", EnterForwardLF, true},
+		{"cr", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseEnterAction(tt.in)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ParseEnterAction(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestParseSubmitNewline(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   SubmitNewline
+		wantOK bool
+		bytes  string
+	}{
+		{"", SubmitCR, true, "\r"},
+		{"cr", SubmitCR, true, "\r"},
+		{"lf", SubmitLF, true, "\n"},
+		{"crlf", SubmitCRLF, true, "\r\n"},
+		{"CRLF", "", false, ""},
+		{"\\n", "", false, ""},
+	}
+	for _, tt := range tests {
+		got, ok := ParseSubmitNewline(tt.in)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("ParseSubmitNewline(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.wantOK)
+			continue
+		}
+		if ok && string(got.Bytes()) != tt.bytes {
+			t.Errorf("%q.Bytes() = %q, want %q", tt.in, got.Bytes(), tt.bytes)
+		}
+	}
+}
+
+func TestSoftNewline(t *testing.T) {
+	tests := []struct {
+		n    SubmitNewline
+		want string
+	}{
+		{SubmitCR, "\n"},
+		{SubmitLF, "\n"},
+		{SubmitCRLF, "\r\n"},
+	}
+	for _, tt := range tests {
+		if got := string(tt.n.SoftNewline()); got != tt.want {
+			t.Errorf("%q.SoftNewline() = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFormatIdleDuration(t *testing.T) {
+	tests := []struct {
+		d       time.Duration
+		compact string
+		full    string
+	}{
+		{0, "1s", "1s"},
+		{400 * time.Millisecond, "1s", "1s"},
+		{30 * time.Second, "30s", "30s"},
+		{59*time.Second + 900*time.Millisecond, "59s", "59s"},
+		{time.Minute, "1m", "1m00s"},
+		{5*time.Minute + 3*time.Second, "5m", "5m03s"},
+		{time.Hour, "1h", "1h00m00s"},
+		{time.Hour + 30*time.Minute + 5*time.Second, "1h", "1h30m05s"},
+		{23*time.Hour + 59*time.Minute + 59*time.Second, "23h", "23h59m59s"},
+		{24 * time.Hour, "1d", "1d00h00m00s"},
+		{3*24*time.Hour + 4*time.Hour + 9*time.Second, "3d", "3d04h00m09s"},
+	}
+	for _, tt := range tests {
+		if got := FormatIdleDuration(tt.d); got != tt.compact {
+			t.Errorf("FormatIdleDuration(%v) = %q, want %q", tt.d, got, tt.compact)
+		}
+		if got := FormatDuration(tt.d, DurationCompact); got != tt.compact {
+			t.Errorf("FormatDuration(%v, compact) = %q, want %q", tt.d, got, tt.compact)
+		}
+		if got := FormatDuration(tt.d, DurationFull); got != tt.full {
+			t.Errorf("FormatDuration(%v, full) = %q, want %q", tt.d, got, tt.full)
+		}
+	}
+}
+
+func TestParseDurationPrecision(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   DurationPrecision
+		wantOK bool
+	}{
+		{"", DurationCompact, true},
+		{"compact", DurationCompact, true},
+		{"full", DurationFull, true},
+		{"Full", "", false},
+		{"seconds", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseDurationPrecision(tt.in)
+		if ok != tt.wantOK || got != tt.want {
+			t.Errorf("ParseDurationPrecision(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -2,10 +2,12 @@ package virtualterminal
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -30,12 +32,40 @@ type VT struct {
 	OscFg      string           // cached OSC 10 response (foreground color)
 	OscBg      string           // cached OSC 11 response (background color)
 	LastOut    time.Time        // last time child output updated the screen
+	IdleThreshold time.Duration // quiet time before the child counts as idle (0 = DefaultIdleThreshold)
 	Restore    *term.State      // original terminal state for cleanup
 
 	// Child process lifecycle state.
 	ChildExited bool
 	ChildHung   bool
 	ExitError   error
+
+	// ReadErr is set when reading the PTY kept failing while the child had
+	// not exited, so the screen is no longer updating.
+	ReadErr error
+
+	// Starting is true while the daemon is up but the child hasn't produced
+	// any output yet. Clients render a placeholder screen until it clears.
+	Starting bool
+
+	// AgentStatus is the latest short status the child announced with
+	// OSC 1337;h2-status=<text>, shown in the status bar ("" = none).
+	AgentStatus string
+
+	// BracketedPaste is true while the child has bracketed paste mode
+	// enabled (CSI ?2004h), so paste markers may be forwarded to it.
+	BracketedPaste bool
+
+	// OnClipboard, when set, receives each complete OSC 52 (clipboard set)
+	// sequence from the child, unchanged, with Mu held. midterm swallows
+	// them, so the session forwards them to the user's terminal.
+	OnClipboard func(seq []byte)
+	clipboard   clipboardScanner
+
+	// PromptMarks are the Scrollback rows at which input was submitted to
+	// the child, oldest first. They bound the agent's responses for
+	// LastResponse.
+	PromptMarks []int
 }
 
 // KillChild sends SIGKILL to the child process. Used when the child is hung
@@ -51,24 +81,7 @@ func (vt *VT) KillChild() {
 // overriding any existing values.
 func (vt *VT) StartPTY(command string, args []string, childRows, cols int, extraEnv map[string]string) error {
 	vt.Cmd = exec.Command(command, args...)
-	if len(extraEnv) > 0 {
-		// Build new env, filtering out keys we're overriding
-		env := make([]string, 0, len(os.Environ())+len(extraEnv))
-		for _, e := range os.Environ() {
-			key := e
-			if idx := strings.Index(e, "="); idx >= 0 {
-				key = e[:idx]
-			}
-			if _, override := extraEnv[key]; !override {
-				env = append(env, e)
-			}
-		}
-		// Add our overrides
-		for k, v := range extraEnv {
-			env = append(env, k+"="+v)
-		}
-		vt.Cmd.Env = env
-	}
+	vt.Cmd.Env = childEnv(os.Environ(), extraEnv, childRows, cols)
 	var err error
 	vt.Ptm, err = pty.StartWithSize(vt.Cmd, &pty.Winsize{
 		Rows: uint16(childRows),
@@ -80,17 +93,77 @@ func (vt *VT) StartPTY(command string, args []string, childRows, cols int, extra
 	return nil
 }
 
+// childEnv builds the child's environment from base plus extraEnv. LINES
+// and COLUMNS are always set to the PTY size (the child area, not the outer
+// terminal) for programs that read them instead of querying the tty.
+func childEnv(base []string, extraEnv map[string]string, childRows, cols int) []string {
+	overrides := make(map[string]string, len(extraEnv)+2)
+	for k, v := range extraEnv {
+		overrides[k] = v
+	}
+	overrides["LINES"] = strconv.Itoa(childRows)
+	overrides["COLUMNS"] = strconv.Itoa(cols)
+
+	// Build new env, filtering out keys we're overriding
+	env := make([]string, 0, len(base)+len(overrides))
+	for _, e := range base {
+		key := e
+		if idx := strings.Index(e, "="); idx >= 0 {
+			key = e[:idx]
+		}
+		if _, override := overrides[key]; !override {
+			env = append(env, e)
+		}
+	}
+	// Add our overrides
+	for k, v := range overrides {
+		env = append(env, k+"="+v)
+	}
+	return env
+}
+
+// maxPTYReadRetries bounds how many consecutive failed PTY reads are retried
+// before PipeOutput gives up on a child that is still running.
+const maxPTYReadRetries = 5
+
+// ptyReadBackoff is the delay before the first retry of a failed PTY read;
+// it doubles on each further retry. Var so tests can shorten it.
+var ptyReadBackoff = 50 * time.Millisecond
+
 // PipeOutput reads child PTY output into the virtual terminal and calls
 // onData after each write so the caller can re-render.
 func (vt *VT) PipeOutput(onData func()) {
+	vt.pipeOutputFrom(vt.Ptm, onData)
+}
+
+// pipeOutputFrom is PipeOutput reading from r. A read error ends the loop
+// at EOF, once the PTY is closed, or once the child has exited. Any other
+// error may be transient (e.g. EIO while the child is still running), so
+// the read is retried with backoff; if it keeps failing, ReadErr records
+// the error and onData is called so clients can show it.
+func (vt *VT) pipeOutputFrom(r io.Reader, onData func()) {
 	buf := make([]byte, 4096)
+	failures := 0
 	for {
-		n, err := vt.Ptm.Read(buf)
+		n, err := r.Read(buf)
 		if n > 0 {
+			failures = 0
 			vt.RespondOSCColors(buf[:n])
 
 			vt.Mu.Lock()
+			vt.Starting = false
 			vt.LastOut = time.Now()
+			if st, ok := ParseStatusOSC(buf[:n]); ok {
+				vt.AgentStatus = st
+			}
+			if on, ok := ParseBracketedPasteMode(buf[:n]); ok {
+				vt.BracketedPaste = on
+			}
+			if vt.OnClipboard != nil {
+				for _, seq := range vt.clipboard.Scan(buf[:n]) {
+					vt.OnClipboard(seq)
+				}
+			}
 			vt.Vt.Write(buf[:n])
 			if vt.Scrollback != nil {
 				vt.Scrollback.Write(buf[:n])
@@ -98,12 +171,78 @@ func (vt *VT) PipeOutput(onData func()) {
 			onData()
 			vt.Mu.Unlock()
 		}
-		if err != nil {
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, os.ErrClosed) {
+			return
+		}
+		if failures >= maxPTYReadRetries {
+			vt.Mu.Lock()
+			if !vt.ChildExited {
+				vt.ReadErr = err
+				onData()
+			}
+			vt.Mu.Unlock()
+			return
+		}
+		time.Sleep(ptyReadBackoff << failures)
+		failures++
+
+		vt.Mu.Lock()
+		exited := vt.ChildExited
+		vt.Mu.Unlock()
+		if exited {
 			return
 		}
 	}
 }
 
+// PlainText returns t's content as plain text: one line per row, trailing
+// blanks trimmed, and trailing empty rows dropped.
+func PlainText(t *midterm.Terminal) string {
+	lines := make([]string, 0, len(t.Content))
+	for _, row := range t.Content {
+		lines = append(lines, strings.TrimRight(string(row), " \x00"))
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// ScreenSnapshot is the plain text of the child's visible rows, with the
+// cursor position so callers can tell where the agent is focused.
+type ScreenSnapshot struct {
+	Lines     []string // one per visible row, formatting stripped, trailing blanks trimmed
+	CursorRow int      // 0-based
+	CursorCol int      // 0-based
+}
+
+// Snapshot returns the visible screen as plain text, taken under vt.Mu.
+func (vt *VT) Snapshot() ScreenSnapshot {
+	vt.Mu.Lock()
+	defer vt.Mu.Unlock()
+	return SnapshotOf(vt.Vt)
+}
+
+// SnapshotOf returns t's rows as plain text along with its cursor. Callers
+// must hold the lock guarding t.
+func SnapshotOf(t *midterm.Terminal) ScreenSnapshot {
+	if t == nil {
+		return ScreenSnapshot{}
+	}
+	snap := ScreenSnapshot{
+		Lines:     make([]string, len(t.Content)),
+		CursorRow: t.Cursor.Y,
+		CursorCol: t.Cursor.X,
+	}
+	for i, row := range t.Content {
+		snap.Lines[i] = strings.TrimRight(string(row), " \x00")
+	}
+	return snap
+}
+
 // RespondOSCColors responds to OSC 10/11 color queries from the child.
 func (vt *VT) RespondOSCColors(data []byte) {
 	if vt.OscFg != "" && bytes.Contains(data, []byte("\033]10;?")) {
@@ -114,6 +253,59 @@ func (vt *VT) RespondOSCColors(data []byte) {
 	}
 }
 
+// ParseBracketedPasteMode reports the child's last bracketed paste mode
+// change (CSI ?2004h or ?2004l) in data; ok is false if data has none.
+func ParseBracketedPasteMode(data []byte) (on, ok bool) {
+	set := bytes.LastIndex(data, []byte("\033[?2004h"))
+	reset := bytes.LastIndex(data, []byte("\033[?2004l"))
+	if set < 0 && reset < 0 {
+		return false, false
+	}
+	return set > reset, true
+}
+
+// statusOSCPrefix starts the sentinel an agent prints to set its status text.
+var statusOSCPrefix = []byte("\033]1337;h2-status=")
+
+// maxAgentStatusLen caps the status text (in runes) so it can't crowd the bar.
+const maxAgentStatusLen = 60
+
+// ParseStatusOSC extracts the text of the last complete
+// OSC 1337;h2-status=<text> sequence (BEL or ST terminated) in data.
+// Control characters are dropped and an empty text clears the status.
+// Sequences split across reads are not recognized.
+func ParseStatusOSC(data []byte) (string, bool) {
+	idx := bytes.LastIndex(data, statusOSCPrefix)
+	for idx >= 0 {
+		rest := data[idx+len(statusOSCPrefix):]
+		end := bytes.IndexByte(rest, '\a')
+		if st := bytes.Index(rest, []byte("\033\\")); st >= 0 && (end < 0 || st < end) {
+			end = st
+		}
+		if end >= 0 {
+			return cleanAgentStatus(string(rest[:end])), true
+		}
+		// Unterminated; fall back to an earlier complete sequence.
+		idx = bytes.LastIndex(data[:idx], statusOSCPrefix)
+	}
+	return "", false
+}
+
+// cleanAgentStatus strips control characters, trims, and truncates s.
+func cleanAgentStatus(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7F || (r >= 0x80 && r < 0xA0) {
+			return -1
+		}
+		return r
+	}, s)
+	s = strings.TrimSpace(s)
+	if r := []rune(s); len(r) > maxAgentStatusLen {
+		s = string(r[:maxAgentStatusLen-1]) + "…"
+	}
+	return s
+}
+
 // Resize updates dimensions and resizes the virtual terminal and PTY.
 func (vt *VT) Resize(totalRows, cols, childRows int) {
 	vt.Rows = totalRows
@@ -123,18 +315,33 @@ func (vt *VT) Resize(totalRows, cols, childRows int) {
 	if vt.Scrollback != nil {
 		vt.Scrollback.ResizeX(cols)
 	}
+	if vt.Ptm == nil {
+		return // child not started yet; StartPTY picks up the new size
+	}
 	pty.Setsize(vt.Ptm, &pty.Winsize{
 		Rows: uint16(childRows),
 		Cols: uint16(cols),
 	})
 }
 
+// DefaultIdleThreshold is how long the child must go without output to
+// count as idle when no per-agent idle_threshold is configured.
+const DefaultIdleThreshold = 2 * time.Second
+
+// IdleAfter returns the VT's idle threshold: IdleThreshold when set,
+// DefaultIdleThreshold otherwise.
+func (vt *VT) IdleAfter() time.Duration {
+	if vt.IdleThreshold > 0 {
+		return vt.IdleThreshold
+	}
+	return DefaultIdleThreshold
+}
+
 // IsIdle returns true if the child process has been idle for at least the threshold.
 func (vt *VT) IsIdle() bool {
-	const idleThreshold = 2 * time.Second
 	vt.Mu.Lock()
 	defer vt.Mu.Unlock()
-	return !vt.LastOut.IsZero() && time.Since(vt.LastOut) > idleThreshold
+	return !vt.LastOut.IsZero() && time.Since(vt.LastOut) > vt.IdleAfter()
 }
 
 // ErrPTYWriteTimeout is returned by WritePTY when the write does not complete
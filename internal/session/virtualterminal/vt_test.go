@@ -1,9 +1,15 @@
 package virtualterminal
 
 import (
+	"errors"
+	"io"
 	"os"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
+
+	"github.com/vito/midterm"
 )
 
 func TestWritePTY_Success(t *testing.T) {
@@ -85,3 +91,209 @@ func TestWritePTY_WriteError(t *testing.T) {
 		t.Fatal("expected a pipe error, not a timeout")
 	}
 }
+
+func TestChildEnv_SetsLinesAndColumns(t *testing.T) {
+	base := []string{"PATH=/bin", "LINES=50", "COLUMNS=200", "TERM=xterm"}
+	env := childEnv(base, map[string]string{"H2_ACTOR": "a1"}, 22, 80)
+
+	got := make(map[string][]string)
+	for _, e := range env {
+		k, v, _ := strings.Cut(e, "=")
+		got[k] = append(got[k], v)
+	}
+	for k, want := range map[string]string{"LINES": "22", "COLUMNS": "80", "H2_ACTOR": "a1", "PATH": "/bin", "TERM": "xterm"} {
+		if vals := got[k]; len(vals) != 1 || vals[0] != want {
+			t.Errorf("%s = %v, want [%s]", k, vals, want)
+		}
+	}
+}
+
+func TestStartPTY_ChildSeesLinesAndColumns(t *testing.T) {
+	vt := &VT{}
+	if err := vt.StartPTY("sh", []string{"-c", `echo "size=$LINES:$COLUMNS"`}, 17, 63, nil); err != nil {
+		t.Fatalf("StartPTY: %v", err)
+	}
+	defer vt.Ptm.Close()
+
+	out, _ := io.ReadAll(vt.Ptm)
+	vt.Cmd.Wait()
+	if !strings.Contains(string(out), "size=17:63") {
+		t.Fatalf("child output %q, want size=17:63", out)
+	}
+}
+
+func TestParseStatusOSC(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   string
+		want   string
+		wantOK bool
+	}{
+		{"bel terminated", "out\033]1337;h2-status=waiting for review\007more", "waiting for review", true},
+		{"st terminated", "\033]1337;h2-status=  running tests \033\\", "running tests", true},
+		{"last wins", "\033]1337;h2-status=one\007\033]1337;h2-status=two\007", "two", true},
+		{"empty clears", "\033]1337;h2-status=\007", "", true},
+		{"unterminated ignored", "\033]1337;h2-status=half", "", false},
+		{"unterminated falls back", "\033]1337;h2-status=done\007\033]1337;h2-status=ha", "done", true},
+		{"control chars dropped", "\033]1337;h2-status=a\033[31mb\007", "a[31mb", true},
+		{"no sentinel", "plain output\033]0;title\007", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ParseStatusOSC([]byte(tt.data))
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ParseStatusOSC(%q) = %q, %v; want %q, %v", tt.data, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestParseStatusOSC_Truncates(t *testing.T) {
+	long := strings.Repeat("x", 100)
+	got, _ := ParseStatusOSC([]byte("\033]1337;h2-status=" + long + "\007"))
+	if n := len([]rune(got)); n != maxAgentStatusLen {
+		t.Fatalf("len = %d, want %d", n, maxAgentStatusLen)
+	}
+}
+
+// flakyReader replays a scripted sequence of reads: each step returns its
+// data and error once, then io.EOF after the last step.
+type flakyReader struct {
+	steps []readStep
+}
+
+type readStep struct {
+	data string
+	err  error
+}
+
+func (r *flakyReader) Read(p []byte) (int, error) {
+	if len(r.steps) == 0 {
+		return 0, io.EOF
+	}
+	step := r.steps[0]
+	r.steps = r.steps[1:]
+	return copy(p, step.data), step.err
+}
+
+func newPipeTestVT() *VT {
+	return &VT{Vt: midterm.NewTerminal(5, 40), Rows: 7, Cols: 40, ChildRows: 5}
+}
+
+func TestPipeOutput_RecoversFromTransientReadErrors(t *testing.T) {
+	ptyReadBackoff = time.Millisecond
+	t.Cleanup(func() { ptyReadBackoff = 50 * time.Millisecond })
+
+	vt := newPipeTestVT()
+	r := &flakyReader{steps: []readStep{
+		{data: "before "},
+		{err: syscall.EIO},
+		{err: syscall.EIO},
+		{data: "after"},
+	}}
+	done := make(chan struct{})
+	go func() {
+		vt.pipeOutputFrom(r, func() {})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("pipeOutputFrom did not return at EOF")
+	}
+
+	if got := string(vt.Vt.Content[0]); !strings.Contains(got, "before after") {
+		t.Errorf("screen = %q, want output from both sides of the errors", got)
+	}
+	if vt.ReadErr != nil {
+		t.Errorf("ReadErr = %v, want nil after recovery", vt.ReadErr)
+	}
+}
+
+func TestPipeOutput_PersistentReadErrorSurfaced(t *testing.T) {
+	ptyReadBackoff = time.Millisecond
+	t.Cleanup(func() { ptyReadBackoff = 50 * time.Millisecond })
+
+	vt := newPipeTestVT()
+	var steps []readStep
+	for i := 0; i <= maxPTYReadRetries; i++ {
+		steps = append(steps, readStep{err: syscall.EIO})
+	}
+	notified := false
+	vt.pipeOutputFrom(&flakyReader{steps: steps}, func() { notified = true })
+
+	if !errors.Is(vt.ReadErr, syscall.EIO) {
+		t.Errorf("ReadErr = %v, want EIO", vt.ReadErr)
+	}
+	if !notified {
+		t.Error("onData should be called so clients can show the stalled output")
+	}
+}
+
+func TestPipeOutput_StopsWhenChildExited(t *testing.T) {
+	ptyReadBackoff = time.Millisecond
+	t.Cleanup(func() { ptyReadBackoff = 50 * time.Millisecond })
+
+	vt := newPipeTestVT()
+	vt.ChildExited = true
+	r := &flakyReader{steps: []readStep{{err: syscall.EIO}, {data: "late"}}}
+	vt.pipeOutputFrom(r, func() {})
+
+	if vt.ReadErr != nil {
+		t.Errorf("ReadErr = %v, want nil for an exited child", vt.ReadErr)
+	}
+	if len(r.steps) != 1 {
+		t.Errorf("reads after child exit: %d steps consumed, want 1", 2-len(r.steps))
+	}
+}
+
+func TestPlainText_TrimsPaddingAndTrailingRows(t *testing.T) {
+	term := midterm.NewTerminal(5, 20)
+	term.Write([]byte("hello   \r\n\r\nworld"))
+
+	if got, want := PlainText(term), "hello\n\nworld"; got != want {
+		t.Errorf("PlainText = %q, want %q", got, want)
+	}
+}
+
+func TestSnapshot_PlainTextAndCursor(t *testing.T) {
+	vt := newPipeTestVT()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	go func() {
+		vt.pipeOutputFrom(r, func() {})
+		close(done)
+	}()
+	w.Write([]byte("\033[1;31mred\033[0m   \r\n> ty"))
+	w.Close()
+	<-done
+
+	snap := vt.Snapshot()
+	want := []string{"red", "> ty", "", "", ""}
+	if strings.Join(snap.Lines, "|") != strings.Join(want, "|") {
+		t.Errorf("Lines = %q, want %q", snap.Lines, want)
+	}
+	if snap.CursorRow != 1 || snap.CursorCol != 4 {
+		t.Errorf("cursor = %d,%d, want 1,4", snap.CursorRow, snap.CursorCol)
+	}
+}
+
+func TestParseBracketedPasteMode(t *testing.T) {
+	for _, tc := range []struct {
+		data   string
+		on, ok bool
+	}{
+		{"plain output", false, false},
+		{"\033[?2004h", true, true},
+		{"\033[?2004hprompt\033[?2004l", false, true},
+		{"\033[?2004l\033[?2004h> ", true, true},
+	} {
+		on, ok := ParseBracketedPasteMode([]byte(tc.data))
+		if on != tc.on || ok != tc.ok {
+			t.Errorf("ParseBracketedPasteMode(%q) = %v, %v; want %v, %v", tc.data, on, ok, tc.on, tc.ok)
+		}
+	}
+}
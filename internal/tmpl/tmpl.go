@@ -2,7 +2,9 @@ package tmpl
 
 import (
 	"fmt"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"text/template"
 
@@ -11,16 +13,74 @@ import (
 
 // VarDef defines a template variable with optional default.
 // Default is a pointer: nil means "required" (no default), non-nil means "optional".
+// Type and Enum optionally constrain the values the variable accepts.
 type VarDef struct {
-	Description string  `yaml:"description"`
-	Default     *string `yaml:"default"`
+	Description string   `yaml:"description"`
+	Default     *string  `yaml:"default"`
+	Type        string   `yaml:"type,omitempty"` // string (default), int, or bool
+	Enum        []string `yaml:"enum,omitempty"` // allowed values
 }
 
+// varTypes are the accepted VarDef.Type values.
+var varTypes = []string{"string", "int", "bool"}
+
 // Required returns true if the variable has no default value.
 func (v VarDef) Required() bool {
 	return v.Default == nil
 }
 
+// Check reports whether value satisfies the variable's type and enum
+// constraints. Untyped variables without an enum accept anything.
+func (v VarDef) Check(value string) error {
+	switch v.Type {
+	case "int":
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("must be an int")
+		}
+	case "bool":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("must be a bool (true or false)")
+		}
+	}
+	if len(v.Enum) > 0 && !slices.Contains(v.Enum, value) {
+		return fmt.Errorf("must be one of: %s", strings.Join(v.Enum, ", "))
+	}
+	return nil
+}
+
+// placeholder is the value shown for the variable in a --var hint.
+func (v VarDef) placeholder() string {
+	switch {
+	case len(v.Enum) > 0:
+		return strings.Join(v.Enum, "|")
+	case v.Type == "int":
+		return "INT"
+	case v.Type == "bool":
+		return "true|false"
+	}
+	return "VALUE"
+}
+
+// validateDef checks the definition itself: a known type, enum values and a
+// default that fit the type, and a default that is in the enum.
+func validateDef(name string, def VarDef) error {
+	if def.Type != "" && !slices.Contains(varTypes, def.Type) {
+		return fmt.Errorf("variable %q: invalid type %q (valid: %s)", name, def.Type, strings.Join(varTypes, ", "))
+	}
+	typed := VarDef{Type: def.Type}
+	for _, e := range def.Enum {
+		if err := typed.Check(e); err != nil {
+			return fmt.Errorf("variable %q: enum value %q %s", name, e, err)
+		}
+	}
+	if def.Default != nil {
+		if err := def.Check(*def.Default); err != nil {
+			return fmt.Errorf("variable %q: default %q %s", name, *def.Default, err)
+		}
+	}
+	return nil
+}
+
 // Context holds all template data available during rendering.
 type Context struct {
 	AgentName string
@@ -73,10 +133,48 @@ func ParseVarDefs(yamlText string) (map[string]VarDef, string, error) {
 	if defs == nil {
 		defs = map[string]VarDef{}
 	}
+	names := make([]string, 0, len(defs))
+	for name := range defs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := validateDef(name, defs[name]); err != nil {
+			return nil, "", err
+		}
+	}
 
 	return defs, remaining, nil
 }
 
+// ParseConsts extracts the consts section from raw YAML text.
+// Returns the constants and the YAML text with the consts section removed.
+// Like variables, the consts section must not contain template expressions.
+func ParseConsts(yamlText string) (map[string]string, string, error) {
+	block, remaining := extractYAMLSection(yamlText, "consts")
+	if block == "" {
+		return nil, yamlText, nil
+	}
+
+	var wrapper struct {
+		Consts map[string]string `yaml:"consts"`
+	}
+	if err := yaml.Unmarshal([]byte("consts:\n"+block), &wrapper); err != nil {
+		return nil, "", fmt.Errorf("parse consts section: %w", err)
+	}
+	return wrapper.Consts, remaining, nil
+}
+
+// MergeConsts adds consts to vars for any key not already set. Consts have
+// the lowest precedence: defaults and --var values always win.
+func MergeConsts(vars map[string]string, consts map[string]string) {
+	for k, v := range consts {
+		if _, ok := vars[k]; !ok {
+			vars[k] = v
+		}
+	}
+}
+
 // extractYAMLSection finds a top-level YAML key, extracts its indented block,
 // and returns (block text, remaining text without the section).
 // Returns ("", original text) if the key is not found.
@@ -123,8 +221,10 @@ func extractYAMLSection(yamlText string, key string) (string, string) {
 	return strings.Join(block, "\n"), strings.Join(remaining, "\n")
 }
 
-// ValidateVars checks that all required variables (no default) are provided.
-// Returns a descriptive error listing all missing variables with descriptions.
+// ValidateVars checks that all required variables (no default) are provided
+// and that every provided value satisfies its variable's type and enum.
+// Returns a descriptive error listing all missing variables with descriptions,
+// or, when none are missing, every invalid value with its constraint.
 func ValidateVars(defs map[string]VarDef, provided map[string]string) error {
 	var missing []string
 	for name, def := range defs {
@@ -135,7 +235,7 @@ func ValidateVars(defs map[string]VarDef, provided map[string]string) error {
 		}
 	}
 	if len(missing) == 0 {
-		return nil
+		return validateValues(defs, provided)
 	}
 
 	sort.Strings(missing)
@@ -160,13 +260,51 @@ func ValidateVars(defs map[string]VarDef, provided map[string]string) error {
 	return fmt.Errorf("%s", buf.String())
 }
 
+// validateValues checks provided values against their definitions' type and
+// enum constraints, listing every invalid value in one error.
+func validateValues(defs map[string]VarDef, provided map[string]string) error {
+	var invalid []string
+	problems := map[string]error{}
+	for name, def := range defs {
+		value, ok := provided[name]
+		if !ok {
+			continue
+		}
+		if err := def.Check(value); err != nil {
+			invalid = append(invalid, name)
+			problems[name] = err
+		}
+	}
+	if len(invalid) == 0 {
+		return nil
+	}
+
+	sort.Strings(invalid)
+
+	var buf strings.Builder
+	buf.WriteString("invalid variable values:\n\n")
+	for _, name := range invalid {
+		fmt.Fprintf(&buf, "  %-16s — %q %s\n", name, provided[name], problems[name])
+	}
+	buf.WriteString("\nProvide them with: --var ")
+	for i, name := range invalid {
+		if i > 0 {
+			buf.WriteString(" --var ")
+		}
+		fmt.Fprintf(&buf, "%s=%s", name, defs[name].placeholder())
+	}
+	return fmt.Errorf("%s", buf.String())
+}
+
 // funcMap returns the custom template functions.
 func funcMap() template.FuncMap {
 	return template.FuncMap{
 		"seq":       seqFunc,
+		"zeropad":   zeropadFunc,
 		"split":     splitFunc,
 		"join":      joinFunc,
 		"default":   defaultFunc,
+		"required":  requiredFunc,
 		"upper":     strings.ToUpper,
 		"lower":     strings.ToLower,
 		"contains":  strings.Contains,
@@ -192,6 +330,16 @@ func seqFunc(start, end int) ([]int, error) {
 	return result, nil
 }
 
+// zeropadFunc formats n with leading zeros to at least width digits, for
+// sortable agent names: {{ zeropad .Index 2 }} gives "01" ... "10". Wider
+// numbers are left as-is. Returns an error if width is outside [0, 20].
+func zeropadFunc(n, width int) (string, error) {
+	if width < 0 || width > 20 {
+		return "", fmt.Errorf("zeropad width %d out of range (0-20)", width)
+	}
+	return fmt.Sprintf("%0*d", width, n), nil
+}
+
 func splitFunc(s, sep string) []string {
 	return strings.Split(s, sep)
 }
@@ -209,6 +357,16 @@ func defaultFunc(val, fallback string) string {
 	return fallback
 }
 
+// requiredFunc returns val if non-empty, otherwise fails rendering with msg.
+// It guards a code path that needs a variable the schema leaves optional:
+// {{ .Var.region | required "region is needed in prod" }}.
+func requiredFunc(msg, val string) (string, error) {
+	if val == "" {
+		return "", fmt.Errorf("%s", msg)
+	}
+	return val, nil
+}
+
 // quoteFunc returns a YAML-safe quoted string.
 func quoteFunc(s string) string {
 	// Use Go %q which produces a double-quoted string with proper escaping.
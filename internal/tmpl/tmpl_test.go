@@ -273,6 +273,91 @@ func TestValidateVars_ErrorFormat(t *testing.T) {
 	})
 }
 
+func TestValidateVars_TypeAndEnum(t *testing.T) {
+	defs := map[string]VarDef{
+		"env":      {Enum: []string{"dev", "staging", "prod"}},
+		"replicas": {Type: "int"},
+		"debug":    {Type: "bool"},
+		"note":     {Default: new(string)},
+	}
+	tests := []struct {
+		name     string
+		provided map[string]string
+		errParts []string // nil means no error
+	}{
+		{
+			name:     "valid values",
+			provided: map[string]string{"env": "prod", "replicas": "3", "debug": "true", "note": "anything"},
+		},
+		{
+			name:     "value outside enum",
+			provided: map[string]string{"env": "banana", "replicas": "1", "debug": "false"},
+			errParts: []string{"env", `"banana"`, "must be one of: dev, staging, prod", "--var env=dev|staging|prod"},
+		},
+		{
+			name:     "unparseable int and bool",
+			provided: map[string]string{"env": "dev", "replicas": "three", "debug": "maybe"},
+			errParts: []string{`"three" must be an int`, `"maybe" must be a bool`, "--var debug=true|false --var replicas=INT"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateVars(defs, tt.provided)
+			if tt.errParts == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			for _, part := range tt.errParts {
+				if !strings.Contains(err.Error(), part) {
+					t.Errorf("error %q does not contain %q", err.Error(), part)
+				}
+			}
+		})
+	}
+}
+
+func TestParseVarDefs_TypeAndEnum(t *testing.T) {
+	t.Run("parses constraints", func(t *testing.T) {
+		defs, _, err := ParseVarDefs(`variables:
+  env:
+    enum: [dev, staging, prod]
+    default: dev
+  replicas:
+    type: int
+`)
+		if err != nil {
+			t.Fatalf("ParseVarDefs: %v", err)
+		}
+		if got := strings.Join(defs["env"].Enum, ","); got != "dev,staging,prod" {
+			t.Errorf("env enum = %q", got)
+		}
+		if defs["replicas"].Type != "int" {
+			t.Errorf("replicas type = %q, want int", defs["replicas"].Type)
+		}
+	})
+
+	for _, tt := range []struct {
+		name, input, errPart string
+	}{
+		{"unknown type", "variables:\n  n:\n    type: float\n", `invalid type "float"`},
+		{"default outside enum", "variables:\n  env:\n    enum: [dev]\n    default: prod\n", `default "prod" must be one of: dev`},
+		{"default not an int", "variables:\n  n:\n    type: int\n    default: x\n", `default "x" must be an int`},
+		{"enum value not a bool", "variables:\n  b:\n    type: bool\n    enum: [yes-ish]\n", `enum value "yes-ish" must be a bool`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := ParseVarDefs(tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.errPart) {
+				t.Fatalf("expected error containing %q, got %v", tt.errPart, err)
+			}
+		})
+	}
+}
+
 // --- Section 3: Template Rendering ---
 
 func TestRender_BasicSubstitution(t *testing.T) {
@@ -625,6 +710,86 @@ func TestSeqFunc_ViaTemplate(t *testing.T) {
 	}
 }
 
+func TestZeropadFunc(t *testing.T) {
+	tests := []struct {
+		name    string
+		n       int
+		width   int
+		want    string
+		wantErr bool
+	}{
+		{"pads to width", 1, 2, "01", false},
+		{"exact width", 10, 2, "10", false},
+		{"wider than width", 123, 2, "123", false},
+		{"zero width", 7, 0, "7", false},
+		{"width one", 0, 1, "0", false},
+		{"negative counts the sign", -1, 3, "-01", false},
+		{"negative width", 1, -1, "", true},
+		{"width too large", 1, 21, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := zeropadFunc(tt.n, tt.width)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("zeropadFunc(%d, %d) error = %v, wantErr %v", tt.n, tt.width, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("zeropadFunc(%d, %d) = %q, want %q", tt.n, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestZeropadFunc_ViaTemplate(t *testing.T) {
+	got, err := Render(`coder-{{ zeropad .Index 2 }}`, &Context{Index: 3})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if got != "coder-03" {
+		t.Errorf("got %q, want %q", got, "coder-03")
+	}
+}
+
+func TestRequiredFunc_ViaTemplate(t *testing.T) {
+	const text = `region: {{ .Var.region | required "region is needed in prod" }}`
+
+	got, err := Render(text, &Context{Var: map[string]string{"region": "eu-west-1"}})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if got != "region: eu-west-1" {
+		t.Errorf("got %q, want %q", got, "region: eu-west-1")
+	}
+
+	for name, vars := range map[string]map[string]string{
+		"empty":   {"region": ""},
+		"missing": {},
+	} {
+		_, err := Render(text, &Context{Var: vars})
+		if err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+		if !strings.Contains(err.Error(), "region is needed in prod") {
+			t.Errorf("%s: error should carry the message, got: %v", name, err)
+		}
+	}
+}
+
+func TestRequiredFunc_OnlyEvaluatedOnItsPath(t *testing.T) {
+	text := `{{ if eq .Var.env "prod" }}{{ .Var.region | required "region is needed in prod" }}{{ else }}local{{ end }}`
+	got, err := Render(text, &Context{Var: map[string]string{"env": "dev"}})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if got != "local" {
+		t.Errorf("got %q, want %q", got, "local")
+	}
+	if _, err := Render(text, &Context{Var: map[string]string{"env": "prod"}}); err == nil {
+		t.Fatal("expected error on the prod path without region")
+	}
+}
+
 func TestSplitFunc(t *testing.T) {
 	tests := []struct {
 		name string
@@ -937,3 +1102,42 @@ agents:
 func strPtr(s string) *string {
 	return &s
 }
+
+func TestParseConsts(t *testing.T) {
+	input := `name: my-role
+consts:
+  repo: acme/app
+  port: "8080"
+instructions: |
+  Hello.
+`
+	consts, remaining, err := ParseConsts(input)
+	if err != nil {
+		t.Fatalf("ParseConsts: %v", err)
+	}
+	if consts["repo"] != "acme/app" || consts["port"] != "8080" {
+		t.Errorf("consts = %v", consts)
+	}
+	if strings.Contains(remaining, "consts:") {
+		t.Error("remaining should not contain 'consts:'")
+	}
+	if !strings.Contains(remaining, "instructions:") {
+		t.Error("remaining should contain 'instructions:'")
+	}
+
+	consts, remaining, err = ParseConsts("name: x\n")
+	if err != nil || consts != nil || remaining != "name: x\n" {
+		t.Errorf("no consts section: got %v, %q, %v", consts, remaining, err)
+	}
+}
+
+func TestMergeConsts_LowestPrecedence(t *testing.T) {
+	vars := map[string]string{"env": "prod"}
+	MergeConsts(vars, map[string]string{"env": "dev", "region": "us"})
+	if vars["env"] != "prod" {
+		t.Errorf("env = %q, want prod (existing value wins)", vars["env"])
+	}
+	if vars["region"] != "us" {
+		t.Errorf("region = %q, want us", vars["region"])
+	}
+}